@@ -1,22 +1,31 @@
 #!/bin/bash
 
-redirect_ports=${1}
+# redirect rules in '<protocol>:<port>:<redirect-port>' format, separated by ','
+# rules in legacy '<port>:<redirect-port>' format are treated as tcp
+redirect_rules=${1}
 
-if [ -n "${redirect_ports}" ]; then
-  export KT_REDIRECT_PORTS=${redirect_ports}
+parse_rule(){
+  IFS=":" read -r -a fields <<< "${1}"
+  if [ ${#fields[@]} -eq 2 ]; then
+    fields=("tcp" "${fields[0]}" "${fields[1]}")
+  fi
+}
+
+if [ -n "${redirect_rules}" ]; then
+  export KT_REDIRECT_RULES=${redirect_rules}
 
   remove_iptables_rules(){
-    for pair in $(echo "${KT_REDIRECT_PORTS}" | tr -d ,); do
-      echo "remove redirect rule: ${pair}"
-      IFS=":" read -r -a ports <<< "${pair}"
-      iptables -t nat -D PREROUTING -p tcp --dport ${ports[0]} -j REDIRECT --to-ports ${ports[1]}
+    for rule in $(echo "${KT_REDIRECT_RULES}" | tr , ' '); do
+      echo "remove redirect rule: ${rule}"
+      parse_rule "${rule}"
+      iptables -t nat -D PREROUTING -p "${fields[0]}" --dport "${fields[1]}" -j REDIRECT --to-ports "${fields[2]}"
     done
   }
   trap 'remove_iptables_rules' SIGTERM
 
-  for pair in $(echo "${redirect_ports}" | tr -d ,); do
-    echo "add redirect rule: ${pair}"
-    IFS=":" read -r -a ports <<< "${pair}"
-    iptables -t nat -A PREROUTING -p tcp --dport "${ports[0]}" -j REDIRECT --to-ports "${ports[1]}"
+  for rule in $(echo "${redirect_rules}" | tr , ' '); do
+    echo "add redirect rule: ${rule}"
+    parse_rule "${rule}"
+    iptables -t nat -A PREROUTING -p "${fields[0]}" --dport "${fields[1]}" -j REDIRECT --to-ports "${fields[2]}"
   done
-fi
\ No newline at end of file
+fi
@@ -14,7 +14,7 @@ if [ -n "${privateKey}" ]; then
   echo "Private key created created"
 fi
 
-if [ "${KT_DNS_PROTOCOL}" = "" ]; then
+if [ "${KT_DNS_PROTOCOL}" = "" ] && [ "${KT_HEALTH_PORT}" = "" ]; then
   echo "Skip shadow process"
 elif [ "${1}" = "--debug" ]; then
   echo "Run shadow in debug mode"
@@ -24,4 +24,15 @@ else
   /usr/sbin/shadow &
 fi
 
+# restrict ssh algorithms to those used by ktctl
+if [ -n "${KT_SSH_CIPHERS}" ]; then
+  sed -i "s/^Ciphers .*/Ciphers ${KT_SSH_CIPHERS}/" /etc/ssh/sshd_config
+fi
+if [ -n "${KT_SSH_KEX}" ]; then
+  sed -i "s/^KexAlgorithms .*/KexAlgorithms ${KT_SSH_KEX}/" /etc/ssh/sshd_config
+fi
+if [ -n "${KT_SSH_MACS}" ]; then
+  sed -i "s/^MACs .*/MACs ${KT_SSH_MACS}/" /etc/ssh/sshd_config
+fi
+
 /usr/sbin/sshd -D
\ No newline at end of file
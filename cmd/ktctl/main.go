@@ -34,9 +34,9 @@ func main() {
 	cobra.EnableCommandSorting = false
 
 	var rootCmd = &cobra.Command{
-		Use:   "ktctl",
+		Use:     "ktctl",
 		Version: version,
-		Short: "A utility tool to help you work with Kubernetes dev environment more efficiently",
+		Short:   "A utility tool to help you work with Kubernetes dev environment more efficiently",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return cmd.Help()
 		},
@@ -49,9 +49,14 @@ func main() {
 	rootCmd.AddCommand(command.NewPreviewCommand())
 	rootCmd.AddCommand(command.NewForwardCommand())
 	rootCmd.AddCommand(command.NewRecoverCommand())
+	rootCmd.AddCommand(command.NewDiffCommand())
 	rootCmd.AddCommand(command.NewCleanCommand())
 	rootCmd.AddCommand(command.NewConfigCommand())
+	rootCmd.AddCommand(command.NewPreheatCommand())
+	rootCmd.AddCommand(command.NewGatewayCommand())
 	rootCmd.AddCommand(command.NewBirdseyeCommand())
+	rootCmd.AddCommand(command.NewReplayCommand())
+	rootCmd.AddCommand(command.NewVersionCommand())
 	rootCmd.SetHelpCommand(&cobra.Command{Hidden: true})
 	rootCmd.SetUsageTemplate(general.UsageTemplate(false))
 	rootCmd.SilenceUsage = true
@@ -59,8 +64,13 @@ func main() {
 	opt.SetOptions(rootCmd, rootCmd.PersistentFlags(), opt.Get().Global, opt.GlobalFlags())
 
 	// process will hang here
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	if err != nil {
 		log.Error().Msgf("Exit: %s", err)
 	}
 	general.CleanupWorkspace()
+	if err != nil {
+		// let scripts know the command failed, e.g. probe of connect not passed
+		os.Exit(1)
+	}
 }
@@ -1,4 +1,41 @@
 package main
 
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/common"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"os"
+)
+
+func init() {
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+}
+
+const actionUdpRelay = "udp-relay"
+
 func main() {
+	if len(os.Args) < 4 {
+		usage()
+		return
+	}
+	switch os.Args[1] {
+	case actionUdpRelay:
+		udpAddress := fmt.Sprintf(":%s", os.Args[2])
+		tcpAddress := fmt.Sprintf("%s:%s", common.Localhost, os.Args[3])
+		if err := common.RelayUdpToTcp(udpAddress, tcpAddress); err != nil {
+			log.Error().Err(err).Msgf("Udp relay exited")
+			os.Exit(1)
+		}
+	default:
+		log.Error().Msgf("Invalid action '%s'", os.Args[1])
+		usage()
+	}
+}
+
+func usage() {
+	log.Info().Msgf(`Usage:
+navigator %s <udp-port> <tcp-port>
+`, actionUdpRelay)
 }
@@ -3,9 +3,11 @@ package main
 import (
 	"github.com/alibaba/kt-connect/pkg/common"
 	"github.com/alibaba/kt-connect/pkg/shadow/dnsserver"
+	"github.com/alibaba/kt-connect/pkg/shadow/health"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -16,6 +18,8 @@ const (
 	ArgDnsProtocol = "--protocol"
 	// ArgLogLevel application argument for shadow pod log level
 	ArgLogLevel = "--log-level"
+	// ArgHealthPort application argument for shadow pod health endpoint port
+	ArgHealthPort = "--health-port"
 )
 
 func init() {
@@ -29,6 +33,18 @@ func main() {
 		log.Error().Err(err).Msgf("Failed to parse log level")
 	}
 	zerolog.SetGlobalLevel(level)
+	if healthPort := getParameter(common.EnvVarHealthPort, ArgHealthPort, ""); healthPort != "" {
+		port, err2 := strconv.Atoi(healthPort)
+		if err2 != nil {
+			log.Error().Err(err2).Msgf("Invalid health port %s", healthPort)
+		} else if os.Getenv(common.EnvVarDnsProtocol) == "" {
+			// exchange shadow only need the health endpoint
+			health.Start(port)
+			return
+		} else {
+			go health.Start(port)
+		}
+	}
 	dnsPort := common.StandardDnsPort
 	dnsProtocol := getParameter(common.EnvVarDnsProtocol, ArgDnsProtocol, "udp")
 	localDomain := getParameter(common.EnvVarLocalDomains, ArgLocalDomains, "")
@@ -17,4 +17,14 @@ const (
 	EnvVarDnsProtocol = "KT_DNS_PROTOCOL"
 	// EnvVarLogLevel environment variable for shadow pod log level
 	EnvVarLogLevel = "KT_LOG_LEVEL"
+	// EnvVarHealthPort environment variable for shadow pod health endpoint port
+	EnvVarHealthPort = "KT_HEALTH_PORT"
+	// EnvVarSshCiphers environment variable for ciphers allowed by sshd of shadow pod
+	EnvVarSshCiphers = "KT_SSH_CIPHERS"
+	// EnvVarSshKex environment variable for key exchange algorithms allowed by sshd of shadow pod
+	EnvVarSshKex = "KT_SSH_KEX"
+	// EnvVarSshMacs environment variable for mac algorithms allowed by sshd of shadow pod
+	EnvVarSshMacs = "KT_SSH_MACS"
+	// ShadowHealthPath path of shadow pod health endpoint
+	ShadowHealthPath = "/healthz"
 )
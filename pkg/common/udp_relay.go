@@ -0,0 +1,140 @@
+package common
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// UdpRelayIdleTimeout relay session without any packet longer than this will be closed
+const UdpRelayIdleTimeout = 60 * time.Second
+
+// maxUdpPacketSize largest payload of a udp packet
+const maxUdpPacketSize = 65535
+
+// WriteUdpFrame write udp packet to stream with 2 bytes length prefix
+func WriteUdpFrame(w io.Writer, data []byte) error {
+	if len(data) > maxUdpPacketSize {
+		return fmt.Errorf("udp packet too large (%d bytes)", len(data))
+	}
+	frame := make([]byte, 2+len(data))
+	binary.BigEndian.PutUint16(frame, uint16(len(data)))
+	copy(frame[2:], data)
+	_, err := w.Write(frame)
+	return err
+}
+
+// ReadUdpFrame read one udp packet written by WriteUdpFrame
+func ReadUdpFrame(r *bufio.Reader) ([]byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	data := make([]byte, binary.BigEndian.Uint16(header))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// RelayUdpToTcp receive udp packets on specified address, and send them to tcp address as frames,
+// each udp client use a separate tcp connection, so that responses can be sent back to it
+func RelayUdpToTcp(udpAddress, tcpAddress string) error {
+	addr, err := net.ResolveUDPAddr("udp", udpAddress)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	log.Info().Msgf("Relaying udp %s to tcp %s", udpAddress, tcpAddress)
+
+	sessions := sync.Map{}
+	buf := make([]byte, maxUdpPacketSize)
+	for {
+		n, client, err2 := conn.ReadFromUDP(buf)
+		if err2 != nil {
+			return err2
+		}
+		var stream net.Conn
+		if s, exists := sessions.Load(client.String()); exists {
+			stream = s.(net.Conn)
+		} else {
+			if stream, err2 = net.Dial("tcp", tcpAddress); err2 != nil {
+				log.Warn().Err(err2).Msgf("Failed to connect %s for udp client %s", tcpAddress, client)
+				continue
+			}
+			sessions.Store(client.String(), stream)
+			go func(client *net.UDPAddr, stream net.Conn) {
+				defer sessions.Delete(client.String())
+				defer stream.Close()
+				reader := bufio.NewReader(stream)
+				for {
+					_ = stream.SetReadDeadline(time.Now().Add(UdpRelayIdleTimeout))
+					data, err3 := ReadUdpFrame(reader)
+					if err3 != nil {
+						return
+					}
+					if _, err3 = conn.WriteToUDP(data, client); err3 != nil {
+						return
+					}
+				}
+			}(client, stream)
+		}
+		if err2 = WriteUdpFrame(stream, buf[:n]); err2 != nil {
+			log.Debug().Err(err2).Msgf("Failed to relay udp packet of client %s", client)
+			_ = stream.Close()
+		}
+	}
+}
+
+// RelayTcpToUdp accept tcp connections carrying udp frames, and send the packets to specified udp address,
+// responses are sent back via the same tcp connection
+func RelayTcpToUdp(listener net.Listener, udpAddress string) error {
+	for {
+		stream, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer stream.Close()
+			conn, err2 := net.Dial("udp", udpAddress)
+			if err2 != nil {
+				log.Warn().Err(err2).Msgf("Failed to connect udp %s", udpAddress)
+				return
+			}
+			defer conn.Close()
+			go func() {
+				buf := make([]byte, maxUdpPacketSize)
+				for {
+					_ = conn.SetReadDeadline(time.Now().Add(UdpRelayIdleTimeout))
+					n, err3 := conn.Read(buf)
+					if err3 != nil {
+						_ = stream.Close()
+						return
+					}
+					if err3 = WriteUdpFrame(stream, buf[:n]); err3 != nil {
+						return
+					}
+				}
+			}()
+			reader := bufio.NewReader(stream)
+			for {
+				data, err3 := ReadUdpFrame(reader)
+				if err3 != nil {
+					return
+				}
+				if _, err3 = conn.Write(data); err3 != nil {
+					log.Debug().Err(err3).Msgf("Failed to send udp packet to %s", udpAddress)
+				}
+			}
+		}()
+	}
+}
@@ -0,0 +1,71 @@
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestUdpFrame(t *testing.T) {
+	var buf bytes.Buffer
+	require.Nil(t, WriteUdpFrame(&buf, []byte("hello")))
+	require.Nil(t, WriteUdpFrame(&buf, []byte{}))
+	reader := bufio.NewReader(&buf)
+	data, err := ReadUdpFrame(reader)
+	require.Nil(t, err)
+	require.Equal(t, "hello", string(data))
+	data, err = ReadUdpFrame(reader)
+	require.Nil(t, err)
+	require.Empty(t, data)
+	_, err = ReadUdpFrame(reader)
+	require.NotNil(t, err)
+}
+
+func TestUdpRelay(t *testing.T) {
+	// udp echo server as target service
+	target, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP(Localhost)})
+	require.Nil(t, err)
+	defer target.Close()
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err2 := target.ReadFromUDP(buf)
+			if err2 != nil {
+				return
+			}
+			_, _ = target.WriteToUDP(append([]byte("echo "), buf[:n]...), addr)
+		}
+	}()
+
+	// udp packets -> relay -> tcp stream -> relay -> target
+	listener, err := net.Listen("tcp", Localhost+":0")
+	require.Nil(t, err)
+	defer listener.Close()
+	go func() {
+		_ = RelayTcpToUdp(listener, target.LocalAddr().String())
+	}()
+	relay, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP(Localhost)})
+	require.Nil(t, err)
+	relayAddress := relay.LocalAddr().String()
+	_ = relay.Close()
+	go func() {
+		_ = RelayUdpToTcp(relayAddress, listener.Addr().String())
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	client, err := net.Dial("udp", relayAddress)
+	require.Nil(t, err)
+	defer client.Close()
+	buf := make([]byte, 1024)
+	for _, msg := range []string{"a", "bc"} {
+		_, err = client.Write([]byte(msg))
+		require.Nil(t, err)
+		_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, err2 := client.Read(buf)
+		require.Nil(t, err2)
+		require.Equal(t, "echo "+msg, string(buf[:n]))
+	}
+}
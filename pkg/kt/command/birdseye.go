@@ -18,9 +18,9 @@ func NewBirdseyeCommand() *cobra.Command {
 		Short: "Show summary of services status in cluster",
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 0 {
-				return fmt.Errorf("too many options specified (%s)", strings.Join(args, ",") )
+				return fmt.Errorf("too many options specified (%s)", strings.Join(args, ","))
 			}
-			return general.Prepare()
+			return prepareAndReap(cmd)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return Birdseye()
@@ -47,7 +47,7 @@ func GetKtPodsAndDeployments() ([]coreV1.Pod, []appV1.Deployment, error) {
 }
 
 func GetConnectors(pods []coreV1.Pod, apps []appV1.Deployment) []string {
-	users:= make([]string, 0)
+	users := make([]string, 0)
 	for _, pod := range pods {
 		if user := checkConnector(pod.Annotations); user != "" {
 			users = append(users, user)
@@ -66,21 +66,21 @@ func GetServiceStatus(ktSvcs []coreV1.Service, pods []coreV1.Pod, svcs []coreV1.
 	for _, svc := range ktSvcs {
 		for _, p := range pods {
 			if p.Labels[util.KtRole] == util.RolePreviewShadow && util.MapContains(svc.Spec.Selector, p.Labels) {
-				allServices = append(allServices, []string{svc.Name, fmt.Sprintf("previewing by [%s]", getUserName(p))})
+				allServices = append(allServices, []string{svc.Name, fmt.Sprintf("previewing by [%s]", getUserName(p.Annotations))})
 				break
 			}
 		}
 	}
-	svcLoop:
+svcLoop:
 	for _, svc := range svcs {
 		for _, p := range pods {
 			if util.MapContains(svc.Spec.Selector, p.Labels) {
 				if role := p.Labels[util.KtRole]; role == util.RoleExchangeShadow {
-					allServices = append(allServices, []string{svc.Name, fmt.Sprintf("exchanged by [%s]", getUserName(p))})
+					allServices = append(allServices, []string{svc.Name, fmt.Sprintf("exchanged by [%s]", getUserName(p.Annotations))})
 					continue svcLoop
 				} else if role == util.RoleRouter {
 					allServices = append(allServices, []string{svc.Name, "meshed (auto) by " +
-						getMeshedUserNames(ktSvcs, pods, svc.Name + util.MeshPodInfix)})
+						getMeshedUserNames(ktSvcs, pods, svc.Name+util.MeshPodInfix)})
 					continue svcLoop
 				} else if role == util.RoleMeshShadow {
 					allServices = append(allServices, []string{svc.Name, "meshed (manual) by " +
@@ -96,10 +96,17 @@ func GetServiceStatus(ktSvcs []coreV1.Service, pods []coreV1.Pod, svcs []coreV1.
 	return allServices
 }
 
-func getUserName(p coreV1.Pod) string {
-	user := p.Annotations[util.KtUser]
+// getUserName local username, with hostname and kubernetes user if recorded
+func getUserName(annotations map[string]string) string {
+	user := annotations[util.KtUser]
 	if user == "" {
-		user = UnknownUser
+		return UnknownUser
+	}
+	if host := annotations[util.KtHost]; host != "" {
+		user += "@" + host
+	}
+	if kubeUser := annotations[util.KtKubeUser]; kubeUser != "" {
+		user += " as " + kubeUser
 	}
 	return user
 }
@@ -110,9 +117,8 @@ func getMeshedUserNames(svcs []coreV1.Service, pods []coreV1.Pod, namePrefix str
 		if strings.HasPrefix(s.Name, namePrefix) {
 			for _, p := range pods {
 				if p.Labels[util.KtRole] == util.RoleMeshShadow && util.MapContains(s.Spec.Selector, p.Labels) {
-					user := p.Annotations[util.KtUser]
-					if user != "" {
-						users = append(users, user)
+					if p.Annotations[util.KtUser] != "" {
+						users = append(users, getUserName(p.Annotations))
 					}
 					break
 				}
@@ -126,7 +132,8 @@ func getMeshedUserNames(svcs []coreV1.Service, pods []coreV1.Pod, namePrefix str
 }
 
 func checkConnector(annotations map[string]string) string {
-	if user, exists := annotations[util.KtUser]; exists {
+	if _, exists := annotations[util.KtUser]; exists {
+		user := getUserName(annotations)
 		lastHeartBeat := util.ParseTimestamp(annotations[util.KtLastHeartBeat])
 		if lastHeartBeat > 0 {
 			lastActiveInMin := (util.GetTime() - lastHeartBeat) / 60
@@ -0,0 +1,14 @@
+package birdseye
+
+import (
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_getUserName(t *testing.T) {
+	require.Equal(t, UnknownUser, getUserName(map[string]string{}))
+	require.Equal(t, "alice", getUserName(map[string]string{util.KtUser: "alice"}))
+	require.Equal(t, "alice@laptop as admin", getUserName(map[string]string{
+		util.KtUser: "alice", util.KtHost: "laptop", util.KtKubeUser: "admin"}))
+}
@@ -5,23 +5,28 @@ import (
 	"github.com/alibaba/kt-connect/pkg/kt/command/clean"
 	"github.com/alibaba/kt-connect/pkg/kt/command/general"
 	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+	"os"
 	"strings"
 )
 
 // NewCleanCommand return new connect command
 func NewCleanCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:  "clean",
+		Use:   "clean",
 		Short: "Delete unavailing resources created by kt from kubernetes cluster",
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 0 {
-				return fmt.Errorf("too many options specified (%s)", strings.Join(args, ",") )
+				return fmt.Errorf("too many options specified (%s)", strings.Join(args, ","))
 			}
-			return general.Prepare()
+			return general.Prepare(cmd)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if opt.Get().Clean.RestoreOrigins {
+				return clean.RestoreOrigins(!isNamespaceSpecified(cmd))
+			}
 			return Clean()
 		},
 		Example: "ktctl clean [command options]",
@@ -58,5 +63,22 @@ func isEmpty(r *clean.ResourceToClean) bool {
 		len(r.DeploymentsToScale) == 0 &&
 		len(r.ServicesToDelete) == 0 &&
 		len(r.ServicesToUnlock) == 0 &&
+		len(r.ServicesToRestoreAffinity) == 0 &&
+		len(r.HttpRoutesToDelete) == 0 &&
+		len(r.DaemonSetsToDelete) == 0 &&
+		len(r.IngressesToDelete) == 0 &&
 		len(r.ServicesToRecover) == 0
 }
+
+// prepareAndReap prepare command, then reap expired shadows in target namespace left by crashed sessions
+func prepareAndReap(cmd *cobra.Command) error {
+	if err := general.Prepare(cmd); err != nil {
+		return err
+	}
+	clean.ReapExpiredShadows()
+	return nil
+}
+
+func isNamespaceSpecified(cmd *cobra.Command) bool {
+	return cmd.Flags().Changed("namespace") || os.Getenv(util.EnvKtNamespace) != ""
+}
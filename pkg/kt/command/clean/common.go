@@ -12,22 +12,27 @@ import (
 	"io/ioutil"
 	appV1 "k8s.io/api/apps/v1"
 	coreV1 "k8s.io/api/core/v1"
+	netV1 "k8s.io/api/networking/v1"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type ResourceToClean struct {
-	PodsToDelete        []string
-	ServicesToDelete    []string
-	ConfigMapsToDelete  []string
-	DeploymentsToDelete []string
-	DeploymentsToScale  map[string]int32
-	ServicesToRecover   []string
-	ServicesToUnlock   []string
+	PodsToDelete              []string
+	ServicesToDelete          []string
+	ConfigMapsToDelete        []string
+	DeploymentsToDelete       []string
+	DeploymentsToScale        map[string]int32
+	ServicesToRecover         []string
+	ServicesToUnlock          []string
+	ServicesToRestoreAffinity []string
+	HttpRoutesToDelete        []string
+	DaemonSetsToDelete        []string
+	IngressesToDelete         []string
 }
 
-
 func CheckClusterResources() (*ResourceToClean, error) {
 	pods, cfs, apps, svcs, err := cluster.Ins().GetKtResources(opt.Get().Global.Namespace)
 	if err != nil {
@@ -35,13 +40,17 @@ func CheckClusterResources() (*ResourceToClean, error) {
 	}
 	log.Debug().Msgf("Find %d kt pods", len(pods))
 	resourceToClean := ResourceToClean{
-		PodsToDelete:        make([]string, 0),
-		ServicesToDelete:    make([]string, 0),
-		ConfigMapsToDelete:  make([]string, 0),
-		DeploymentsToDelete: make([]string, 0),
-		DeploymentsToScale:  make(map[string]int32),
-		ServicesToRecover:   make([]string, 0),
-		ServicesToUnlock:    make([]string, 0),
+		PodsToDelete:              make([]string, 0),
+		ServicesToDelete:          make([]string, 0),
+		ConfigMapsToDelete:        make([]string, 0),
+		DeploymentsToDelete:       make([]string, 0),
+		DeploymentsToScale:        make(map[string]int32),
+		ServicesToRecover:         make([]string, 0),
+		ServicesToUnlock:          make([]string, 0),
+		ServicesToRestoreAffinity: make([]string, 0),
+		HttpRoutesToDelete:        make([]string, 0),
+		DaemonSetsToDelete:        make([]string, 0),
+		IngressesToDelete:         make([]string, 0),
 	}
 	for _, pod := range pods {
 		analysisExpiredPods(pod, opt.Get().Clean.ThresholdInMinus, &resourceToClean)
@@ -49,14 +58,40 @@ func CheckClusterResources() (*ResourceToClean, error) {
 	for _, cf := range cfs {
 		analysisExpiredConfigmaps(cf, opt.Get().Clean.ThresholdInMinus, &resourceToClean)
 	}
+	analysisOrphanSshConfigmaps(cfs, &resourceToClean)
 	for _, app := range apps {
 		analysisExpiredDeployments(app, opt.Get().Clean.ThresholdInMinus, &resourceToClean)
 	}
 	for _, svc := range svcs {
 		analysisExpiredServices(svc, opt.Get().Clean.ThresholdInMinus, &resourceToClean)
 	}
+	if dsList, err2 := cluster.Ins().GetDaemonSetsByLabel(map[string]string{util.ControlBy: util.KubernetesToolkit},
+		opt.Get().Global.Namespace); err2 != nil {
+		log.Debug().Err(err2).Msgf("Failed to list daemon sets")
+	} else {
+		for _, ds := range dsList.Items {
+			analysisExpiredDaemonSets(ds, opt.Get().Clean.ThresholdInMinus, &resourceToClean)
+		}
+	}
+	if ingresses, err2 := cluster.Ins().GetIngressesByLabel(map[string]string{util.ControlBy: util.KubernetesToolkit},
+		opt.Get().Global.Namespace); err2 != nil {
+		log.Debug().Err(err2).Msgf("Failed to list ingresses")
+	} else {
+		for _, ingress := range ingresses.Items {
+			analysisExpiredIngresses(ingress, opt.Get().Clean.ThresholdInMinus, &resourceToClean)
+		}
+	}
 	svcList, err := cluster.Ins().GetAllServiceInNamespace(opt.Get().Global.Namespace)
 	analysisLockAndOrphanServices(svcList.Items, &resourceToClean)
+	if cluster.Ins().IsGatewayApiInstalled() {
+		if routes, err2 := cluster.Ins().GetAllHttpRouteInNamespace(opt.Get().Global.Namespace); err2 != nil {
+			log.Debug().Err(err2).Msgf("Failed to list http routes")
+		} else {
+			for _, route := range routes {
+				analysisExpiredHttpRoutes(route, opt.Get().Clean.ThresholdInMinus, &resourceToClean)
+			}
+		}
+	}
 	return &resourceToClean, nil
 }
 
@@ -88,6 +123,15 @@ func TidyClusterResources(r *ResourceToClean) {
 			log.Info().Msgf(" * %s", name)
 		}
 	}
+	log.Info().Msgf("Deleting %d unavailing daemon sets", len(r.DaemonSetsToDelete))
+	for _, name := range r.DaemonSetsToDelete {
+		err := cluster.Ins().RemoveDaemonSet(name, opt.Get().Global.Namespace)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to delete daemon set %s", name)
+		} else {
+			log.Info().Msgf(" * %s", name)
+		}
+	}
 	log.Info().Msgf("Recovering %d scaled deployments", len(r.DeploymentsToScale))
 	for name, replica := range r.DeploymentsToScale {
 		err := cluster.Ins().ScaleTo(name, opt.Get().Global.Namespace, &replica)
@@ -106,10 +150,35 @@ func TidyClusterResources(r *ResourceToClean) {
 			log.Info().Msgf(" * %s", name)
 		}
 	}
+	log.Info().Msgf("Deleting %d unavailing http routes", len(r.HttpRoutesToDelete))
+	for _, name := range r.HttpRoutesToDelete {
+		err := cluster.Ins().RemoveHttpRoute(name, opt.Get().Global.Namespace)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to delete http route %s", name)
+		} else {
+			log.Info().Msgf(" * %s", name)
+		}
+	}
+	log.Info().Msgf("Deleting %d unavailing ingresses", len(r.IngressesToDelete))
+	for _, name := range r.IngressesToDelete {
+		err := cluster.Ins().RemoveIngress(name, opt.Get().Global.Namespace)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to delete ingress %s", name)
+		} else {
+			log.Info().Msgf(" * %s", name)
+		}
+	}
 	log.Info().Msgf("Recovering %d meshed services", len(r.ServicesToRecover))
 	for _, name := range r.ServicesToRecover {
-		general.RecoverOriginalService(name, opt.Get().Global.Namespace)
-		log.Info().Msgf(" * %s", name)
+		if err := general.RecoverOriginalService(name, opt.Get().Global.Namespace); err == nil {
+			log.Info().Msgf(" * %s", name)
+		}
+	}
+	log.Info().Msgf("Recovering session affinity of %d services", len(r.ServicesToRestoreAffinity))
+	for _, name := range r.ServicesToRestoreAffinity {
+		if err := general.RecoverSessionAffinity(name, opt.Get().Global.Namespace); err == nil {
+			log.Info().Msgf(" * %s", name)
+		}
 	}
 	log.Info().Msgf("Recovering %d locked services", len(r.ServicesToUnlock))
 	for _, name := range r.ServicesToUnlock {
@@ -139,6 +208,10 @@ func PrintClusterResourcesToClean(r *ResourceToClean) {
 	for _, name := range r.DeploymentsToDelete {
 		log.Info().Msgf(" * %s", name)
 	}
+	log.Info().Msgf("Find %d unavailing daemon sets to delete:", len(r.DaemonSetsToDelete))
+	for _, name := range r.DaemonSetsToDelete {
+		log.Info().Msgf(" * %s", name)
+	}
 	log.Info().Msgf("Find %d exchanged deployments to recover:", len(r.DeploymentsToScale))
 	for name, replica := range r.DeploymentsToScale {
 		log.Info().Msgf(" * %s -> %d", name, replica)
@@ -147,10 +220,22 @@ func PrintClusterResourcesToClean(r *ResourceToClean) {
 	for _, name := range r.ServicesToDelete {
 		log.Info().Msgf(" * %s", name)
 	}
+	log.Info().Msgf("Find %d unavailing http routes to delete:", len(r.HttpRoutesToDelete))
+	for _, name := range r.HttpRoutesToDelete {
+		log.Info().Msgf(" * %s", name)
+	}
+	log.Info().Msgf("Find %d unavailing ingresses to delete:", len(r.IngressesToDelete))
+	for _, name := range r.IngressesToDelete {
+		log.Info().Msgf(" * %s", name)
+	}
 	log.Info().Msgf("Find %d meshed service to recover:", len(r.ServicesToRecover))
 	for _, name := range r.ServicesToRecover {
 		log.Info().Msgf(" * %s", name)
 	}
+	log.Info().Msgf("Find %d services to recover session affinity:", len(r.ServicesToRestoreAffinity))
+	for _, name := range r.ServicesToRestoreAffinity {
+		log.Info().Msgf(" * %s", name)
+	}
 	log.Info().Msgf("Find %d locked services to recover:", len(r.ServicesToUnlock))
 	for _, name := range r.ServicesToUnlock {
 		log.Info().Msgf(" * %s", name)
@@ -201,7 +286,7 @@ func parseComponentAndPid(pidFileName string) (string, int) {
 	startPos := strings.LastIndex(pidFileName, "-")
 	endPos := strings.Index(pidFileName, ".")
 	if startPos > 0 && endPos > startPos {
-		component := pidFileName[0 : startPos]
+		component := pidFileName[0:startPos]
 		pid, err := strconv.Atoi(pidFileName[startPos+1 : endPos])
 		if err != nil {
 			return "", -1
@@ -233,6 +318,59 @@ func analysisExpiredConfigmaps(cf coreV1.ConfigMap, cleanThresholdInMinus int64,
 	}
 }
 
+// analysisOrphanSshConfigmaps find ssh config maps whose shadow is already gone, e.g. left by crashed session
+func analysisOrphanSshConfigmaps(cfs []coreV1.ConfigMap, resourceToClean *ResourceToClean) {
+	inUse, err := getSshConfigmapsInUse(opt.Get().Global.Namespace)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to check ssh config maps in use")
+		return
+	}
+	for _, cf := range cfs {
+		if _, exists := cf.Data[util.SshAuthKey]; !exists || inUse[cf.Name] ||
+			util.Contains(resourceToClean.ConfigMapsToDelete, cf.Name) {
+			continue
+		}
+		// shadow pod may not be created yet
+		if time.Since(cf.CreationTimestamp.Time) < time.Duration(opt.Get().Global.PodCreationTimeout)*time.Second {
+			continue
+		}
+		log.Debug().Msgf(" * config map %s has no live shadow", cf.Name)
+		resourceToClean.ConfigMapsToDelete = append(resourceToClean.ConfigMapsToDelete, cf.Name)
+	}
+}
+
+// getSshConfigmapsInUse get name of config maps mounted by live pods or deployments,
+// as well as name of live pods, since ssh config map of ephemeral container is named after its pod
+func getSshConfigmapsInUse(namespace string) (map[string]bool, error) {
+	inUse := make(map[string]bool)
+	addVolumes := func(spec coreV1.PodSpec) {
+		for _, v := range spec.Volumes {
+			if v.ConfigMap != nil {
+				inUse[v.ConfigMap.Name] = true
+			}
+		}
+	}
+	pods, err := cluster.Ins().GetPodsByLabel(map[string]string{}, namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == coreV1.PodSucceeded || pod.Status.Phase == coreV1.PodFailed {
+			continue
+		}
+		inUse[pod.Name] = true
+		addVolumes(pod.Spec)
+	}
+	apps, err := cluster.Ins().GetAllDeploymentInNamespace(namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, app := range apps.Items {
+		addVolumes(app.Spec.Template.Spec)
+	}
+	return inUse, nil
+}
+
 func analysisExpiredDeployments(app appV1.Deployment, cleanThresholdInMinus int64, resourceToClean *ResourceToClean) {
 	lastHeartBeat := util.ParseTimestamp(app.Annotations[util.KtLastHeartBeat])
 	if lastHeartBeat < 0 {
@@ -243,6 +381,16 @@ func analysisExpiredDeployments(app appV1.Deployment, cleanThresholdInMinus int6
 	}
 }
 
+// analysisExpiredDaemonSets find preheat daemon sets left by crashed session, their pods are removed along with them
+func analysisExpiredDaemonSets(ds appV1.DaemonSet, cleanThresholdInMinus int64, resourceToClean *ResourceToClean) {
+	lastHeartBeat := util.ParseTimestamp(ds.Annotations[util.KtLastHeartBeat])
+	if lastHeartBeat < 0 {
+		log.Debug().Msgf("Daemon set %s does no have heart beat annotation", ds.Name)
+	} else if isExpired(lastHeartBeat, cleanThresholdInMinus) {
+		resourceToClean.DaemonSetsToDelete = append(resourceToClean.DaemonSetsToDelete, ds.Name)
+	}
+}
+
 func analysisExpiredServices(svc coreV1.Service, cleanThresholdInMinus int64, resourceToClean *ResourceToClean) {
 	lastHeartBeat := util.ParseTimestamp(svc.Annotations[util.KtLastHeartBeat])
 	if lastHeartBeat < 0 {
@@ -252,14 +400,44 @@ func analysisExpiredServices(svc coreV1.Service, cleanThresholdInMinus int64, re
 	}
 }
 
+// analysisExpiredHttpRoutes find http routes created by gateway exchange whose session is gone, otherwise they'd keep
+// routing requests with the header to a removed backend
+func analysisExpiredHttpRoutes(route cluster.HTTPRoute, cleanThresholdInMinus int64, resourceToClean *ResourceToClean) {
+	if route.Metadata.Labels[util.ControlBy] != util.KubernetesToolkit {
+		return
+	}
+	lastHeartBeat := util.ParseTimestamp(route.Metadata.Annotations[util.KtLastHeartBeat])
+	if lastHeartBeat < 0 {
+		log.Debug().Msgf("Http route %s does no have heart beat annotation", route.Metadata.Name)
+	} else if isExpired(lastHeartBeat, cleanThresholdInMinus) {
+		resourceToClean.HttpRoutesToDelete = append(resourceToClean.HttpRoutesToDelete, route.Metadata.Name)
+	}
+}
+
+// analysisExpiredIngresses find ingresses created by exchange whose session is gone, otherwise the host would keep
+// pointing at the origin service
+func analysisExpiredIngresses(ingress netV1.Ingress, cleanThresholdInMinus int64, resourceToClean *ResourceToClean) {
+	lastHeartBeat := util.ParseTimestamp(ingress.Annotations[util.KtLastHeartBeat])
+	if lastHeartBeat < 0 {
+		log.Debug().Msgf("Ingress %s does no have heart beat annotation", ingress.Name)
+	} else if isExpired(lastHeartBeat, cleanThresholdInMinus) {
+		resourceToClean.IngressesToDelete = append(resourceToClean.IngressesToDelete, ingress.Name)
+	}
+}
+
 func analysisLockAndOrphanServices(svcs []coreV1.Service, resourceToClean *ResourceToClean) {
 	for _, svc := range svcs {
 		if svc.Annotations == nil {
 			continue
 		}
-		if lock, exists := svc.Annotations[util.KtLock]; exists && util.GetTime() - util.ParseTimestamp(lock) > general.LockTimeout {
+		if lock, exists := svc.Annotations[util.KtLock]; exists && util.GetTime()-util.ParseTimestamp(lock) > general.LockTimeout {
 			resourceToClean.ServicesToUnlock = append(resourceToClean.ServicesToUnlock, svc.Name)
 		}
+		if svc.Annotations[util.KtSessionAffinity] != "" && svc.Annotations[util.KtSelector] == "" &&
+			!isExchangeShadowSelected(svc.Spec.Selector, svc.Namespace) {
+			// session affinity disabled by scale exchange, but shadow pod already gone
+			resourceToClean.ServicesToRestoreAffinity = append(resourceToClean.ServicesToRestoreAffinity, svc.Name)
+		}
 		if svc.Annotations[util.KtSelector] != "" {
 			if svc.Spec.Selector[util.KtRole] == util.RoleRouter {
 				// it's a meshed service, but router pod already gone
@@ -294,6 +472,23 @@ func analysisConfigAnnotation(role string, config map[string]string, resourceToC
 	}
 }
 
+func isExchangeShadowSelected(selector map[string]string, namespace string) bool {
+	if len(selector) == 0 {
+		return false
+	}
+	pods, err := cluster.Ins().GetPodsByLabel(selector, namespace)
+	if err != nil {
+		// treat as in use to avoid changing service of a working exchange
+		return true
+	}
+	for _, pod := range pods.Items {
+		if pod.Labels[util.KtRole] == util.RoleExchangeShadow && pod.DeletionTimestamp == nil {
+			return true
+		}
+	}
+	return false
+}
+
 func isShadowPodExist(selector map[string]string, svcName, namespace, suffix string) bool {
 	pods, err := cluster.Ins().GetPodsByLabel(selector, namespace)
 	if err != nil {
@@ -314,6 +509,5 @@ func isRouterPodExist(svcName, namespace string) bool {
 }
 
 func isExpired(lastHeartBeat, cleanThresholdInMinus int64) bool {
-	return util.GetTime() - lastHeartBeat > cleanThresholdInMinus*60
+	return util.GetTime()-lastHeartBeat > cleanThresholdInMinus*60
 }
-
@@ -1,7 +1,18 @@
 package clean
 
 import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster/fake"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	appV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	netV1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func Test_toPid(t *testing.T) {
@@ -18,3 +29,134 @@ func Test_toPid(t *testing.T) {
 		t.Errorf("unmatch %d", pid)
 	}
 }
+
+func Test_analysisOrphanSshConfigmaps(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	opt.Get().Global.PodCreationTimeout = 60
+	sshVolume := func(name string) []coreV1.Volume {
+		return []coreV1.Volume{{Name: "ssh-public-key", VolumeSource: coreV1.VolumeSource{
+			ConfigMap: &coreV1.ConfigMapVolumeSource{LocalObjectReference: coreV1.LocalObjectReference{Name: name}},
+		}}}
+	}
+	cluster.SetIns(fake.NewKubernetes(
+		&coreV1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "shadow-pod-abc", Namespace: "default"},
+			Spec:       coreV1.PodSpec{Volumes: sshVolume("shadow-pod")},
+			Status:     coreV1.PodStatus{Phase: coreV1.PodRunning},
+		},
+		&coreV1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "ephemeral-target", Namespace: "default"},
+			Status:     coreV1.PodStatus{Phase: coreV1.PodRunning},
+		},
+		&coreV1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "failed-pod", Namespace: "default"},
+			Spec:       coreV1.PodSpec{Volumes: sshVolume("failed-pod")},
+			Status:     coreV1.PodStatus{Phase: coreV1.PodFailed},
+		},
+		&appV1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "shadow-app", Namespace: "default"},
+			Spec: appV1.DeploymentSpec{Template: coreV1.PodTemplateSpec{
+				Spec: coreV1.PodSpec{Volumes: sshVolume("shadow-app")},
+			}},
+		},
+	))
+	defer cluster.SetIns(nil)
+
+	sshConfigmap := func(name string, age time.Duration) coreV1.ConfigMap {
+		return coreV1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default",
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-age))},
+			Data: map[string]string{util.SshAuthKey: "key"},
+		}
+	}
+	cfs := []coreV1.ConfigMap{
+		sshConfigmap("shadow-pod", time.Hour),
+		sshConfigmap("ephemeral-target", time.Hour),
+		sshConfigmap("shadow-app", time.Hour),
+		sshConfigmap("failed-pod", time.Hour),
+		sshConfigmap("crashed", time.Hour),
+		sshConfigmap("just-created", time.Second),
+		{ObjectMeta: metav1.ObjectMeta{Name: "not-ssh", Namespace: "default"}},
+	}
+	r := &ResourceToClean{ConfigMapsToDelete: []string{"crashed"}}
+	analysisOrphanSshConfigmaps(cfs, r)
+	require.Equal(t, []string{"crashed", "failed-pod"}, r.ConfigMapsToDelete)
+}
+
+func TestCheckClusterResources_httpRoutes(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	opt.Get().Clean.ThresholdInMinus = 15
+	k := fake.NewKubernetes()
+	k.GatewayApiInstalled = true
+	cluster.SetIns(k)
+	defer cluster.SetIns(nil)
+	expired := strconv.FormatInt(util.GetTime()-3600, 10)
+	ktLabels := map[string]string{util.ControlBy: util.KubernetesToolkit}
+	for _, route := range []cluster.HTTPRoute{
+		{Metadata: cluster.HTTPRouteMeta{Name: "web-app-kt-gateway-abcde", Namespace: "default", Labels: ktLabels,
+			Annotations: map[string]string{util.KtLastHeartBeat: expired}}},
+		{Metadata: cluster.HTTPRouteMeta{Name: "web-app-kt-gateway-fghij", Namespace: "default", Labels: ktLabels,
+			Annotations: map[string]string{util.KtLastHeartBeat: util.GetTimestamp()}}},
+		{Metadata: cluster.HTTPRouteMeta{Name: "web", Namespace: "default",
+			Annotations: map[string]string{util.KtLastHeartBeat: expired}}},
+	} {
+		require.Nil(t, k.CreateHttpRoute(&route))
+	}
+
+	r, err := CheckClusterResources()
+	require.Nil(t, err)
+	require.Equal(t, []string{"web-app-kt-gateway-abcde"}, r.HttpRoutesToDelete,
+		"only expired http routes created by kt should be deleted")
+}
+
+func TestCheckClusterResources_daemonSets(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	opt.Get().Clean.ThresholdInMinus = 15
+	expired := strconv.FormatInt(util.GetTime()-3600, 10)
+	ktLabels := map[string]string{util.ControlBy: util.KubernetesToolkit}
+	cluster.SetIns(fake.NewKubernetes(
+		&appV1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "kt-preheat-linux-abcde", Namespace: "default",
+			Labels: ktLabels, Annotations: map[string]string{util.KtLastHeartBeat: expired}}},
+		&appV1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "kt-preheat-linux-fghij", Namespace: "default",
+			Labels: ktLabels, Annotations: map[string]string{util.KtLastHeartBeat: util.GetTimestamp()}}},
+		&appV1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default",
+			Annotations: map[string]string{util.KtLastHeartBeat: expired}}},
+	))
+	defer cluster.SetIns(nil)
+
+	r, err := CheckClusterResources()
+	require.Nil(t, err)
+	require.Equal(t, []string{"kt-preheat-linux-abcde"}, r.DaemonSetsToDelete,
+		"only expired daemon sets created by kt should be deleted")
+	TidyClusterResources(r)
+	_, err = cluster.Ins().GetDaemonSet("kt-preheat-linux-abcde", "default")
+	require.NotNil(t, err)
+	_, err = cluster.Ins().GetDaemonSet("kt-preheat-linux-fghij", "default")
+	require.Nil(t, err)
+}
+
+func TestCheckClusterResources_ingresses(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	opt.Get().Clean.ThresholdInMinus = 15
+	expired := strconv.FormatInt(util.GetTime()-3600, 10)
+	ktLabels := map[string]string{util.ControlBy: util.KubernetesToolkit}
+	cluster.SetIns(fake.NewKubernetes(
+		&netV1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "app-kt-ingress-abcde", Namespace: "default",
+			Labels: ktLabels, Annotations: map[string]string{util.KtLastHeartBeat: expired}}},
+		&netV1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "app-kt-ingress-fghij", Namespace: "default",
+			Labels: ktLabels, Annotations: map[string]string{util.KtLastHeartBeat: util.GetTimestamp()}}},
+		&netV1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default",
+			Annotations: map[string]string{util.KtLastHeartBeat: expired}}},
+	))
+	defer cluster.SetIns(nil)
+
+	r, err := CheckClusterResources()
+	require.Nil(t, err)
+	require.Equal(t, []string{"app-kt-ingress-abcde"}, r.IngressesToDelete,
+		"only expired ingresses created by kt should be deleted")
+	TidyClusterResources(r)
+	ingresses, err := cluster.Ins().GetIngressesByLabel(ktLabels, "default")
+	require.Nil(t, err)
+	require.Len(t, ingresses.Items, 1)
+	require.Equal(t, "app-kt-ingress-fghij", ingresses.Items[0].Name)
+}
@@ -0,0 +1,156 @@
+package clean
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/command/general"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strconv"
+)
+
+// OriginToRestore origin resource referenced by an orphaned exchange shadow
+type OriginToRestore struct {
+	Namespace    string
+	Shadow       string
+	IsDeployment bool
+	App          string
+	Replicas     int32
+	Service      string
+}
+
+// RestoreOrigins restore origins of orphaned exchange shadows, in all namespaces if no namespace specified
+func RestoreOrigins(allNamespaces bool) error {
+	namespaces := []string{opt.Get().Global.Namespace}
+	if allNamespaces {
+		nsList, err := cluster.Ins().GetAllNamespaces()
+		if err != nil {
+			return fmt.Errorf("failed to list namespaces, please specify one via '--namespace': %s", err)
+		}
+		namespaces = make([]string, 0)
+		for _, ns := range nsList.Items {
+			namespaces = append(namespaces, ns.Name)
+		}
+	}
+	origins := make([]OriginToRestore, 0)
+	for _, ns := range namespaces {
+		found, err := findOriginsToRestore(ns)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to check exchange shadows in namespace %s", ns)
+			continue
+		}
+		origins = append(origins, found...)
+	}
+	if len(origins) == 0 {
+		log.Info().Msg("No orphaned origin found (^.^)YYa!!")
+		return nil
+	}
+	if opt.Get().Clean.DryRun {
+		log.Info().Msgf("Find %d orphaned origins to restore:", len(origins))
+		for _, o := range origins {
+			log.Info().Msgf(" * %s", describeOrigin(o))
+		}
+		return nil
+	}
+	log.Info().Msgf("Restoring %d orphaned origins", len(origins))
+	for _, o := range origins {
+		restoreOrigin(o)
+	}
+	log.Info().Msg("Done")
+	return nil
+}
+
+func findOriginsToRestore(namespace string) ([]OriginToRestore, error) {
+	labels := map[string]string{util.ControlBy: util.KubernetesToolkit, util.KtRole: util.RoleExchangeShadow}
+	metas := make([]metav1.ObjectMeta, 0)
+	apps, err := cluster.Ins().GetDeploymentsByLabel(labels, namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, app := range apps.Items {
+		metas = append(metas, app.ObjectMeta)
+	}
+	pods, err := cluster.Ins().GetPodsByLabel(labels, namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		// pods of shadow deployment are handled along with the deployment
+		if len(pod.OwnerReferences) == 0 {
+			metas = append(metas, pod.ObjectMeta)
+		}
+	}
+	origins := make([]OriginToRestore, 0)
+	for i, meta := range metas {
+		if o := parseOriginToRestore(meta, i < len(apps.Items), opt.Get().Clean.ThresholdInMinus); o != nil {
+			origins = append(origins, *o)
+		}
+	}
+	return origins, nil
+}
+
+// parseOriginToRestore get origin from config annotation of shadow, return nil if shadow is still alive
+func parseOriginToRestore(meta metav1.ObjectMeta, isDeployment bool, cleanThresholdInMinus int64) *OriginToRestore {
+	config, exists := meta.Annotations[util.KtConfig]
+	if !exists || meta.DeletionTimestamp != nil {
+		return nil
+	}
+	lastHeartBeat := util.ParseTimestamp(meta.Annotations[util.KtLastHeartBeat])
+	if lastHeartBeat >= 0 && !isExpired(lastHeartBeat, cleanThresholdInMinus) {
+		log.Debug().Msgf("Shadow %s in namespace %s is still alive", meta.Name, meta.Namespace)
+		return nil
+	}
+	configMap := util.String2Map(config)
+	replicas, _ := strconv.ParseInt(configMap["replicas"], 10, 32)
+	o := &OriginToRestore{
+		Namespace:    meta.Namespace,
+		Shadow:       meta.Name,
+		IsDeployment: isDeployment,
+		Service:      configMap["service"],
+	}
+	if replicas > 0 && configMap["app"] != "" {
+		o.App = configMap["app"]
+		o.Replicas = int32(replicas)
+	}
+	if o.App == "" && o.Service == "" {
+		log.Debug().Msgf("Shadow %s does not reference any origin", meta.Name)
+		return nil
+	}
+	return o
+}
+
+func restoreOrigin(o OriginToRestore) {
+	if o.App != "" {
+		if err := cluster.Ins().ScaleTo(o.App, o.Namespace, &o.Replicas); err != nil {
+			log.Warn().Err(err).Msgf("Failed to scale deployment %s to %d", o.App, o.Replicas)
+			return
+		}
+	}
+	if o.Service != "" {
+		if err := general.RecoverOriginalService(o.Service, o.Namespace); err != nil {
+			return
+		}
+	}
+	var err error
+	if o.IsDeployment {
+		err = cluster.Ins().RemoveDeployment(o.Shadow, o.Namespace)
+	} else {
+		err = cluster.Ins().RemovePod(o.Shadow, o.Namespace)
+	}
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to delete shadow %s", o.Shadow)
+	}
+	if err = cluster.Ins().RemoveConfigMap(o.Shadow, o.Namespace); err != nil {
+		log.Debug().Msgf("Config map of shadow %s not removed: %s", o.Shadow, err)
+	}
+	log.Info().Msgf(" * %s", describeOrigin(o))
+}
+
+func describeOrigin(o OriginToRestore) string {
+	if o.App != "" {
+		return fmt.Sprintf("%s/deployment/%s -> %d (shadow %s)", o.Namespace, o.App, o.Replicas, o.Shadow)
+	}
+	return fmt.Sprintf("%s/service/%s (shadow %s)", o.Namespace, o.Service, o.Shadow)
+}
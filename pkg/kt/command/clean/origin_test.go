@@ -0,0 +1,30 @@
+package clean
+
+import (
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func Test_parseOriginToRestore(t *testing.T) {
+	expired := "1"
+	alive := util.GetTimestamp()
+	meta := metav1.ObjectMeta{Name: "app-kt-exchange-abcde", Namespace: "ns", Annotations: map[string]string{
+		util.KtConfig: "app=app,replicas=2", util.KtLastHeartBeat: expired}}
+	require.Equal(t, &OriginToRestore{Namespace: "ns", Shadow: "app-kt-exchange-abcde", App: "app", Replicas: 2},
+		parseOriginToRestore(meta, false, 11))
+
+	meta.Annotations[util.KtConfig] = "service=svc"
+	require.Equal(t, &OriginToRestore{Namespace: "ns", Shadow: "app-kt-exchange-abcde", IsDeployment: true, Service: "svc"},
+		parseOriginToRestore(meta, true, 11))
+
+	meta.Annotations[util.KtLastHeartBeat] = alive
+	require.Nil(t, parseOriginToRestore(meta, false, 11), "alive shadow should be skipped")
+
+	meta.Annotations = map[string]string{util.KtConfig: "app=app,replicas=0", util.KtLastHeartBeat: expired}
+	require.Nil(t, parseOriginToRestore(meta, false, 11), "shadow without origin should be skipped")
+
+	meta.Annotations = map[string]string{util.KtLastHeartBeat: expired}
+	require.Nil(t, parseOriginToRestore(meta, false, 11), "shadow without config should be skipped")
+}
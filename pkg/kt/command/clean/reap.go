@@ -0,0 +1,74 @@
+package clean
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReapExpiredShadows remove shadows in current namespace which passed expiry time and whose heartbeat stopped,
+// origins of exchange shadows are restored, failures are only logged
+func ReapExpiredShadows() {
+	namespace := opt.Get().Global.Namespace
+	labels := map[string]string{util.ControlBy: util.KubernetesToolkit}
+	apps, err := cluster.Ins().GetDeploymentsByLabel(labels, namespace)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to check expired shadow deployments")
+		return
+	}
+	pods, err := cluster.Ins().GetPodsByLabel(labels, namespace)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to check expired shadow pods")
+		return
+	}
+	for _, app := range apps.Items {
+		reapIfExpired(app.ObjectMeta, true)
+	}
+	for _, pod := range pods.Items {
+		// pods of shadow deployment are handled along with the deployment
+		if len(pod.OwnerReferences) == 0 && pod.DeletionTimestamp == nil {
+			reapIfExpired(pod.ObjectMeta, false)
+		}
+	}
+}
+
+func reapIfExpired(meta metav1.ObjectMeta, isDeployment bool) {
+	if !isShadowExpired(meta, opt.Get().Clean.ThresholdInMinus) {
+		return
+	}
+	log.Info().Msgf("Reaping expired shadow %s", meta.Name)
+	if meta.Labels[util.KtRole] == util.RoleExchangeShadow {
+		if o := parseOriginToRestore(meta, isDeployment, opt.Get().Clean.ThresholdInMinus); o != nil {
+			restoreOrigin(*o)
+			return
+		}
+	}
+	var err error
+	if isDeployment {
+		err = cluster.Ins().RemoveDeployment(meta.Name, meta.Namespace)
+	} else {
+		err = cluster.Ins().RemovePod(meta.Name, meta.Namespace)
+	}
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to delete shadow %s", meta.Name)
+	}
+	if err = cluster.Ins().RemoveConfigMap(meta.Name, meta.Namespace); err != nil {
+		log.Debug().Msgf("Config map of shadow %s not removed: %s", meta.Name, err)
+	}
+}
+
+// isShadowExpired shadow without expiry annotation never expires, shadow with live heartbeat is considered in use
+func isShadowExpired(meta metav1.ObjectMeta, cleanThresholdInMinus int64) bool {
+	expireAt := util.ParseTimestamp(meta.Annotations[util.KtExpireAt])
+	if expireAt < 0 || util.GetTime() < expireAt {
+		return false
+	}
+	lastHeartBeat := util.ParseTimestamp(meta.Annotations[util.KtLastHeartBeat])
+	if lastHeartBeat >= 0 && !isExpired(lastHeartBeat, cleanThresholdInMinus) {
+		log.Debug().Msgf("Shadow %s passed expiry time but is still alive", meta.Name)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package clean
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster/fake"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	appV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strconv"
+	"testing"
+)
+
+func TestReapExpiredShadows(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	opt.Get().Clean.ThresholdInMinus = 5
+	past := strconv.FormatInt(util.GetTime()-60, 10)
+	future := strconv.FormatInt(util.GetTime()+600, 10)
+	shadowLabels := map[string]string{util.ControlBy: util.KubernetesToolkit, util.KtRole: util.RoleExchangeShadow}
+	replicas := int32(0)
+	cluster.SetIns(fake.NewKubernetes(
+		&appV1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			Spec: appV1.DeploymentSpec{Replicas: &replicas}},
+		&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-kt-exchange-abcde", Namespace: "default", Labels: shadowLabels,
+			Annotations: map[string]string{util.KtConfig: "app=app,replicas=2", util.KtExpireAt: past, util.KtLastHeartBeat: "1"}}},
+		&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "live-kt-exchange-abcde", Namespace: "default", Labels: shadowLabels,
+			Annotations: map[string]string{util.KtExpireAt: past, util.KtLastHeartBeat: util.GetTimestamp()}}},
+		&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "new-kt-exchange-abcde", Namespace: "default", Labels: shadowLabels,
+			Annotations: map[string]string{util.KtExpireAt: future, util.KtLastHeartBeat: "1"}}},
+		&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "old-kt-exchange-abcde", Namespace: "default", Labels: shadowLabels,
+			Annotations: map[string]string{util.KtLastHeartBeat: "1"}}},
+	))
+	defer cluster.SetIns(nil)
+
+	ReapExpiredShadows()
+	_, err := cluster.Ins().GetPod("app-kt-exchange-abcde", "default")
+	require.NotNil(t, err, "expired shadow should be removed")
+	app, err := cluster.Ins().GetDeployment("app", "default")
+	require.Nil(t, err)
+	require.Equal(t, int32(2), *app.Spec.Replicas, "origin of expired shadow should be restored")
+	for _, name := range []string{"live-kt-exchange-abcde", "new-kt-exchange-abcde", "old-kt-exchange-abcde"} {
+		_, err = cluster.Ins().GetPod(name, "default")
+		require.Nil(t, err, "shadow %s should be kept", name)
+	}
+}
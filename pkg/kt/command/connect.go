@@ -7,6 +7,7 @@ import (
 	"github.com/alibaba/kt-connect/pkg/kt/command/general"
 	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
 	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/dns"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -16,16 +17,16 @@ import (
 // NewConnectCommand return new connect command
 func NewConnectCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:  "connect",
+		Use:   "connect",
 		Short: "Create a network tunnel to kubernetes cluster",
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 0 {
-				return fmt.Errorf("too many options specified (%s)", strings.Join(args, ",") )
+				return fmt.Errorf("too many options specified (%s)", strings.Join(args, ","))
 			}
 			if err := preCheck(); err != nil {
 				return err
 			}
-			return general.Prepare()
+			return prepareAndReap(cmd)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return Connect()
@@ -40,6 +41,11 @@ func NewConnectCommand() *cobra.Command {
 
 // Connect setup vpn to kubernetes cluster
 func Connect() error {
+	probeTargets, err := connect.ParseProbeTargets(opt.Get().Connect.Probe)
+	if err != nil {
+		return err
+	}
+
 	ch, err := general.SetupProcess(util.ComponentConnect)
 	if err != nil {
 		return err
@@ -50,6 +56,12 @@ func Connect() error {
 	}
 
 	log.Info().Msgf("Using %s mode", opt.Get().Connect.Mode)
+	if opt.Get().Connect.Compression {
+		log.Info().Msgf("Ssh compression enabled")
+	}
+	if opt.Get().Connect.Exec != "" && !util.IsLinux() {
+		log.Warn().Msgf("Per-process routing is only supported on linux, traffic of whole system will be routed to cluster")
+	}
 	if opt.Get().Connect.Mode == util.ConnectModeTun2Socks {
 		err = connect.ByTun2Socks()
 	} else if opt.Get().Connect.Mode == util.ConnectModeShuttle {
@@ -61,9 +73,24 @@ func Connect() error {
 	if err != nil {
 		return err
 	}
-	log.Info().Msg("---------------------------------------------------------------")
-	log.Info().Msgf(" All looks good, now you can access to resources in the kubernetes cluster")
-	log.Info().Msg("---------------------------------------------------------------")
+	if len(probeTargets) > 0 {
+		if err = connect.Probe(probeTargets); err != nil {
+			return err
+		}
+	}
+	util.StatusLog().Msg("---------------------------------------------------------------")
+	if opt.Get().Connect.Exec != "" && util.IsLinux() {
+		util.StatusLog().Msgf(" All looks good, only command '%s' can access to resources in the kubernetes cluster",
+			opt.Get().Connect.Exec)
+	} else {
+		util.StatusLog().Msgf(" All looks good, now you can access to resources in the kubernetes cluster")
+	}
+	util.StatusLog().Msg("---------------------------------------------------------------")
+	if opt.Get().Connect.Exec != "" {
+		if err = connect.RunExec(opt.Get().Connect.Exec, ch); err != nil {
+			return err
+		}
+	}
 
 	// watch background process, clean the workspace and exit if background process occur exception
 	s := <-ch
@@ -108,5 +135,70 @@ func checkPermissionAndOptions() error {
 	if opt.Get().Connect.Mode == util.ConnectModeTun2Socks && opt.Get().Connect.DnsMode == util.DnsModePodDns {
 		return fmt.Errorf("dns mode '%s' is not available for connect mode '%s'", util.DnsModePodDns, util.ConnectModeTun2Socks)
 	}
+	if opt.Get().Connect.Mtu < util.MinTunMtu || opt.Get().Connect.Mtu > util.MaxTunMtu {
+		return fmt.Errorf("mtu %d is out of range, should be between %d and %d",
+			opt.Get().Connect.Mtu, util.MinTunMtu, util.MaxTunMtu)
+	}
+	if _, err := dns.ParseServiceMapping(opt.Get().Connect.MapService); err != nil {
+		return err
+	}
+	if opt.Get().Connect.MapService != "" && opt.Get().Connect.DnsMode == util.DnsModePodDns {
+		return fmt.Errorf("'--mapService' is not available for dns mode '%s'", util.DnsModePodDns)
+	}
+	if opt.Get().Connect.DnsTtl < 0 {
+		return fmt.Errorf("dns ttl should not be negative")
+	}
+	if opt.Get().Connect.Gateway {
+		if opt.Get().Connect.ShareShadow || opt.Get().Connect.Replicas > 1 {
+			return fmt.Errorf("'--gateway' cannot be used together with '--shareShadow' or '--replicas'")
+		} else if opt.Get().Connect.GatewayTokenTtl <= 0 {
+			return fmt.Errorf("gateway token ttl should be a positive number")
+		}
+	}
+	if opt.Get().Connect.Replicas < 1 {
+		return fmt.Errorf("replicas should be at least 1")
+	}
+	if opt.Get().Connect.Replicas > 1 {
+		if opt.Get().Connect.ShareShadow {
+			return fmt.Errorf("'--replicas' is not available when '--shareShadow' is used")
+		}
+		if opt.Get().Connect.DnsMode == util.DnsModePodDns {
+			return fmt.Errorf("'--replicas' is not available for dns mode '%s'", util.DnsModePodDns)
+		}
+		// multiple shadow pods are managed by deployment
+		opt.Get().Global.UseShadowDeployment = true
+	}
+	if opt.Get().Connect.Compression && opt.Get().Connect.Mode != util.ConnectModeShuttle {
+		return fmt.Errorf("'--compression' is only supported in %s mode", util.ConnectModeShuttle)
+	}
+	if opt.Get().Connect.TcpOnly {
+		if opt.Get().Connect.Mode != util.ConnectModeTun2Socks {
+			return fmt.Errorf("'--tcpOnly' is only supported in %s mode", util.ConnectModeTun2Socks)
+		}
+		if !util.IsLinux() {
+			return fmt.Errorf("'--tcpOnly' is only supported on linux")
+		}
+		if opt.Get().Connect.DisableTunDevice || opt.Get().Connect.DisableTunRoute {
+			return fmt.Errorf("'--tcpOnly' cannot be used together with '--disableTunDevice' or '--disableTunRoute'")
+		}
+		if opt.Get().Connect.DnsMode == util.DnsModePodDns {
+			return fmt.Errorf("'--tcpOnly' is not available for dns mode '%s', which query dns via udp", util.DnsModePodDns)
+		}
+	}
+	if opt.Get().Connect.Exec != "" {
+		if opt.Get().Connect.Mode != util.ConnectModeTun2Socks {
+			return fmt.Errorf("'--exec' is only supported in %s mode", util.ConnectModeTun2Socks)
+		}
+		if opt.Get().Connect.DisableTunDevice || opt.Get().Connect.DisableTunRoute {
+			return fmt.Errorf("'--exec' cannot be used together with '--disableTunDevice' or '--disableTunRoute'")
+		}
+		if opt.Get().Connect.TcpOnly {
+			return fmt.Errorf("'--exec' cannot be used together with '--tcpOnly'")
+		}
+		if opt.Get().Connect.DnsMode == util.DnsModePodDns && util.IsLinux() {
+			return fmt.Errorf("'--exec' is not available for dns mode '%s', which requires dns server in cluster "+
+				"being reachable from whole system", util.DnsModePodDns)
+		}
+	}
 	return nil
 }
@@ -9,17 +9,21 @@ import (
 	"github.com/alibaba/kt-connect/pkg/kt/transmission"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
+	appV1 "k8s.io/api/apps/v1"
 	coreV1 "k8s.io/api/core/v1"
 	"strings"
 	"time"
 )
 
 func setupDns(shadowPodName, shadowPodIp string) error {
+	if opt.Get().Connect.ClusterDomain == "" {
+		opt.Get().Connect.ClusterDomain = detectClusterDomain(shadowPodName)
+	}
 	if strings.HasPrefix(opt.Get().Connect.DnsMode, util.DnsModeHosts) {
 		log.Info().Msgf("Setting up dns in hosts mode")
 		dump2HostsNamespaces := ""
 		pos := len(util.DnsModeHosts)
-		if len(opt.Get().Connect.DnsMode) > pos + 1 && opt.Get().Connect.DnsMode[pos:pos+1] == ":" {
+		if len(opt.Get().Connect.DnsMode) > pos+1 && opt.Get().Connect.DnsMode[pos:pos+1] == ":" {
 			dump2HostsNamespaces = opt.Get().Connect.DnsMode[pos+1:]
 		}
 		if err := dumpToHost(dump2HostsNamespaces); err != nil {
@@ -37,7 +41,7 @@ func setupDns(shadowPodName, shadowPodIp string) error {
 		watchServicesAndPods(opt.Get().Global.Namespace, svcToIp, headlessPods, true)
 
 		forwardedPodPort := util.GetRandomTcpPort()
-		if _, err := transmission.SetupPortForwardToLocal(shadowPodName, common.StandardDnsPort, forwardedPodPort); err != nil {
+		if err := forwardShadowPortToLocal(shadowPodName, common.StandardDnsPort, forwardedPodPort); err != nil {
 			return err
 		}
 
@@ -61,9 +65,40 @@ func setupDns(shadowPodName, shadowPodIp string) error {
 	return nil
 }
 
+// detectClusterDomain read cluster domain from dns search domains of shadow pod
+func detectClusterDomain(shadowPodName string) string {
+	stdout, stderr, err := cluster.Ins().ExecInPod(util.DefaultContainer, shadowPodName, opt.Get().Global.Namespace,
+		"cat", "/etc/resolv.conf")
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to read resolv.conf of shadow pod: %s", stderr)
+	} else if domain := parseClusterDomain(stdout); domain != "" {
+		log.Info().Msgf("Using cluster domain %s", domain)
+		return domain
+	}
+	log.Warn().Msgf("Cannot detect cluster domain, using default %s, specify it via '--clusterDomain' if incorrect",
+		util.DefaultClusterDomain)
+	return util.DefaultClusterDomain
+}
+
+// parseClusterDomain find cluster domain from 'svc.<cluster-domain>' search domain of pod resolv.conf
+func parseClusterDomain(resolvConf string) string {
+	for _, line := range strings.Split(resolvConf, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "search" {
+			continue
+		}
+		for _, domain := range fields[1:] {
+			if strings.HasPrefix(domain, "svc.") {
+				return strings.TrimSuffix(domain[len("svc."):], ".")
+			}
+		}
+	}
+	return ""
+}
+
 func getDnsOrder(dnsMode string) []string {
-	if ! strings.Contains(dnsMode, ":") {
-		return []string{ util.DnsOrderCluster, util.DnsOrderUpstream }
+	if !strings.Contains(dnsMode, ":") {
+		return []string{util.DnsOrderCluster, util.DnsOrderUpstream}
 	}
 	return strings.Split(strings.SplitN(dnsMode, ":", 2)[1], ",")
 }
@@ -73,7 +108,7 @@ func watchServicesAndPods(namespace string, svcToIp map[string]string, headlessP
 	go cluster.Ins().WatchService("", namespace,
 		func(svc *coreV1.Service) {
 			// ignore add service event during watch setup
-			if time.Now().Unix() - setupTime > 3 {
+			if time.Now().Unix()-setupTime > 3 {
 				svcToIp, headlessPods = getServiceHosts(namespace, shortDomainOnly)
 				_ = dns.DumpHosts(svcToIp, namespace)
 			}
@@ -146,17 +181,25 @@ func getServiceHosts(namespace string, shortDomainOnly bool) (map[string]string,
 			}
 		}
 	}
+	// services mapped via '--mapService' always resolve to mapped ip, even if not found in cluster
+	mappedServices, _ := dns.ParseServiceMapping(opt.Get().Connect.MapService)
+	for domain, ip := range dns.GetServiceMappingDomains(mappedServices, opt.Get().Global.Namespace, opt.Get().Connect.ClusterDomain) {
+		hosts[domain] = ip
+	}
 	return hosts, podNames
 }
 
 func getOrCreateShadow() (string, string, string, error) {
-	shadowPodName := fmt.Sprintf("kt-connect-shadow-%s", strings.ToLower(util.RandomString(5)))
+	if opt.Get().Connect.Gateway {
+		return useConnectGateway()
+	}
+	shadowPodName := util.RandomName("kt-connect-shadow-")
 	if opt.Get().Connect.ShareShadow {
 		shadowPodName = fmt.Sprintf("kt-connect-shadow-daemon")
 	}
 
 	endPointIP, podName, privateKeyPath, err := cluster.Ins().GetOrCreateShadow(shadowPodName, getLabels(),
-		make(map[string]string), getEnvs(), "", map[int]string{})
+		make(map[string]string), getEnvs(), "", map[int]string{}, nil)
 	if err != nil {
 		return "", "", "", err
 	}
@@ -164,6 +207,27 @@ func getOrCreateShadow() (string, string, string, error) {
 	return endPointIP, podName, privateKeyPath, nil
 }
 
+// forwardShadowPortToLocal port forward shadow pod to local, with failover to other shadow pods if multiple replicas used
+func forwardShadowPortToLocal(podName string, remotePort, localPort int) error {
+	if opt.Get().Connect.Replicas <= 1 {
+		_, err := transmission.SetupPortForwardToLocal(podName, remotePort, localPort)
+		return err
+	}
+	pod, err := cluster.Ins().GetPod(podName, opt.Get().Global.Namespace)
+	if err != nil {
+		return err
+	}
+	labels := make(map[string]string)
+	for k, v := range pod.Labels {
+		// replicas of new replica set should also be available for failover
+		if k != appV1.DefaultDeploymentUniqueLabelKey {
+			labels[k] = v
+		}
+	}
+	_, err = transmission.SetupPortForwardWithFailover(podName, labels, remotePort, localPort)
+	return err
+}
+
 func getEnvs() map[string]string {
 	envs := make(map[string]string)
 	localDomains := dns.GetLocalDomains()
@@ -186,7 +250,7 @@ func getEnvs() map[string]string {
 
 func getLabels() map[string]string {
 	labels := map[string]string{
-		util.KtRole:    util.RoleConnectShadow,
+		util.KtRole: util.RoleConnectShadow,
 	}
 	if opt.Get().Global.UseShadowDeployment {
 		labels[util.KtTarget] = util.RandomString(20)
@@ -0,0 +1,14 @@
+package connect
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_parseClusterDomain(t *testing.T) {
+	require.Equal(t, "cluster.internal", parseClusterDomain("nameserver 10.96.0.10\n"+
+		"search default.svc.cluster.internal svc.cluster.internal cluster.internal\noptions ndots:5\n"))
+	require.Equal(t, "cluster.local", parseClusterDomain("search dev.svc.cluster.local. svc.cluster.local.\n"))
+	require.Equal(t, "", parseClusterDomain("nameserver 10.96.0.10\nsearch example.com\n"))
+	require.Equal(t, "", parseClusterDomain(""))
+}
@@ -0,0 +1,42 @@
+package connect
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+)
+
+// RunExec start command specified by '--exec', and notify connect to exit when the command finished
+func RunExec(command string, ch chan os.Signal) error {
+	cmd := exec.Command("sh", "-c", command)
+	if util.IsWindows() {
+		cmd = exec.Command("cmd", "/C", command)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	release, err := scopeCommand(cmd)
+	if err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+	if err = release(cmd.Process.Pid); err != nil {
+		_ = cmd.Process.Kill()
+		return err
+	}
+	log.Info().Msgf("Command '%s' started with pid %d", command, cmd.Process.Pid)
+	go func() {
+		if err2 := cmd.Wait(); err2 != nil {
+			log.Warn().Msgf("Command '%s' exited: %s", command, err2)
+		} else {
+			log.Info().Msgf("Command '%s' finished", command)
+		}
+		ch <- syscall.SIGTERM
+	}()
+	return nil
+}
@@ -0,0 +1,53 @@
+package connect
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+
+	"github.com/alibaba/kt-connect/pkg/kt/service/tun"
+)
+
+// scopeCommand hold the command until it's moved into kt cgroup, so that none of its traffic escapes the scope,
+// the command is run as the user invoked sudo instead of root
+func scopeCommand(cmd *exec.Cmd) (func(int) error, error) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	// fd 3 is the first of extra files
+	cmd.Args[len(cmd.Args)-1] = "read _ <&3; exec 3<&-; " + cmd.Args[len(cmd.Args)-1]
+	cmd.ExtraFiles = []*os.File{reader}
+	if credential, err2 := sudoCredential(); err2 != nil {
+		return nil, err2
+	} else if credential != nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Credential: credential}
+	}
+	return func(pid int) error {
+		defer writer.Close()
+		_ = reader.Close()
+		if err2 := tun.JoinProcessScope(pid); err2 != nil {
+			return fmt.Errorf("failed to limit route to command: %s", err2)
+		}
+		_, err2 := writer.Write([]byte("\n"))
+		return err2
+	}, nil
+}
+
+func sudoCredential() (*syscall.Credential, error) {
+	uidText, gidText := os.Getenv("SUDO_UID"), os.Getenv("SUDO_GID")
+	if uidText == "" || gidText == "" {
+		return nil, nil
+	}
+	uid, err := strconv.ParseUint(uidText, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SUDO_UID '%s'", uidText)
+	}
+	gid, err := strconv.ParseUint(gidText, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SUDO_GID '%s'", gidText)
+	}
+	return &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}, nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package connect
+
+import (
+	"os/exec"
+)
+
+// scopeCommand per-process routing is not supported, traffic of the command is routed system-wide
+func scopeCommand(_ *exec.Cmd) (func(int) error, error) {
+	return func(int) error { return nil }, nil
+}
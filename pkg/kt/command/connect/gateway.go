@@ -0,0 +1,42 @@
+package connect
+
+import (
+	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"os"
+	"strings"
+	"time"
+)
+
+// useConnectGateway authorize a short-lived key on connect gateway pod, instead of creating a shadow pod
+func useConnectGateway() (string, string, string, error) {
+	namespace := opt.Get().Global.Namespace
+	podName, podIp, err := cluster.Ins().GetConnectGatewayPod(namespace)
+	if err != nil {
+		return "", "", "", err
+	}
+	generator, err := util.Generate(util.PrivateKeyPath(util.GatewayKeyName()))
+	if err != nil {
+		return "", "", "", err
+	}
+	publicKey := strings.TrimSpace(string(generator.PublicKey))
+	expireAt := time.Now().Add(time.Duration(opt.Get().Connect.GatewayTokenTtl) * time.Minute)
+	// sshd refuses the key after expiry time, connections already established are not affected
+	if _, stderr, err2 := cluster.Ins().ExecInPod(util.DefaultContainer, podName, namespace, "sh", "-c",
+		fmt.Sprintf("echo '%s' >> %s", gatewayKeyEntry(publicKey, expireAt), util.AuthorizedKeysFile)); err2 != nil {
+		_ = os.Remove(generator.PrivateKeyPath)
+		return "", "", "", fmt.Errorf("failed to authorize access key on connect gateway %s: %s %s", podName, err2, stderr)
+	}
+	opt.Store.Gateway = podName
+	opt.Store.GatewayKey = publicKey
+	log.Info().Msgf("Connecting through gateway pod %s, access key expires at %s", podName, expireAt.Format(time.RFC3339))
+	return podIp, podName, generator.PrivateKeyPath, nil
+}
+
+// gatewayKeyEntry line of authorized_keys file with expiry time option
+func gatewayKeyEntry(publicKey string, expireAt time.Time) string {
+	return fmt.Sprintf("expiry-time=\"%sZ\" %s", expireAt.UTC().Format("20060102150405"), publicKey)
+}
@@ -0,0 +1,53 @@
+package connect
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster/fake"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_useConnectGateway(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	opt.Get().Connect.GatewayTokenTtl = 60
+	k := fake.NewKubernetes(&coreV1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "kt-connect-gateway-abc", Namespace: "default",
+			Labels: map[string]string{util.KtRole: util.RoleConnectGateway}},
+		Status: coreV1.PodStatus{Phase: coreV1.PodRunning, PodIP: "10.0.0.8"},
+	})
+	var script string
+	k.ExecHandler = func(containerName, podName, namespace string, cmd ...string) (string, string, error) {
+		script = cmd[len(cmd)-1]
+		return "", "", nil
+	}
+	cluster.SetIns(k)
+	defer cluster.SetIns(nil)
+	defer func() {
+		opt.Store.Gateway = ""
+		opt.Store.GatewayKey = ""
+	}()
+
+	podIp, podName, keyPath, err := useConnectGateway()
+	require.Nil(t, err)
+	defer os.Remove(keyPath)
+	require.Equal(t, util.PrivateKeyPath(util.GatewayKeyName()), keyPath, "each process should use its own key")
+	require.Equal(t, "10.0.0.8", podIp)
+	require.Equal(t, "kt-connect-gateway-abc", podName)
+	require.Equal(t, podName, opt.Store.Gateway)
+	require.True(t, strings.HasPrefix(opt.Store.GatewayKey, "ssh-rsa "))
+	require.Contains(t, script, "expiry-time=")
+	require.Contains(t, script, opt.Store.GatewayKey)
+	require.Contains(t, script, util.AuthorizedKeysFile)
+}
+
+func Test_gatewayKeyEntry(t *testing.T) {
+	expireAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.FixedZone("UTC+8", 8*3600))
+	require.Equal(t, "expiry-time=\"20300101190405Z\" ssh-rsa AAAA", gatewayKeyEntry("ssh-rsa AAAA", expireAt))
+}
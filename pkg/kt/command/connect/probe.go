@@ -0,0 +1,71 @@
+package connect
+
+import (
+	"context"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const probeTimeout = 5 * time.Second
+
+// ParseProbeTargets parse comma separated '<host>:<port>' targets
+func ParseProbeTargets(probe string) ([]string, error) {
+	targets := make([]string, 0)
+	if probe == "" {
+		return targets, nil
+	}
+	for _, target := range strings.Split(probe, ",") {
+		host, port, err := net.SplitHostPort(target)
+		if err != nil || host == "" {
+			return nil, fmt.Errorf("invalid probe target '%s', should be in '<host>:<port>' format", target)
+		}
+		if p, err2 := strconv.Atoi(port); err2 != nil || p <= 0 || p > 65535 {
+			return nil, fmt.Errorf("invalid port of probe target '%s'", target)
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// Probe check each target is reachable, return error if any of them failed
+func Probe(targets []string) error {
+	failed := 0
+	for _, target := range targets {
+		if err := probeTarget(target); err != nil {
+			log.Error().Msgf("Probe %s failed: %s", target, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d probe targets are unreachable", failed, len(targets))
+	}
+	return nil
+}
+
+func probeTarget(target string) error {
+	host, port, _ := net.SplitHostPort(target)
+	start := time.Now()
+	ips := []string{host}
+	if net.ParseIP(host) == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+		defer cancel()
+		var err error
+		if ips, err = net.DefaultResolver.LookupHost(ctx, host); err != nil || len(ips) == 0 {
+			return fmt.Errorf("dns lookup of %s failed: %v", host, err)
+		}
+		log.Debug().Msgf("Probe %s resolved to %v in %d ms", host, ips, time.Since(start).Milliseconds())
+	}
+	dialStart := time.Now()
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(ips[0], port), probeTimeout)
+	if err != nil {
+		return fmt.Errorf("unable to connect %s:%s, %s", ips[0], port, err)
+	}
+	_ = conn.Close()
+	log.Info().Msgf("Probe %s succeed, dns %d ms, connect %d ms", target,
+		dialStart.Sub(start).Milliseconds(), time.Since(dialStart).Milliseconds())
+	return nil
+}
@@ -0,0 +1,35 @@
+package connect
+
+import (
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+)
+
+func TestParseProbeTargets(t *testing.T) {
+	targets, err := ParseProbeTargets("svc-a:80,svc-b.ns:8080,[::1]:22")
+	require.Nil(t, err)
+	require.Equal(t, []string{"svc-a:80", "svc-b.ns:8080", "[::1]:22"}, targets)
+	targets, err = ParseProbeTargets("")
+	require.Nil(t, err)
+	require.Empty(t, targets)
+	_, err = ParseProbeTargets("svc-a")
+	require.NotNil(t, err, "target without port should fail")
+	_, err = ParseProbeTargets(":80")
+	require.NotNil(t, err, "target without host should fail")
+	_, err = ParseProbeTargets("svc-a:http")
+	require.NotNil(t, err, "target with invalid port should fail")
+}
+
+func TestProbe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer listener.Close()
+	require.Nil(t, Probe([]string{listener.Addr().String()}))
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	address := closed.Addr().String()
+	_ = closed.Close()
+	require.NotNil(t, Probe([]string{listener.Addr().String(), address}))
+}
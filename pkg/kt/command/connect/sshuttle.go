@@ -5,7 +5,6 @@ import (
 	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
 	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
 	"github.com/alibaba/kt-connect/pkg/kt/service/sshuttle"
-	"github.com/alibaba/kt-connect/pkg/kt/transmission"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
 	"time"
@@ -22,7 +21,7 @@ func BySshuttle() error {
 	cidr, excludeCidr := cluster.Ins().ClusterCidr(opt.Get().Global.Namespace)
 
 	localSshPort := util.GetRandomTcpPort()
-	if _, err = transmission.SetupPortForwardToLocal(podName, common.StandardSshPort, localSshPort); err != nil {
+	if err = forwardShadowPortToLocal(podName, common.StandardSshPort, localSshPort); err != nil {
 		return err
 	}
 
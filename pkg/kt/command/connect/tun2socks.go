@@ -7,7 +7,6 @@ import (
 	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
 	"github.com/alibaba/kt-connect/pkg/kt/service/sshchannel"
 	"github.com/alibaba/kt-connect/pkg/kt/service/tun"
-	"github.com/alibaba/kt-connect/pkg/kt/transmission"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/net/proxy"
@@ -26,7 +25,7 @@ func ByTun2Socks() error {
 
 	localSshPort := util.GetRandomTcpPort()
 	socksAddr := fmt.Sprintf("socks5://%s:%d", opt.Get().Connect.ProxyAddr, opt.Get().Connect.ProxyPort)
-	if _, err = transmission.SetupPortForwardToLocal(podName, common.StandardSshPort, localSshPort); err != nil {
+	if err = forwardShadowPortToLocal(podName, common.StandardSshPort, localSshPort); err != nil {
 		return err
 	}
 	if err = startSocks5Connection(podIP, privateKeyPath, localSshPort, true); err != nil {
@@ -93,7 +92,7 @@ func startSocks5Connection(podIP, privateKey string, localSshPort int, isInitCon
 		// will hang here if not error happen
 		err := sshchannel.Ins().StartSocks5Proxy(privateKey, sshAddress, socks5Address)
 		if !gone {
-			res <-err
+			res <- err
 		}
 		log.Debug().Err(err).Msgf("Socks proxy interrupted")
 		if ticker != nil {
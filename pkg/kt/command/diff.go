@@ -0,0 +1,32 @@
+package command
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/command/exchange"
+	"github.com/alibaba/kt-connect/pkg/kt/command/general"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+// NewDiffCommand return new diff command
+func NewDiffCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Compare origin deployment and its services against running exchange shadow",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("name of resource to diff is required")
+			} else if len(args) > 1 {
+				return fmt.Errorf("too many resource names are specified (%s), should be one", strings.Join(args, ","))
+			}
+			return general.Prepare(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return exchange.Diff(args[0])
+		},
+		Example: "ktctl diff <resource-name> [command options]",
+	}
+
+	cmd.SetUsageTemplate(general.UsageTemplate(true))
+	return cmd
+}
@@ -5,29 +5,66 @@ import (
 	"github.com/alibaba/kt-connect/pkg/kt/command/exchange"
 	"github.com/alibaba/kt-connect/pkg/kt/command/general"
 	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/sshchannel"
+	"github.com/alibaba/kt-connect/pkg/kt/transmission"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"strings"
+	"time"
 )
 
 // NewExchangeCommand return new exchange command
 func NewExchangeCommand() *cobra.Command {
+	var targetsInFile []string
 	cmd := &cobra.Command{
-		Use:  "exchange",
+		Use:   "exchange",
 		Short: "Redirect all requests of specified kubernetes service to local",
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) == 0 {
-				return fmt.Errorf("name of service to exchange is required")
-			} else if len(args) > 1 {
-				return fmt.Errorf("too many service names are spcified (%s), should be one", strings.Join(args, ",") )
+			if opt.Get().Exchange.File != "" {
+				var err error
+				if targetsInFile, err = exchange.LoadFile(opt.Get().Exchange.File, cmd.Flags()); err != nil {
+					return err
+				}
 			}
-			return general.Prepare()
+			if err := exchange.CheckTrafficScope(len(args)+len(targetsInFile), exchange.GetConnectPid()); err != nil {
+				return err
+			}
+			if opt.Get().Exchange.OutboundOnly {
+				if err := preCheck(); err != nil {
+					return err
+				}
+				return prepareAndReap(cmd)
+			}
+			if len(args) == 0 && len(targetsInFile) == 0 {
+				if !opt.Get().Exchange.AutoResolve {
+					return fmt.Errorf("name of service to exchange is required")
+				} else if opt.Get().Exchange.Simulate {
+					return fmt.Errorf("'--autoResolve' cannot be used together with '--simulate'")
+				}
+			}
+			if opt.Get().Exchange.Simulate {
+				// no real cluster is needed in simulation
+				general.SetupLogger()
+				if opt.Get().Global.Namespace == "" {
+					opt.Get().Global.Namespace = util.DefaultNamespace
+				}
+				return nil
+			}
+			if opt.Get().Exchange.EmitManifests != "" {
+				// nothing in cluster should be changed
+				return general.Prepare(cmd)
+			}
+			return prepareAndReap(cmd)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return Exchange(args[0])
+			if opt.Get().Exchange.OutboundOnly {
+				return Connect()
+			}
+			return Exchange(append(targetsInFile, args...))
 		},
-		Example: "ktctl exchange <service-name> [command options]",
+		Example: "ktctl exchange <service-name>[:<ports>] [<service-name>[:<ports>] ...] [command options]",
 	}
 
 	cmd.SetUsageTemplate(general.UsageTemplate(true))
@@ -35,37 +72,340 @@ func NewExchangeCommand() *cobra.Command {
 	return cmd
 }
 
-//Exchange exchange kubernetes workload
-func Exchange(resourceName string) error {
+// Exchange exchange kubernetes workload
+func Exchange(resourceNames []string) error {
+	if len(resourceNames) == 0 {
+		resourceName, err := exchange.AutoResolveTarget(opt.Get().Global.Namespace)
+		if err != nil {
+			return err
+		}
+		resourceNames = []string{resourceName}
+	}
+	if opt.Get().Exchange.ListPorts {
+		return exchange.ListPorts(resourceNames)
+	}
+	if opt.Get().Exchange.Simulate {
+		targets, err := exchange.ParseTargets(resourceNames, opt.Get().Exchange.Expose)
+		if err != nil {
+			return err
+		}
+		for _, target := range targets {
+			if err = exchange.Simulate(target.Resource, target.Expose); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if opt.Get().Exchange.EmitManifests != "" {
+		targets, err := exchange.ParseTargets(resourceNames, opt.Get().Exchange.Expose)
+		if err != nil {
+			return err
+		}
+		if opt.Get().Exchange.DebugPort != "" {
+			if err = exchange.AddDebugPort(targets, opt.Get().Exchange.DebugPort); err != nil {
+				return err
+			}
+		}
+		if err = exchange.ResolveTargets(targets); err != nil {
+			return err
+		}
+		resolveAutoMode(targets)
+		return exchange.EmitManifests(targets, opt.Get().Exchange.EmitManifests)
+	}
+
 	ch, err := general.SetupProcess(util.ComponentExchange)
 	if err != nil {
 		return err
 	}
 
+	targets, err := exchange.ParseTargets(resourceNames, opt.Get().Exchange.Expose)
+	if err != nil {
+		return err
+	}
+	if opt.Get().Exchange.DebugPort != "" {
+		if err = exchange.AddDebugPort(targets, opt.Get().Exchange.DebugPort); err != nil {
+			return err
+		}
+	}
+	if err = exchange.ResolveTargets(targets); err != nil {
+		return err
+	}
+	if err = exchange.CheckProtectedTargets(targets); err != nil {
+		return err
+	}
+	if err = general.CheckProtectedNamespace(opt.Get().Global.Namespace); err != nil {
+		return err
+	}
+	resolveAutoMode(targets)
+	if err = general.CheckAllowedMode(util.ComponentExchange, opt.Get().Exchange.Mode); err != nil {
+		return err
+	}
+	if opt.Get().Exchange.Mode != util.ExchangeModeEphemeral {
+		for _, target := range targets {
+			if strings.Contains(target.Expose, "/") {
+				return fmt.Errorf("protocol of exposed port is only supported in %s mode", util.ExchangeModeEphemeral)
+			}
+		}
+	}
+	if opt.Get().Exchange.HealthPort > 0 {
+		if err = exchange.CheckRemotePortConflict(targets, opt.Get().Exchange.HealthPort); err != nil {
+			return fmt.Errorf("%s, cannot be used as health port", err)
+		}
+	}
+
+	if opt.Get().Exchange.Mirror {
+		if opt.Get().Exchange.Mode != util.ExchangeModeSelector {
+			return fmt.Errorf("'--mirror' is only supported in %s mode", util.ExchangeModeSelector)
+		}
+		if opt.Get().Exchange.MirrorPercent <= 0 || opt.Get().Exchange.MirrorPercent > 100 {
+			return fmt.Errorf("mirror percent should be between 1 and 100")
+		}
+		if opt.Get().Exchange.ReuseShadow {
+			return fmt.Errorf("'--reuseShadow' cannot be used together with '--mirror'")
+		}
+	}
+
+	if opt.Get().Exchange.Gateway {
+		if opt.Get().Exchange.Mode != util.ExchangeModeSelector {
+			return fmt.Errorf("'--gateway' is only supported in %s mode", util.ExchangeModeSelector)
+		}
+		if opt.Get().Exchange.Mirror {
+			return fmt.Errorf("'--gateway' cannot be used together with '--mirror'")
+		}
+		if opt.Get().Exchange.Header == "" {
+			return fmt.Errorf("'--header' is required when '--gateway' is used")
+		}
+		if _, _, err = exchange.ParseHeader(opt.Get().Exchange.Header); err != nil {
+			return err
+		}
+	} else if opt.Get().Exchange.Header != "" {
+		return fmt.Errorf("'--header' should be used together with '--gateway'")
+	}
+
+	if opt.Get().Exchange.OriginReplicas < 0 {
+		return fmt.Errorf("origin replicas should not be negative")
+	} else if opt.Get().Exchange.OriginReplicas > 0 && opt.Get().Exchange.Mode != util.ExchangeModeScale {
+		return fmt.Errorf("'--originReplicas' is only supported in %s mode", util.ExchangeModeScale)
+	}
+	if opt.Get().Exchange.GracefulCutover {
+		if opt.Get().Exchange.Mode != util.ExchangeModeScale {
+			// origin pods are never removed in other modes, existing connections to them are kept anyway
+			return fmt.Errorf("'--gracefulCutover' is only supported in %s mode, origin pods keep serving "+
+				"existing connections in %s mode without it", util.ExchangeModeScale, opt.Get().Exchange.Mode)
+		} else if opt.Get().Exchange.DrainTimeout <= 0 {
+			return fmt.Errorf("'--gracefulCutover' requires '--drainTimeout' to be specified")
+		} else if opt.Get().Exchange.OriginReplicas > 0 {
+			return fmt.Errorf("'--gracefulCutover' cannot be used together with '--originReplicas'")
+		}
+	}
+	if opt.Get().Exchange.Passthrough != "" {
+		if opt.Get().Exchange.Mode != util.ExchangeModeScale {
+			return fmt.Errorf("'--passthrough' is only supported in %s mode", util.ExchangeModeScale)
+		} else if opt.Get().Exchange.KeepOtherPorts {
+			return fmt.Errorf("'--passthrough' cannot be used together with '--keepOtherPorts'")
+		}
+	}
+	if opt.Get().Exchange.TailOrigin && opt.Get().Exchange.Mode != util.ExchangeModeScale {
+		return fmt.Errorf("'--tailOrigin' is only supported in %s mode", util.ExchangeModeScale)
+	}
+	if opt.Get().Exchange.RequireHealthy && opt.Get().Exchange.Mode != util.ExchangeModeScale {
+		return fmt.Errorf("'--requireHealthy' is only supported in %s mode", util.ExchangeModeScale)
+	}
+	if opt.Get().Exchange.ServicesOnly != "" && opt.Get().Exchange.Mode != util.ExchangeModeScale {
+		return fmt.Errorf("'--servicesOnly' is only supported in %s mode", util.ExchangeModeScale)
+	}
+	if opt.Get().Exchange.AllowOutage && opt.Get().Exchange.Mode != util.ExchangeModeScale {
+		return fmt.Errorf("'--allowOutage' is only supported in %s mode", util.ExchangeModeScale)
+	}
+	if opt.Get().Exchange.ExcludeContainer != "" && opt.Get().Exchange.Mode != util.ExchangeModeEphemeral {
+		return fmt.Errorf("'--excludeContainer' is only supported in %s mode", util.ExchangeModeEphemeral)
+	}
+	if opt.Get().Exchange.PrintRules && opt.Get().Exchange.Mode != util.ExchangeModeEphemeral {
+		return fmt.Errorf("'--printRules' is only supported in %s mode", util.ExchangeModeEphemeral)
+	}
+	if opt.Get().Exchange.ShareProcessNs && opt.Get().Exchange.Mode != util.ExchangeModeEphemeral {
+		return fmt.Errorf("'--shareProcessNs' is only supported in %s mode", util.ExchangeModeEphemeral)
+	}
+	if opt.Get().Exchange.ResetAffinity && opt.Get().Exchange.Mode == util.ExchangeModeEphemeral {
+		return fmt.Errorf("'--resetAffinity' is not supported in %s mode", util.ExchangeModeEphemeral)
+	}
+	if _, err = cluster.ParseHostAliases(opt.Get().Exchange.HostAlias); err != nil {
+		return err
+	}
+	if (opt.Get().Exchange.HostAlias != "" || opt.Get().Exchange.CopyHostAliases) &&
+		opt.Get().Exchange.Mode == util.ExchangeModeEphemeral {
+		return fmt.Errorf("'--hostAlias' and '--copyHostAliases' are not supported in %s mode", util.ExchangeModeEphemeral)
+	}
+
+	if opt.Get().Exchange.Ingress != "" {
+		if err = exchange.CheckIngressHost(opt.Get().Exchange.Ingress, targets); err != nil {
+			return err
+		}
+	} else if opt.Get().Exchange.IngressClass != "" || opt.Get().Exchange.IngressTls != "" {
+		return fmt.Errorf("'--ingressClass' and '--ingressTls' should be used together with '--ingress'")
+	}
+
+	if opt.Get().Exchange.ShadowName != "" {
+		if err = exchange.CheckShadowName(opt.Get().Exchange.ShadowName, targets); err != nil {
+			return err
+		}
+	}
+
+	if err = exchange.CheckLoadOptions(targets); err != nil {
+		return err
+	}
+
+	if opt.Get().Exchange.Sync != "" {
+		if opt.Get().Exchange.Mode == util.ExchangeModeEphemeral {
+			return fmt.Errorf("'--sync' is not supported in %s mode", util.ExchangeModeEphemeral)
+		}
+		if _, _, err = transmission.ParseSyncPaths(opt.Get().Exchange.Sync); err != nil {
+			return err
+		}
+	}
+
+	if opt.Get().Exchange.Record != "" {
+		if err = sshchannel.StartRecording(opt.Get().Exchange.Record, opt.Get().Exchange.RecordBodyLimit,
+			opt.Get().Exchange.RedactHeaders); err != nil {
+			return fmt.Errorf("failed to open record file: %s", err)
+		}
+		log.Info().Msgf("Recording requests to %s", opt.Get().Exchange.Record)
+	} else if opt.Get().Exchange.RecordBodyLimit > 0 {
+		return fmt.Errorf("'--recordBodyLimit' should be used together with '--record'")
+	}
+
+	if opt.Get().Exchange.ProbePath != "" {
+		if !strings.HasPrefix(opt.Get().Exchange.ProbePath, "/") {
+			return fmt.Errorf("probe path should start with '/'")
+		} else if opt.Get().Exchange.ProbeStatus != 0 &&
+			(opt.Get().Exchange.ProbeStatus < 100 || opt.Get().Exchange.ProbeStatus > 599) {
+			return fmt.Errorf("invalid probe status %d", opt.Get().Exchange.ProbeStatus)
+		}
+	}
+
+	if opt.Get().Exchange.NotifyUrl != "" && !strings.HasPrefix(opt.Get().Exchange.NotifyUrl, "http://") &&
+		!strings.HasPrefix(opt.Get().Exchange.NotifyUrl, "https://") {
+		return fmt.Errorf("notify url should start with 'http://' or 'https://'")
+	}
+	if opt.Get().Exchange.NotifySignal != "" {
+		if opt.Get().Exchange.Exec == "" {
+			return fmt.Errorf("'--notifySignal' requires local service started by '--exec'")
+		}
+		if _, _, err = general.ParseNotifySignal(opt.Get().Exchange.NotifySignal); err != nil {
+			return err
+		}
+	}
+
+	if opt.Get().Exchange.Throttle != "" || opt.Get().Exchange.Latency != 0 {
+		if opt.Get().Exchange.Latency < 0 {
+			return fmt.Errorf("latency should not be negative")
+		}
+		bytesPerSecond := 0
+		if opt.Get().Exchange.Throttle != "" {
+			if bytesPerSecond, err = sshchannel.ParseRate(opt.Get().Exchange.Throttle); err != nil {
+				return err
+			}
+		}
+		sshchannel.StartThrottle(bytesPerSecond, time.Duration(opt.Get().Exchange.Latency)*time.Millisecond)
+		log.Info().Msgf("Throttling connections forwarded to local")
+	}
+
+	if opt.Get().Exchange.DumpMounts != "" {
+		if err = exchange.DumpMounts(targets, opt.Get().Exchange.DumpMounts); err != nil {
+			return err
+		}
+	}
+
+	if opt.Get().Exchange.Exec != "" {
+		if err = exchange.RunExec(opt.Get().Exchange.Exec, ch); err != nil {
+			return err
+		}
+	}
+
 	if opt.Get().Exchange.SkipPortChecking {
-		if port := util.FindBrokenLocalPort(opt.Get().Exchange.Expose); port != "" {
-			return fmt.Errorf("no application is running on port %s", port)
+		for _, target := range targets {
+			tcpPorts := util.FilterExposeByProtocol(target.Expose, util.ProtocolTcp)
+			if tcpPorts == "" {
+				continue
+			}
+			if port := util.FindBrokenLocalPort(tcpPorts); port != "" {
+				return fmt.Errorf("no application is running on port %s", port)
+			}
+		}
+	}
+
+	if opt.Get().Exchange.ProbePath != "" {
+		if err = exchange.ProbeLocal(targets); err != nil {
+			return err
 		}
 	}
 
 	log.Info().Msgf("Using %s mode", opt.Get().Exchange.Mode)
-	if opt.Get().Exchange.Mode == util.ExchangeModeScale {
-		err = exchange.ByScale(resourceName)
-	} else if opt.Get().Exchange.Mode == util.ExchangeModeEphemeral {
-		err = exchange.ByEphemeralContainer(resourceName)
-	} else if opt.Get().Exchange.Mode == util.ExchangeModeSelector {
-		err = exchange.BySelector(resourceName)
-	} else {
-		err = fmt.Errorf("invalid exchange method '%s', supportted are %s, %s, %s", opt.Get().Exchange.Mode,
-			util.ExchangeModeSelector, util.ExchangeModeScale, util.ExchangeModeEphemeral)
+	for _, target := range targets {
+		if opt.Get().Exchange.Mode == util.ExchangeModeScale {
+			err = exchange.ByScale(target.Resource, target.Expose)
+		} else if opt.Get().Exchange.Mode == util.ExchangeModeEphemeral {
+			err = exchange.ByEphemeralContainer(target.Resource, target.Expose)
+		} else if opt.Get().Exchange.Mode == util.ExchangeModeSelector && opt.Get().Exchange.Mirror {
+			err = exchange.ByMirror(target.Resource, target.Expose)
+		} else if opt.Get().Exchange.Mode == util.ExchangeModeSelector && opt.Get().Exchange.Gateway {
+			err = exchange.ByGateway(target.Resource, target.Expose)
+		} else if opt.Get().Exchange.Mode == util.ExchangeModeSelector {
+			err = exchange.BySelector(target.Resource, target.Expose)
+		} else {
+			err = fmt.Errorf("invalid exchange method '%s', supportted are %s, %s, %s, %s", opt.Get().Exchange.Mode,
+				util.ExchangeModeSelector, util.ExchangeModeScale, util.ExchangeModeEphemeral, util.ExchangeModeAuto)
+		}
+		if err != nil {
+			return err
+		}
 	}
-	if err != nil {
-		return err
+	if opt.Get().Exchange.Ingress != "" {
+		if err = exchange.CreateIngress(targets[0]); err != nil {
+			return err
+		}
+	}
+	util.StatusLog().Msg("---------------------------------------------------------------")
+	for _, target := range targets {
+		resourceType, realName, _ := general.ParseResourceName(target.Resource)
+		if opt.Get().Exchange.Mirror {
+			util.StatusLog().Msgf(" Now %d%% request to %s '%s' will be copied to local", opt.Get().Exchange.MirrorPercent,
+				resourceType, realName)
+		} else if opt.Get().Exchange.Gateway && opt.Store.HttpRoute != "" {
+			util.StatusLog().Msgf(" Now request to %s '%s' with header '%s' will be redirected to local",
+				resourceType, realName, opt.Get().Exchange.Header)
+		} else if opt.Get().Exchange.Mode == util.ExchangeModeScale && opt.Get().Exchange.OriginReplicas > 0 {
+			util.StatusLog().Msgf(" Now request to %s '%s' will be shared between local and %d origin replicas",
+				resourceType, realName, opt.Get().Exchange.OriginReplicas)
+		} else {
+			util.StatusLog().Msgf(" Now all request to %s '%s' will be redirected to local", resourceType, realName)
+		}
+	}
+	if opt.Get().Exchange.Ingress != "" {
+		util.StatusLog().Msgf(" Teammates can access local service via host '%s'", opt.Get().Exchange.Ingress)
+	}
+	if opt.Get().Exchange.DebugPort != "" {
+		localPort, remotePort, _ := util.ParsePortMapping(opt.Get().Exchange.DebugPort)
+		util.StatusLog().Msgf(" Debug port %d of shadow pod is forwarded to local debugger at port %d", remotePort, localPort)
+	}
+	util.StatusLog().Msg(exchange.OutboundScopeMessage(exchange.GetConnectPid()))
+	util.StatusLog().Msg("---------------------------------------------------------------")
+
+	if opt.Get().Exchange.NotifyUrl != "" || opt.Get().Exchange.NotifySignal != "" {
+		var lifecycleTargets []general.LifecycleTarget
+		for _, target := range targets {
+			lifecycleTargets = append(lifecycleTargets, general.NewLifecycleTarget(target.Resource, target.Expose))
+		}
+		general.NotifyActive(lifecycleTargets)
+	}
+
+	if opt.Get().Exchange.Load > 0 {
+		generator, err2 := exchange.StartLoad(targets[0])
+		if err2 != nil {
+			return err2
+		}
+		defer generator.Stop()
 	}
-	resourceType, realName := toTypeAndName(resourceName)
-	log.Info().Msg("---------------------------------------------------------------")
-	log.Info().Msgf(" Now all request to %s '%s' will be redirected to local", resourceType, realName)
-	log.Info().Msg("---------------------------------------------------------------")
 
 	// watch background process, clean the workspace and exit if background process occur exception
 	s := <-ch
@@ -73,11 +413,11 @@ func Exchange(resourceName string) error {
 	return nil
 }
 
-func toTypeAndName(name string) (string, string) {
-	parts := strings.Split(name, "/")
-	if len(parts) > 1 {
-		return parts[0], parts[1]
-	} else {
-		return "service", parts[0]
+// resolveAutoMode replace 'auto' exchange method with the one detected from cluster
+func resolveAutoMode(targets []exchange.Target) {
+	if opt.Get().Exchange.Mode == util.ExchangeModeAuto {
+		mode, reason := exchange.DetectMode(targets)
+		log.Info().Msgf("Choosing %s mode, because %s", mode, reason)
+		opt.Get().Exchange.Mode = mode
 	}
 }
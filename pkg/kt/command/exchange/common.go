@@ -0,0 +1,353 @@
+package exchange
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/command/general"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/transmission"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"strconv"
+	"strings"
+)
+
+// Target resource to exchange and the ports it should expose
+type Target struct {
+	Resource string
+	Expose   string
+}
+
+// ParseTargets parse '<resource>[:<ports>]' arguments, resource without ports will use default expose value
+func ParseTargets(args []string, defaultExpose string) ([]Target, error) {
+	targets := make([]Target, 0)
+	for _, arg := range args {
+		target := Target{Resource: arg, Expose: defaultExpose}
+		if pos := strings.Index(arg, ":"); pos >= 0 {
+			target.Resource = arg[0:pos]
+			target.Expose = arg[pos+1:]
+		}
+		if target.Resource == "" {
+			return nil, fmt.Errorf("invalid exchange target '%s', resource name is required", arg)
+		} else if target.Expose == "" {
+			return nil, fmt.Errorf("no port to expose for '%s', please specify it via '--expose' or '%s:<ports>'",
+				target.Resource, target.Resource)
+		}
+		targets = append(targets, target)
+	}
+	if err := checkLocalPortConflict(targets); err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+// AddDebugPort append debug port to exposed ports of the only target
+func AddDebugPort(targets []Target, debugPort string) error {
+	if len(targets) != 1 {
+		return fmt.Errorf("'--debugPort' is only supported when exchanging single target")
+	}
+	if strings.Contains(debugPort, ",") || strings.Contains(debugPort, "/") {
+		return fmt.Errorf("invalid debug port '%s', should be in [port] or [local:remote] format", debugPort)
+	}
+	_, remotePort, err := util.ParsePortMapping(debugPort)
+	if err != nil {
+		return err
+	}
+	if err = CheckRemotePortConflict(targets, remotePort); err != nil {
+		return fmt.Errorf("%s, cannot be used as debug port", err)
+	}
+	targets[0].Expose = targets[0].Expose + "," + debugPort
+	return checkLocalPortConflict(targets)
+}
+
+// withDebugPortAnnotation mark remote debug port in shadow pod annotations
+func withDebugPortAnnotation(annotations map[string]string) map[string]string {
+	if opt.Get().Exchange.DebugPort != "" {
+		_, remotePort, _ := util.ParsePortMapping(opt.Get().Exchange.DebugPort)
+		annotations[util.KtDebugPort] = strconv.Itoa(remotePort)
+	}
+	return annotations
+}
+
+// logRoutedServices print whether requests to each service would be redirected to shadow with specified labels
+func logRoutedServices(svcs []coreV1.Service, shadowLabels map[string]string) {
+	for _, svc := range svcs {
+		if util.MapContains(svc.Spec.Selector, shadowLabels) {
+			log.Info().Msgf("Requests to service %s will be redirected to local", svc.Name)
+		} else {
+			log.Info().Msgf("Requests to service %s will NOT be redirected to local", svc.Name)
+		}
+	}
+}
+
+// warnExternalTrafficPolicy external requests to service with 'Local' policy only reach pods on the node receiving them
+func warnExternalTrafficPolicy(svc *coreV1.Service) {
+	if (svc.Spec.Type == coreV1.ServiceTypeNodePort || svc.Spec.Type == coreV1.ServiceTypeLoadBalancer) &&
+		svc.Spec.ExternalTrafficPolicy == coreV1.ServiceExternalTrafficPolicyTypeLocal {
+		log.Warn().Msgf("Service %s has 'Local' external traffic policy, external requests can only reach local "+
+			"via the node which shadow pod is running on", svc.Name)
+	}
+}
+
+func checkLocalPortConflict(targets []Target) error {
+	// same port number of different protocols do not conflict
+	localPortOwner := make(map[string]string)
+	for _, target := range targets {
+		for _, exposePort := range strings.Split(target.Expose, ",") {
+			portMapping, protocol, err := util.SplitExposeProtocol(exposePort)
+			if err != nil {
+				return err
+			}
+			localPort, _, err := util.ParsePortMapping(portMapping)
+			if err != nil {
+				return err
+			}
+			port := strconv.Itoa(localPort)
+			if protocol != util.ProtocolTcp {
+				port = fmt.Sprintf("%d/%s", localPort, protocol)
+			}
+			if owner, exists := localPortOwner[port]; exists {
+				if owner == target.Resource {
+					return fmt.Errorf("local port %s is specified more than once for '%s'", port, owner)
+				}
+				return fmt.Errorf("local port %s is used by both '%s' and '%s'", port, owner, target.Resource)
+			}
+			localPortOwner[port] = target.Resource
+		}
+	}
+	return nil
+}
+
+// CheckRemotePortConflict check whether specified port is already used as remote port of any target
+func CheckRemotePortConflict(targets []Target, port int) error {
+	for _, target := range targets {
+		tcpPorts := util.FilterExposeByProtocol(target.Expose, util.ProtocolTcp)
+		if tcpPorts == "" {
+			continue
+		}
+		for _, exposePort := range strings.Split(tcpPorts, ",") {
+			_, remotePort, err := util.ParsePortMapping(exposePort)
+			if err != nil {
+				return err
+			}
+			if remotePort == port {
+				return fmt.Errorf("port %d is already exposed by '%s'", port, target.Resource)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckDeclaredPorts warn about (or reject with '--strictPorts') remote ports not declared by any container of target,
+// nor by the target service
+func CheckDeclaredPorts(resourceName, expose string, spec *coreV1.PodSpec) error {
+	ports, err := findUndeclaredPorts(expose, spec, getTargetService(resourceName))
+	if err != nil {
+		return err
+	}
+	_, debugPort, _ := util.ParsePortMapping(opt.Get().Exchange.DebugPort)
+	for _, port := range ports {
+		if opt.Get().Exchange.DebugPort != "" && port == debugPort {
+			// debug port is usually not declared by container
+			continue
+		}
+		if opt.Get().Exchange.StrictPorts {
+			return fmt.Errorf("remote port %d is not declared by any container or service port of '%s'", port, resourceName)
+		}
+		log.Warn().Msgf("Remote port %d is not declared by any container or service port of '%s', "+
+			"please make sure it is correct", port, resourceName)
+	}
+	return nil
+}
+
+// getTargetService service to exchange if target is a service, otherwise nil
+func getTargetService(resourceName string) *coreV1.Service {
+	resourceType, name, err := general.ParseResourceName(resourceName)
+	if err != nil || (resourceType != "svc" && resourceType != "service") {
+		return nil
+	}
+	svc, err := cluster.Ins().GetService(name, opt.Get().Global.Namespace)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to get service %s", name)
+		return nil
+	}
+	return svc
+}
+
+// findUndeclaredPorts remote ports which are neither container port, nor port or target port of service (if not nil)
+func findUndeclaredPorts(expose string, spec *coreV1.PodSpec, svc *coreV1.Service) ([]int, error) {
+	declaredPorts := make(map[int]bool)
+	for _, c := range spec.Containers {
+		for _, p := range c.Ports {
+			if p.Protocol != coreV1.ProtocolUDP {
+				declaredPorts[int(p.ContainerPort)] = true
+			}
+		}
+	}
+	if svc != nil {
+		for _, p := range svc.Spec.Ports {
+			if p.Protocol == coreV1.ProtocolUDP {
+				continue
+			}
+			declaredPorts[int(p.Port)] = true
+			// named target port is resolved against containers
+			if targetPort := resolveTargetPort(p, spec); targetPort > 0 {
+				declaredPorts[int(targetPort)] = true
+			}
+		}
+	}
+	ports := make([]int, 0)
+	for _, exposePort := range strings.Split(expose, ",") {
+		_, remotePort, err := util.ParsePortMapping(exposePort)
+		if err != nil {
+			return nil, err
+		}
+		if !declaredPorts[remotePort] {
+			ports = append(ports, remotePort)
+		}
+	}
+	return ports, nil
+}
+
+// StartSync keep local files synced to shadow pod if '--sync' is specified
+func StartSync(shadowName string, localSshPort int) error {
+	return transmission.StartSync(opt.Get().Exchange.Sync, opt.Get().Exchange.SyncIgnore, localSshPort,
+		util.PrivateKeyPath(shadowName))
+}
+
+// getShadowName use name specified by '--shadowName', or generate one with random suffix
+func getShadowName(prefix string) (string, error) {
+	if opt.Get().Exchange.ShadowName != "" {
+		return opt.Get().Exchange.ShadowName, nil
+	}
+	name := util.RandomName(prefix + util.ExchangePodInfix)
+	if err := util.CheckNameValid(name); err != nil {
+		return "", fmt.Errorf("%s, please specify shadow name via '--shadowName'", err)
+	}
+	return name, nil
+}
+
+// getShadowSuffix random suffix of generated shadow name, resources created along with the shadow carry it too,
+// so that they won't collide with those of other exchanges on same service, shadow named by user gets a new suffix
+func getShadowSuffix(shadowName string) string {
+	if i := strings.LastIndex(shadowName, "-"); opt.Get().Exchange.ShadowName == "" && i >= 0 {
+		return shadowName[i+1:]
+	}
+	return util.RandomSuffix()
+}
+
+// withShadowSuffix append shadow suffix to prefix, the prefix is truncated if the name would exceed length of dns label
+func withShadowSuffix(prefix, suffix string) string {
+	if maxLen := validation.DNS1123LabelMaxLength - len(suffix) - 1; len(prefix) > maxLen {
+		prefix = strings.TrimRight(prefix[:maxLen], "-")
+	}
+	return prefix + "-" + suffix
+}
+
+// CheckShadowName verify the specified shadow name is valid, and not occupied unless the shadow is going to be reused
+func CheckShadowName(name string, targets []Target) error {
+	if opt.Get().Exchange.Mode == util.ExchangeModeEphemeral {
+		return fmt.Errorf("'--shadowName' is not supported in %s mode", util.ExchangeModeEphemeral)
+	}
+	if len(targets) > 1 {
+		return fmt.Errorf("'--shadowName' cannot be used when exchanging multiple resources")
+	}
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		return fmt.Errorf("invalid shadow name '%s': %s", name, strings.Join(errs, ", "))
+	}
+	if opt.Get().Exchange.ReuseShadow && opt.Get().Exchange.Mode == util.ExchangeModeSelector {
+		return nil
+	}
+	namespace := opt.Get().Global.Namespace
+	existing := ""
+	if _, err := cluster.Ins().GetPod(name, namespace); err == nil {
+		existing = "pod"
+	} else if _, err = cluster.Ins().GetDeployment(name, namespace); err == nil {
+		existing = "deployment"
+	} else if _, err = cluster.Ins().GetConfigMap(name, namespace); err == nil {
+		existing = "config map"
+	}
+	if existing == "" {
+		return nil
+	} else if opt.Get().Exchange.Mode == util.ExchangeModeSelector && !opt.Get().Exchange.Mirror {
+		return fmt.Errorf("%s %s already exists in namespace %s, use '--reuseShadow' to reuse it",
+			existing, name, namespace)
+	}
+	return fmt.Errorf("%s %s already exists in namespace %s", existing, name, namespace)
+}
+
+// DetectMode choose ephemeral mode if cluster supports it and none of target pods has istio sidecar,
+// otherwise choose scale mode, the reason of choice is returned as well
+func DetectMode(targets []Target) (string, string) {
+	if ok, reason := cluster.Ins().IsEphemeralContainerSupported(); !ok {
+		return util.ExchangeModeScale, reason
+	}
+	for _, target := range targets {
+		pods, err := getPodsOfResource(target.Resource, opt.Get().Global.Namespace)
+		if err != nil {
+			return util.ExchangeModeScale, fmt.Sprintf("failed to get pods of %s: %s", target.Resource, err)
+		}
+		for _, pod := range pods {
+			if pod.Status.Phase != coreV1.PodRunning {
+				continue
+			}
+			if hasIstioSidecar(&pod) {
+				return util.ExchangeModeScale, fmt.Sprintf("pod %s has istio sidecar", pod.Name)
+			}
+			if cluster.Ins().GetPodOs(&pod.Spec) == util.OsWindows {
+				return util.ExchangeModeScale, fmt.Sprintf("pod %s is running on windows node", pod.Name)
+			}
+		}
+	}
+	return util.ExchangeModeEphemeral, "cluster supports ephemeral container and no istio sidecar found"
+}
+
+// CheckProtectedTargets refuse to exchange kubernetes system components or resources created by kt-connect,
+// since redirecting their traffic to local could break the whole cluster
+func CheckProtectedTargets(targets []Target) error {
+	if opt.Get().Exchange.IKnowWhatImDoing {
+		return nil
+	}
+	namespace := opt.Get().Global.Namespace
+	if namespace == util.NamespaceKubeSystem {
+		return fmt.Errorf("refuse to exchange resources in %s namespace, use '--iKnowWhatImDoing' if you're sure",
+			util.NamespaceKubeSystem)
+	}
+	for _, target := range targets {
+		labels := getResourceLabels(target.Resource, namespace)
+		if labels[util.ControlBy] == util.KubernetesToolkit || labels[util.KtRole] != "" {
+			return fmt.Errorf("refuse to exchange %s, which is created by kt-connect, "+
+				"use '--iKnowWhatImDoing' if you're sure", target.Resource)
+		}
+	}
+	return nil
+}
+
+// getResourceLabels get labels of resource in same format as exchange target, return nil if not found
+func getResourceLabels(resource, namespace string) map[string]string {
+	resourceType, name, err := general.ParseResourceName(resource)
+	if err != nil {
+		return nil
+	}
+	switch normalizeResourceType(resourceType) {
+	case "service":
+		if svc, err2 := cluster.Ins().GetService(name, namespace); err2 == nil {
+			return svc.Labels
+		}
+	case "deployment":
+		if deployment, err2 := cluster.Ins().GetDeployment(name, namespace); err2 == nil {
+			return deployment.Labels
+		}
+	case "pod":
+		if pod, err2 := cluster.Ins().GetPod(name, namespace); err2 == nil {
+			return pod.Labels
+		}
+	case "job":
+		if job, err2 := cluster.Ins().GetJob(name, namespace); err2 == nil {
+			return job.Labels
+		}
+	}
+	return nil
+}
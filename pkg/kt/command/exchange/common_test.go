@@ -0,0 +1,215 @@
+package exchange
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster/fake"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"strings"
+	"testing"
+)
+
+func TestParseTargets(t *testing.T) {
+	targets, err := ParseTargets([]string{"svc-a", "deployment/b:9090,7001:80"}, "8080")
+	require.Nil(t, err)
+	require.Equal(t, []Target{
+		{Resource: "svc-a", Expose: "8080"},
+		{Resource: "deployment/b", Expose: "9090,7001:80"},
+	}, targets)
+
+	_, err = ParseTargets([]string{"svc-a", "svc-b"}, "")
+	require.NotNil(t, err, "resource without expose ports should fail")
+	_, err = ParseTargets([]string{":8080"}, "")
+	require.NotNil(t, err, "target without resource name should fail")
+	_, err = ParseTargets([]string{"svc-a", "svc-b"}, "8080")
+	require.NotNil(t, err, "same local port for different resources should fail")
+	_, err = ParseTargets([]string{"svc-a:8080", "svc-b:8080:80"}, "")
+	require.NotNil(t, err, "same local port for different resources should fail")
+	_, err = ParseTargets([]string{"svc-a:8080,8080:80"}, "")
+	require.NotNil(t, err, "duplicated local port of one resource should fail")
+	_, err = ParseTargets([]string{"pod/a:53,53/udp"}, "")
+	require.Nil(t, err, "same local port of different protocols should not conflict")
+	_, err = ParseTargets([]string{"pod/a:53/udp,5353:53/udp"}, "")
+	require.Nil(t, err, "different local ports should not conflict")
+	_, err = ParseTargets([]string{"pod/a:53/udp,53:5353/udp"}, "")
+	require.NotNil(t, err, "same local udp port should fail")
+	_, err = ParseTargets([]string{"svc-a:abc"}, "")
+	require.NotNil(t, err, "invalid port should fail")
+}
+
+func TestCheckRemotePortConflict(t *testing.T) {
+	targets := []Target{{Resource: "svc-a", Expose: "8080,7001:80"}}
+	require.Nil(t, CheckRemotePortConflict(targets, 7001))
+	err := CheckRemotePortConflict(targets, 80)
+	require.NotNil(t, err)
+	require.Equal(t, "port 80 is already exposed by 'svc-a'", err.Error(), "caller should add its own wording")
+	require.NotNil(t, CheckRemotePortConflict(targets, 8080))
+}
+
+func TestAddDebugPort(t *testing.T) {
+	targets := []Target{{Resource: "svc-a", Expose: "8080"}}
+	require.Nil(t, AddDebugPort(targets, "5005"))
+	require.Equal(t, "8080,5005", targets[0].Expose)
+	err := AddDebugPort([]Target{{Resource: "svc-a", Expose: "8080"}}, "8080")
+	require.NotNil(t, err, "debug port already exposed should fail")
+	require.Equal(t, "port 8080 is already exposed by 'svc-a', cannot be used as debug port", err.Error())
+	require.NotNil(t, AddDebugPort([]Target{{Resource: "svc-a", Expose: "9229:80"}}, "9229:9230"),
+		"local debug port already used should fail")
+	require.NotNil(t, AddDebugPort([]Target{{Resource: "svc-a", Expose: "8080"}}, "5005/udp"))
+	require.NotNil(t, AddDebugPort([]Target{{Resource: "svc-a", Expose: "8080"}, {Resource: "svc-b", Expose: "8081"}}, "5005"),
+		"multiple targets should fail")
+
+	opt.Get().Exchange.DebugPort = "9229:9230"
+	defer func() { opt.Get().Exchange.DebugPort = "" }()
+	require.Equal(t, "9230", withDebugPortAnnotation(map[string]string{})[util.KtDebugPort])
+}
+
+func Test_findUndeclaredPorts(t *testing.T) {
+	spec := &coreV1.PodSpec{Containers: []coreV1.Container{
+		{Ports: []coreV1.ContainerPort{{ContainerPort: 8080}, {ContainerPort: 53, Protocol: coreV1.ProtocolUDP}}},
+		{Ports: []coreV1.ContainerPort{{ContainerPort: 9090, Protocol: coreV1.ProtocolTCP}}},
+	}}
+	ports, err := findUndeclaredPorts("8080,7001:9090", spec, nil)
+	require.Nil(t, err)
+	require.Empty(t, ports)
+	ports, err = findUndeclaredPorts("8088:8081,53", spec, nil)
+	require.Nil(t, err)
+	require.Equal(t, []int{8081, 53}, ports)
+	_, err = findUndeclaredPorts("abc", spec, nil)
+	require.NotNil(t, err)
+}
+
+func Test_findUndeclaredPorts_servicePorts(t *testing.T) {
+	spec := &coreV1.PodSpec{Containers: []coreV1.Container{
+		{Ports: []coreV1.ContainerPort{{Name: "http", ContainerPort: 8080}}},
+	}}
+	svc := &coreV1.Service{Spec: coreV1.ServiceSpec{Ports: []coreV1.ServicePort{
+		{Port: 80, TargetPort: intstr.FromInt(8080)},
+		{Port: 81, TargetPort: intstr.FromString("http")},
+		{Port: 53, TargetPort: intstr.FromInt(5353), Protocol: coreV1.ProtocolUDP},
+	}}}
+	ports, err := findUndeclaredPorts("80", spec, nil)
+	require.Nil(t, err)
+	require.Equal(t, []int{80}, ports, "service port is not declared by container")
+	ports, err = findUndeclaredPorts("80,8081:81,8080", spec, svc)
+	require.Nil(t, err)
+	require.Empty(t, ports, "service ports and their target ports should be declared")
+	ports, err = findUndeclaredPorts("53,5353", spec, svc)
+	require.Nil(t, err)
+	require.Equal(t, []int{53, 5353}, ports, "udp service ports should not be declared")
+}
+
+func TestCheckShadowName(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	opt.Get().Exchange.Mode = util.ExchangeModeSelector
+	cluster.SetIns(fake.NewKubernetes(&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "demo-shadow", Namespace: "default"}}))
+	defer cluster.SetIns(nil)
+	single := []Target{{Resource: "svc-a", Expose: "8080"}}
+
+	require.Nil(t, CheckShadowName("my-shadow", single))
+	require.NotNil(t, CheckShadowName("My_Shadow", single), "invalid pod name should fail")
+	require.NotNil(t, CheckShadowName("my-shadow", append(single, Target{Resource: "svc-b", Expose: "8080"})),
+		"same name for multiple targets should fail")
+	err := CheckShadowName("demo-shadow", single)
+	require.NotNil(t, err, "existing shadow should fail")
+	require.Contains(t, err.Error(), "--reuseShadow")
+
+	opt.Get().Exchange.ReuseShadow = true
+	require.Nil(t, CheckShadowName("demo-shadow", single), "existing shadow should be reused")
+	opt.Get().Exchange.Mode = util.ExchangeModeScale
+	require.NotNil(t, CheckShadowName("demo-shadow", single), "shadow cannot be reused in scale mode")
+	opt.Get().Exchange.Mode = util.ExchangeModeEphemeral
+	require.NotNil(t, CheckShadowName("my-shadow", single), "ephemeral mode has no shadow pod")
+	opt.Get().Exchange.ReuseShadow = false
+	opt.Get().Exchange.Mode = util.ExchangeModeSelector
+
+	opt.Get().Exchange.ShadowName = "my-shadow"
+	defer func() { opt.Get().Exchange.ShadowName = "" }()
+	name, err := getShadowName("svc-a")
+	require.Nil(t, err)
+	require.Equal(t, "my-shadow", name)
+	opt.Get().Exchange.ShadowName = ""
+	name, err = getShadowName("svc-a")
+	require.Nil(t, err)
+	require.Regexp(t, "^svc-a"+util.ExchangePodInfix+"[a-z0-9]{5}$", name)
+	name, err = getShadowName(strings.Repeat("a", 60))
+	require.Nil(t, err, "long origin name should be truncated")
+	require.Len(t, name, 63)
+	_, err = getShadowName("app.v1")
+	require.NotNil(t, err, "name not valid as dns label should fail")
+}
+
+func Test_withShadowSuffix(t *testing.T) {
+	require.Equal(t, "abcde", getShadowSuffix("app-kt-exchange-abcde"))
+	opt.Get().Exchange.ShadowName = "my-shadow"
+	defer func() { opt.Get().Exchange.ShadowName = "" }()
+	require.NotEqual(t, "shadow", getShadowSuffix("my-shadow"), "shadow named by user should get a random suffix")
+
+	require.Equal(t, "app-kt-gateway-abcde", withShadowSuffix("app"+util.GatewaySuffix, "abcde"))
+	name := withShadowSuffix(strings.Repeat("a", 60)+util.GatewaySuffix, "abcde")
+	require.Nil(t, util.CheckNameValid(name))
+	require.True(t, strings.HasSuffix(name, "-abcde"))
+}
+
+func TestDetectMode(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	k := fake.NewKubernetes(&coreV1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "default"},
+		Spec:       coreV1.PodSpec{Containers: []coreV1.Container{{Name: "app"}}},
+		Status:     coreV1.PodStatus{Phase: coreV1.PodRunning},
+	}, &coreV1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-2", Namespace: "default"},
+		Spec:       coreV1.PodSpec{Containers: []coreV1.Container{{Name: "app"}, {Name: util.IstioSidecarContainer}}},
+		Status:     coreV1.PodStatus{Phase: coreV1.PodRunning},
+	})
+	cluster.SetIns(k)
+	defer cluster.SetIns(nil)
+	targets := []Target{{Resource: "pod/app-1", Expose: "8080"}}
+
+	mode, _ := DetectMode(targets)
+	require.Equal(t, util.ExchangeModeScale, mode, "cluster without ephemeral container api should use scale mode")
+
+	discovery := k.Clientset.Discovery().(*fakediscovery.FakeDiscovery)
+	discovery.FakedServerVersion = &version.Info{Major: "1", Minor: "25"}
+	discovery.Resources = []*metav1.APIResourceList{{GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "pods/ephemeralcontainers"}}}}
+	mode, _ = DetectMode(targets)
+	require.Equal(t, util.ExchangeModeEphemeral, mode)
+
+	mode, _ = DetectMode(append(targets, Target{Resource: "pod/app-2", Expose: "8080"}))
+	require.Equal(t, util.ExchangeModeScale, mode, "pod with istio sidecar should use scale mode")
+}
+
+func TestCheckProtectedTargets(t *testing.T) {
+	cluster.SetIns(fake.NewKubernetes(&coreV1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+	}, &coreV1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-kt-router", Namespace: "default",
+			Labels: map[string]string{util.ControlBy: util.KubernetesToolkit, util.KtRole: util.RoleRouter}},
+	}))
+	defer cluster.SetIns(nil)
+	defer func() {
+		opt.Get().Global.Namespace = "default"
+		opt.Get().Exchange.IKnowWhatImDoing = false
+	}()
+
+	opt.Get().Global.Namespace = "default"
+	require.Nil(t, CheckProtectedTargets([]Target{{Resource: "service/app"}, {Resource: "deployment/absent"}}))
+	require.NotNil(t, CheckProtectedTargets([]Target{{Resource: "pod/app-kt-router"}}),
+		"resource created by kt-connect should be refused")
+	require.NotNil(t, CheckProtectedTargets([]Target{{Resource: "po/app-kt-router"}}),
+		"short name of resource type should be recognized")
+	require.NotNil(t, CheckProtectedTargets([]Target{{Resource: "v1/pod/app-kt-router"}}),
+		"qualified resource name should be recognized")
+	opt.Get().Global.Namespace = util.NamespaceKubeSystem
+	require.NotNil(t, CheckProtectedTargets([]Target{{Resource: "deployment/coredns"}}),
+		"resource in kube-system should be refused")
+	opt.Get().Exchange.IKnowWhatImDoing = true
+	require.Nil(t, CheckProtectedTargets([]Target{{Resource: "deployment/coredns"}}))
+}
@@ -0,0 +1,173 @@
+package exchange
+
+import (
+	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	appV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labelApi "k8s.io/apimachinery/pkg/labels"
+	"strings"
+	"time"
+)
+
+// detachOriginPods remove labels selected by deployment and services from origin pods, and release them from their
+// replica set, before the deployment is scaled down, so that new connections only go to shadow, while existing
+// connections keep working until they close or drain timeout reached, pods already terminating are left to drain
+// in their termination grace period
+func detachOriginPods(app *appV1.Deployment, svcs []coreV1.Service, origins []coreV1.Pod) ([]string, error) {
+	keys := detachLabelKeys(app, svcs)
+	var detached []string
+	for _, origin := range origins {
+		pod, err := detachOriginPod(origin.Name, origin.Namespace, keys)
+		if err != nil {
+			return detached, err
+		} else if pod == nil {
+			continue
+		}
+		opt.LockStore(func(store *opt.RuntimeStore) {
+			store.OriginDraining = util.Append(store.OriginDraining, pod.Name)
+		})
+		cluster.SetupHeartBeat(pod.Name, pod.Namespace, cluster.Ins().UpdatePodHeartBeat)
+		detached = append(detached, pod.Name)
+	}
+	return detached, nil
+}
+
+// detachOriginPod remove selected labels and controller reference of pod, retry if pod is modified meanwhile,
+// return nil if pod is gone or terminating
+func detachOriginPod(name, namespace string, keys []string) (*coreV1.Pod, error) {
+	for i := 0; ; i++ {
+		pod, err := cluster.Ins().GetPod(name, namespace)
+		if k8sErrors.IsNotFound(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if pod.DeletionTimestamp != nil {
+			log.Debug().Msgf("Origin pod %s is already terminating", pod.Name)
+			return nil, nil
+		}
+		for _, key := range keys {
+			delete(pod.Labels, key)
+		}
+		pod.Labels = util.MapPut(pod.Labels, util.ControlBy, util.KubernetesToolkit)
+		pod.Labels[util.KtRole] = util.RoleOriginDraining
+		pod.Annotations = util.MapPut(pod.Annotations, util.KtLastHeartBeat, util.GetTimestamp())
+		// without controller reference, pod is neither adopted again nor removed by scaling down
+		var owners []metav1.OwnerReference
+		for _, owner := range pod.OwnerReferences {
+			if owner.Controller == nil || !*owner.Controller {
+				owners = append(owners, owner)
+			}
+		}
+		pod.OwnerReferences = owners
+		if _, err = cluster.Ins().UpdatePod(pod); err == nil {
+			return pod, nil
+		} else if k8sErrors.IsNotFound(err) {
+			return nil, nil
+		} else if !k8sErrors.IsConflict(err) || i >= 2 {
+			return nil, fmt.Errorf("failed to detach origin pod %s: %s", name, err)
+		}
+	}
+}
+
+// removeReplacementPods replica set creates new pods to replace the detached ones until deployment scaled down,
+// remove those still left after scaling down to 0, detached pods are no longer origin pods of the deployment
+func removeReplacementPods(app *appV1.Deployment) error {
+	pods, err := getOriginPods(app)
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		log.Info().Msgf("Removing pod %s created to replace detached origin pods", pod.Name)
+		if err = cluster.Ins().RemovePod(pod.Name, pod.Namespace); err != nil && !k8sErrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// getOriginPods pods created by the deployment, kt pods and pods of other workloads sharing the labels are excluded
+func getOriginPods(app *appV1.Deployment) ([]coreV1.Pod, error) {
+	// empty selector matches every pod in namespace
+	if app.Spec.Selector == nil || (len(app.Spec.Selector.MatchLabels) == 0 && len(app.Spec.Selector.MatchExpressions) == 0) {
+		return nil, fmt.Errorf("deployment %s has no pod selector", app.Name)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(app.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector of deployment %s: %s", app.Name, err)
+	}
+	pods, err := cluster.Ins().GetPodsByLabel(app.Spec.Selector.MatchLabels, app.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	var origins []coreV1.Pod
+	for _, pod := range pods.Items {
+		if pod.Labels[util.KtRole] != "" || !selector.Matches(labelApi.Set(pod.Labels)) || !isOwnedByDeployment(&pod, app) {
+			continue
+		}
+		origins = append(origins, pod)
+	}
+	return origins, nil
+}
+
+// isOwnedByDeployment whether pod is created by replica set of the deployment, pods selected by accident are left alone
+func isOwnedByDeployment(pod *coreV1.Pod, app *appV1.Deployment) bool {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil || owner.Kind != "ReplicaSet" {
+		return false
+	}
+	rs, err := cluster.Ins().GetReplicaSet(owner.Name, pod.Namespace)
+	if err != nil || rs.UID != owner.UID {
+		return false
+	}
+	rsOwner := metav1.GetControllerOf(rs)
+	return rsOwner != nil && rsOwner.Kind == "Deployment" && rsOwner.UID == app.UID
+}
+
+// detachLabelKeys keys of labels used by selector of deployment and services
+func detachLabelKeys(app *appV1.Deployment, svcs []coreV1.Service) []string {
+	keys := make(map[string]string)
+	for k := range app.Spec.Selector.MatchLabels {
+		keys[k] = ""
+	}
+	for _, e := range app.Spec.Selector.MatchExpressions {
+		keys[e.Key] = ""
+	}
+	for _, svc := range svcs {
+		for k := range svc.Spec.Selector {
+			keys[k] = ""
+		}
+	}
+	return sortedKeys(keys)
+}
+
+// drainOriginPods remove detached origin pods after drain timeout
+func drainOriginPods(pods []string, timeout time.Duration) {
+	time.Sleep(timeout)
+	for _, pod := range pods {
+		var draining bool
+		opt.LockStore(func(store *opt.RuntimeStore) {
+			draining = util.Contains(strings.Split(store.OriginDraining, ","), pod)
+		})
+		if !draining {
+			continue
+		}
+		log.Info().Msgf("Drain timeout reached, removing origin pod %s", pod)
+		if err := cluster.Ins().RemovePod(pod, opt.Get().Global.Namespace); err != nil {
+			log.Warn().Err(err).Msgf("Failed to remove draining origin pod %s", pod)
+			continue
+		}
+		opt.LockStore(func(store *opt.RuntimeStore) {
+			store.OriginDraining = util.Remove(store.OriginDraining, pod)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster/fake"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	appV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func Test_detachOriginPods(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	app := &appV1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", UID: "app-uid"},
+		Spec: appV1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}},
+		},
+	}
+	isController := true
+	now := metav1.Now()
+	rs := &appV1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "app-abc", Namespace: "default", UID: "rs-uid",
+		OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "app", UID: "app-uid", Controller: &isController}}}}
+	ownedByRs := []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "app-abc", UID: "rs-uid", Controller: &isController}}
+	svcs := []coreV1.Service{{Spec: coreV1.ServiceSpec{Selector: map[string]string{"app": "demo", "tier": "web"}}}}
+	cluster.SetIns(fake.NewKubernetes(rs,
+		&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "default", OwnerReferences: ownedByRs,
+			Labels: map[string]string{"app": "demo", "tier": "web", "version": "v1"}}},
+		&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-2", Namespace: "default", OwnerReferences: ownedByRs,
+			DeletionTimestamp: &now, Labels: map[string]string{"app": "demo", "tier": "web"}}},
+		&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-kt-exchange", Namespace: "default",
+			Labels: map[string]string{"app": "demo", util.KtRole: util.RoleExchangeShadow}}},
+		&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default",
+			Labels: map[string]string{"app": "demo"}}},
+	))
+	defer cluster.SetIns(nil)
+	defer func() { opt.Store.OriginDraining = "" }()
+
+	origins, err := getOriginPods(app)
+	require.Nil(t, err)
+	require.Len(t, origins, 2, "only origin pods owned by deployment should be selected")
+	detached, err := detachOriginPods(app, svcs, origins)
+	require.Nil(t, err)
+	require.Equal(t, []string{"app-1"}, detached, "pod terminated by scaling down should not be detached")
+	require.Equal(t, "app-1", opt.Store.OriginDraining)
+	pod, err := cluster.Ins().GetPod("app-1", "default")
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{"version": "v1", util.ControlBy: util.KubernetesToolkit,
+		util.KtRole: util.RoleOriginDraining}, pod.Labels, "selected labels should be removed")
+	require.Empty(t, pod.OwnerReferences, "pod should be released from replica set")
+	require.NotEmpty(t, pod.Annotations[util.KtLastHeartBeat])
+
+	drainOriginPods(detached, 0)
+	_, err = cluster.Ins().GetPod("app-1", "default")
+	require.NotNil(t, err, "draining pod should be removed after timeout")
+	require.Empty(t, opt.Store.OriginDraining)
+}
+
+// replacingCluster create a new pod of replica set whenever an origin pod is released, as the controller would do
+type replacingCluster struct {
+	*fake.Kubernetes
+	owners   []metav1.OwnerReference
+	replaced int
+}
+
+func (k *replacingCluster) UpdatePod(pod *coreV1.Pod) (*coreV1.Pod, error) {
+	released := len(pod.OwnerReferences) == 0
+	updated, err := k.Kubernetes.UpdatePod(pod)
+	if err == nil && released {
+		k.replaced++
+		_, err = k.Clientset.CoreV1().Pods(pod.Namespace).Create(context.TODO(), &coreV1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("app-new-%d", k.replaced), Namespace: pod.Namespace, OwnerReferences: k.owners,
+			Labels: map[string]string{"app": "demo", "tier": "web"}}}, metav1.CreateOptions{})
+	}
+	return updated, err
+}
+
+func Test_scaleDownOrigin_gracefulCutover(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	opt.Get().Exchange.GracefulCutover = true
+	opt.Get().Exchange.DrainTimeout = 60
+	defer func() {
+		opt.Get().Exchange.GracefulCutover = false
+		opt.Get().Exchange.DrainTimeout = 0
+		opt.Store.OriginDraining = ""
+	}()
+	app, rs, owned := newOwnedOrigin(&metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}})
+	replicas := int32(2)
+	app.Spec.Replicas = &replicas
+	svcs := []coreV1.Service{{Spec: coreV1.ServiceSpec{Selector: map[string]string{"app": "demo", "tier": "web"}}}}
+	k := &replacingCluster{Kubernetes: fake.NewKubernetes(app, rs,
+		&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "default", OwnerReferences: owned,
+			Labels: map[string]string{"app": "demo", "tier": "web"}}},
+		&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-2", Namespace: "default", OwnerReferences: owned,
+			Labels: map[string]string{"app": "demo", "tier": "web"}}},
+	), owners: owned}
+	cluster.SetIns(k)
+	defer cluster.SetIns(nil)
+
+	// fake cluster removes pods on scaling down, origin pods would be lost if they were not detached before
+	draining, err := scaleDownOrigin(app, svcs, "app-kt-exchange-abcde")
+	require.Nil(t, err)
+	require.Equal(t, []string{"app-1", "app-2"}, draining)
+	for _, name := range draining {
+		pod, err2 := cluster.Ins().GetPod(name, "default")
+		require.Nil(t, err2, "detached pod should survive scaling down")
+		require.Equal(t, util.RoleOriginDraining, pod.Labels[util.KtRole])
+	}
+	require.Equal(t, 2, k.replaced)
+	pods, err := cluster.Ins().GetPodsByLabel(map[string]string{"app": "demo"}, "default")
+	require.Nil(t, err)
+	require.Empty(t, pods.Items, "replacement pods should be removed")
+	deployment, err := cluster.Ins().GetDeployment("app", "default")
+	require.Nil(t, err)
+	require.Equal(t, int32(0), *deployment.Spec.Replicas)
+}
+
+func Test_getOriginPods_emptySelector(t *testing.T) {
+	cluster.SetIns(fake.NewKubernetes(&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "any", Namespace: "default"}}))
+	defer cluster.SetIns(nil)
+	app := &appV1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec:       appV1.DeploymentSpec{Selector: &metav1.LabelSelector{}},
+	}
+	_, err := getOriginPods(app)
+	require.NotNil(t, err, "deployment with empty selector should not select every pod")
+}
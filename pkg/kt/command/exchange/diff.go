@@ -0,0 +1,189 @@
+package exchange
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/command/general"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	appV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"sort"
+)
+
+// Diff compare selector and ports of origin deployment and its services against running exchange shadows,
+// report mismatches which stop traffic from reaching the shadow
+func Diff(resourceName string) error {
+	namespace := opt.Get().Global.Namespace
+	resource, err := resolveResourceType(resourceName, namespace)
+	if err != nil {
+		return err
+	}
+	app, err := general.GetDeploymentByResourceName(resource, namespace)
+	if err != nil {
+		return err
+	}
+	services, err := cluster.Ins().GetAllServiceInNamespace(namespace)
+	if err != nil {
+		return err
+	}
+	var svcs []coreV1.Service
+	for _, svc := range services.Items {
+		if selector := originSelector(&svc); len(selector) > 0 && util.MapContains(selector, app.Spec.Template.Labels) {
+			svcs = append(svcs, svc)
+		}
+	}
+	shadows, err := getShadowsOf(app.Name, svcs, namespace)
+	if err != nil {
+		return err
+	}
+	if len(shadows) == 0 {
+		return fmt.Errorf("no exchange shadow of deployment %s found in namespace %s", app.Name, namespace)
+	}
+
+	found := 0
+	for _, shadow := range shadows {
+		mismatches, err2 := diffShadow(app, svcs, &shadow)
+		if err2 != nil {
+			return err2
+		}
+		if len(mismatches) == 0 {
+			log.Info().Msgf("Shadow %s matches deployment %s and its services", shadow.Name, app.Name)
+			continue
+		}
+		log.Info().Msgf("---- Mismatches of shadow %s ----", shadow.Name)
+		for _, m := range mismatches {
+			log.Warn().Msgf("> %s", m)
+		}
+		found += len(mismatches)
+	}
+	if found > 0 {
+		return fmt.Errorf("%d mismatches found, traffic may not reach the shadow", found)
+	}
+	return nil
+}
+
+// getShadowsOf find exchange shadow pods created for specified deployment or its services
+func getShadowsOf(appName string, svcs []coreV1.Service, namespace string) ([]coreV1.Pod, error) {
+	pods, err := cluster.Ins().GetPodsByLabel(map[string]string{
+		util.ControlBy: util.KubernetesToolkit,
+		util.KtRole:    util.RoleExchangeShadow,
+	}, namespace)
+	if err != nil {
+		return nil, err
+	}
+	var shadows []coreV1.Pod
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		config := util.String2Map(pod.Annotations[util.KtConfig])
+		if config["app"] == appName {
+			shadows = append(shadows, pod)
+			continue
+		}
+		for _, svc := range svcs {
+			if config["service"] == svc.Name {
+				shadows = append(shadows, pod)
+				break
+			}
+		}
+	}
+	return shadows, nil
+}
+
+// diffShadow list mismatches between shadow and what origin deployment and services expect
+func diffShadow(app *appV1.Deployment, svcs []coreV1.Service, shadow *coreV1.Pod) ([]string, error) {
+	var mismatches []string
+	config := util.String2Map(shadow.Annotations[util.KtConfig])
+	if config["app"] == app.Name {
+		// shadow of scale mode replaces pods of deployment, so it should carry the same labels
+		expected, err := getExchangeLabels(app)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range sortedKeys(expected) {
+			if m := diffLabel(key, expected[key], shadow.Labels); m != "" {
+				mismatches = append(mismatches, fmt.Sprintf("deployment %s selector requires %s", app.Name, m))
+			}
+		}
+	}
+	exposed := map[int32]bool{}
+	for _, c := range shadow.Spec.Containers {
+		for _, p := range c.Ports {
+			exposed[p.ContainerPort] = true
+		}
+	}
+	for _, svc := range svcs {
+		// shadow of selector mode only receives requests of the service it's created for
+		if config["service"] != "" && config["service"] != svc.Name {
+			continue
+		}
+		for _, key := range sortedKeys(svc.Spec.Selector) {
+			if m := diffLabel(key, svc.Spec.Selector[key], shadow.Labels); m != "" {
+				mismatches = append(mismatches, fmt.Sprintf("service %s selector requires %s", svc.Name, m))
+			}
+		}
+		for _, p := range svc.Spec.Ports {
+			if p.Protocol != "" && p.Protocol != coreV1.ProtocolTCP {
+				continue
+			}
+			targetPort := resolveTargetPort(p, &app.Spec.Template.Spec)
+			if targetPort > 0 && !exposed[targetPort] {
+				mismatches = append(mismatches, fmt.Sprintf("service %s port %d targets port %d, which is not exposed by shadow",
+					svc.Name, p.Port, targetPort))
+			}
+		}
+	}
+	return mismatches, nil
+}
+
+func diffLabel(key, value string, labels map[string]string) string {
+	if actual, exists := labels[key]; !exists {
+		return fmt.Sprintf("label %s=%s, but shadow doesn't have it", key, value)
+	} else if actual != value {
+		return fmt.Sprintf("label %s=%s, but shadow has %s=%s", key, value, key, actual)
+	}
+	return ""
+}
+
+// resolveTargetPort get number of service target port, named port is looked up in containers of origin pod,
+// return 0 if it cannot be resolved
+func resolveTargetPort(port coreV1.ServicePort, spec *coreV1.PodSpec) int32 {
+	if port.TargetPort.StrVal == "" {
+		if port.TargetPort.IntVal > 0 {
+			return port.TargetPort.IntVal
+		}
+		return port.Port
+	}
+	for _, c := range spec.Containers {
+		for _, p := range c.Ports {
+			if p.Name == port.TargetPort.StrVal {
+				return p.ContainerPort
+			}
+		}
+	}
+	return 0
+}
+
+// originSelector selector of service before changed by kt
+func originSelector(svc *coreV1.Service) map[string]string {
+	if text, exists := svc.Annotations[util.KtSelector]; exists {
+		var selector map[string]string
+		if err := json.Unmarshal([]byte(text), &selector); err == nil {
+			return selector
+		}
+	}
+	return svc.Spec.Selector
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,101 @@
+package exchange
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster/fake"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	appV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	labels := map[string]string{"app": "demo", "tier": "web"}
+	app := &appV1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "default"},
+		Spec: appV1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}},
+			Template: coreV1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: coreV1.PodSpec{Containers: []coreV1.Container{{
+					Name:  "demo",
+					Ports: []coreV1.ContainerPort{{Name: "http", ContainerPort: 8080}, {Name: "admin", ContainerPort: 9090}},
+				}}},
+			},
+		},
+	}
+	svc := &coreV1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "default"},
+		Spec: coreV1.ServiceSpec{
+			Selector: labels,
+			Ports: []coreV1.ServicePort{
+				{Port: 80, TargetPort: intstr.FromString("http")},
+				{Port: 9090, TargetPort: intstr.FromInt(9090)},
+			},
+		},
+	}
+	shadow := &coreV1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "demo-kt-exchange-abcde",
+			Namespace: "default",
+			Labels: map[string]string{util.ControlBy: util.KubernetesToolkit, util.KtRole: util.RoleExchangeShadow,
+				"app": "demo"},
+			Annotations: map[string]string{util.KtConfig: "app=demo,replicas=1"},
+		},
+		Spec: coreV1.PodSpec{Containers: []coreV1.Container{{
+			Name:  "shadow",
+			Ports: []coreV1.ContainerPort{{ContainerPort: 8080}},
+		}}},
+	}
+	cluster.SetIns(fake.NewKubernetes(app, svc, shadow))
+	defer cluster.SetIns(nil)
+
+	mismatches, err := diffShadow(app, []coreV1.Service{*svc}, shadow)
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		"service demo selector requires label tier=web, but shadow doesn't have it",
+		"service demo port 9090 targets port 9090, which is not exposed by shadow",
+	}, mismatches)
+	require.NotNil(t, Diff("demo"), "mismatches should be reported as error")
+
+	shadow.Labels["tier"] = "web"
+	shadow.Spec.Containers[0].Ports = append(shadow.Spec.Containers[0].Ports, coreV1.ContainerPort{ContainerPort: 9090})
+	cluster.SetIns(fake.NewKubernetes(app, svc, shadow))
+	require.Nil(t, Diff("deployment/demo"))
+}
+
+func Test_diffShadow_selectorMode(t *testing.T) {
+	app := &appV1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "default"}}
+	svcs := []coreV1.Service{{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "default"},
+		Spec: coreV1.ServiceSpec{
+			Selector: map[string]string{util.KtTarget: "abc"},
+			Ports:    []coreV1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}, {
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-admin", Namespace: "default"},
+		Spec: coreV1.ServiceSpec{
+			Selector: map[string]string{"app": "demo"},
+			Ports:    []coreV1.ServicePort{{Port: 9090}},
+		},
+	}}
+	shadow := &coreV1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "demo-kt-exchange-abcde",
+			Labels:      map[string]string{util.KtTarget: "abc"},
+			Annotations: map[string]string{util.KtConfig: "service=demo"},
+		},
+		Spec: coreV1.PodSpec{Containers: []coreV1.Container{{
+			Name:  "shadow",
+			Ports: []coreV1.ContainerPort{{ContainerPort: 8080}},
+		}}},
+	}
+	mismatches, err := diffShadow(app, svcs, shadow)
+	require.Nil(t, err)
+	require.Empty(t, mismatches, "services other than the exchanged one should not be compared")
+}
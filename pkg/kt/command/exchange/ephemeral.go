@@ -2,7 +2,7 @@ package exchange
 
 import (
 	"fmt"
-	"github.com/alibaba/kt-connect/pkg/kt/command/general"
+	"github.com/alibaba/kt-connect/pkg/common"
 	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
 	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
 	"github.com/alibaba/kt-connect/pkg/kt/service/sshchannel"
@@ -10,22 +10,63 @@ import (
 	"github.com/alibaba/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
 	coreV1 "k8s.io/api/core/v1"
+	"net"
 	"strconv"
 	"strings"
-	"time"
 )
 
-func ByEphemeralContainer(resourceName string) error {
-	log.Warn().Msgf("Experimental feature. It just works on kubernetes above v1.23, and it can NOT work with istio.")
+func ByEphemeralContainer(resourceName, expose string) error {
+	log.Warn().Msgf("Experimental feature. It just works on kubernetes above v1.23.")
 
+	ports, err := parseEphemeralPorts(expose)
+	if err != nil {
+		return err
+	}
 	pods, err := getPodsOfResource(resourceName, opt.Get().Global.Namespace)
+	if err != nil {
+		return err
+	}
+	// check before any pod is changed, istio sidecar intercepts inbound traffic ahead of ephemeral container
+	for _, pod := range pods {
+		if pod.Status.Phase == coreV1.PodRunning && hasIstioSidecar(&pod) {
+			return fmt.Errorf("pod %s has istio sidecar, which is not supported by ephemeral mode, "+
+				"please use '--mode %s' or '--mode %s' instead", pod.Name, util.ExchangeModeSelector, util.ExchangeModeScale)
+		}
+	}
+	if opt.Get().Exchange.ExcludeContainer != "" && len(pods) > 0 {
+		if ports, err = excludeContainerPorts(ports, opt.Get().Exchange.ExcludeContainer, &pods[0].Spec); err != nil {
+			return err
+		}
+	}
+	if tcpPorts := util.FilterExposeByProtocol(expose, util.ProtocolTcp); len(pods) > 0 && tcpPorts != "" {
+		if err = CheckDeclaredPorts(resourceName, tcpPorts, &pods[0].Spec); err != nil {
+			return err
+		}
+	}
+
+	if len(pods) > 0 {
+		// ports of origin pods are hijacked, requests to every service selecting them are redirected
+		if svcs, err2 := cluster.Ins().GetServicesBySelector(pods[0].Labels, opt.Get().Global.Namespace); err2 == nil {
+			logRoutedServices(svcs, pods[0].Labels)
+		}
+	}
 
 	for _, pod := range pods {
 		if pod.Status.Phase != coreV1.PodRunning {
 			log.Warn().Msgf("Pod %s is not running (%s), will not be exchanged", pod.Name, pod.Status.Phase)
 			continue
 		}
-		privateKey, err2 := createEphemeralContainer(util.KtExchangeContainer, pod.Name)
+		if cluster.Ins().GetPodOs(&pod.Spec) == util.OsWindows {
+			return fmt.Errorf("pod %s is running on windows node, which is not supported by ephemeral mode, "+
+				"please use '--mode %s' or '--mode %s' instead", pod.Name, util.ExchangeModeSelector, util.ExchangeModeScale)
+		}
+		targetContainer := ""
+		if opt.Get().Exchange.ShareProcessNs {
+			targetContainer = getTargetContainer(ports, &pod.Spec)
+			log.Warn().Msgf("Ephemeral container will share process namespace of container %s, its processes, "+
+				"environment variables and files (via /proc) are visible to the ephemeral container", targetContainer)
+		}
+		privateKey, err2 := createEphemeralContainer(util.KtExchangeContainer, pod.Name, targetContainer)
 		if err2 != nil {
 			return err2
 		}
@@ -33,80 +74,57 @@ func ByEphemeralContainer(resourceName string) error {
 		// record data
 		opt.Store.Shadow = util.Append(opt.Store.Shadow, pod.Name)
 
-		localSSHPort, err2 := transmission.ForwardPodToLocal(opt.Get().Exchange.Expose, pod.Name, privateKey)
+		log.Info().Msgf("Forwarding pod %s to local via port %s", pod.Name, expose)
+		localSSHPort, err2 := transmission.ForwardSshPortToLocal(pod.Name)
 		if err2 != nil {
 			return err2
 		}
-		err = exchangeWithEphemeralContainer(opt.Get().Exchange.Expose, localSSHPort, privateKey)
+		err = exchangeWithEphemeralContainer(pod.Name, ports, localSSHPort, privateKey)
 		if err != nil {
 			return err
 		}
+		if opt.Get().Exchange.PrintRules {
+			printRedirectRules(pod.Name)
+		}
 	}
 	return nil
 }
 
-
-func getPodsOfResource(resourceName, namespace string) ([]coreV1.Pod, error) {
-	resourceType, name, err := general.ParseResourceName(resourceName)
-	if err != nil {
-		return nil, err
+// hasIstioSidecar check whether istio sidecar is injected to the pod
+func hasIstioSidecar(pod *coreV1.Pod) bool {
+	if _, exists := pod.Annotations[util.IstioSidecarStatus]; exists {
+		return true
 	}
-
-	switch resourceType {
-	case "pod":
-		pod, err := cluster.Ins().GetPod(name, namespace)
-		if err != nil {
-			return nil, err
-		} else {
-			return []coreV1.Pod{*pod}, nil
+	for _, c := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		if c.Name == util.IstioSidecarContainer {
+			return true
 		}
-	case "svc":
-		fallthrough
-	case "service":
-		return getPodsOfService(name, namespace)
 	}
-	return nil, fmt.Errorf("invalid resource type: %s", resourceType)
+	return false
 }
 
-func getPodsOfService(serviceName, namespace string) ([]coreV1.Pod, error) {
-	svc, err := cluster.Ins().GetService(serviceName, namespace)
-	if err != nil {
-		return nil, err
-	}
-	pods, err := cluster.Ins().GetPodsByLabel(svc.Spec.Selector, namespace)
-	if err != nil {
-		return nil, err
-	}
-	return pods.Items, nil
-}
-
-func createEphemeralContainer(containerName, podName string) (string, error) {
+func createEphemeralContainer(containerName, podName, targetContainer string) (string, error) {
 	log.Info().Msgf("Adding ephemeral container for pod %s", podName)
 
 	envs := make(map[string]string)
-	privateKey, err := cluster.Ins().AddEphemeralContainer(containerName, podName, envs)
+	privateKey, err := cluster.Ins().AddEphemeralContainer(containerName, podName, targetContainer, envs)
 	if err != nil {
 		return "", err
 	}
 
-	for i := 0; i < 10; i++ {
-		log.Info().Msgf("Waiting for ephemeral container %s to be ready", containerName)
-		ready, err2 := isEphemeralContainerReady(containerName, podName, opt.Get().Global.Namespace)
-		if err2 != nil {
-			return "", err2
-		} else if ready {
-			break
-		}
-		time.Sleep(5 * time.Second)
+	_, err = cluster.WaitPod(fmt.Sprintf("ephemeral container %s", containerName), opt.Get().Global.PodCreationTimeout,
+		func() (*coreV1.Pod, error) {
+			return cluster.Ins().GetPod(podName, opt.Get().Global.Namespace)
+		}, func(pod *coreV1.Pod) (bool, error) {
+			return isEphemeralContainerReady(pod, containerName)
+		})
+	if err != nil {
+		return "", err
 	}
 	return privateKey, nil
 }
 
-func isEphemeralContainerReady(podName, containerName, namespace string) (bool, error) {
-	pod, err := cluster.Ins().GetPod(podName, namespace)
-	if err != nil {
-		return false, err
-	}
+func isEphemeralContainerReady(pod *coreV1.Pod, containerName string) (bool, error) {
 	cStats := pod.Status.EphemeralContainerStatuses
 	for i := range cStats {
 		if cStats[i].Name == containerName {
@@ -121,40 +139,61 @@ func isEphemeralContainerReady(podName, containerName, namespace string) (bool,
 	return false, nil
 }
 
-func exchangeWithEphemeralContainer(exposePorts string, localSSHPort int, privateKey string) error {
+func exchangeWithEphemeralContainer(podName string, ports []ephemeralPort, localSSHPort int, privateKey string) error {
 	// Get all listened ports on remote host
 	listenedPorts, err := getListenedPorts(localSSHPort, privateKey)
 	if err != nil {
 		return err
 	}
 
-	redirectPorts, err := remoteRedirectPort(exposePorts, listenedPorts)
+	rules, err := remoteRedirectPort(ports, listenedPorts)
 	if err != nil {
 		return err
 	}
-	var redirectPortStr string
-	for k, v := range redirectPorts {
-		redirectPortStr += fmt.Sprintf("%d:%d,", k, v)
+	var redirectRules []string
+	for _, r := range rules {
+		redirectRules = append(redirectRules, fmt.Sprintf("%s:%d:%d", r.protocol, r.remotePort, r.redirectPort))
 	}
-	redirectPortStr = redirectPortStr[:len(redirectPortStr)-1]
-	err = setupIptables(redirectPortStr, localSSHPort, privateKey)
+	err = setupIptables(strings.Join(redirectRules, ","), localSSHPort, privateKey)
 	if err != nil {
 		return err
 	}
 
-	if err = transmission.ForwardRemotePortsViaSshTunnel(exposePorts, localSSHPort, privateKey); err != nil {
+	// tcp requests are forwarded from redirect port directly, udp packets are carried by a tcp tunnel via relays
+	var tunnelPorts []string
+	for _, r := range rules {
+		if r.protocol == util.ProtocolUdp {
+			relayPort, err2 := startLocalUdpRelay(r.localPort)
+			if err2 != nil {
+				return err2
+			}
+			tunnelPorts = append(tunnelPorts, fmt.Sprintf("%d:%d", relayPort, r.tunnelPort))
+		} else {
+			tunnelPorts = append(tunnelPorts, fmt.Sprintf("%d:%d", r.localPort, r.redirectPort))
+		}
+	}
+	if err = transmission.ForwardRemotePortsViaSshTunnel(strings.Join(tunnelPorts, ","), localSSHPort, privateKey); err != nil {
 		return err
 	}
-
+	for _, r := range rules {
+		if r.protocol == util.ProtocolUdp {
+			if err = startRemoteUdpRelay(podName, r, localSSHPort, privateKey); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
-
-func setupIptables(redirectPorts string, localSSHPort int, privateKey string) error {
+func setupIptables(redirectRules string, localSSHPort int, privateKey string) error {
+	listenAddress, err := transmission.GetListenAddress()
+	if err != nil {
+		return err
+	}
 	res, err := sshchannel.Ins().RunScript(
 		privateKey,
-		fmt.Sprintf("127.0.0.1:%d", localSSHPort),
-		fmt.Sprintf("/setup_iptables.sh %s", redirectPorts))
+		net.JoinHostPort(listenAddress, strconv.Itoa(localSSHPort)),
+		fmt.Sprintf("/setup_iptables.sh %s", redirectRules))
 
 	if err != nil {
 		log.Error().Err(err).Msgf("Setup iptables failed, error")
@@ -164,10 +203,92 @@ func setupIptables(redirectPorts string, localSSHPort int, privateKey string) er
 	return err
 }
 
+// printRedirectRules show iptables rules actually installed in ephemeral container, for verifying hijacked ports
+func printRedirectRules(podName string) {
+	rules, err := getRedirectRules(podName)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to fetch redirect rules of pod %s", podName)
+		return
+	}
+	log.Info().Msgf("---- Redirect rules of pod %s ----", podName)
+	for _, r := range rules {
+		log.Info().Msgf("> %s", r)
+	}
+	if len(rules) == 0 {
+		log.Warn().Msgf("No redirect rule found, traffic of pod %s is not hijacked", podName)
+	}
+}
+
+func getRedirectRules(podName string) ([]string, error) {
+	stdout, stderr, err := cluster.Ins().ExecInPod(util.KtExchangeContainer, podName, opt.Get().Global.Namespace,
+		"iptables", "-t", "nat", "-S", "PREROUTING")
+	if err != nil {
+		return nil, fmt.Errorf("%s, %s", err, stderr)
+	}
+	var rules []string
+	for _, line := range strings.Split(stdout, "\n") {
+		if strings.Contains(line, "REDIRECT") {
+			rules = append(rules, strings.TrimSpace(line))
+		}
+	}
+	return rules, nil
+}
+
+// startLocalUdpRelay listen on a random local port, and send packets carried by incoming tcp connections to local udp port
+func startLocalUdpRelay(localPort int) (int, error) {
+	listenAddress, err := transmission.GetListenAddress()
+	if err != nil {
+		return -1, err
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:0", listenAddress))
+	if err != nil {
+		return -1, err
+	}
+	go func() {
+		if err2 := common.RelayTcpToUdp(listener, net.JoinHostPort(listenAddress, strconv.Itoa(localPort))); err2 != nil {
+			log.Debug().Err(err2).Msgf("Local udp relay of port %d stopped", localPort)
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// startRemoteUdpRelay run relay in ephemeral container, which send udp packets of redirect port to the tcp tunnel,
+// relay left by previous exchange of the same port is stopped first, pid of new relay is recorded for teardown
+func startRemoteUdpRelay(podName string, rule redirectRule, localSSHPort int, privateKey string) error {
+	listenAddress, err := transmission.GetListenAddress()
+	if err != nil {
+		return err
+	}
+	out, err := sshchannel.Ins().RunScript(
+		privateKey,
+		net.JoinHostPort(listenAddress, strconv.Itoa(localSSHPort)),
+		getUdpRelayScript(rule))
+	if err != nil {
+		return fmt.Errorf("failed to start udp relay for port %d: %s", rule.remotePort, err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(out))
+	if err != nil {
+		log.Warn().Msgf("Failed to get pid of udp relay for port %d, got '%s'", rule.remotePort, out)
+		return nil
+	}
+	opt.Store.UdpRelay = util.Append(opt.Store.UdpRelay, fmt.Sprintf("%s:%d", podName, pid))
+	return nil
+}
+
+func getUdpRelayScript(rule redirectRule) string {
+	return fmt.Sprintf("if [ -f %[1]s ]; then kill $(cat %[1]s) 2>/dev/null; fi; "+
+		"nohup /usr/sbin/navigator udp-relay %[2]d %[3]d > /dev/null 2>&1 & echo $! > %[1]s; cat %[1]s",
+		fmt.Sprintf(util.UdpRelayPidFile, rule.redirectPort), rule.redirectPort, rule.tunnelPort)
+}
+
 func getListenedPorts(localSSHPort int, privateKey string) (map[int]struct{}, error) {
+	listenAddress, err := transmission.GetListenAddress()
+	if err != nil {
+		return nil, err
+	}
 	result, err := sshchannel.Ins().RunScript(
 		privateKey,
-		fmt.Sprintf("127.0.0.1:%d", localSSHPort),
+		net.JoinHostPort(listenAddress, strconv.Itoa(localSSHPort)),
 		`netstat -tuln | grep -E '^(tcp|udp|tcp6)' | grep LISTEN | awk '{print $4}' | awk -F: '{printf("%s\n", $NF)}'`)
 
 	if err != nil {
@@ -194,31 +315,124 @@ func getListenedPorts(localSSHPort int, privateKey string) (map[int]struct{}, er
 	return listenedPorts, nil
 }
 
-func remoteRedirectPort(exposePorts string, listenedPorts map[int]struct{}) (map[int]int, error) {
-	portPairs := strings.Split(exposePorts, ",")
-	redirectPort := make(map[int]int)
-	for _, exposePort := range portPairs {
-		_, remotePort, err := util.ParsePortMapping(exposePort)
+// ephemeralPort port to expose in ephemeral mode
+type ephemeralPort struct {
+	protocol   string
+	localPort  int
+	remotePort int
+}
+
+// redirectRule iptables rule which redirects traffic of pod port to a port served by kt
+type redirectRule struct {
+	ephemeralPort
+	redirectPort int
+	// tunnelPort port of tcp tunnel carrying udp packets, only used by udp rules
+	tunnelPort int
+}
+
+// parseEphemeralPorts parse expose ports with optional protocol suffix, e.g. '8080,5353:53/udp',
+// the same port number is allowed to be used by tcp and udp at the same time
+func parseEphemeralPorts(expose string) ([]ephemeralPort, error) {
+	var ports []ephemeralPort
+	exposed := make(map[string]bool)
+	for _, exposePort := range strings.Split(expose, ",") {
+		portMapping, protocol, err := util.SplitExposeProtocol(exposePort)
+		if err != nil {
+			return nil, err
+		}
+		localPort, remotePort, err := util.ParsePortMapping(portMapping)
 		if err != nil {
 			return nil, err
 		}
-		port := randPort(listenedPorts)
-		if port == -1 {
-			return nil, fmt.Errorf("failed to find redirect port for port: %d", remotePort)
+		key := fmt.Sprintf("%d/%s", remotePort, protocol)
+		if exposed[key] {
+			return nil, fmt.Errorf("remote port %s is exposed more than once", key)
+		}
+		exposed[key] = true
+		ports = append(ports, ephemeralPort{protocol: protocol, localPort: localPort, remotePort: remotePort})
+	}
+	return ports, nil
+}
+
+// getTargetContainer find the container declaring first exposed port, or the first container if none of them
+// is declared, whose process namespace is shared with ephemeral container
+func getTargetContainer(ports []ephemeralPort, spec *coreV1.PodSpec) string {
+	for _, p := range ports {
+		for _, c := range spec.Containers {
+			for _, cp := range c.Ports {
+				if int(cp.ContainerPort) == p.remotePort {
+					return c.Name
+				}
+			}
+		}
+	}
+	if len(spec.Containers) == 0 {
+		return ""
+	}
+	return spec.Containers[0].Name
+}
+
+// excludeContainerPorts remove ports declared by excluded containers, so that traffic to them is not hijacked
+func excludeContainerPorts(ports []ephemeralPort, excludeContainers string, spec *coreV1.PodSpec) ([]ephemeralPort, error) {
+	portOwner := make(map[string]string)
+	for _, name := range strings.Split(excludeContainers, ",") {
+		found := false
+		for _, c := range spec.Containers {
+			if c.Name != name {
+				continue
+			}
+			found = true
+			for _, p := range c.Ports {
+				protocol := util.ProtocolTcp
+				if p.Protocol == coreV1.ProtocolUDP {
+					protocol = util.ProtocolUdp
+				}
+				portOwner[fmt.Sprintf("%d/%s", p.ContainerPort, protocol)] = name
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("container '%s' to exclude is not found in pod", name)
 		}
-		redirectPort[remotePort] = port
 	}
+	var remainPorts []ephemeralPort
+	for _, p := range ports {
+		if owner, exists := portOwner[fmt.Sprintf("%d/%s", p.remotePort, p.protocol)]; exists {
+			log.Info().Msgf("Port %d/%s is declared by excluded container %s, skipped", p.remotePort, p.protocol, owner)
+			continue
+		}
+		remainPorts = append(remainPorts, p)
+	}
+	if len(remainPorts) == 0 {
+		return nil, fmt.Errorf("all exposed ports are declared by excluded containers, nothing to exchange")
+	}
+	return remainPorts, nil
+}
 
-	return redirectPort, nil
+func remoteRedirectPort(ports []ephemeralPort, listenedPorts map[int]struct{}) ([]redirectRule, error) {
+	var rules []redirectRule
+	for _, p := range ports {
+		rule := redirectRule{ephemeralPort: p}
+		if rule.redirectPort = randPort(listenedPorts); rule.redirectPort == -1 {
+			return nil, fmt.Errorf("failed to find redirect port for port: %d/%s", p.remotePort, p.protocol)
+		}
+		if p.protocol == util.ProtocolUdp {
+			if rule.tunnelPort = randPort(listenedPorts); rule.tunnelPort == -1 {
+				return nil, fmt.Errorf("failed to find tunnel port for port: %d/%s", p.remotePort, p.protocol)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
 }
 
 func randPort(listenedPorts map[int]struct{}) int {
 	for i := 0; i < 100; i++ {
 		port := util.RandomPort()
 		if _, exists := listenedPorts[port]; !exists {
+			// avoid being picked again by another rule
+			listenedPorts[port] = struct{}{}
 			return port
 		}
 	}
 	return -1
 }
-
@@ -0,0 +1,188 @@
+package exchange
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster/fake"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func Test_createEphemeralContainer(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	cluster.SetIns(fake.NewKubernetes(&coreV1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "default"},
+		Status:     coreV1.PodStatus{Phase: coreV1.PodRunning},
+	}))
+	defer cluster.SetIns(nil)
+
+	privateKey, err := createEphemeralContainer(util.KtExchangeContainer, "app-1", "")
+	require.Nil(t, err)
+	require.Equal(t, util.PrivateKeyPath("app-1"), privateKey)
+	pod, err := cluster.Ins().GetPod("app-1", "default")
+	require.Nil(t, err)
+	ready, err := isEphemeralContainerReady(pod, util.KtExchangeContainer)
+	require.Nil(t, err)
+	require.True(t, ready)
+}
+
+func TestByEphemeralContainer(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	cluster.SetIns(fake.NewKubernetes(&coreV1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "default"},
+		Status:     coreV1.PodStatus{Phase: coreV1.PodPending},
+	}))
+	defer cluster.SetIns(nil)
+
+	shadows := opt.Store.Shadow
+	require.Nil(t, ByEphemeralContainer("pod/app-1", "8080"))
+	require.Equal(t, shadows, opt.Store.Shadow, "pod not running should be skipped")
+	pod, err := cluster.Ins().GetPod("app-1", "default")
+	require.Nil(t, err)
+	require.Empty(t, pod.Spec.EphemeralContainers)
+}
+
+func Test_hasIstioSidecar(t *testing.T) {
+	require.False(t, hasIstioSidecar(&coreV1.Pod{Spec: coreV1.PodSpec{Containers: []coreV1.Container{{Name: "app"}}}}))
+	require.True(t, hasIstioSidecar(&coreV1.Pod{Spec: coreV1.PodSpec{Containers: []coreV1.Container{
+		{Name: "app"}, {Name: util.IstioSidecarContainer},
+	}}}))
+	require.True(t, hasIstioSidecar(&coreV1.Pod{Spec: coreV1.PodSpec{InitContainers: []coreV1.Container{
+		{Name: util.IstioSidecarContainer},
+	}}}))
+	require.True(t, hasIstioSidecar(&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{util.IstioSidecarStatus: "{}"},
+	}}))
+}
+
+func TestByEphemeralContainer_istio(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	cluster.SetIns(fake.NewKubernetes(&coreV1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "default"},
+		Spec:       coreV1.PodSpec{Containers: []coreV1.Container{{Name: "app"}, {Name: util.IstioSidecarContainer}}},
+		Status:     coreV1.PodStatus{Phase: coreV1.PodRunning},
+	}))
+	defer cluster.SetIns(nil)
+
+	require.NotNil(t, ByEphemeralContainer("pod/app-1", "8080"), "pod with istio sidecar should fail")
+	pod, err := cluster.Ins().GetPod("app-1", "default")
+	require.Nil(t, err)
+	require.Empty(t, pod.Spec.EphemeralContainers)
+}
+
+func Test_parseEphemeralPorts(t *testing.T) {
+	ports, err := parseEphemeralPorts("8080,5353:53/udp,53/tcp")
+	require.Nil(t, err)
+	require.Equal(t, []ephemeralPort{
+		{protocol: util.ProtocolTcp, localPort: 8080, remotePort: 8080},
+		{protocol: util.ProtocolUdp, localPort: 5353, remotePort: 53},
+		{protocol: util.ProtocolTcp, localPort: 53, remotePort: 53},
+	}, ports, "same port of different protocols should be allowed")
+	_, err = parseEphemeralPorts("8080,9090:8080/tcp")
+	require.NotNil(t, err, "same port of same protocol should fail")
+	_, err = parseEphemeralPorts("53/udp,5353:53/udp")
+	require.NotNil(t, err, "same port of same protocol should fail")
+	_, err = parseEphemeralPorts("53/sctp")
+	require.NotNil(t, err, "unsupported protocol should fail")
+}
+
+func Test_excludeContainerPorts(t *testing.T) {
+	spec := &coreV1.PodSpec{Containers: []coreV1.Container{
+		{Name: "app", Ports: []coreV1.ContainerPort{{ContainerPort: 8080}}},
+		{Name: "log-agent", Ports: []coreV1.ContainerPort{{ContainerPort: 9090}, {ContainerPort: 53, Protocol: coreV1.ProtocolUDP}}},
+	}}
+	ports, err := parseEphemeralPorts("8080,9090,53/tcp,53/udp")
+	require.Nil(t, err)
+	remain, err := excludeContainerPorts(ports, "log-agent", spec)
+	require.Nil(t, err)
+	require.Equal(t, []ephemeralPort{
+		{protocol: util.ProtocolTcp, localPort: 8080, remotePort: 8080},
+		{protocol: util.ProtocolTcp, localPort: 53, remotePort: 53},
+	}, remain, "only ports of same protocol declared by excluded container should be removed")
+	_, err = excludeContainerPorts(ports, "not-exist", spec)
+	require.NotNil(t, err, "unknown container should fail")
+	_, err = excludeContainerPorts(ports[1:2], "log-agent", spec)
+	require.NotNil(t, err, "nothing left to exchange should fail")
+}
+
+func Test_getTargetContainer(t *testing.T) {
+	spec := &coreV1.PodSpec{Containers: []coreV1.Container{
+		{Name: "log-agent", Ports: []coreV1.ContainerPort{{ContainerPort: 9090}}},
+		{Name: "app", Ports: []coreV1.ContainerPort{{ContainerPort: 8080}}},
+	}}
+	ports, err := parseEphemeralPorts("8080,9090")
+	require.Nil(t, err)
+	require.Equal(t, "app", getTargetContainer(ports, spec))
+	ports, err = parseEphemeralPorts("7070")
+	require.Nil(t, err)
+	require.Equal(t, "log-agent", getTargetContainer(ports, spec), "first container is used if port not declared")
+}
+
+func Test_remoteRedirectPort(t *testing.T) {
+	ports, err := parseEphemeralPorts("8080,5353:53/udp,53")
+	require.Nil(t, err)
+	rules, err := remoteRedirectPort(ports, map[int]struct{}{8080: {}, 53: {}})
+	require.Nil(t, err)
+	require.Len(t, rules, 3)
+	used := map[int]bool{8080: true, 53: true}
+	for _, r := range rules {
+		require.False(t, used[r.redirectPort], "redirect port should not conflict")
+		used[r.redirectPort] = true
+		if r.protocol == util.ProtocolUdp {
+			require.False(t, used[r.tunnelPort], "tunnel port should not conflict")
+			used[r.tunnelPort] = true
+		} else {
+			require.Zero(t, r.tunnelPort)
+		}
+	}
+	require.Equal(t, util.ProtocolUdp, rules[1].protocol)
+	require.Equal(t, 53, rules[1].remotePort)
+	require.Equal(t, 5353, rules[1].localPort)
+}
+
+func TestByEphemeralContainer_mixedProtocols(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	cluster.SetIns(fake.NewKubernetes(&coreV1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "default"},
+		Spec: coreV1.PodSpec{Containers: []coreV1.Container{{Name: "app", Ports: []coreV1.ContainerPort{
+			{ContainerPort: 53, Protocol: coreV1.ProtocolTCP}, {ContainerPort: 53, Protocol: coreV1.ProtocolUDP},
+		}}}},
+		Status: coreV1.PodStatus{Phase: coreV1.PodPending},
+	}))
+	defer cluster.SetIns(nil)
+
+	opt.Get().Exchange.StrictPorts = true
+	defer func() { opt.Get().Exchange.StrictPorts = false }()
+	require.Nil(t, ByEphemeralContainer("pod/app-1", "53,5353:53/udp"), "udp port should not be checked as tcp")
+	require.NotNil(t, ByEphemeralContainer("pod/app-1", "53,5353:53/tcp"), "conflict ports should fail")
+}
+
+func Test_getRedirectRules(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	k := fake.NewKubernetes()
+	k.ExecHandler = func(containerName, podName, namespace string, cmd ...string) (string, string, error) {
+		require.Equal(t, util.KtExchangeContainer, containerName)
+		return "-P PREROUTING ACCEPT\n" +
+			"-A PREROUTING -p tcp -m tcp --dport 8080 -j REDIRECT --to-ports 38080\n" +
+			"-A PREROUTING -p udp -m udp --dport 53 -j REDIRECT --to-ports 30053\n", "", nil
+	}
+	cluster.SetIns(k)
+	defer cluster.SetIns(nil)
+
+	rules, err := getRedirectRules("app-1")
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		"-A PREROUTING -p tcp -m tcp --dport 8080 -j REDIRECT --to-ports 38080",
+		"-A PREROUTING -p udp -m udp --dport 53 -j REDIRECT --to-ports 30053",
+	}, rules)
+}
+
+func Test_getUdpRelayScript(t *testing.T) {
+	script := getUdpRelayScript(redirectRule{redirectPort: 40053, tunnelPort: 40054})
+	require.Equal(t, "if [ -f /tmp/kt-udp-relay-40053.pid ]; then kill $(cat /tmp/kt-udp-relay-40053.pid) 2>/dev/null; fi; "+
+		"nohup /usr/sbin/navigator udp-relay 40053 40054 > /dev/null 2>&1 & "+
+		"echo $! > /tmp/kt-udp-relay-40053.pid; cat /tmp/kt-udp-relay-40053.pid", script)
+}
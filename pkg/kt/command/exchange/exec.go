@@ -0,0 +1,37 @@
+package exchange
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+)
+
+// RunExec start local service with command specified by '--exec', lifecycle signals are sent to it,
+// and exchange exits when the command finished
+func RunExec(command string, ch chan os.Signal) error {
+	cmd := exec.Command("sh", "-c", command)
+	if util.IsWindows() {
+		cmd = exec.Command("cmd", "/C", command)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	opt.Store.ExecProcess = cmd.Process
+	log.Info().Msgf("Command '%s' started with pid %d", command, cmd.Process.Pid)
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			log.Warn().Msgf("Command '%s' exited: %s", command, err)
+		} else {
+			log.Info().Msgf("Command '%s' finished", command)
+		}
+		ch <- syscall.SIGTERM
+	}()
+	return nil
+}
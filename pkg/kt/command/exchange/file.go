@@ -0,0 +1,294 @@
+package exchange
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// File structure of exchange config file, both yaml and json format are accepted
+type File struct {
+	Mode    yaml.Node            `yaml:"mode"`
+	Targets []FileTarget         `yaml:"targets"`
+	Options map[string]yaml.Node `yaml:"options"`
+}
+
+// FileTarget resource to exchange and its ports
+type FileTarget struct {
+	Resource string     `yaml:"resource"`
+	Ports    []FilePort `yaml:"ports"`
+	line     int
+}
+
+// FilePort port to expose, can be written as '8080', '8080:80' or a mapping
+type FilePort struct {
+	Local    int    `yaml:"local"`
+	Remote   int    `yaml:"remote"`
+	Protocol string `yaml:"protocol"`
+	Name     string `yaml:"name"`
+}
+
+var lineNumberPattern = regexp.MustCompile(`line (\d+)`)
+
+// UnmarshalYAML record line number of target
+func (t *FileTarget) UnmarshalYAML(node *yaml.Node) error {
+	type plain FileTarget
+	if err := node.Decode((*plain)(t)); err != nil {
+		return err
+	}
+	t.line = node.Line
+	return nil
+}
+
+// UnmarshalYAML parse port in either scalar or mapping format
+func (p *FilePort) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		parts := strings.Split(node.Value, ":")
+		if len(parts) > 2 {
+			return lineError(node.Line, "invalid port '%s'", node.Value)
+		}
+		var err error
+		if p.Local, err = strconv.Atoi(parts[0]); err != nil {
+			return lineError(node.Line, "invalid port '%s'", node.Value)
+		}
+		p.Remote = p.Local
+		if len(parts) == 2 {
+			if p.Remote, err = strconv.Atoi(parts[1]); err != nil {
+				return lineError(node.Line, "invalid port '%s'", node.Value)
+			}
+		}
+	} else {
+		type plain FilePort
+		if err := node.Decode((*plain)(p)); err != nil {
+			return err
+		}
+		if p.Remote == 0 {
+			p.Remote = p.Local
+		}
+	}
+	if p.Local <= 0 || p.Local > 65535 || p.Remote <= 0 || p.Remote > 65535 {
+		return lineError(node.Line, "port number must be between 1 and 65535")
+	}
+	if p.Protocol != "" && strings.ToLower(p.Protocol) != "tcp" {
+		return lineError(node.Line, "protocol '%s' is not supported, only tcp port can be exchanged", p.Protocol)
+	}
+	return nil
+}
+
+// LoadFile read exchange config file, apply its options to flags not specified in command line,
+// and return targets in '<resource>:<ports>' format
+func LoadFile(path string, flags *flag.FlagSet) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exchange file %s: %s", path, err)
+	}
+	file, err := parseFile(data, flags)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exchange file %s, %s", path, withLineContext(data, err))
+	}
+	args := make([]string, 0)
+	for _, t := range file.Targets {
+		ports := make([]string, 0)
+		for _, p := range t.Ports {
+			ports = append(ports, fmt.Sprintf("%d:%d", p.Local, p.Remote))
+			if p.Name != "" {
+				log.Debug().Msgf("Port %s of %s maps to local port %d", p.Name, t.Resource, p.Local)
+			}
+		}
+		args = append(args, t.Resource+":"+strings.Join(ports, ","))
+	}
+	return args, nil
+}
+
+func parseFile(data []byte, flags *flag.FlagSet) (*File, error) {
+	root := &yaml.Node{}
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(root); err != nil {
+		return nil, err
+	}
+	if err := validateSchema(root); err != nil {
+		return nil, err
+	}
+	file := &File{}
+	if err := root.Decode(file); err != nil {
+		return nil, err
+	}
+	if len(file.Targets) == 0 {
+		return nil, fmt.Errorf("at least one target is required")
+	}
+	for _, t := range file.Targets {
+		if t.Resource == "" {
+			return nil, lineError(t.line, "resource of target is required")
+		} else if len(t.Ports) == 0 {
+			return nil, lineError(t.line, "no port specified for '%s'", t.Resource)
+		}
+	}
+	if file.Mode.Value != "" {
+		if err := setFlag("mode", file.Mode.Value, file.Mode.Line, flags); err != nil {
+			return nil, err
+		}
+	}
+	for key, value := range file.Options {
+		if value.Kind != yaml.ScalarNode {
+			return nil, lineError(value.Line, "value of option '%s' must be a scalar", key)
+		}
+		if err := setFlag(key, value.Value, value.Line, flags); err != nil {
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+// setFlag apply value from file, flags specified in command line take precedence
+func setFlag(name, value string, line int, flags *flag.FlagSet) error {
+	f := flags.Lookup(name)
+	if f == nil {
+		return lineError(line, "unknown option '%s'", name)
+	}
+	if f.Changed {
+		log.Debug().Msgf("Option '%s' is specified in command line, ignoring value in file", name)
+		return nil
+	}
+	if err := flags.Set(name, value); err != nil {
+		return lineError(line, "invalid value '%s' of option '%s'", value, name)
+	}
+	return nil
+}
+
+// validateSchema check keys and value types of whole file before any of its content is used
+func validateSchema(root *yaml.Node) error {
+	if len(root.Content) == 0 {
+		return fmt.Errorf("file is empty")
+	}
+	doc := root.Content[0]
+	if err := expectKind(doc, yaml.MappingNode, "file"); err != nil {
+		return err
+	}
+	return walkFields(doc, func(key string, value *yaml.Node) error {
+		switch key {
+		case "mode":
+			return expectType(value, "!!str", key)
+		case "targets":
+			return walkItems(value, key, validateTargetSchema)
+		case "options":
+			if err := expectKind(value, yaml.MappingNode, key); err != nil {
+				return err
+			}
+			return walkFields(value, func(name string, v *yaml.Node) error {
+				return expectKind(v, yaml.ScalarNode, "option '"+name+"'")
+			})
+		}
+		return fmt.Errorf("unknown field '%s'", key)
+	})
+}
+
+func validateTargetSchema(node *yaml.Node) error {
+	if err := expectKind(node, yaml.MappingNode, "target"); err != nil {
+		return err
+	}
+	return walkFields(node, func(key string, value *yaml.Node) error {
+		switch key {
+		case "resource":
+			return expectType(value, "!!str", key)
+		case "ports":
+			return walkItems(value, key, validatePortSchema)
+		}
+		return fmt.Errorf("unknown field '%s'", key)
+	})
+}
+
+func validatePortSchema(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		if node.Tag != "!!int" && node.Tag != "!!str" {
+			return lineError(node.Line, "port must be a number, a '<local>:<remote>' string or a mapping")
+		}
+		return nil
+	}
+	if err := expectKind(node, yaml.MappingNode, "port"); err != nil {
+		return err
+	}
+	return walkFields(node, func(key string, value *yaml.Node) error {
+		switch key {
+		case "local", "remote":
+			return expectType(value, "!!int", key)
+		case "protocol", "name":
+			return expectType(value, "!!str", key)
+		}
+		return fmt.Errorf("unknown field '%s'", key)
+	})
+}
+
+// walkFields visit each key of mapping node, errors without line number are attached with line of the key
+func walkFields(node *yaml.Node, visit func(string, *yaml.Node) error) error {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if err := visit(key.Value, node.Content[i+1]); err != nil {
+			if !lineNumberPattern.MatchString(err.Error()) {
+				return lineError(key.Line, "%s", err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func walkItems(node *yaml.Node, name string, visit func(*yaml.Node) error) error {
+	if err := expectKind(node, yaml.SequenceNode, name); err != nil {
+		return err
+	}
+	for _, item := range node.Content {
+		if err := visit(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func expectKind(node *yaml.Node, kind yaml.Kind, name string) error {
+	if node.Kind != kind {
+		return lineError(node.Line, "%s must be a %s", name, kindNames[kind])
+	}
+	return nil
+}
+
+func expectType(node *yaml.Node, tag string, name string) error {
+	if node.Kind != yaml.ScalarNode || node.Tag != tag {
+		return lineError(node.Line, "value of '%s' must be a %s", name, tagNames[tag])
+	}
+	return nil
+}
+
+var kindNames = map[yaml.Kind]string{
+	yaml.MappingNode:  "mapping",
+	yaml.SequenceNode: "list",
+	yaml.ScalarNode:   "scalar",
+}
+
+var tagNames = map[string]string{
+	"!!str": "string",
+	"!!int": "number",
+}
+
+func lineError(line int, format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// withLineContext append content of the line mentioned in error message
+func withLineContext(data []byte, err error) string {
+	msg := err.Error()
+	match := lineNumberPattern.FindStringSubmatch(msg)
+	if match == nil {
+		return msg
+	}
+	lineNum, _ := strconv.Atoi(match[1])
+	lines := strings.Split(string(data), "\n")
+	if lineNum < 1 || lineNum > len(lines) {
+		return msg
+	}
+	return fmt.Sprintf("%s\n  %d | %s", msg, lineNum, strings.TrimRight(lines[lineNum-1], "\r"))
+}
@@ -0,0 +1,113 @@
+package exchange
+
+import (
+	flag "github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func newTestFlags() *flag.FlagSet {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.String("mode", "selector", "")
+	flags.Int("recoverWaitTime", 120, "")
+	return flags
+}
+
+func Test_parseFile(t *testing.T) {
+	flags := newTestFlags()
+	file, err := parseFile([]byte(`
+mode: scale
+targets:
+  - resource: svc/a
+    ports:
+      - 8080
+      - 7001:80
+  - resource: deployment/b
+    ports:
+      - local: 9090
+        protocol: TCP
+        name: http
+options:
+  recoverWaitTime: 60
+`), flags)
+	require.Nil(t, err)
+	require.Equal(t, []FilePort{{Local: 8080, Remote: 8080}, {Local: 7001, Remote: 80}}, file.Targets[0].Ports)
+	require.Equal(t, []FilePort{{Local: 9090, Remote: 9090, Protocol: "TCP", Name: "http"}}, file.Targets[1].Ports)
+	mode, _ := flags.GetString("mode")
+	require.Equal(t, "scale", mode)
+	waitTime, _ := flags.GetInt("recoverWaitTime")
+	require.Equal(t, 60, waitTime)
+
+	// json format
+	_, err = parseFile([]byte(`{"targets": [{"resource": "svc-a", "ports": ["8080"]}]}`), newTestFlags())
+	require.Nil(t, err)
+
+	// command line option takes precedence
+	flags = newTestFlags()
+	_ = flags.Set("mode", "ephemeral")
+	_, err = parseFile([]byte("mode: scale\ntargets:\n  - resource: svc-a\n    ports: [80]\n"), flags)
+	require.Nil(t, err)
+	mode, _ = flags.GetString("mode")
+	require.Equal(t, "ephemeral", mode)
+}
+
+func Test_parseFileWithError(t *testing.T) {
+	cases := []struct {
+		content string
+		line    string
+	}{
+		{"targets:\n  - resource: svc-a\n    ports: [80]\n    unknown: 1\n", "line 4"},
+		{"targets:\n  - resource: svc-a\n    ports:\n      - local: 70000\n", "line 4"},
+		{"targets:\n  - resource: svc-a\n    ports:\n      - local: 53\n        protocol: udp\n", "line 4"},
+		{"targets:\n  - ports: [80]\n", "line 2"},
+		{"targets:\n  - resource: svc-a\n", "line 2"},
+		{"targets:\n  - resource: svc-a\n    ports: [80]\noptions:\n  notExist: 1\n", "line 5"},
+		{"targets:\n  - resource: svc-a\n    ports: [80]\noptions:\n  recoverWaitTime: abc\n", "line 5"},
+		{"targets:\n  - resource: svc-a\n   ports: [80]\n", "line "},
+	}
+	for _, c := range cases {
+		_, err := parseFile([]byte(c.content), newTestFlags())
+		require.NotNil(t, err, c.content)
+		require.Contains(t, err.Error(), c.line, c.content)
+	}
+	_, err := parseFile([]byte("mode: scale\n"), newTestFlags())
+	require.NotNil(t, err, "file without target should fail")
+}
+
+func Test_validateSchema(t *testing.T) {
+	cases := []struct {
+		content string
+		message string
+	}{
+		{"target:\n  - resource: svc-a\n", "line 1: unknown field 'target'"},
+		{"mode: [scale]\ntargets: []\n", "line 1: value of 'mode' must be a string"},
+		{"targets:\n  resource: svc-a\n", "line 2: targets must be a list"},
+		{"targets:\n  - svc-a\n", "line 2: target must be a mapping"},
+		{"targets:\n  - resource: 123\n    ports: [80]\n", "line 2: value of 'resource' must be a string"},
+		{"targets:\n  - resource: svc-a\n    ports: 80\n", "line 3: ports must be a list"},
+		{"targets:\n  - resource: svc-a\n    ports:\n      - local: \"80\"\n", "line 4: value of 'local' must be a number"},
+		{"targets:\n  - resource: svc-a\n    ports:\n      - local: 80\n        bind: 0.0.0.0\n", "line 5: unknown field 'bind'"},
+		{"targets:\n  - resource: svc-a\n    ports: [80]\noptions:\n  withLabel: [a]\n", "line 5: option 'withLabel' must be a scalar"},
+	}
+	for _, c := range cases {
+		flags := newTestFlags()
+		_, err := parseFile([]byte(c.content), flags)
+		require.NotNil(t, err, c.content)
+		require.Equal(t, c.message, err.Error(), c.content)
+	}
+
+	// options must not be applied when any part of file is invalid
+	flags := newTestFlags()
+	_, err := parseFile([]byte("mode: scale\noptions:\n  recoverWaitTime: 60\ntargets:\n  - resource: 123\n"), flags)
+	require.NotNil(t, err)
+	waitTime, _ := flags.GetInt("recoverWaitTime")
+	require.Equal(t, 120, waitTime)
+	mode, _ := flags.GetString("mode")
+	require.Equal(t, "selector", mode)
+}
+
+func Test_withLineContext(t *testing.T) {
+	data := []byte("targets:\n  - resource: svc-a\n    ports: [abc]\n")
+	_, err := parseFile(data, newTestFlags())
+	require.Contains(t, withLineContext(data, err), "3 |     ports: [abc]")
+}
@@ -0,0 +1,156 @@
+package exchange
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/command/general"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"strings"
+)
+
+// ByGateway route requests with specified header to local via gateway api http routes, without changing the service itself
+func ByGateway(resourceName, expose string) error {
+	if !cluster.Ins().IsGatewayApiInstalled() {
+		return fmt.Errorf("gateway api is not found in cluster, please remove '--gateway' to exchange by selector")
+	}
+	svc, resolvedExpose, targetPorts, err := getServiceAndPorts(resourceName, expose)
+	if err != nil {
+		return err
+	}
+	routes, err := getHttpRoutesOfService(svc.Name)
+	if err != nil {
+		return err
+	} else if len(routes) == 0 {
+		return fmt.Errorf("no http route references service %s, please remove '--gateway' to exchange by selector", svc.Name)
+	}
+	headerName, headerValue, err := ParseHeader(opt.Get().Exchange.Header)
+	if err != nil {
+		return err
+	}
+	gatewayPorts, err := getMirrorPorts(svc, resolvedExpose, targetPorts)
+	if err != nil {
+		return err
+	}
+
+	shadowName, shadowLabels, err := getMirrorShadowMeta(svc)
+	if err != nil {
+		return err
+	}
+	localSshPort, err := general.CreateShadowAndInbound(shadowName, resolvedExpose,
+		shadowLabels, withDebugPortAnnotation(map[string]string{}), targetPorts, getTargetPodSpec(svc))
+	if err != nil {
+		return err
+	}
+	if err = StartSync(shadowName, localSshPort); err != nil {
+		return err
+	}
+
+	// both service and routes carry suffix of shadow, to avoid overwriting those of others exchanging same service
+	gatewaySvcName := withShadowSuffix(svc.Name+util.GatewaySuffix, getShadowSuffix(shadowName))
+	if _, err = cluster.Ins().CreateService(newMirrorService(gatewaySvcName, gatewayPorts, shadowLabels)); err != nil {
+		return err
+	}
+	opt.Store.Service = util.Append(opt.Store.Service, gatewaySvcName)
+	log.Info().Msgf("Service %s created", gatewaySvcName)
+
+	for _, route := range routes {
+		headerRoute := newHeaderRoute(&route, svc.Name, gatewaySvcName, gatewayPorts, headerName, headerValue)
+		if headerRoute == nil {
+			log.Warn().Msgf("None of exposed ports is used by http route %s, skipped", route.Metadata.Name)
+			continue
+		}
+		if err = cluster.Ins().CreateHttpRoute(headerRoute); err != nil {
+			return err
+		}
+		opt.Store.HttpRoute = util.Append(opt.Store.HttpRoute, headerRoute.Metadata.Name)
+		log.Info().Msgf("Http route %s created, requests of route %s with header '%s: %s' will be sent to local",
+			headerRoute.Metadata.Name, route.Metadata.Name, headerName, headerValue)
+	}
+	return nil
+}
+
+// ParseHeader parse header in '<name>:<value>' format
+func ParseHeader(header string) (string, string, error) {
+	name, value, found := strings.Cut(header, ":")
+	name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+	if !found || name == "" || value == "" {
+		return "", "", fmt.Errorf("invalid header '%s', should be in '<name>:<value>' format", header)
+	}
+	return name, value, nil
+}
+
+func getHttpRoutesOfService(svcName string) ([]cluster.HTTPRoute, error) {
+	routes, err := cluster.Ins().GetAllHttpRouteInNamespace(opt.Get().Global.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	var matched []cluster.HTTPRoute
+	for _, route := range routes {
+		if route.Metadata.Labels[util.ControlBy] == util.KubernetesToolkit {
+			continue
+		}
+		for _, rule := range route.Spec.Rules {
+			if findBackendOf(rule, svcName) != nil {
+				matched = append(matched, route)
+				break
+			}
+		}
+	}
+	return matched, nil
+}
+
+// newHeaderRoute copy rules of origin route which send requests to exposed ports of service, add header condition to
+// each match of them, and let them send requests to specified backend instead, the copy is named after origin route
+// and the backend, return nil if no rule is copied
+func newHeaderRoute(origin *cluster.HTTPRoute, svcName, backendName string, ports map[int]int,
+	headerName, headerValue string) *cluster.HTTPRoute {
+	header := cluster.HTTPHeaderMatch{Type: "Exact", Name: headerName, Value: headerValue}
+	var rules []cluster.HTTPRouteRule
+	for _, rule := range origin.Spec.Rules {
+		backend := findBackendOf(rule, svcName)
+		if backend == nil || backend.Port == nil {
+			continue
+		}
+		if _, exposed := ports[int(*backend.Port)]; !exposed {
+			continue
+		}
+		matches := []cluster.HTTPRouteMatch{{}}
+		if len(rule.Matches) > 0 {
+			matches = make([]cluster.HTTPRouteMatch, len(rule.Matches))
+			copy(matches, rule.Matches)
+		}
+		for i := range matches {
+			matches[i].Headers = append(append([]cluster.HTTPHeaderMatch{}, matches[i].Headers...), header)
+		}
+		rules = append(rules, cluster.HTTPRouteRule{
+			Matches:     matches,
+			Filters:     rule.Filters,
+			BackendRefs: []cluster.BackendRef{{Name: backendName, Port: backend.Port}},
+		})
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	return &cluster.HTTPRoute{
+		Metadata: cluster.HTTPRouteMeta{
+			Name:      fmt.Sprintf("%s-%s", origin.Metadata.Name, backendName),
+			Namespace: origin.Metadata.Namespace,
+		},
+		Spec: cluster.HTTPRouteSpec{
+			ParentRefs: origin.Spec.ParentRefs,
+			Hostnames:  origin.Spec.Hostnames,
+			Rules:      rules,
+		},
+	}
+}
+
+func findBackendOf(rule cluster.HTTPRouteRule, svcName string) *cluster.BackendRef {
+	for i := range rule.BackendRefs {
+		if rule.BackendRefs[i].IsServiceOf(svcName) {
+			return &rule.BackendRefs[i]
+		}
+	}
+	return nil
+}
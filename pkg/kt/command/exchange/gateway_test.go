@@ -0,0 +1,72 @@
+package exchange
+
+import (
+	"encoding/json"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster/fake"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseHeader(t *testing.T) {
+	name, value, err := ParseHeader("X-Version: alice")
+	require.Nil(t, err)
+	require.Equal(t, "X-Version", name)
+	require.Equal(t, "alice", value)
+	_, _, err = ParseHeader("X-Version")
+	require.NotNil(t, err)
+	_, _, err = ParseHeader("X-Version:")
+	require.NotNil(t, err)
+}
+
+func TestByGateway_notInstalled(t *testing.T) {
+	k := fake.NewKubernetes()
+	cluster.SetIns(k)
+	defer cluster.SetIns(nil)
+	err := ByGateway("service/web", "80")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "gateway api is not found")
+	// target service should not be exchanged by selector instead
+	pods, err := k.GetPodsByLabel(map[string]string{}, "default")
+	require.Nil(t, err)
+	require.Empty(t, pods.Items)
+}
+
+func Test_newHeaderRoute(t *testing.T) {
+	port80, port90 := int32(80), int32(90)
+	origin := &cluster.HTTPRoute{
+		Metadata: cluster.HTTPRouteMeta{Name: "web", Namespace: "default"},
+		Spec: cluster.HTTPRouteSpec{
+			ParentRefs: []json.RawMessage{json.RawMessage(`{"name":"gw"}`)},
+			Hostnames:  []string{"web.example.com"},
+			Rules: []cluster.HTTPRouteRule{{
+				Matches:     []cluster.HTTPRouteMatch{{Path: json.RawMessage(`{"type":"PathPrefix","value":"/api"}`)}},
+				BackendRefs: []cluster.BackendRef{{Name: "app", Port: &port80}},
+			}, {
+				BackendRefs: []cluster.BackendRef{{Name: "app", Port: &port90}},
+			}, {
+				BackendRefs: []cluster.BackendRef{{Name: "other", Port: &port80}},
+			}},
+		},
+	}
+
+	route := newHeaderRoute(origin, "app", "app"+util.GatewaySuffix+"-abcde", map[int]int{80: 8080}, "X-Version", "alice")
+	require.NotNil(t, route)
+	require.Equal(t, "web-app"+util.GatewaySuffix+"-abcde", route.Metadata.Name)
+	require.Equal(t, origin.Spec.ParentRefs, route.Spec.ParentRefs)
+	require.Equal(t, origin.Spec.Hostnames, route.Spec.Hostnames)
+	require.Len(t, route.Spec.Rules, 1, "only rules to exposed port of the service should be copied")
+	rule := route.Spec.Rules[0]
+	require.Equal(t, origin.Spec.Rules[0].Matches[0].Path, rule.Matches[0].Path)
+	require.Equal(t, []cluster.HTTPHeaderMatch{{Type: "Exact", Name: "X-Version", Value: "alice"}}, rule.Matches[0].Headers)
+	require.Equal(t, "app"+util.GatewaySuffix+"-abcde", rule.BackendRefs[0].Name)
+	require.Equal(t, port80, *rule.BackendRefs[0].Port)
+	require.Empty(t, origin.Spec.Rules[0].Matches[0].Headers, "origin route should not be changed")
+
+	route = newHeaderRoute(origin, "app", "app"+util.GatewaySuffix+"-abcde", map[int]int{90: 9090}, "X-Version", "alice")
+	require.NotNil(t, route)
+	require.Len(t, route.Spec.Rules[0].Matches, 1, "rule without match should get a header only match")
+
+	require.Nil(t, newHeaderRoute(origin, "app", "app"+util.GatewaySuffix+"-abcde", map[int]int{70: 7070}, "X-Version", "alice"))
+}
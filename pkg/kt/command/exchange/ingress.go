@@ -0,0 +1,96 @@
+package exchange
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/command/general"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"strings"
+)
+
+// defaultIngressClassAnnotation annotation marking the ingress class used by ingresses without class name
+const defaultIngressClassAnnotation = "ingressclass.kubernetes.io/is-default-class"
+
+// CheckIngressHost check whether ingress could be created for targets with specified host
+func CheckIngressHost(host string, targets []Target) error {
+	if len(targets) > 1 {
+		return fmt.Errorf("'--ingress' cannot be used when exchanging multiple resources")
+	}
+	if errs := validation.IsDNS1123Subdomain(host); len(errs) > 0 {
+		return fmt.Errorf("invalid ingress host '%s': %s", host, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// CreateIngress create ingress of specified host pointing at the service of exchanged target
+func CreateIngress(target Target) error {
+	namespace := opt.Get().Global.Namespace
+	svc, err := general.GetServiceByResourceName(target.Resource, namespace)
+	if err != nil {
+		return err
+	}
+	port, err := getIngressPort(svc, target.Expose)
+	if err != nil {
+		return err
+	}
+	checkIngressClass(opt.Get().Exchange.IngressClass)
+
+	// ingress of each exchange carries suffix of its shadow, only one target is allowed with '--ingress'
+	shadows := strings.Split(opt.Store.Shadow, ",")
+	name := withShadowSuffix(svc.Name+util.IngressSuffix, getShadowSuffix(shadows[len(shadows)-1]))
+	if _, err = cluster.Ins().CreateIngress(&cluster.IngressMetaAndSpec{
+		Meta: &cluster.ResourceMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Labels:      map[string]string{},
+			Annotations: map[string]string{},
+		},
+		Host:        opt.Get().Exchange.Ingress,
+		ClassName:   opt.Get().Exchange.IngressClass,
+		TlsSecret:   opt.Get().Exchange.IngressTls,
+		Service:     svc.Name,
+		ServicePort: port,
+	}); err != nil {
+		return err
+	}
+	opt.Store.Ingress = util.Append(opt.Store.Ingress, name)
+	log.Info().Msgf("Ingress %s created", name)
+	return nil
+}
+
+// getIngressPort find the service port whose port or target port is the first exposed remote port
+func getIngressPort(svc *coreV1.Service, expose string) (int, error) {
+	_, remotePort, err := util.ParsePortMapping(strings.Split(expose, ",")[0])
+	if err != nil {
+		return 0, err
+	}
+	for _, p := range svc.Spec.Ports {
+		if int(p.Port) == remotePort || p.TargetPort.IntValue() == remotePort {
+			return int(p.Port), nil
+		}
+	}
+	return 0, fmt.Errorf("exposed port %d is not a port of service %s", remotePort, svc.Name)
+}
+
+// checkIngressClass warn if ingress would not be served by any ingress controller, failure of checking is ignored
+func checkIngressClass(className string) {
+	classes, err := cluster.Ins().GetIngressClasses()
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to get ingress classes")
+		return
+	}
+	for _, c := range classes {
+		if (className != "" && c.Name == className) || (className == "" && c.Annotations[defaultIngressClassAnnotation] == "true") {
+			return
+		}
+	}
+	if className != "" {
+		log.Warn().Msgf("Ingress class '%s' not found, the ingress may not be served", className)
+	} else {
+		log.Warn().Msgf("No default ingress class found, please use '--ingressClass' to specify one")
+	}
+}
@@ -0,0 +1,69 @@
+package exchange
+
+import (
+	"context"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster/fake"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"testing"
+)
+
+func TestCreateIngress(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	k := fake.NewKubernetes(&coreV1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: coreV1.ServiceSpec{Ports: []coreV1.ServicePort{
+			{Port: 80, TargetPort: intstr.FromInt(8080)},
+			{Port: 90, TargetPort: intstr.FromInt(9090)},
+		}},
+	})
+	cluster.SetIns(k)
+	defer cluster.SetIns(nil)
+	opt.Get().Exchange.Ingress = "app.example.com"
+	opt.Get().Exchange.IngressTls = "app-tls"
+	opt.Store.Shadow = "app-kt-exchange-abcde"
+	defer func() {
+		opt.Get().Exchange.Ingress = ""
+		opt.Get().Exchange.IngressTls = ""
+		opt.Store.Ingress = ""
+		opt.Store.Shadow = ""
+	}()
+
+	require.Nil(t, CreateIngress(Target{Resource: "service/app", Expose: "9090"}))
+	require.Equal(t, "app"+util.IngressSuffix+"-abcde", opt.Store.Ingress, "ingress should carry suffix of shadow")
+	ingress, err := k.Clientset.NetworkingV1().Ingresses("default").Get(context.TODO(),
+		"app"+util.IngressSuffix+"-abcde", metav1.GetOptions{})
+	require.Nil(t, err)
+	require.NotEmpty(t, ingress.Annotations[util.KtLastHeartBeat], "ingress should have heart beat for clean")
+	require.Equal(t, "app.example.com", ingress.Spec.Rules[0].Host)
+	backend := ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service
+	require.Equal(t, "app", backend.Name)
+	require.Equal(t, int32(90), backend.Port.Number)
+	require.Equal(t, "app-tls", ingress.Spec.TLS[0].SecretName)
+	require.Nil(t, ingress.Spec.IngressClassName)
+}
+
+func TestCheckIngressHost(t *testing.T) {
+	require.Nil(t, CheckIngressHost("app.example.com", []Target{{Resource: "service/app"}}))
+	require.NotNil(t, CheckIngressHost("App_1", []Target{{Resource: "service/app"}}))
+	require.NotNil(t, CheckIngressHost("app.example.com", []Target{{Resource: "service/a"}, {Resource: "service/b"}}))
+}
+
+func Test_getIngressPort(t *testing.T) {
+	svc := &coreV1.Service{Spec: coreV1.ServiceSpec{Ports: []coreV1.ServicePort{
+		{Port: 80, TargetPort: intstr.FromInt(8080)},
+	}}}
+	port, err := getIngressPort(svc, "8080")
+	require.Nil(t, err)
+	require.Equal(t, 80, port)
+	port, err = getIngressPort(svc, "7001:80")
+	require.Nil(t, err)
+	require.Equal(t, 80, port)
+	_, err = getIngressPort(svc, "9090")
+	require.NotNil(t, err)
+}
@@ -0,0 +1,87 @@
+package exchange
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/common"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/command/replay"
+	"github.com/alibaba/kt-connect/pkg/kt/service/sshchannel"
+	"github.com/alibaba/kt-connect/pkg/kt/transmission"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"strings"
+)
+
+// CheckLoadOptions verify options of '--load' before anything in cluster is changed
+func CheckLoadOptions(targets []Target) error {
+	if opt.Get().Exchange.Load <= 0 {
+		if opt.Get().Exchange.Load < 0 {
+			return fmt.Errorf("requests per second of '--load' should not be negative")
+		} else if opt.Get().Exchange.LoadTemplate != "" {
+			return fmt.Errorf("'--loadTemplate' should be used together with '--load'")
+		}
+		return nil
+	}
+	if opt.Get().Exchange.Load > replay.MaxLoadRps {
+		return fmt.Errorf("requests per second of '--load' should not exceed %d", replay.MaxLoadRps)
+	}
+	if len(targets) != 1 {
+		return fmt.Errorf("'--load' is only supported when exchanging single target")
+	}
+	if _, _, err := getLoadPort(targets[0]); err != nil {
+		return err
+	}
+	if opt.Get().Exchange.LoadTemplate != "" {
+		if _, err := readLoadTemplates(opt.Get().Exchange.LoadTemplate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartLoad generate requests to the exchanged target, via shadow pod if possible, so that they go through the
+// same tunnel as real requests
+func StartLoad(target Target) (*replay.LoadGenerator, error) {
+	localPort, remotePort, err := getLoadPort(target)
+	if err != nil {
+		return nil, err
+	}
+	var templates []sshchannel.RecordedRequest
+	if opt.Get().Exchange.LoadTemplate != "" {
+		if templates, err = readLoadTemplates(opt.Get().Exchange.LoadTemplate); err != nil {
+			return nil, err
+		}
+	}
+	address := fmt.Sprintf("%s:%d", common.Localhost, localPort)
+	if opt.Get().Exchange.Mode == util.ExchangeModeEphemeral || opt.Get().Global.UseShadowDeployment ||
+		opt.Store.Shadow == "" || strings.Contains(opt.Store.Shadow, ",") {
+		// port forward to pod is not redirected by iptables rules of ephemeral container
+		log.Info().Msgf("Load requests are sent to local port %d directly", localPort)
+	} else {
+		entryPort := util.GetRandomTcpPort()
+		if _, err = transmission.SetupPortForwardToLocal(opt.Store.Shadow, remotePort, entryPort); err != nil {
+			return nil, err
+		}
+		address = fmt.Sprintf("%s:%d", common.Localhost, entryPort)
+	}
+	return replay.StartLoad(address, opt.Get().Exchange.Load, templates), nil
+}
+
+// getLoadPort the first tcp port of target to send load requests to
+func getLoadPort(target Target) (int, int, error) {
+	tcpPorts := util.FilterExposeByProtocol(target.Expose, util.ProtocolTcp)
+	if tcpPorts == "" {
+		return 0, 0, fmt.Errorf("'--load' requires at least one tcp port to expose")
+	}
+	return util.ParsePortMapping(strings.Split(tcpPorts, ",")[0])
+}
+
+func readLoadTemplates(file string) ([]sshchannel.RecordedRequest, error) {
+	templates, err := sshchannel.ReadRecords(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read load template %s: %s", file, err)
+	} else if len(templates) == 0 {
+		return nil, fmt.Errorf("no request found in load template %s", file)
+	}
+	return templates, nil
+}
@@ -0,0 +1,117 @@
+package exchange
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/command/general"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"os"
+	"path/filepath"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+// EmitManifests write resources which exchange would create into specified folder, without applying them
+func EmitManifests(targets []Target, dir string) error {
+	if opt.Get().Exchange.Mode == util.ExchangeModeEphemeral {
+		return fmt.Errorf("'--emitManifests' is not supported in %s mode", util.ExchangeModeEphemeral)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create folder %s: %s", dir, err)
+	}
+	opt.Store.Component = util.ComponentExchange
+	for _, target := range targets {
+		objects, err := renderExchangeResources(target.Resource, target.Expose)
+		if err != nil {
+			return err
+		}
+		for _, obj := range objects {
+			file, err2 := writeManifest(dir, obj)
+			if err2 != nil {
+				return err2
+			}
+			log.Info().Msgf("Manifest %s generated", file)
+		}
+	}
+	return nil
+}
+
+func renderExchangeResources(resourceName, expose string) ([]any, error) {
+	if opt.Get().Exchange.Mode == util.ExchangeModeScale {
+		app, err := general.GetDeploymentByResourceName(resourceName, opt.Get().Global.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		opt.Store.Replicas[app.Name] = *app.Spec.Replicas
+		shadowPodName, err := getShadowName(app.Name)
+		if err != nil {
+			return nil, err
+		}
+		labels, err := getExchangeLabels(app)
+		if err != nil {
+			return nil, err
+		}
+		return general.RenderShadow(shadowPodName, expose, labels, getExchangeAnnotation(app.Name),
+			map[int]string{}, &app.Spec.Template.Spec)
+	} else if opt.Get().Exchange.Mode != util.ExchangeModeSelector {
+		return nil, fmt.Errorf("invalid exchange method '%s', supportted are %s, %s", opt.Get().Exchange.Mode,
+			util.ExchangeModeSelector, util.ExchangeModeScale)
+	}
+
+	svc, expose, targetPorts, err := getServiceAndPorts(resourceName, expose)
+	if err != nil {
+		return nil, err
+	}
+	if !opt.Get().Exchange.Mirror {
+		shadowName, shadowLabels, annotation, err2 := getSelectorShadowMeta(svc, expose)
+		if err2 != nil {
+			return nil, err2
+		}
+		return general.RenderShadow(shadowName, expose, shadowLabels, annotation, targetPorts, getTargetPodSpec(svc))
+	}
+	mirrorPorts, err := getMirrorPorts(svc, expose, targetPorts)
+	if err != nil {
+		return nil, err
+	}
+	shadowName, shadowLabels, err := getMirrorShadowMeta(svc)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := general.RenderShadow(shadowName, expose, shadowLabels, map[string]string{},
+		targetPorts, getTargetPodSpec(svc))
+	if err != nil {
+		return nil, err
+	}
+	mirrorName := getMirrorName(svc.Name, shadowName)
+	return append(objects,
+		cluster.Ins().RenderService(newMirrorService(mirrorName, mirrorPorts, shadowLabels)),
+		cluster.Ins().RenderMirrorVirtualService(mirrorName, opt.Get().Global.Namespace, svc.Name, mirrorName,
+			opt.Get().Exchange.MirrorPercent)), nil
+}
+
+// writeManifest save resource as '<name>-<kind>.yaml' file, and return the file path
+func writeManifest(dir string, obj any) (string, error) {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	var header struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+	}
+	if err = yaml.Unmarshal(data, &header); err != nil {
+		return "", err
+	}
+	if header.Kind == "" || header.Metadata.Name == "" {
+		return "", fmt.Errorf("resource without kind or name cannot be saved as manifest")
+	}
+	file := filepath.Join(dir, fmt.Sprintf("%s-%s.yaml", header.Metadata.Name, strings.ToLower(header.Kind)))
+	if err = os.WriteFile(file, data, 0644); err != nil {
+		return "", err
+	}
+	return file, nil
+}
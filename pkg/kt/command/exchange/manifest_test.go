@@ -0,0 +1,28 @@
+package exchange
+
+import (
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_writeManifest(t *testing.T) {
+	dir := t.TempDir()
+	pod := &coreV1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "svc-kt-exchange-abcde", Namespace: "default"},
+	}
+	file, err := writeManifest(dir, pod)
+	require.Nil(t, err)
+	require.Equal(t, filepath.Join(dir, "svc-kt-exchange-abcde-pod.yaml"), file)
+	data, err := os.ReadFile(file)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "kind: Pod")
+	require.Contains(t, string(data), "namespace: default")
+
+	_, err = writeManifest(dir, &coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "no-kind"}})
+	require.NotNil(t, err, "resource without kind should fail")
+}
@@ -0,0 +1,130 @@
+package exchange
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/command/general"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	"strings"
+)
+
+// ByMirror copy requests of service to local via istio mirror route, without changing the service itself
+func ByMirror(resourceName, expose string) error {
+	if !cluster.Ins().IsIstioInstalled() {
+		return fmt.Errorf("istio is not found in cluster, '--mirror' requires istio virtual service")
+	}
+	svc, expose, targetPorts, err := getServiceAndPorts(resourceName, expose)
+	if err != nil {
+		return err
+	}
+	mirrorPorts, err := getMirrorPorts(svc, expose, targetPorts)
+	if err != nil {
+		return err
+	}
+	warnConflictVirtualService(svc.Name)
+	log.Warn().Msgf("Requests to service %s will be handled by both origin pods and local service, "+
+		"any side effect (e.g. database writes, outgoing calls) of local service would happen twice", svc.Name)
+
+	shadowName, shadowLabels, err := getMirrorShadowMeta(svc)
+	if err != nil {
+		return err
+	}
+	localSshPort, err := general.CreateShadowAndInbound(shadowName, expose,
+		shadowLabels, withDebugPortAnnotation(map[string]string{}), targetPorts, getTargetPodSpec(svc))
+	if err != nil {
+		return err
+	}
+	if err = StartSync(shadowName, localSshPort); err != nil {
+		return err
+	}
+
+	mirrorName := getMirrorName(svc.Name, shadowName)
+	if _, err = cluster.Ins().CreateService(newMirrorService(mirrorName, mirrorPorts, shadowLabels)); err != nil {
+		return err
+	}
+	opt.Store.Service = util.Append(opt.Store.Service, mirrorName)
+	log.Info().Msgf("Service %s created", mirrorName)
+
+	if err = cluster.Ins().CreateMirrorVirtualService(mirrorName, opt.Get().Global.Namespace,
+		svc.Name, mirrorName, opt.Get().Exchange.MirrorPercent); err != nil {
+		return err
+	}
+	opt.Store.MirrorRoute = util.Append(opt.Store.MirrorRoute, mirrorName)
+	log.Info().Msgf("Virtual service %s created, mirroring %d%% requests of %s",
+		mirrorName, opt.Get().Exchange.MirrorPercent, svc.Name)
+	return nil
+}
+
+func getMirrorShadowMeta(svc *coreV1.Service) (string, map[string]string, error) {
+	shadowName, err := getShadowName(svc.Name)
+	if err != nil {
+		return "", nil, err
+	}
+	shadowLabels := map[string]string{
+		util.KtRole:   util.RoleExchangeShadow,
+		util.KtTarget: util.RandomString(20),
+	}
+	return shadowName, shadowLabels, nil
+}
+
+// getMirrorName name of mirror service and virtual service, carrying suffix of the shadow,
+// so that two mirrors of the same service don't collide
+func getMirrorName(svcName, shadowName string) string {
+	return withShadowSuffix(svcName+util.MirrorSuffix, getShadowSuffix(shadowName))
+}
+
+func newMirrorService(name string, ports map[int]int, selectors map[string]string) *cluster.SvcMetaAndSpec {
+	return &cluster.SvcMetaAndSpec{
+		Meta: &cluster.ResourceMeta{
+			Name:        name,
+			Namespace:   opt.Get().Global.Namespace,
+			Labels:      map[string]string{},
+			Annotations: map[string]string{},
+		},
+		External:  false,
+		Ports:     ports,
+		Selectors: selectors,
+	}
+}
+
+// getMirrorPorts mirrored requests keep the service port, so mirror service should map each service port
+// whose target port is exposed to the same target port of shadow pod
+func getMirrorPorts(svc *coreV1.Service, expose string, targetPorts map[int]string) (map[int]int, error) {
+	exposedPorts := make(map[int]bool)
+	for _, exposePort := range strings.Split(expose, ",") {
+		_, remotePort, err := util.ParsePortMapping(exposePort)
+		if err != nil {
+			return nil, err
+		}
+		exposedPorts[remotePort] = true
+	}
+	ports := make(map[int]int)
+	for _, p := range svc.Spec.Ports {
+		if targetPort := getTargetPortOf(p, targetPorts); exposedPorts[targetPort] {
+			ports[int(p.Port)] = targetPort
+		}
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("none of exposed ports is target port of service %s", svc.Name)
+	}
+	return ports, nil
+}
+
+func warnConflictVirtualService(svcName string) {
+	vss, err := cluster.Ins().GetAllVirtualServiceInNamespace(opt.Get().Global.Namespace)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to list virtual services")
+		return
+	}
+	for _, vs := range vss {
+		for _, host := range vs.Spec.Hosts {
+			if host == svcName || strings.HasPrefix(host, svcName+".") {
+				log.Warn().Msgf("Virtual service %s also routes requests of %s, mirror route may not take effect",
+					vs.Metadata.Name, svcName)
+			}
+		}
+	}
+}
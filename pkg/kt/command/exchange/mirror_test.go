@@ -0,0 +1,37 @@
+package exchange
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"strings"
+	"testing"
+)
+
+func Test_getMirrorPorts(t *testing.T) {
+	svc := &coreV1.Service{
+		Spec: coreV1.ServiceSpec{
+			Ports: []coreV1.ServicePort{
+				{Port: 80, TargetPort: intstr.FromInt(8080)},
+				{Port: 443, TargetPort: intstr.FromString("https")},
+				{Port: 9090, TargetPort: intstr.FromInt(9090)},
+			},
+		},
+	}
+	targetPorts := map[int]string{8080: "kt-8080", 8443: "https", 9090: "kt-9090"}
+	ports, err := getMirrorPorts(svc, "7001:8080,8443", targetPorts)
+	require.Nil(t, err)
+	require.Equal(t, map[int]int{80: 8080, 443: 8443}, ports)
+	_, err = getMirrorPorts(svc, "7001", targetPorts)
+	require.NotNil(t, err, "no service port to mirror should fail")
+}
+
+func Test_getMirrorName(t *testing.T) {
+	require.Equal(t, "app-kt-mirror-abcde", getMirrorName("app", "app-kt-exchange-abcde"))
+	opt.Get().Exchange.ShadowName = "my-shadow"
+	defer func() { opt.Get().Exchange.ShadowName = "" }()
+	name := getMirrorName("app", "my-shadow")
+	require.True(t, strings.HasPrefix(name, "app-kt-mirror-"))
+	require.NotEqual(t, "app-kt-mirror-shadow", name, "shadow named by user should get a random suffix")
+}
@@ -0,0 +1,262 @@
+package exchange
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/command/general"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// dumpDirMode only current user could access dumped files, since they may contain secrets
+	dumpDirMode = 0700
+	// dumpFileMode permission of each dumped file
+	dumpFileMode = 0600
+	// maxPreviewLength max length of configmap value printed in debug log
+	maxPreviewLength = 64
+)
+
+// mountFile a file projected into origin container from configmap or secret
+type mountFile struct {
+	path   string
+	data   []byte
+	source string
+	secret bool
+}
+
+// DumpMounts write configmaps and secrets mounted by origin container of each target to local directory,
+// keeping their mount paths, so that local service could use the same files
+func DumpMounts(targets []Target, dir string) error {
+	namespace := opt.Get().Global.Namespace
+	for _, target := range targets {
+		pod, err := getOriginPod(target.Resource, namespace)
+		if err != nil {
+			return err
+		}
+		spec := &pod.Spec
+		container := getOriginContainer(target.Expose, spec)
+		if container == nil {
+			return fmt.Errorf("pod %s has no container", pod.Name)
+		}
+		targetDir := dir
+		if len(targets) > 1 {
+			_, name, _ := general.ParseResourceName(target.Resource)
+			targetDir = filepath.Join(dir, name)
+		}
+		count := 0
+		for _, mount := range container.VolumeMounts {
+			volume := getVolume(spec, mount.Name)
+			if volume == nil {
+				continue
+			}
+			files, err2 := getVolumeFiles(volume, namespace)
+			if err2 != nil {
+				return err2
+			}
+			for _, f := range files {
+				mountPath, ok := getFileMountPath(mount, f.path)
+				if !ok {
+					continue
+				}
+				if err2 = writeMountFile(targetDir, mountPath, f); err2 != nil {
+					return err2
+				}
+				count++
+			}
+		}
+		log.Info().Msgf("Dumped %d files mounted by container %s of pod %s to %s",
+			count, container.Name, pod.Name, targetDir)
+	}
+	return nil
+}
+
+// getOriginPod any pod selected by target resource, pods created by kt are excluded
+func getOriginPod(resource, namespace string) (*coreV1.Pod, error) {
+	pods, err := getPodsOfResource(resource, namespace)
+	if err != nil {
+		return nil, err
+	}
+	for i, pod := range pods {
+		if _, exists := pod.Labels[util.KtRole]; !exists {
+			return &pods[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no pod of %s found, cannot dump its mounted files", resource)
+}
+
+// getOriginContainer find the container declaring first exposed port, or the first container if none of them is declared
+func getOriginContainer(expose string, spec *coreV1.PodSpec) *coreV1.Container {
+	for _, exposePort := range strings.Split(expose, ",") {
+		portMapping, _, err := util.SplitExposeProtocol(exposePort)
+		if err != nil {
+			continue
+		}
+		_, remotePort, err := util.ParsePortMapping(portMapping)
+		if err != nil {
+			continue
+		}
+		for i, c := range spec.Containers {
+			for _, cp := range c.Ports {
+				if int(cp.ContainerPort) == remotePort {
+					return &spec.Containers[i]
+				}
+			}
+		}
+	}
+	if len(spec.Containers) == 0 {
+		return nil
+	}
+	return &spec.Containers[0]
+}
+
+func getVolume(spec *coreV1.PodSpec, name string) *coreV1.Volume {
+	for i, v := range spec.Volumes {
+		if v.Name == name {
+			return &spec.Volumes[i]
+		}
+	}
+	return nil
+}
+
+// getVolumeFiles files of configmap, secret or projected volume, other kinds of volume are ignored
+func getVolumeFiles(volume *coreV1.Volume, namespace string) ([]mountFile, error) {
+	if volume.ConfigMap != nil {
+		return getConfigMapFiles(volume.ConfigMap.Name, volume.ConfigMap.Items, volume.ConfigMap.Optional, namespace)
+	} else if volume.Secret != nil {
+		return getSecretFiles(volume.Secret.SecretName, volume.Secret.Items, volume.Secret.Optional, namespace)
+	} else if volume.Projected != nil {
+		var files []mountFile
+		for _, src := range volume.Projected.Sources {
+			var sourceFiles []mountFile
+			var err error
+			if src.ConfigMap != nil {
+				sourceFiles, err = getConfigMapFiles(src.ConfigMap.Name, src.ConfigMap.Items, src.ConfigMap.Optional, namespace)
+			} else if src.Secret != nil {
+				sourceFiles, err = getSecretFiles(src.Secret.Name, src.Secret.Items, src.Secret.Optional, namespace)
+			}
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, sourceFiles...)
+		}
+		return files, nil
+	}
+	log.Debug().Msgf("Volume %s is neither configmap nor secret, skipped", volume.Name)
+	return nil, nil
+}
+
+func getConfigMapFiles(name string, items []coreV1.KeyToPath, optional *bool, namespace string) ([]mountFile, error) {
+	cm, err := cluster.Ins().GetConfigMap(name, namespace)
+	if err != nil {
+		if k8sErrors.IsNotFound(err) && optional != nil && *optional {
+			log.Debug().Msgf("Optional configmap %s not found, skipped", name)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read configmap %s: %s", name, err)
+	}
+	data := make(map[string][]byte)
+	for k, v := range cm.Data {
+		data[k] = []byte(v)
+	}
+	for k, v := range cm.BinaryData {
+		data[k] = v
+	}
+	return selectMountFiles(data, items, "configmap "+name, false)
+}
+
+func getSecretFiles(name string, items []coreV1.KeyToPath, optional *bool, namespace string) ([]mountFile, error) {
+	secret, err := cluster.Ins().GetSecret(name, namespace)
+	if err != nil {
+		if k8sErrors.IsNotFound(err) && optional != nil && *optional {
+			log.Debug().Msgf("Optional secret %s not found, skipped", name)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read secret %s: %s", name, err)
+	}
+	return selectMountFiles(secret.Data, items, "secret "+name, true)
+}
+
+// selectMountFiles all keys are projected as files of same name, unless items specified
+func selectMountFiles(data map[string][]byte, items []coreV1.KeyToPath, source string, secret bool) ([]mountFile, error) {
+	var files []mountFile
+	if len(items) == 0 {
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			files = append(files, mountFile{path: k, data: data[k], source: source, secret: secret})
+		}
+		return files, nil
+	}
+	for _, item := range items {
+		v, exists := data[item.Key]
+		if !exists {
+			return nil, fmt.Errorf("key '%s' not found in %s", item.Key, source)
+		}
+		files = append(files, mountFile{path: item.Path, data: v, source: source, secret: secret})
+	}
+	return files, nil
+}
+
+// getFileMountPath path of file inside container, return false if file is not visible due to sub path of mount
+func getFileMountPath(mount coreV1.VolumeMount, filePath string) (string, bool) {
+	if mount.SubPath == "" {
+		return path.Join(mount.MountPath, filePath), true
+	}
+	if filePath == mount.SubPath {
+		return mount.MountPath, true
+	}
+	if strings.HasPrefix(filePath, mount.SubPath+"/") {
+		return path.Join(mount.MountPath, strings.TrimPrefix(filePath, mount.SubPath+"/")), true
+	}
+	return "", false
+}
+
+func writeMountFile(dir, mountPath string, f mountFile) error {
+	file := filepath.Join(dir, filepath.FromSlash(mountPath))
+	if rel, err := filepath.Rel(dir, file); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("mount path %s of %s is outside dump directory", mountPath, f.source)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), dumpDirMode); err != nil {
+		return err
+	}
+	// never write through symlink or into special file left in dump directory
+	if info, err := os.Lstat(file); err == nil && !info.Mode().IsRegular() {
+		return fmt.Errorf("%s already exists and is not a regular file, refused to dump %s file %s", file, f.source, f.path)
+	} else if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.WriteFile(file, f.data, dumpFileMode); err != nil {
+		return err
+	}
+	// file may already exist with looser permission
+	if err := os.Chmod(file, dumpFileMode); err != nil {
+		return err
+	}
+	log.Info().Msgf("Dumped %s file %s to %s", f.source, f.path, file)
+	if f.secret {
+		log.Debug().Msgf("  %d bytes, value masked", len(f.data))
+	} else {
+		log.Debug().Msgf("  %d bytes: %s", len(f.data), previewValue(f.data))
+	}
+	return nil
+}
+
+func previewValue(data []byte) string {
+	text := strings.ReplaceAll(string(data), "\n", "\\n")
+	if len(text) > maxPreviewLength {
+		return text[:maxPreviewLength] + "..."
+	}
+	return text
+}
@@ -0,0 +1,106 @@
+package exchange
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster/fake"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	appV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDumpMounts(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	optional := true
+	app := &appV1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec:       appV1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "app"}}},
+	}
+	shadow := &coreV1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-kt-shadow", Namespace: "default",
+			Labels: map[string]string{"app": "app", util.KtRole: util.RoleExchangeShadow}},
+		Spec: coreV1.PodSpec{Containers: []coreV1.Container{{Name: "shadow"}}},
+	}
+	pod := &coreV1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "default", Labels: map[string]string{"app": "app"}},
+		Spec: coreV1.PodSpec{
+			Containers: []coreV1.Container{
+				{Name: "sidecar", VolumeMounts: []coreV1.VolumeMount{{Name: "config", MountPath: "/sidecar"}}},
+				{Name: "app", Ports: []coreV1.ContainerPort{{ContainerPort: 80}}, VolumeMounts: []coreV1.VolumeMount{
+					{Name: "config", MountPath: "/etc/app"},
+					{Name: "cert", MountPath: "/etc/tls/tls.key", SubPath: "tls.key"},
+					{Name: "missing", MountPath: "/etc/missing"},
+					{Name: "cache", MountPath: "/cache"},
+				}},
+			},
+			Volumes: []coreV1.Volume{
+				{Name: "config", VolumeSource: coreV1.VolumeSource{ConfigMap: &coreV1.ConfigMapVolumeSource{
+					LocalObjectReference: coreV1.LocalObjectReference{Name: "app-config"},
+					Items:                []coreV1.KeyToPath{{Key: "app.yaml", Path: "conf/app.yaml"}},
+				}}},
+				{Name: "cert", VolumeSource: coreV1.VolumeSource{Secret: &coreV1.SecretVolumeSource{SecretName: "app-tls"}}},
+				{Name: "missing", VolumeSource: coreV1.VolumeSource{Secret: &coreV1.SecretVolumeSource{
+					SecretName: "not-exist", Optional: &optional}}},
+				{Name: "cache", VolumeSource: coreV1.VolumeSource{EmptyDir: &coreV1.EmptyDirVolumeSource{}}},
+			},
+		},
+	}
+	cluster.SetIns(fake.NewKubernetes(app, shadow, pod,
+		&coreV1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default"},
+			Data: map[string]string{"app.yaml": "port: 80", "unused": "x"}},
+		&coreV1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "app-tls", Namespace: "default"},
+			Data: map[string][]byte{"tls.key": []byte("secret-key"), "tls.crt": []byte("cert")}},
+	))
+	defer cluster.SetIns(nil)
+
+	dir := t.TempDir()
+	err := DumpMounts([]Target{{Resource: "deployment/app", Expose: "8080:80"}}, dir)
+	require.Nil(t, err)
+	data, err := os.ReadFile(filepath.Join(dir, "etc", "app", "conf", "app.yaml"))
+	require.Nil(t, err)
+	require.Equal(t, "port: 80", string(data))
+	data, err = os.ReadFile(filepath.Join(dir, "etc", "tls", "tls.key"))
+	require.Nil(t, err)
+	require.Equal(t, "secret-key", string(data))
+	for _, f := range []string{"sidecar", filepath.Join("etc", "app", "unused"), filepath.Join("etc", "tls", "tls.crt"),
+		filepath.Join("etc", "missing"), "cache"} {
+		_, err = os.Stat(filepath.Join(dir, f))
+		require.True(t, os.IsNotExist(err), "%s should not be dumped", f)
+	}
+	if !util.IsWindows() {
+		info, err2 := os.Stat(filepath.Join(dir, "etc", "tls", "tls.key"))
+		require.Nil(t, err2)
+		require.Equal(t, os.FileMode(dumpFileMode), info.Mode().Perm())
+	}
+}
+
+func Test_getFileMountPath(t *testing.T) {
+	mountPath, ok := getFileMountPath(coreV1.VolumeMount{MountPath: "/etc/app"}, "conf/app.yaml")
+	require.True(t, ok)
+	require.Equal(t, "/etc/app/conf/app.yaml", mountPath)
+	mountPath, ok = getFileMountPath(coreV1.VolumeMount{MountPath: "/etc/app", SubPath: "conf"}, "conf/app.yaml")
+	require.True(t, ok)
+	require.Equal(t, "/etc/app/app.yaml", mountPath)
+	_, ok = getFileMountPath(coreV1.VolumeMount{MountPath: "/etc/app.yaml", SubPath: "app.yaml"}, "other.yaml")
+	require.False(t, ok)
+}
+
+func Test_writeMountFile(t *testing.T) {
+	err := writeMountFile(t.TempDir(), "../outside", mountFile{path: "outside", source: "configmap test"})
+	require.NotNil(t, err, "file outside dump directory should be refused")
+	if util.IsWindows() {
+		return
+	}
+	dir := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "outside")
+	require.Nil(t, os.Symlink(outside, filepath.Join(dir, "app.yaml")))
+	err = writeMountFile(dir, "app.yaml", mountFile{path: "app.yaml", data: []byte("x"), source: "configmap test"})
+	require.NotNil(t, err, "symlink in dump directory should be refused")
+	_, err = os.Stat(outside)
+	require.True(t, os.IsNotExist(err), "symlink target should not be written")
+}
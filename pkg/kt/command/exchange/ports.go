@@ -0,0 +1,60 @@
+package exchange
+
+import (
+	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	"strings"
+)
+
+// ListPorts print ports declared by containers and services of specified resources, without changing anything
+func ListPorts(resourceNames []string) error {
+	namespace := opt.Get().Global.Namespace
+	services, err := cluster.Ins().GetAllServiceInNamespace(namespace)
+	if err != nil {
+		return err
+	}
+	for _, resourceName := range resourceNames {
+		// ports specified in '<resource>:<ports>' format are ignored
+		resourceName = strings.SplitN(resourceName, ":", 2)[0]
+		pods, err2 := getPodsOfResource(resourceName, namespace)
+		if err2 != nil {
+			return err2
+		}
+		if len(pods) == 0 {
+			return fmt.Errorf("no pod found for '%s' in namespace %s", resourceName, namespace)
+		}
+		log.Info().Msgf("---- Ports of %s ----", resourceName)
+		// pods of same resource usually share the same spec, only the first one is shown
+		pod := pods[0]
+		for _, c := range pod.Spec.Containers {
+			for _, p := range c.Ports {
+				log.Info().Msgf("> container %s: %s", c.Name, formatPort(p.Name, p.Protocol, p.ContainerPort))
+			}
+		}
+		for _, svc := range services.Items {
+			if len(svc.Spec.Selector) == 0 || svc.Spec.Selector[util.KtRole] != "" ||
+				!util.MapContains(svc.Spec.Selector, pod.Labels) {
+				continue
+			}
+			for _, p := range svc.Spec.Ports {
+				log.Info().Msgf("> service %s: %s -> %s", svc.Name,
+					formatPort(p.Name, p.Protocol, p.Port), p.TargetPort.String())
+			}
+		}
+	}
+	return nil
+}
+
+func formatPort(name string, protocol coreV1.Protocol, port int32) string {
+	if protocol == "" {
+		protocol = coreV1.ProtocolTCP
+	}
+	if name == "" {
+		return fmt.Sprintf("%d/%s", port, protocol)
+	}
+	return fmt.Sprintf("%d/%s (%s)", port, protocol, name)
+}
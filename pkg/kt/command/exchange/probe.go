@@ -0,0 +1,64 @@
+package exchange
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/common"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// probeInterval interval between probe retries
+const probeInterval = 1 * time.Second
+
+// ProbeLocal send http request to local port of each target, make sure local service responds expected status
+// before any request is redirected to it
+func ProbeLocal(targets []Target) error {
+	for _, target := range targets {
+		tcpPorts := util.FilterExposeByProtocol(target.Expose, util.ProtocolTcp)
+		if tcpPorts == "" {
+			continue
+		}
+		localPort, _, err := util.ParsePortMapping(strings.Split(tcpPorts, ",")[0])
+		if err != nil {
+			return err
+		}
+		url := fmt.Sprintf("http://%s:%d%s", common.Localhost, localPort, opt.Get().Exchange.ProbePath)
+		if err = probeUrl(url, opt.Get().Exchange.ProbeStatus, time.Duration(opt.Get().Exchange.ProbeTimeout)*time.Second); err != nil {
+			return fmt.Errorf("local service of %s is not ready: %s", target.Resource, err)
+		}
+		log.Info().Msgf("Local service responded as expected at %s", url)
+	}
+	return nil
+}
+
+// probeUrl retry until url responds expected status (or any 2xx status if expected is 0), or timeout reached
+func probeUrl(url string, expected int, timeout time.Duration) error {
+	client := &http.Client{Timeout: probeInterval}
+	deadline := time.Now().Add(timeout)
+	for {
+		err := probeOnce(client, url, expected)
+		if err == nil {
+			return nil
+		} else if time.Now().Add(probeInterval).After(deadline) {
+			return err
+		}
+		log.Debug().Msgf("Probe of %s failed: %s, retrying", url, err)
+		time.Sleep(probeInterval)
+	}
+}
+
+func probeOnce(client *http.Client, url string, expected int) error {
+	rsp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	_ = rsp.Body.Close()
+	if (expected > 0 && rsp.StatusCode != expected) || (expected == 0 && (rsp.StatusCode < 200 || rsp.StatusCode >= 300)) {
+		return fmt.Errorf("%s responded status %d", url, rsp.StatusCode)
+	}
+	return nil
+}
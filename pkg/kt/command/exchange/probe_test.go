@@ -0,0 +1,57 @@
+package exchange
+
+import (
+	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProbeLocal(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	targets := []Target{{Resource: "deployment/app", Expose: fmt.Sprintf("%s:80", port)}}
+	defer func() {
+		opt.Get().Exchange.ProbePath = ""
+		opt.Get().Exchange.ProbeStatus = 0
+		opt.Get().Exchange.ProbeTimeout = 10
+	}()
+
+	opt.Get().Exchange.ProbePath = "/healthz"
+	opt.Get().Exchange.ProbeTimeout = 0
+	require.NotNil(t, ProbeLocal(targets), "non-2xx status should fail")
+	status = http.StatusNoContent
+	require.Nil(t, ProbeLocal(targets))
+	opt.Get().Exchange.ProbeStatus = http.StatusOK
+	require.NotNil(t, ProbeLocal(targets), "status other than expected should fail")
+	opt.Get().Exchange.ProbeStatus = 0
+	opt.Get().Exchange.ProbePath = "/ready"
+	require.NotNil(t, ProbeLocal(targets))
+}
+
+func Test_probeUrl(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls++; calls < 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	start := time.Now()
+	require.Nil(t, probeUrl(server.URL, 0, 5*time.Second), "probe should be retried until success")
+	require.Equal(t, 2, calls)
+	require.Less(t, time.Since(start), 3*time.Second)
+}
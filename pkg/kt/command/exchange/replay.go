@@ -0,0 +1,39 @@
+package exchange
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/common"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/transmission"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"strings"
+)
+
+// ForReplay exchange the service in selector method for replaying recorded requests, and forward a local port to
+// each exposed port of its shadow pod, return the address to send requests recorded on each local port
+func ForReplay(resourceName, expose string) (map[int]string, error) {
+	if opt.Get().Global.UseShadowDeployment {
+		return nil, fmt.Errorf("replaying through exchange is not supported with '--useShadowDeployment'")
+	}
+	// remote ports are resolved the same way as exchange does, service port is replaced by its target port
+	_, expose, _, err := getServiceAndPorts(resourceName, expose)
+	if err != nil {
+		return nil, err
+	}
+	if err = BySelector(resourceName, expose); err != nil {
+		return nil, err
+	}
+	addresses := make(map[int]string)
+	for _, exposePort := range strings.Split(expose, ",") {
+		localPort, remotePort, err2 := util.ParsePortMapping(exposePort)
+		if err2 != nil {
+			return nil, err2
+		}
+		entryPort := util.GetRandomTcpPort()
+		if _, err2 = transmission.SetupPortForwardToLocal(opt.Store.Shadow, remotePort, entryPort); err2 != nil {
+			return nil, err2
+		}
+		addresses[localPort] = fmt.Sprintf("%s:%d", common.Localhost, entryPort)
+	}
+	return addresses, nil
+}
@@ -0,0 +1,233 @@
+package exchange
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/command/general"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/term"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PodResolver find out pods belong to the specified resource
+type PodResolver func(name, namespace string) ([]coreV1.Pod, error)
+
+var (
+	podResolvers     = map[string]PodResolver{}
+	podResolversLock sync.RWMutex
+)
+
+// resourceTypeAliases short names of builtin resource types
+var resourceTypeAliases = map[string]string{
+	"po":     "pod",
+	"svc":    "service",
+	"deploy": "deployment",
+}
+
+func init() {
+	RegisterPodResolver(getPodsOfPod, "pod", "po")
+	RegisterPodResolver(getPodsOfService, "service", "svc")
+	RegisterPodResolver(getPodsOfDeployment, "deployment", "deploy")
+	RegisterPodResolver(getPodsOfJob, "job")
+}
+
+// normalizeResourceType replace short name of builtin resource type with its full name
+func normalizeResourceType(resourceType string) string {
+	if fullName, exists := resourceTypeAliases[resourceType]; exists {
+		return fullName
+	}
+	return resourceType
+}
+
+// RegisterPodResolver register resolver for resource types, a custom build could use it to support its own workload
+// types (e.g. CRDs which own pods), resolver registered later will replace the existing one of same resource type
+func RegisterPodResolver(resolver PodResolver, resourceTypes ...string) {
+	podResolversLock.Lock()
+	defer podResolversLock.Unlock()
+	for _, resourceType := range resourceTypes {
+		podResolvers[resourceType] = resolver
+	}
+}
+
+func getPodsOfResource(resourceName, namespace string) ([]coreV1.Pod, error) {
+	resourceType, name, err := general.ParseResourceName(resourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	podResolversLock.RLock()
+	resolver, exists := podResolvers[resourceType]
+	podResolversLock.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("invalid resource type: %s", resourceType)
+	}
+	return resolver(name, namespace)
+}
+
+func getPodsOfPod(podName, namespace string) ([]coreV1.Pod, error) {
+	pod, err := cluster.Ins().GetPod(podName, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return []coreV1.Pod{*pod}, nil
+}
+
+func getPodsOfService(serviceName, namespace string) ([]coreV1.Pod, error) {
+	svc, err := cluster.Ins().GetService(serviceName, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return getPodsByLabel(svc.Spec.Selector, namespace)
+}
+
+func getPodsOfDeployment(deploymentName, namespace string) ([]coreV1.Pod, error) {
+	app, err := cluster.Ins().GetDeployment(deploymentName, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return getPodsBySelector(app.Spec.Selector, namespace)
+}
+
+func getPodsOfJob(jobName, namespace string) ([]coreV1.Pod, error) {
+	job, err := cluster.Ins().GetJob(jobName, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return getPodsBySelector(job.Spec.Selector, namespace)
+}
+
+func getPodsBySelector(selector *metav1.LabelSelector, namespace string) ([]coreV1.Pod, error) {
+	if selector == nil || len(selector.MatchLabels) == 0 {
+		return nil, fmt.Errorf("only resource with 'matchLabels' selector is supported")
+	}
+	return getPodsByLabel(selector.MatchLabels, namespace)
+}
+
+func getPodsByLabel(labels map[string]string, namespace string) ([]coreV1.Pod, error) {
+	pods, err := cluster.Ins().GetPodsByLabel(labels, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return pods.Items, nil
+}
+
+// isInteractive whether user can be prompted to choose, stub it in tests
+var isInteractive = func() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// readChoice read user input of prompt, stub it in tests
+var readChoice = func() (string, error) {
+	var answer string
+	_, err := fmt.Scanln(&answer)
+	return answer, err
+}
+
+// ResolveTargets add resource type to targets without 'type/' prefix, if resources of different types share
+// the same name, ask user to choose one, or fail in non-interactive mode
+func ResolveTargets(targets []Target) error {
+	for i := range targets {
+		resource, err := resolveResourceType(targets[i].Resource, opt.Get().Global.Namespace)
+		if err != nil {
+			return err
+		}
+		targets[i].Resource = resource
+	}
+	return nil
+}
+
+// AutoResolveTarget find the only deployment in namespace, shadow deployments created by kt are ignored
+func AutoResolveTarget(namespace string) (string, error) {
+	apps, err := cluster.Ins().GetAllDeploymentInNamespace(namespace)
+	if err != nil {
+		return "", err
+	}
+	var names []string
+	for _, app := range apps.Items {
+		if app.Labels[util.ControlBy] != util.KubernetesToolkit {
+			names = append(names, app.Name)
+		}
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("no deployment found in namespace %s, please specify the resource to exchange", namespace)
+	} else if len(names) > 1 {
+		return "", fmt.Errorf("%d deployments found in namespace %s (%s), please specify the resource to exchange",
+			len(names), namespace, strings.Join(names, ", "))
+	}
+	log.Info().Msgf("Using deployment '%s', the only one in namespace %s", names[0], namespace)
+	return "deployment/" + names[0], nil
+}
+
+func resolveResourceType(resourceName, namespace string) (string, error) {
+	segments, err := general.SplitResourceName(resourceName)
+	if err != nil {
+		return "", err
+	} else if len(segments) > 1 {
+		return resourceName, nil
+	}
+	var candidates []string
+	for _, resourceType := range getCandidateTypes() {
+		if isResourceExist(resourceType, segments[0], namespace) {
+			candidates = append(candidates, resourceType+"/"+resourceName)
+		}
+	}
+	if len(candidates) == 0 {
+		// let later steps report resource not found
+		return resourceName, nil
+	} else if len(candidates) == 1 {
+		return candidates[0], nil
+	} else if opt.Get().Exchange.Yes {
+		log.Info().Msgf("Multiple resources named '%s' found, using %s", resourceName, candidates[0])
+		return candidates[0], nil
+	} else if !isInteractive() {
+		return "", fmt.Errorf("multiple resources named '%s' found in namespace %s, please specify one of %s",
+			resourceName, namespace, strings.Join(candidates, ", "))
+	}
+
+	fmt.Printf("Multiple resources named '%s' found in namespace %s:\n", resourceName, namespace)
+	for i, candidate := range candidates {
+		fmt.Printf("  [%d] %s\n", i+1, candidate)
+	}
+	fmt.Printf("Which one to exchange ? (default 1) ")
+	answer, err := readChoice()
+	if err != nil || answer == "" {
+		return candidates[0], nil
+	}
+	index, err := strconv.Atoi(answer)
+	if err != nil || index < 1 || index > len(candidates) {
+		return "", fmt.Errorf("invalid choice '%s', should be a number between 1 and %d", answer, len(candidates))
+	}
+	return candidates[index-1], nil
+}
+
+// getCandidateTypes resource types could be exchanged in current mode, the preferred type comes first
+func getCandidateTypes() []string {
+	switch opt.Get().Exchange.Mode {
+	case util.ExchangeModeScale:
+		return []string{"deployment", "service"}
+	case util.ExchangeModeEphemeral:
+		return []string{"service", "deployment", "pod"}
+	default:
+		return []string{"service", "deployment"}
+	}
+}
+
+func isResourceExist(resourceType, name, namespace string) bool {
+	var err error
+	switch resourceType {
+	case "service":
+		_, err = cluster.Ins().GetService(name, namespace)
+	case "deployment":
+		_, err = cluster.Ins().GetDeployment(name, namespace)
+	case "pod":
+		_, err = cluster.Ins().GetPod(name, namespace)
+	}
+	return err == nil
+}
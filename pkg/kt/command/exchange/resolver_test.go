@@ -0,0 +1,102 @@
+package exchange
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster/fake"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	appV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestRegisterPodResolver(t *testing.T) {
+	RegisterPodResolver(func(name, namespace string) ([]coreV1.Pod, error) {
+		return []coreV1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: name + "-0", Namespace: namespace}}}, nil
+	}, "rollout", "ro")
+
+	pods, err := getPodsOfResource("ro/demo", "default")
+	require.Nil(t, err)
+	require.Len(t, pods, 1)
+	require.Equal(t, "demo-0", pods[0].Name)
+	require.Equal(t, "default", pods[0].Namespace)
+
+	_, err = getPodsOfResource("unknown/demo", "default")
+	require.NotNil(t, err, "unregistered resource type should fail")
+	pods, err = getPodsOfResource("example.io/v1/Rollout/demo", "default")
+	require.Nil(t, err, "fully-qualified resource name should be accepted")
+	require.Equal(t, "demo-0", pods[0].Name)
+	_, err = getPodsOfResource("a/b/c", "default")
+	require.NotNil(t, err, "invalid resource name should fail")
+}
+
+func TestResolveTargets(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	opt.Get().Exchange.Mode = util.ExchangeModeSelector
+	cluster.SetIns(fake.NewKubernetes(
+		&coreV1.Service{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}},
+		&appV1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}},
+		&appV1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "default"}},
+	))
+	defer cluster.SetIns(nil)
+	interactive, choice := isInteractive, readChoice
+	defer func() { isInteractive, readChoice = interactive, choice }()
+	answer := ""
+	isInteractive = func() bool { return false }
+	readChoice = func() (string, error) { return answer, nil }
+
+	targets := []Target{{Resource: "bar"}, {Resource: "pod/foo"}, {Resource: "none"}, {Resource: "apps/v1/deployment/foo"}}
+	require.Nil(t, ResolveTargets(targets))
+	require.Equal(t, "apps/v1/deployment/foo", targets[3].Resource, "fully-qualified resource should be kept")
+	require.Equal(t, "deployment/bar", targets[0].Resource, "unique resource should be used")
+	require.Equal(t, "pod/foo", targets[1].Resource, "resource with type should be kept")
+	require.Equal(t, "none", targets[2].Resource, "resource not found should be kept")
+
+	err := ResolveTargets([]Target{{Resource: "foo"}})
+	require.NotNil(t, err, "ambiguous name should fail in non-interactive mode")
+	require.Contains(t, err.Error(), "service/foo, deployment/foo")
+
+	opt.Get().Exchange.Yes = true
+	targets = []Target{{Resource: "foo"}}
+	require.Nil(t, ResolveTargets(targets))
+	require.Equal(t, "service/foo", targets[0].Resource, "preferred type should be used with '--yes'")
+	opt.Get().Exchange.Mode = util.ExchangeModeScale
+	targets = []Target{{Resource: "foo"}}
+	require.Nil(t, ResolveTargets(targets))
+	require.Equal(t, "deployment/foo", targets[0].Resource, "deployment is preferred in scale mode")
+	opt.Get().Exchange.Yes = false
+	opt.Get().Exchange.Mode = util.ExchangeModeSelector
+
+	isInteractive = func() bool { return true }
+	for input, expected := range map[string]string{"": "service/foo", "1": "service/foo", "2": "deployment/foo"} {
+		answer = input
+		targets = []Target{{Resource: "foo"}}
+		require.Nil(t, ResolveTargets(targets))
+		require.Equal(t, expected, targets[0].Resource)
+	}
+	answer = "3"
+	require.NotNil(t, ResolveTargets([]Target{{Resource: "foo"}}), "invalid choice should fail")
+}
+
+func TestAutoResolveTarget(t *testing.T) {
+	shadow := &appV1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "demo-kt-abcde", Namespace: "default",
+		Labels: map[string]string{util.ControlBy: util.KubernetesToolkit}}}
+	cluster.SetIns(fake.NewKubernetes(shadow))
+	defer cluster.SetIns(nil)
+	_, err := AutoResolveTarget("default")
+	require.NotNil(t, err, "shadow deployment should be ignored")
+
+	cluster.SetIns(fake.NewKubernetes(shadow,
+		&appV1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "default"}}))
+	resource, err := AutoResolveTarget("default")
+	require.Nil(t, err)
+	require.Equal(t, "deployment/demo", resource)
+
+	cluster.SetIns(fake.NewKubernetes(shadow,
+		&appV1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "default"}},
+		&appV1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"}}))
+	_, err = AutoResolveTarget("default")
+	require.NotNil(t, err)
+}
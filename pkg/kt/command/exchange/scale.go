@@ -5,46 +5,353 @@ import (
 	"github.com/alibaba/kt-connect/pkg/kt/command/general"
 	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
 	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/transmission"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
 	appV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strconv"
 	"strings"
+	"time"
 )
 
-func ByScale(resourceName string) error {
+func ByScale(resourceName, expose string) error {
 	app, err := general.GetDeploymentByResourceName(resourceName, opt.Get().Global.Namespace)
 	if err != nil {
 		return err
 	}
+	if err = CheckDeclaredPorts(resourceName, expose, &app.Spec.Template.Spec); err != nil {
+		return err
+	}
+	if opt.Get().Exchange.RequireHealthy {
+		if err = checkOriginHealthy(app); err != nil {
+			return err
+		}
+	}
+	if err = checkSingleReplica(app); err != nil {
+		return err
+	}
 
 	// record context inorder to remove after command exit
-	opt.Store.Origin = app.Name
-	opt.Store.Replicas = *app.Spec.Replicas
+	opt.Store.Origin = util.Append(opt.Store.Origin, app.Name)
+	opt.Store.Replicas[app.Name] = *app.Spec.Replicas
+	if *app.Spec.Replicas == 0 && opt.Get().Exchange.RestoreReplicas <= 0 {
+		log.Warn().Msgf("Deployment %s has 0 replicas now, it would not be scaled up after exchange finished, "+
+			"use '--restoreReplicas' to specify the replicas to recover", app.Name)
+	}
 
-	shadowPodName := app.Name + util.ExchangePodInfix + strings.ToLower(util.RandomString(5))
+	var originCopy *coreV1.Pod
+	otherPorts, err := getPassthroughPorts(app, expose)
+	if err != nil {
+		return err
+	}
+	if len(otherPorts) > 0 {
+		copyPodName := util.RandomName(app.Name + util.OriginCopyPodInfix)
+		log.Info().Msgf("Creating origin copy %s for ports %v", copyPodName, otherPorts)
+		opt.Store.OriginCopy = util.Append(opt.Store.OriginCopy, copyPodName)
+		if originCopy, err = cluster.Ins().CreateOriginCopyPod(copyPodName, app); err != nil {
+			return err
+		}
+	}
+
+	svcs, err := cluster.Ins().GetServicesBySelector(app.Spec.Template.Labels, opt.Get().Global.Namespace)
+	if err != nil {
+		return err
+	}
+	shadowLabels, err := getExchangeLabels(app)
+	if err != nil {
+		return err
+	}
+	if opt.Get().Exchange.ServicesOnly != "" {
+		if shadowLabels, err = getServicesOnlyLabels(app.Name, svcs); err != nil {
+			return err
+		}
+	}
+	logRoutedServices(svcs, shadowLabels)
+	for _, svc := range svcs {
+		if util.MapContains(svc.Spec.Selector, shadowLabels) {
+			warnExternalTrafficPolicy(&svc)
+		}
+	}
+
+	shadowPodName, err := getShadowName(app.Name)
+	if err != nil {
+		return err
+	}
 
 	log.Info().Msgf("Creating exchange shadow %s in namespace %s", shadowPodName, opt.Get().Global.Namespace)
-	if err = general.CreateShadowAndInbound(shadowPodName, opt.Get().Exchange.Expose,
-		getExchangeLabels(app), getExchangeAnnotation(), map[int]string{}); err != nil {
+	podName, privateKeyPath, err := general.CreateShadow(shadowPodName, expose,
+		shadowLabels, getExchangeAnnotation(app.Name), map[int]string{}, &app.Spec.Template.Spec)
+	if err != nil {
 		return err
 	}
+	if opt.Get().Exchange.TailOrigin {
+		tailOriginLogs(app)
+	}
 
-	down := int32(0)
-	if err = cluster.Ins().ScaleTo(app.Name, opt.Get().Global.Namespace, &down); err != nil {
+	// shadow pod becomes service endpoint once ready, inbound must be started before origin pods leave
+	localSshPort, err := general.Inbound(shadowPodName, expose, podName, privateKeyPath)
+	if err != nil {
+		return err
+	}
+	if originCopy != nil {
+		if err = transmission.ForwardRemotePortsToUpstream(otherPorts, originCopy.Status.PodIP, localSshPort,
+			util.PrivateKeyPath(shadowPodName)); err != nil {
+			return err
+		}
+	}
+	if err = StartSync(shadowPodName, localSshPort); err != nil {
+		return err
+	}
+
+	// exchange is ready after origin pods left service endpoints, so that requests would not be split between them
+	draining, err := scaleDownOrigin(app, svcs, podName)
+	if err != nil {
 		return err
 	}
+	if len(draining) > 0 {
+		go drainOriginPods(draining, time.Duration(opt.Get().Exchange.DrainTimeout)*time.Second)
+	}
+	return nil
+}
+
+// scaleDownOrigin scale origin deployment down and wait for its pods removed from service endpoints,
+// return pods detached for draining with '--gracefulCutover'
+func scaleDownOrigin(app *appV1.Deployment, svcs []coreV1.Service, shadowPodName string) ([]string, error) {
+	down := int32(opt.Get().Exchange.OriginReplicas)
+	if down >= *app.Spec.Replicas {
+		log.Warn().Msgf("Deployment %s has %d replicas now, not scaling it down", app.Name, *app.Spec.Replicas)
+		return nil, nil
+	}
+	if err := general.CheckSessionAffinity(svcs); err != nil {
+		return nil, err
+	}
+	var draining []string
+	if opt.Get().Exchange.GracefulCutover {
+		// detach before scaling down, otherwise the pods would be terminated by scaling down
+		origins, err := getOriginPods(app)
+		if err != nil {
+			return nil, err
+		}
+		if draining, err = detachOriginPods(app, svcs, origins); err != nil {
+			return nil, err
+		}
+		log.Info().Msgf("Detached %d pods from deployment %s, existing connections are drained in %d seconds",
+			len(draining), app.Name, opt.Get().Exchange.DrainTimeout)
+	}
+	if err := cluster.Ins().ScaleTo(app.Name, opt.Get().Global.Namespace, &down); err != nil {
+		return nil, err
+	}
+	if opt.Get().Exchange.GracefulCutover {
+		if err := removeReplacementPods(app); err != nil {
+			return nil, err
+		}
+	}
+	if down > 0 {
+		log.Info().Msgf("Keeping %d replicas of deployment %s, requests will be shared with local", down, app.Name)
+	}
+	waitOriginEndpointsRemoved(app, svcs, shadowPodName, int(down))
+	return draining, nil
+}
 
+// checkSingleReplica refuse to scale down the only replica of origin deployment unless '--allowOutage' is specified,
+// since the service would have no origin pod to fall back on during exchange
+func checkSingleReplica(app *appV1.Deployment) error {
+	if app.Spec.Replicas == nil || *app.Spec.Replicas != 1 || opt.Get().Exchange.OriginReplicas > 0 {
+		return nil
+	}
+	if !opt.Get().Exchange.AllowOutage {
+		return fmt.Errorf("deployment %s has only 1 replica, scaling it down would leave no origin pod serving, "+
+			"please use '--mode %s' or '--mode %s' instead, or '--allowOutage' if you're sure",
+			app.Name, util.ExchangeModeSelector, util.ExchangeModeEphemeral)
+	}
+	log.Warn().Msgf("***************************************************************")
+	log.Warn().Msgf(" Scaling down the ONLY replica of deployment %s,", app.Name)
+	log.Warn().Msgf(" it will not serve any request until exchange finished")
+	log.Warn().Msgf("***************************************************************")
 	return nil
 }
 
-func getExchangeAnnotation() map[string]string {
-	return map[string]string{
+// checkOriginHealthy make sure at least one origin pod is ready, to avoid an existing outage being hidden by exchange
+func checkOriginHealthy(app *appV1.Deployment) error {
+	pods, err := getOriginPods(app)
+	if err != nil {
+		return err
+	}
+	var reasons []string
+	for _, pod := range pods {
+		if pod.Status.Phase == coreV1.PodRunning && pod.DeletionTimestamp == nil && isPodReady(&pod) {
+			log.Info().Msgf("Origin pod %s is ready", pod.Name)
+			return nil
+		}
+		reasons = append(reasons, cluster.DescribePodStatus(&pod))
+	}
+	if len(reasons) == 0 {
+		return fmt.Errorf("deployment %s has no pod running, refuse to exchange an unhealthy target", app.Name)
+	}
+	return fmt.Errorf("deployment %s has no ready pod (%s), refuse to exchange an unhealthy target",
+		app.Name, strings.Join(reasons, "; "))
+}
+
+func isPodReady(pod *coreV1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == coreV1.PodReady {
+			return c.Status == coreV1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// waitOriginEndpointsRemoved wait until each service has no more than specified number of ready endpoints
+// besides the shadow pod, return whether origin pods to scale down are all out of service
+func waitOriginEndpointsRemoved(app *appV1.Deployment, svcs []coreV1.Service, shadowPodName string, keep int) bool {
+	counts := opt.Get().Exchange.TerminateWaitTime
+	for i := 0; i < counts; i++ {
+		remaining := 0
+		for _, svc := range svcs {
+			count, err := countOriginEndpoints(svc.Name, shadowPodName)
+			if err != nil {
+				log.Warn().Err(err).Msgf("Failed to fetch endpoints of service %s", svc.Name)
+				return false
+			}
+			if count-keep > remaining {
+				remaining = count - keep
+			}
+		}
+		if remaining <= 0 {
+			log.Info().Msgf("All pods of deployment %s to scale down removed from service endpoints", app.Name)
+			return true
+		}
+		if i%5 == 0 {
+			log.Info().Msgf("Waiting for %d pods of deployment %s to leave service endpoints ...", remaining, app.Name)
+		}
+		time.Sleep(1 * time.Second)
+	}
+	if counts > 0 {
+		log.Warn().Msgf("Pods of deployment %s are still in service endpoints, some requests may still hit them", app.Name)
+	}
+	return false
+}
+
+// countOriginEndpoints number of pods in ready endpoint addresses of service besides the shadow pod
+func countOriginEndpoints(svcName, shadowPodName string) (int, error) {
+	endpoints, err := cluster.Ins().GetEndpoints(svcName, opt.Get().Global.Namespace)
+	if k8sErrors.IsNotFound(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	// subsets with different ports may contain the same address
+	addresses := make(map[string]bool)
+	for _, subset := range endpoints.Subsets {
+		for _, address := range subset.Addresses {
+			if address.TargetRef == nil || address.TargetRef.Name != shadowPodName {
+				addresses[address.IP] = true
+			}
+		}
+	}
+	return len(addresses), nil
+}
+
+// getUnexposedPorts container ports of deployment not specified in expose ports
+func getUnexposedPorts(app *appV1.Deployment, expose string) ([]int, error) {
+	exposedPorts := make(map[int]bool)
+	for _, exposePort := range strings.Split(expose, ",") {
+		_, remotePort, err := util.ParsePortMapping(exposePort)
+		if err != nil {
+			return nil, err
+		}
+		exposedPorts[remotePort] = true
+	}
+	ports := make([]int, 0)
+	for _, c := range app.Spec.Template.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.Protocol != coreV1.ProtocolUDP && !exposedPorts[int(p.ContainerPort)] {
+				ports = append(ports, int(p.ContainerPort))
+			}
+		}
+	}
+	return ports, nil
+}
+
+// getPassthroughPorts ports forwarded to origin copy, all unexposed ports with '--keepOtherPorts' or '--healthPort',
+// or ports specified via '--passthrough'
+func getPassthroughPorts(app *appV1.Deployment, expose string) ([]int, error) {
+	if opt.Get().Exchange.Passthrough == "" {
+		if !opt.Get().Exchange.KeepOtherPorts && opt.Get().Exchange.HealthPort <= 0 {
+			return nil, nil
+		}
+		return getUnexposedPorts(app, expose)
+	}
+	unexposed, err := getUnexposedPorts(app, expose)
+	if err != nil {
+		return nil, err
+	}
+	ports := make([]int, 0)
+	for _, text := range strings.Split(opt.Get().Exchange.Passthrough, ",") {
+		port, err2 := strconv.Atoi(strings.TrimSpace(text))
+		if err2 != nil || port <= 0 || port > 65535 {
+			return nil, fmt.Errorf("invalid passthrough port '%s'", text)
+		}
+		if !util.Contains(unexposed, port) {
+			return nil, fmt.Errorf("port %d is either exposed or not a tcp port declared by deployment %s, "+
+				"it cannot be passed through", port, app.Name)
+		}
+		if !util.Contains(ports, port) {
+			ports = append(ports, port)
+		}
+	}
+	return ports, nil
+}
+
+func getExchangeAnnotation(origin string) map[string]string {
+	return withDebugPortAnnotation(map[string]string{
 		util.KtConfig: fmt.Sprintf("app=%s,replicas=%d",
-			opt.Store.Origin, opt.Store.Replicas),
+			origin, opt.Store.Replicas[origin]),
+	})
+}
+
+// getServicesOnlyLabels shadow labels matching selectors of services specified via '--servicesOnly' only,
+// svcs are all services selecting the origin deployment
+func getServicesOnlyLabels(appName string, svcs []coreV1.Service) (map[string]string, error) {
+	labels := map[string]string{
+		util.KtRole: util.RoleExchangeShadow,
+	}
+	names := strings.Split(opt.Get().Exchange.ServicesOnly, ",")
+	for _, name := range names {
+		found := false
+		for _, svc := range svcs {
+			if svc.Name == name {
+				for k, v := range svc.Spec.Selector {
+					labels[k] = v
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("service %s does not select pods of deployment %s", name, appName)
+		}
 	}
+	for _, svc := range svcs {
+		if util.Contains(names, svc.Name) {
+			continue
+		}
+		if util.MapContains(svc.Spec.Selector, labels) {
+			return nil, fmt.Errorf("selector of service %s is covered by services in '--servicesOnly', "+
+				"it cannot be excluded", svc.Name)
+		}
+		if opt.Get().Exchange.OriginReplicas == 0 {
+			log.Warn().Msgf("Service %s is excluded, it will have no endpoint after deployment %s scaled down",
+				svc.Name, appName)
+		}
+	}
+	return labels, nil
 }
 
-func getExchangeLabels(origin *appV1.Deployment) map[string]string {
+func getExchangeLabels(origin *appV1.Deployment) (map[string]string, error) {
 	labels := map[string]string{
 		util.KtRole: util.RoleExchangeShadow,
 	}
@@ -52,6 +359,52 @@ func getExchangeLabels(origin *appV1.Deployment) map[string]string {
 		for k, v := range origin.Spec.Selector.MatchLabels {
 			labels[k] = v
 		}
+		if err := addExpressionLabels(labels, origin.Spec.Selector.MatchExpressions, origin.Spec.Template.Labels); err != nil {
+			return nil, fmt.Errorf("cannot generate shadow labels for selector of deployment %s: %s", origin.Name, err)
+		}
 	}
-	return labels
+	return labels, nil
+}
+
+// addExpressionLabels add labels satisfying 'In' and 'Exists' expressions of selector, value in pod template is
+// preferred, 'NotIn' and 'DoesNotExist' expressions are satisfied by not adding the label, conflicts are reported
+func addExpressionLabels(labels map[string]string, expressions []metav1.LabelSelectorRequirement,
+	templateLabels map[string]string) error {
+	for _, e := range expressions {
+		value, inTemplate := templateLabels[e.Key]
+		switch e.Operator {
+		case metav1.LabelSelectorOpIn:
+			if existing, exists := labels[e.Key]; exists {
+				if !util.Contains(e.Values, existing) {
+					return fmt.Errorf("label %s=%s required by matchLabels is not in %v", e.Key, existing, e.Values)
+				}
+			} else if inTemplate && util.Contains(e.Values, value) {
+				labels[e.Key] = value
+			} else if len(e.Values) > 0 {
+				labels[e.Key] = e.Values[0]
+			} else {
+				return fmt.Errorf("expression '%s In' has no value", e.Key)
+			}
+		case metav1.LabelSelectorOpExists:
+			if _, exists := labels[e.Key]; exists {
+				continue
+			} else if !inTemplate {
+				return fmt.Errorf("no value of label %s found in pod template for expression '%s Exists'", e.Key, e.Key)
+			}
+			labels[e.Key] = value
+		case metav1.LabelSelectorOpNotIn, metav1.LabelSelectorOpDoesNotExist:
+			// checked after all required labels added
+		default:
+			return fmt.Errorf("operator '%s' of label %s is not supported", e.Operator, e.Key)
+		}
+	}
+	for _, e := range expressions {
+		existing, exists := labels[e.Key]
+		if e.Operator == metav1.LabelSelectorOpNotIn && exists && util.Contains(e.Values, existing) {
+			return fmt.Errorf("label %s=%s conflicts with expression '%s NotIn %v'", e.Key, existing, e.Key, e.Values)
+		} else if e.Operator == metav1.LabelSelectorOpDoesNotExist && exists {
+			return fmt.Errorf("label %s is both required and forbidden by selector", e.Key)
+		}
+	}
+	return nil
 }
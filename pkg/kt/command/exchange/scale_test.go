@@ -0,0 +1,388 @@
+package exchange
+
+import (
+	"bytes"
+	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster/fake"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+	appV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestByScale(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	replicas := int32(2)
+	cluster.SetIns(fake.NewKubernetes(&appV1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: appV1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: coreV1.PodTemplateSpec{Spec: coreV1.PodSpec{Containers: []coreV1.Container{
+				{Ports: []coreV1.ContainerPort{{ContainerPort: 8080}}},
+			}}},
+		},
+	}))
+	defer cluster.SetIns(nil)
+
+	require.NotNil(t, ByScale("deployment/not-exist", "8080"), "deployment not exist should fail")
+	opt.Get().Exchange.StrictPorts = true
+	defer func() { opt.Get().Exchange.StrictPorts = false }()
+	require.NotNil(t, ByScale("deployment/app", "9090"), "undeclared port should fail in strict mode")
+	require.NotContains(t, opt.Store.Origin, "app", "origin should not be recorded before exchange")
+}
+
+func Test_getExchangeLabels(t *testing.T) {
+	app := &appV1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app"},
+		Spec: appV1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "demo"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"web", "api"}},
+					{Key: "track", Operator: metav1.LabelSelectorOpExists},
+					{Key: "env", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"prod"}},
+					{Key: "legacy", Operator: metav1.LabelSelectorOpDoesNotExist},
+				},
+			},
+			Template: coreV1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{"app": "demo", "tier": "api", "track": "stable", "env": "test"},
+			}},
+		},
+	}
+	shadowLabels, err := getExchangeLabels(app)
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{util.KtRole: util.RoleExchangeShadow,
+		"app": "demo", "tier": "api", "track": "stable"}, shadowLabels)
+	selector, err := metav1.LabelSelectorAsSelector(app.Spec.Selector)
+	require.Nil(t, err)
+	require.True(t, selector.Matches(labels.Set(shadowLabels)), "shadow should satisfy selector of deployment")
+
+	app.Spec.Selector.MatchExpressions = append(app.Spec.Selector.MatchExpressions,
+		metav1.LabelSelectorRequirement{Key: "app", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"demo"}})
+	_, err = getExchangeLabels(app)
+	require.NotNil(t, err, "conflicting expressions should fail")
+	app.Spec.Selector.MatchExpressions = []metav1.LabelSelectorRequirement{{Key: "tier", Operator: "Gt"}}
+	_, err = getExchangeLabels(app)
+	require.NotNil(t, err, "unknown operator should fail")
+}
+
+func TestByScale_externalServices(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	replicas := int32(2)
+	selector := map[string]string{"app": "demo", "tier": "backend"}
+	appName := "demo"
+	service := func(name string, svcType coreV1.ServiceType, policy coreV1.ServiceExternalTrafficPolicyType,
+		selector map[string]string) *coreV1.Service {
+		return &coreV1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: coreV1.ServiceSpec{Type: svcType, ExternalTrafficPolicy: policy, Selector: selector}}
+	}
+	cluster.SetIns(fake.NewKubernetes(&appV1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: appName, Namespace: "default"},
+		Spec: appV1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: selector},
+			Template: coreV1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: selector},
+				Spec: coreV1.PodSpec{Containers: []coreV1.Container{
+					{Ports: []coreV1.ContainerPort{{ContainerPort: 8080}}},
+				}}},
+		},
+	},
+		service("svc-node-port", coreV1.ServiceTypeNodePort, coreV1.ServiceExternalTrafficPolicyTypeCluster, selector),
+		service("svc-lb-local", coreV1.ServiceTypeLoadBalancer, coreV1.ServiceExternalTrafficPolicyTypeLocal, selector),
+		service("svc-lb-app", coreV1.ServiceTypeLoadBalancer, coreV1.ServiceExternalTrafficPolicyTypeLocal,
+			map[string]string{"app": "demo"}),
+	))
+	defer cluster.SetIns(nil)
+	defer func() {
+		opt.Store.Origin = ""
+		opt.Store.Shadow = ""
+		delete(opt.Store.Replicas, appName)
+	}()
+	origin := log.Logger
+	defer func() { log.Logger = origin }()
+	buf := &bytes.Buffer{}
+	log.Logger = zerolog.New(buf)
+
+	// exchange fails before port-forward to fake shadow pod, which requires a real api server
+	opt.Get().Global.ListenInterface = "invalid"
+	defer func() { opt.Get().Global.ListenInterface = "" }()
+	require.NotNil(t, ByScale("deployment/"+appName, "8080"))
+	output := buf.String()
+	for _, name := range []string{"svc-node-port", "svc-lb-local", "svc-lb-app"} {
+		require.Contains(t, output, fmt.Sprintf("Requests to service %s will be redirected to local", name))
+	}
+	require.Contains(t, output, "Service svc-lb-local has 'Local' external traffic policy")
+	require.Contains(t, output, "Service svc-lb-app has 'Local' external traffic policy")
+	require.NotContains(t, output, "Service svc-node-port has 'Local' external traffic policy")
+}
+
+func Test_getUnexposedPorts(t *testing.T) {
+	app := &appV1.Deployment{
+		Spec: appV1.DeploymentSpec{
+			Template: coreV1.PodTemplateSpec{
+				Spec: coreV1.PodSpec{
+					Containers: []coreV1.Container{
+						{Ports: []coreV1.ContainerPort{{ContainerPort: 80}, {ContainerPort: 8080}}},
+						{Ports: []coreV1.ContainerPort{{ContainerPort: 9090}, {ContainerPort: 53, Protocol: coreV1.ProtocolUDP}}},
+					},
+				},
+			},
+		},
+	}
+	ports, err := getUnexposedPorts(app, "7001:80")
+	require.Nil(t, err)
+	require.Equal(t, []int{8080, 9090}, ports)
+	ports, err = getUnexposedPorts(app, "80,8080,9090")
+	require.Nil(t, err)
+	require.Empty(t, ports)
+	_, err = getUnexposedPorts(app, "abc")
+	require.NotNil(t, err)
+}
+
+func Test_getServicesOnlyLabels(t *testing.T) {
+	svcs := []coreV1.Service{
+		{ObjectMeta: metav1.ObjectMeta{Name: "internal"}, Spec: coreV1.ServiceSpec{
+			Selector: map[string]string{"app": "demo", "expose": "internal"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "external"}, Spec: coreV1.ServiceSpec{
+			Selector: map[string]string{"app": "demo", "expose": "external"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "all"}, Spec: coreV1.ServiceSpec{
+			Selector: map[string]string{"app": "demo"}}},
+	}
+	defer func() { opt.Get().Exchange.ServicesOnly = "" }()
+
+	opt.Get().Exchange.ServicesOnly = "internal,all"
+	labels, err := getServicesOnlyLabels("app", svcs)
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{util.KtRole: util.RoleExchangeShadow, "app": "demo", "expose": "internal"}, labels)
+
+	opt.Get().Exchange.ServicesOnly = "internal"
+	_, err = getServicesOnlyLabels("app", svcs)
+	require.NotNil(t, err, "service with selector covered by specified services cannot be excluded")
+	opt.Get().Exchange.ServicesOnly = "not-exist"
+	_, err = getServicesOnlyLabels("app", svcs)
+	require.NotNil(t, err, "service not selecting origin should fail")
+}
+
+func Test_checkSingleReplica(t *testing.T) {
+	one, two := int32(1), int32(2)
+	app := &appV1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec:       appV1.DeploymentSpec{Replicas: &one},
+	}
+	defer func() {
+		opt.Get().Exchange.AllowOutage = false
+		opt.Get().Exchange.OriginReplicas = 0
+	}()
+	require.NotNil(t, checkSingleReplica(app), "single replica should fail without '--allowOutage'")
+	opt.Get().Exchange.OriginReplicas = 1
+	require.Nil(t, checkSingleReplica(app), "origin replica is kept")
+	opt.Get().Exchange.OriginReplicas = 0
+	opt.Get().Exchange.AllowOutage = true
+	require.Nil(t, checkSingleReplica(app))
+	opt.Get().Exchange.AllowOutage = false
+	app.Spec.Replicas = &two
+	require.Nil(t, checkSingleReplica(app))
+}
+
+// newOwnedOrigin deployment with given selector and the replica set owning its pods
+func newOwnedOrigin(selector *metav1.LabelSelector) (*appV1.Deployment, *appV1.ReplicaSet, []metav1.OwnerReference) {
+	isController := true
+	app := &appV1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", UID: "app-uid"},
+		Spec:       appV1.DeploymentSpec{Selector: selector},
+	}
+	rs := &appV1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "app-abc", Namespace: "default", UID: "rs-uid",
+		OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "app", UID: "app-uid", Controller: &isController}}}}
+	return app, rs, []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "app-abc", UID: "rs-uid", Controller: &isController}}
+}
+
+func Test_waitOriginEndpointsRemoved(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	opt.Get().Exchange.TerminateWaitTime = 3
+	defer func() { opt.Get().Exchange.TerminateWaitTime = 0 }()
+	app := &appV1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+	svcs := []coreV1.Service{
+		{ObjectMeta: metav1.ObjectMeta{Name: "svc-a", Namespace: "default"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "svc-no-endpoints", Namespace: "default"}},
+	}
+	address := func(ip, pod string) coreV1.EndpointAddress {
+		return coreV1.EndpointAddress{IP: ip, TargetRef: &coreV1.ObjectReference{Kind: "Pod", Name: pod}}
+	}
+	cluster.SetIns(fake.NewKubernetes(&coreV1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "svc-a", Namespace: "default"},
+		Subsets: []coreV1.EndpointSubset{
+			{
+				Addresses:         []coreV1.EndpointAddress{address("10.0.0.1", "app-kt-exchange-abcde"), address("10.0.0.2", "app-1")},
+				NotReadyAddresses: []coreV1.EndpointAddress{address("10.0.0.3", "app-2")},
+				Ports:             []coreV1.EndpointPort{{Port: 8080}},
+			},
+			{
+				Addresses: []coreV1.EndpointAddress{address("10.0.0.2", "app-1")},
+				Ports:     []coreV1.EndpointPort{{Port: 9090}},
+			},
+		},
+	}))
+	defer cluster.SetIns(nil)
+
+	count, err := countOriginEndpoints("svc-a", "app-kt-exchange-abcde")
+	require.Nil(t, err)
+	require.Equal(t, 1, count, "shadow pod, not ready and duplicated addresses should not be counted")
+	count, err = countOriginEndpoints("svc-no-endpoints", "app-kt-exchange-abcde")
+	require.Nil(t, err)
+	require.Equal(t, 0, count)
+
+	start := time.Now()
+	require.True(t, waitOriginEndpointsRemoved(app, svcs, "app-kt-exchange-abcde", 1), "one origin pod is expected to keep")
+	require.Less(t, time.Since(start), time.Second, "should return without waiting")
+	opt.Get().Exchange.TerminateWaitTime = 1
+	require.False(t, waitOriginEndpointsRemoved(app, svcs, "app-kt-exchange-abcde", 0), "origin pod still in endpoints")
+	opt.Get().Exchange.TerminateWaitTime = 0
+	require.False(t, waitOriginEndpointsRemoved(app, svcs, "app-kt-exchange-abcde", 0), "no wait at all")
+}
+
+func Test_getOriginPods(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	app, rs, owned := newOwnedOrigin(&metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+		{Key: "app", Operator: metav1.LabelSelectorOpIn, Values: []string{"demo"}},
+	}})
+	cluster.SetIns(fake.NewKubernetes(rs,
+		&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "default",
+			Labels: map[string]string{"app": "demo"}, OwnerReferences: owned}},
+		&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "sibling-1", Namespace: "default",
+			Labels: map[string]string{"app": "demo"}}},
+		&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "unrelated-1", Namespace: "default",
+			Labels: map[string]string{"app": "other"}, OwnerReferences: owned}},
+	))
+	defer cluster.SetIns(nil)
+
+	pods, err := getOriginPods(app)
+	require.Nil(t, err)
+	require.Len(t, pods, 1, "only pods matching expressions and owned by deployment should be selected")
+	require.Equal(t, "app-1", pods[0].Name)
+
+	app.Spec.Selector = &metav1.LabelSelector{}
+	_, err = getOriginPods(app)
+	require.NotNil(t, err, "empty selector should be refused")
+}
+
+func Test_checkOriginHealthy(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	labels := map[string]string{"app": "demo"}
+	shadowLabels := map[string]string{"app": "demo", util.KtRole: util.RoleExchangeShadow}
+	readyStatus := coreV1.PodStatus{Phase: coreV1.PodRunning, Conditions: []coreV1.PodCondition{
+		{Type: coreV1.PodReady, Status: coreV1.ConditionTrue},
+	}}
+	notReadyStatus := coreV1.PodStatus{Phase: coreV1.PodRunning, Conditions: []coreV1.PodCondition{
+		{Type: coreV1.PodReady, Status: coreV1.ConditionFalse, Reason: "ContainersNotReady", Message: "containers not ready"},
+	}}
+	app, rs, owned := newOwnedOrigin(&metav1.LabelSelector{MatchLabels: labels})
+	defer cluster.SetIns(nil)
+
+	cluster.SetIns(fake.NewKubernetes(rs,
+		&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "default", Labels: labels,
+			OwnerReferences: owned}, Status: notReadyStatus},
+		&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-2", Namespace: "default", Labels: labels,
+			OwnerReferences: owned}, Status: readyStatus},
+	))
+	require.Nil(t, checkOriginHealthy(app), "one ready pod is enough")
+
+	cluster.SetIns(fake.NewKubernetes(rs,
+		&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "default", Labels: labels,
+			OwnerReferences: owned}, Status: notReadyStatus},
+		&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-kt-exchange-abcde", Namespace: "default", Labels: shadowLabels},
+			Status: readyStatus},
+		&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "sibling-1", Namespace: "default", Labels: labels},
+			Status: readyStatus},
+	))
+	err := checkOriginHealthy(app)
+	require.NotNil(t, err, "ready shadow pod or pod of other workload should not be counted")
+	require.Contains(t, err.Error(), "Ready=False (ContainersNotReady: containers not ready)")
+
+	cluster.SetIns(fake.NewKubernetes())
+	require.NotNil(t, checkOriginHealthy(app), "deployment without pod should fail")
+}
+
+func Test_tailOriginLogs(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	labels := map[string]string{"app": "demo"}
+	shadowLabels := map[string]string{"app": "demo", util.KtRole: util.RoleExchangeShadow}
+	app, rs, owned := newOwnedOrigin(&metav1.LabelSelector{MatchLabels: labels})
+	cluster.SetIns(fake.NewKubernetes(rs,
+		&coreV1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "default", Labels: labels, OwnerReferences: owned},
+			Spec:       coreV1.PodSpec{Containers: []coreV1.Container{{Name: "app"}, {Name: "sidecar"}}},
+		},
+		&coreV1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-kt-exchange-abcde", Namespace: "default", Labels: shadowLabels},
+			Spec:       coreV1.PodSpec{Containers: []coreV1.Container{{Name: "shadow"}}},
+		},
+		&coreV1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "sibling-1", Namespace: "default", Labels: labels},
+			Spec:       coreV1.PodSpec{Containers: []coreV1.Container{{Name: "app"}}},
+		},
+	))
+	defer cluster.SetIns(nil)
+	var buf bytes.Buffer
+	originLogWriter = &buf
+	defer func() { originLogWriter = os.Stdout }()
+
+	tailOriginLogs(app)
+	require.Eventually(t, func() bool {
+		originLogLock.Lock()
+		defer originLogLock.Unlock()
+		return strings.Contains(buf.String(), "[app-1/app] ") && strings.Contains(buf.String(), "[app-1/sidecar] ")
+	}, time.Second, 10*time.Millisecond)
+	originLogLock.Lock()
+	defer originLogLock.Unlock()
+	require.NotContains(t, buf.String(), "app-kt-exchange-abcde", "logs of shadow pod should not be printed")
+	require.NotContains(t, buf.String(), "sibling-1", "logs of pod of other workload should not be printed")
+}
+
+func Test_getPassthroughPorts(t *testing.T) {
+	app := &appV1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app"},
+		Spec: appV1.DeploymentSpec{
+			Template: coreV1.PodTemplateSpec{Spec: coreV1.PodSpec{Containers: []coreV1.Container{{Ports: []coreV1.ContainerPort{
+				{ContainerPort: 80}, {ContainerPort: 443}, {ContainerPort: 9090}, {ContainerPort: 53, Protocol: coreV1.ProtocolUDP},
+			}}}}},
+		},
+	}
+	defer func() {
+		opt.Get().Exchange.Passthrough = ""
+		opt.Get().Exchange.KeepOtherPorts = false
+		opt.Get().Exchange.HealthPort = 0
+	}()
+	ports, err := getPassthroughPorts(app, "8080:80")
+	require.Nil(t, err)
+	require.Empty(t, ports, "nothing passed through by default")
+	opt.Get().Exchange.KeepOtherPorts = true
+	ports, err = getPassthroughPorts(app, "8080:80")
+	require.Nil(t, err)
+	require.Equal(t, []int{443, 9090}, ports)
+	opt.Get().Exchange.KeepOtherPorts = false
+	opt.Get().Exchange.HealthPort = 8086
+	ports, err = getPassthroughPorts(app, "8080:80")
+	require.Nil(t, err)
+	require.Equal(t, []int{443, 9090}, ports, "unhandled ports passed through when shadow reports ready")
+	opt.Get().Exchange.HealthPort = 0
+
+	opt.Get().Exchange.Passthrough = "443, 443"
+	ports, err = getPassthroughPorts(app, "8080:80")
+	require.Nil(t, err)
+	require.Equal(t, []int{443}, ports)
+	for _, passthrough := range []string{"80", "8443", "53", "https"} {
+		opt.Get().Exchange.Passthrough = passthrough
+		_, err = getPassthroughPorts(app, "8080:80")
+		require.NotNil(t, err, "passthrough '%s' should fail", passthrough)
+	}
+}
@@ -0,0 +1,38 @@
+package exchange
+
+import (
+	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+)
+
+// CheckTrafficScope validate '--inboundOnly' and '--outboundOnly' options,
+// connectPid is pid of the running connect process, or -1 if there is none
+func CheckTrafficScope(targetCount int, connectPid int) error {
+	if opt.Get().Exchange.InboundOnly && opt.Get().Exchange.OutboundOnly {
+		return fmt.Errorf("'--inboundOnly' cannot be used together with '--outboundOnly'")
+	}
+	if opt.Get().Exchange.OutboundOnly && targetCount > 0 {
+		return fmt.Errorf("'--outboundOnly' only connects local to cluster, no resource should be specified")
+	}
+	if opt.Get().Exchange.InboundOnly && connectPid > 0 {
+		return fmt.Errorf("'--inboundOnly' is specified, but connect process %d is routing local traffic to cluster",
+			connectPid)
+	}
+	return nil
+}
+
+// OutboundScopeMessage describe where requests sent by local service go during exchange
+func OutboundScopeMessage(connectPid int) string {
+	if opt.Get().Exchange.InboundOnly {
+		return " Only inbound requests are redirected, requests from local are not routed to cluster"
+	} else if connectPid > 0 {
+		return fmt.Sprintf(" Requests from local are routed to cluster by connect process %d", connectPid)
+	}
+	return " Requests from local are not routed to cluster, run 'ktctl connect' to access cluster dependencies"
+}
+
+// GetConnectPid fetch pid of running connect process, or -1 if not exist
+func GetConnectPid() int {
+	return util.GetDaemonRunning(util.ComponentConnect)
+}
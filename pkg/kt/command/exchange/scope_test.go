@@ -0,0 +1,44 @@
+package exchange
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCheckTrafficScope(t *testing.T) {
+	defer func() {
+		opt.Get().Exchange.InboundOnly = false
+		opt.Get().Exchange.OutboundOnly = false
+	}()
+	cases := []struct {
+		inboundOnly  bool
+		outboundOnly bool
+		targetCount  int
+		connectPid   int
+		failed       bool
+	}{
+		{false, false, 1, -1, false},
+		{false, false, 1, 100, false},
+		{true, false, 1, -1, false},
+		{true, false, 1, 100, true},
+		{false, true, 0, -1, false},
+		{false, true, 0, 100, false},
+		{false, true, 1, -1, true},
+		{true, true, 0, -1, true},
+	}
+	for _, c := range cases {
+		opt.Get().Exchange.InboundOnly = c.inboundOnly
+		opt.Get().Exchange.OutboundOnly = c.outboundOnly
+		err := CheckTrafficScope(c.targetCount, c.connectPid)
+		require.Equal(t, c.failed, err != nil, "%+v", c)
+	}
+}
+
+func TestOutboundScopeMessage(t *testing.T) {
+	defer func() { opt.Get().Exchange.InboundOnly = false }()
+	require.Contains(t, OutboundScopeMessage(-1), "run 'ktctl connect'")
+	require.Contains(t, OutboundScopeMessage(100), "connect process 100")
+	opt.Get().Exchange.InboundOnly = true
+	require.Contains(t, OutboundScopeMessage(100), "Only inbound requests")
+}
@@ -4,23 +4,26 @@ import (
 	"fmt"
 	"github.com/alibaba/kt-connect/pkg/kt/command/general"
 	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/transmission"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sort"
 	"strings"
 )
 
-func BySelector(resourceName string) error {
+func BySelector(resourceName, expose string) error {
 	// Get service to exchange
-	svc, err := general.GetServiceByResourceName(resourceName, opt.Get().Global.Namespace)
+	svc, expose, targetPorts, err := getServiceAndPorts(resourceName, expose)
 	if err != nil {
 		return err
 	}
-	if port := util.FindInvalidRemotePort(opt.Get().Exchange.Expose, general.GetTargetPorts(svc)); port != "" {
-		return fmt.Errorf("target port %s not exists in service %s", port, svc.Name)
-	}
+	warnExternalTrafficPolicy(svc)
 
 	// Lock service to avoid conflict, must be first step
-	svc, err = general.LockService(svc.Name, opt.Get().Global.Namespace, 0);
+	svc, err = general.LockService(svc.Name, opt.Get().Global.Namespace, 0)
 	if err != nil {
 		return err
 	}
@@ -39,24 +42,195 @@ func BySelector(resourceName string) error {
 	}
 
 	// Create shadow pod
-	shadowName := svc.Name + util.ExchangePodInfix + strings.ToLower(util.RandomString(5))
-	shadowLabels := map[string]string{
-		util.KtRole:   util.RoleExchangeShadow,
-		util.KtTarget: util.RandomString(20),
+	shadowName, shadowLabels, annotation, err := getSelectorShadowMeta(svc, expose)
+	if err != nil {
+		return err
 	}
-	annotation := map[string]string{
-		util.KtConfig: fmt.Sprintf("service=%s", svc.Name),
+	localSshPort, err := general.CreateShadowAndInbound(shadowName, expose,
+		shadowLabels, annotation, targetPorts, getTargetPodSpec(svc))
+	if err != nil {
+		return err
+	}
+	if opt.Get().Exchange.HealthPort > 0 {
+		if err = proxyUnhandledPorts(svc, expose, targetPorts, shadowName, localSshPort); err != nil {
+			return err
+		}
 	}
-	if err = general.CreateShadowAndInbound(shadowName, opt.Get().Exchange.Expose,
-		shadowLabels, annotation, general.GetTargetPorts(svc)); err != nil {
+	if err = StartSync(shadowName, localSshPort); err != nil {
 		return err
 	}
 
+	logOtherServicesOfPods(svc)
+
 	// Let target service select shadow pod
-	opt.Store.Origin = svc.Name
+	opt.Store.Origin = util.Append(opt.Store.Origin, svc.Name)
+	if err = general.CheckSessionAffinity([]coreV1.Service{*svc}); err != nil {
+		return err
+	}
 	if err = general.UpdateServiceSelector(svc.Name, opt.Get().Global.Namespace, shadowLabels); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// logOtherServicesOfPods print services which select the same pods as exchanged service, they still route to origin
+func logOtherServicesOfPods(svc *coreV1.Service) {
+	log.Info().Msgf("Requests to service %s will be redirected to local", svc.Name)
+	svcs, err := cluster.Ins().GetServicesBySelector(svc.Spec.Selector, opt.Get().Global.Namespace)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to get services selecting pods of service %s", svc.Name)
+		return
+	}
+	for _, s := range svcs {
+		if s.Name != svc.Name {
+			log.Info().Msgf("Requests to service %s will NOT be redirected to local, it also selects pods of service %s",
+				s.Name, svc.Name)
+		}
+	}
+}
+
+// getServiceAndPorts get service to exchange, with expose ports resolved to its target ports
+func getServiceAndPorts(resourceName, expose string) (*coreV1.Service, string, map[int]string, error) {
+	svc, err := general.GetServiceByResourceName(resourceName, opt.Get().Global.Namespace)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	targetPorts := general.GetTargetPorts(svc)
+	if expose, err = resolveServicePorts(svc, expose, targetPorts); err != nil {
+		return nil, "", nil, err
+	}
+	if port := util.FindInvalidRemotePort(expose, targetPorts); port != "" {
+		return nil, "", nil, fmt.Errorf("target port %s not exists in service %s", port, svc.Name)
+	}
+	return svc, expose, targetPorts, nil
+}
+
+// getSelectorShadowMeta name, labels and annotations of shadow pod in selector mode
+func getSelectorShadowMeta(svc *coreV1.Service, expose string) (string, map[string]string, map[string]string, error) {
+	shadowName, err := getShadowName(svc.Name)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	shadowLabels := map[string]string{
+		util.KtRole:   util.RoleExchangeShadow,
+		util.KtTarget: util.RandomString(20),
+	}
+	if opt.Get().Exchange.ReuseShadow {
+		// stable name and target label, so that shadow pod can be found by later exchange
+		reuseKey := fmt.Sprintf("%s/%s/%s/%s", util.GetLocalUserName(), opt.Get().Global.Namespace, svc.Name, expose)
+		if opt.Get().Exchange.ShadowName == "" {
+			shadowName = svc.Name + util.ExchangePodInfix + util.ShortHash(reuseKey, 5)
+			if err = util.CheckNameValid(shadowName); err != nil {
+				return "", nil, nil, fmt.Errorf("%s, please specify shadow name via '--shadowName'", err)
+			}
+		}
+		shadowLabels[util.KtTarget] = util.ShortHash(reuseKey, 20)
+	}
+	annotation := withDebugPortAnnotation(map[string]string{
+		util.KtConfig: fmt.Sprintf("service=%s", svc.Name),
+	})
+	return shadowName, shadowLabels, annotation, nil
+}
+
+// getTargetPodSpec spec of any pod selected by the service, nil if not found
+func getTargetPodSpec(svc *coreV1.Service) *coreV1.PodSpec {
+	if len(svc.Spec.Selector) == 0 {
+		return nil
+	}
+	pods, err := getPodsByLabel(svc.Spec.Selector, svc.Namespace)
+	if err != nil || len(pods) == 0 {
+		return nil
+	}
+	return &pods[0].Spec
+}
+
+// proxyUnhandledPorts forward requests to target ports not exposed to a running origin pod, since shadow pod
+// keeps reporting ready, these requests would otherwise be refused
+func proxyUnhandledPorts(svc *coreV1.Service, expose string, targetPorts map[int]string,
+	shadowName string, localSshPort int) error {
+	ports := getUnhandledPorts(expose, targetPorts)
+	if len(ports) == 0 {
+		return nil
+	}
+	upstreamIp := getRunningOriginPodIp(svc)
+	if upstreamIp == "" {
+		log.Warn().Msgf("No running pod of service %s found, requests to ports %v would not be handled", svc.Name, ports)
+		return nil
+	}
+	log.Info().Msgf("Forwarding requests to ports %v of service %s to origin pod %s", ports, svc.Name, upstreamIp)
+	return transmission.ForwardRemotePortsToUpstream(ports, upstreamIp, localSshPort, util.PrivateKeyPath(shadowName))
+}
+
+// getUnhandledPorts target ports of service not exposed to local, in ascending order
+func getUnhandledPorts(expose string, targetPorts map[int]string) []int {
+	exposedPorts := make(map[int]bool)
+	for _, exposePort := range strings.Split(expose, ",") {
+		if _, remotePort, err := util.ParsePortMapping(exposePort); err == nil {
+			exposedPorts[remotePort] = true
+		}
+	}
+	ports := make([]int, 0)
+	for port := range targetPorts {
+		if !exposedPorts[port] {
+			ports = append(ports, port)
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+func getRunningOriginPodIp(svc *coreV1.Service) string {
+	if len(svc.Spec.Selector) == 0 {
+		return ""
+	}
+	pods, err := getPodsByLabel(svc.Spec.Selector, svc.Namespace)
+	if err != nil {
+		return ""
+	}
+	for _, pod := range pods {
+		if pod.Status.Phase == coreV1.PodRunning && pod.DeletionTimestamp == nil && pod.Status.PodIP != "" {
+			return pod.Status.PodIP
+		}
+	}
+	return ""
+}
+
+// resolveServicePorts replace remote port which is service port instead of target port with its target port
+func resolveServicePorts(svc *coreV1.Service, expose string, targetPorts map[int]string) (string, error) {
+	exposePorts := make([]string, 0)
+	for _, exposePort := range strings.Split(expose, ",") {
+		localPort, remotePort, err := util.ParsePortMapping(exposePort)
+		if err != nil {
+			return "", err
+		}
+		if _, exists := targetPorts[remotePort]; !exists {
+			for _, p := range svc.Spec.Ports {
+				if int(p.Port) != remotePort {
+					continue
+				}
+				targetPort := getTargetPortOf(p, targetPorts)
+				if targetPort > 0 && targetPort != remotePort {
+					log.Warn().Msgf("Port %d is service port of %s, exchanging its target port %d instead",
+						remotePort, svc.Name, targetPort)
+					remotePort = targetPort
+				}
+				break
+			}
+		}
+		exposePorts = append(exposePorts, fmt.Sprintf("%d:%d", localPort, remotePort))
+	}
+	return strings.Join(exposePorts, ","), nil
+}
+
+func getTargetPortOf(svcPort coreV1.ServicePort, targetPorts map[int]string) int {
+	if svcPort.TargetPort.Type == intstr.Int {
+		return svcPort.TargetPort.IntValue()
+	}
+	for port, name := range targetPorts {
+		if name == svcPort.TargetPort.StrVal {
+			return port
+		}
+	}
+	return -1
+}
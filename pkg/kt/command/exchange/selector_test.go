@@ -0,0 +1,45 @@
+package exchange
+
+import (
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"testing"
+)
+
+func Test_resolveServicePorts(t *testing.T) {
+	svc := &coreV1.Service{
+		Spec: coreV1.ServiceSpec{
+			Ports: []coreV1.ServicePort{
+				{Port: 80, TargetPort: intstr.FromInt(8080)},
+				{Port: 443, TargetPort: intstr.FromString("https")},
+				{Port: 9090, TargetPort: intstr.FromInt(9090)},
+			},
+		},
+	}
+	targetPorts := map[int]string{8080: "kt-8080", 8443: "https", 9090: "kt-9090"}
+	cases := []struct {
+		expose   string
+		expected string
+	}{
+		{"80", "80:8080"},
+		{"7001:80", "7001:8080"},
+		{"8080", "8080:8080"},
+		{"443,9090", "443:8443,9090:9090"},
+		{"3000", "3000:3000"},
+	}
+	for _, c := range cases {
+		expose, err := resolveServicePorts(svc, c.expose, targetPorts)
+		require.Nil(t, err)
+		require.Equal(t, c.expected, expose, c.expose)
+	}
+	_, err := resolveServicePorts(svc, "abc", targetPorts)
+	require.NotNil(t, err)
+}
+
+func Test_getUnhandledPorts(t *testing.T) {
+	targetPorts := map[int]string{8080: "kt-8080", 8443: "https", 9090: "kt-9090"}
+	require.Equal(t, []int{8443, 9090}, getUnhandledPorts("80:8080", targetPorts))
+	require.Equal(t, []int{8443}, getUnhandledPorts("80:8080,9090", targetPorts))
+	require.Empty(t, getUnhandledPorts("80:8080,8443,9090", targetPorts))
+}
@@ -0,0 +1,174 @@
+//go:build simulate
+
+package exchange
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/command/general"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster/fake"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"io"
+	appV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// simulateReplicas replicas of demo deployment in simulation
+const simulateReplicas = 2
+
+// Simulate walk through exchange in scale mode with an in-memory cluster, nothing in real cluster is touched,
+// only built with 'simulate' tag, so that fake clientset is not included in release binary
+func Simulate(resourceName, expose string) error {
+	_, name, err := general.ParseResourceName(resourceName)
+	if err != nil {
+		return err
+	}
+	namespace := opt.Get().Global.Namespace
+	localPort, remotePort, err := util.ParsePortMapping(strings.Split(strings.Split(expose, ",")[0], "/")[0])
+	if err != nil {
+		return err
+	}
+
+	step(1, "Preparing in-memory cluster with deployment %s (%d replicas) and service %s in namespace %s",
+		name, simulateReplicas, name, namespace)
+	app := newSimulateDeployment(name, namespace, remotePort)
+	cluster.SetIns(fake.NewKubernetes(app, &coreV1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: coreV1.ServiceSpec{
+			Selector: app.Spec.Selector.MatchLabels,
+			Ports:    []coreV1.ServicePort{{Port: int32(remotePort)}},
+		},
+	}))
+	defer cluster.SetIns(nil)
+
+	shadowName, err := getShadowName(name)
+	if err != nil {
+		return err
+	}
+	step(2, "Creating exchange shadow %s, which listens on port %d for requests of service %s",
+		shadowName, remotePort, name)
+	opt.Store.Replicas[name] = *app.Spec.Replicas
+	labels, err := getExchangeLabels(app)
+	if err != nil {
+		return err
+	}
+	podIp, podName, _, err := cluster.Ins().GetOrCreateShadow(shadowName, labels,
+		getExchangeAnnotation(name), map[string]string{}, expose, map[int]string{}, &app.Spec.Template.Spec)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("  Shadow pod %s is running at %s", podName, podIp)
+
+	down := int32(0)
+	step(3, "Scaling deployment %s from %d to 0 replicas, so that all requests go to shadow pod", name, simulateReplicas)
+	if err = cluster.Ins().ScaleTo(name, namespace, &down); err != nil {
+		return err
+	}
+
+	step(4, "Forwarding port %d of shadow pod to local port %d, using a local echo server as your service",
+		remotePort, localPort)
+	if err = simulateInbound(name, remotePort); err != nil {
+		return err
+	}
+
+	step(5, "Cleaning up: restoring deployment %s to %d replicas and removing shadow %s", name, simulateReplicas, shadowName)
+	replicas := int32(simulateReplicas)
+	if err = cluster.Ins().ScaleTo(name, namespace, &replicas); err != nil {
+		return err
+	}
+	if err = cluster.Ins().RemovePod(podName, namespace); err != nil {
+		return err
+	}
+	if err = cluster.Ins().RemoveConfigMap(shadowName, namespace); err != nil {
+		return err
+	}
+	opt.Store.Shadow = util.Remove(opt.Store.Shadow, shadowName)
+	delete(opt.Store.Replicas, name)
+	log.Info().Msgf("Simulation finished, run without '--simulate' to exchange %s in real cluster", resourceName)
+	return nil
+}
+
+func step(index int, format string, args ...any) {
+	log.Info().Msgf("[%d/5] %s", index, fmt.Sprintf(format, args...))
+}
+
+func newSimulateDeployment(name, namespace string, port int) *appV1.Deployment {
+	replicas := int32(simulateReplicas)
+	labels := map[string]string{"app": name}
+	return &appV1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: appV1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: coreV1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: coreV1.PodSpec{Containers: []coreV1.Container{{
+					Name:  name,
+					Ports: []coreV1.ContainerPort{{ContainerPort: int32(port)}},
+				}}},
+			},
+		},
+	}
+}
+
+// simulateInbound relay a request from a simulated shadow port to local echo server, and print the response
+func simulateInbound(name string, remotePort int) error {
+	local, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	defer local.Close()
+	go func() {
+		_ = http.Serve(local, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, "echo from local: %s %s", r.Method, r.URL.Path)
+		}))
+	}()
+
+	// in real exchange this is done by ssh reverse tunnel from shadow pod
+	shadow, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	defer shadow.Close()
+	go func() {
+		for {
+			conn, err2 := shadow.Accept()
+			if err2 != nil {
+				return
+			}
+			go relay(conn, local.Addr().String())
+		}
+	}()
+
+	url := fmt.Sprintf("http://%s/hello", shadow.Addr().String())
+	log.Info().Msgf("  Sending request to %s:%d/hello", name, remotePort)
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	rsp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	line, _ := bufio.NewReader(rsp.Body).ReadString('\n')
+	log.Info().Msgf("  Got response %d: %s", rsp.StatusCode, line)
+	return nil
+}
+
+func relay(conn net.Conn, address string) {
+	defer conn.Close()
+	target, err := net.Dial("tcp", address)
+	if err != nil {
+		return
+	}
+	defer target.Close()
+	go func() {
+		_, _ = io.Copy(target, conn)
+	}()
+	_, _ = io.Copy(conn, target)
+}
@@ -0,0 +1,10 @@
+//go:build !simulate
+
+package exchange
+
+import "fmt"
+
+// Simulate not available unless built with 'simulate' tag
+func Simulate(string, string) error {
+	return fmt.Errorf("simulation is not supported by this build, please use ktctl built with '-tags simulate'")
+}
@@ -0,0 +1,20 @@
+//go:build simulate
+
+package exchange
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSimulate(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	require.Nil(t, Simulate("deployment/demo", "8080:80"))
+	require.NotContains(t, opt.Store.Shadow, "demo", "shadow should be cleaned up after simulation")
+	_, exists := opt.Store.Replicas["demo"]
+	require.False(t, exists)
+	require.NotNil(t, Simulate("demo", "abc"), "invalid port should fail")
+	cluster.SetIns(nil)
+}
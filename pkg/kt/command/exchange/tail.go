@@ -0,0 +1,54 @@
+package exchange
+
+import (
+	"bufio"
+	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/rs/zerolog/log"
+	"io"
+	appV1 "k8s.io/api/apps/v1"
+	"os"
+	"sync"
+)
+
+// originLogWriter where logs of origin pods are printed to
+var originLogWriter io.Writer = os.Stdout
+
+// originLogLock avoid lines of different pods mixed up
+var originLogLock sync.Mutex
+
+// tailOriginLogs print logs of current origin pods in background, until the pod terminated or exchange exit
+func tailOriginLogs(app *appV1.Deployment) {
+	pods, err := getOriginPods(app)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to fetch pods of deployment %s", app.Name)
+		return
+	}
+	for _, pod := range pods {
+		for _, c := range pod.Spec.Containers {
+			prefix := pod.Name
+			if len(pod.Spec.Containers) > 1 {
+				prefix = fmt.Sprintf("%s/%s", pod.Name, c.Name)
+			}
+			go tailContainerLog(c.Name, pod.Name, prefix)
+		}
+	}
+}
+
+func tailContainerLog(containerName, podName, prefix string) {
+	stream, err := cluster.Ins().TailPodLogs(containerName, podName, opt.Get().Global.Namespace)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to tail logs of pod %s", prefix)
+		return
+	}
+	defer stream.Close()
+	log.Info().Msgf("Tailing logs of origin pod %s", prefix)
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		originLogLock.Lock()
+		_, _ = fmt.Fprintf(originLogWriter, "[%s] %s\n", prefix, scanner.Text())
+		originLogLock.Unlock()
+	}
+	log.Debug().Msgf("Logs of origin pod %s ended", prefix)
+}
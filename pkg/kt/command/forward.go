@@ -20,18 +20,19 @@ func NewForwardCommand() *cobra.Command {
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
 				return fmt.Errorf("a service name or target address must be specified")
-			} else if len(args) == 1 && strings.Contains(args[0], ".") {
+			} else if len(args) == 1 && strings.Contains(args[0], ".") && !forward.IsAddressMapping(args[0]) {
 				return fmt.Errorf("a port must be specified because '%s' is not a service name", args[0])
-			} else if len(args) > 2 {
-				return fmt.Errorf("too many target addresses are spcified (%s)", strings.Join(args, ",") )
+			} else if len(args) > 2 && !forward.IsAddressMapping(args[0]) {
+				return fmt.Errorf("too many target addresses are spcified (%s)", strings.Join(args, ","))
 			}
 			opt.Get().Global.UseLocalTime = true
-			return general.Prepare()
+			return prepareAndReap(cmd)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return Forward(args)
 		},
-		Example: "ktctl forward <service-name|remote-address> [<local-port>:<remote-port>] [command options]",
+		Example: "ktctl forward <service-name|remote-address> [<local-port>:<remote-port>] [command options]\n" +
+			"  ktctl forward <local-port>:<remote-address>:<remote-port> [<local-port>:<remote-address>:<remote-port> ...]",
 	}
 
 	cmd.SetUsageTemplate(general.UsageTemplate(true))
@@ -46,19 +47,37 @@ func Forward(args []string) error {
 	}
 
 	target := args[0]
+	if forward.IsAddressMapping(target) {
+		mappings, err2 := forward.ParseAddressMappings(args)
+		if err2 != nil {
+			return err2
+		}
+		if err = forward.RedirectAddresses(mappings); err != nil {
+			return err
+		}
+		util.StatusLog().Msg("---------------------------------------------------------------")
+		for _, m := range mappings {
+			util.StatusLog().Msgf(" Now you can access to '%s:%d' via 'localhost:%d'", m.Address, m.RemotePort, m.LocalPort)
+		}
+		util.StatusLog().Msg("---------------------------------------------------------------")
+		s := <-ch
+		log.Info().Msgf("Terminal Signal is %s", s)
+		return nil
+	}
+
 	localPort, remotePort, err := parsePort(args)
 	if err != nil {
 		return err
 	}
 
 	if strings.Contains(target, ".") {
-		err = forward.RedirectAddress(target, localPort, remotePort)
+		localPort, remotePort, err = forward.RedirectAddress(target, localPort, remotePort)
 		if err != nil {
 			return err
 		}
-		log.Info().Msg("---------------------------------------------------------------")
-		log.Info().Msgf(" Now you can access to '%s:%d' via 'localhost:%d'", target, remotePort, localPort)
-		log.Info().Msg("---------------------------------------------------------------")
+		util.StatusLog().Msg("---------------------------------------------------------------")
+		util.StatusLog().Msgf(" Now you can access to '%s:%d' via 'localhost:%d'", target, remotePort, localPort)
+		util.StatusLog().Msg("---------------------------------------------------------------")
 	} else {
 		localPort, err = forward.RedirectService(target, localPort, remotePort)
 		if err != nil {
@@ -68,9 +87,9 @@ func Forward(args []string) error {
 		if remotePort > 0 {
 			portMsg = fmt.Sprintf(" port %d of", remotePort)
 		}
-		log.Info().Msg("---------------------------------------------------------------")
-		log.Info().Msgf(" Now you can access%s service '%s' via 'localhost:%d'", portMsg, target, localPort)
-		log.Info().Msg("---------------------------------------------------------------")
+		util.StatusLog().Msg("---------------------------------------------------------------")
+		util.StatusLog().Msgf(" Now you can access%s service '%s' via 'localhost:%d'", portMsg, target, localPort)
+		util.StatusLog().Msg("---------------------------------------------------------------")
 	}
 
 	// watch background process, clean the workspace and exit if background process occur exception
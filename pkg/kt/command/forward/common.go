@@ -2,11 +2,17 @@ package forward
 
 import (
 	"fmt"
+	"github.com/alibaba/kt-connect/pkg/common"
 	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
 	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/sshchannel"
 	"github.com/alibaba/kt-connect/pkg/kt/transmission"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"net"
+	"strconv"
+	"strings"
 )
 
 func RedirectService(serviceName string, localPort, remotePort int) (int, error) {
@@ -25,15 +31,87 @@ func RedirectService(serviceName string, localPort, remotePort int) (int, error)
 	return localPort, err
 }
 
-func RedirectAddress(remoteAddress string, localPort, remotePort int) error {
+func RedirectAddress(remoteAddress string, localPort, remotePort int) (int, int, error) {
 	if remotePort <= 0 {
 		if localPort <= 0 {
-			return fmt.Errorf("port parameter must be specified")
+			return 0, 0, fmt.Errorf("port parameter must be specified")
 		} else {
 			remotePort = localPort
 		}
 	}
-	return fmt.Errorf("redirecting to an arbitrary address havn't been implemented yet")
+	if localPort <= 0 {
+		localPort = remotePort
+	}
+	err := RedirectAddresses([]AddressMapping{{LocalPort: localPort, Address: remoteAddress, RemotePort: remotePort}})
+	return localPort, remotePort, err
+}
+
+// AddressMapping local port to be forwarded to an in-cluster address
+type AddressMapping struct {
+	LocalPort  int
+	Address    string
+	RemotePort int
+}
+
+// IsAddressMapping check whether the argument is in '<local-port>:<address>:<port>' format
+func IsAddressMapping(arg string) bool {
+	return strings.Count(arg, ":") == 2
+}
+
+// ParseAddressMappings parse arguments in '<local-port>:<address>:<port>' format
+func ParseAddressMappings(args []string) ([]AddressMapping, error) {
+	mappings := make([]AddressMapping, 0)
+	localPorts := make(map[int]bool)
+	for _, arg := range args {
+		parts := strings.Split(arg, ":")
+		if len(parts) != 3 || parts[1] == "" {
+			return nil, fmt.Errorf("invalid mapping '%s', should be in '<local-port>:<address>:<port>' format", arg)
+		}
+		localPort, err := strconv.Atoi(parts[0])
+		if err != nil || localPort <= 0 || localPort > 65535 {
+			return nil, fmt.Errorf("local port '%s' format invalid", parts[0])
+		}
+		remotePort, err := strconv.Atoi(parts[2])
+		if err != nil || remotePort <= 0 || remotePort > 65535 {
+			return nil, fmt.Errorf("port '%s' format invalid", parts[2])
+		}
+		if localPorts[localPort] {
+			return nil, fmt.Errorf("local port %d is used by multiple mappings", localPort)
+		}
+		localPorts[localPort] = true
+		mappings = append(mappings, AddressMapping{LocalPort: localPort, Address: parts[1], RemotePort: remotePort})
+	}
+	return mappings, nil
+}
+
+// RedirectAddresses forward local ports to in-cluster addresses through a shadow pod
+func RedirectAddresses(mappings []AddressMapping) error {
+	// shadow pod would be re-created by deployment if gone, and port forward switches to the new one
+	opt.Get().Global.UseShadowDeployment = true
+	shadowName := util.RandomName("kt-forward-shadow-")
+	labels := map[string]string{
+		util.KtRole:   util.RoleForwardShadow,
+		util.KtTarget: util.RandomString(20),
+	}
+	_, podName, privateKeyPath, err := cluster.Ins().GetOrCreateShadow(shadowName, util.MergeMap(labels, nil),
+		map[string]string{}, map[string]string{}, "", map[int]string{}, nil)
+	if err != nil {
+		return err
+	}
+
+	localSshPort := util.GetRandomTcpPort()
+	if _, err = transmission.SetupPortForwardWithFailover(podName, labels, common.StandardSshPort, localSshPort); err != nil {
+		return err
+	}
+	sshAddress := fmt.Sprintf("%s:%d", common.Localhost, localSshPort)
+	for _, m := range mappings {
+		localEndpoint := fmt.Sprintf("%s:%d", common.Localhost, m.LocalPort)
+		remoteEndpoint := net.JoinHostPort(m.Address, strconv.Itoa(m.RemotePort))
+		if err = sshchannel.Ins().ForwardLocalToRemote(privateKeyPath, sshAddress, localEndpoint, remoteEndpoint); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func getPodNameAndPort(serviceName string, remotePort int, namespace string) (string, int, int, error) {
@@ -0,0 +1,25 @@
+package forward
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseAddressMappings(t *testing.T) {
+	require.True(t, IsAddressMapping("3306:10.0.0.5:3306"))
+	require.False(t, IsAddressMapping("8080:80"))
+	mappings, err := ParseAddressMappings([]string{"3306:10.0.0.5:3306", "16379:redis.cache:6379"})
+	require.Nil(t, err)
+	require.Equal(t, []AddressMapping{
+		{LocalPort: 3306, Address: "10.0.0.5", RemotePort: 3306},
+		{LocalPort: 16379, Address: "redis.cache", RemotePort: 6379},
+	}, mappings)
+	_, err = ParseAddressMappings([]string{"3306:10.0.0.5:3306", "3306:10.0.0.6:3306"})
+	require.NotNil(t, err, "same local port should fail")
+	_, err = ParseAddressMappings([]string{"3306::3306"})
+	require.NotNil(t, err, "empty address should fail")
+	_, err = ParseAddressMappings([]string{"abc:10.0.0.5:3306"})
+	require.NotNil(t, err, "invalid port should fail")
+	_, err = ParseAddressMappings([]string{"3306:10.0.0.5:3306", "8080:80"})
+	require.NotNil(t, err, "mixed format should fail")
+}
@@ -0,0 +1,66 @@
+package command
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/command/gateway"
+	"github.com/alibaba/kt-connect/pkg/kt/command/general"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+// NewGatewayCommand return new gateway command
+func NewGatewayCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gateway",
+		Short: "Install or uninstall shared gateway for 'ktctl connect --gateway'",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+		Example: "ktctl gateway <sub-command> [command options]",
+	}
+
+	install := &cobra.Command{
+		Use:   "install",
+		Short: "Deploy connect gateway to the namespace",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("too many options specified (%s)", strings.Join(args, ","))
+			}
+			if opt.Get().Gateway.Replicas < 1 {
+				return fmt.Errorf("replicas should be at least 1")
+			}
+			if opt.Get().Gateway.DnsProtocol != "tcp" && opt.Get().Gateway.DnsProtocol != "udp" {
+				return fmt.Errorf("dns protocol should be 'tcp' or 'udp'")
+			}
+			return general.Prepare(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return gateway.Install()
+		},
+		Example: "ktctl gateway install [command options]",
+	}
+	install.SetUsageTemplate(general.UsageTemplate(true))
+	opt.SetOptions(install, install.Flags(), opt.Get().Gateway, opt.GatewayFlags())
+
+	uninstall := &cobra.Command{
+		Use:   "uninstall",
+		Short: "Remove connect gateway from the namespace",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("too many options specified (%s)", strings.Join(args, ","))
+			}
+			return general.Prepare(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return gateway.Uninstall()
+		},
+		Example: "ktctl gateway uninstall [command options]",
+	}
+	uninstall.SetUsageTemplate(general.UsageTemplate(true))
+
+	cmd.AddCommand(install)
+	cmd.AddCommand(uninstall)
+	cmd.SetUsageTemplate(general.UsageTemplate(false))
+	return cmd
+}
@@ -0,0 +1,49 @@
+package gateway
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/common"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Install deploy connect gateway, which is shared by clients connecting with '--gateway'
+func Install() error {
+	namespace := opt.Get().Global.Namespace
+	if _, err := cluster.Ins().GetDeployment(util.ConnectGatewayName, namespace); err == nil {
+		return fmt.Errorf("connect gateway already installed in namespace %s", namespace)
+	} else if !k8sErrors.IsNotFound(err) {
+		return err
+	}
+	envs := map[string]string{
+		common.EnvVarDnsProtocol: opt.Get().Gateway.DnsProtocol,
+		common.EnvVarLogLevel:    "info",
+	}
+	if err := cluster.Ins().CreateConnectGateway(util.ConnectGatewayName,
+		int32(opt.Get().Gateway.Replicas), envs); err != nil {
+		return err
+	}
+	log.Info().Msgf("Connect gateway is starting, use 'ktctl connect --gateway -n %s' to connect through it", namespace)
+	return nil
+}
+
+// Uninstall remove connect gateway, clients connected through it would be disconnected
+func Uninstall() error {
+	namespace := opt.Get().Global.Namespace
+	if _, err := cluster.Ins().GetDeployment(util.ConnectGatewayName, namespace); k8sErrors.IsNotFound(err) {
+		return fmt.Errorf("connect gateway not found in namespace %s", namespace)
+	} else if err != nil {
+		return err
+	}
+	if err := cluster.Ins().RemoveDeployment(util.ConnectGatewayName, namespace); err != nil {
+		return err
+	}
+	if err := cluster.Ins().RemoveConfigMap(util.ConnectGatewayName, namespace); err != nil && !k8sErrors.IsNotFound(err) {
+		return err
+	}
+	log.Info().Msgf("Connect gateway removed from namespace %s", namespace)
+	return nil
+}
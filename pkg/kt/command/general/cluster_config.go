@@ -0,0 +1,158 @@
+package general
+
+import (
+	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+// policyGroup config map items in this group are policies instead of default options
+const policyGroup = "policy"
+
+// policyProtectedNamespaces policy item of namespaces in which exchange and mesh are refused
+const policyProtectedNamespaces = "protectedNamespaces"
+
+// policyMutateServiceNamespaces policy item of namespaces in which changing service selector requires '--mutateService'
+const policyMutateServiceNamespaces = "mutateServiceNamespaces"
+
+// policyModesSuffix policy item of allowed modes of a command, e.g. 'exchangeModes'
+const policyModesSuffix = "Modes"
+
+// loadClusterConfig read default options and policies from config map in cluster, options specified by user are kept,
+// default options are read from '--configNamespace', while policies are always read from kube-system,
+// so that they could not be redirected to a namespace without the config map
+func loadClusterConfig(cmd *cobra.Command) {
+	namespace := opt.Get().Global.ConfigNamespace
+	if namespace == "" {
+		namespace = util.NamespaceKubeSystem
+	}
+	if namespace != util.NamespaceKubeSystem {
+		if data := readClusterConfig(util.NamespaceKubeSystem); data != nil {
+			loadPolicies(data)
+		}
+	}
+	data := readClusterConfig(namespace)
+	if data == nil {
+		return
+	}
+	config := make(map[string]map[string]string)
+	for key, value := range data {
+		group, item, found := strings.Cut(key, ".")
+		if !found {
+			log.Warn().Msgf("Invalid cluster config item '%s', should be in '<group>.<item>' format", key)
+			continue
+		}
+		if group == policyGroup {
+			if namespace == util.NamespaceKubeSystem {
+				applyPolicy(item, value)
+			} else {
+				log.Warn().Msgf("Cluster policy '%s' in namespace %s is ignored, policies are only read from %s",
+					key, namespace, util.NamespaceKubeSystem)
+			}
+		} else if group == "global" || group == cmd.Name() {
+			if config[group] == nil {
+				config[group] = make(map[string]string)
+			}
+			// both 'imagePullSecret' and 'image-pull-secret' format are accepted, same as local config
+			config[group][util.DashSeparated(item)] = value
+		}
+	}
+	opt.MergeClusterConfig(config, func(group, key string) bool {
+		return cmd.Flags().Changed(util.UnCapitalize(util.Capitalize(key)))
+	})
+	log.Debug().Msgf("Loaded cluster config from %s/%s", namespace, util.KtClusterConfig)
+}
+
+// readClusterConfig fetch data of cluster config map in namespace, nil if not available
+func readClusterConfig(namespace string) map[string]string {
+	configMap, err := cluster.Ins().GetConfigMap(util.KtClusterConfig, namespace)
+	if err != nil {
+		log.Debug().Msgf("Cluster config %s not available in namespace %s: %s", util.KtClusterConfig, namespace, err)
+		return nil
+	}
+	return configMap.Data
+}
+
+// loadPolicies apply only policy items of cluster config
+func loadPolicies(data map[string]string) {
+	for key, value := range data {
+		if group, item, found := strings.Cut(key, "."); found && group == policyGroup {
+			applyPolicy(item, value)
+		}
+	}
+}
+
+func applyPolicy(item, value string) {
+	if item == policyProtectedNamespaces {
+		opt.Store.ProtectedNamespaces = value
+	} else if item == policyMutateServiceNamespaces {
+		opt.Store.MutateServiceNamespaces = value
+	} else if strings.HasSuffix(item, policyModesSuffix) {
+		if opt.Store.AllowedModes == nil {
+			opt.Store.AllowedModes = map[string]string{}
+		}
+		opt.Store.AllowedModes[strings.TrimSuffix(item, policyModesSuffix)] = value
+	} else {
+		log.Warn().Msgf("Unknown cluster policy '%s.%s'", policyGroup, item)
+	}
+}
+
+// CheckAllowedMode check whether mode of command is allowed by cluster policy
+func CheckAllowedMode(command, mode string) error {
+	allowed := opt.Store.AllowedModes[command]
+	if allowed == "" || mode == "" || util.Contains(strings.Split(allowed, ","), mode) {
+		return nil
+	}
+	return fmt.Errorf("%s mode '%s' is not allowed in this cluster, allowed modes are '%s'", command, mode, allowed)
+}
+
+// CheckProtectedNamespace check whether namespace is protected by cluster policy
+func CheckProtectedNamespace(namespace string) error {
+	if opt.Store.ProtectedNamespaces != "" &&
+		util.Contains(strings.Split(opt.Store.ProtectedNamespaces, ","), namespace) {
+		return fmt.Errorf("namespace %s is protected in this cluster, modifying resources in it is not allowed", namespace)
+	}
+	return nil
+}
+
+// defaultMutateServiceNamespaces namespaces in which changing service selector always requires '--mutateService',
+// more namespaces could be protected by cluster policy
+var defaultMutateServiceNamespaces = []string{util.NamespaceKubeSystem, "kube-public", "kube-node-lease"}
+
+// CheckServiceMutation check whether changing service selector in namespace is allowed,
+// it's refused in protected namespaces by default, unless '--mutateService' specified
+func CheckServiceMutation(namespace string) error {
+	if opt.Get().Global.MutateService {
+		return nil
+	}
+	namespaces := defaultMutateServiceNamespaces
+	if opt.Store.MutateServiceNamespaces != "" {
+		namespaces = append(strings.Split(opt.Store.MutateServiceNamespaces, ","), namespaces...)
+	}
+	if util.Contains(namespaces, "*") || util.Contains(namespaces, namespace) {
+		return fmt.Errorf("changing service selector in protected namespace %s is refused, "+
+			"please use '--mutateService' if you're sure, or use a method not changing service", namespace)
+	}
+	return nil
+}
+
+// commandMode get mode option of commands which have multiple modes,
+// exchange in auto mode is checked after the actual mode detected
+func commandMode(command string) string {
+	switch command {
+	case util.ComponentConnect:
+		return opt.Get().Connect.Mode
+	case util.ComponentExchange:
+		if opt.Get().Exchange.Mode == util.ExchangeModeAuto {
+			return ""
+		}
+		return opt.Get().Exchange.Mode
+	case util.ComponentMesh:
+		return opt.Get().Mesh.Mode
+	}
+	return ""
+}
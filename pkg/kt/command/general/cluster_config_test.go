@@ -0,0 +1,116 @@
+package general
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster/fake"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestLoadClusterConfig(t *testing.T) {
+	cluster.SetIns(fake.NewKubernetes(&coreV1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: util.KtClusterConfig, Namespace: util.NamespaceKubeSystem},
+		Data: map[string]string{
+			"global.image":               "registry.corp/kt-connect-shadow:latest",
+			"global.imagePullSecret":     "corp-registry",
+			"global.node-selector":       "pool=dev",
+			"exchange.mode":              util.ExchangeModeScale,
+			"mesh.mode":                  util.MeshModeManual,
+			"policy.exchangeModes":       "scale,selector",
+			"policy.protectedNamespaces": "prod,finance",
+		},
+	}))
+	defer cluster.SetIns(nil)
+	defer func() {
+		opt.Store.AllowedModes = nil
+		opt.Store.ProtectedNamespaces = ""
+		opt.Get().Global.NodeSelector = ""
+	}()
+
+	opt.Get().Global.ConfigNamespace = util.NamespaceKubeSystem
+	opt.Get().Exchange.Mode = util.ExchangeModeSelector
+	cmd := &cobra.Command{Use: util.ComponentExchange}
+	opt.SetOptions(cmd, cmd.Flags(), opt.Get().Global, opt.GlobalFlags())
+	opt.SetOptions(cmd, cmd.Flags(), opt.Get().Exchange, opt.ExchangeFlags())
+	require.Nil(t, cmd.ParseFlags([]string{"--imagePullSecret", "my-secret"}))
+
+	loadClusterConfig(cmd)
+	require.Equal(t, "registry.corp/kt-connect-shadow:latest", opt.Get().Global.Image)
+	require.Equal(t, "my-secret", opt.Get().Global.ImagePullSecret, "option specified by user should be kept")
+	require.Equal(t, "pool=dev", opt.Get().Global.NodeSelector, "dash separated item should be accepted")
+	require.Equal(t, util.ExchangeModeScale, opt.Get().Exchange.Mode)
+	require.NotEqual(t, util.MeshModeManual, opt.Get().Mesh.Mode, "options of other commands should not be loaded")
+
+	require.Nil(t, CheckAllowedMode(util.ComponentExchange, util.ExchangeModeScale))
+	require.NotNil(t, CheckAllowedMode(util.ComponentExchange, util.ExchangeModeEphemeral))
+	require.Nil(t, CheckAllowedMode(util.ComponentMesh, util.MeshModeAuto))
+	require.NotNil(t, CheckProtectedNamespace("prod"))
+	require.Nil(t, CheckProtectedNamespace("default"))
+}
+
+func TestLoadClusterConfig_emptyNamespace(t *testing.T) {
+	cluster.SetIns(fake.NewKubernetes(&coreV1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: util.KtClusterConfig, Namespace: util.NamespaceKubeSystem},
+		Data:       map[string]string{"policy.protectedNamespaces": "prod"},
+	}))
+	defer cluster.SetIns(nil)
+	defer func() {
+		opt.Store.ProtectedNamespaces = ""
+		opt.Get().Global.ConfigNamespace = util.NamespaceKubeSystem
+	}()
+
+	opt.Get().Global.ConfigNamespace = ""
+	loadClusterConfig(&cobra.Command{Use: util.ComponentExchange})
+	require.NotNil(t, CheckProtectedNamespace("prod"), "policy should not be skipped by empty namespace")
+}
+
+func TestLoadClusterConfig_otherNamespace(t *testing.T) {
+	cluster.SetIns(fake.NewKubernetes(
+		&coreV1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: util.KtClusterConfig, Namespace: util.NamespaceKubeSystem},
+			Data:       map[string]string{"policy.protectedNamespaces": "prod", "global.imagePullSecret": "corp-registry"},
+		},
+		&coreV1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: util.KtClusterConfig, Namespace: "team"},
+			Data:       map[string]string{"policy.protectedNamespaces": "", "global.imagePullSecret": "team-registry"},
+		},
+	))
+	defer cluster.SetIns(nil)
+	defer func() {
+		opt.Store.ProtectedNamespaces = ""
+		opt.Get().Global.ConfigNamespace = util.NamespaceKubeSystem
+		opt.Get().Global.ImagePullSecret = ""
+	}()
+
+	opt.Get().Global.ConfigNamespace = "team"
+	loadClusterConfig(&cobra.Command{Use: util.ComponentExchange})
+	require.Equal(t, "team-registry", opt.Get().Global.ImagePullSecret, "default options should be read from config namespace")
+	require.NotNil(t, CheckProtectedNamespace("prod"), "policy should not be overridden by config namespace")
+
+	opt.Store.ProtectedNamespaces = ""
+	opt.Get().Global.ConfigNamespace = "not-exist"
+	loadClusterConfig(&cobra.Command{Use: util.ComponentExchange})
+	require.NotNil(t, CheckProtectedNamespace("prod"), "policy should not be skipped by namespace without config map")
+}
+
+func TestCheckServiceMutation(t *testing.T) {
+	defer func() {
+		opt.Store.MutateServiceNamespaces = ""
+		opt.Get().Global.MutateService = false
+	}()
+	require.Nil(t, CheckServiceMutation("prod"), "only system namespaces are protected without policy")
+	require.NotNil(t, CheckServiceMutation(util.NamespaceKubeSystem), "system namespace should be protected by default")
+	applyPolicy(policyMutateServiceNamespaces, "prod,shared")
+	require.NotNil(t, CheckServiceMutation("prod"))
+	require.Nil(t, CheckServiceMutation("dev"))
+	require.NotNil(t, CheckServiceMutation(util.NamespaceKubeSystem), "policy should not unprotect system namespace")
+	applyPolicy(policyMutateServiceNamespaces, "*")
+	require.NotNil(t, CheckServiceMutation("dev"))
+	opt.Get().Global.MutateService = true
+	require.Nil(t, CheckServiceMutation("dev"))
+}
@@ -0,0 +1,66 @@
+package general
+
+import (
+	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"os"
+	"strings"
+)
+
+const (
+	// socketCommandStatus command to get status of current process
+	socketCommandStatus = "status"
+	// socketCommandTeardown command to exit current process, same as pressing Ctrl+C
+	socketCommandTeardown = "teardown"
+)
+
+// processStatus result of status command of event socket
+type processStatus struct {
+	Component string            `json:"component"`
+	Pid       int               `json:"pid"`
+	Namespace string            `json:"namespace"`
+	Shadow    []string          `json:"shadow,omitempty"`
+	Origin    []string          `json:"origin,omitempty"`
+	Phases    map[string]string `json:"phases,omitempty"`
+}
+
+// startEventSocket serve lifecycle events and commands via unix socket, if '--eventSocket' specified
+func startEventSocket(ch chan os.Signal) error {
+	if opt.Get().Global.EventSocket == "" {
+		return nil
+	}
+	return util.StartEventSocket(opt.Get().Global.EventSocket, func(command string) (any, error) {
+		return handleSocketCommand(command, ch)
+	})
+}
+
+func handleSocketCommand(command string, ch chan os.Signal) (any, error) {
+	switch command {
+	case socketCommandStatus:
+		return &processStatus{
+			Component: opt.Store.Component,
+			Pid:       os.Getpid(),
+			Namespace: opt.Get().Global.Namespace,
+			Shadow:    splitStored(opt.Store.Shadow),
+			Origin:    splitStored(opt.Store.Origin),
+			Phases:    util.ReachedPhases(),
+		}, nil
+	case socketCommandTeardown:
+		select {
+		case ch <- os.Interrupt:
+		default:
+			// exit already in progress
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown command '%s', supported are '%s', '%s'",
+		command, socketCommandStatus, socketCommandTeardown)
+}
+
+func splitStored(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
@@ -0,0 +1,104 @@
+package general
+
+import (
+	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// namespaceTerminated set to 1 once target namespace found terminated
+var namespaceTerminated int32 = 0
+
+// checkNamespace abort immediately if target namespace is terminating or not exist,
+// or create it when '--createNamespace' is specified
+func checkNamespace(namespace string) error {
+	ns, err := cluster.Ins().GetNamespace(namespace)
+	if cluster.IsNamespaceTerminated(ns, err) {
+		if err != nil && k8sErrors.IsNotFound(err) && opt.Get().Global.CreateNamespace {
+			return createNamespace(namespace)
+		}
+		if err != nil {
+			return fmt.Errorf("namespace %s not exists", namespace)
+		}
+		return fmt.Errorf("namespace %s is terminating, please use another namespace", namespace)
+	} else if err != nil {
+		log.Debug().Err(err).Msgf("Failed to check status of namespace %s", namespace)
+	}
+	return nil
+}
+
+func createNamespace(namespace string) error {
+	log.Info().Msgf("Creating namespace %s", namespace)
+	ns, err := cluster.Ins().CreateNamespace(namespace)
+	if err != nil {
+		return fmt.Errorf("failed to create namespace %s: %s", namespace, err)
+	}
+	opt.Store.CreatedNamespace = namespace
+	opt.Store.CreatedNamespaceUid = string(ns.UID)
+	return nil
+}
+
+// cleanCreatedNamespace remove namespace created by kt, if nothing else was created in it
+func cleanCreatedNamespace() error {
+	if opt.Store.CreatedNamespace == "" {
+		return nil
+	}
+	namespace := opt.Store.CreatedNamespace
+	ns, err := cluster.Ins().GetNamespace(namespace)
+	if cluster.IsNamespaceTerminated(ns, err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("get namespace %s failed: %s", namespace, err)
+	} else if ns.Labels[util.ControlBy] != util.KubernetesToolkit || string(ns.UID) != opt.Store.CreatedNamespaceUid {
+		log.Warn().Msgf("Namespace %s is not created by current kt process, keep it", namespace)
+		return nil
+	}
+	// pods of removed shadow deployment may take a while to be deleted
+	for i := 0; ; i++ {
+		empty, err2 := cluster.Ins().IsNamespaceEmpty(namespace)
+		if err2 != nil {
+			log.Warn().Err(err2).Msgf("Unable to verify namespace %s is empty, keep it", namespace)
+			return nil
+		} else if empty {
+			break
+		} else if i >= util.NamespaceEmptyCheckTimes {
+			log.Info().Msgf("Namespace %s is not empty, keep it", namespace)
+			return nil
+		}
+		time.Sleep(1 * time.Second)
+	}
+	log.Info().Msgf("Cleaning namespace %s", namespace)
+	if err = cluster.Ins().RemoveNamespace(namespace); err != nil {
+		log.Error().Err(err).Msgf("Delete namespace %s failed", namespace)
+		return fmt.Errorf("delete namespace %s failed: %s", namespace, err)
+	}
+	return nil
+}
+
+// watchNamespace notify process to exit once target namespace is terminating or deleted
+func watchNamespace(namespace string, ch chan os.Signal) {
+	ticker := time.NewTicker(util.NamespaceCheckIntervalSec * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		ns, err := cluster.Ins().GetNamespace(namespace)
+		if cluster.IsNamespaceTerminated(ns, err) {
+			log.Error().Msgf("Namespace %s is terminated, exiting", namespace)
+			atomic.StoreInt32(&namespaceTerminated, 1)
+			ch <- os.Interrupt
+			return
+		} else if k8sErrors.IsForbidden(err) {
+			log.Debug().Msgf("No permission to check status of namespace %s", namespace)
+			return
+		}
+	}
+}
+
+func isNamespaceTerminated() bool {
+	return atomic.LoadInt32(&namespaceTerminated) == 1
+}
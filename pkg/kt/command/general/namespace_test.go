@@ -0,0 +1,44 @@
+package general
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster/fake"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestCheckNamespace_createNamespace(t *testing.T) {
+	cluster.SetIns(fake.NewKubernetes(&coreV1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shared"}}))
+	defer cluster.SetIns(nil)
+	defer func() {
+		opt.Get().Global.CreateNamespace = false
+		opt.Store.CreatedNamespace = ""
+		opt.Store.CreatedNamespaceUid = ""
+	}()
+
+	require.NotNil(t, checkNamespace("fresh"), "namespace not exist should fail")
+	opt.Get().Global.CreateNamespace = true
+	require.Nil(t, checkNamespace("fresh"))
+	require.Equal(t, "fresh", opt.Store.CreatedNamespace)
+	ns, err := cluster.Ins().GetNamespace("fresh")
+	require.Nil(t, err)
+	require.Equal(t, util.KubernetesToolkit, ns.Labels[util.ControlBy])
+	require.Nil(t, cleanCreatedNamespace())
+	_, err = cluster.Ins().GetNamespace("fresh")
+	require.NotNil(t, err, "empty namespace created by kt should be removed")
+
+	require.Nil(t, checkNamespace("fresh"))
+	opt.Store.CreatedNamespaceUid = "uid-of-previous-namespace"
+	require.Nil(t, cleanCreatedNamespace())
+	_, err = cluster.Ins().GetNamespace("fresh")
+	require.Nil(t, err, "namespace re-created by others should be kept")
+
+	opt.Store.CreatedNamespace = "shared"
+	require.Nil(t, cleanCreatedNamespace())
+	_, err = cluster.Ins().GetNamespace("shared")
+	require.Nil(t, err, "namespace not created by kt should be kept")
+}
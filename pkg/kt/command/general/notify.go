@@ -0,0 +1,172 @@
+package general
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// LifecycleActive event sent when exchange takes effect
+	LifecycleActive = "active"
+	// LifecycleTeardown event sent when exchange starts to restore the origin
+	LifecycleTeardown = "teardown"
+	// notifyTimeout timeout of each notify request
+	notifyTimeout = 5 * time.Second
+)
+
+// LifecycleEvent payload posted to '--notifyUrl'
+type LifecycleEvent struct {
+	Time      string            `json:"time"`
+	Event     string            `json:"event"`
+	Component string            `json:"component"`
+	Namespace string            `json:"namespace"`
+	Pid       int               `json:"pid"`
+	Targets   []LifecycleTarget `json:"targets,omitempty"`
+}
+
+// LifecycleTarget exchanged resource and its exposed ports
+type LifecycleTarget struct {
+	Resource string          `json:"resource"`
+	Ports    []LifecyclePort `json:"ports"`
+}
+
+// LifecyclePort local port receiving requests sent to remote port
+type LifecyclePort struct {
+	Local    int    `json:"local"`
+	Remote   int    `json:"remote"`
+	Protocol string `json:"protocol"`
+}
+
+// notifiedTargets targets reported by active event, teardown is only notified after active
+var notifiedTargets []LifecycleTarget
+
+// notifyLock guard notifiedTargets, since teardown could be triggered by signal while exchange is activating
+var notifyLock sync.Mutex
+
+// NewLifecycleTarget convert resource and its '--expose' value to lifecycle target
+func NewLifecycleTarget(resource, expose string) LifecycleTarget {
+	target := LifecycleTarget{Resource: resource, Ports: []LifecyclePort{}}
+	for _, exposePort := range strings.Split(expose, ",") {
+		portMapping, protocol, err := util.SplitExposeProtocol(exposePort)
+		if err != nil {
+			continue
+		}
+		if localPort, remotePort, err2 := util.ParsePortMapping(portMapping); err2 == nil {
+			target.Ports = append(target.Ports, LifecyclePort{Local: localPort, Remote: remotePort, Protocol: protocol})
+		}
+	}
+	return target
+}
+
+// ParseNotifySignal parse '<activeSignal>[:<teardownSignal>]' parameter,
+// same signal is used for both events if teardown signal not specified
+func ParseNotifySignal(text string) (os.Signal, os.Signal, error) {
+	parts := strings.Split(text, ":")
+	if len(parts) > 2 {
+		return nil, nil, fmt.Errorf("invalid notify signal '%s', should be '<signal>[:<teardownSignal>]'", text)
+	}
+	activeSignal, err := util.ParseSignal(parts[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	teardownSignal := activeSignal
+	if len(parts) == 2 {
+		if teardownSignal, err = util.ParseSignal(parts[1]); err != nil {
+			return nil, nil, err
+		}
+	}
+	return activeSignal, teardownSignal, nil
+}
+
+// NotifyActive tell local process that exchange of targets is active
+func NotifyActive(targets []LifecycleTarget) {
+	notifyLock.Lock()
+	notifiedTargets = targets
+	notifyLock.Unlock()
+	notifyLifecycle(LifecycleActive, targets)
+}
+
+// notifyTeardown tell local process that exchange is about to be restored, only if active event was sent
+func notifyTeardown() {
+	notifyLock.Lock()
+	targets := notifiedTargets
+	notifiedTargets = nil
+	notifyLock.Unlock()
+	if targets == nil {
+		return
+	}
+	notifyLifecycle(LifecycleTeardown, targets)
+}
+
+func notifyLifecycle(event string, targets []LifecycleTarget) {
+	if opt.Get().Exchange.NotifySignal != "" {
+		sendNotifySignal(event, opt.Get().Exchange.NotifySignal)
+	}
+	if opt.Get().Exchange.NotifyUrl != "" {
+		postLifecycleEvent(opt.Get().Exchange.NotifyUrl, LifecycleEvent{
+			Time:      time.Now().Format(time.RFC3339),
+			Event:     event,
+			Component: opt.Store.Component,
+			Namespace: opt.Get().Global.Namespace,
+			Pid:       os.Getpid(),
+			Targets:   targets,
+		})
+	}
+}
+
+func sendNotifySignal(event, notifySignal string) {
+	activeSignal, teardownSignal, err := ParseNotifySignal(notifySignal)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to send %s signal", event)
+		return
+	}
+	process := opt.Store.ExecProcess
+	if process == nil {
+		log.Warn().Msgf("No command started by '--exec' to send %s signal", event)
+		return
+	}
+	sig := activeSignal
+	if event == LifecycleTeardown {
+		sig = teardownSignal
+	}
+	if err = process.Signal(sig); err != nil {
+		if errors.Is(err, os.ErrProcessDone) {
+			log.Debug().Msgf("Command of pid %d already finished, %s signal skipped", process.Pid, event)
+		} else {
+			log.Warn().Err(err).Msgf("Failed to send %s signal %s to process %d", event, sig, process.Pid)
+		}
+		return
+	}
+	log.Info().Msgf("Sent %s signal %s to process %d", event, sig, process.Pid)
+}
+
+// postLifecycleEvent failure of notifying is only logged, it should not break exchange or its teardown
+func postLifecycleEvent(url string, event LifecycleEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to encode %s event", event.Event)
+		return
+	}
+	client := &http.Client{Timeout: notifyTimeout}
+	rsp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to notify %s event to %s", event.Event, url)
+		return
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode >= 300 {
+		log.Warn().Msgf("Notify %s event to %s responded with status %d", event.Event, url, rsp.StatusCode)
+		return
+	}
+	log.Info().Msgf("Notified %s event to %s", event.Event, url)
+}
@@ -0,0 +1,61 @@
+package general
+
+import (
+	"encoding/json"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+)
+
+func TestNewLifecycleTarget(t *testing.T) {
+	require.Equal(t, LifecycleTarget{Resource: "deployment/app", Ports: []LifecyclePort{
+		{Local: 8080, Remote: 80, Protocol: util.ProtocolTcp},
+		{Local: 9090, Remote: 9090, Protocol: util.ProtocolUdp},
+	}}, NewLifecycleTarget("deployment/app", "8080:80,9090/udp"))
+}
+
+func TestParseNotifySignal(t *testing.T) {
+	if util.IsWindows() {
+		t.Skip("signal is not supported on windows")
+	}
+	usr1, _ := util.ParseSignal("SIGUSR1")
+	active, teardown, err := ParseNotifySignal("USR1:sigterm")
+	require.Nil(t, err)
+	require.Equal(t, usr1, active)
+	require.Equal(t, syscall.SIGTERM, teardown)
+	active, teardown, err = ParseNotifySignal("HUP")
+	require.Nil(t, err)
+	require.Equal(t, active, teardown, "teardown signal should default to active signal")
+	_, _, err = ParseNotifySignal("123:USR1:USR2")
+	require.NotNil(t, err, "pid is no longer accepted")
+	_, _, err = ParseNotifySignal("NOPE")
+	require.NotNil(t, err, "unknown signal should fail")
+}
+
+func TestNotifyLifecycle(t *testing.T) {
+	var events []LifecycleEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event LifecycleEvent
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&event))
+		events = append(events, event)
+	}))
+	defer server.Close()
+	opt.Get().Exchange.NotifyUrl = server.URL
+	defer func() { opt.Get().Exchange.NotifyUrl = "" }()
+
+	notifyTeardown()
+	require.Empty(t, events, "teardown should not be notified before active")
+	targets := []LifecycleTarget{NewLifecycleTarget("deployment/app", "8080")}
+	NotifyActive(targets)
+	notifyTeardown()
+	notifyTeardown()
+	require.Len(t, events, 2, "teardown should be notified only once")
+	require.Equal(t, LifecycleActive, events[0].Event)
+	require.Equal(t, targets, events[0].Targets)
+	require.Equal(t, LifecycleTeardown, events[1].Event)
+	require.Equal(t, targets, events[1].Targets)
+}
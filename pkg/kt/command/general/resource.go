@@ -3,6 +3,7 @@ package general
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/alibaba/kt-connect/pkg/common"
 	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
 	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
 	"github.com/alibaba/kt-connect/pkg/kt/transmission"
@@ -12,22 +13,56 @@ import (
 	coreV1 "k8s.io/api/core/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
-func CreateShadowAndInbound(shadowPodName, portsToExpose string, labels, annotations map[string]string, portNameDict map[int]string) error {
+// CreateShadowAndInbound create shadow pod and forward its ports to local, return the local ssh port of shadow pod
+func CreateShadowAndInbound(shadowPodName, portsToExpose string, labels, annotations map[string]string, portNameDict map[int]string,
+	target *coreV1.PodSpec) (int, error) {
 
-	envs := make(map[string]string)
-	_, podName, privateKeyPath, err := cluster.Ins().GetOrCreateShadow(shadowPodName, labels, annotations, envs, portsToExpose, portNameDict)
+	podName, privateKeyPath, err := CreateShadow(shadowPodName, portsToExpose, labels, annotations, portNameDict, target)
 	if err != nil {
-		return err
+		return -1, err
 	}
+	return Inbound(shadowPodName, portsToExpose, podName, privateKeyPath)
+}
 
-	if _, err = transmission.ForwardPodToLocal(portsToExpose, podName, privateKeyPath); err != nil {
-		return err
+// CreateShadow create shadow pod without forwarding its ports, return name of the pod and path of its private key
+func CreateShadow(shadowPodName, portsToExpose string, labels, annotations map[string]string, portNameDict map[int]string,
+	target *coreV1.PodSpec) (string, string, error) {
+	_, podName, privateKeyPath, err := cluster.Ins().GetOrCreateShadow(shadowPodName, labels, annotations, getShadowEnvs(),
+		portsToExpose, portNameDict, target)
+	return podName, privateKeyPath, err
+}
+
+// Inbound forward ports of shadow pod created by CreateShadow to local, return the local ssh port of shadow pod
+func Inbound(shadowPodName, portsToExpose, podName, privateKeyPath string) (int, error) {
+	localSshPort, err := transmission.ForwardPodToLocal(portsToExpose, podName, privateKeyPath)
+	if err != nil {
+		return -1, err
 	}
-	return nil
+	if opt.Store.Component == util.ComponentExchange && opt.Get().Exchange.KeyRotateInterval > 0 {
+		SetupSshKeyRotation(shadowPodName, privateKeyPath, localSshPort,
+			time.Duration(opt.Get().Exchange.KeyRotateInterval)*time.Minute)
+	}
+	return localSshPort, nil
+}
+
+// RenderShadow generate resources of shadow pod without creating them
+func RenderShadow(shadowPodName, portsToExpose string, labels, annotations map[string]string, portNameDict map[int]string,
+	target *coreV1.PodSpec) ([]any, error) {
+	return cluster.Ins().RenderShadow(shadowPodName, labels, annotations, getShadowEnvs(), portsToExpose, portNameDict, target)
+}
+
+func getShadowEnvs() map[string]string {
+	envs := make(map[string]string)
+	if opt.Store.Component == util.ComponentExchange && opt.Get().Exchange.HealthPort > 0 {
+		envs[common.EnvVarHealthPort] = strconv.Itoa(opt.Get().Exchange.HealthPort)
+	}
+	return envs
 }
 
 func GetServiceByResourceName(resourceName, namespace string) (*coreV1.Service, error) {
@@ -92,22 +127,140 @@ func GetDeploymentByResourceName(resourceName, namespace string) (*appV1.Deploym
 	}
 }
 
+// builtinKindGroups api group of built-in resource kinds, used to validate fully-qualified resource names
+var builtinKindGroups = map[string]string{
+	"pod":        "",
+	"service":    "",
+	"deployment": "apps",
+	"job":        "batch",
+}
+
+// builtinKindAliases short names of built-in resource kinds, validated against group of their full names
+var builtinKindAliases = map[string]string{
+	"po":     "pod",
+	"svc":    "service",
+	"deploy": "deployment",
+}
+
+// ParseResourceName parse resource name in '<name>', '<type>/<name>', 'v1/<kind>/<name>' or
+// '<group>/<version>/<kind>/<name>' format, use '\/' for '/' and '\\' for '\' in name
 func ParseResourceName(resourceName string) (string, string, error) {
-	segments := strings.Split(resourceName, "/")
-	var resourceType, name string
-	if len(segments) > 2 {
-		return "", "", fmt.Errorf("invalid resource name: %s", resourceName)
-	} else if len(segments) == 2 {
-		resourceType = segments[0]
-		name = segments[1]
-	} else {
-		resourceType = "service"
-		name = resourceName
+	segments, err := SplitResourceName(resourceName)
+	if err != nil {
+		return "", "", err
+	}
+	switch len(segments) {
+	case 1:
+		return "service", segments[0], nil
+	case 2:
+		return segments[0], segments[1], nil
+	case 3:
+		// only core group could be omitted, e.g. 'v1/service/foo'
+		if segments[0] != "v1" {
+			return "", "", fmt.Errorf("ambiguous resource name '%s', please use '<group>/<version>/<kind>/<name>' "+
+				"format, or escape '/' in name with '\\/'", resourceName)
+		}
+		return parseQualifiedResource(resourceName, "", segments[1], segments[2])
+	case 4:
+		return parseQualifiedResource(resourceName, segments[0], segments[2], segments[3])
+	default:
+		return "", "", fmt.Errorf("invalid resource name '%s', please escape '/' in name with '\\/'", resourceName)
+	}
+}
+
+// SplitResourceName split resource name by unescaped '/', escaped characters in each segment are restored
+func SplitResourceName(resourceName string) ([]string, error) {
+	var segments []string
+	var segment strings.Builder
+	for i := 0; i < len(resourceName); i++ {
+		switch resourceName[i] {
+		case '\\':
+			if i+1 >= len(resourceName) || (resourceName[i+1] != '/' && resourceName[i+1] != '\\') {
+				return nil, fmt.Errorf("invalid escape in resource name '%s', only '\\/' and '\\\\' are supported",
+					resourceName)
+			}
+			i++
+			segment.WriteByte(resourceName[i])
+		case '/':
+			segments = append(segments, segment.String())
+			segment.Reset()
+		default:
+			segment.WriteByte(resourceName[i])
+		}
+	}
+	segments = append(segments, segment.String())
+	for _, seg := range segments {
+		if seg == "" {
+			return nil, fmt.Errorf("invalid resource name '%s', empty segment is not allowed", resourceName)
+		}
+	}
+	return segments, nil
+}
+
+func parseQualifiedResource(resourceName, group, kind, name string) (string, string, error) {
+	resourceType := strings.ToLower(kind)
+	fullType := resourceType
+	if fullName, exists := builtinKindAliases[resourceType]; exists {
+		fullType = fullName
+	}
+	if expected, exists := builtinKindGroups[fullType]; exists && expected != group {
+		if expected == "" {
+			return "", "", fmt.Errorf("kind %s in resource name '%s' should belong to core group, e.g. 'v1/%s/%s'",
+				kind, resourceName, resourceType, name)
+		}
+		return "", "", fmt.Errorf("kind %s in resource name '%s' should belong to group '%s'",
+			kind, resourceName, expected)
 	}
 	return resourceType, name, nil
 }
 
+// CheckSessionAffinity warn about services pinning clients to specific pods,
+// or disable their session affinity during exchange if '--resetAffinity' specified
+func CheckSessionAffinity(svcs []coreV1.Service) error {
+	for _, svc := range svcs {
+		if svc.Spec.SessionAffinity != coreV1.ServiceAffinityClientIP {
+			continue
+		}
+		if !opt.Get().Exchange.ResetAffinity {
+			log.Warn().Msgf("Service %s has '%s' session affinity, existing clients may keep reaching origin pods, "+
+				"use '--resetAffinity' to disable it during exchange", svc.Name, coreV1.ServiceAffinityClientIP)
+			continue
+		}
+		if err := disableSessionAffinity(svc.Name, svc.Namespace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func disableSessionAffinity(svcName, namespace string) error {
+	svc, err := cluster.Ins().GetService(svcName, namespace)
+	if err != nil {
+		return err
+	}
+	// keep the recorded one if already exists, which is left by another exchange
+	if svc.Annotations == nil || svc.Annotations[util.KtSessionAffinity] == "" {
+		rawConfig, err2 := json.Marshal(svc.Spec.SessionAffinityConfig)
+		if err2 != nil {
+			log.Error().Err(err2).Msgf("Unable to record original session affinity of service %s", svc.Name)
+			return err2
+		}
+		svc.Annotations = util.MapPut(svc.Annotations, util.KtSessionAffinity, string(rawConfig))
+	}
+	svc.Spec.SessionAffinity = coreV1.ServiceAffinityNone
+	svc.Spec.SessionAffinityConfig = nil
+	if _, err = cluster.Ins().UpdateService(svc); err != nil {
+		return err
+	}
+	opt.Store.AffinityService = util.Append(opt.Store.AffinityService, svcName)
+	log.Info().Msgf("Session affinity of service %s disabled during exchange", svcName)
+	return nil
+}
+
 func UpdateServiceSelector(svcName, namespace string, selector map[string]string) error {
+	if err := CheckServiceMutation(namespace); err != nil {
+		return err
+	}
 	svc, err := cluster.Ins().GetService(svcName, namespace)
 	if err != nil {
 		return err
@@ -139,7 +292,12 @@ func UpdateServiceSelector(svcName, namespace string, selector map[string]string
 			return err
 		}
 	}
+	// record selectors, so that service will not be overwritten during recover if anyone else changed it
+	appliedSelector, _ := json.Marshal(selector)
+	opt.Store.ServiceSelectors[svcName] = marshaledSelector
+	opt.Store.AppliedSelectors[svcName] = string(appliedSelector)
 
+	var warnOnce sync.Once
 	go cluster.Ins().WatchService(svcName, namespace, nil, nil, func(newSvc *coreV1.Service) {
 		if pods, err2 := cluster.Ins().GetPodsByLabel(selector, namespace); err2 != nil || len(pods.Items) == 0 {
 			log.Warn().Msgf("Router pod has gone")
@@ -153,11 +311,18 @@ func UpdateServiceSelector(svcName, namespace string, selector map[string]string
 		if !isServiceChanged(newSvc, selector, marshaledSelector) {
 			return
 		}
+		if !isSelectorReset(newSvc, selector, marshaledSelector) {
+			// selector changed by others is not overwritten, so that it could be detected and kept on exit
+			warnOnce.Do(func() {
+				log.Warn().Msgf("Selector of service %s was changed by others, it will not be recovered on exit", svcName)
+			})
+			return
+		}
 		log.Debug().Msgf("Change in service %s detected", svcName)
 		// delay and double check to avoid multiple clients conflict
 		time.Sleep(util.RandomSeconds(1, 10))
 		if svc, err = cluster.Ins().GetService(svcName, namespace); err == nil {
-			if isServiceChanged(svc, selector, marshaledSelector) {
+			if isServiceChanged(svc, selector, marshaledSelector) && isSelectorReset(svc, selector, marshaledSelector) {
 				svc.Spec.Selector = selector
 				svc.Annotations = util.MapPut(svc.Annotations, util.KtSelector, marshaledSelector)
 				if _, err = cluster.Ins().UpdateService(svc); err != nil {
@@ -203,6 +368,16 @@ func isServiceChanged(svc *coreV1.Service, selector map[string]string, marshaled
 	return !util.MapEquals(svc.Spec.Selector, selector) || svc.Annotations == nil || svc.Annotations[util.KtSelector] != marshaledSelector
 }
 
+// isSelectorReset check whether service is reset back to its original selector, or only lost the kt annotation,
+// in which case selector of kt should be re-applied, other changes are made by third party and should be kept
+func isSelectorReset(svc *coreV1.Service, selector map[string]string, marshaledSelector string) bool {
+	if util.MapEquals(svc.Spec.Selector, selector) {
+		return true
+	}
+	current, _ := json.Marshal(svc.Spec.Selector)
+	return string(current) == marshaledSelector
+}
+
 func getServiceByDeployment(app *appV1.Deployment, namespace string) (*coreV1.Service, error) {
 	svcList, err := cluster.Ins().GetServicesBySelector(app.Spec.Selector.MatchLabels, namespace)
 	if err != nil {
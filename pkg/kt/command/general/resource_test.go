@@ -0,0 +1,68 @@
+package general
+
+import (
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestParseResourceName(t *testing.T) {
+	accepted := map[string][]string{
+		"foo":                        {"service", "foo"},
+		"deploy/foo":                 {"deploy", "foo"},
+		"v1/service/foo":             {"service", "foo"},
+		"v1/Pod/foo":                 {"pod", "foo"},
+		"apps/v1/deployment/foo":     {"deployment", "foo"},
+		"apps/v1/deploy/foo":         {"deploy", "foo"},
+		"v1/svc/foo":                 {"svc", "foo"},
+		"batch/v1/Job/foo":           {"job", "foo"},
+		"argoproj.io/v1/rollout/foo": {"rollout", "foo"},
+		"svc/foo\\/bar":              {"svc", "foo/bar"},
+		"foo\\\\bar":                 {"service", "foo\\bar"},
+		"apps/v1/deployment/a\\/b":   {"deployment", "a/b"},
+	}
+	for resourceName, expected := range accepted {
+		resourceType, name, err := ParseResourceName(resourceName)
+		require.Nil(t, err, resourceName)
+		require.Equal(t, expected[0], resourceType, resourceName)
+		require.Equal(t, expected[1], name, resourceName)
+	}
+
+	rejected := map[string]string{
+		"apps/deployment/foo":     "ambiguous",
+		"a/b/c/d/e":               "escape '/'",
+		"batch/v1/deployment/foo": "group 'apps'",
+		"apps/v1/service/foo":     "core group",
+		"apps/v1/svc/foo":         "core group",
+		"batch/v1/deploy/foo":     "group 'apps'",
+		"v1/deploy/foo":           "group 'apps'",
+		"deploy/foo\\bar":         "invalid escape",
+		"deploy/foo\\":            "invalid escape",
+		"deploy/":                 "empty segment",
+		"/foo":                    "empty segment",
+	}
+	for resourceName, message := range rejected {
+		_, _, err := ParseResourceName(resourceName)
+		require.NotNil(t, err, resourceName)
+		require.Contains(t, err.Error(), message, resourceName)
+	}
+}
+
+func Test_isSelectorReset(t *testing.T) {
+	applied := map[string]string{util.KtRole: util.RoleExchangeShadow}
+	original := `{"app":"demo","tier":"web"}`
+	newSvc := func(selector map[string]string, annotation string) *coreV1.Service {
+		return &coreV1.Service{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{util.KtSelector: annotation}},
+			Spec:       coreV1.ServiceSpec{Selector: selector},
+		}
+	}
+	require.True(t, isSelectorReset(newSvc(map[string]string{"tier": "web", "app": "demo"}, ""), applied, original),
+		"reset to original selector should be re-applied")
+	require.True(t, isSelectorReset(newSvc(applied, ""), applied, original),
+		"lost annotation should be re-applied")
+	require.False(t, isSelectorReset(newSvc(map[string]string{"app": "demo-v2"}, original), applied, original),
+		"selector of third party should be kept")
+}
@@ -0,0 +1,131 @@
+package general
+
+import (
+	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/sshchannel"
+	"github.com/alibaba/kt-connect/pkg/kt/transmission"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keyRotation stop channels of running key rotations, rotation in progress holds the lock,
+// so that it is never interrupted by or run after StopSshKeyRotation
+var keyRotation struct {
+	sync.Mutex
+	stops   []chan struct{}
+	stopped bool
+}
+
+// SetupSshKeyRotation periodically replace the ssh key used to access shadow pod,
+// shadow reused via '--reuseShadow' is skipped, since other clients may be using the same key
+func SetupSshKeyRotation(sshcm, privateKeyPath string, localSshPort int, interval time.Duration) {
+	if isReusableExchangeShadow() {
+		log.Warn().Msgf("Ssh key of shadow pod %s is not rotated, since it may be shared with other clients", sshcm)
+		return
+	}
+	keyRotation.Lock()
+	defer keyRotation.Unlock()
+	if keyRotation.stopped {
+		return
+	}
+	stop := make(chan struct{})
+	keyRotation.stops = append(keyRotation.stops, stop)
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				runKeyRotation(stop, sshcm, privateKeyPath, localSshPort)
+			}
+		}
+	}()
+}
+
+// StopSshKeyRotation stop all key rotations, wait for the one in progress to finish
+func StopSshKeyRotation() {
+	keyRotation.Lock()
+	defer keyRotation.Unlock()
+	for _, stop := range keyRotation.stops {
+		close(stop)
+	}
+	keyRotation.stops = nil
+	keyRotation.stopped = true
+}
+
+func runKeyRotation(stop chan struct{}, sshcm, privateKeyPath string, localSshPort int) {
+	keyRotation.Lock()
+	defer keyRotation.Unlock()
+	select {
+	case <-stop:
+		// stopped while waiting for lock
+		return
+	default:
+	}
+	if err := rotateSshKey(sshcm, privateKeyPath, localSshPort); err != nil {
+		log.Warn().Err(err).Msgf("Failed to rotate ssh key of shadow pod %s", sshcm)
+	} else {
+		log.Info().Msgf("Ssh key of shadow pod %s rotated", sshcm)
+	}
+}
+
+// rotateSshKey authorize the new key before revoking the old one, so established tunnels are never interrupted,
+// and reconnecting tunnels always find a valid key file, keys authorized by others are kept
+func rotateSshKey(sshcm, privateKeyPath string, localSshPort int) error {
+	listenAddress, err := transmission.GetListenAddress()
+	if err != nil {
+		return err
+	}
+	sshAddress := net.JoinHostPort(listenAddress, strconv.Itoa(localSshPort))
+	configMap, err := cluster.Ins().GetConfigMap(sshcm, opt.Get().Global.Namespace)
+	if err != nil {
+		return err
+	}
+	oldPublicKey := strings.TrimSpace(configMap.Data[util.SshAuthKey])
+
+	newKeyPath := privateKeyPath + ".new"
+	generator, err := util.Generate(newKeyPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(newKeyPath)
+	publicKey := strings.TrimSpace(string(generator.PublicKey))
+
+	// authorize new key with current key, and verify it via a fresh connection
+	if _, err = sshchannel.Ins().RunScript(privateKeyPath, sshAddress,
+		fmt.Sprintf("echo %s >> /root/.ssh/authorized_keys", util.ShellQuote(publicKey))); err != nil {
+		return err
+	}
+	if _, err = sshchannel.Ins().RunScript(newKeyPath, sshAddress, "true"); err != nil {
+		return fmt.Errorf("new key not accepted by shadow pod: %s", err)
+	}
+
+	// keep configmap consistent, in case of shadow pod restart
+	configMap.Data[util.SshAuthKey] = string(generator.PublicKey)
+	configMap.Data[util.SshAuthPrivateKey] = string(generator.PrivateKey)
+	if _, err = cluster.Ins().UpdateConfigMap(configMap); err != nil {
+		return err
+	}
+
+	// replace local key file atomically, then revoke only the old key of this client
+	if err = os.Rename(newKeyPath, privateKeyPath); err != nil {
+		return err
+	}
+	if oldPublicKey == "" {
+		return nil
+	}
+	_, err = sshchannel.Ins().RunScript(privateKeyPath, sshAddress, fmt.Sprintf(
+		"grep -vxF %s /root/.ssh/authorized_keys > /root/.ssh/authorized_keys.kt-rotate; "+
+			"mv -f /root/.ssh/authorized_keys.kt-rotate /root/.ssh/authorized_keys", util.ShellQuote(oldPublicKey)))
+	return err
+}
@@ -0,0 +1,129 @@
+package general
+
+import (
+	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster/fake"
+	"github.com/alibaba/kt-connect/pkg/kt/service/sshchannel"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubChannel record scripts run on shadow pod, other methods are not expected to be called
+type stubChannel struct {
+	sshchannel.Channel
+	sync.Mutex
+	scripts []string
+	failKey string
+}
+
+func (c *stubChannel) RunScript(privateKey, sshAddress, script string) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+	if privateKey == c.failKey {
+		return "", fmt.Errorf("permission denied")
+	}
+	c.scripts = append(c.scripts, script)
+	return "", nil
+}
+
+func (c *stubChannel) getScripts() []string {
+	c.Lock()
+	defer c.Unlock()
+	return append([]string{}, c.scripts...)
+}
+
+func setupRotationTest(t *testing.T) (*stubChannel, string) {
+	opt.Get().Global.Namespace = "default"
+	cluster.SetIns(fake.NewKubernetes(&coreV1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-kt-exchange-abcde", Namespace: "default"},
+		Data:       map[string]string{util.SshAuthKey: "ssh-rsa OLD kt\n", util.SshAuthPrivateKey: "old"},
+	}))
+	t.Cleanup(func() { cluster.SetIns(nil) })
+	channel := &stubChannel{}
+	sshchannel.SetIns(channel)
+	t.Cleanup(func() { sshchannel.SetIns(nil) })
+	privateKeyPath := filepath.Join(t.TempDir(), "app-kt-exchange-abcde.key")
+	require.Nil(t, os.WriteFile(privateKeyPath, []byte("old"), 0600))
+	return channel, privateKeyPath
+}
+
+func Test_rotateSshKey(t *testing.T) {
+	channel, privateKeyPath := setupRotationTest(t)
+
+	require.Nil(t, rotateSshKey("app-kt-exchange-abcde", privateKeyPath, 2222))
+	configMap, err := cluster.Ins().GetConfigMap("app-kt-exchange-abcde", "default")
+	require.Nil(t, err)
+	newPublicKey := strings.TrimSpace(configMap.Data[util.SshAuthKey])
+	require.NotEqual(t, "ssh-rsa OLD kt", newPublicKey)
+	privateKey, err := os.ReadFile(privateKeyPath)
+	require.Nil(t, err)
+	require.Equal(t, configMap.Data[util.SshAuthPrivateKey], string(privateKey))
+
+	scripts := channel.getScripts()
+	require.Len(t, scripts, 3)
+	require.Equal(t, fmt.Sprintf("echo %s >> /root/.ssh/authorized_keys", util.ShellQuote(newPublicKey)), scripts[0],
+		"new key should be appended")
+	require.Equal(t, "true", scripts[1])
+	require.True(t, strings.HasPrefix(scripts[2], "grep -vxF 'ssh-rsa OLD kt' /root/.ssh/authorized_keys "),
+		"only old key of this client should be removed")
+	for _, script := range scripts {
+		require.NotContains(t, script, "> /root/.ssh/authorized_keys;", "authorized keys should never be overwritten")
+	}
+}
+
+func Test_rotateSshKey_newKeyRejected(t *testing.T) {
+	channel, privateKeyPath := setupRotationTest(t)
+	channel.failKey = privateKeyPath + ".new"
+
+	require.NotNil(t, rotateSshKey("app-kt-exchange-abcde", privateKeyPath, 2222))
+	configMap, err := cluster.Ins().GetConfigMap("app-kt-exchange-abcde", "default")
+	require.Nil(t, err)
+	require.Equal(t, "ssh-rsa OLD kt\n", configMap.Data[util.SshAuthKey], "configmap should be kept")
+	privateKey, err := os.ReadFile(privateKeyPath)
+	require.Nil(t, err)
+	require.Equal(t, "old", string(privateKey), "local key should be kept")
+	require.Len(t, channel.getScripts(), 1, "old key should not be revoked")
+}
+
+func TestSshKeyRotation_reusedShadowSkipped(t *testing.T) {
+	channel, privateKeyPath := setupRotationTest(t)
+	opt.Store.Component = util.ComponentExchange
+	opt.Get().Exchange.Mode = util.ExchangeModeSelector
+	opt.Get().Exchange.ReuseShadow = true
+	keyRotation.stopped = false
+	defer func() {
+		opt.Store.Component = ""
+		opt.Get().Exchange.ReuseShadow = false
+		StopSshKeyRotation()
+	}()
+
+	SetupSshKeyRotation("app-kt-exchange-abcde", privateKeyPath, 2222, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	require.Empty(t, channel.getScripts())
+}
+
+func TestStopSshKeyRotation(t *testing.T) {
+	channel, privateKeyPath := setupRotationTest(t)
+	keyRotation.stopped = false
+
+	SetupSshKeyRotation("app-kt-exchange-abcde", privateKeyPath, 2222, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return len(channel.getScripts()) > 0 }, time.Second, 5*time.Millisecond)
+	StopSshKeyRotation()
+	count := len(channel.getScripts())
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, count, len(channel.getScripts()), "no rotation should happen after stopped")
+
+	SetupSshKeyRotation("app-kt-exchange-abcde", privateKeyPath, 2222, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, count, len(channel.getScripts()), "rotation should not be started after stopped")
+}
@@ -1,36 +1,88 @@
 package general
 
 import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
 	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/sshchannel"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"io/ioutil"
 	k8sRuntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/klog/v2"
+	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
-	"syscall"
 	"strings"
+	"syscall"
+	"time"
 )
 
-// Prepare setup log level, time difference and kube config
-func Prepare() error {
+// tokenExpiryWarnThreshold warn if static token in kubeconfig expires within this duration
+const tokenExpiryWarnThreshold = 2 * time.Hour
+
+// Prepare setup log level, time difference, kube config and cluster config
+func Prepare(cmd *cobra.Command) error {
+	if opt.Get().Global.Quiet && opt.Get().Global.Debug {
+		return fmt.Errorf("'--quiet' cannot be used together with '--debug'")
+	}
+	if opt.Get().Global.DisableInjection && opt.Get().Global.AllowInjection {
+		return fmt.Errorf("'--disableInjection' cannot be used together with '--allowInjection'")
+	}
 	// then setup logs
 	SetupLogger()
 
 	if err := combineKubeOpts(); err != nil {
 		return err
 	}
+	loadClusterConfig(cmd)
+
+	// options could be changed by cluster config, validate them afterwards
+	if err := sshchannel.ValidateAlgorithms(opt.Get().Global.SshCiphers, opt.Get().Global.SshKex,
+		opt.Get().Global.SshMacs); err != nil {
+		return err
+	}
+	if _, _, err := sshchannel.ParseStubResponse(opt.Get().Global.StubUnbound); err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("tunnelPoolSize") && cmd.Name() != util.ComponentExchange && cmd.Name() != util.ComponentForward {
+		// channels of requests redirected to local are opened by shadow pod, there's nothing to pool on local side
+		return fmt.Errorf("'--tunnelPoolSize' is only supported by %s and %s command, "+
+			"requests redirected to local are not pooled", util.ComponentExchange, util.ComponentForward)
+	}
+	if err := util.SetNameSuffix(opt.Get().Global.NameSuffixLength, opt.Get().Global.NameSuffixCharset); err != nil {
+		return err
+	}
+	if err := util.SetProgressOutput(opt.Get().Global.Output); err != nil {
+		return err
+	}
+	if _, err := cluster.ParseTopologySpread(opt.Get().Global.TopologySpread, nil); err != nil {
+		return err
+	}
+	if err := CheckAllowedMode(cmd.Name(), commandMode(cmd.Name())); err != nil {
+		return err
+	}
 
 	log.Info().Msgf("KtConnect %s start at %d (%s %s)",
 		opt.Store.Version, os.Getpid(), runtime.GOOS, runtime.GOARCH)
 
+	if err := checkNamespace(opt.Get().Global.Namespace); err != nil {
+		return err
+	}
+
 	if !opt.Get().Global.UseLocalTime {
 		if err := cluster.SetupTimeDifference(); err != nil {
 			return err
@@ -42,6 +94,9 @@ func Prepare() error {
 func SetupLogger() {
 	if opt.Get().Global.Debug {
 		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	} else if opt.Get().Global.Quiet {
+		// set level of logger instead of global level, so that status messages could still be printed
+		log.Logger = log.Logger.Level(zerolog.ErrorLevel)
 	}
 	util.PrepareLogger(opt.Get().Global.Debug)
 	k8sRuntime.ErrorHandlers = []func(error){
@@ -55,17 +110,83 @@ func SetupLogger() {
 
 // SetupProcess write pid file and set component type
 func SetupProcess(componentName string) (chan os.Signal, error) {
-	ch := make(chan os.Signal)
+	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, os.Interrupt, syscall.SIGHUP, syscall.SIGTERM, syscall.SIGQUIT)
 	opt.Store.Component = componentName
+	go watchNamespace(opt.Get().Global.Namespace, ch)
+	if err := startEventSocket(ch); err != nil {
+		return nil, err
+	}
 	return ch, util.WritePidFile(componentName, ch)
 }
 
 // combineKubeOpts set default options of kubectl if not assign
 func combineKubeOpts() (err error) {
-	var config *clientcmdapi.Config
-	if opt.Get().Global.Kubeconfig != ""{
-		// if kubeconfig specified, always read from it
+	config, err := loadKubeConfig()
+	if err != nil {
+		return err
+	}
+	contextNamespace := ""
+	if ctx, exists := config.Contexts[config.CurrentContext]; exists {
+		contextNamespace = ctx.Namespace
+	}
+	namespace, source := resolveNamespace(opt.Get().Global.Namespace, os.Getenv(util.EnvKtNamespace), contextNamespace)
+	log.Debug().Msgf("Using namespace %s from %s", namespace, source)
+	opt.Get().Global.Namespace = namespace
+	if opt.Get().Global.Proxy != "" {
+		proxyUrl, err2 := parseProxyUrl(opt.Get().Global.Proxy)
+		if err2 != nil {
+			return err2
+		}
+		log.Info().Msgf("Using proxy %s", proxyUrl.Host)
+	}
+	restConfig, err := setupKubeClient(config)
+	if err != nil {
+		return err
+	}
+	if ctx, exists := config.Contexts[config.CurrentContext]; exists {
+		opt.Store.KubeUser = getKubeUser(restConfig, ctx.AuthInfo)
+		warnExpiringToken(config.AuthInfos[ctx.AuthInfo])
+	}
+
+	if opt.Get().Global.IpVersion == 6 || strings.Contains(restConfig.Host, "[") {
+		opt.Store.Ipv6Cluster = true
+	}
+
+	clusterName := "none"
+	for name, context := range config.Contexts {
+		if name == config.CurrentContext {
+			clusterName = context.Cluster
+			break
+		}
+	}
+	log.Info().Msgf("Using cluster context %s (%s)", config.CurrentContext, clusterName)
+
+	return nil
+}
+
+// RefreshKubeClient reload kubeconfig and recreate kubernetes client, to pick up credential renewed during session
+func RefreshKubeClient() error {
+	config, err := loadKubeConfig()
+	if err != nil {
+		return err
+	}
+	if _, err = setupKubeClient(config); err != nil {
+		return err
+	}
+	cluster.SetIns(&cluster.Kubernetes{Clientset: opt.Store.Clientset})
+	return nil
+}
+
+// loadKubeConfig read kubeconfig and switch to the context to use
+func loadKubeConfig() (config *clientcmdapi.Config, err error) {
+	if opt.Get().Global.Kubeconfig != "" {
+		// if kubeconfig specified, always read from it, multiple files are merged in the same way as $KUBECONFIG
+		for _, file := range filepath.SplitList(opt.Get().Global.Kubeconfig) {
+			if _, err = os.Stat(file); err != nil {
+				return nil, fmt.Errorf("kubeconfig file '%s' not exist", file)
+			}
+		}
 		_ = os.Setenv(util.EnvKubeConfig, opt.Get().Global.Kubeconfig)
 		config, err = clientcmd.NewDefaultClientConfigLoadingRules().Load()
 	} else if customize, exist := opt.GetCustomizeKubeConfig(); exist {
@@ -76,10 +197,10 @@ func combineKubeOpts() (err error) {
 		config, err = clientcmd.NewDefaultClientConfigLoadingRules().Load()
 	}
 	if err != nil {
-		return fmt.Errorf("failed to parse kubeconfig: %s", err)
+		return nil, fmt.Errorf("failed to parse kubeconfig: %s", err)
 	} else if config == nil {
 		// should not happen, but issue-275 and issue-285 may cause by it
-		return fmt.Errorf("failed to parse kubeconfig")
+		return nil, fmt.Errorf("failed to parse kubeconfig")
 	}
 	if len(opt.Get().Global.Context) > 0 {
 		found := false
@@ -90,18 +211,20 @@ func combineKubeOpts() (err error) {
 			}
 		}
 		if !found {
-			return fmt.Errorf("context '%s' not exist, check your kubeconfig file please", opt.Get().Global.Context)
+			if files := filepath.SplitList(os.Getenv(util.EnvKubeConfig)); len(files) > 1 {
+				return nil, fmt.Errorf("context '%s' not exist in any of kubeconfig files %s",
+					opt.Get().Global.Context, strings.Join(files, ", "))
+			}
+			return nil, fmt.Errorf("context '%s' not exist, check your kubeconfig file please", opt.Get().Global.Context)
 		}
 		config.CurrentContext = opt.Get().Global.Context
 	}
-	if len(opt.Get().Global.Namespace) == 0 {
-		ctx, exists := config.Contexts[config.CurrentContext]
-		if exists && len(ctx.Namespace) > 0 {
-			opt.Get().Global.Namespace = config.Contexts[config.CurrentContext].Namespace
-		} else {
-			opt.Get().Global.Namespace = util.DefaultNamespace
-		}
-	}
+	return config, nil
+}
+
+// setupKubeClient create kubernetes client with specified kubeconfig, and save it to runtime store.
+// exec plugin and auth provider in kubeconfig are kept, so that tokens are renewed by client-go automatically
+func setupKubeClient(config *clientcmdapi.Config) (*rest.Config, error) {
 	kubeConfigGetter := func() clientcmd.KubeconfigGetter {
 		return func() (*clientcmdapi.Config, error) {
 			return config, nil
@@ -109,27 +232,94 @@ func combineKubeOpts() (err error) {
 	}
 	restConfig, err := clientcmd.BuildConfigFromKubeconfigGetter("", kubeConfigGetter())
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if opt.Get().Global.Proxy != "" {
+		proxyUrl, _ := parseProxyUrl(opt.Get().Global.Proxy)
+		restConfig.Proxy = http.ProxyURL(proxyUrl)
 	}
 	clientSet, err := kubernetes.NewForConfig(restConfig)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	opt.Store.Clientset = clientSet
 	opt.Store.RestConfig = restConfig
+	return restConfig, nil
+}
 
-	if opt.Get().Global.IpVersion == 6 || strings.Contains(restConfig.Host, "[") {
-		opt.Store.Ipv6Cluster = true
+// warnExpiringToken static token cannot be renewed during session, warn if it's going to expire soon
+func warnExpiringToken(authInfo *clientcmdapi.AuthInfo) {
+	if authInfo == nil || authInfo.Token == "" || authInfo.Exec != nil || authInfo.AuthProvider != nil {
+		return
 	}
+	if expiry := getTokenExpiry(authInfo.Token); !expiry.IsZero() && time.Until(expiry) < tokenExpiryWarnThreshold {
+		log.Warn().Msgf("Token in kubeconfig expires at %s, and cannot be renewed automatically, "+
+			"consider using exec credential plugin in kubeconfig for long sessions", expiry.Format(time.RFC3339))
+	}
+}
 
-	clusterName := "none"
-	for name, context := range config.Contexts {
-		if name == config.CurrentContext {
-			clusterName = context.Cluster
-			break
+// getTokenExpiry read expiry time of jwt token, return zero time if token is not jwt or has no expiry
+func getTokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil || claims.Exp <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
+
+// getKubeUser use username or common name of client certificate if available, otherwise the user name in kubeconfig
+func getKubeUser(restConfig *rest.Config, authInfo string) string {
+	if restConfig.Username != "" {
+		return restConfig.Username
+	}
+	certData := restConfig.TLSClientConfig.CertData
+	if len(certData) == 0 && restConfig.TLSClientConfig.CertFile != "" {
+		certData, _ = ioutil.ReadFile(restConfig.TLSClientConfig.CertFile)
+	}
+	if block, _ := pem.Decode(certData); block != nil {
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil && cert.Subject.CommonName != "" {
+			return cert.Subject.CommonName
 		}
 	}
-	log.Info().Msgf("Using cluster context %s (%s)", config.CurrentContext, clusterName)
+	return authInfo
+}
 
-	return nil
+// parseProxyUrl proxy without scheme is treated as http proxy
+func parseProxyUrl(proxy string) (*url.URL, error) {
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	proxyUrl, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy address '%s': %s", proxy, err)
+	}
+	if proxyUrl.Scheme != "http" && proxyUrl.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported proxy scheme '%s', only http and https proxy are supported", proxyUrl.Scheme)
+	}
+	if proxyUrl.Host == "" {
+		return nil, fmt.Errorf("invalid proxy address '%s', host is required", proxy)
+	}
+	return proxyUrl, nil
+}
+
+// resolveNamespace pick namespace in order of: --namespace, $KT_NAMESPACE, kubeconfig context, "default"
+func resolveNamespace(optionNamespace, envNamespace, contextNamespace string) (string, string) {
+	if optionNamespace != "" {
+		return optionNamespace, "option"
+	} else if envNamespace != "" {
+		return envNamespace, "environment variable " + util.EnvKtNamespace
+	} else if contextNamespace != "" {
+		return contextNamespace, "kubeconfig context"
+	}
+	return util.DefaultNamespace, "default value"
 }
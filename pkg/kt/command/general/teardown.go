@@ -6,9 +6,13 @@ import (
 	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
 	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
 	"github.com/alibaba/kt-connect/pkg/kt/service/dns"
+	"github.com/alibaba/kt-connect/pkg/kt/service/sshchannel"
 	"github.com/alibaba/kt-connect/pkg/kt/service/tun"
+	"github.com/alibaba/kt-connect/pkg/kt/transmission"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	"os"
 	"os/signal"
 	"strings"
@@ -16,21 +20,97 @@ import (
 	"time"
 )
 
-// CleanupWorkspace clean workspace
+// CleanupWorkspace clean workspace, failure of one step would not stop the following steps
 func CleanupWorkspace() {
 	log.Debug().Msgf("Cleaning workspace")
+	defer transmission.StopDirectRelays()
+	StopSshKeyRotation()
+	transmission.StopSync()
+	// let local process finish its work before traffic goes back to origin
+	notifyTeardown()
 	cleanLocalFiles()
 	if opt.Store.Component == util.ComponentConnect {
 		recoverGlobalHostsAndProxy()
 	}
+	if opt.Store.Component != "" && !ensureKubeCredential() {
+		log.Error().Msgf("Kubernetes credential expired, please re-login to the cluster, " +
+			"then use 'ktctl recover' or 'ktctl clean' to restore resources changed by kt")
+		removeLoopbackAlias()
+		return
+	}
+	if isNamespaceTerminated() {
+		// resources in cluster are already gone along with the namespace
+		removeLoopbackAlias()
+		return
+	}
 
+	var errs []string
 	if opt.Store.Component == util.ComponentExchange {
-		recoverExchangedTarget()
+		drainForwardedRequests()
+		// recovering origin is the most important step, do it before removing anything
+		runCleanupStep("recover exchanged target", recoverExchangedTarget, &errs)
+		runCleanupStep("clean mirror routes", cleanMirrorRoutes, &errs)
+		runCleanupStep("clean http routes", cleanHttpRoutes, &errs)
+		runCleanupStep("recover session affinity", recoverSessionAffinities, &errs)
+		runCleanupStep("clean ingresses", cleanIngresses, &errs)
 	} else if opt.Store.Component == util.ComponentMesh {
-		recoverAutoMeshRoute()
+		runCleanupStep("recover mesh route", recoverAutoMeshRoute, &errs)
+	}
+	runCleanupStep("clean services", cleanService, &errs)
+	runCleanupStep("clean shadow", cleanShadowPodAndConfigMap, &errs)
+	runCleanupStep("revoke gateway key", revokeGatewayKey, &errs)
+	runCleanupStep("clean origin copy pods", cleanOriginCopyPods, &errs)
+	runCleanupStep("clean draining origin pods", cleanDrainingOriginPods, &errs)
+	runCleanupStep("clean preheat daemon sets", cleanDaemonSets, &errs)
+	// namespace must be checked after all kt resources removed
+	runCleanupStep("clean namespace", cleanCreatedNamespace, &errs)
+	removeLoopbackAlias()
+	if len(errs) > 0 {
+		log.Warn().Msgf("Cleanup finished with %d failed step(s), remaining resources can be removed via 'ktctl clean':", len(errs))
+		for _, e := range errs {
+			log.Warn().Msgf(" * %s", e)
+		}
 	}
-	cleanService()
-	cleanShadowPodAndConfigMap()
+}
+
+// ensureKubeCredential make sure kubernetes api is still accessible, reload kubeconfig if token already expired
+func ensureKubeCredential() bool {
+	if _, err := cluster.Ins().GetNamespace(opt.Get().Global.Namespace); !k8sErrors.IsUnauthorized(err) {
+		return true
+	}
+	log.Warn().Msgf("Kubernetes credential expired, reloading kubeconfig")
+	if err := RefreshKubeClient(); err != nil {
+		log.Debug().Err(err).Msgf("Failed to reload kubeconfig")
+		return false
+	}
+	_, err := cluster.Ins().GetNamespace(opt.Get().Global.Namespace)
+	return !k8sErrors.IsUnauthorized(err)
+}
+
+// runCleanupStep run one cleanup step and record its error, panic of the step is also treated as error
+func runCleanupStep(step string, f func() error, errs *[]string) {
+	defer func() {
+		if r := recover(); r != nil {
+			*errs = append(*errs, fmt.Sprintf("%s: %v", step, r))
+		}
+	}()
+	if err := f(); err != nil {
+		*errs = append(*errs, fmt.Sprintf("%s: %s", step, err))
+	}
+}
+
+// combineErrors merge errors of multiple resources into one
+func combineErrors(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	} else if len(errs) == 1 {
+		return errs[0]
+	}
+	var msgs []string
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Errorf("%s", strings.Join(msgs, "; "))
 }
 
 func recoverGlobalHostsAndProxy() {
@@ -39,7 +119,8 @@ func recoverGlobalHostsAndProxy() {
 		log.Debug().Msg("Dropping hosts records ...")
 		dns.DropHosts()
 	}
-	if strings.HasPrefix(opt.Get().Connect.DnsMode, util.DnsModeLocalDns) {
+	if strings.HasPrefix(opt.Get().Connect.DnsMode, util.DnsModeLocalDns) || opt.Get().Connect.TcpOnly ||
+		opt.Get().Connect.Exec != "" {
 		if err := tun.Ins().RestoreRoute(); err != nil {
 			log.Debug().Err(err).Msgf("Failed to restore route table")
 		}
@@ -50,6 +131,7 @@ func cleanLocalFiles() {
 	if opt.Store.Component == "" {
 		return
 	}
+	util.StopEventSocket()
 	pidFile := fmt.Sprintf("%s/%s-%d.pid", util.KtPidDir, opt.Store.Component, os.Getpid())
 	if err := os.Remove(pidFile); os.IsNotExist(err) {
 		log.Debug().Msgf("Pid file %s not exist", pidFile)
@@ -73,135 +155,434 @@ func cleanLocalFiles() {
 	}
 }
 
-func recoverExchangedTarget() {
+func drainForwardedRequests() {
+	if opt.Get().Exchange.DrainTimeout <= 0 || opt.Store.Shadow == "" {
+		return
+	}
+	log.Info().Msgf("Draining in-flight requests to local ...")
+	if sshchannel.Ins().Drain(time.Duration(opt.Get().Exchange.DrainTimeout) * time.Second) {
+		log.Info().Msgf("All in-flight requests finished")
+	} else {
+		log.Warn().Msgf("Drain timeout, remaining requests will be interrupted")
+	}
+}
+
+func recoverExchangedTarget() error {
 	if opt.Store.Origin == "" {
 		// process exit before target exchanged
-		return
+		return nil
 	}
+	var errs []error
+	origins := strings.Split(opt.Store.Origin, ",")
 	if opt.Get().Exchange.Mode == util.ExchangeModeScale {
-		log.Info().Msgf("Recovering origin deployment %s", opt.Store.Origin)
-		err := cluster.Ins().ScaleTo(opt.Store.Origin, opt.Get().Global.Namespace, &opt.Store.Replicas)
-		if err != nil {
-			log.Error().Err(err).Msgf("Scale deployment %s to %d failed",
-				opt.Store.Origin, opt.Store.Replicas)
+		restoreReplicas := make(map[string]int32)
+		for _, origin := range origins {
+			replicas := opt.Store.Replicas[origin]
+			if opt.Get().Exchange.RestoreReplicas > 0 {
+				replicas = int32(opt.Get().Exchange.RestoreReplicas)
+			}
+			if replicas == 0 {
+				log.Warn().Msgf("Origin deployment %s had 0 replicas before exchange, skip scaling it up", origin)
+				continue
+			}
+			log.Info().Msgf("Recovering origin deployment %s", origin)
+			err := cluster.Ins().ScaleTo(origin, opt.Get().Global.Namespace, &replicas)
+			if err != nil {
+				log.Error().Err(err).Msgf("Scale deployment %s to %d failed", origin, replicas)
+				errs = append(errs, fmt.Errorf("scale deployment %s to %d failed: %s", origin, replicas, err))
+				continue
+			}
+			restoreReplicas[origin] = replicas
+		}
+		if len(restoreReplicas) == 0 {
+			return combineErrors(errs)
 		}
 		// wait for scale complete
 		ch := make(chan os.Signal, 1)
 		signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
 		go func() {
-			waitDeploymentRecoverComplete()
+			waitDeploymentRecoverComplete(restoreReplicas)
 			ch <- os.Interrupt
 		}()
 		_ = <-ch
 	} else if opt.Get().Exchange.Mode == util.ExchangeModeSelector {
-		RecoverOriginalService(opt.Store.Origin, opt.Get().Global.Namespace)
-		log.Info().Msgf("Original service %s recovered", opt.Store.Origin)
+		for _, origin := range origins {
+			if err := checkAppliedSelector(origin); err != nil {
+				errs = append(errs, err)
+			} else if err = RecoverOriginalService(origin, opt.Get().Global.Namespace); err != nil {
+				errs = append(errs, err)
+			} else {
+				log.Info().Msgf("Original service %s recovered", origin)
+			}
+		}
 	}
+	return combineErrors(errs)
 }
 
-func recoverAutoMeshRoute() {
-	if opt.Store.Router != "" {
-		routerPod, err := cluster.Ins().GetPod(opt.Store.Router, opt.Get().Global.Namespace)
-		if err != nil {
-			log.Error().Err(err).Msgf("Router pod has been removed unexpectedly")
-			// in case of router pod gone, try recover origin service via runtime store
-			if opt.Store.Origin != "" {
-				recoverService(opt.Store.Origin)
-			}
-			return
+func recoverAutoMeshRoute() error {
+	if opt.Store.Router == "" {
+		return nil
+	}
+	routerPod, err := cluster.Ins().GetPod(opt.Store.Router, opt.Get().Global.Namespace)
+	if err != nil {
+		log.Error().Err(err).Msgf("Router pod has been removed unexpectedly")
+		// in case of router pod gone, try recover origin service via runtime store
+		if opt.Store.Origin != "" {
+			return recoverService(opt.Store.Origin)
 		}
-		if shouldDelRouter, err2 := cluster.Ins().DecreasePodRef(opt.Store.Router, opt.Get().Global.Namespace); err2 != nil {
-			log.Error().Err(err2).Msgf("Decrease router pod %s reference failed", opt.Store.Shadow)
-		} else if shouldDelRouter {
-			routerConfig := routerPod.Annotations[util.KtConfig]
-			config := util.String2Map(routerConfig)
-			recoverService(config["service"])
-			if err = cluster.Ins().RemovePod(opt.Store.Router, opt.Get().Global.Namespace); err != nil {
-				log.Warn().Err(err).Msgf("Failed to remove router pod")
-			}
-		} else {
-			stdout, stderr, err3 := cluster.Ins().ExecInPod(util.DefaultContainer, opt.Store.Router, opt.Get().Global.Namespace,
-				util.RouterBin, "remove", opt.Store.Mesh)
-			log.Debug().Msgf("Stdout: %s", stdout)
-			log.Debug().Msgf("Stderr: %s", stderr)
-			if err3 != nil {
-				log.Warn().Err(err3).Msgf("Failed to remove version %s from router pod", opt.Store.Mesh)
-			}
+		return nil
+	}
+	if shouldDelRouter, err2 := cluster.Ins().DecreasePodRef(opt.Store.Router, opt.Get().Global.Namespace); err2 != nil {
+		log.Error().Err(err2).Msgf("Decrease router pod %s reference failed", opt.Store.Shadow)
+		return fmt.Errorf("decrease router pod %s reference failed: %s", opt.Store.Router, err2)
+	} else if shouldDelRouter {
+		routerConfig := routerPod.Annotations[util.KtConfig]
+		config := util.String2Map(routerConfig)
+		var errs []error
+		if err = recoverService(config["service"]); err != nil {
+			errs = append(errs, err)
+		}
+		if err = cluster.Ins().RemovePod(opt.Store.Router, opt.Get().Global.Namespace); err != nil {
+			log.Warn().Err(err).Msgf("Failed to remove router pod")
+			errs = append(errs, fmt.Errorf("remove router pod %s failed: %s", opt.Store.Router, err))
+		}
+		return combineErrors(errs)
+	} else {
+		stdout, stderr, err3 := cluster.Ins().ExecInPod(util.DefaultContainer, opt.Store.Router, opt.Get().Global.Namespace,
+			util.RouterBin, "remove", opt.Store.Mesh)
+		log.Debug().Msgf("Stdout: %s", stdout)
+		log.Debug().Msgf("Stderr: %s", stderr)
+		if err3 != nil {
+			log.Warn().Err(err3).Msgf("Failed to remove version %s from router pod", opt.Store.Mesh)
+			return fmt.Errorf("remove version %s from router pod failed: %s", opt.Store.Mesh, err3)
 		}
 	}
+	return nil
 }
 
-func recoverService(originSvcName string) {
-	RecoverOriginalService(originSvcName, opt.Get().Global.Namespace)
-	log.Info().Msgf("Original service %s recovered", originSvcName)
+func recoverService(originSvcName string) error {
+	var errs []error
+	if err := checkAppliedSelector(originSvcName); err != nil {
+		errs = append(errs, err)
+	} else if err = RecoverOriginalService(originSvcName, opt.Get().Global.Namespace); err != nil {
+		errs = append(errs, err)
+	} else {
+		log.Info().Msgf("Original service %s recovered", originSvcName)
+	}
 
 	stuntmanSvcName := originSvcName + util.StuntmanServiceSuffix
 	if err := cluster.Ins().RemoveService(stuntmanSvcName, opt.Get().Global.Namespace); err != nil {
 		log.Error().Err(err).Msgf("Failed to remove stuntman service %s", stuntmanSvcName)
+		errs = append(errs, fmt.Errorf("remove stuntman service %s failed: %s", stuntmanSvcName, err))
+	} else {
+		log.Info().Msgf("Stuntman service %s removed", stuntmanSvcName)
 	}
-	log.Info().Msgf("Stuntman service %s removed", stuntmanSvcName)
+	return combineErrors(errs)
 }
 
-func RecoverOriginalService(svcName, namespace string) {
-	if svc, err := cluster.Ins().GetService(svcName, namespace); err != nil {
+// RecoverOriginalService restore selector of service exchanged or meshed by kt
+func RecoverOriginalService(svcName, namespace string) error {
+	svc, err := cluster.Ins().GetService(svcName, namespace)
+	if err != nil {
 		log.Error().Err(err).Msgf("Original service %s not found", svcName)
-		return
-	} else {
-		var selector map[string]string
-		if svc.Annotations == nil {
-			log.Warn().Msgf("No annotation found in service %s, skipping", svcName)
-			return
-		}
-		originSelector, exists := svc.Annotations[util.KtSelector]
-		if !exists {
-			log.Warn().Msgf("No selector annotation found in service %s, skipping", svcName)
-			return
-		}
-		if err = json.Unmarshal([]byte(originSelector), &selector); err != nil {
-			log.Error().Err(err).Msgf("Failed to unmarshal original selector of service %s", svcName)
-			return
-		}
-		svc.Spec.Selector = selector
-		delete(svc.Annotations, util.KtSelector)
+		return fmt.Errorf("original service %s not found: %s", svcName, err)
+	}
+	var selector map[string]string
+	if svc.Annotations == nil {
+		log.Warn().Msgf("No annotation found in service %s, skipping", svcName)
+		return nil
+	}
+	originSelector, exists := svc.Annotations[util.KtSelector]
+	if !exists {
+		log.Warn().Msgf("No selector annotation found in service %s, skipping", svcName)
+		return nil
+	}
+	if err = json.Unmarshal([]byte(originSelector), &selector); err != nil {
+		log.Error().Err(err).Msgf("Failed to unmarshal original selector of service %s", svcName)
+		return fmt.Errorf("failed to unmarshal original selector of service %s: %s", svcName, err)
+	}
+	svc.Spec.Selector = selector
+	delete(svc.Annotations, util.KtSelector)
+	if err = restoreSessionAffinity(svc); err != nil {
+		return err
+	}
+	if _, err = cluster.Ins().UpdateService(svc); err != nil {
+		log.Error().Err(err).Msgf("Failed to recover selector of original service %s", svcName)
+		return fmt.Errorf("failed to recover selector of original service %s: %s", svcName, err)
+	}
+	return nil
+}
+
+// checkAppliedSelector make sure service still selects pods as kt changed it to, so that change made by others
+// is not overwritten, and put back recorded original selector if its annotation is lost
+func checkAppliedSelector(svcName string) error {
+	applied, exists := opt.Store.AppliedSelectors[svcName]
+	if !exists {
+		return nil
+	}
+	svc, err := cluster.Ins().GetService(svcName, opt.Get().Global.Namespace)
+	if err != nil {
+		// reported by following recover step
+		return nil
+	}
+	var selector map[string]string
+	if err = json.Unmarshal([]byte(applied), &selector); err != nil {
+		return nil
+	}
+	original := opt.Store.ServiceSelectors[svcName]
+	if current, _ := json.Marshal(svc.Spec.Selector); string(current) == original {
+		// already reset back to original selector, recovering it again is harmless
+		return nil
+	}
+	if !util.MapEquals(svc.Spec.Selector, selector) {
+		log.Warn().Msgf("Selector of service %s was changed by others, skip recovering it, original selector is %s",
+			svcName, original)
+		return fmt.Errorf("selector of service %s was changed by others, original selector is %s", svcName, original)
+	}
+	if svc.Annotations[util.KtSelector] != original {
+		log.Warn().Msgf("Original selector annotation of service %s was changed, using recorded one", svcName)
+		svc.Annotations = util.MapPut(svc.Annotations, util.KtSelector, original)
 		if _, err = cluster.Ins().UpdateService(svc); err != nil {
-			log.Error().Err(err).Msgf("Failed to recover selector of original service %s", svcName)
+			return fmt.Errorf("failed to restore original selector annotation of service %s: %s", svcName, err)
+		}
+	}
+	return nil
+}
+
+// RecoverSessionAffinity restore session affinity of service disabled during exchange
+func RecoverSessionAffinity(svcName, namespace string) error {
+	svc, err := cluster.Ins().GetService(svcName, namespace)
+	if err != nil {
+		log.Error().Err(err).Msgf("Service %s not found", svcName)
+		return fmt.Errorf("service %s not found: %s", svcName, err)
+	}
+	if svc.Annotations[util.KtSessionAffinity] == "" {
+		// already recovered along with selector
+		return nil
+	}
+	if err = restoreSessionAffinity(svc); err != nil {
+		return err
+	}
+	if _, err = cluster.Ins().UpdateService(svc); err != nil {
+		log.Error().Err(err).Msgf("Failed to recover session affinity of service %s", svcName)
+		return fmt.Errorf("failed to recover session affinity of service %s: %s", svcName, err)
+	}
+	log.Info().Msgf("Session affinity of service %s recovered", svcName)
+	return nil
+}
+
+// restoreSessionAffinity set session affinity of service back to the config recorded in annotation
+func restoreSessionAffinity(svc *coreV1.Service) error {
+	recorded := svc.Annotations[util.KtSessionAffinity]
+	if recorded == "" {
+		return nil
+	}
+	var config *coreV1.SessionAffinityConfig
+	if err := json.Unmarshal([]byte(recorded), &config); err != nil {
+		log.Error().Err(err).Msgf("Failed to unmarshal original session affinity of service %s", svc.Name)
+		return fmt.Errorf("failed to unmarshal original session affinity of service %s: %s", svc.Name, err)
+	}
+	svc.Spec.SessionAffinity = coreV1.ServiceAffinityClientIP
+	svc.Spec.SessionAffinityConfig = config
+	delete(svc.Annotations, util.KtSessionAffinity)
+	return nil
+}
+
+func recoverSessionAffinities() error {
+	var errs []error
+	if opt.Store.AffinityService != "" {
+		for _, name := range strings.Split(opt.Store.AffinityService, ",") {
+			if err := RecoverSessionAffinity(name, opt.Get().Global.Namespace); err != nil {
+				errs = append(errs, err)
+			}
 		}
 	}
+	return combineErrors(errs)
 }
 
-func waitDeploymentRecoverComplete() {
-	ok := false
+func waitDeploymentRecoverComplete(restoreReplicas map[string]int32) {
+	pending := make([]string, 0)
+	for origin := range restoreReplicas {
+		pending = append(pending, origin)
+	}
 	counts := opt.Get().Exchange.RecoverWaitTime / 5
-	for i := 0; i < counts; i++ {
-		deployment, err := cluster.Ins().GetDeployment(opt.Store.Origin, opt.Get().Global.Namespace)
-		if err != nil {
-			log.Error().Err(err).Msgf("Cannot fetch original deployment %s", opt.Store.Origin)
-			break
-		} else if deployment.Status.ReadyReplicas == opt.Store.Replicas {
-			ok = true
-			break
-		} else {
-			log.Info().Msgf("Wait for deployment %s recover ...", opt.Store.Origin)
+	for i := 0; i < counts && len(pending) > 0; i++ {
+		notReady := make([]string, 0)
+		for _, origin := range pending {
+			deployment, err := cluster.Ins().GetDeployment(origin, opt.Get().Global.Namespace)
+			if err != nil {
+				log.Error().Err(err).Msgf("Cannot fetch original deployment %s", origin)
+			} else if deployment.Status.ReadyReplicas != restoreReplicas[origin] {
+				notReady = append(notReady, origin)
+			}
+		}
+		pending = notReady
+		if len(pending) > 0 {
+			log.Info().Msgf("Wait for deployment %s recover ...", strings.Join(pending, ", "))
 			time.Sleep(5 * time.Second)
 		}
 	}
-	if !ok {
-		log.Warn().Msgf("Deployment %s recover timeout", opt.Store.Origin)
+	for _, origin := range pending {
+		log.Warn().Msgf("Deployment %s recover timeout", origin)
+	}
+}
+
+func isReusableExchangeShadow() bool {
+	return opt.Store.Component == util.ComponentExchange && opt.Get().Exchange.ReuseShadow &&
+		opt.Get().Exchange.Mode == util.ExchangeModeSelector
+}
+
+func cleanOriginCopyPods() error {
+	var errs []error
+	if opt.Store.OriginCopy != "" {
+		for _, pod := range strings.Split(opt.Store.OriginCopy, ",") {
+			log.Info().Msgf("Cleaning origin copy pod %s", pod)
+			if err := cluster.Ins().RemovePod(pod, opt.Get().Global.Namespace); err != nil {
+				log.Error().Err(err).Msgf("Delete origin copy pod %s failed", pod)
+				errs = append(errs, fmt.Errorf("delete origin copy pod %s failed: %s", pod, err))
+			}
+		}
+	}
+	return combineErrors(errs)
+}
+
+// revokeGatewayKey remove access key of current process from connect gateway pod
+func revokeGatewayKey() error {
+	if opt.Store.Gateway == "" {
+		return nil
+	}
+	_ = os.Remove(util.PrivateKeyPath(util.GatewayKeyName()))
+	log.Info().Msgf("Revoking access key from connect gateway %s", opt.Store.Gateway)
+	if _, stderr, err := cluster.Ins().ExecInPod(util.DefaultContainer, opt.Store.Gateway, opt.Get().Global.Namespace,
+		"sh", "-c", fmt.Sprintf("grep -vF '%[1]s' %[2]s > %[2]s.tmp; cat %[2]s.tmp > %[2]s && rm -f %[2]s.tmp",
+			opt.Store.GatewayKey, util.AuthorizedKeysFile)); err != nil {
+		log.Warn().Err(err).Msgf("Failed to revoke access key from connect gateway %s: %s", opt.Store.Gateway, stderr)
+		return fmt.Errorf("revoke access key from connect gateway %s failed: %s, it expires by itself", opt.Store.Gateway, err)
+	}
+	return nil
+}
+
+func cleanDrainingOriginPods() error {
+	var errs []error
+	var draining string
+	opt.LockStore(func(store *opt.RuntimeStore) {
+		draining = store.OriginDraining
+	})
+	if draining != "" {
+		for _, pod := range strings.Split(draining, ",") {
+			log.Info().Msgf("Cleaning draining origin pod %s", pod)
+			if err := cluster.Ins().RemovePod(pod, opt.Get().Global.Namespace); err != nil && !k8sErrors.IsNotFound(err) {
+				log.Error().Err(err).Msgf("Delete draining origin pod %s failed", pod)
+				errs = append(errs, fmt.Errorf("delete draining origin pod %s failed: %s", pod, err))
+			}
+		}
+	}
+	return combineErrors(errs)
+}
+
+func removeLoopbackAlias() {
+	if opt.Store.LoopbackAlias != "" {
+		log.Info().Msgf("Removing loopback alias %s", opt.Store.LoopbackAlias)
+		if err := util.RemoveLoopbackAlias(opt.Store.LoopbackAlias); err != nil {
+			log.Warn().Err(err).Msgf("Failed to remove loopback alias %s", opt.Store.LoopbackAlias)
+		}
 	}
 }
 
-func cleanService() {
+func cleanMirrorRoutes() error {
+	var errs []error
+	if opt.Store.MirrorRoute != "" {
+		for _, name := range strings.Split(opt.Store.MirrorRoute, ",") {
+			log.Info().Msgf("Cleaning virtual service %s", name)
+			if err := cluster.Ins().RemoveVirtualService(name, opt.Get().Global.Namespace); err != nil {
+				log.Error().Err(err).Msgf("Delete virtual service %s failed", name)
+				errs = append(errs, fmt.Errorf("delete virtual service %s failed: %s", name, err))
+			}
+		}
+	}
+	return combineErrors(errs)
+}
+
+func cleanHttpRoutes() error {
+	var errs []error
+	if opt.Store.HttpRoute != "" {
+		for _, name := range strings.Split(opt.Store.HttpRoute, ",") {
+			log.Info().Msgf("Cleaning http route %s", name)
+			if err := cluster.Ins().RemoveHttpRoute(name, opt.Get().Global.Namespace); err != nil {
+				log.Error().Err(err).Msgf("Delete http route %s failed", name)
+				errs = append(errs, fmt.Errorf("delete http route %s failed: %s", name, err))
+			}
+		}
+	}
+	return combineErrors(errs)
+}
+
+func cleanIngresses() error {
+	var errs []error
+	if opt.Store.Ingress != "" {
+		for _, name := range strings.Split(opt.Store.Ingress, ",") {
+			log.Info().Msgf("Cleaning ingress %s", name)
+			if err := cluster.Ins().RemoveIngress(name, opt.Get().Global.Namespace); err != nil {
+				log.Error().Err(err).Msgf("Delete ingress %s failed", name)
+				errs = append(errs, fmt.Errorf("delete ingress %s failed: %s", name, err))
+			}
+		}
+	}
+	return combineErrors(errs)
+}
+
+func cleanService() error {
+	var errs []error
 	if opt.Store.Service != "" {
-		log.Info().Msgf("Cleaning service %s", opt.Store.Service)
-		err := cluster.Ins().RemoveService(opt.Store.Service, opt.Get().Global.Namespace)
-		if err != nil {
-			log.Error().Err(err).Msgf("Delete service %s failed", opt.Store.Service)
+		for _, name := range strings.Split(opt.Store.Service, ",") {
+			log.Info().Msgf("Cleaning service %s", name)
+			err := cluster.Ins().RemoveService(name, opt.Get().Global.Namespace)
+			if err != nil {
+				log.Error().Err(err).Msgf("Delete service %s failed", name)
+				errs = append(errs, fmt.Errorf("delete service %s failed: %s", name, err))
+			}
+		}
+	}
+	return combineErrors(errs)
+}
+
+func cleanDaemonSets() error {
+	var errs []error
+	if opt.Store.DaemonSet != "" {
+		for _, name := range strings.Split(opt.Store.DaemonSet, ",") {
+			log.Info().Msgf("Cleaning daemon set %s", name)
+			err := cluster.Ins().RemoveDaemonSet(name, opt.Get().Global.Namespace)
+			if err != nil {
+				log.Error().Err(err).Msgf("Delete daemon set %s failed", name)
+				errs = append(errs, fmt.Errorf("delete daemon set %s failed: %s", name, err))
+			}
+		}
+	}
+	return combineErrors(errs)
+}
+
+// stopUdpRelays kill udp relays started in ephemeral containers, they would not exit by themselves
+func stopUdpRelays() {
+	if opt.Store.UdpRelay == "" {
+		return
+	}
+	for _, relay := range strings.Split(opt.Store.UdpRelay, ",") {
+		podName, pid, found := strings.Cut(relay, ":")
+		if !found {
+			continue
+		}
+		log.Debug().Msgf("Stopping udp relay %s in pod %s", pid, podName)
+		if _, stderr, err := cluster.Ins().ExecInPod(util.KtExchangeContainer, podName, opt.Get().Global.Namespace,
+			"kill", pid); err != nil {
+			log.Warn().Err(err).Msgf("Failed to stop udp relay %s in pod %s: %s", pid, podName, stderr)
 		}
 	}
+	opt.Store.UdpRelay = ""
 }
 
-func cleanShadowPodAndConfigMap() {
+func cleanShadowPodAndConfigMap() error {
 	var err error
+	var errs []error
 	if opt.Store.Shadow != "" {
 		shouldDelWithShared := false
 		if opt.Get().Connect.ShareShadow {
@@ -213,14 +594,20 @@ func cleanShadowPodAndConfigMap() {
 			}
 			if err != nil {
 				log.Error().Err(err).Msgf("Decrease shadow daemon %s ref count failed", opt.Store.Shadow)
+				errs = append(errs, fmt.Errorf("decrease shadow daemon %s ref count failed: %s", opt.Store.Shadow, err))
 			}
 		}
-		if shouldDelWithShared || !opt.Get().Connect.ShareShadow {
+		if isReusableExchangeShadow() {
+			for _, shadow := range strings.Split(opt.Store.Shadow, ",") {
+				log.Info().Msgf("Keeping shadow pod %s for later exchange", shadow)
+			}
+		} else if shouldDelWithShared || !opt.Get().Connect.ShareShadow {
 			for _, shadow := range strings.Split(opt.Store.Shadow, ",") {
 				log.Info().Msgf("Cleaning configmap %s", shadow)
 				err = cluster.Ins().RemoveConfigMap(shadow, opt.Get().Global.Namespace)
 				if err != nil {
 					log.Error().Err(err).Msgf("Delete configmap %s failed", shadow)
+					errs = append(errs, fmt.Errorf("delete configmap %s failed: %s", shadow, err))
 				}
 				log.Info().Msgf("Cleaning shadow pod %s", shadow)
 				if opt.Get().Global.UseShadowDeployment {
@@ -230,17 +617,21 @@ func cleanShadowPodAndConfigMap() {
 				}
 				if err != nil {
 					log.Error().Err(err).Msgf("Delete shadow pod %s failed", shadow)
+					errs = append(errs, fmt.Errorf("delete shadow pod %s failed: %s", shadow, err))
 				}
 			}
 		}
 		if opt.Get().Exchange.Mode == util.ExchangeModeEphemeral {
+			stopUdpRelays()
 			for _, shadow := range strings.Split(opt.Store.Shadow, ",") {
 				log.Info().Msgf("Removing ephemeral container of pod %s", shadow)
 				err = cluster.Ins().RemoveEphemeralContainer(util.KtExchangeContainer, shadow, opt.Get().Global.Namespace)
 				if err != nil {
 					log.Error().Err(err).Msgf("Remove ephemeral container of pod %s failed", shadow)
+					errs = append(errs, fmt.Errorf("remove ephemeral container of pod %s failed: %s", shadow, err))
 				}
 			}
 		}
 	}
+	return combineErrors(errs)
 }
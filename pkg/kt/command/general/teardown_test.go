@@ -0,0 +1,132 @@
+package general
+
+import (
+	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster/fake"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func Test_runCleanupStep(t *testing.T) {
+	var errs []string
+	runCleanupStep("ok", func() error { return nil }, &errs)
+	runCleanupStep("failed", func() error { return fmt.Errorf("forbidden") }, &errs)
+	runCleanupStep("panic", func() error { panic("unexpected") }, &errs)
+	require.Equal(t, []string{"failed: forbidden", "panic: unexpected"}, errs)
+}
+
+func TestCleanupWorkspace_recoverOriginWhenShadowDeletionFailed(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	opt.Get().Exchange.Mode = util.ExchangeModeSelector
+	opt.Store.Component = util.ComponentExchange
+	opt.Store.Origin = "app"
+	opt.Store.Shadow = "app-kt-exchange-gone"
+	defer func() {
+		opt.Store.Component, opt.Store.Origin, opt.Store.Shadow = "", "", ""
+	}()
+	cluster.SetIns(fake.NewKubernetes(&coreV1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default",
+			Annotations: map[string]string{util.KtSelector: `{"app":"demo"}`}},
+		Spec: coreV1.ServiceSpec{Selector: map[string]string{util.KtRole: util.RoleExchangeShadow}},
+	}))
+	defer cluster.SetIns(nil)
+
+	var errs []string
+	runCleanupStep("clean shadow", cleanShadowPodAndConfigMap, &errs)
+	require.Len(t, errs, 1, "deleting a shadow already gone should fail")
+
+	CleanupWorkspace()
+	svc, err := cluster.Ins().GetService("app", "default")
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{"app": "demo"}, svc.Spec.Selector, "origin service should be recovered")
+	require.NotContains(t, svc.Annotations, util.KtSelector)
+}
+
+func Test_checkAppliedSelector(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	opt.Store.ServiceSelectors["app"] = `{"app":"demo"}`
+	opt.Store.AppliedSelectors["app"] = `{"kt-role":"shadow-exchange"}`
+	defer func() {
+		delete(opt.Store.ServiceSelectors, "app")
+		delete(opt.Store.AppliedSelectors, "app")
+	}()
+	cluster.SetIns(fake.NewKubernetes(&coreV1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec:       coreV1.ServiceSpec{Selector: map[string]string{util.KtRole: util.RoleExchangeShadow}},
+	}))
+	defer cluster.SetIns(nil)
+
+	require.Nil(t, checkAppliedSelector("app"))
+	svc, err := cluster.Ins().GetService("app", "default")
+	require.Nil(t, err)
+	require.Equal(t, `{"app":"demo"}`, svc.Annotations[util.KtSelector], "lost annotation should be restored")
+
+	svc.Spec.Selector = map[string]string{"app": "demo-v2"}
+	_, err = cluster.Ins().UpdateService(svc)
+	require.Nil(t, err)
+	require.NotNil(t, checkAppliedSelector("app"), "service changed by others should not be recovered")
+	svc.Spec.Selector = map[string]string{"app": "demo"}
+	_, err = cluster.Ins().UpdateService(svc)
+	require.Nil(t, err)
+	require.Nil(t, checkAppliedSelector("app"), "service reset to original selector should be recovered")
+	require.Nil(t, checkAppliedSelector("other"), "service not changed by kt should be skipped")
+}
+
+func TestRecoverSessionAffinity(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	opt.Get().Exchange.ResetAffinity = true
+	defer func() {
+		opt.Get().Exchange.ResetAffinity = false
+		opt.Store.AffinityService = ""
+	}()
+	timeout := int32(600)
+	svc := coreV1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: coreV1.ServiceSpec{
+			Selector:        map[string]string{"app": "demo"},
+			SessionAffinity: coreV1.ServiceAffinityClientIP,
+			SessionAffinityConfig: &coreV1.SessionAffinityConfig{
+				ClientIP: &coreV1.ClientIPConfig{TimeoutSeconds: &timeout},
+			},
+		},
+	}
+	cluster.SetIns(fake.NewKubernetes(&svc))
+	defer cluster.SetIns(nil)
+
+	require.Nil(t, CheckSessionAffinity([]coreV1.Service{svc}))
+	require.Equal(t, "app", opt.Store.AffinityService)
+	updated, err := cluster.Ins().GetService("app", "default")
+	require.Nil(t, err)
+	require.Equal(t, coreV1.ServiceAffinityNone, updated.Spec.SessionAffinity)
+	require.Nil(t, updated.Spec.SessionAffinityConfig)
+
+	require.Nil(t, recoverSessionAffinities())
+	updated, err = cluster.Ins().GetService("app", "default")
+	require.Nil(t, err)
+	require.Equal(t, coreV1.ServiceAffinityClientIP, updated.Spec.SessionAffinity)
+	require.Equal(t, svc.Spec.SessionAffinityConfig, updated.Spec.SessionAffinityConfig)
+	require.NotContains(t, updated.Annotations, util.KtSessionAffinity)
+}
+
+func Test_stopUdpRelays(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	opt.Store.UdpRelay = "pod-a:101,pod-b:202"
+	k := fake.NewKubernetes()
+	var killed []string
+	k.ExecHandler = func(containerName, podName, namespace string, cmd ...string) (string, string, error) {
+		require.Equal(t, util.KtExchangeContainer, containerName)
+		killed = append(killed, fmt.Sprintf("%s %v", podName, cmd))
+		return "", "", nil
+	}
+	cluster.SetIns(k)
+	defer cluster.SetIns(nil)
+
+	stopUdpRelays()
+	require.Equal(t, []string{"pod-a [kill 101]", "pod-b [kill 202]"}, killed)
+	require.Empty(t, opt.Store.UdpRelay)
+}
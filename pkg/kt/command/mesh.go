@@ -14,15 +14,15 @@ import (
 // NewMeshCommand return new mesh command
 func NewMeshCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:  "mesh",
+		Use:   "mesh",
 		Short: "Redirect marked requests of specified kubernetes service to local",
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
 				return fmt.Errorf("name of service to mesh is required")
 			} else if len(args) > 1 {
-				return fmt.Errorf("too many service names are spcified (%s), should be one", strings.Join(args, ",") )
+				return fmt.Errorf("too many service names are spcified (%s), should be one", strings.Join(args, ","))
 			}
-			return general.Prepare()
+			return prepareAndReap(cmd)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return Mesh(args[0])
@@ -35,8 +35,11 @@ func NewMeshCommand() *cobra.Command {
 	return cmd
 }
 
-//Mesh exchange kubernetes workload
+// Mesh exchange kubernetes workload
 func Mesh(resourceName string) error {
+	if err := general.CheckProtectedNamespace(opt.Get().Global.Namespace); err != nil {
+		return err
+	}
 	ch, err := general.SetupProcess(util.ComponentMesh)
 	if err != nil {
 		return err
@@ -48,7 +51,7 @@ func Mesh(resourceName string) error {
 		}
 	}
 
-  // Get service to mesh
+	// Get service to mesh
 	svc, err := general.GetServiceByResourceName(resourceName, opt.Get().Global.Namespace)
 	if err != nil {
 		return err
@@ -58,6 +61,10 @@ func Mesh(resourceName string) error {
 		return fmt.Errorf("target port %s not exists in service %s", port, svc.Name)
 	}
 
+	if opt.Get().Mesh.VersionService && opt.Get().Mesh.Mode != util.MeshModeManual {
+		return fmt.Errorf("'--versionService' is only supported in %s mode", util.MeshModeManual)
+	}
+
 	log.Info().Msgf("Using %s mode", opt.Get().Mesh.Mode)
 	if opt.Get().Mesh.Mode == util.MeshModeManual {
 		err = mesh.ManualMesh(svc)
@@ -76,4 +83,3 @@ func Mesh(resourceName string) error {
 	log.Info().Msgf("Terminal Signal is %s", s)
 	return nil
 }
-
@@ -9,7 +9,6 @@ import (
 	"github.com/rs/zerolog/log"
 	coreV1 "k8s.io/api/core/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/util/intstr"
 	"strconv"
 	"strings"
 	"time"
@@ -34,24 +33,9 @@ func AutoMesh(svc *coreV1.Service) error {
 	opt.Store.Mesh = versionMark
 
 	portToNames := general.GetTargetPorts(svc)
-	ports := make(map[int]int)
-	for _, specPort := range svc.Spec.Ports {
-		if specPort.TargetPort.Type == intstr.Int {
-			ports[int(specPort.Port)] = specPort.TargetPort.IntValue()
-		} else {
-			podPort := -1
-			for p, n := range portToNames {
-				if n == specPort.TargetPort.StrVal {
-					podPort = p
-					break
-				}
-			}
-			if podPort < 0 {
-				return fmt.Errorf("cannot found port number of target port '%s' of service %s",
-					specPort.TargetPort.StrVal, svc.Name)
-			}
-			ports[int(specPort.Port)] = podPort
-		}
+	ports, err := getServicePorts(svc, portToNames)
+	if err != nil {
+		return err
 	}
 
 	// Check name usable
@@ -78,7 +62,7 @@ func AutoMesh(svc *coreV1.Service) error {
 	// Must after stuntman service and shadow service, otherwise will cause 'host not found in upstream' error
 	routerPodName := svc.Name + util.RouterPodSuffix
 	routerLabels := map[string]string{
-		util.KtRole:   util.RoleRouter,
+		util.KtRole: util.RoleRouter,
 	}
 	if err = createRouter(routerPodName, svc.Name, ports, routerLabels, versionMark); err != nil {
 		return err
@@ -95,13 +79,13 @@ func AutoMesh(svc *coreV1.Service) error {
 	annotations := map[string]string{
 		util.KtConfig: fmt.Sprintf("service=%s", shadowName),
 	}
-	if err = general.CreateShadowAndInbound(shadowName, opt.Get().Mesh.Expose,
-		shadowLabels, annotations, portToNames); err != nil {
+	if _, err = general.CreateShadowAndInbound(shadowName, opt.Get().Mesh.Expose,
+		shadowLabels, annotations, portToNames, nil); err != nil {
 		return err
 	}
-	log.Info().Msg("---------------------------------------------------------------")
-	log.Info().Msgf(" Now you can access your service by header '%s: %s' ", strings.ToUpper(meshKey), meshVersion)
-	log.Info().Msg("---------------------------------------------------------------")
+	util.StatusLog().Msg("---------------------------------------------------------------")
+	util.StatusLog().Msgf(" Now you can access your service by header '%s: %s' ", strings.ToUpper(meshKey), meshVersion)
+	util.StatusLog().Msg("---------------------------------------------------------------")
 	return nil
 }
 
@@ -116,11 +100,11 @@ func isNameUsable(name, meshVersion string, times int) error {
 			if opt.Get().Mesh.VersionMark != "" {
 				return fmt.Errorf("%s, please specify a different version mark", msg)
 			}
-			return fmt.Errorf( "%s, please retry or use '--versionMark' parameter to spcify an uniq one", msg)
+			return fmt.Errorf("%s, please retry or use '--versionMark' parameter to spcify an uniq one", msg)
 		}
 		log.Info().Msgf("Previous meshing pod for service '%s' not finished yet, waiting ...", name)
 		time.Sleep(3 * time.Second)
-		return isNameUsable(name, meshVersion, times + 1)
+		return isNameUsable(name, meshVersion, times+1)
 	}
 	return nil
 }
@@ -1,15 +1,18 @@
 package mesh
 
 import (
+	"fmt"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"regexp"
 	"strings"
 )
 
 func getVersion(versionMark string) (string, string) {
 	versionKey := "version"
-	versionVal := strings.ToLower(util.RandomString(5))
+	versionVal := util.RandomSuffix()
 	if len(versionMark) != 0 {
 		versionParts := strings.Split(versionMark, ":")
 		if len(versionParts) > 1 {
@@ -32,3 +35,27 @@ func isValidKey(key string) bool {
 	ok, err := regexp.MatchString("^[a-z][a-z0-9_-]*$", key)
 	return err == nil && ok
 }
+
+// getServicePorts map each service port to the port number of target pod
+func getServicePorts(svc *coreV1.Service, portToNames map[int]string) (map[int]int, error) {
+	ports := make(map[int]int)
+	for _, specPort := range svc.Spec.Ports {
+		if specPort.TargetPort.Type == intstr.Int {
+			ports[int(specPort.Port)] = specPort.TargetPort.IntValue()
+		} else {
+			podPort := -1
+			for p, n := range portToNames {
+				if n == specPort.TargetPort.StrVal {
+					podPort = p
+					break
+				}
+			}
+			if podPort < 0 {
+				return nil, fmt.Errorf("cannot found port number of target port '%s' of service %s",
+					specPort.TargetPort.StrVal, svc.Name)
+			}
+			ports[int(specPort.Port)] = podPort
+		}
+	}
+	return ports, nil
+}
@@ -2,6 +2,9 @@ package mesh
 
 import (
 	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"testing"
 )
 
@@ -31,3 +34,18 @@ func Test_getVersion(t *testing.T) {
 	require.Equal(t, k, "mark")
 	require.Equal(t, v, "test")
 }
+
+func Test_getServicePorts(t *testing.T) {
+	svc := &coreV1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "svc"},
+		Spec: coreV1.ServiceSpec{Ports: []coreV1.ServicePort{
+			{Port: 80, TargetPort: intstr.FromInt(8080)},
+			{Port: 90, TargetPort: intstr.FromString("grpc")},
+		}},
+	}
+	ports, err := getServicePorts(svc, map[int]string{9090: "grpc"})
+	require.Nil(t, err)
+	require.Equal(t, map[int]int{80: 8080, 90: 9090}, ports)
+	_, err = getServicePorts(svc, map[int]string{})
+	require.NotNil(t, err, "named target port not found in pod should fail")
+}
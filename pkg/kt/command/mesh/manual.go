@@ -4,7 +4,6 @@ import (
 	"github.com/alibaba/kt-connect/pkg/kt/command/general"
 	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
-	"github.com/rs/zerolog/log"
 	coreV1 "k8s.io/api/core/v1"
 )
 
@@ -13,13 +12,28 @@ func ManualMesh(svc *coreV1.Service) error {
 	shadowPodName := svc.Name + util.MeshPodInfix + meshVersion
 	labels := getMeshLabels(meshKey, meshVersion, svc)
 	annotations := make(map[string]string)
-	if err := general.CreateShadowAndInbound(shadowPodName, opt.Get().Mesh.Expose, labels,
-		annotations, general.GetTargetPorts(svc)); err != nil {
+	if _, err := general.CreateShadowAndInbound(shadowPodName, opt.Get().Mesh.Expose, labels,
+		annotations, general.GetTargetPorts(svc), nil); err != nil {
 		return err
 	}
-	log.Info().Msg("---------------------------------------------------------")
-	log.Info().Msgf(" Now you can update Istio rule by label '%s=%s' ", meshKey, meshVersion)
-	log.Info().Msg("---------------------------------------------------------")
+	if opt.Get().Mesh.VersionService {
+		// only the mesh pod has both origin selector and version label, so the new service would not select origin pods
+		ports, err := getServicePorts(svc, general.GetTargetPorts(svc))
+		if err != nil {
+			return err
+		}
+		selectors := getMeshLabels(meshKey, meshVersion, svc)
+		if err = createShadowService(shadowPodName, ports, selectors); err != nil {
+			return err
+		}
+		util.StatusLog().Msg("---------------------------------------------------------")
+		util.StatusLog().Msgf(" Now you can access version '%s' via service '%s' ", meshVersion, shadowPodName)
+		util.StatusLog().Msg("---------------------------------------------------------")
+		return nil
+	}
+	util.StatusLog().Msg("---------------------------------------------------------")
+	util.StatusLog().Msgf(" Now you can update Istio rule by label '%s=%s' ", meshKey, meshVersion)
+	util.StatusLog().Msg("---------------------------------------------------------")
 	return nil
 }
 
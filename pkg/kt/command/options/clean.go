@@ -8,7 +8,7 @@ func CleanFlags() []OptionConfig {
 	flags := []OptionConfig{
 		{
 			Target:       "ThresholdInMinus",
-			DefaultValue: util.ResourceHeartBeatIntervalMinus * 2 + 1,
+			DefaultValue: util.ResourceHeartBeatIntervalMinus*2 + 1,
 			Description:  "Length of allowed disconnection time before a unavailing shadow pod be deleted",
 		},
 		{
@@ -21,6 +21,11 @@ func CleanFlags() []OptionConfig {
 			DefaultValue: false,
 			Description:  "Only check and restore local changes made by kt",
 		},
+		{
+			Target:       "RestoreOrigins",
+			DefaultValue: false,
+			Description:  "Restore origins of orphaned exchange shadows in all namespaces (or only the one specified via --namespace)",
+		},
 	}
 	return flags
 }
@@ -7,86 +7,146 @@ import (
 func ConnectFlags() []OptionConfig {
 	flags := []OptionConfig{
 		{
-			Target:      "Mode",
+			Target:       "Mode",
 			DefaultValue: util.ConnectModeTun2Socks,
-			Description: "Connect mode 'tun2socks' or 'sshuttle'",
+			Description:  "Connect mode 'tun2socks' or 'sshuttle'",
 		},
 		{
-			Target:      "DnsMode",
+			Target:       "DnsMode",
 			DefaultValue: util.DnsModeLocalDns,
-			Description: "Specify how to resolve service domains, can be 'localDNS', 'podDNS', 'hosts' or 'hosts:<namespaces>', for multiple namespaces use ',' separation",
+			Description:  "Specify how to resolve service domains, can be 'localDNS', 'podDNS', 'hosts' or 'hosts:<namespaces>', for multiple namespaces use ',' separation",
 		},
 		{
-			Target:      "ShareShadow",
+			Target:       "ShareShadow",
 			DefaultValue: false,
-			Description: "Use shared shadow pod",
+			Description:  "Use shared shadow pod",
 		},
 		{
-			Target:      "ClusterDomain",
-			DefaultValue: "cluster.local",
-			Description: "The cluster domain provided to kubernetes api-server",
+			Target:       "ClusterDomain",
+			DefaultValue: "",
+			Description:  "The cluster domain provided to kubernetes api-server, auto detect from shadow pod if not specified",
 		},
 		{
-			Target:      "DisablePodIp",
+			Target:       "DisablePodIp",
 			DefaultValue: false,
-			Description: "Disable access to pod IP address",
+			Description:  "Disable access to pod IP address",
 		},
 		{
-			Target:      "SkipCleanup",
+			Target:       "AutoCidr",
 			DefaultValue: false,
-			Description: "Do not auto cleanup residual resources in cluster",
+			Description:  "Detect cluster CIDR from pod CIDR of nodes and service IP range of api server",
 		},
 		{
-			Target:      "IncludeIps",
+			Target:       "SkipCleanup",
+			DefaultValue: false,
+			Description:  "Do not auto cleanup residual resources in cluster",
+		},
+		{
+			Target:       "IncludeIps",
 			DefaultValue: "",
-			Description: "Specify extra IP ranges which should be route to cluster, e.g. '172.2.0.0/16', use ',' separated",
+			Description:  "Specify extra IP ranges which should be route to cluster, e.g. '172.2.0.0/16', use ',' separated",
 		},
 		{
-			Target:      "ExcludeIps",
+			Target:       "ExcludeIps",
 			DefaultValue: "",
-			Description: "Do not route specified IPs to cluster, e.g. '192.168.64.2' or '192.168.64.0/24', use ',' separated",
+			Description:  "Do not route specified IPs to cluster, e.g. '192.168.64.2' or '192.168.64.0/24', use ',' separated",
 		},
 		{
-			Target:      "IngressIp",
+			Target:       "IngressIp",
 			DefaultValue: "",
-			Description: "Specify an IP address which all ingress domains should be resolve to",
+			Description:  "Specify an IP address which all ingress domains should be resolve to",
 		},
 		{
-			Target:      "DisableTunDevice",
+			Target:       "DisableTunDevice",
 			DefaultValue: false,
-			Description: "(tun2socks mode only) Create socks5 proxy without tun device",
+			Description:  "(tun2socks mode only) Create socks5 proxy without tun device",
 		},
 		{
-			Target:      "DisableTunRoute",
+			Target:       "DisableTunRoute",
 			DefaultValue: false,
-			Description: "(tun2socks mode only) Do not auto setup tun device route",
+			Description:  "(tun2socks mode only) Do not auto setup tun device route",
 		},
 		{
-			Target:      "ProxyPort",
+			Target:       "Mtu",
+			DefaultValue: util.DefaultTunMtu,
+			Description:  "(tun2socks mode only) MTU of tun device, lower it if large responses hang or get truncated",
+		},
+		{
+			Target:       "ProxyPort",
 			DefaultValue: 2223,
-			Description: "(tun2socks mode only) Specify the local port which socks5 proxy should use",
+			Description:  "(tun2socks mode only) Specify the local port which socks5 proxy should use",
 		},
 		{
-			Target:      "ProxyAddr",
+			Target:       "ProxyAddr",
 			DefaultValue: "127.0.0.1",
-			Description: "(tun2socks mode only) Specify the ip address or hostname which socks5 proxy should use",
+			Description:  "(tun2socks mode only) Specify the ip address or hostname which socks5 proxy should use",
 		},
 		{
-			Target:      "DnsCacheTtl",
+			Target:       "DnsCacheTtl",
 			DefaultValue: 60,
-			Description: "(local dns mode only) DNS cache refresh interval in seconds",
+			Description:  "(local dns mode only) DNS cache refresh interval in seconds",
+		},
+		{
+			Target:       "DnsTtl",
+			DefaultValue: 5,
+			Description:  "(local dns mode only) TTL in seconds of DNS records of cluster domains returned to applications",
+		},
+		{
+			Target:       "SplitDns",
+			DefaultValue: false,
+			Description:  "(local dns mode only) Only query cluster domains via cluster DNS, other domains via upstream DNS",
+		},
+		{
+			Target:       "Probe",
+			DefaultValue: "",
+			Description:  "Check reachability of specified targets after connected, e.g. 'svc-a:80,svc-b.ns:8080', use ',' separated",
+		},
+		{
+			Target:       "MapService",
+			DefaultValue: "",
+			Description:  "Resolve specified service names to fixed IPs instead of querying cluster DNS, e.g. 'svc-a=10.0.0.5', use ',' separated",
+		},
+		{
+			Target:       "Replicas",
+			DefaultValue: 1,
+			Description:  "Number of shadow pods to deploy, local client switches to another one when current shadow pod is gone",
+		},
+		{
+			Target:       "Compression",
+			DefaultValue: false,
+			Description:  "(sshuttle mode only) Enable compression of ssh tunnel, may slow down high-throughput binary transfers",
+		},
+		{
+			Target:       "TcpOnly",
+			DefaultValue: false,
+			Description:  "(tun2socks mode only) Only route tcp traffic to cluster, let udp traffic stay on host network (linux only)",
+		},
+		{
+			Target:       "Exec",
+			DefaultValue: "",
+			Description:  "(tun2socks mode only) Run specified command and only route its traffic to cluster, connect exits with it (per-process routing is linux only)",
+		},
+		{
+			Target:       "Gateway",
+			DefaultValue: false,
+			Description:  "Connect through the connect gateway installed via 'ktctl gateway install' instead of creating a shadow pod",
+		},
+		{
+			Target:       "GatewayTokenTtl",
+			DefaultValue: 720,
+			Description:  "(gateway only) Minutes before the access key authorized on connect gateway expires",
 		},
 	}
 	if util.IsMacos() {
 		flags = append(flags,
-			OptionConfig {
-				Target:      "DnsPort",
+			OptionConfig{
+				Target:       "DnsPort",
 				DefaultValue: util.AlternativeDnsPort,
-				Description: "(local dns mode only) Specify local DNS port",
-			}, OptionConfig {
-				Target:      "IncludeDomains",
+				Description:  "(local dns mode only) Specify local DNS port",
+			}, OptionConfig{
+				Target:       "IncludeDomains",
 				DefaultValue: "",
-				Description: "Query domain names of specified suffixes via kt DNS, e.g. 'com', use ',' separated",
+				Description:  "Query domain names of specified suffixes via kt DNS, e.g. 'com', use ',' separated",
 			},
 		)
 	}
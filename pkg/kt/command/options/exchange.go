@@ -7,24 +7,304 @@ func ExchangeFlags() []OptionConfig {
 		{
 			Target:       "Expose",
 			DefaultValue: "",
-			Description:  "Ports to expose, use ',' separated, in [port] or [local:remote] format, e.g. 7001,8080:80",
-			Required:     true,
+			Description:  "Ports to expose, use ',' separated, in [port] or [local:remote] format, e.g. 7001,8080:80 (can be overridden via '<service-name>:<ports>')",
+		},
+		{
+			Target:       "File",
+			DefaultValue: "",
+			Description:  "Yaml or json file describing targets, ports and options of exchange",
 		},
 		{
 			Target:       "Mode",
 			DefaultValue: util.ExchangeModeSelector,
-			Description:  "Exchange method 'selector', 'scale' or 'ephemeral'(experimental)",
+			Description:  "Exchange method 'selector', 'scale', 'ephemeral'(experimental) or 'auto'",
+		},
+		{
+			Target:       "Gateway",
+			DefaultValue: false,
+			Description:  "(selector method only) Only route requests with specified header to local via gateway api http routes",
+		},
+		{
+			Target:       "Header",
+			DefaultValue: "",
+			Description:  "Header of requests to route to local when '--gateway' is used, in '<name>:<value>' format",
 		},
 		{
 			Target:       "SkipPortChecking",
 			DefaultValue: false,
 			Description:  "Do not check whether specified local ports are listened",
 		},
+		{
+			Target:       "StrictPorts",
+			DefaultValue: false,
+			Description:  "(scale and ephemeral method only) Abort instead of warning when remote port is not declared by any container or service port of the target",
+		},
+		{
+			Target:       "ListPorts",
+			DefaultValue: false,
+			Description:  "Only list ports of containers and services of the target, without exchanging it",
+		},
 		{
 			Target:       "RecoverWaitTime",
 			DefaultValue: 120,
 			Description:  "(scale method only) Seconds to wait for original deployment recover before turn off the shadow pod",
 		},
+		{
+			Target:       "DrainTimeout",
+			DefaultValue: 0,
+			Description:  "Seconds to wait for in-flight requests to local finish before recover the origin, 0 for no waiting",
+		},
+		{
+			Target:       "RestoreReplicas",
+			DefaultValue: 0,
+			Description:  "(scale method only) Replicas to scale the original deployment back to, 0 for using recorded replicas",
+		},
+		{
+			Target:       "OriginReplicas",
+			DefaultValue: 0,
+			Description:  "(scale method only) Replicas of the original deployment to keep during exchange",
+		},
+		{
+			Target:       "GracefulCutover",
+			DefaultValue: false,
+			Description:  "(scale method only) Detach origin pods from service instead of killing them, existing connections are drained over '--drainTimeout' seconds",
+		},
+		{
+			Target:       "TerminateWaitTime",
+			DefaultValue: 60,
+			Description:  "(scale method only) Seconds to wait for original pods to leave service endpoints after deployment scaled down, before exchange is reported ready",
+		},
+		{
+			Target:       "TailOrigin",
+			DefaultValue: false,
+			Description:  "(scale method only) Print logs of origin pods during exchange",
+		},
+		{
+			Target:       "ServicesOnly",
+			DefaultValue: "",
+			Description:  "(scale method only) Only redirect requests of specified services selecting the origin, use ',' separated",
+		},
+		{
+			Target:       "AllowOutage",
+			DefaultValue: false,
+			Description:  "(scale method only) Allow scaling down the only replica of origin deployment",
+		},
+		{
+			Target:       "RequireHealthy",
+			DefaultValue: false,
+			Description:  "(scale method only) Abort if origin deployment has no ready pod before exchange",
+		},
+		{
+			Target:       "ExcludeContainer",
+			DefaultValue: "",
+			Description:  "(ephemeral method only) Do not hijack ports declared by specified containers, e.g. 'log-agent', use ',' separated",
+		},
+		{
+			Target:       "PrintRules",
+			DefaultValue: false,
+			Description:  "(ephemeral method only) Print redirect rules installed in the ephemeral container",
+		},
+		{
+			Target:       "ShareProcessNs",
+			DefaultValue: false,
+			Description:  "(ephemeral method only) Let the ephemeral container share process namespace of the container serving exposed port",
+		},
+		{
+			Target:       "Throttle",
+			DefaultValue: "",
+			Description:  "Limit bandwidth of each connection forwarded to local, in bytes per second, e.g. '512', '100K' or '1M'",
+		},
+		{
+			Target:       "Latency",
+			DefaultValue: 0,
+			Description:  "Milliseconds to delay data forwarded to local, for simulating slow network, 0 for disable",
+		},
+		{
+			Target:       "ProbePath",
+			DefaultValue: "",
+			Description:  "Http path of local service to check before exchange, exchange aborts unless the expected status responded",
+		},
+		{
+			Target:       "ProbeStatus",
+			DefaultValue: 0,
+			Description:  "Expected http status of '--probePath' response, 0 for any 2xx status",
+		},
+		{
+			Target:       "ProbeTimeout",
+			DefaultValue: 10,
+			Description:  "Seconds to keep retrying '--probePath' until expected status responded",
+		},
+		{
+			Target:       "NotifyUrl",
+			DefaultValue: "",
+			Description:  "Post lifecycle events to specified url when exchange becomes active and when teardown begins",
+		},
+		{
+			Target:       "NotifySignal",
+			DefaultValue: "",
+			Description:  "Send signal to command of '--exec' when exchange becomes active and when teardown begins, in '<signal>[:<teardownSignal>]' format",
+		},
+		{
+			Target:       "Exec",
+			DefaultValue: "",
+			Description:  "Run specified command as local service before exchanging, exchange exits with it",
+		},
+		{
+			Target:       "DumpMounts",
+			DefaultValue: "",
+			Description:  "Write configmaps and secrets mounted by origin container to specified directory, following their mount paths",
+		},
+		{
+			Target:       "ResetAffinity",
+			DefaultValue: false,
+			Description:  "(selector and scale method only) Disable 'ClientIP' session affinity of target service during exchange",
+		},
+		{
+			Target:       "HostAlias",
+			DefaultValue: "",
+			Description:  "(selector and scale method only) Add host aliases to shadow pod, e.g. 'db.internal=10.0.0.5', use ',' separated",
+		},
+		{
+			Target:       "CopyHostAliases",
+			DefaultValue: false,
+			Description:  "(selector and scale method only) Copy host aliases of origin pod to shadow pod",
+		},
+		{
+			Target:       "Ingress",
+			DefaultValue: "",
+			Description:  "Create an ingress of specified host to exchanged service, e.g. 'demo.example.com'",
+		},
+		{
+			Target:       "IngressClass",
+			DefaultValue: "",
+			Description:  "Specify ingress class name of the ingress created by '--ingress'",
+		},
+		{
+			Target:       "IngressTls",
+			DefaultValue: "",
+			Description:  "Specify tls secret name of the ingress created by '--ingress'",
+		},
+		{
+			Target:       "KeepOtherPorts",
+			DefaultValue: false,
+			Description:  "(scale method only) Forward requests to ports not exposed to a copy of original pod",
+		},
+		{
+			Target:       "Passthrough",
+			DefaultValue: "",
+			Description:  "(scale method only) Forward requests to specified ports not exposed to a copy of original pod, use ',' separated",
+		},
+		{
+			Target:       "HealthPort",
+			DefaultValue: 0,
+			Description:  "(selector and scale method only) Port of shadow pod health endpoint for readiness probe, 0 for no probe",
+		},
+		{
+			Target:       "ReuseShadow",
+			DefaultValue: false,
+			Description:  "(selector method only) Keep shadow pod after exit, and reuse it in later exchange of same service and ports",
+		},
+		{
+			Target:       "ReuseShadowTtl",
+			DefaultValue: 60,
+			Description:  "(selector method only) Minutes before a reusable shadow pod should be recreated",
+		},
+		{
+			Target:       "ShadowName",
+			DefaultValue: "",
+			Description:  "(selector and scale method only) Specify name of shadow pod instead of generating a random one",
+		},
+		{
+			Target:       "KeyRotateInterval",
+			DefaultValue: 0,
+			Description:  "(selector and scale method only) Minutes between rotating ssh key of shadow pod, 0 for never rotate",
+		},
+		{
+			Target:       "Mirror",
+			DefaultValue: false,
+			Description:  "(selector method only) Copy requests to local via istio mirror route, while origin pods keep handling them",
+		},
+		{
+			Target:       "MirrorPercent",
+			DefaultValue: 100,
+			Description:  "(selector method only) Percentage of requests to copy to local when '--mirror' is specified",
+		},
+		{
+			Target:       "Sync",
+			DefaultValue: "",
+			Description:  "(selector and scale method only) Keep local files synced to shadow pod, in '<local-path>:<remote-path>' format",
+		},
+		{
+			Target:       "SyncIgnore",
+			DefaultValue: ".git",
+			Description:  "(selector and scale method only) Comma separated patterns of files not to sync",
+		},
+		{
+			Target:       "Yes",
+			Alias:        "y",
+			DefaultValue: false,
+			Description:  "Do not prompt, use the preferred resource type when resources of different types share the name",
+		},
+		{
+			Target:       "IKnowWhatImDoing",
+			DefaultValue: false,
+			Description:  "Allow exchanging resources in kube-system namespace or created by kt-connect",
+		},
+		{
+			Target:       "EmitManifests",
+			DefaultValue: "",
+			Description:  "Write resources to create into specified folder as yaml files, without applying them to cluster",
+		},
+		{
+			Target:       "Record",
+			DefaultValue: "",
+			Description:  "Record http requests forwarded to local into specified file, which could be re-sent via 'ktctl replay'",
+		},
+		{
+			Target:       "RecordBodyLimit",
+			DefaultValue: 0,
+			Description:  "Max bytes of request body to record when '--record' is specified, 0 for not record body",
+		},
+		{
+			Target:       "RedactHeaders",
+			DefaultValue: "Authorization,Cookie,Proxy-Authorization",
+			Description:  "Comma separated headers whose value are hidden in record file",
+		},
+		{
+			Target:       "Simulate",
+			DefaultValue: false,
+			Description:  "Walk through exchange steps with an in-memory cluster and local echo server, for demo purpose",
+		},
+		{
+			Target:       "DebugPort",
+			DefaultValue: "",
+			Description:  "Port of local debugger to expose, in [port] or [local:remote] format, e.g. 5005 or 9229:9230",
+		},
+		{
+			Target:       "Load",
+			DefaultValue: 0,
+			Description:  "Send specified number of requests per second to exchanged ports after exchange is ready, 0 for disable",
+		},
+		{
+			Target:       "LoadTemplate",
+			DefaultValue: "",
+			Description:  "Record file of 'exchange --record' whose requests are sent in turn by '--load', use 'GET /' if not specified",
+		},
+		{
+			Target:       "AutoResolve",
+			DefaultValue: false,
+			Description:  "Exchange the only deployment in namespace when no resource is specified",
+		},
+		{
+			Target:       "InboundOnly",
+			DefaultValue: false,
+			Description:  "Only redirect requests of target to local, refuse to run if connect is routing local requests to cluster",
+		},
+		{
+			Target:       "OutboundOnly",
+			DefaultValue: false,
+			Description:  "Only connect local to cluster without exchanging any resource, same as 'ktctl connect'",
+		},
 	}
 	return flags
 }
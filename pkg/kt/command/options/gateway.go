@@ -0,0 +1,17 @@
+package options
+
+func GatewayFlags() []OptionConfig {
+	flags := []OptionConfig{
+		{
+			Target:       "Replicas",
+			DefaultValue: 1,
+			Description:  "Number of connect gateway pods",
+		},
+		{
+			Target:       "DnsProtocol",
+			DefaultValue: "tcp",
+			Description:  "Protocol of dns server in connect gateway, 'tcp' for clients using 'localDNS' dns mode, 'udp' for others",
+		},
+	}
+	return flags
+}
@@ -3,6 +3,7 @@ package options
 import (
 	"fmt"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"os"
 )
 
 func GlobalFlags() []OptionConfig {
@@ -10,14 +11,15 @@ func GlobalFlags() []OptionConfig {
 		{
 			Target:       "Namespace",
 			Alias:        "n",
-			DefaultValue: util.DefaultNamespace,
-			Description:  "Specify target namespace (otherwise follow kubeconfig current context)",
+			DefaultValue: "",
+			Description:  "Specify target namespace (otherwise follow $KT_NAMESPACE or kubeconfig current context)",
 		},
 		{
-			Target:       "Kubeconfig",
-			Alias:        "c",
-			DefaultValue: "",
-			Description:  "Specify path of KubeConfig file",
+			Target:        "Kubeconfig",
+			Alias:         "c",
+			DefaultValue:  "",
+			Description:   "Specify path of KubeConfig file, could be specified multiple times to merge files in order",
+			ListSeparator: string(os.PathListSeparator),
 		},
 		{
 			Target:       "Context",
@@ -30,6 +32,11 @@ func GlobalFlags() []OptionConfig {
 			DefaultValue: fmt.Sprintf("%s:v%s", util.ImageKtShadow, Store.Version),
 			Description:  "Customize shadow image",
 		},
+		{
+			Target:       "WindowsImage",
+			DefaultValue: "",
+			Description:  "Shadow image for exchanging pods running on windows node",
+		},
 		{
 			Target:       "ImagePullSecret",
 			DefaultValue: "",
@@ -45,12 +52,28 @@ func GlobalFlags() []OptionConfig {
 			DefaultValue: "",
 			Description:  "Specify location of shadow and route pod by node label, e.g. 'disk=ssd,region=hangzhou'",
 		},
+		{
+			Target:       "TopologySpread",
+			DefaultValue: "",
+			Description:  "Topology spread constraints of shadow pod in '<topologyKey>:<maxSkew>[:<whenUnsatisfiable>]' format, use ',' separated",
+		},
+		{
+			Target:       "CopyTopologySpread",
+			DefaultValue: false,
+			Description:  "(exchange and mesh only) Copy topology spread constraints of target pod to shadow pod",
+		},
 		{
 			Target:       "Debug",
 			Alias:        "d",
 			DefaultValue: false,
 			Description:  "Print debug log",
 		},
+		{
+			Target:       "Quiet",
+			Alias:        "q",
+			DefaultValue: false,
+			Description:  "Only print error log and key status messages",
+		},
 		{
 			Target:       "WithLabel",
 			Alias:        "l",
@@ -62,16 +85,36 @@ func GlobalFlags() []OptionConfig {
 			DefaultValue: "",
 			Description:  "Extra annotation on shadow pod e.g. 'annotation1=val1,annotation2=val2'",
 		},
+		{
+			Target:       "DisableInjection",
+			DefaultValue: false,
+			Description:  "Add opt-out labels and annotations of sidecar injectors to shadow pod, which is already the default unless '--allowInjection' is set",
+		},
+		{
+			Target:       "AllowInjection",
+			DefaultValue: false,
+			Description:  "Do not add opt-out labels and annotations of sidecar injectors (istio, linkerd, vault, etc.) to shadow pod",
+		},
 		{
 			Target:       "PortForwardTimeout",
 			DefaultValue: 10,
 			Description:  "Seconds to wait before port-forward connection timeout",
 		},
+		{
+			Target:       "DirectPodIp",
+			DefaultValue: false,
+			Description:  "Tunnel ssh to shadow pod via its ip instead of api server port-forward, if the ip is directly reachable",
+		},
 		{
 			Target:       "PodCreationTimeout",
 			DefaultValue: 60,
 			Description:  "Seconds to wait before shadow or router pod creation timeout",
 		},
+		{
+			Target:       "PodPollInterval",
+			DefaultValue: 3,
+			Description:  "Seconds between each check of pod status while waiting for pod to be ready",
+		},
 		{
 			Target:       "UseShadowDeployment",
 			DefaultValue: false,
@@ -88,6 +131,26 @@ func GlobalFlags() []OptionConfig {
 			DefaultValue: false,
 			Description:  "Always re-pull the latest shadow and router image",
 		},
+		{
+			Target:       "ForceDeleteExisting",
+			DefaultValue: false,
+			Description:  "Delete shadow with the same name or target label left by previous run before creating",
+		},
+		{
+			Target:       "MutateService",
+			DefaultValue: false,
+			Description:  "Allow changing selector of services in protected namespaces, i.e. system namespaces and those listed by cluster policy",
+		},
+		{
+			Target:       "ShadowTtl",
+			DefaultValue: 0,
+			Description:  "Minutes after which shadow could be reaped by other kt command once its heartbeat stopped, 0 for never",
+		},
+		{
+			Target:       "CreateNamespace",
+			DefaultValue: false,
+			Description:  "Create target namespace if not exist, and remove it on exit if nothing else is left in it",
+		},
 		{
 			Target:       "AsWorker",
 			DefaultValue: false,
@@ -99,11 +162,86 @@ func GlobalFlags() []OptionConfig {
 			DefaultValue: "",
 			Description:  "Specify resource limit for shadow and router pod, e.g. '0.5c,512m'",
 		},
+		{
+			Target:       "PriorityClass",
+			DefaultValue: "",
+			Description:  "Specify priority class name of shadow and router pod",
+		},
 		{
 			Target:       "IpVersion",
 			DefaultValue: 4,
 			Description:  "network type connect local and remote,the value could be '4' or '6'",
 		},
+		{
+			Target:       "ListenInterface",
+			DefaultValue: "",
+			Description:  "(exchange, mesh and preview only) Local address for tunnel listeners and accessing local service, e.g. 127.0.0.50",
+		},
+		{
+			Target:       "Proxy",
+			DefaultValue: "",
+			Description:  "HTTP proxy for connecting to kubernetes api server, e.g. http://proxy.corp:3128, use $HTTPS_PROXY if not specified",
+		},
+		{
+			Target:       "SshCiphers",
+			DefaultValue: "",
+			Description:  "Ciphers allowed for ssh tunnel, use ',' separated, e.g. 'aes256-ctr,aes128-gcm@openssh.com'",
+		},
+		{
+			Target:       "SshKex",
+			DefaultValue: "",
+			Description:  "Key exchange algorithms allowed for ssh tunnel, use ',' separated, e.g. 'curve25519-sha256'",
+		},
+		{
+			Target:       "SshMacs",
+			DefaultValue: "",
+			Description:  "MAC algorithms allowed for ssh tunnel, use ',' separated, e.g. 'hmac-sha2-256'",
+		},
+		{
+			Target:       "SshLegacyAlgorithms",
+			DefaultValue: false,
+			Description:  "Allow weak ssh algorithms (e.g. arcfour, cbc ciphers, diffie-hellman-group1-sha1) for legacy environments",
+		},
+		{
+			Target:       "TunnelPoolSize",
+			DefaultValue: 0,
+			Description:  "(exchange and forward only) Number of established ssh channels kept for each outbound tunnel to target behind shadow pod, 0 for disable",
+		},
+		{
+			Target:       "StubUnbound",
+			DefaultValue: "",
+			Description:  "(exchange, mesh and preview only) Respond with specified http status and message when local port is not listened, e.g. '503:Not started'",
+		},
+		{
+			Target:       "RestartGrace",
+			DefaultValue: 0,
+			Description:  "(exchange, mesh and preview only) Seconds to hold and retry requests while local service is restarting, 0 for disable",
+		},
+		{
+			Target:       "NameSuffixLength",
+			DefaultValue: util.DefaultNameSuffixLength,
+			Description:  "Length of random suffix of generated resource names",
+		},
+		{
+			Target:       "NameSuffixCharset",
+			DefaultValue: "",
+			Description:  "Characters used in random suffix of generated resource names, only lowercase letters and digits allowed, use lowercase letters if not specified",
+		},
+		{
+			Target:       "ConfigNamespace",
+			DefaultValue: util.NamespaceKubeSystem,
+			Description:  "Namespace of '" + util.KtClusterConfig + "' config map for cluster wide default options, policies are always read from kube-system",
+		},
+		{
+			Target:       "Output",
+			DefaultValue: util.OutputText,
+			Description:  "Format of shadow pod creation progress, 'text' for log or 'json' for events printed to stdout",
+		},
+		{
+			Target:       "EventSocket",
+			DefaultValue: "",
+			Description:  "Path of unix socket to send progress events to and accept 'status' or 'teardown' command from, e.g. for IDE plugins",
+		},
 	}
 	return flags
 }
@@ -28,6 +28,11 @@ func MeshFlags() []OptionConfig {
 			DefaultValue: false,
 			Description:  "Do not check whether specified local ports are listened",
 		},
+		{
+			Target:       "VersionService",
+			DefaultValue: false,
+			Description:  "(manual method only) Create a service which only select the mesh pod",
+		},
 		{
 			Target:       "RouterImage",
 			DefaultValue: fmt.Sprintf("%s:v%s", util.ImageKtRouter, Store.Version),
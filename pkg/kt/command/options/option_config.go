@@ -9,12 +9,40 @@ import (
 )
 
 type OptionConfig struct {
-	Target string
-	Alias string
+	Target       string
+	Alias        string
 	DefaultValue any
-	Description string
-	Hidden bool
-	Required bool
+	Description  string
+	Hidden       bool
+	Required     bool
+	// ListSeparator make string option repeatable, values of repeated flags are joined with it
+	ListSeparator string
+}
+
+// listValue string flag which could be specified multiple times
+type listValue struct {
+	target    *string
+	separator string
+	changed   bool
+}
+
+func (v *listValue) Set(s string) error {
+	if v.changed {
+		*v.target = *v.target + v.separator + s
+	} else {
+		// first value replaces the default one
+		*v.target = s
+		v.changed = true
+	}
+	return nil
+}
+
+func (v *listValue) String() string {
+	return *v.target
+}
+
+func (v *listValue) Type() string {
+	return "string"
 }
 
 func SetOptions(cmd *cobra.Command, flags *flag.FlagSet, optionStore any, config []OptionConfig) {
@@ -32,7 +60,10 @@ func SetOptions(cmd *cobra.Command, flags *flag.FlagSet, optionStore any, config
 			if field.String() != "" {
 				defaultValue = field.String()
 			}
-			if c.Alias != "" {
+			if c.ListSeparator != "" {
+				*fieldPtr = defaultValue
+				flags.VarP(&listValue{target: fieldPtr, separator: c.ListSeparator}, name, c.Alias, c.Description)
+			} else if c.Alias != "" {
 				flags.StringVarP(fieldPtr, name, c.Alias, defaultValue, c.Description)
 			} else {
 				flags.StringVar(fieldPtr, name, defaultValue, c.Description)
@@ -67,4 +98,4 @@ func SetOptions(cmd *cobra.Command, flags *flag.FlagSet, optionStore any, config
 			_ = cmd.MarkFlagRequired(name)
 		}
 	}
-}
\ No newline at end of file
+}
@@ -13,12 +13,16 @@ import (
 type ConnectOptions struct {
 	Global           bool
 	DisablePodIp     bool
+	AutoCidr         bool
 	DisableTunDevice bool
 	DisableTunRoute  bool
+	Mtu              int
 	ProxyPort        int
 	ProxyAddr        string
 	DnsPort          int
 	DnsCacheTtl      int
+	DnsTtl           int
+	SplitDns         bool
 	IncludeIps       string
 	ExcludeIps       string
 	IngressIp        string
@@ -28,14 +32,78 @@ type ConnectOptions struct {
 	ClusterDomain    string
 	SkipCleanup      bool
 	IncludeDomains   string
+	Probe            string
+	MapService       string
+	Replicas         int
+	Compression      bool
+	TcpOnly          bool
+	Exec             string
+	Gateway          bool
+	GatewayTokenTtl  int
 }
 
 // ExchangeOptions ...
 type ExchangeOptions struct {
-	Mode             string
-	Expose           string
-	RecoverWaitTime  int
-	SkipPortChecking bool
+	Mode              string
+	Expose            string
+	RecoverWaitTime   int
+	DrainTimeout      int
+	RestoreReplicas   int
+	OriginReplicas    int
+	GracefulCutover   bool
+	TailOrigin        bool
+	RequireHealthy    bool
+	ExcludeContainer  string
+	PrintRules        bool
+	ResetAffinity     bool
+	HostAlias         string
+	CopyHostAliases   bool
+	Ingress           string
+	IngressClass      string
+	IngressTls        string
+	TerminateWaitTime int
+	KeyRotateInterval int
+	KeepOtherPorts    bool
+	Passthrough       string
+	File              string
+	HealthPort        int
+	ReuseShadow       bool
+	ReuseShadowTtl    int
+	Sync              string
+	SyncIgnore        string
+	StrictPorts       bool
+	Mirror            bool
+	MirrorPercent     int
+	Gateway           bool
+	Header            string
+	SkipPortChecking  bool
+	ListPorts         bool
+	EmitManifests     string
+	ShadowName        string
+	Yes               bool
+	IKnowWhatImDoing  bool
+	Record            string
+	RecordBodyLimit   int
+	RedactHeaders     string
+	Simulate          bool
+	DebugPort         string
+	AllowOutage       bool
+	ServicesOnly      string
+	Load              int
+	LoadTemplate      string
+	AutoResolve       bool
+	ShareProcessNs    bool
+	Throttle          string
+	Latency           int
+	ProbePath         string
+	ProbeStatus       int
+	ProbeTimeout      int
+	NotifyUrl         string
+	NotifySignal      string
+	Exec              string
+	DumpMounts        string
+	InboundOnly       bool
+	OutboundOnly      bool
 }
 
 // MeshOptions ...
@@ -45,10 +113,12 @@ type MeshOptions struct {
 	VersionMark      string
 	RouterImage      string
 	SkipPortChecking bool
+	VersionService   bool
 }
 
 // RecoverOptions ...
 type RecoverOptions struct {
+	RestoreReplicas int
 }
 
 // PreviewOptions ...
@@ -56,6 +126,8 @@ type PreviewOptions struct {
 	External         bool
 	Expose           string
 	SkipPortChecking bool
+	Sync             string
+	SyncIgnore       string
 }
 
 // ForwardOptions ...
@@ -67,12 +139,31 @@ type CleanOptions struct {
 	DryRun           bool
 	ThresholdInMinus int64
 	LocalOnly        bool
+	RestoreOrigins   bool
+}
+
+// PreheatOptions ...
+type PreheatOptions struct {
+	Timeout int
+}
+
+// GatewayOptions ...
+type GatewayOptions struct {
+	Replicas    int
+	DnsProtocol string
 }
 
 // ConfigOptions ...
 type ConfigOptions struct {
 }
 
+// ReplayOptions ...
+type ReplayOptions struct {
+	Target   string
+	Exchange string
+	Expose   string
+}
+
 // BirdseyeOptions ...
 type BirdseyeOptions struct {
 	SortBy             string
@@ -80,6 +171,11 @@ type BirdseyeOptions struct {
 	HideNaturalService bool
 }
 
+// VersionOptions ...
+type VersionOptions struct {
+	Remote bool
+}
+
 // GlobalOptions ...
 type GlobalOptions struct {
 	AsWorker            bool
@@ -87,20 +183,47 @@ type GlobalOptions struct {
 	Namespace           string
 	ServiceAccount      string
 	Debug               bool
+	Quiet               bool
 	Image               string
 	ImagePullSecret     string
 	NodeSelector        string
+	TopologySpread      string
+	CopyTopologySpread  bool
 	WithLabel           string
 	WithAnnotation      string
+	DisableInjection    bool
+	AllowInjection      bool
 	PortForwardTimeout  int
+	DirectPodIp         bool
 	PodCreationTimeout  int
+	PodPollInterval     int
 	UseShadowDeployment bool
 	ForceUpdate         bool
+	ForceDeleteExisting bool
+	CreateNamespace     bool
+	ShadowTtl           int
+	MutateService       bool
 	UseLocalTime        bool
 	Context             string
 	PodQuota            string
+	PriorityClass       string
 	ListenCheck         bool
 	IpVersion           int
+	ListenInterface     string
+	Proxy               string
+	WindowsImage        string
+	SshCiphers          string
+	SshKex              string
+	SshMacs             string
+	SshLegacyAlgorithms bool
+	TunnelPoolSize      int
+	StubUnbound         string
+	RestartGrace        int
+	NameSuffixLength    int
+	NameSuffixCharset   string
+	ConfigNamespace     string
+	Output              string
+	EventSocket         string
 }
 
 // DaemonOptions cli options
@@ -113,7 +236,11 @@ type DaemonOptions struct {
 	Recover  *RecoverOptions
 	Clean    *CleanOptions
 	Config   *ConfigOptions
+	Preheat  *PreheatOptions
+	Gateway  *GatewayOptions
 	Birdseye *BirdseyeOptions
+	Replay   *ReplayOptions
+	Version  *VersionOptions
 	Global   *GlobalOptions
 }
 
@@ -131,7 +258,11 @@ func Get() *DaemonOptions {
 			Forward:  &ForwardOptions{},
 			Recover:  &RecoverOptions{},
 			Clean:    &CleanOptions{},
+			Preheat:  &PreheatOptions{},
+			Gateway:  &GatewayOptions{},
 			Birdseye: &BirdseyeOptions{},
+			Replay:   &ReplayOptions{},
+			Version:  &VersionOptions{},
 			Config:   &ConfigOptions{},
 		}
 		if customize, exist := GetCustomizeKtConfig(); exist {
@@ -151,8 +282,26 @@ func mergeOptions(opt *DaemonOptions, data []byte) {
 		log.Warn().Msgf("Invalid config content, skipping ...")
 		return
 	}
+	mergeConfig(opt, config, nil)
+}
+
+// MergeClusterConfig apply options from cluster config, items specified by user via flag or local config are skipped
+func MergeClusterConfig(config map[string]map[string]string, isUserSpecified func(group, key string) bool) {
+	mergeConfig(Get(), config, func(group, key string) bool {
+		return localConfigItems[group+"."+key] || isUserSpecified(group, key)
+	})
+}
+
+// localConfigItems items loaded from local config file, in '<group>.<item>' format
+var localConfigItems = map[string]bool{}
+
+func mergeConfig(opt *DaemonOptions, config map[string]map[string]string, isUserSpecified func(group, key string) bool) {
 	for group, item := range config {
 		for key, value := range item {
+			if isUserSpecified != nil && isUserSpecified(group, key) {
+				log.Debug().Msgf("Config item '%s.%s' specified by user, skipping", group, key)
+				continue
+			}
 			groupField := reflect.ValueOf(opt).Elem().FieldByName(util.Capitalize(group))
 			if groupField.IsValid() {
 				itemField := groupField.Elem().FieldByName(util.Capitalize(key))
@@ -176,6 +325,9 @@ func mergeOptions(opt *DaemonOptions, data []byte) {
 						log.Warn().Msgf("Config item '%s.%s' of invalid type: %s",
 							group, key, itemField.Kind().String())
 					}
+					if isUserSpecified == nil {
+						localConfigItems[group+"."+key] = true
+					}
 					log.Debug().Msgf("Loaded %s.%s = %s", group, key, value)
 				}
 			}
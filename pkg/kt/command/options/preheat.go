@@ -0,0 +1,12 @@
+package options
+
+func PreheatFlags() []OptionConfig {
+	flags := []OptionConfig{
+		{
+			Target:       "Timeout",
+			DefaultValue: 300,
+			Description:  "Seconds to wait for shadow image pulled on all nodes",
+		},
+	}
+	return flags
+}
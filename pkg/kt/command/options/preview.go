@@ -18,6 +18,16 @@ func PreviewFlags() []OptionConfig {
 			DefaultValue: false,
 			Description:  "Do not check whether specified local ports are listened",
 		},
+		{
+			Target:       "Sync",
+			DefaultValue: "",
+			Description:  "Keep local files synced to shadow pod, in '<local-path>:<remote-path>' format",
+		},
+		{
+			Target:       "SyncIgnore",
+			DefaultValue: ".git",
+			Description:  "Comma separated patterns of files not to sync",
+		},
 	}
 	return flags
 }
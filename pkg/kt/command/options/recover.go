@@ -2,6 +2,11 @@ package options
 
 func RecoverFlags() []OptionConfig {
 	flags := []OptionConfig{
+		{
+			Target:       "RestoreReplicas",
+			DefaultValue: 0,
+			Description:  "Replicas to scale the deployment exchanged by scale method back to, 0 for using recorded replicas",
+		},
 	}
 	return flags
-}
\ No newline at end of file
+}
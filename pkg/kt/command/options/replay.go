@@ -0,0 +1,22 @@
+package options
+
+func ReplayFlags() []OptionConfig {
+	flags := []OptionConfig{
+		{
+			Target:       "Target",
+			DefaultValue: "",
+			Description:  "Address to send requests to, e.g. 'localhost:8080', use the local port where requests are recorded if not specified",
+		},
+		{
+			Target:       "Exchange",
+			DefaultValue: "",
+			Description:  "Name of service to exchange, requests are replayed through the shadow pod of a new exchange",
+		},
+		{
+			Target:       "Expose",
+			DefaultValue: "",
+			Description:  "Ports to expose when '--exchange' is specified, in [port] or [local:remote] format, use recorded ports if not specified",
+		},
+	}
+	return flags
+}
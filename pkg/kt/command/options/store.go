@@ -3,9 +3,25 @@ package options
 import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"os"
+	"sync"
 )
 
-var Store = &RuntimeStore{}
+var Store = &RuntimeStore{
+	Replicas:         map[string]int32{},
+	ServiceSelectors: map[string]string{},
+	AppliedSelectors: map[string]string{},
+}
+
+// storeLock guard fields of runtime store changed by background routines
+var storeLock sync.Mutex
+
+// LockStore access runtime store exclusively, for fields changed by background routines, e.g. OriginDraining
+func LockStore(f func(store *RuntimeStore)) {
+	storeLock.Lock()
+	defer storeLock.Unlock()
+	f(Store)
+}
 
 // RuntimeStore ...
 type RuntimeStore struct {
@@ -13,22 +29,63 @@ type RuntimeStore struct {
 	Clientset kubernetes.Interface
 	// RestConfig kubectl config
 	RestConfig *rest.Config
+	// KubeUser user authenticated by kubernetes
+	KubeUser string
 	// Version ktctl version
 	Version string
 	// Component current sub-command (connect, exchange, mesh or preview)
 	Component string
 	// Shadow pod name
 	Shadow string
+	// Gateway connect gateway pod used by connect
+	Gateway string
+	// GatewayKey public key authorized on connect gateway pod
+	GatewayKey string
 	// Router pod name
 	Router string
 	// Mesh version of mesh pod
 	Mesh string
-	// Origin the origin deployment or service name
+	// Origin the origin deployment or service name, comma separated if more than one
 	Origin string
-	// Replicas the origin replicas
-	Replicas int32
-	// Service exposed service name
+	// OriginCopy copy of origin pod name, comma separated if more than one
+	OriginCopy string
+	// OriginDraining origin pods detached from deployment for draining, comma separated if more than one,
+	// should be accessed via LockStore
+	OriginDraining string
+	// UdpRelay udp relays running in ephemeral container, in '<pod>:<pid>' format, comma separated if more than one
+	UdpRelay string
+	// MirrorRoute istio virtual service name for mirroring, comma separated if more than one
+	MirrorRoute string
+	// Replicas the origin replicas of each deployment
+	Replicas map[string]int32
+	// Service exposed service name, comma separated if more than one
 	Service string
+	// HttpRoute gateway api http route name, comma separated if more than one
+	HttpRoute string
+	// Ingress ingress name, comma separated if more than one
+	Ingress string
+	// AffinityService service whose session affinity is disabled, comma separated if more than one
+	AffinityService string
 	// isIpv6Cluster
 	Ipv6Cluster bool
+	// LoopbackAlias loopback alias address created for tunnel listeners
+	LoopbackAlias string
+	// DaemonSet preheat daemon set name, comma separated if more than one
+	DaemonSet string
+	// AllowedModes modes allowed by cluster config of each command, comma separated, empty for no limitation
+	AllowedModes map[string]string
+	// CreatedNamespace namespace created by kt, removed on exit if it's empty
+	CreatedNamespace string
+	// CreatedNamespaceUid uid of namespace created by kt, namespace re-created by others with same name is kept
+	CreatedNamespaceUid string
+	// ServiceSelectors original selector of each service changed by kt, in json format
+	ServiceSelectors map[string]string
+	// AppliedSelectors selector applied by kt to each service, in json format
+	AppliedSelectors map[string]string
+	// MutateServiceNamespaces namespaces in which changing service selector requires '--mutateService', comma separated
+	MutateServiceNamespaces string
+	// ProtectedNamespaces namespaces in which resources are refused to modify by cluster config, comma separated
+	ProtectedNamespaces string
+	// ExecProcess local service started by '--exec' of exchange, which receives '--notifySignal'
+	ExecProcess *os.Process
 }
@@ -0,0 +1,12 @@
+package options
+
+func VersionFlags() []OptionConfig {
+	flags := []OptionConfig{
+		{
+			Target:       "Remote",
+			DefaultValue: false,
+			Description:  "Also show version of kt pods in current namespace",
+		},
+	}
+	return flags
+}
@@ -0,0 +1,79 @@
+package command
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/command/general"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/command/preheat"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+// NewPreheatCommand return new preheat command
+func NewPreheatCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "preheat",
+		Short: "Pull shadow image on cluster nodes in advance",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("too many options specified (%s)", strings.Join(args, ","))
+			}
+			if opt.Get().Preheat.Timeout <= 0 {
+				return fmt.Errorf("timeout should be a positive number")
+			}
+			return prepareAndReap(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return Preheat()
+		},
+		Example: "ktctl preheat [command options]",
+	}
+
+	cmd.SetUsageTemplate(general.UsageTemplate(true))
+	opt.SetOptions(cmd, cmd.Flags(), opt.Get().Preheat, opt.PreheatFlags())
+	return cmd
+}
+
+// Preheat deploy temporary daemon sets to pull shadow image on nodes
+func Preheat() error {
+	ch, err := general.SetupProcess(util.ComponentPreheat)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, target := range preheat.GetTargets() {
+		name := util.RandomName(fmt.Sprintf("%s%s-", util.PreheatPrefix, target.Os))
+		log.Info().Msgf("Pulling image %s on %s nodes", target.Image, target.Os)
+		if _, err = cluster.Ins().CreatePreheatDaemonSet(name, target.Image, target.Os); err != nil {
+			return err
+		}
+		opt.Store.DaemonSet = util.Append(opt.Store.DaemonSet, name)
+		names = append(names, name)
+	}
+
+	statuses, err := preheat.WaitImagePulled(names, ch)
+	if err != nil {
+		return err
+	}
+	if len(statuses) == 0 {
+		log.Warn().Msgf("No node available for shadow pod, please check the '--nodeSelector' option")
+		return nil
+	}
+	failedCount := 0
+	log.Info().Msgf("---- Image pulling status of nodes ----")
+	for _, s := range statuses {
+		log.Info().Msgf("> %s - %s (%s)", s.Node, s.Message, s.Image)
+		if !s.Pulled {
+			failedCount++
+		}
+	}
+	if failedCount > 0 {
+		return fmt.Errorf("image not pulled on %d of %d nodes", failedCount, len(statuses))
+	}
+	log.Info().Msgf("Image pulled on all %d nodes", len(statuses))
+	return nil
+}
@@ -0,0 +1,119 @@
+package preheat
+
+import (
+	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	"os"
+	"sort"
+	"time"
+)
+
+// Target image to pull on nodes of specified os
+type Target struct {
+	Os    string
+	Image string
+}
+
+// NodeStatus image pulling status of one node
+type NodeStatus struct {
+	Node    string
+	Image   string
+	Done    bool
+	Pulled  bool
+	Message string
+}
+
+// pullFailureReasons waiting reasons indicating image cannot be pulled
+var pullFailureReasons = []string{"ErrImagePull", "ImagePullBackOff", "InvalidImageName", "ErrImageNeverPull",
+	"RegistryUnavailable"}
+
+// GetTargets shadow images to pull, same as the ones shadow pods would use
+func GetTargets() []Target {
+	targets := []Target{{Os: util.OsLinux, Image: opt.Get().Global.Image}}
+	if opt.Get().Global.WindowsImage != "" {
+		targets = append(targets, Target{Os: util.OsWindows, Image: opt.Get().Global.WindowsImage})
+	}
+	return targets
+}
+
+// WaitImagePulled wait until image pulling of all daemon sets finished or failed, return status of each node
+func WaitImagePulled(names []string, ch chan os.Signal) ([]NodeStatus, error) {
+	deadline := time.Now().Add(time.Duration(opt.Get().Preheat.Timeout) * time.Second)
+	for {
+		statuses, finished, err := getNodeStatuses(names)
+		if err != nil {
+			return nil, err
+		}
+		if finished {
+			return statuses, nil
+		}
+		if time.Now().After(deadline) {
+			log.Warn().Msgf("Image pulling not finished in %d seconds", opt.Get().Preheat.Timeout)
+			return statuses, nil
+		}
+		select {
+		case s := <-ch:
+			log.Info().Msgf("Terminal signal is %s", s)
+			return statuses, fmt.Errorf("preheat interrupted")
+		case <-time.After(time.Duration(opt.Get().Global.PodPollInterval) * time.Second):
+		}
+	}
+}
+
+func getNodeStatuses(names []string) ([]NodeStatus, bool, error) {
+	statuses := make([]NodeStatus, 0)
+	finished := true
+	for _, name := range names {
+		ds, err := cluster.Ins().GetDaemonSet(name, opt.Get().Global.Namespace)
+		if err != nil {
+			return nil, false, err
+		}
+		pods, err := cluster.Ins().GetPodsByLabel(map[string]string{util.KtTarget: name}, opt.Get().Global.Namespace)
+		if err != nil {
+			return nil, false, err
+		}
+		if ds.Status.ObservedGeneration < ds.Generation || len(pods.Items) < int(ds.Status.DesiredNumberScheduled) {
+			// daemon set controller haven't created pods for all nodes yet
+			finished = false
+		}
+		for _, pod := range pods.Items {
+			status := GetPullStatus(&pod)
+			finished = finished && status.Done
+			statuses = append(statuses, status)
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Node < statuses[j].Node
+	})
+	return statuses, finished, nil
+}
+
+// GetPullStatus check image pulling status of preheat pod
+func GetPullStatus(pod *coreV1.Pod) NodeStatus {
+	status := NodeStatus{Node: pod.Spec.NodeName, Message: "pulling"}
+	if status.Node == "" {
+		status.Node = fmt.Sprintf("(pod %s not scheduled)", pod.Name)
+		status.Message = "waiting for scheduling"
+	}
+	if len(pod.Spec.Containers) > 0 {
+		status.Image = pod.Spec.Containers[0].Image
+	}
+	for _, c := range pod.Status.ContainerStatuses {
+		if c.Name != util.DefaultContainer {
+			continue
+		}
+		if c.ImageID != "" {
+			status.Done = true
+			status.Pulled = true
+			status.Message = "pulled"
+		} else if c.State.Waiting != nil && util.Contains(pullFailureReasons, c.State.Waiting.Reason) {
+			status.Done = true
+			status.Message = fmt.Sprintf("failed, %s: %s", c.State.Waiting.Reason, c.State.Waiting.Message)
+		}
+	}
+	return status
+}
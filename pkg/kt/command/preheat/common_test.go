@@ -0,0 +1,82 @@
+package preheat
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster/fake"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	appV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestGetPullStatus(t *testing.T) {
+	status := GetPullStatus(preheatPod("kt-preheat-a", "node-1", coreV1.ContainerStatus{
+		Name:    util.DefaultContainer,
+		ImageID: "docker-pullable://kt-connect-shadow@sha256:abc",
+	}))
+	require.Equal(t, NodeStatus{Node: "node-1", Image: "shadow", Done: true, Pulled: true, Message: "pulled"}, status)
+
+	status = GetPullStatus(preheatPod("kt-preheat-a", "node-2", coreV1.ContainerStatus{
+		Name:  util.DefaultContainer,
+		State: coreV1.ContainerState{Waiting: &coreV1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "not found"}},
+	}))
+	require.True(t, status.Done)
+	require.False(t, status.Pulled)
+	require.Equal(t, "failed, ImagePullBackOff: not found", status.Message)
+
+	status = GetPullStatus(preheatPod("kt-preheat-a", "node-3", coreV1.ContainerStatus{
+		Name:  util.DefaultContainer,
+		State: coreV1.ContainerState{Waiting: &coreV1.ContainerStateWaiting{Reason: "ContainerCreating"}},
+	}))
+	require.False(t, status.Done)
+
+	status = GetPullStatus(preheatPod("kt-preheat-a", ""))
+	require.False(t, status.Done)
+	require.Equal(t, "waiting for scheduling", status.Message)
+}
+
+func TestGetNodeStatuses(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	ds := &appV1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "kt-preheat-a", Namespace: "default", Generation: 1},
+		Status:     appV1.DaemonSetStatus{ObservedGeneration: 1, DesiredNumberScheduled: 2},
+	}
+	pulled := preheatPod("kt-preheat-a", "node-b", coreV1.ContainerStatus{Name: util.DefaultContainer, ImageID: "sha256:abc"})
+	cluster.SetIns(fake.NewKubernetes(ds, pulled))
+	statuses, finished, err := getNodeStatuses([]string{"kt-preheat-a"})
+	require.Nil(t, err)
+	require.False(t, finished, "should wait for pods of all nodes")
+	require.Len(t, statuses, 1)
+
+	pulling := preheatPod("kt-preheat-a", "node-a", coreV1.ContainerStatus{Name: util.DefaultContainer})
+	pulling.Name = "kt-preheat-a-2"
+	cluster.SetIns(fake.NewKubernetes(ds, pulled, pulling))
+	statuses, finished, err = getNodeStatuses([]string{"kt-preheat-a"})
+	require.Nil(t, err)
+	require.False(t, finished)
+	require.Equal(t, "node-a", statuses[0].Node, "statuses should be sorted by node")
+
+	pulling.Status.ContainerStatuses[0].ImageID = "sha256:abc"
+	cluster.SetIns(fake.NewKubernetes(ds, pulled, pulling))
+	_, finished, err = getNodeStatuses([]string{"kt-preheat-a"})
+	require.Nil(t, err)
+	require.True(t, finished)
+}
+
+func preheatPod(dsName, node string, containerStatuses ...coreV1.ContainerStatus) *coreV1.Pod {
+	return &coreV1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      dsName + "-1",
+			Namespace: "default",
+			Labels:    map[string]string{util.KtTarget: dsName},
+		},
+		Spec: coreV1.PodSpec{
+			NodeName:   node,
+			Containers: []coreV1.Container{{Name: util.DefaultContainer, Image: "shadow"}},
+		},
+		Status: coreV1.PodStatus{ContainerStatuses: containerStatuses},
+	}
+}
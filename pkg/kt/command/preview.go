@@ -6,6 +6,7 @@ import (
 	"github.com/alibaba/kt-connect/pkg/kt/command/general"
 	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
 	"github.com/alibaba/kt-connect/pkg/kt/command/preview"
+	"github.com/alibaba/kt-connect/pkg/kt/transmission"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -21,9 +22,14 @@ func NewPreviewCommand() *cobra.Command {
 			if len(args) == 0 {
 				return fmt.Errorf("a service name must be specified")
 			} else if len(args) > 1 {
-				return fmt.Errorf("too many service names are spcified (%s), should be one", strings.Join(args, ",") )
+				return fmt.Errorf("too many service names are spcified (%s), should be one", strings.Join(args, ","))
 			}
-			return general.Prepare()
+			if opt.Get().Preview.Sync != "" {
+				if _, _, err := transmission.ParseSyncPaths(opt.Get().Preview.Sync); err != nil {
+					return err
+				}
+			}
+			return prepareAndReap(cmd)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return Preview(args[0])
@@ -52,9 +58,9 @@ func Preview(serviceName string) error {
 	if err = preview.Expose(serviceName); err != nil {
 		return err
 	}
-	log.Info().Msg("---------------------------------------------------------------")
-	log.Info().Msgf(" Now you can access your local service in cluster by name '%s'", serviceName)
-	log.Info().Msg("---------------------------------------------------------------")
+	util.StatusLog().Msg("---------------------------------------------------------------")
+	util.StatusLog().Msgf(" Now you can access your local service in cluster by name '%s'", serviceName)
+	util.StatusLog().Msg("---------------------------------------------------------------")
 
 	// watch background process, clean the workspace and exit if background process occur exception
 	s := <-ch
@@ -12,11 +12,11 @@ import (
 
 // Expose create a new service in cluster
 func Expose(serviceName string) error {
-	version := strings.ToLower(util.RandomString(5))
+	version := util.RandomSuffix()
 	shadowPodName := fmt.Sprintf("%s-kt-%s", serviceName, version)
 	labels := map[string]string{
-		util.KtRole:    util.RolePreviewShadow,
-		util.KtTarget:  util.RandomString(20),
+		util.KtRole:   util.RolePreviewShadow,
+		util.KtTarget: util.RandomString(20),
 	}
 	annotations := map[string]string{
 		util.KtConfig: fmt.Sprintf("service=%s", serviceName),
@@ -30,7 +30,7 @@ func exposeLocalService(serviceName, shadowPodName string, labels, annotations m
 
 	envs := make(map[string]string)
 	_, podName, privateKeyPath, err := cluster.Ins().GetOrCreateShadow(shadowPodName, labels, annotations, envs,
-		opt.Get().Preview.Expose, map[int]string{})
+		opt.Get().Preview.Expose, map[int]string{}, nil)
 	if err != nil {
 		return err
 	}
@@ -61,7 +61,12 @@ func exposeLocalService(serviceName, shadowPodName string, labels, annotations m
 	}
 	opt.Store.Service = serviceName
 
-	if _, err = transmission.ForwardPodToLocal(opt.Get().Preview.Expose, podName, privateKeyPath); err != nil {
+	localSshPort, err := transmission.ForwardPodToLocal(opt.Get().Preview.Expose, podName, privateKeyPath)
+	if err != nil {
+		return err
+	}
+	if err = transmission.StartSync(opt.Get().Preview.Sync, opt.Get().Preview.SyncIgnore, localSshPort,
+		privateKeyPath); err != nil {
 		return err
 	}
 
@@ -18,16 +18,16 @@ import (
 // NewRecoverCommand return new recover command
 func NewRecoverCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:  "recover",
+		Use:   "recover",
 		Short: "Restore traffic of specified kubernetes service changed by exchange or mesh",
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
 				return fmt.Errorf("name of service to recover is required")
 			} else if len(args) > 1 {
-				return fmt.Errorf("too many service names are spcified (%s), should be one", strings.Join(args, ",") )
+				return fmt.Errorf("too many service names are spcified (%s), should be one", strings.Join(args, ","))
 			}
 			opt.Get().Global.UseLocalTime = true
-			return general.Prepare()
+			return prepareAndReap(cmd)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return Recover(args[0])
@@ -134,4 +134,4 @@ func checkAndMarkUnlock(serviceName string, svc *coreV1.Service) bool {
 		return true
 	}
 	return false
-}
\ No newline at end of file
+}
@@ -2,6 +2,7 @@ package recover
 
 import (
 	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
 	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
@@ -12,7 +13,6 @@ import (
 	"time"
 )
 
-
 func UnlockServiceOnly(svc *coreV1.Service) error {
 	_, err := cluster.Ins().UpdateService(svc)
 	return err
@@ -34,10 +34,17 @@ func HandleExchangedByScaleService(svc *coreV1.Service, deployment *appV1.Deploy
 		_ = cluster.Ins().RemoveDeployment(deployment.Name, deployment.Namespace)
 	}
 	replica, _ := strconv.ParseInt(config["replicas"], 10, 32)
+	if opt.Get().Recover.RestoreReplicas > 0 {
+		replica = int64(opt.Get().Recover.RestoreReplicas)
+	}
 	app := config["app"]
 	if replica > 0 && app != "" {
 		originReplica := int32(replica)
+		log.Info().Msgf("Scaling deployment %s to %d", app, originReplica)
 		return cluster.Ins().ScaleTo(app, svc.Namespace, &originReplica)
+	} else if app != "" {
+		log.Warn().Msgf("Deployment %s had 0 replicas before exchange, use '--restoreReplicas' to specify "+
+			"the replicas to recover", app)
 	}
 	return nil
 }
@@ -66,8 +73,8 @@ func HandleMeshedByAutoService(svc *coreV1.Service, deployment *appV1.Deployment
 	if _, err := cluster.Ins().UpdateService(svc); err != nil {
 		return err
 	}
-	log.Info().Msgf("Deleting stuntman service %s", svc.Name + util.StuntmanServiceSuffix)
-	if err := cluster.Ins().RemoveService(svc.Name + util.StuntmanServiceSuffix, svc.Namespace); err != nil {
+	log.Info().Msgf("Deleting stuntman service %s", svc.Name+util.StuntmanServiceSuffix)
+	if err := cluster.Ins().RemoveService(svc.Name+util.StuntmanServiceSuffix, svc.Namespace); err != nil {
 		log.Debug().Err(err).Msgf("Failed to remove service %s", svc.Name)
 	}
 	shadowLabels := map[string]string{
@@ -77,7 +84,7 @@ func HandleMeshedByAutoService(svc *coreV1.Service, deployment *appV1.Deployment
 	shadowSvcNames := make([]string, 0)
 	if apps, err := cluster.Ins().GetDeploymentsByLabel(shadowLabels, svc.Namespace); err == nil {
 		for _, shadowApp := range apps.Items {
-			if strings.HasPrefix(shadowApp.Name, svc.Name + util.MeshPodInfix) {
+			if strings.HasPrefix(shadowApp.Name, svc.Name+util.MeshPodInfix) {
 				log.Info().Msgf("Deleting shadow deployment %s", shadowApp.Name)
 				if err2 := cluster.Ins().RemoveDeployment(shadowApp.Name, shadowApp.Namespace); err2 != nil {
 					log.Debug().Err(err2).Msgf("Failed to remove deployment %s", shadowApp.Name)
@@ -88,7 +95,7 @@ func HandleMeshedByAutoService(svc *coreV1.Service, deployment *appV1.Deployment
 	}
 	if pods, err := cluster.Ins().GetPodsByLabel(shadowLabels, svc.Namespace); err == nil {
 		for _, shadowPod := range pods.Items {
-			if strings.HasPrefix(shadowPod.Name, svc.Name + util.MeshPodInfix) && shadowPod.DeletionTimestamp == nil {
+			if strings.HasPrefix(shadowPod.Name, svc.Name+util.MeshPodInfix) && shadowPod.DeletionTimestamp == nil {
 				log.Info().Msgf("Deleting shadow pod %s", shadowPod.Name)
 				if err2 := cluster.Ins().RemovePod(shadowPod.Name, shadowPod.Namespace); err2 != nil {
 					log.Debug().Err(err2).Msgf("Failed to remove pod %s", pod.Name)
@@ -120,4 +127,3 @@ func HandleServiceSelectorAndRemotePods(svc *coreV1.Service, deployment *appV1.D
 	}
 	return nil
 }
-
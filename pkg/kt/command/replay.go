@@ -0,0 +1,107 @@
+package command
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/command/exchange"
+	"github.com/alibaba/kt-connect/pkg/kt/command/general"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/command/replay"
+	"github.com/alibaba/kt-connect/pkg/kt/service/sshchannel"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NewReplayCommand re-send requests recorded by exchange
+func NewReplayCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Re-send requests recorded via 'exchange --record' to local service",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("record file to replay is required")
+			} else if len(args) > 1 {
+				return fmt.Errorf("too many record files are specified (%v), should be one", args)
+			}
+			if opt.Get().Replay.Exchange != "" {
+				if opt.Get().Replay.Target != "" {
+					return fmt.Errorf("'--target' cannot be used together with '--exchange'")
+				}
+				return prepareAndReap(cmd)
+			} else if opt.Get().Replay.Expose != "" {
+				return fmt.Errorf("'--expose' should be used together with '--exchange'")
+			}
+			general.SetupLogger()
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return Replay(args[0])
+		},
+		Example: "ktctl replay <record-file> [command options]",
+	}
+
+	cmd.SetUsageTemplate(general.UsageTemplate(false))
+	opt.SetOptions(cmd, cmd.Flags(), opt.Get().Replay, opt.ReplayFlags())
+	return cmd
+}
+
+// Replay send each recorded request again, in the order they were recorded
+func Replay(file string) error {
+	records, err := sshchannel.ReadRecords(file)
+	if err != nil {
+		return fmt.Errorf("failed to read record file %s: %s", file, err)
+	}
+	// address to send requests recorded on each local port, empty for sending to target or local port directly
+	addresses := make(map[int]string)
+	if opt.Get().Replay.Exchange != "" {
+		if _, err = general.SetupProcess(util.ComponentExchange); err != nil {
+			return err
+		}
+		expose := opt.Get().Replay.Expose
+		if expose == "" {
+			expose = getRecordedPorts(records)
+		}
+		log.Info().Msgf("Exchanging service %s to replay requests through its shadow pod", opt.Get().Replay.Exchange)
+		if addresses, err = exchange.ForReplay(opt.Get().Replay.Exchange, expose); err != nil {
+			return err
+		}
+	}
+	failed := 0
+	for _, record := range records {
+		target := opt.Get().Replay.Target
+		if address, exists := addresses[record.Port]; exists {
+			target = address
+		}
+		status, err2 := replay.Send(record, target)
+		if err2 != nil {
+			log.Warn().Err(err2).Msgf("> %s %s failed", record.Method, record.Uri)
+			failed++
+		} else {
+			log.Info().Msgf("> %s %s - %d", record.Method, record.Uri, status)
+		}
+	}
+	log.Info().Msgf("Replayed %d requests, %d failed", len(records), failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d requests failed to replay", failed, len(records))
+	}
+	return nil
+}
+
+// getRecordedPorts local ports of recorded requests, in the format of '--expose'
+func getRecordedPorts(records []sshchannel.RecordedRequest) string {
+	var ports []int
+	for _, record := range records {
+		if !util.Contains(ports, record.Port) {
+			ports = append(ports, record.Port)
+		}
+	}
+	sort.Ints(ports)
+	var exposes []string
+	for _, port := range ports {
+		exposes = append(exposes, strconv.Itoa(port))
+	}
+	return strings.Join(exposes, ",")
+}
@@ -0,0 +1,52 @@
+package replay
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/common"
+	"github.com/alibaba/kt-connect/pkg/kt/service/sshchannel"
+	"github.com/rs/zerolog/log"
+	"io"
+	"net/http"
+	"time"
+)
+
+// replayTimeout max time to wait for response of each replayed request
+const replayTimeout = 30 * time.Second
+
+var client = &http.Client{
+	Timeout: replayTimeout,
+	// redirect response should be shown as it is
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// Send re-send recorded request to target address, or local port where it was recorded, return status of response
+func Send(record sshchannel.RecordedRequest, target string) (int, error) {
+	if target == "" {
+		target = fmt.Sprintf("%s:%d", common.Localhost, record.Port)
+	}
+	req, err := http.NewRequest(record.Method, "http://"+target+record.Uri, bytes.NewReader(record.Body))
+	if err != nil {
+		return 0, err
+	}
+	for key, values := range record.Header {
+		if len(values) == 1 && values[0] == sshchannel.RedactedValue {
+			// value of redacted header is unknown
+			continue
+		}
+		req.Header[key] = values
+	}
+	req.Host = record.Host
+	if record.BodyTruncated {
+		log.Warn().Msgf("Body of %s %s is truncated when recording, replaying with partial body", record.Method, record.Uri)
+	}
+	rsp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer rsp.Body.Close()
+	_, _ = io.Copy(io.Discard, rsp.Body)
+	return rsp.StatusCode, nil
+}
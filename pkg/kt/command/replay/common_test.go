@@ -0,0 +1,40 @@
+package replay
+
+import (
+	"github.com/alibaba/kt-connect/pkg/kt/service/sshchannel"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSend(t *testing.T) {
+	var received *http.Request
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		received, body = r, string(data)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	status, err := Send(sshchannel.RecordedRequest{
+		Method: "POST",
+		Uri:    "/api?id=1",
+		Host:   "app.default",
+		Header: http.Header{
+			"Authorization": []string{sshchannel.RedactedValue},
+			"X-Request-Id":  []string{"r1"},
+		},
+		Body: []byte("abc"),
+	}, strings.TrimPrefix(server.URL, "http://"))
+	require.Nil(t, err)
+	require.Equal(t, http.StatusCreated, status)
+	require.Equal(t, "/api?id=1", received.RequestURI)
+	require.Equal(t, "app.default", received.Host)
+	require.Equal(t, "r1", received.Header.Get("X-Request-Id"))
+	require.Empty(t, received.Header.Get("Authorization"), "redacted header should not be sent")
+	require.Equal(t, "abc", body)
+}
@@ -0,0 +1,168 @@
+package replay
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/service/sshchannel"
+	"github.com/rs/zerolog/log"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// loadReportInterval interval of printing statistics of generated load
+const loadReportInterval = 10 * time.Second
+
+// loadMaxInflight max requests waiting for response, further requests are skipped to avoid piling up
+const loadMaxInflight = 200
+
+// loadSampleSize max latencies kept for calculating percentiles, so that memory won't grow with load duration
+const loadSampleSize = 10000
+
+// MaxLoadRps max requests per second could be generated
+const MaxLoadRps = 10000
+
+// defaultLoadRequest request to send when no template specified
+var defaultLoadRequest = sshchannel.RecordedRequest{Method: http.MethodGet, Uri: "/"}
+
+// LoadGenerator send requests to target at fixed rate, and report latency and error statistics
+type LoadGenerator struct {
+	target    string
+	templates []sshchannel.RecordedRequest
+	stop      chan struct{}
+	done      sync.WaitGroup
+	inflight  int32
+	interval  *loadStats
+	total     *loadStats
+	sync.Mutex
+}
+
+// loadStats statistics of requests in a period, latencies are randomly sampled once exceeding loadSampleSize
+type loadStats struct {
+	latencies []time.Duration
+	received  int
+	max       time.Duration
+	errors    int
+	skipped   int
+}
+
+// StartLoad generate requests of specified rate to target, templates are sent in turn, use a simple GET if empty,
+// rate should be verified to be within (0, MaxLoadRps]
+func StartLoad(target string, rps int, templates []sshchannel.RecordedRequest) *LoadGenerator {
+	if len(templates) == 0 {
+		templates = []sshchannel.RecordedRequest{defaultLoadRequest}
+	}
+	g := &LoadGenerator{
+		target:    target,
+		templates: templates,
+		stop:      make(chan struct{}),
+		interval:  &loadStats{},
+		total:     &loadStats{},
+	}
+	g.done.Add(1)
+	go g.run(time.Second / time.Duration(rps))
+	log.Info().Msgf("Generating %d requests per second to %s", rps, target)
+	return g
+}
+
+// Stop stop generating requests, wait for in-flight ones and print the summary
+func (g *LoadGenerator) Stop() {
+	close(g.stop)
+	g.done.Wait()
+	g.Lock()
+	defer g.Unlock()
+	log.Info().Msgf("Load finished, %s", g.total.summary())
+}
+
+func (g *LoadGenerator) run(interval time.Duration) {
+	defer g.done.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	reporter := time.NewTicker(loadReportInterval)
+	defer reporter.Stop()
+	for i := 0; ; i++ {
+		select {
+		case <-g.stop:
+			return
+		case <-reporter.C:
+			g.report()
+		case <-ticker.C:
+			if atomic.LoadInt32(&g.inflight) >= loadMaxInflight {
+				g.record(0, false, true)
+				continue
+			}
+			atomic.AddInt32(&g.inflight, 1)
+			g.done.Add(1)
+			go g.send(g.templates[i%len(g.templates)])
+		}
+	}
+}
+
+func (g *LoadGenerator) send(template sshchannel.RecordedRequest) {
+	defer g.done.Done()
+	defer atomic.AddInt32(&g.inflight, -1)
+	start := time.Now()
+	status, err := Send(template, g.target)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Load request %s %s failed", template.Method, template.Uri)
+	}
+	g.record(time.Since(start), err != nil || status >= http.StatusInternalServerError, false)
+}
+
+func (g *LoadGenerator) record(latency time.Duration, failed, skipped bool) {
+	g.Lock()
+	defer g.Unlock()
+	for _, s := range []*loadStats{g.interval, g.total} {
+		if skipped {
+			s.skipped++
+			continue
+		}
+		s.addLatency(latency)
+		if failed {
+			s.errors++
+		}
+	}
+}
+
+func (g *LoadGenerator) report() {
+	g.Lock()
+	defer g.Unlock()
+	log.Info().Msgf("Load in last %s: %s", loadReportInterval, g.interval.summary())
+	g.interval = &loadStats{}
+}
+
+// addLatency reservoir sampling, each latency has the same chance to be kept
+func (s *loadStats) addLatency(latency time.Duration) {
+	s.received++
+	if latency > s.max {
+		s.max = latency
+	}
+	if len(s.latencies) < loadSampleSize {
+		s.latencies = append(s.latencies, latency)
+	} else if i := rand.Intn(s.received); i < loadSampleSize {
+		s.latencies[i] = latency
+	}
+}
+
+// summary text of request count, error count and latency percentiles
+func (s *loadStats) summary() string {
+	if len(s.latencies) == 0 {
+		return fmt.Sprintf("no response received, %d skipped", s.skipped)
+	}
+	sorted := make([]time.Duration, len(s.latencies))
+	copy(sorted, s.latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return fmt.Sprintf("%d sent, %d errors, %d skipped, latency p50 %s, p90 %s, p99 %s, max %s",
+		s.received, s.errors, s.skipped, percentile(sorted, 50), percentile(sorted, 90),
+		percentile(sorted, 99), s.max.Round(time.Microsecond))
+}
+
+func percentile(sorted []time.Duration, p int) time.Duration {
+	index := (len(sorted)*p+99)/100 - 1
+	if index < 0 {
+		index = 0
+	}
+	return sorted[index].Round(time.Microsecond)
+}
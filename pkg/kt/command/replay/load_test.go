@@ -0,0 +1,58 @@
+package replay
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/service/sshchannel"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStartLoad(t *testing.T) {
+	var received int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	g := StartLoad(strings.TrimPrefix(server.URL, "http://"), 50, []sshchannel.RecordedRequest{
+		{Method: "GET", Uri: "/ok"},
+		{Method: "GET", Uri: "/fail"},
+	})
+	time.Sleep(300 * time.Millisecond)
+	g.Stop()
+	count := atomic.LoadInt32(&received)
+	require.True(t, count > 5, "requests should be sent at specified rate")
+	require.Equal(t, int(count), g.total.received, "all in-flight requests should be finished")
+	require.Equal(t, int(count)/2, g.total.errors, "5xx response should be counted as error")
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, count, atomic.LoadInt32(&received), "no request should be sent after stopped")
+}
+
+func Test_percentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+	require.Equal(t, 50*time.Millisecond, percentile(sorted, 50))
+	require.Equal(t, 99*time.Millisecond, percentile(sorted, 99))
+	require.Equal(t, time.Millisecond, percentile(sorted[:1], 90))
+}
+
+func TestLoadStats_addLatency(t *testing.T) {
+	s := &loadStats{}
+	for i := 1; i <= loadSampleSize*3; i++ {
+		s.addLatency(time.Duration(i) * time.Microsecond)
+	}
+	require.Equal(t, loadSampleSize*3, s.received)
+	require.Len(t, s.latencies, loadSampleSize, "sampled latencies should not exceed sample size")
+	require.Equal(t, time.Duration(loadSampleSize*3)*time.Microsecond, s.max)
+	require.Contains(t, s.summary(), fmt.Sprintf("%d sent", loadSampleSize*3))
+}
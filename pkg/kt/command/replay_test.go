@@ -0,0 +1,44 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/sshchannel"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "record.jsonl")
+	var lines []string
+	for _, uri := range []string{"/a", "/b"} {
+		data, err := json.Marshal(sshchannel.RecordedRequest{Port: 8080, Method: http.MethodGet, Uri: uri})
+		require.Nil(t, err)
+		lines = append(lines, string(data))
+	}
+	require.Nil(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0600))
+	defer func() { opt.Get().Replay.Target = "" }()
+
+	opt.Get().Replay.Target = strings.TrimPrefix(server.URL, "http://")
+	require.Nil(t, Replay(path))
+	opt.Get().Replay.Target = fmt.Sprintf("127.0.0.1:%d", util.GetRandomTcpPort())
+	err := Replay(path)
+	require.NotNil(t, err, "failed requests should be reported")
+	require.Contains(t, err.Error(), "2 of 2")
+}
+
+func Test_getRecordedPorts(t *testing.T) {
+	require.Equal(t, "80,8080", getRecordedPorts([]sshchannel.RecordedRequest{{Port: 8080}, {Port: 80}, {Port: 8080}}))
+	require.Equal(t, "", getRecordedPorts(nil))
+}
@@ -0,0 +1,95 @@
+package command
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/command/general"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	coreV1 "k8s.io/api/core/v1"
+	"runtime"
+	"strings"
+)
+
+// NewVersionCommand show version of ktctl and shadow image
+func NewVersionCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Show version of ktctl and shadow image",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("too many options specified (%s)", strings.Join(args, ","))
+			}
+			if opt.Get().Version.Remote {
+				return general.Prepare(cmd)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return Version()
+		},
+		Example: "ktctl version [command options]",
+	}
+
+	cmd.SetUsageTemplate(general.UsageTemplate(false))
+	opt.SetOptions(cmd, cmd.Flags(), opt.Get().Version, opt.VersionFlags())
+	return cmd
+}
+
+// Version print versions of client and shadow image
+func Version() error {
+	log.Info().Msgf("Client version: %s", opt.Store.Version)
+	log.Info().Msgf("Go version: %s %s/%s", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	log.Info().Msgf("Shadow image: %s", opt.Get().Global.Image)
+	if !opt.Get().Version.Remote {
+		return nil
+	}
+
+	pods, err := cluster.Ins().GetPodsByLabel(map[string]string{util.ControlBy: util.KubernetesToolkit},
+		opt.Get().Global.Namespace)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("---- Kt pods in namespace %s ----", opt.Get().Global.Namespace)
+	if len(pods.Items) == 0 {
+		log.Info().Msgf("No kt pod found")
+		return nil
+	}
+	for _, pod := range pods.Items {
+		image := getKtImage(&pod)
+		createdBy := pod.Annotations[util.KtVersion]
+		if createdBy == "" {
+			createdBy = "unknown"
+		}
+		log.Info().Msgf("> %s (%s) image %s, created by ktctl %s", pod.Name, pod.Labels[util.KtRole], image, createdBy)
+		if tag := getImageTag(image); tag != "" && tag != "v"+opt.Store.Version {
+			log.Warn().Msgf("Image version of pod %s is different from client, they may not be compatible", pod.Name)
+		}
+	}
+	return nil
+}
+
+// getKtImage image of the container created by kt
+func getKtImage(pod *coreV1.Pod) string {
+	for _, c := range pod.Spec.EphemeralContainers {
+		if c.Name == util.KtExchangeContainer {
+			return c.Image
+		}
+	}
+	if len(pod.Spec.Containers) > 0 {
+		return pod.Spec.Containers[0].Image
+	}
+	return ""
+}
+
+// getImageTag tag of image, empty if image has no tag
+func getImageTag(image string) string {
+	pos := strings.LastIndex(image, ":")
+	if pos < 0 || strings.Contains(image[pos+1:], "/") {
+		// colon of registry port
+		return ""
+	}
+	return image[pos+1:]
+}
@@ -0,0 +1,13 @@
+package command
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_getImageTag(t *testing.T) {
+	require.Equal(t, "v0.3.6", getImageTag("registry.cn-hangzhou.aliyuncs.com/rdc-incubator/kt-connect-shadow:v0.3.6"))
+	require.Equal(t, "v0.3.6", getImageTag("localhost:5000/kt-connect-shadow:v0.3.6"))
+	require.Equal(t, "", getImageTag("localhost:5000/kt-connect-shadow"))
+	require.Equal(t, "", getImageTag("kt-connect-shadow"))
+}
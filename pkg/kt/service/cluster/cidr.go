@@ -6,37 +6,50 @@ import (
 	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	"regexp"
 	"strconv"
 	"strings"
 )
 
+// kubernetesServiceName name of service for accessing api server from inside cluster
+const kubernetesServiceName = "kubernetes"
+
 // ClusterCidr get cluster CIDR
 func (k *Kubernetes) ClusterCidr(namespace string) ([]string, []string) {
-	ips := getServiceIps(k.Clientset, namespace)
-	log.Debug().Msgf("Found %d IPs", len(ips))
-	svcCidr := calculateMinimalIpRange(ips)
-	log.Debug().Msgf("Service ips are: %v", ips)
+	svcCidr := getServiceCidr(k.Clientset, namespace)
 	log.Debug().Msgf("Service CIDR are: %v", svcCidr)
 
 	var podCidr []string
 	if !opt.Get().Connect.DisablePodIp {
-		ips = getPodIps(k.Clientset, namespace)
-		log.Debug().Msgf("Found %d IPs", len(ips))
-		podCidr = calculateMinimalIpRange(ips)
-		log.Debug().Msgf("Pod ips are: %v", ips)
+		podCidr = getPodCidr(k.Clientset, namespace)
 		log.Debug().Msgf("Pod CIDR are: %v", podCidr)
 	}
 
 	apiServerIp := util.ExtractHostIp(opt.Store.RestConfig.Host)
 	log.Debug().Msgf("Using cluster IP %s", apiServerIp)
 
-	if opt.Store.Ipv6Cluster == true  && strings.Contains(apiServerIp, ":"){
+	if opt.Store.Ipv6Cluster == true && strings.Contains(apiServerIp, ":") {
 		apiServerIp = strings.Split(strings.Split(opt.Store.RestConfig.Host, "[")[1], "]")[0]
 	}
 
-	cidr := mergeIpRange(svcCidr, podCidr, apiServerIp)
+	var cidr []string
+	if opt.Get().Connect.AutoCidr {
+		// detected ranges are already minimal, do not merge them into larger aligned ranges
+		cidr = excludeApiServerIp(append(svcCidr, podCidr...), apiServerIp)
+	} else {
+		cidr = mergeIpRange(svcCidr, podCidr, apiServerIp)
+	}
+	// api server could also be reached via cluster ip or endpoints of kubernetes service, keep them out of tunnel,
+	// otherwise requests of ktctl itself may loop back through the tunnel
+	for _, ip := range k.getKubernetesServiceIps() {
+		if ip != apiServerIp {
+			log.Debug().Msgf("Excluding api server address %s", ip)
+			cidr = excludeApiServerIp(cidr, ip)
+		}
+	}
 	log.Debug().Msgf("Cluster CIDR are: %v", cidr)
 
 	excludeIps := strings.Split(opt.Get().Connect.ExcludeIps, ",")
@@ -83,7 +96,7 @@ func (k *Kubernetes) ClusterCidr(namespace string) ([]string, []string) {
 	}
 
 	// remove ipv6 api address
-	if opt.Store.Ipv6Cluster == true  && strings.Contains(apiServerIp, ":") {
+	if opt.Store.Ipv6Cluster == true && strings.Contains(apiServerIp, ":") {
 		s := strings.Split(apiServerIp, ":")
 		ipmask := fmt.Sprintf("%s:%s::/32", s[0], s[1])
 		cidr = util.ArrayDelete(cidr, ipmask)
@@ -91,8 +104,39 @@ func (k *Kubernetes) ClusterCidr(namespace string) ([]string, []string) {
 	return cidr, excludeCidr
 }
 
+// getKubernetesServiceIps get ipv4 cluster ip and endpoint addresses of the 'kubernetes' service in default namespace
+func (k *Kubernetes) getKubernetesServiceIps() []string {
+	var ips []string
+	if svc, err := k.Clientset.CoreV1().Services(util.DefaultNamespace).
+		Get(context.TODO(), kubernetesServiceName, metav1.GetOptions{}); err == nil {
+		ips = append(ips, svc.Spec.ClusterIP)
+	} else {
+		log.Debug().Err(err).Msgf("Failed to get api server service")
+	}
+	if endpoints, err := k.Clientset.CoreV1().Endpoints(util.DefaultNamespace).
+		Get(context.TODO(), kubernetesServiceName, metav1.GetOptions{}); err == nil {
+		for _, subset := range endpoints.Subsets {
+			for _, address := range subset.Addresses {
+				ips = append(ips, address.IP)
+			}
+		}
+	} else {
+		log.Debug().Err(err).Msgf("Failed to get api server endpoints")
+	}
+	validIps := make([]string, 0)
+	for _, ip := range ips {
+		if util.IsValidIp(ip) && !util.Contains(validIps, ip) {
+			validIps = append(validIps, ip)
+		}
+	}
+	return validIps
+}
+
 func mergeIpRange(svcCidr []string, podCidr []string, apiServerIp string) []string {
-	cidr := calculateMinimalIpRange(append(svcCidr, podCidr...))
+	return excludeApiServerIp(calculateMinimalIpRange(append(svcCidr, podCidr...)), apiServerIp)
+}
+
+func excludeApiServerIp(cidr []string, apiServerIp string) []string {
 	mergedCidr := make([]string, 0)
 	for _, r := range cidr {
 		if isPartOfRange(r, apiServerIp+"/32") {
@@ -162,6 +206,95 @@ func isPartOfRange(ipRange string, subIpRange string) bool {
 	return true
 }
 
+func getServiceCidr(k kubernetes.Interface, namespace string) []string {
+	if opt.Get().Connect.AutoCidr {
+		if cidr := getServiceCidrFromApiServer(k, namespace); len(cidr) > 0 {
+			return cidr
+		}
+		log.Debug().Msgf("Unable to detect service CIDR from api server, fallback to calculate by service IPs")
+	}
+	ips := getServiceIps(k, namespace)
+	log.Debug().Msgf("Found %d IPs", len(ips))
+	log.Debug().Msgf("Service ips are: %v", ips)
+	return calculateMinimalIpRange(ips)
+}
+
+func getPodCidr(k kubernetes.Interface, namespace string) []string {
+	if opt.Get().Connect.AutoCidr {
+		if cidr := getPodCidrFromNodes(k); len(cidr) > 0 {
+			return cidr
+		}
+		log.Debug().Msgf("Unable to detect pod CIDR from nodes, fallback to calculate by pod IPs")
+	}
+	ips := getPodIps(k, namespace)
+	log.Debug().Msgf("Found %d IPs", len(ips))
+	log.Debug().Msgf("Pod ips are: %v", ips)
+	return calculateMinimalIpRange(ips)
+}
+
+// getPodCidrFromNodes read pod CIDR assigned to each node, which requires permission to list nodes
+func getPodCidrFromNodes(k kubernetes.Interface) []string {
+	nodeList, err := k.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{
+		TimeoutSeconds: &apiTimeout,
+	})
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to fetch nodes")
+		return []string{}
+	}
+
+	cidr := make([]string, 0)
+	for _, node := range nodeList.Items {
+		nodeCidr := node.Spec.PodCIDRs
+		if len(nodeCidr) == 0 && node.Spec.PodCIDR != "" {
+			nodeCidr = []string{node.Spec.PodCIDR}
+		}
+		for _, r := range nodeCidr {
+			if strings.Contains(r, ":") != opt.Store.Ipv6Cluster {
+				continue
+			}
+			if !util.Contains(cidr, r) {
+				cidr = append(cidr, r)
+			}
+		}
+	}
+	return cidr
+}
+
+// getServiceCidrFromApiServer try create a service with out-of-range cluster ip in dry-run mode,
+// the api server will reject it with valid service ip range in error message
+func getServiceCidrFromApiServer(k kubernetes.Interface, namespace string) []string {
+	invalidIp := "1.1.1.1"
+	if opt.Store.Ipv6Cluster {
+		invalidIp = "2001:db8::1"
+	}
+	_, err := k.CoreV1().Services(namespace).Create(context.TODO(), &coreV1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      util.RandomName("kt-cidr-probe-"),
+			Namespace: namespace,
+		},
+		Spec: coreV1.ServiceSpec{
+			ClusterIP: invalidIp,
+			Ports:     []coreV1.ServicePort{{Port: 80}},
+		},
+	}, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+	if err == nil {
+		return []string{}
+	}
+	if cidr := parseServiceCidr(err.Error()); cidr != "" {
+		return []string{cidr}
+	}
+	log.Debug().Err(err).Msgf("No service CIDR found in api server response")
+	return []string{}
+}
+
+func parseServiceCidr(errMsg string) string {
+	cidrExp := regexp.MustCompile("range of valid IPs is ([0-9a-fA-F.:]+/[0-9]+)")
+	if cidrExp.MatchString(errMsg) {
+		return cidrExp.FindStringSubmatch(errMsg)[1]
+	}
+	return ""
+}
+
 func getPodIps(k kubernetes.Interface, namespace string) []string {
 	podList, err := k.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
 		Limit:          1000,
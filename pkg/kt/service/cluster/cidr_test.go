@@ -63,6 +63,35 @@ func TestKubernetes_ClusterCidr(t *testing.T) {
 	}
 }
 
+func TestKubernetes_ClusterCidrExcludeApiServer(t *testing.T) {
+	k := &Kubernetes{
+		Clientset: testclient.NewSimpleClientset(
+			buildPod("default", "pod1", "image", "172.168.0.7", map[string]string{"label": "value"}),
+			buildService("default", "svc1", "10.96.0.10"),
+			buildService("default", "kubernetes", "10.96.0.1"),
+			&coreV1.Endpoints{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kubernetes"},
+				Subsets:    []coreV1.EndpointSubset{{Addresses: []coreV1.EndpointAddress{{IP: "172.168.0.100"}}}},
+			},
+		),
+	}
+	opt.Get().Connect.IncludeIps = ""
+	opt.Store.RestConfig = &rest.Config{Host: "https://1.2.3.4:6443"}
+	cidr, _ := k.ClusterCidr("default")
+	isRouted := func(ip string) bool {
+		for _, r := range cidr {
+			if isPartOfRange(r, ip+"/32") {
+				return true
+			}
+		}
+		return false
+	}
+	require.False(t, isRouted("10.96.0.1"), "cluster ip of api server should not go through tunnel")
+	require.False(t, isRouted("172.168.0.100"), "endpoint of api server should not go through tunnel")
+	require.True(t, isRouted("10.96.0.10"))
+	require.True(t, isRouted("172.168.0.7"))
+}
+
 func Test_mergeIpRange(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -225,6 +254,36 @@ func Test_binToIpRange(t *testing.T) {
 	require.Equal(t, "100.25.254.0/23", binToIpRange(ipBin, false))
 }
 
+func Test_parseServiceCidr(t *testing.T) {
+	require.Equal(t, "10.96.0.0/12", parseServiceCidr("Service \"kt-cidr-probe-abcde\" is invalid: spec.clusterIPs: "+
+		"Invalid value: []string{\"1.1.1.1\"}: failed to allocate IP 1.1.1.1: the provided IP (1.1.1.1) is not in the "+
+		"valid range. The range of valid IPs is 10.96.0.0/12"))
+	require.Equal(t, "fd00:10:96::/112", parseServiceCidr("provided IP is not in the valid range. "+
+		"The range of valid IPs is fd00:10:96::/112"))
+	require.Equal(t, "", parseServiceCidr("services is forbidden: User \"dev\" cannot create resource \"services\""))
+}
+
+func Test_getPodCidrFromNodes(t *testing.T) {
+	opt.Store.Ipv6Cluster = false
+	k := testclient.NewSimpleClientset(
+		buildNode("node1", "10.244.0.0/24", nil),
+		buildNode("node2", "", []string{"10.244.1.0/24", "fd00:10:244:1::/64"}),
+		buildNode("node3", "10.244.0.0/24", nil),
+	)
+	require.Equal(t, []string{"10.244.0.0/24", "10.244.1.0/24"}, getPodCidrFromNodes(k))
+	require.Equal(t, []string{}, getPodCidrFromNodes(testclient.NewSimpleClientset()))
+}
+
+func buildNode(name, podCidr string, podCidrs []string) *coreV1.Node {
+	return &coreV1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: coreV1.NodeSpec{
+			PodCIDR:  podCidr,
+			PodCIDRs: podCidrs,
+		},
+	}
+}
+
 func buildService(namespace, name, clusterIP string) *coreV1.Service {
 	return &coreV1.Service{
 		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
@@ -15,6 +15,11 @@ func (k *Kubernetes) GetConfigMap(name, namespace string) (*coreV1.ConfigMap, er
 	return k.Clientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), name, metav1.GetOptions{})
 }
 
+// GetSecret get secret
+func (k *Kubernetes) GetSecret(name, namespace string) (*coreV1.Secret, error) {
+	return k.Clientset.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
 // GetConfigMapsByLabel get deployments by label
 func (k *Kubernetes) GetConfigMapsByLabel(labels map[string]string, namespace string) (pods *coreV1.ConfigMapList, err error) {
 	return k.Clientset.CoreV1().ConfigMaps(namespace).List(context.TODO(), metav1.ListOptions{
@@ -23,6 +28,11 @@ func (k *Kubernetes) GetConfigMapsByLabel(labels map[string]string, namespace st
 	})
 }
 
+// UpdateConfigMap ...
+func (k *Kubernetes) UpdateConfigMap(configMap *coreV1.ConfigMap) (*coreV1.ConfigMap, error) {
+	return k.Clientset.CoreV1().ConfigMaps(configMap.Namespace).Update(context.TODO(), configMap, metav1.UpdateOptions{})
+}
+
 // RemoveConfigMap remove ConfigMap instance
 func (k *Kubernetes) RemoveConfigMap(name, namespace string) (err error) {
 	deletePolicy := metav1.DeletePropagationBackground
@@ -49,10 +59,14 @@ func (k *Kubernetes) UpdateConfigMapHeartBeat(name, namespace string) {
 
 func (k *Kubernetes) createConfigMapWithSshKey(labels map[string]string, sshcm string, namespace string,
 	generator *util.SSHGenerator) (configMap *coreV1.ConfigMap, err error) {
-	SetupHeartBeat(sshcm, namespace, k.UpdateConfigMapHeartBeat)
+	return k.Clientset.CoreV1().ConfigMaps(namespace).Create(context.TODO(),
+		newSshConfigMap(labels, sshcm, namespace, string(generator.PublicKey), string(generator.PrivateKey)),
+		metav1.CreateOptions{})
+}
 
+func newSshConfigMap(labels map[string]string, sshcm, namespace, publicKey, privateKey string) *coreV1.ConfigMap {
 	labels = util.MergeMap(labels, map[string]string{util.ControlBy: util.KubernetesToolkit})
-	return k.Clientset.CoreV1().ConfigMaps(namespace).Create(context.TODO(), &coreV1.ConfigMap{
+	return &coreV1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        sshcm,
 			Namespace:   namespace,
@@ -60,8 +74,8 @@ func (k *Kubernetes) createConfigMapWithSshKey(labels map[string]string, sshcm s
 			Annotations: map[string]string{util.KtLastHeartBeat: util.GetTimestamp()},
 		},
 		Data: map[string]string{
-			util.SshAuthKey:        string(generator.PublicKey),
-			util.SshAuthPrivateKey: string(generator.PrivateKey),
+			util.SshAuthKey:        publicKey,
+			util.SshAuthPrivateKey: privateKey,
 		},
-	}, metav1.CreateOptions{})
+	}
 }
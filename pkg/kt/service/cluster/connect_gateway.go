@@ -0,0 +1,62 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+)
+
+// CreateConnectGateway create shared shadow deployment for clients to connect through, it has no heart beat,
+// thus never cleaned as expired, clients are authorized by short-lived keys added to the gateway pod
+func (k *Kubernetes) CreateConnectGateway(name string, replicas int32, envs map[string]string) error {
+	podMeta, sshKeyMeta, err := k.newShadowMeta(name, map[string]string{util.KtRole: util.RoleConnectGateway},
+		map[string]string{}, envs, "", map[int]string{}, nil)
+	if err != nil {
+		return err
+	}
+	podMeta.Replicas = replicas
+	// gateway is shared by clients, it should never be reaped as expired shadow
+	delete(podMeta.Meta.Annotations, util.KtExpireAt)
+	// key in config map is only used for starting sshd, its private part is dropped immediately
+	generator, err := util.Generate(sshKeyMeta.PrivateKeyPath)
+	if err != nil {
+		return err
+	}
+	_ = os.Remove(generator.PrivateKeyPath)
+
+	configMap := newSshConfigMap(podMeta.Meta.Labels, name, podMeta.Meta.Namespace, string(generator.PublicKey), "")
+	delete(configMap.Annotations, util.KtLastHeartBeat)
+	if _, err = k.Clientset.CoreV1().ConfigMaps(configMap.Namespace).
+		Create(context.TODO(), configMap, metav1.CreateOptions{}); err != nil {
+		return withNamespaceTerminatingHint(err, configMap.Namespace)
+	}
+	deployment := createDeployment(podMeta)
+	delete(deployment.Annotations, util.KtLastHeartBeat)
+	k.appendSshVolume(&deployment.Spec.Template.Spec, name)
+	if _, err = k.Clientset.AppsV1().Deployments(deployment.Namespace).
+		Create(context.TODO(), deployment, metav1.CreateOptions{}); err != nil {
+		_ = k.RemoveConfigMap(name, configMap.Namespace)
+		return err
+	}
+	log.Info().Msgf("Connect gateway %s created in namespace %s", name, deployment.Namespace)
+	return nil
+}
+
+// GetConnectGatewayPod get a running pod of connect gateway
+func (k *Kubernetes) GetConnectGatewayPod(namespace string) (string, string, error) {
+	pods, err := k.GetPodsByLabel(map[string]string{util.KtRole: util.RoleConnectGateway}, namespace)
+	if err != nil {
+		return "", "", err
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == coreV1.PodRunning && pod.DeletionTimestamp == nil {
+			return pod.Name, pod.Status.PodIP, nil
+		}
+	}
+	return "", "", fmt.Errorf("no running connect gateway in namespace %s, it can be installed via 'ktctl gateway install'",
+		namespace)
+}
@@ -0,0 +1,55 @@
+package cluster
+
+import (
+	"context"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func TestKubernetes_CreateConnectGateway(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	opt.Get().Global.ShadowTtl = 60
+	defer func() { opt.Get().Global.ShadowTtl = 0 }()
+	k := &Kubernetes{Clientset: testclient.NewSimpleClientset()}
+	require.Nil(t, k.CreateConnectGateway(util.ConnectGatewayName, 2, map[string]string{}))
+
+	app, err := k.GetDeployment(util.ConnectGatewayName, "default")
+	require.Nil(t, err)
+	require.Equal(t, int32(2), *app.Spec.Replicas)
+	require.Equal(t, util.RoleConnectGateway, app.Spec.Template.Labels[util.KtRole])
+	require.NotContains(t, app.Annotations, util.KtLastHeartBeat, "gateway should never be cleaned as expired")
+	require.NotContains(t, app.Annotations, util.KtExpireAt, "gateway should never be reaped by shadow ttl")
+	require.NotContains(t, app.Spec.Template.Annotations, util.KtExpireAt)
+	configMap, err := k.GetConfigMap(util.ConnectGatewayName, "default")
+	require.Nil(t, err)
+	require.NotEmpty(t, configMap.Data[util.SshAuthKey])
+	require.Empty(t, configMap.Data[util.SshAuthPrivateKey], "private key should not be saved")
+	require.NotContains(t, configMap.Annotations, util.KtLastHeartBeat)
+	require.NotNil(t, k.CreateConnectGateway(util.ConnectGatewayName, 1, map[string]string{}))
+}
+
+func TestKubernetes_GetConnectGatewayPod(t *testing.T) {
+	k := &Kubernetes{Clientset: testclient.NewSimpleClientset()}
+	_, _, err := k.GetConnectGatewayPod("default")
+	require.NotNil(t, err, "should fail when no gateway running")
+
+	labels := map[string]string{util.KtRole: util.RoleConnectGateway}
+	for _, pod := range []*coreV1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "gateway-pending", Namespace: "default", Labels: labels},
+			Status: coreV1.PodStatus{Phase: coreV1.PodPending}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "gateway-running", Namespace: "default", Labels: labels},
+			Status: coreV1.PodStatus{Phase: coreV1.PodRunning, PodIP: "10.0.0.8"}},
+	} {
+		_, err = k.Clientset.CoreV1().Pods("default").Create(context.TODO(), pod, metav1.CreateOptions{})
+		require.Nil(t, err)
+	}
+	name, ip, err := k.GetConnectGatewayPod("default")
+	require.Nil(t, err)
+	require.Equal(t, "gateway-running", name)
+	require.Equal(t, "10.0.0.8", ip)
+}
@@ -0,0 +1,74 @@
+package cluster
+
+import (
+	"context"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	appV1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labelApi "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// CreatePreheatDaemonSet create daemon set to pull specified image on every node of specified os
+func (k *Kubernetes) CreatePreheatDaemonSet(name, image, os string) (*appV1.DaemonSet, error) {
+	metaAndSpec := &PodMetaAndSpec{
+		Meta: &ResourceMeta{
+			Name:      name,
+			Namespace: opt.Get().Global.Namespace,
+			Labels: map[string]string{
+				util.KtRole:   util.RolePreheat,
+				util.KtTarget: name,
+			},
+			Annotations: map[string]string{},
+		},
+		Image: image,
+		Os:    os,
+	}
+	ds := createDaemonSet(metaAndSpec)
+	ds.Spec.Template.Spec.NodeSelector = util.MapPut(ds.Spec.Template.Spec.NodeSelector, util.LabelOs, os)
+	created, err := k.Clientset.AppsV1().DaemonSets(ds.Namespace).Create(context.TODO(), ds, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	SetupHeartBeat(name, ds.Namespace, k.UpdateDaemonSetHeartBeat)
+	return created, nil
+}
+
+// GetDaemonSetsByLabel get daemon sets by label
+func (k *Kubernetes) GetDaemonSetsByLabel(labels map[string]string, namespace string) (*appV1.DaemonSetList, error) {
+	return k.Clientset.AppsV1().DaemonSets(namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labelApi.SelectorFromSet(labels).String(),
+	})
+}
+
+// UpdateDaemonSetHeartBeat refresh heart beat annotation of daemon set, so that it won't be removed by clean
+func (k *Kubernetes) UpdateDaemonSetHeartBeat(name, namespace string) {
+	key := "daemonset_" + name
+	if _, err := k.Clientset.AppsV1().DaemonSets(namespace).
+		Patch(context.TODO(), name, types.JSONPatchType, []byte(resourceHeartbeatPatch()), metav1.PatchOptions{}); err != nil {
+		if healthy, exists := LastHeartBeatStatus.Get(key); healthy || !exists {
+			log.Warn().Err(err).Msgf("Failed to update heart beat of daemon set %s", name)
+		} else {
+			log.Debug().Err(err).Msgf("Daemon set %s heart beat interrupted", name)
+		}
+		LastHeartBeatStatus.Set(key, false)
+	} else {
+		log.Debug().Msgf("Heartbeat daemon set %s ticked at %s", name, util.FormattedTime())
+		LastHeartBeatStatus.Set(key, true)
+	}
+}
+
+// GetDaemonSet ...
+func (k *Kubernetes) GetDaemonSet(name string, namespace string) (*appV1.DaemonSet, error) {
+	return k.Clientset.AppsV1().DaemonSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+// RemoveDaemonSet remove daemon set and its pods
+func (k *Kubernetes) RemoveDaemonSet(name, namespace string) error {
+	deletePolicy := metav1.DeletePropagationBackground
+	return k.Clientset.AppsV1().DaemonSets(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{
+		PropagationPolicy: &deletePolicy,
+	})
+}
@@ -0,0 +1,24 @@
+package cluster
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func TestKubernetes_CreatePreheatDaemonSet(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	k := &Kubernetes{Clientset: testclient.NewSimpleClientset()}
+	ds, err := k.CreatePreheatDaemonSet("kt-preheat-linux-abcde", "busybox", "linux")
+	require.Nil(t, err)
+	require.Equal(t, util.KubernetesToolkit, ds.Labels[util.ControlBy])
+	require.NotEmpty(t, ds.Annotations[util.KtLastHeartBeat], "daemon set should have heart beat for clean")
+	require.Equal(t, util.KubernetesToolkit, ds.Spec.Template.Labels[util.ControlBy], "pods should be marked as kt resources")
+	require.NotContains(t, ds.Spec.Selector.MatchLabels, util.ControlBy)
+
+	list, err := k.GetDaemonSetsByLabel(map[string]string{util.ControlBy: util.KubernetesToolkit}, "default")
+	require.Nil(t, err)
+	require.Len(t, list.Items, 1)
+}
@@ -24,6 +24,11 @@ func (k *Kubernetes) GetDeploymentsByLabel(labels map[string]string, namespace s
 	})
 }
 
+// GetReplicaSet get replica set
+func (k *Kubernetes) GetReplicaSet(name string, namespace string) (*appV1.ReplicaSet, error) {
+	return k.Clientset.AppsV1().ReplicaSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
 // GetAllDeploymentInNamespace get all deployment in specified namespace
 func (k *Kubernetes) GetAllDeploymentInNamespace(namespace string) (*appV1.DeploymentList, error) {
 	return k.Clientset.AppsV1().Deployments(namespace).List(context.TODO(), metav1.ListOptions{
@@ -3,15 +3,24 @@ package cluster
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
 	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
-// AddEphemeralContainer add ephemeral container to specified pod
-func (k *Kubernetes) AddEphemeralContainer(containerName string, name string,
+// AddEphemeralContainer add ephemeral container to specified pod, it shares process namespace of
+// target container if specified
+func (k *Kubernetes) AddEphemeralContainer(containerName, name, targetContainer string,
 	envs map[string]string) (string, error) {
 	pod, err := k.GetPod(name, opt.Get().Global.Namespace)
 	if err != nil {
@@ -19,6 +28,11 @@ func (k *Kubernetes) AddEphemeralContainer(containerName string, name string,
 	}
 
 	privateKeyPath := util.PrivateKeyPath(name)
+	if existing := getEphemeralContainer(pod, containerName); existing != nil {
+		// left by a previous attempt whose response was lost, reuse it instead of adding again
+		log.Info().Msgf("Ephemeral container %s already exists in pod %s, reusing it", containerName, name)
+		return privateKeyPath, restorePrivateKey(existing, privateKeyPath)
+	}
 	generator, err := util.Generate(privateKeyPath)
 	if err != nil {
 		return "", err
@@ -28,6 +42,7 @@ func (k *Kubernetes) AddEphemeralContainer(containerName string, name string,
 	if err2 != nil {
 		return "", fmt.Errorf("found shadow pod but no configMap. Please delete the pod %s", pod.Name)
 	}
+	SetupHeartBeat(name, opt.Get().Global.Namespace, k.UpdateConfigMapHeartBeat)
 
 	err = util.WritePrivateKey(generator.PrivateKeyPath, []byte(configMap.Data[util.SshAuthPrivateKey]))
 
@@ -49,11 +64,78 @@ func (k *Kubernetes) AddEphemeralContainer(containerName string, name string,
 	for k, v := range envs {
 		ec.Env = append(ec.Env, coreV1.EnvVar{Name: k, Value: v})
 	}
+	if targetContainer != "" {
+		ec.TargetContainerName = targetContainer
+	}
+
+	for i := 0; i < 3; i++ {
+		pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, ec)
+		_, err = k.Clientset.CoreV1().Pods(pod.Namespace).UpdateEphemeralContainers(context.TODO(), pod.Name, pod, metav1.UpdateOptions{})
+		if err == nil {
+			return privateKeyPath, nil
+		} else if isTimeout(err) {
+			// the patch could still take effect after api server timed out
+			log.Debug().Err(err).Msgf("Timeout adding ephemeral container to pod %s, checking whether it's added", name)
+		} else if isEphemeralContainerUnsupported(err) {
+			return "", fmt.Errorf("ephemeral container is not supported by the cluster (feature gate disabled or "+
+				"'ephemeralcontainers' subresource not permitted), please use '--mode scale' instead: %s", err)
+		} else if !k8sErrors.IsConflict(err) {
+			return "", err
+		} else {
+			// pod changed since fetched, refresh and try again
+			log.Debug().Err(err).Msgf("Pod %s changed, retrying to add ephemeral container", name)
+			time.Sleep(1 * time.Second)
+		}
+		latestPod, err2 := k.GetPod(name, opt.Get().Global.Namespace)
+		if err2 != nil {
+			return "", err2
+		}
+		if getEphemeralContainer(latestPod, containerName) != nil {
+			return privateKeyPath, nil
+		}
+		pod = latestPod
+	}
+	return "", fmt.Errorf("failed to add ephemeral container to pod %s: %s", name, err)
+}
+
+// getEphemeralContainer find ephemeral container with specified name in pod, return nil if not exists
+func getEphemeralContainer(pod *coreV1.Pod, containerName string) *coreV1.EphemeralContainer {
+	for i := range pod.Spec.EphemeralContainers {
+		if pod.Spec.EphemeralContainers[i].Name == containerName {
+			return &pod.Spec.EphemeralContainers[i]
+		}
+	}
+	return nil
+}
 
-	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, ec)
+// restorePrivateKey write private key carried by env of existing ephemeral container to local key file
+func restorePrivateKey(ec *coreV1.EphemeralContainer, privateKeyPath string) error {
+	for _, env := range ec.Env {
+		if env.Name != util.SshAuthPrivateKey {
+			continue
+		}
+		privateKey, err := base64.StdEncoding.DecodeString(env.Value)
+		if err != nil {
+			return fmt.Errorf("invalid private key in ephemeral container %s: %s", ec.Name, err)
+		}
+		_ = os.Remove(privateKeyPath)
+		return util.WritePrivateKey(privateKeyPath, privateKey)
+	}
+	return fmt.Errorf("ephemeral container %s is not created by kt, no private key found", ec.Name)
+}
 
-	pod, err = k.Clientset.CoreV1().Pods(pod.Namespace).UpdateEphemeralContainers(context.TODO(), pod.Name, pod, metav1.UpdateOptions{})
-	return privateKeyPath, err
+// isTimeout check whether the error is caused by request timeout, the request may or may not take effect
+func isTimeout(err error) bool {
+	if k8sErrors.IsTimeout(err) || k8sErrors.IsServerTimeout(err) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isEphemeralContainerUnsupported check whether the error indicates ephemeral containers subresource is unavailable
+func isEphemeralContainerUnsupported(err error) bool {
+	return k8sErrors.IsNotFound(err) || k8sErrors.IsForbidden(err) || k8sErrors.IsMethodNotSupported(err)
 }
 
 // RemoveEphemeralContainer remove ephemeral container from specified pod
@@ -61,3 +143,38 @@ func (k *Kubernetes) RemoveEphemeralContainer(_, podName string, namespace strin
 	// TODO: implement container removal
 	return k.RemovePod(podName, namespace)
 }
+
+// IsEphemeralContainerSupported check whether cluster version and api allow adding ephemeral container,
+// return the reason if not supported
+func (k *Kubernetes) IsEphemeralContainerSupported() (bool, string) {
+	ver, err := k.Clientset.Discovery().ServerVersion()
+	if err != nil {
+		return false, fmt.Sprintf("failed to get cluster version: %s", err)
+	}
+	if !isVersionAbove(ver.Major, ver.Minor, 1, 23) {
+		return false, fmt.Sprintf("cluster version %s.%s is lower than v1.23", ver.Major, ver.Minor)
+	}
+	resources, err := k.Clientset.Discovery().ServerResourcesForGroupVersion("v1")
+	if err != nil {
+		return false, fmt.Sprintf("failed to get cluster api resources: %s", err)
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == "pods/ephemeralcontainers" {
+			return true, fmt.Sprintf("cluster version %s.%s supports ephemeral container", ver.Major, ver.Minor)
+		}
+	}
+	return false, "pods/ephemeralcontainers api is not enabled"
+}
+
+// isVersionAbove check whether version is not lower than expected one, minor version could have suffix like '23+'
+func isVersionAbove(major, minor string, expectMajor, expectMinor int) bool {
+	majorNum, err := strconv.Atoi(strings.TrimRight(major, "+"))
+	if err != nil {
+		return false
+	}
+	minorNum, err := strconv.Atoi(strings.TrimRight(minor, "+"))
+	if err != nil {
+		return false
+	}
+	return majorNum > expectMajor || (majorNum == expectMajor && minorNum >= expectMinor)
+}
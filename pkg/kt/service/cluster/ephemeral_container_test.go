@@ -0,0 +1,81 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	k8sTesting "k8s.io/client-go/testing"
+	"testing"
+)
+
+func Test_isEphemeralContainerUnsupported(t *testing.T) {
+	resource := schema.GroupResource{Resource: "pods/ephemeralcontainers"}
+	require.True(t, isEphemeralContainerUnsupported(k8sErrors.NewNotFound(resource, "demo")))
+	require.True(t, isEphemeralContainerUnsupported(k8sErrors.NewForbidden(resource, "demo", fmt.Errorf("denied"))))
+	require.True(t, isEphemeralContainerUnsupported(k8sErrors.NewMethodNotSupported(resource, "patch")))
+	require.False(t, isEphemeralContainerUnsupported(k8sErrors.NewConflict(resource, "demo", fmt.Errorf("changed"))))
+	require.False(t, isEphemeralContainerUnsupported(fmt.Errorf("io timeout")))
+}
+
+func TestKubernetes_IsEphemeralContainerSupported(t *testing.T) {
+	clientset := testclient.NewSimpleClientset()
+	k := &Kubernetes{Clientset: clientset}
+	discovery := clientset.Discovery().(*fakediscovery.FakeDiscovery)
+
+	discovery.FakedServerVersion = &version.Info{Major: "1", Minor: "22"}
+	supported, _ := k.IsEphemeralContainerSupported()
+	require.False(t, supported, "v1.22 should not be supported")
+
+	discovery.FakedServerVersion = &version.Info{Major: "1", Minor: "24+"}
+	discovery.Resources = []*metav1.APIResourceList{{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "pods"}}}}
+	supported, _ = k.IsEphemeralContainerSupported()
+	require.False(t, supported, "should not be supported without ephemeralcontainers api")
+
+	discovery.Resources[0].APIResources = append(discovery.Resources[0].APIResources,
+		metav1.APIResource{Name: "pods/ephemeralcontainers"})
+	supported, _ = k.IsEphemeralContainerSupported()
+	require.True(t, supported)
+}
+
+func TestKubernetes_AddEphemeralContainer(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	clientset := testclient.NewSimpleClientset(&coreV1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "default"},
+	})
+	k := &Kubernetes{Clientset: clientset}
+	patched := 0
+	// the patch takes effect, but api server responses with timeout
+	clientset.PrependReactor("update", "pods", func(action k8sTesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "ephemeralcontainers" {
+			return false, nil, nil
+		}
+		patched++
+		pod := action.(k8sTesting.UpdateAction).GetObject()
+		if err := clientset.Tracker().Update(coreV1.SchemeGroupVersion.WithResource("pods"), pod, "default"); err != nil {
+			return true, nil, err
+		}
+		return true, nil, k8sErrors.NewTimeoutError("request did not complete within requested timeout", 1)
+	})
+
+	privateKey, err := k.AddEphemeralContainer(util.KtExchangeContainer, "app-1", "", map[string]string{})
+	require.Nil(t, err, "timeout followed by container present should succeed")
+	require.Equal(t, util.PrivateKeyPath("app-1"), privateKey)
+	require.Equal(t, 1, patched)
+
+	_, err = k.AddEphemeralContainer(util.KtExchangeContainer, "app-1", "", map[string]string{})
+	require.Nil(t, err, "existing container should be reused")
+	require.Equal(t, 1, patched, "existing container should not be added again")
+	pod, err := clientset.CoreV1().Pods("default").Get(context.TODO(), "app-1", metav1.GetOptions{})
+	require.Nil(t, err)
+	require.Len(t, pod.Spec.EphemeralContainers, 1)
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
+	appV1 "k8s.io/api/apps/v1"
 	coreV1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -20,7 +22,7 @@ func (k *Kubernetes) CreateRouterPod(name string, labels, annotations map[string
 		Namespace:   opt.Get().Global.Namespace,
 		Labels:      labels,
 		Annotations: annotations,
-	}, opt.Get().Mesh.RouterImage, map[string]string{}, targetPorts, true}
+	}, opt.Get().Mesh.RouterImage, map[string]string{}, targetPorts, true, "", nil, 0, nil, nil}
 	pod := createPod(metaAndSpec)
 	if _, err := k.Clientset.CoreV1().Pods(metaAndSpec.Meta.Namespace).
 		Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
@@ -38,7 +40,7 @@ func (k *Kubernetes) CreateRectifierPod(name string) (*coreV1.Pod, error) {
 		Namespace:   opt.Get().Global.Namespace,
 		Labels:      map[string]string{},
 		Annotations: map[string]string{},
-	}, opt.Get().Global.Image, map[string]string{}, map[string]int{}, true}
+	}, opt.Get().Global.Image, map[string]string{}, map[string]int{}, true, "", nil, 0, nil, nil}
 	pod := createPod(metaAndSpec)
 	pod.Spec.Containers[0].Command = []string{"tail", "-f", "/dev/null"}
 	if _, err := k.Clientset.CoreV1().Pods(metaAndSpec.Meta.Namespace).
@@ -48,3 +50,28 @@ func (k *Kubernetes) CreateRectifierPod(name string) (*coreV1.Pod, error) {
 	log.Debug().Msgf("Rectify pod %s created", name)
 	return k.WaitPodReady(name, opt.Get().Global.Namespace, opt.Get().Global.PodCreationTimeout)
 }
+
+// CreateOriginCopyPod create pod with same spec of deployment, but not selected by any service
+func (k *Kubernetes) CreateOriginCopyPod(name string, app *appV1.Deployment) (*coreV1.Pod, error) {
+	pod := &coreV1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: opt.Get().Global.Namespace,
+			Labels: map[string]string{
+				util.ControlBy: util.KubernetesToolkit,
+				util.KtRole:    util.RoleOriginCopy,
+			},
+			Annotations: map[string]string{
+				util.KtRefCount:      "1",
+				util.KtLastHeartBeat: util.GetTimestamp(),
+			},
+		},
+		Spec: *app.Spec.Template.Spec.DeepCopy(),
+	}
+	if _, err := k.Clientset.CoreV1().Pods(pod.Namespace).Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		return nil, err
+	}
+	SetupHeartBeat(name, pod.Namespace, k.UpdatePodHeartBeat)
+	log.Info().Msgf("Origin copy pod %s created", name)
+	return k.WaitPodReady(name, opt.Get().Global.Namespace, opt.Get().Global.PodCreationTimeout)
+}
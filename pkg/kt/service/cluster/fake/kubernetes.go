@@ -0,0 +1,292 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	appV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"sync"
+)
+
+// Kubernetes in-memory implementation of cluster.KubernetesInterface, backed by fake clientset of client-go.
+// Operations which need a real api server (pod exec, watch, istio resources) are simulated
+type Kubernetes struct {
+	*cluster.Kubernetes
+	// PodIp ip address assigned to shadow pods
+	PodIp string
+	// IstioInstalled value returned by IsIstioInstalled
+	IstioInstalled bool
+	// GatewayApiInstalled value returned by IsGatewayApiInstalled
+	GatewayApiInstalled bool
+	// ExecHandler handle commands executed in pod, return empty output if not specified
+	ExecHandler     func(containerName, podName, namespace string, cmd ...string) (string, string, error)
+	virtualServices []cluster.VirtualService
+	httpRoutes      []cluster.HTTPRoute
+	lock            sync.Mutex
+}
+
+var _ cluster.KubernetesInterface = &Kubernetes{}
+
+// NewKubernetes create fake kubernetes with specified objects pre-loaded
+func NewKubernetes(objects ...runtime.Object) *Kubernetes {
+	return &Kubernetes{
+		Kubernetes: &cluster.Kubernetes{
+			Clientset: testclient.NewSimpleClientset(objects...),
+		},
+		PodIp: "10.0.0.1",
+	}
+}
+
+// GetOrCreateShadow create shadow resources in fake clientset, shadow pod is running as soon as created
+func (k *Kubernetes) GetOrCreateShadow(name string, labels, annotations, envs map[string]string, exposePorts string,
+	portNameDict map[int]string, target *coreV1.PodSpec) (string, string, string, error) {
+	objects, err := k.RenderShadow(name, labels, annotations, envs, exposePorts, portNameDict, target)
+	if err != nil {
+		return "", "", "", err
+	}
+	opt.Store.Shadow = util.Append(opt.Store.Shadow, name)
+	podName := name
+	for _, obj := range objects {
+		switch o := obj.(type) {
+		case *coreV1.ConfigMap:
+			_, err = k.Clientset.CoreV1().ConfigMaps(o.Namespace).Create(context.TODO(), o, metav1.CreateOptions{})
+		case *coreV1.Pod:
+			err = k.createRunningPod(o)
+		case *appV1.Deployment:
+			_, err = k.Clientset.AppsV1().Deployments(o.Namespace).Create(context.TODO(), o, metav1.CreateOptions{})
+			if err == nil {
+				// no controller in fake clientset, create the pod of deployment directly
+				podName = util.RandomName(name + "-")
+				err = k.createRunningPod(&coreV1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        podName,
+						Namespace:   o.Namespace,
+						Labels:      o.Spec.Template.Labels,
+						Annotations: o.Spec.Template.Annotations,
+					},
+					Spec: o.Spec.Template.Spec,
+				})
+			}
+		}
+		if err != nil && !k8sErrors.IsAlreadyExists(err) {
+			return "", "", "", err
+		}
+	}
+	return k.PodIp, podName, util.PrivateKeyPath(name), nil
+}
+
+// WaitPodReady return the pod immediately, pods in fake clientset never change status by themselves
+func (k *Kubernetes) WaitPodReady(name, namespace string, _ int) (*coreV1.Pod, error) {
+	pod, err := k.GetPod(name, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if pod.Status.Phase != coreV1.PodRunning {
+		return nil, fmt.Errorf("pod %s failed to start", name)
+	}
+	return pod, nil
+}
+
+// WaitPodTerminate return the pod immediately, or not found error if it's already removed
+func (k *Kubernetes) WaitPodTerminate(name, namespace string) (*coreV1.Pod, error) {
+	return k.GetPod(name, namespace)
+}
+
+// ScaleTo update replicas of deployment, and remove its pods beyond the replicas, as the controller would do.
+// Only pods controlled by replica sets of the deployment are counted, new pods are never created
+func (k *Kubernetes) ScaleTo(name, namespace string, replicas *int32) error {
+	if err := k.Kubernetes.ScaleTo(name, namespace, replicas); err != nil {
+		return err
+	}
+	app, err := k.GetDeployment(name, namespace)
+	if err != nil || app.Spec.Selector == nil {
+		return err
+	}
+	pods, err := k.GetPodsByLabel(app.Spec.Selector.MatchLabels, namespace)
+	if err != nil {
+		return err
+	}
+	kept := int32(0)
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil || !k.isControlledByDeployment(&pod, app) {
+			continue
+		}
+		if kept < *replicas {
+			kept++
+			continue
+		}
+		if err = k.RemovePod(pod.Name, namespace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (k *Kubernetes) isControlledByDeployment(pod *coreV1.Pod, app *appV1.Deployment) bool {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil || owner.Kind != "ReplicaSet" {
+		return false
+	}
+	rs, err := k.GetReplicaSet(owner.Name, pod.Namespace)
+	if err != nil {
+		return false
+	}
+	rsOwner := metav1.GetControllerOf(rs)
+	return rsOwner != nil && rsOwner.UID == app.UID
+}
+
+// WatchPod not supported by fake clientset, do nothing
+func (k *Kubernetes) WatchPod(_, _ string, _, _, _ func(*coreV1.Pod)) {
+}
+
+// WatchService not supported by fake clientset, do nothing
+func (k *Kubernetes) WatchService(_, _ string, _, _, _ func(*coreV1.Service)) {
+}
+
+// ExecInPod delegate to ExecHandler
+func (k *Kubernetes) ExecInPod(containerName, podName, namespace string, cmd ...string) (string, string, error) {
+	if k.ExecHandler == nil {
+		return "", "", nil
+	}
+	return k.ExecHandler(containerName, podName, namespace, cmd...)
+}
+
+// AddEphemeralContainer append a running ephemeral container to pod, existing one with same name is reused
+func (k *Kubernetes) AddEphemeralContainer(containerName, podName, targetContainer string, envs map[string]string) (string, error) {
+	pod, err := k.GetPod(podName, opt.Get().Global.Namespace)
+	if err != nil {
+		return "", err
+	}
+	for _, ec := range pod.Spec.EphemeralContainers {
+		if ec.Name == containerName {
+			return util.PrivateKeyPath(podName), nil
+		}
+	}
+	ec := coreV1.EphemeralContainer{
+		EphemeralContainerCommon: coreV1.EphemeralContainerCommon{
+			Name:  containerName,
+			Image: fmt.Sprintf("%s:v%s", util.ImageKtNavigator, opt.Store.Version),
+		},
+		TargetContainerName: targetContainer,
+	}
+	for key, val := range envs {
+		ec.Env = append(ec.Env, coreV1.EnvVar{Name: key, Value: val})
+	}
+	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, ec)
+	pod.Status.EphemeralContainerStatuses = append(pod.Status.EphemeralContainerStatuses, coreV1.ContainerStatus{
+		Name:  containerName,
+		State: coreV1.ContainerState{Running: &coreV1.ContainerStateRunning{}},
+	})
+	if _, err = k.UpdatePod(pod); err != nil {
+		return "", err
+	}
+	return util.PrivateKeyPath(podName), nil
+}
+
+// IsIstioInstalled return value of IstioInstalled
+func (k *Kubernetes) IsIstioInstalled() bool {
+	return k.IstioInstalled
+}
+
+// GetAllVirtualServiceInNamespace get virtual services created via fake
+func (k *Kubernetes) GetAllVirtualServiceInNamespace(namespace string) ([]cluster.VirtualService, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	vss := make([]cluster.VirtualService, 0)
+	for _, vs := range k.virtualServices {
+		if vs.Metadata.Namespace == namespace {
+			vss = append(vss, vs)
+		}
+	}
+	return vss, nil
+}
+
+// CreateMirrorVirtualService save mirror virtual service in memory
+func (k *Kubernetes) CreateMirrorVirtualService(name, namespace, host, mirrorHost string, percent int) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	for _, vs := range k.virtualServices {
+		if vs.Metadata.Name == name && vs.Metadata.Namespace == namespace {
+			return k8sErrors.NewAlreadyExists(virtualServiceResource, name)
+		}
+	}
+	vs := k.RenderMirrorVirtualService(name, namespace, host, mirrorHost, percent).(*cluster.VirtualService)
+	k.virtualServices = append(k.virtualServices, *vs)
+	return nil
+}
+
+// RemoveVirtualService remove virtual service from memory
+func (k *Kubernetes) RemoveVirtualService(name, namespace string) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	for i, vs := range k.virtualServices {
+		if vs.Metadata.Name == name && vs.Metadata.Namespace == namespace {
+			k.virtualServices = append(k.virtualServices[:i], k.virtualServices[i+1:]...)
+			return nil
+		}
+	}
+	return k8sErrors.NewNotFound(virtualServiceResource, name)
+}
+
+var virtualServiceResource = schema.GroupResource{Group: "networking.istio.io", Resource: "virtualservices"}
+
+// IsGatewayApiInstalled return value of GatewayApiInstalled
+func (k *Kubernetes) IsGatewayApiInstalled() bool {
+	return k.GatewayApiInstalled
+}
+
+// GetAllHttpRouteInNamespace get http routes created via fake
+func (k *Kubernetes) GetAllHttpRouteInNamespace(namespace string) ([]cluster.HTTPRoute, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	routes := make([]cluster.HTTPRoute, 0)
+	for _, r := range k.httpRoutes {
+		if r.Metadata.Namespace == namespace {
+			routes = append(routes, r)
+		}
+	}
+	return routes, nil
+}
+
+// CreateHttpRoute save http route in memory
+func (k *Kubernetes) CreateHttpRoute(route *cluster.HTTPRoute) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	for _, r := range k.httpRoutes {
+		if r.Metadata.Name == route.Metadata.Name && r.Metadata.Namespace == route.Metadata.Namespace {
+			return k8sErrors.NewAlreadyExists(httpRouteResource, route.Metadata.Name)
+		}
+	}
+	k.httpRoutes = append(k.httpRoutes, *route)
+	return nil
+}
+
+// RemoveHttpRoute remove http route from memory
+func (k *Kubernetes) RemoveHttpRoute(name, namespace string) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	for i, r := range k.httpRoutes {
+		if r.Metadata.Name == name && r.Metadata.Namespace == namespace {
+			k.httpRoutes = append(k.httpRoutes[:i], k.httpRoutes[i+1:]...)
+			return nil
+		}
+	}
+	return k8sErrors.NewNotFound(httpRouteResource, name)
+}
+
+var httpRouteResource = schema.GroupResource{Group: "gateway.networking.k8s.io", Resource: "httproutes"}
+
+func (k *Kubernetes) createRunningPod(pod *coreV1.Pod) error {
+	pod.Status.Phase = coreV1.PodRunning
+	pod.Status.PodIP = k.PodIp
+	_, err := k.Clientset.CoreV1().Pods(pod.Namespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+	return err
+}
@@ -0,0 +1,35 @@
+package fake
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	"testing"
+)
+
+func TestKubernetes_GetOrCreateShadow(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	k := NewKubernetes()
+	podIp, podName, _, err := k.GetOrCreateShadow("svc-kt-exchange-abcde", map[string]string{}, map[string]string{},
+		map[string]string{}, "8080", map[int]string{}, nil)
+	require.Nil(t, err)
+	require.Equal(t, k.PodIp, podIp)
+	require.Equal(t, "svc-kt-exchange-abcde", podName)
+	pod, err := k.WaitPodReady(podName, "default", 10)
+	require.Nil(t, err)
+	require.Equal(t, coreV1.PodRunning, pod.Status.Phase)
+	_, err = k.GetConfigMap(podName, "default")
+	require.Nil(t, err)
+}
+
+func TestKubernetes_VirtualService(t *testing.T) {
+	k := NewKubernetes()
+	require.Nil(t, k.CreateMirrorVirtualService("svc-kt-mirror", "default", "svc", "svc-kt-mirror", 50))
+	require.NotNil(t, k.CreateMirrorVirtualService("svc-kt-mirror", "default", "svc", "svc-kt-mirror", 50))
+	vss, err := k.GetAllVirtualServiceInNamespace("default")
+	require.Nil(t, err)
+	require.Len(t, vss, 1)
+	require.Equal(t, []string{"svc"}, vss[0].Spec.Hosts)
+	require.Nil(t, k.RemoveVirtualService("svc-kt-mirror", "default"))
+	require.NotNil(t, k.RemoveVirtualService("svc-kt-mirror", "default"))
+}
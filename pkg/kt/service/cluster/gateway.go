@@ -0,0 +1,140 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const gatewayApi = "gateway.networking.k8s.io/v1beta1"
+
+// HTTPRoute the fields of gateway api http route used by kt, fields not changed by kt are kept as raw json
+type HTTPRoute struct {
+	ApiVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   HTTPRouteMeta `json:"metadata"`
+	Spec       HTTPRouteSpec `json:"spec"`
+}
+
+// HTTPRouteMeta metadata of http route
+type HTTPRouteMeta struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// HTTPRouteSpec spec of http route
+type HTTPRouteSpec struct {
+	ParentRefs []json.RawMessage `json:"parentRefs,omitempty"`
+	Hostnames  []string          `json:"hostnames,omitempty"`
+	Rules      []HTTPRouteRule   `json:"rules,omitempty"`
+}
+
+// HTTPRouteRule rule of http route
+type HTTPRouteRule struct {
+	Matches     []HTTPRouteMatch  `json:"matches,omitempty"`
+	Filters     []json.RawMessage `json:"filters,omitempty"`
+	BackendRefs []BackendRef      `json:"backendRefs,omitempty"`
+}
+
+// HTTPRouteMatch condition of http route rule
+type HTTPRouteMatch struct {
+	Path        json.RawMessage   `json:"path,omitempty"`
+	Headers     []HTTPHeaderMatch `json:"headers,omitempty"`
+	QueryParams json.RawMessage   `json:"queryParams,omitempty"`
+	Method      string            `json:"method,omitempty"`
+}
+
+// HTTPHeaderMatch header condition of http route
+type HTTPHeaderMatch struct {
+	Type  string `json:"type,omitempty"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// BackendRef backend to send requests to
+type BackendRef struct {
+	Group     *string `json:"group,omitempty"`
+	Kind      *string `json:"kind,omitempty"`
+	Name      string  `json:"name"`
+	Namespace *string `json:"namespace,omitempty"`
+	Port      *int32  `json:"port,omitempty"`
+	Weight    *int32  `json:"weight,omitempty"`
+}
+
+// IsServiceOf check whether the backend is specified service
+func (b BackendRef) IsServiceOf(name string) bool {
+	isService := (b.Group == nil || *b.Group == "") && (b.Kind == nil || *b.Kind == "Service")
+	return isService && b.Namespace == nil && b.Name == name
+}
+
+// IsGatewayApiInstalled check whether gateway api is available
+func (k *Kubernetes) IsGatewayApiInstalled() bool {
+	_, err := k.Clientset.Discovery().ServerResourcesForGroupVersion(gatewayApi)
+	return err == nil
+}
+
+// GetAllHttpRouteInNamespace get all gateway api http routes
+func (k *Kubernetes) GetAllHttpRouteInNamespace(namespace string) ([]HTTPRoute, error) {
+	data, err := k.Clientset.CoreV1().RESTClient().Get().AbsPath(httpRoutePath(namespace)).
+		Do(context.TODO()).Raw()
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Items []HTTPRoute `json:"items"`
+	}
+	if err = json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// CreateHttpRoute create gateway api http route
+func (k *Kubernetes) CreateHttpRoute(route *HTTPRoute) error {
+	route.ApiVersion = gatewayApi
+	route.Kind = "HTTPRoute"
+	route.Metadata.Labels = util.MergeMap(route.Metadata.Labels, map[string]string{util.ControlBy: util.KubernetesToolkit})
+	route.Metadata.Annotations = util.MapPut(route.Metadata.Annotations, util.KtLastHeartBeat, util.GetTimestamp())
+	body, err := json.Marshal(route)
+	if err != nil {
+		return err
+	}
+	if err = k.Clientset.CoreV1().RESTClient().Post().AbsPath(httpRoutePath(route.Metadata.Namespace)).
+		Body(body).Do(context.TODO()).Error(); err != nil {
+		return err
+	}
+	SetupHeartBeat(route.Metadata.Name, route.Metadata.Namespace, k.UpdateHttpRouteHeartBeat)
+	return nil
+}
+
+// UpdateHttpRouteHeartBeat refresh heart beat annotation of http route, so that it won't be removed by clean
+func (k *Kubernetes) UpdateHttpRouteHeartBeat(name, namespace string) {
+	key := "httproute_" + name
+	if err := k.Clientset.CoreV1().RESTClient().Patch(types.JSONPatchType).AbsPath(httpRoutePath(namespace), name).
+		Body([]byte(resourceHeartbeatPatch())).Do(context.TODO()).Error(); err != nil {
+		if healthy, exists := LastHeartBeatStatus.Get(key); healthy || !exists {
+			log.Warn().Err(err).Msgf("Failed to update heart beat of http route %s", name)
+		} else {
+			log.Debug().Err(err).Msgf("Http route %s heart beat interrupted", name)
+		}
+		LastHeartBeatStatus.Set(key, false)
+	} else {
+		log.Debug().Msgf("Heartbeat http route %s ticked at %s", name, util.FormattedTime())
+		LastHeartBeatStatus.Set(key, true)
+	}
+}
+
+// RemoveHttpRoute remove gateway api http route
+func (k *Kubernetes) RemoveHttpRoute(name, namespace string) error {
+	return k.Clientset.CoreV1().RESTClient().Delete().AbsPath(httpRoutePath(namespace), name).
+		Do(context.TODO()).Error()
+}
+
+func httpRoutePath(namespace string) string {
+	return fmt.Sprintf("/apis/%s/namespaces/%s/httproutes", gatewayApi, namespace)
+}
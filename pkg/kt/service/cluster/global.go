@@ -2,12 +2,16 @@ package cluster
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
 	appV1 "k8s.io/api/apps/v1"
 	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/tools/cache"
 	"strconv"
 	"strings"
@@ -37,6 +41,167 @@ func (k *Kubernetes) GetAllNamespaces() (*coreV1.NamespaceList, error) {
 	})
 }
 
+// GetNamespace get namespace
+func (k *Kubernetes) GetNamespace(name string) (*coreV1.Namespace, error) {
+	return k.Clientset.CoreV1().Namespaces().Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+// CreateNamespace create namespace with kt labels
+func (k *Kubernetes) CreateNamespace(name string) (*coreV1.Namespace, error) {
+	return k.Clientset.CoreV1().Namespaces().Create(context.TODO(), &coreV1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      map[string]string{util.ControlBy: util.KubernetesToolkit},
+			Annotations: map[string]string{util.KtUser: util.GetLocalUserName()},
+		},
+	}, metav1.CreateOptions{})
+}
+
+// RemoveNamespace remove namespace
+func (k *Kubernetes) RemoveNamespace(name string) error {
+	return k.Clientset.CoreV1().Namespaces().Delete(context.TODO(), name, metav1.DeleteOptions{})
+}
+
+// emptyNamespaceSkippedResources resources checked by typed client in IsNamespaceEmpty,
+// or generated by kubernetes for other resources, in '<group>/<resource>' format
+var emptyNamespaceSkippedResources = []string{"/pods", "apps/deployments", "/services", "/configmaps", "/secrets",
+	"/serviceaccounts", "/events", "events.k8s.io/events", "/endpoints", "discovery.k8s.io/endpointslices"}
+
+// IsNamespaceEmpty check whether namespace has no resource except those being deleted
+// or automatically created by kubernetes (default service account, root ca config map and token secrets),
+// all namespaced resources found via discovery are checked, error is returned if any of them could not be verified
+func (k *Kubernetes) IsNamespaceEmpty(name string) (bool, error) {
+	isDeleting := func(meta metav1.ObjectMeta) bool {
+		return meta.DeletionTimestamp != nil
+	}
+	pods, err := k.Clientset.CoreV1().Pods(name).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return false, err
+	}
+	for _, item := range pods.Items {
+		if !isDeleting(item.ObjectMeta) {
+			return false, nil
+		}
+	}
+	apps, err := k.Clientset.AppsV1().Deployments(name).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return false, err
+	}
+	for _, item := range apps.Items {
+		if !isDeleting(item.ObjectMeta) {
+			return false, nil
+		}
+	}
+	services, err := k.Clientset.CoreV1().Services(name).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return false, err
+	}
+	for _, item := range services.Items {
+		if !isDeleting(item.ObjectMeta) {
+			return false, nil
+		}
+	}
+	configMaps, err := k.Clientset.CoreV1().ConfigMaps(name).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return false, err
+	}
+	for _, item := range configMaps.Items {
+		if !isDeleting(item.ObjectMeta) && item.Name != "kube-root-ca.crt" {
+			return false, nil
+		}
+	}
+	secrets, err := k.Clientset.CoreV1().Secrets(name).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return false, err
+	}
+	for _, item := range secrets.Items {
+		if item.Type != coreV1.SecretTypeServiceAccountToken {
+			return false, nil
+		}
+	}
+	accounts, err := k.Clientset.CoreV1().ServiceAccounts(name).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return false, err
+	}
+	for _, item := range accounts.Items {
+		if item.Name != "default" {
+			return false, nil
+		}
+	}
+	return k.isNamespaceResourcesEmpty(name)
+}
+
+// isNamespaceResourcesEmpty check other namespaced resources, e.g. custom resources, found via discovery
+func (k *Kubernetes) isNamespaceResourcesEmpty(name string) (bool, error) {
+	_, resourceLists, err := k.Clientset.Discovery().ServerGroupsAndResources()
+	if err != nil {
+		return false, fmt.Errorf("failed to discover resource types: %s", err)
+	}
+	checked := make(map[string]bool)
+	for _, list := range resourceLists {
+		gv, err2 := schema.ParseGroupVersion(list.GroupVersion)
+		if err2 != nil {
+			return false, err2
+		}
+		for _, res := range list.APIResources {
+			key := gv.Group + "/" + res.Name
+			// sub resources contain '/' in name, and same resource is served by multiple versions
+			if !res.Namespaced || strings.Contains(res.Name, "/") || !util.Contains(res.Verbs, "list") ||
+				util.Contains(emptyNamespaceSkippedResources, key) || checked[key] {
+				continue
+			}
+			checked[key] = true
+			exists, err3 := k.hasUndeletedResource(gv, res.Name, name)
+			if err3 != nil {
+				return false, fmt.Errorf("failed to list %s: %s", key, err3)
+			} else if exists {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+func (k *Kubernetes) hasUndeletedResource(gv schema.GroupVersion, resource, namespace string) (bool, error) {
+	apiPath := fmt.Sprintf("/apis/%s/namespaces/%s/%s", gv.String(), namespace, resource)
+	if gv.Group == "" {
+		apiPath = fmt.Sprintf("/api/%s/namespaces/%s/%s", gv.Version, namespace, resource)
+	}
+	data, err := k.Clientset.CoreV1().RESTClient().Get().AbsPath(apiPath).Do(context.TODO()).Raw()
+	if err != nil {
+		return false, err
+	}
+	var list struct {
+		Items []struct {
+			Metadata metav1.ObjectMeta `json:"metadata"`
+		} `json:"items"`
+	}
+	if err = json.Unmarshal(data, &list); err != nil {
+		return false, err
+	}
+	for _, item := range list.Items {
+		if item.Metadata.DeletionTimestamp == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// IsNamespaceTerminated check whether namespace is terminating or already deleted
+func IsNamespaceTerminated(ns *coreV1.Namespace, err error) bool {
+	if err != nil {
+		return k8sErrors.IsNotFound(err) || k8sErrors.HasStatusCause(err, coreV1.NamespaceTerminatingCause)
+	}
+	return ns.Status.Phase == coreV1.NamespaceTerminating || ns.DeletionTimestamp != nil
+}
+
+func withNamespaceTerminatingHint(err error, namespace string) error {
+	if k8sErrors.HasStatusCause(err, coreV1.NamespaceTerminatingCause) {
+		return fmt.Errorf("namespace %s is terminating, no resource can be created in it", namespace)
+	}
+	return err
+}
+
 // GetKtResources fetch all kt pods and deployments
 func (k *Kubernetes) GetKtResources(namespace string) ([]coreV1.Pod, []coreV1.ConfigMap, []appV1.Deployment, []coreV1.Service, error) {
 	pods, err := Ins().GetPodsByLabel(map[string]string{util.ControlBy: util.KubernetesToolkit}, namespace)
@@ -78,7 +243,7 @@ func (k *Kubernetes) watchResource(name, namespace, resourceType string, objType
 		objType,
 		0,
 		cache.ResourceEventHandlerFuncs{
-			AddFunc: func(obj any) { fAdd(obj) },
+			AddFunc:    func(obj any) { fAdd(obj) },
 			DeleteFunc: func(obj any) { fDel(obj) },
 			UpdateFunc: func(oldObj, newObj any) { fMod(newObj) },
 		},
@@ -93,7 +258,7 @@ func (k *Kubernetes) watchResource(name, namespace, resourceType string, objType
 }
 
 func isSingleIp(ipRange string) bool {
-	return !strings.Contains(ipRange, "/") || strings.Split(ipRange,"/")[1] == "32"
+	return !strings.Contains(ipRange, "/") || strings.Split(ipRange, "/")[1] == "32"
 }
 
 func decreaseRef(refCount string) (count string, err error) {
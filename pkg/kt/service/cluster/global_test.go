@@ -0,0 +1,66 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func TestIsNamespaceTerminated(t *testing.T) {
+	resource := schema.GroupResource{Resource: "namespaces"}
+	require.True(t, IsNamespaceTerminated(nil, k8sErrors.NewNotFound(resource, "ns")))
+	require.False(t, IsNamespaceTerminated(nil, k8sErrors.NewForbidden(resource, "ns", fmt.Errorf("denied"))))
+	terminatingErr := &k8sErrors.StatusError{ErrStatus: metav1.Status{Details: &metav1.StatusDetails{
+		Causes: []metav1.StatusCause{{Type: coreV1.NamespaceTerminatingCause}}}}}
+	require.True(t, IsNamespaceTerminated(nil, terminatingErr))
+	require.True(t, IsNamespaceTerminated(&coreV1.Namespace{
+		Status: coreV1.NamespaceStatus{Phase: coreV1.NamespaceTerminating}}, nil))
+	require.False(t, IsNamespaceTerminated(&coreV1.Namespace{
+		Status: coreV1.NamespaceStatus{Phase: coreV1.NamespaceActive}}, nil))
+}
+
+func TestKubernetes_IsNamespaceEmpty(t *testing.T) {
+	now := metav1.Now()
+	k := &Kubernetes{Clientset: testclient.NewSimpleClientset(
+		&coreV1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "kube-root-ca.crt", Namespace: "ns-a"}},
+		&coreV1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "ns-a"}},
+		&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "shadow", Namespace: "ns-a", DeletionTimestamp: &now}},
+		&coreV1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "kube-root-ca.crt", Namespace: "ns-b"}},
+		&coreV1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "db-password", Namespace: "ns-b"}},
+	)}
+	empty, err := k.IsNamespaceEmpty("ns-a")
+	require.Nil(t, err)
+	require.True(t, empty, "namespace with only default and terminating resources should be empty")
+	empty, err = k.IsNamespaceEmpty("ns-b")
+	require.Nil(t, err)
+	require.False(t, empty, "namespace with user created secret should not be empty")
+}
+
+func TestKubernetes_IsNamespaceEmpty_discovery(t *testing.T) {
+	k := &Kubernetes{Clientset: testclient.NewSimpleClientset()}
+	discovery := k.Clientset.Discovery().(*fakediscovery.FakeDiscovery)
+	discovery.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "pods", Namespaced: true, Verbs: []string{"list"}},
+			{Name: "pods/log", Namespaced: true, Verbs: []string{"get"}},
+			{Name: "events", Namespaced: true, Verbs: []string{"list"}},
+			{Name: "bindings", Namespaced: true, Verbs: []string{"create"}},
+			{Name: "namespaces", Namespaced: false, Verbs: []string{"list"}},
+		},
+	}}
+	empty, err := k.IsNamespaceEmpty("ns-a")
+	require.Nil(t, err)
+	require.True(t, empty, "resources checked by typed client or not listable should be skipped")
+
+	discovery.Resources = append(discovery.Resources, &metav1.APIResourceList{GroupVersion: "invalid/group/version"})
+	empty, err = k.IsNamespaceEmpty("ns-a")
+	require.NotNil(t, err, "namespace should not be considered empty when resources could not be discovered")
+	require.False(t, empty)
+}
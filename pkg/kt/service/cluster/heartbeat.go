@@ -7,7 +7,6 @@ import (
 	"github.com/rs/zerolog/log"
 	"net"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 )
@@ -38,7 +37,7 @@ var LastHeartBeatStatus = &HeartBeatStatus{
 
 // SetupTimeDifference get time difference between cluster and local
 func SetupTimeDifference() error {
-	rectifierPodName := fmt.Sprintf("%s%s", util.RectifierPodPrefix, strings.ToLower(util.RandomString(5)))
+	rectifierPodName := util.RandomName(util.RectifierPodPrefix)
 	_, err := Ins().CreateRectifierPod(rectifierPodName)
 	if err != nil {
 		return err
@@ -67,25 +66,26 @@ func SetupTimeDifference() error {
 	return nil
 }
 
-// SetupHeartBeat setup heartbeat watcher
-func SetupHeartBeat(name, namespace string, updater func(string, string)) {
+// SetupHeartBeat setup heartbeat watcher, stop the returned ticker to stop heartbeat
+func SetupHeartBeat(name, namespace string, updater func(string, string)) *time.Ticker {
 	ticker := time.NewTicker(time.Minute*util.ResourceHeartBeatIntervalMinus - util.RandomSeconds(0, 10))
 	go func() {
 		for range ticker.C {
 			updater(name, namespace)
 		}
 	}()
+	return ticker
 }
 
 // SetupPortForwardHeartBeat setup heartbeat watcher for port forward
-func SetupPortForwardHeartBeat(port int) *time.Ticker {
+func SetupPortForwardHeartBeat(address string, port int) *time.Ticker {
 	ticker := time.NewTicker(util.PortForwardHeartBeatIntervalSec*time.Second - util.RandomSeconds(0, 5))
 	go func() {
 	TickLoop:
 		for {
 			select {
 			case <-ticker.C:
-				if conn, err := net.Dial("tcp", fmt.Sprintf(":%d", port)); err != nil {
+				if conn, err := net.Dial("tcp", net.JoinHostPort(address, strconv.Itoa(port))); err != nil {
 					log.Warn().Err(err).Msgf("Heartbeat port forward %d ticked failed", port)
 				} else {
 					log.Debug().Msgf("Heartbeat port forward %d ticked at %s", port, util.FormattedTime())
@@ -2,7 +2,9 @@ package cluster
 
 import (
 	"fmt"
+	"github.com/alibaba/kt-connect/pkg/common"
 	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/sshchannel"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
 	appV1 "k8s.io/api/apps/v1"
 	coreV1 "k8s.io/api/core/v1"
@@ -10,6 +12,8 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
+	"strconv"
+	"strings"
 )
 
 func getKubernetesClient(kubeConfig string) (clientset *kubernetes.Clientset, err error) {
@@ -63,7 +67,7 @@ func createDeployment(metaAndSpec *PodMetaAndSpec) *appV1.Deployment {
 	}
 	metaAndSpec.Meta.Labels = util.MergeMap(metaAndSpec.Meta.Labels, map[string]string{util.ControlBy: util.KubernetesToolkit})
 
-	return &appV1.Deployment{
+	deployment := &appV1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        metaAndSpec.Meta.Name,
 			Namespace:   metaAndSpec.Meta.Namespace,
@@ -82,6 +86,41 @@ func createDeployment(metaAndSpec *PodMetaAndSpec) *appV1.Deployment {
 			},
 		},
 	}
+	if metaAndSpec.Replicas > 0 {
+		deployment.Spec.Replicas = &metaAndSpec.Replicas
+	}
+	return deployment
+}
+
+func createDaemonSet(metaAndSpec *PodMetaAndSpec) *appV1.DaemonSet {
+	var originLabels = make(map[string]string, 0)
+	for k, v := range metaAndSpec.Meta.Labels {
+		originLabels[k] = v
+	}
+	metaAndSpec.Meta.Labels = util.MergeMap(metaAndSpec.Meta.Labels, map[string]string{util.ControlBy: util.KubernetesToolkit})
+	metaAndSpec.Meta.Annotations = util.MapPut(metaAndSpec.Meta.Annotations, util.KtLastHeartBeat, util.GetTimestamp())
+	// pods are marked as kt resources too, they are removed along with the daemon set by clean
+	podLabels := util.MergeMap(originLabels, map[string]string{util.ControlBy: util.KubernetesToolkit})
+
+	return &appV1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        metaAndSpec.Meta.Name,
+			Namespace:   metaAndSpec.Meta.Namespace,
+			Labels:      metaAndSpec.Meta.Labels,
+			Annotations: metaAndSpec.Meta.Annotations,
+		},
+		Spec: appV1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: originLabels,
+			},
+			Template: coreV1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: podLabels,
+				},
+				Spec: createPod(metaAndSpec).Spec,
+			},
+		},
+	}
 }
 
 func createPod(metaAndSpec *PodMetaAndSpec) *coreV1.Pod {
@@ -112,11 +151,36 @@ func createPod(metaAndSpec *PodMetaAndSpec) *coreV1.Pod {
 		pod.Spec.NodeSelector = util.String2Map(opt.Get().Global.NodeSelector)
 	}
 
+	if len(metaAndSpec.HostAliases) > 0 {
+		pod.Spec.HostAliases = metaAndSpec.HostAliases
+	}
+
+	if len(metaAndSpec.Topology) > 0 {
+		pod.Spec.TopologySpreadConstraints = metaAndSpec.Topology
+	}
+
+	if opt.Get().Global.PriorityClass != "" {
+		pod.Spec.PriorityClassName = opt.Get().Global.PriorityClass
+	}
+
+	if metaAndSpec.Os == util.OsWindows {
+		// linux capabilities is not applicable to windows container
+		pod.Spec.Containers[0].SecurityContext = nil
+		pod.Spec.NodeSelector = util.MapPut(pod.Spec.NodeSelector, util.LabelOs, util.OsWindows)
+		pod.Spec.Tolerations = metaAndSpec.Tolerations
+	}
+
 	return pod
 }
 
 func createContainer(image string, args []string, envs map[string]string, ports map[string]int) coreV1.Container {
 	var envVar []coreV1.EnvVar
+	// sshd of shadow pod accepts the same algorithms as ssh client
+	envs = util.MergeMap(envs, map[string]string{
+		common.EnvVarSshCiphers: strings.Join(sshchannel.Ciphers(), ","),
+		common.EnvVarSshKex:     strings.Join(sshchannel.KeyExchanges(), ","),
+		common.EnvVarSshMacs:    strings.Join(sshchannel.MACs(), ","),
+	})
 	for k, v := range envs {
 		envVar = append(envVar, coreV1.EnvVar{Name: k, Value: v})
 	}
@@ -141,17 +205,28 @@ func createContainer(image string, args []string, envs map[string]string, ports
 		},
 		Ports: []coreV1.ContainerPort{},
 		Resources: coreV1.ResourceRequirements{
-			Limits: coreV1.ResourceList{},
+			Limits:   coreV1.ResourceList{},
 			Requests: coreV1.ResourceList{},
 		},
 	}
 	if opt.Get().Global.PodQuota != "" {
 		addResourceLimit(&container, opt.Get().Global.PodQuota)
 	}
+	if healthPort, err := strconv.Atoi(envs[common.EnvVarHealthPort]); err == nil {
+		container.ReadinessProbe = &coreV1.Probe{
+			Handler: coreV1.Handler{
+				HTTPGet: &coreV1.HTTPGetAction{
+					Path: common.ShadowHealthPath,
+					Port: intstr.FromInt(healthPort),
+				},
+			},
+			PeriodSeconds: 5,
+		}
+	}
 	for name, port := range ports {
 		container.Ports = append(container.Ports, coreV1.ContainerPort{
-			Name: name,
-			Protocol: coreV1.ProtocolTCP,
+			Name:          name,
+			Protocol:      coreV1.ProtocolTCP,
 			ContainerPort: int32(port),
 		})
 	}
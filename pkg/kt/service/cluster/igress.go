@@ -2,13 +2,119 @@ package cluster
 
 import (
 	"context"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
 	extV1 "k8s.io/api/extensions/v1beta1"
+	netV1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labelApi "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 )
 
+// IngressMetaAndSpec ...
+type IngressMetaAndSpec struct {
+	Meta        *ResourceMeta
+	Host        string
+	ClassName   string
+	TlsSecret   string
+	Service     string
+	ServicePort int
+}
+
 // GetAllIngressInNamespace get all ingresses in specified namespace
 func (k *Kubernetes) GetAllIngressInNamespace(namespace string) (*extV1.IngressList, error) {
 	return k.Clientset.ExtensionsV1beta1().Ingresses(namespace).List(context.TODO(), metav1.ListOptions{
 		TimeoutSeconds: &apiTimeout,
 	})
 }
+
+// GetIngressesByLabel get ingresses by label
+func (k *Kubernetes) GetIngressesByLabel(labels map[string]string, namespace string) (*netV1.IngressList, error) {
+	return k.Clientset.NetworkingV1().Ingresses(namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector:  labelApi.SelectorFromSet(labels).String(),
+		TimeoutSeconds: &apiTimeout,
+	})
+}
+
+// CreateIngress create ingress routing all requests of specified host to service
+func (k *Kubernetes) CreateIngress(metaAndSpec *IngressMetaAndSpec) (*netV1.Ingress, error) {
+	ingress, err := k.Clientset.NetworkingV1().Ingresses(metaAndSpec.Meta.Namespace).
+		Create(context.TODO(), createIngress(metaAndSpec), metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	SetupHeartBeat(ingress.Name, ingress.Namespace, k.UpdateIngressHeartBeat)
+	return ingress, nil
+}
+
+// UpdateIngressHeartBeat refresh heart beat annotation of ingress, so that it won't be removed by clean
+func (k *Kubernetes) UpdateIngressHeartBeat(name, namespace string) {
+	key := "ingress_" + name
+	if _, err := k.Clientset.NetworkingV1().Ingresses(namespace).
+		Patch(context.TODO(), name, types.JSONPatchType, []byte(resourceHeartbeatPatch()), metav1.PatchOptions{}); err != nil {
+		if healthy, exists := LastHeartBeatStatus.Get(key); healthy || !exists {
+			log.Warn().Err(err).Msgf("Failed to update heart beat of ingress %s", name)
+		} else {
+			log.Debug().Err(err).Msgf("Ingress %s heart beat interrupted", name)
+		}
+		LastHeartBeatStatus.Set(key, false)
+	} else {
+		log.Debug().Msgf("Heartbeat ingress %s ticked at %s", name, util.FormattedTime())
+		LastHeartBeatStatus.Set(key, true)
+	}
+}
+
+// RemoveIngress remove ingress
+func (k *Kubernetes) RemoveIngress(name, namespace string) error {
+	deletePolicy := metav1.DeletePropagationBackground
+	return k.Clientset.NetworkingV1().Ingresses(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{
+		PropagationPolicy: &deletePolicy,
+	})
+}
+
+// GetIngressClasses get all ingress classes of cluster
+func (k *Kubernetes) GetIngressClasses() ([]netV1.IngressClass, error) {
+	classes, err := k.Clientset.NetworkingV1().IngressClasses().List(context.TODO(), metav1.ListOptions{
+		TimeoutSeconds: &apiTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return classes.Items, nil
+}
+
+func createIngress(metaAndSpec *IngressMetaAndSpec) *netV1.Ingress {
+	metaAndSpec.Meta.Labels = util.MergeMap(metaAndSpec.Meta.Labels, map[string]string{util.ControlBy: util.KubernetesToolkit})
+	metaAndSpec.Meta.Annotations = util.MapPut(metaAndSpec.Meta.Annotations, util.KtLastHeartBeat, util.GetTimestamp())
+	pathType := netV1.PathTypePrefix
+	ingress := &netV1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        metaAndSpec.Meta.Name,
+			Namespace:   metaAndSpec.Meta.Namespace,
+			Labels:      metaAndSpec.Meta.Labels,
+			Annotations: metaAndSpec.Meta.Annotations,
+		},
+		Spec: netV1.IngressSpec{
+			Rules: []netV1.IngressRule{{
+				Host: metaAndSpec.Host,
+				IngressRuleValue: netV1.IngressRuleValue{HTTP: &netV1.HTTPIngressRuleValue{
+					Paths: []netV1.HTTPIngressPath{{
+						Path:     "/",
+						PathType: &pathType,
+						Backend: netV1.IngressBackend{Service: &netV1.IngressServiceBackend{
+							Name: metaAndSpec.Service,
+							Port: netV1.ServiceBackendPort{Number: int32(metaAndSpec.ServicePort)},
+						}},
+					}},
+				}},
+			}},
+		},
+	}
+	if metaAndSpec.ClassName != "" {
+		ingress.Spec.IngressClassName = &metaAndSpec.ClassName
+	}
+	if metaAndSpec.TlsSecret != "" {
+		ingress.Spec.TLS = []netV1.IngressTLS{{Hosts: []string{metaAndSpec.Host}, SecretName: metaAndSpec.TlsSecret}}
+	}
+	return ingress
+}
@@ -0,0 +1,42 @@
+package cluster
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+)
+
+// injectionOptOutLabels labels telling admission webhooks not to mutate the pod
+var injectionOptOutLabels = map[string]string{
+	// istio sidecar injector, label is preferred since istio 1.10
+	"sidecar.istio.io/inject": "false",
+	// kuma sidecar injector
+	"kuma.io/sidecar-injection": "disabled",
+}
+
+// injectionOptOutAnnotations annotations telling admission webhooks not to mutate the pod
+var injectionOptOutAnnotations = map[string]string{
+	// istio sidecar injector, for versions ignoring the label
+	"sidecar.istio.io/inject": "false",
+	// linkerd proxy injector
+	"linkerd.io/inject": "disabled",
+	// hashicorp vault agent injector
+	"vault.hashicorp.com/agent-inject": "false",
+	// dapr sidecar injector
+	"dapr.io/enabled": "false",
+	// open service mesh sidecar injector
+	"openservicemesh.io/sidecar-injection": "disabled",
+}
+
+// disableInjection add opt-out labels and annotations of known injectors to shadow pod, unless '--allowInjection' is set,
+// since sidecars intercepting traffic or mutating containers of shadow pod would break the ssh tunnel,
+// '--disableInjection' explicitly asks for it in case the default changes
+func disableInjection(labels, annotations map[string]string) {
+	if opt.Get().Global.AllowInjection && !opt.Get().Global.DisableInjection {
+		return
+	}
+	for key, val := range injectionOptOutLabels {
+		labels[key] = val
+	}
+	for key, val := range injectionOptOutAnnotations {
+		annotations[key] = val
+	}
+}
@@ -0,0 +1,41 @@
+package cluster
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/stretchr/testify/require"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func TestKubernetes_newShadowMeta_injection(t *testing.T) {
+	k := &Kubernetes{Clientset: testclient.NewSimpleClientset()}
+	defer func() {
+		opt.Get().Global.AllowInjection = false
+		opt.Get().Global.DisableInjection = false
+		opt.Get().Global.WithAnnotation = ""
+	}()
+
+	opt.Get().Global.WithAnnotation = "dapr.io/enabled=true"
+	podMeta, _, err := k.newShadowMeta("shadow", map[string]string{"sidecar.istio.io/inject": "true"},
+		map[string]string{}, map[string]string{}, "", map[int]string{}, nil)
+	require.Nil(t, err)
+	require.Equal(t, "false", podMeta.Meta.Labels["sidecar.istio.io/inject"], "label of origin pod should be overridden")
+	require.Equal(t, "false", podMeta.Meta.Annotations["sidecar.istio.io/inject"])
+	require.Equal(t, "disabled", podMeta.Meta.Annotations["linkerd.io/inject"])
+	require.Equal(t, "false", podMeta.Meta.Annotations["vault.hashicorp.com/agent-inject"])
+	require.Equal(t, "true", podMeta.Meta.Annotations["dapr.io/enabled"], "extra annotation should take precedence")
+
+	opt.Get().Global.AllowInjection = true
+	opt.Get().Global.WithAnnotation = ""
+	podMeta, _, err = k.newShadowMeta("shadow", map[string]string{}, map[string]string{}, map[string]string{},
+		"", map[int]string{}, nil)
+	require.Nil(t, err)
+	require.NotContains(t, podMeta.Meta.Labels, "sidecar.istio.io/inject")
+	require.NotContains(t, podMeta.Meta.Annotations, "vault.hashicorp.com/agent-inject")
+
+	opt.Get().Global.DisableInjection = true
+	podMeta, _, err = k.newShadowMeta("shadow", map[string]string{}, map[string]string{}, map[string]string{},
+		"", map[int]string{}, nil)
+	require.Nil(t, err)
+	require.Equal(t, "false", podMeta.Meta.Labels["sidecar.istio.io/inject"], "explicit opt-out should take effect")
+}
@@ -0,0 +1,120 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+)
+
+const istioNetworkingApi = "networking.istio.io/v1beta1"
+
+// VirtualService the fields of istio virtual service used by kt
+type VirtualService struct {
+	ApiVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Metadata   VirtualServiceMeta `json:"metadata"`
+	Spec       VirtualServiceSpec `json:"spec"`
+}
+
+// VirtualServiceMeta metadata of virtual service
+type VirtualServiceMeta struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// VirtualServiceSpec spec of virtual service
+type VirtualServiceSpec struct {
+	Hosts []string    `json:"hosts"`
+	Http  []HttpRoute `json:"http,omitempty"`
+}
+
+// HttpRoute http route of virtual service
+type HttpRoute struct {
+	Route            []RouteDestination `json:"route"`
+	Mirror           *Destination       `json:"mirror,omitempty"`
+	MirrorPercentage *Percent           `json:"mirrorPercentage,omitempty"`
+}
+
+// RouteDestination destination of http route
+type RouteDestination struct {
+	Destination Destination `json:"destination"`
+}
+
+// Destination host to send requests to
+type Destination struct {
+	Host string `json:"host"`
+}
+
+// Percent percentage value
+type Percent struct {
+	Value float64 `json:"value"`
+}
+
+// IsIstioInstalled check whether istio networking api is available
+func (k *Kubernetes) IsIstioInstalled() bool {
+	_, err := k.Clientset.Discovery().ServerResourcesForGroupVersion(istioNetworkingApi)
+	return err == nil
+}
+
+// GetAllVirtualServiceInNamespace get all istio virtual services
+func (k *Kubernetes) GetAllVirtualServiceInNamespace(namespace string) ([]VirtualService, error) {
+	data, err := k.Clientset.CoreV1().RESTClient().Get().AbsPath(virtualServicePath(namespace)).
+		Do(context.TODO()).Raw()
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Items []VirtualService `json:"items"`
+	}
+	if err = json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// CreateMirrorVirtualService create istio virtual service which copy requests to mirror host
+func (k *Kubernetes) CreateMirrorVirtualService(name, namespace, host, mirrorHost string, percent int) error {
+	body, err := json.Marshal(newMirrorVirtualService(name, namespace, host, mirrorHost, percent))
+	if err != nil {
+		return err
+	}
+	return k.Clientset.CoreV1().RESTClient().Post().AbsPath(virtualServicePath(namespace)).
+		Body(body).Do(context.TODO()).Error()
+}
+
+// RenderMirrorVirtualService generate mirror virtual service without creating it
+func (k *Kubernetes) RenderMirrorVirtualService(name, namespace, host, mirrorHost string, percent int) any {
+	return newMirrorVirtualService(name, namespace, host, mirrorHost, percent)
+}
+
+// RemoveVirtualService remove istio virtual service
+func (k *Kubernetes) RemoveVirtualService(name, namespace string) error {
+	return k.Clientset.CoreV1().RESTClient().Delete().AbsPath(virtualServicePath(namespace), name).
+		Do(context.TODO()).Error()
+}
+
+func newMirrorVirtualService(name, namespace, host, mirrorHost string, percent int) *VirtualService {
+	return &VirtualService{
+		ApiVersion: istioNetworkingApi,
+		Kind:       "VirtualService",
+		Metadata: VirtualServiceMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{util.ControlBy: util.KubernetesToolkit},
+		},
+		Spec: VirtualServiceSpec{
+			Hosts: []string{host},
+			Http: []HttpRoute{{
+				Route:            []RouteDestination{{Destination: Destination{Host: host}}},
+				Mirror:           &Destination{Host: mirrorHost},
+				MirrorPercentage: &Percent{Value: float64(percent)},
+			}},
+		},
+	}
+}
+
+func virtualServicePath(namespace string) string {
+	return fmt.Sprintf("/apis/%s/namespaces/%s/virtualservices", istioNetworkingApi, namespace)
+}
@@ -0,0 +1,25 @@
+package cluster
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_newMirrorVirtualService(t *testing.T) {
+	data, err := json.Marshal(newMirrorVirtualService("svc-kt-mirror", "default", "svc", "svc-kt-mirror", 50))
+	require.Nil(t, err)
+	require.JSONEq(t, `{
+		"apiVersion": "networking.istio.io/v1beta1",
+		"kind": "VirtualService",
+		"metadata": {"name": "svc-kt-mirror", "namespace": "default", "labels": {"control-by": "kt"}},
+		"spec": {
+			"hosts": ["svc"],
+			"http": [{
+				"route": [{"destination": {"host": "svc"}}],
+				"mirror": {"host": "svc-kt-mirror"},
+				"mirrorPercentage": {"value": 50}
+			}]
+		}
+	}`, string(data))
+}
@@ -0,0 +1,12 @@
+package cluster
+
+import (
+	"context"
+	batchV1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetJob ...
+func (k *Kubernetes) GetJob(name string, namespace string) (*batchV1.Job, error) {
+	return k.Clientset.BatchV1().Jobs(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
@@ -0,0 +1,51 @@
+package cluster
+
+import (
+	"context"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetPodOs get operating system of node which the pod is or will be running on
+func (k *Kubernetes) GetPodOs(spec *coreV1.PodSpec) string {
+	if podOs := getOsOfPodSpec(spec); podOs != "" {
+		return podOs
+	}
+	if spec.NodeName != "" {
+		node, err := k.Clientset.CoreV1().Nodes().Get(context.TODO(), spec.NodeName, metav1.GetOptions{})
+		if err != nil {
+			log.Debug().Err(err).Msgf("Failed to get node %s", spec.NodeName)
+		} else if nodeOs := getOsOfLabels(node.Labels); nodeOs != "" {
+			return nodeOs
+		}
+	}
+	return util.OsLinux
+}
+
+func getOsOfPodSpec(spec *coreV1.PodSpec) string {
+	if labelOs := getOsOfLabels(spec.NodeSelector); labelOs != "" {
+		return labelOs
+	}
+	if spec.Affinity == nil || spec.Affinity.NodeAffinity == nil ||
+		spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return ""
+	}
+	for _, term := range spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		for _, expr := range term.MatchExpressions {
+			if (expr.Key == util.LabelOs || expr.Key == util.LabelOsBeta) &&
+				expr.Operator == coreV1.NodeSelectorOpIn && len(expr.Values) == 1 {
+				return expr.Values[0]
+			}
+		}
+	}
+	return ""
+}
+
+func getOsOfLabels(labels map[string]string) string {
+	if labelOs, exists := labels[util.LabelOs]; exists {
+		return labelOs
+	}
+	return labels[util.LabelOsBeta]
+}
@@ -0,0 +1,35 @@
+package cluster
+
+import (
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func TestKubernetes_GetPodOs(t *testing.T) {
+	k := &Kubernetes{
+		Clientset: testclient.NewSimpleClientset(&coreV1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "win-node", Labels: map[string]string{util.LabelOs: util.OsWindows}},
+		}),
+	}
+	require.Equal(t, util.OsLinux, k.GetPodOs(&coreV1.PodSpec{}))
+	require.Equal(t, util.OsWindows, k.GetPodOs(&coreV1.PodSpec{
+		NodeSelector: map[string]string{util.LabelOsBeta: util.OsWindows},
+	}))
+	require.Equal(t, util.OsWindows, k.GetPodOs(&coreV1.PodSpec{NodeName: "win-node"}))
+	require.Equal(t, util.OsLinux, k.GetPodOs(&coreV1.PodSpec{NodeName: "not-exist"}))
+	require.Equal(t, util.OsWindows, k.GetPodOs(&coreV1.PodSpec{
+		Affinity: &coreV1.Affinity{NodeAffinity: &coreV1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &coreV1.NodeSelector{
+				NodeSelectorTerms: []coreV1.NodeSelectorTerm{{
+					MatchExpressions: []coreV1.NodeSelectorRequirement{{
+						Key: util.LabelOs, Operator: coreV1.NodeSelectorOpIn, Values: []string{util.OsWindows},
+					}},
+				}},
+			},
+		}},
+	}))
+}
@@ -25,11 +25,16 @@ import (
 
 // PodMetaAndSpec ...
 type PodMetaAndSpec struct {
-	Meta   *ResourceMeta
-	Image  string
-	Envs   map[string]string
-	Ports  map[string]int
-	IsLeaf bool
+	Meta        *ResourceMeta
+	Image       string
+	Envs        map[string]string
+	Ports       map[string]int
+	IsLeaf      bool
+	Os          string
+	Tolerations []coreV1.Toleration
+	Replicas    int32
+	HostAliases []coreV1.HostAlias
+	Topology    []coreV1.TopologySpreadConstraint
 }
 
 // GetPod ...
@@ -59,12 +64,12 @@ func (k *Kubernetes) RemovePod(name, namespace string) (err error) {
 }
 
 func (k *Kubernetes) WaitPodsReady(labels map[string]string, namespace string, timeoutSec int) ([]coreV1.Pod, error) {
-	return k.waitPodsReady(labels, namespace, timeoutSec, 0)
+	return k.waitPodsReady(labels, namespace, timeoutSec)
 }
 
 // WaitPodReady ...
 func (k *Kubernetes) WaitPodReady(name, namespace string, timeoutSec int) (*coreV1.Pod, error) {
-	return k.waitPodReady(name, namespace, timeoutSec, 0)
+	return k.waitPodReady(name, namespace, timeoutSec)
 }
 
 // WaitPodTerminate ...
@@ -131,6 +136,16 @@ func (k *Kubernetes) ExecInPod(containerName, podName, namespace string, cmd ...
 	return stdoutMsg, stderrMsg, err
 }
 
+// TailPodLogs follow logs of specified container, only logs printed since now are returned
+func (k *Kubernetes) TailPodLogs(containerName, podName, namespace string) (io.ReadCloser, error) {
+	tailLines := int64(0)
+	return k.Clientset.CoreV1().Pods(namespace).GetLogs(podName, &coreV1.PodLogOptions{
+		Container: containerName,
+		Follow:    true,
+		TailLines: &tailLines,
+	}).Stream(context.TODO())
+}
+
 // IncreasePodRef increase pod ref count by 1
 func (k *Kubernetes) IncreasePodRef(name string, namespace string) error {
 	pod, err := k.GetPod(name, namespace)
@@ -184,51 +199,98 @@ func handlePodEvent(obj any, status string, f func(*coreV1.Pod)) {
 	}
 }
 
-func (k *Kubernetes) waitPodsReady(labels map[string]string, namespace string, timeoutSec int, times int) ([]coreV1.Pod, error) {
-	pods, err := k.GetPodsByLabel(labels, namespace)
+func (k *Kubernetes) waitPodsReady(labels map[string]string, namespace string, timeoutSec int) ([]coreV1.Pod, error) {
+	var runningPods []coreV1.Pod
+	_, err := WaitPod("shadow pod", timeoutSec, func() (*coreV1.Pod, error) {
+		pods, err := k.GetPodsByLabel(labels, namespace)
+		if err != nil {
+			return nil, err
+		}
+		if runningPods = filterRunningPods(pods.Items); len(runningPods) > 0 {
+			return &runningPods[0], nil
+		} else if len(pods.Items) > 0 {
+			return &pods.Items[0], nil
+		}
+		return nil, nil
+	}, isShadowRunning)
 	if err != nil {
 		return nil, err
 	}
-	const interval = 6
-	if times > timeoutSec/interval {
-		if len(pods.Items) < 1 {
-			return nil, fmt.Errorf("pod with label %v not found", labels)
-		} else {
-			return nil, fmt.Errorf("pod %s failed to start", pods.Items[0].Name)
-		}
-	}
-	runningPods := filterRunningPods(pods.Items)
-	if len(runningPods) > 0 {
-		log.Info().Msgf("Pod %s is ready", runningPods[0].Name)
-		return runningPods, nil
-	}
-	log.Info().Msgf("Waiting for shadow pod ...")
-	time.Sleep(1 * time.Second)
-	return k.waitPodsReady(labels, namespace, timeoutSec, times+1)
+	log.Info().Msgf("Pod %s is ready", runningPods[0].Name)
+	return runningPods, nil
 }
 
-func (k *Kubernetes) waitPodReady(name, namespace string, timeoutSec int, times int) (*coreV1.Pod, error) {
-	const interval = 6
-	if times > timeoutSec/interval {
-		return nil, fmt.Errorf("pod %s failed to start", name)
+func (k *Kubernetes) waitPodReady(name, namespace string, timeoutSec int) (*coreV1.Pod, error) {
+	description := fmt.Sprintf("pod %s", name)
+	if strings.HasPrefix(name, util.RectifierPodPrefix) {
+		description = "cluster time"
 	}
-	pod, err := k.GetPod(name, namespace)
+	pod, err := WaitPod(description, timeoutSec, func() (*coreV1.Pod, error) {
+		return k.GetPod(name, namespace)
+	}, isPodRunning)
 	if err != nil {
 		return nil, err
 	}
-	if pod.Status.Phase != coreV1.PodRunning {
-		if strings.HasPrefix(name, util.RectifierPodPrefix) {
-			log.Info().Msgf("Fetching cluster time ...")
-		} else {
-			log.Info().Msgf("Waiting for pod %s ...", name)
-		}
-		time.Sleep(interval * time.Second)
-		return k.waitPodReady(name, namespace, timeoutSec, times+1)
-	}
 	if !strings.HasPrefix(name, util.RectifierPodPrefix) {
 		log.Info().Msgf("Pod %s is ready", pod.Name)
 	}
-	return pod, err
+	return pod, nil
+}
+
+// WaitPod poll pod via getPod every '--podPollInterval' seconds until isReady return true,
+// getPod may return nil if the pod not exist yet, and current phase and conditions of pod are reported on timeout
+func WaitPod(description string, timeoutSec int, getPod func() (*coreV1.Pod, error),
+	isReady func(*coreV1.Pod) (bool, error)) (*coreV1.Pod, error) {
+	interval := time.Duration(opt.Get().Global.PodPollInterval) * time.Second
+	if interval <= 0 {
+		interval = time.Second
+	}
+	deadline := time.Now().Add(time.Duration(timeoutSec) * time.Second)
+	for {
+		pod, err := getPod()
+		if err != nil {
+			return nil, err
+		}
+		if pod != nil {
+			if ready, err2 := isReady(pod); err2 != nil {
+				return nil, err2
+			} else if ready {
+				return pod, nil
+			}
+		}
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("%s is not ready in %d seconds, %s", description, timeoutSec, DescribePodStatus(pod))
+		}
+		log.Info().Msgf("Waiting for %s ...", description)
+		time.Sleep(interval)
+	}
+}
+
+func isPodRunning(pod *coreV1.Pod) (bool, error) {
+	return pod.Status.Phase == coreV1.PodRunning && pod.DeletionTimestamp == nil, nil
+}
+
+// DescribePodStatus get phase and not satisfied conditions of pod for diagnostics
+func DescribePodStatus(pod *coreV1.Pod) string {
+	if pod == nil {
+		return "pod not found"
+	}
+	desc := fmt.Sprintf("pod %s is in %s phase", pod.Name, pod.Status.Phase)
+	var conditions []string
+	for _, c := range pod.Status.Conditions {
+		if c.Status == coreV1.ConditionTrue {
+			continue
+		}
+		condition := fmt.Sprintf("%s=%s", c.Type, c.Status)
+		if c.Reason != "" {
+			condition = fmt.Sprintf("%s (%s: %s)", condition, c.Reason, c.Message)
+		}
+		conditions = append(conditions, condition)
+	}
+	if len(conditions) > 0 {
+		desc = fmt.Sprintf("%s, conditions: %s", desc, strings.Join(conditions, ", "))
+	}
+	return desc
 }
 
 func (k *Kubernetes) waitPodTerminate(name, namespace string, times int) (*coreV1.Pod, error) {
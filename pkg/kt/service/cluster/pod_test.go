@@ -0,0 +1,59 @@
+package cluster
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	"testing"
+)
+
+func TestWaitPod(t *testing.T) {
+	opt.Get().Global.PodPollInterval = 1
+	pod := &coreV1.Pod{Status: coreV1.PodStatus{Phase: coreV1.PodPending}}
+	pod.Name = "shadow"
+	polled := 0
+	getPod := func() (*coreV1.Pod, error) {
+		if polled++; polled > 1 {
+			pod.Status.Phase = coreV1.PodRunning
+		}
+		return pod, nil
+	}
+	got, err := WaitPod("pod shadow", 5, getPod, isPodRunning)
+	require.Nil(t, err)
+	require.Equal(t, "shadow", got.Name)
+	require.Equal(t, 2, polled)
+
+	pod.Status = coreV1.PodStatus{Phase: coreV1.PodPending, Conditions: []coreV1.PodCondition{
+		{Type: coreV1.PodInitialized, Status: coreV1.ConditionTrue},
+		{Type: coreV1.PodScheduled, Status: coreV1.ConditionFalse, Reason: "Unschedulable", Message: "0/3 nodes are available"},
+	}}
+	_, err = WaitPod("pod shadow", 0, func() (*coreV1.Pod, error) { return pod, nil }, isPodRunning)
+	require.EqualError(t, err, "pod shadow is not ready in 0 seconds, pod shadow is in Pending phase, "+
+		"conditions: PodScheduled=False (Unschedulable: 0/3 nodes are available)")
+
+	_, err = WaitPod("shadow pod", 0, func() (*coreV1.Pod, error) { return nil, nil }, isPodRunning)
+	require.EqualError(t, err, "shadow pod is not ready in 0 seconds, pod not found")
+}
+
+func Test_getShadowPhase(t *testing.T) {
+	pod := &coreV1.Pod{Status: coreV1.PodStatus{Phase: coreV1.PodPending}}
+	phase, _ := getShadowPhase(pod)
+	require.Equal(t, util.PhaseScheduling, phase)
+
+	pod.Status.Conditions = []coreV1.PodCondition{{Type: coreV1.PodScheduled, Status: coreV1.ConditionFalse, Reason: "Unschedulable"}}
+	phase, detail := getShadowPhase(pod)
+	require.Equal(t, util.PhaseScheduling, phase)
+	require.Equal(t, "Unschedulable", detail)
+
+	pod.Status.Conditions = []coreV1.PodCondition{{Type: coreV1.PodScheduled, Status: coreV1.ConditionTrue}}
+	pod.Status.ContainerStatuses = []coreV1.ContainerStatus{{State: coreV1.ContainerState{
+		Waiting: &coreV1.ContainerStateWaiting{Reason: "ContainerCreating"}}}}
+	phase, detail = getShadowPhase(pod)
+	require.Equal(t, util.PhasePullingImage, phase)
+	require.Equal(t, "ContainerCreating", detail)
+
+	pod.Status.ContainerStatuses[0].State = coreV1.ContainerState{Running: &coreV1.ContainerStateRunning{}}
+	phase, _ = getShadowPhase(pod)
+	require.Equal(t, util.PhaseStarting, phase)
+}
@@ -23,6 +23,11 @@ func (k *Kubernetes) GetService(name, namespace string) (*coreV1.Service, error)
 	return k.Clientset.CoreV1().Services(namespace).Get(context.TODO(), name, metav1.GetOptions{})
 }
 
+// GetEndpoints get endpoints of service
+func (k *Kubernetes) GetEndpoints(name, namespace string) (*coreV1.Endpoints, error) {
+	return k.Clientset.CoreV1().Endpoints(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
 // GetServicesBySelector get services by selector
 func (k *Kubernetes) GetServicesBySelector(matchLabels map[string]string, namespace string) ([]coreV1.Service, error) {
 	var matchedSvcs []coreV1.Service
@@ -60,6 +65,13 @@ func (k *Kubernetes) CreateService(metaAndSpec *SvcMetaAndSpec) (*coreV1.Service
 		Create(context.TODO(), createService(metaAndSpec), metav1.CreateOptions{})
 }
 
+// RenderService generate service without creating it
+func (k *Kubernetes) RenderService(metaAndSpec *SvcMetaAndSpec) *coreV1.Service {
+	svc := createService(metaAndSpec)
+	svc.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Service"}
+	return svc
+}
+
 // UpdateService ...
 func (k *Kubernetes) UpdateService(svc *coreV1.Service) (*coreV1.Service, error) {
 	return k.Clientset.CoreV1().Services(svc.Namespace).Update(context.TODO(), svc, metav1.UpdateOptions{})
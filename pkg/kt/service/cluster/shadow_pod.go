@@ -10,15 +10,120 @@ import (
 	coreV1 "k8s.io/api/core/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
-// GetOrCreateShadow create shadow pod or deployment
-func (k *Kubernetes) GetOrCreateShadow(name string, labels, annotations, envs map[string]string, exposePorts string, portNameDict map[int]string) (
-	string, string, string, error) {
+// setupHeartBeat start heartbeat of reused shadow, stub it in tests
+var setupHeartBeat = SetupHeartBeat
+
+// GetOrCreateShadow create shadow pod or deployment, target is spec of pod to exchange if any
+func (k *Kubernetes) GetOrCreateShadow(name string, labels, annotations, envs map[string]string, exposePorts string, portNameDict map[int]string,
+	target *coreV1.PodSpec) (string, string, string, error) {
+	podMeta, sshKeyMeta, err := k.newShadowMeta(name, labels, annotations, envs, exposePorts, portNameDict, target)
+	if err != nil {
+		return "", "", "", err
+	}
+	resourceMeta := podMeta.Meta
+
 	// record context data
-	opt.Store.Shadow = name
+	opt.Store.Shadow = util.Append(opt.Store.Shadow, name)
+
+	if opt.Store.Component == util.ComponentConnect && opt.Get().Connect.ShareShadow {
+		pod, generator, err2 := k.tryGetExistingShadows(resourceMeta, sshKeyMeta, true)
+		if err2 != nil {
+			return "", "", "", err2
+		}
+		if pod != nil && generator != nil {
+			return pod.Status.PodIP, pod.Name, generator.PrivateKeyPath, nil
+		}
+	} else if opt.Store.Component == util.ComponentExchange && opt.Get().Exchange.ReuseShadow &&
+		opt.Get().Exchange.Mode == util.ExchangeModeSelector {
+		if err := k.removeStaleShadow(resourceMeta); err != nil {
+			return "", "", "", err
+		}
+		pod, generator, err2 := k.tryGetExistingShadows(resourceMeta, sshKeyMeta, false)
+		if err2 != nil {
+			return "", "", "", err2
+		}
+		if pod != nil && generator != nil {
+			// heartbeat of reused shadow was stopped when previous exchange exit
+			if opt.Get().Global.UseShadowDeployment {
+				setupHeartBeat(name, resourceMeta.Namespace, k.UpdateDeploymentHeartBeat)
+			} else {
+				setupHeartBeat(name, resourceMeta.Namespace, k.UpdatePodHeartBeat)
+			}
+			setupHeartBeat(name, resourceMeta.Namespace, k.UpdateConfigMapHeartBeat)
+			return pod.Status.PodIP, pod.Name, generator.PrivateKeyPath, nil
+		}
+	}
+
+	if opt.Get().Global.ForceDeleteExisting {
+		if err = k.removeExistingShadow(resourceMeta); err != nil {
+			return "", "", "", err
+		}
+	}
+	return k.createShadow(podMeta, sshKeyMeta)
+}
+
+// RenderShadow generate shadow pod or deployment and its config map without creating them, ssh keys are left empty
+func (k *Kubernetes) RenderShadow(name string, labels, annotations, envs map[string]string, exposePorts string, portNameDict map[int]string,
+	target *coreV1.PodSpec) ([]any, error) {
+	podMeta, sshKeyMeta, err := k.newShadowMeta(name, labels, annotations, envs, exposePorts, portNameDict, target)
+	if err != nil {
+		return nil, err
+	}
+	configMap := newSshConfigMap(podMeta.Meta.Labels, sshKeyMeta.SshConfigMapName, podMeta.Meta.Namespace, "", "")
+	configMap.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"}
+	if opt.Get().Global.UseShadowDeployment {
+		deployment := createDeployment(podMeta)
+		k.appendSshVolume(&deployment.Spec.Template.Spec, sshKeyMeta.SshConfigMapName)
+		deployment.TypeMeta = metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"}
+		return []any{configMap, deployment}, nil
+	}
+	pod := createPod(podMeta)
+	k.appendSshVolume(&pod.Spec, sshKeyMeta.SshConfigMapName)
+	pod.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"}
+	return []any{configMap, pod}, nil
+}
+
+func (k *Kubernetes) newShadowMeta(name string, labels, annotations, envs map[string]string, exposePorts string,
+	portNameDict map[int]string, target *coreV1.PodSpec) (*PodMetaAndSpec, *SSHkeyMeta, error) {
+	if err := k.checkPriorityClass(opt.Get().Global.PriorityClass); err != nil {
+		return nil, nil, err
+	}
+	podMeta := PodMetaAndSpec{
+		Image: opt.Get().Global.Image,
+		Envs:  envs,
+	}
+	if target != nil && k.GetPodOs(target) == util.OsWindows {
+		if opt.Get().Global.WindowsImage == "" {
+			return nil, nil, fmt.Errorf("target pod is running on windows node, " +
+				"please specify a windows compatible shadow image via '--windowsImage'")
+		}
+		log.Info().Msgf("Target pod is running on windows node, using shadow image %s", opt.Get().Global.WindowsImage)
+		podMeta.Image = opt.Get().Global.WindowsImage
+		podMeta.Os = util.OsWindows
+		podMeta.Tolerations = target.Tolerations
+	}
+	if opt.Store.Component == util.ComponentConnect && opt.Get().Connect.Replicas > 1 {
+		podMeta.Replicas = int32(opt.Get().Connect.Replicas)
+	}
+	if opt.Store.Component == util.ComponentExchange {
+		hostAliases, err := ParseHostAliases(opt.Get().Exchange.HostAlias)
+		if err != nil {
+			return nil, nil, err
+		}
+		if opt.Get().Exchange.CopyHostAliases && target != nil {
+			hostAliases = mergeHostAliases(target.HostAliases, hostAliases)
+		}
+		podMeta.HostAliases = hostAliases
+	}
 
+	disableInjection(labels, annotations)
 	// extra labels must be applied after origin labels
 	for key, val := range util.String2Map(opt.Get().Global.WithLabel) {
 		labels[key] = val
@@ -27,18 +132,36 @@ func (k *Kubernetes) GetOrCreateShadow(name string, labels, annotations, envs ma
 		annotations[key] = val
 	}
 	annotations[util.KtUser] = util.GetLocalUserName()
-	resourceMeta := ResourceMeta{
+	annotations[util.KtHost] = util.GetLocalHostName()
+	annotations[util.KtVersion] = opt.Store.Version
+	if opt.Get().Global.ShadowTtl > 0 {
+		annotations[util.KtExpireAt] = strconv.FormatInt(util.GetTime()+int64(opt.Get().Global.ShadowTtl)*60, 10)
+	}
+	if opt.Store.KubeUser != "" {
+		annotations[util.KtKubeUser] = opt.Store.KubeUser
+	}
+	topology, err := ParseTopologySpread(opt.Get().Global.TopologySpread, labels)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opt.Get().Global.CopyTopologySpread && target != nil {
+		for _, c := range target.TopologySpreadConstraints {
+			topology = append(topology, *c.DeepCopy())
+		}
+	}
+	podMeta.Topology = topology
+	podMeta.Meta = &ResourceMeta{
 		Name:        name,
 		Namespace:   opt.Get().Global.Namespace,
 		Labels:      labels,
 		Annotations: annotations,
 	}
-	sshKeyMeta := SSHkeyMeta{
+	sshKeyMeta := &SSHkeyMeta{
 		SshConfigMapName: name,
 		PrivateKeyPath:   util.PrivateKeyPath(name),
 	}
 
-	ports := map[string]int{}
+	podMeta.Ports = map[string]int{}
 	if exposePorts != "" {
 		portPairs := strings.Split(exposePorts, ",")
 		for _, exposePort := range portPairs {
@@ -47,63 +170,233 @@ func (k *Kubernetes) GetOrCreateShadow(name string, labels, annotations, envs ma
 				log.Warn().Err(err).Msgf("invalid port")
 			} else {
 				// TODO: assume port using http protocol for istio constraint, should support user-defined protocol
-				name = fmt.Sprintf("http-%d", port)
+				portName := fmt.Sprintf("http-%d", port)
 				if n, exists := portNameDict[port]; exists {
-					name = n
+					portName = n
 				}
-				ports[name] = port
+				podMeta.Ports[portName] = port
 			}
 		}
 	}
+	return &podMeta, sshKeyMeta, nil
+}
 
-	if opt.Store.Component == util.ComponentConnect && opt.Get().Connect.ShareShadow {
-		pod, generator, err2 := k.tryGetExistingShadows(&resourceMeta, &sshKeyMeta)
-		if err2 != nil {
-			return "", "", "", err2
-		}
-		if pod != nil && generator != nil {
-			return pod.Status.PodIP, pod.Name, generator.PrivateKeyPath, nil
-		}
-	}
-
-	podMeta := PodMetaAndSpec{
-		Meta:  &resourceMeta,
-		Image: opt.Get().Global.Image,
-		Envs:  envs,
-		Ports: ports,
-	}
-	return k.createShadow(&podMeta, &sshKeyMeta)
+// shadowCreation resources created by one shadow creation, only they are rolled back when it fails
+type shadowCreation struct {
+	configMapCreated  bool
+	shadowCreated     bool
+	privateKeyCreated bool
+	heartBeats        []*time.Ticker
 }
 
 func (k *Kubernetes) createShadow(metaAndSpec *PodMetaAndSpec, sshKeyMeta *SSHkeyMeta) (
 	podIP string, podName string, privateKeyPath string, err error) {
 
-	generator, err := util.Generate(sshKeyMeta.PrivateKeyPath)
+	// private key is saved after config map created, a key file of same name may belong to another session
+	generator, err := util.GenerateKeyPair(sshKeyMeta.PrivateKeyPath)
 	if err != nil {
 		return
 	}
 
+	created := &shadowCreation{}
 	configMap, err := k.createConfigMapWithSshKey(metaAndSpec.Meta.Labels, sshKeyMeta.SshConfigMapName, metaAndSpec.Meta.Namespace, generator)
 	if err != nil {
+		logShadowExists(err, "config map", sshKeyMeta.SshConfigMapName, metaAndSpec.Meta.Namespace)
+		k.rollbackShadow(metaAndSpec.Meta, sshKeyMeta, created)
+		err = withNamespaceTerminatingHint(err, metaAndSpec.Meta.Namespace)
 		return
 	}
 	log.Info().Msgf("Successful create config map %v", configMap.Name)
+	created.configMapCreated = true
+	created.heartBeats = append(created.heartBeats,
+		SetupHeartBeat(sshKeyMeta.SshConfigMapName, metaAndSpec.Meta.Namespace, k.UpdateConfigMapHeartBeat))
+	if err = generator.SavePrivateKey(); err != nil {
+		k.rollbackShadow(metaAndSpec.Meta, sshKeyMeta, created)
+		return
+	}
+	created.privateKeyCreated = true
 
-	pod, err := k.createAndGetPod(metaAndSpec, sshKeyMeta.SshConfigMapName)
+	pod, err := k.createAndGetPod(metaAndSpec, sshKeyMeta.SshConfigMapName, created)
 	if err != nil {
+		// an existing shadow with same name belongs to someone else, leave it alone
+		logShadowExists(err, "shadow", metaAndSpec.Meta.Name, metaAndSpec.Meta.Namespace)
+		k.rollbackShadow(metaAndSpec.Meta, sshKeyMeta, created)
+		err = withNamespaceTerminatingHint(err, metaAndSpec.Meta.Namespace)
 		return
 	}
 	return pod.Status.PodIP, pod.Name, generator.PrivateKeyPath, nil
 }
 
-func (k *Kubernetes) createAndGetPod(metaAndSpec *PodMetaAndSpec, sshcm string) (*coreV1.Pod, error) {
+// removeStaleShadow remove existing shadow which is not running or older than reuse ttl
+func (k *Kubernetes) removeStaleShadow(meta *ResourceMeta) error {
+	var podList []coreV1.Pod
+	if opt.Get().Global.UseShadowDeployment {
+		app, err := k.GetDeployment(meta.Name, meta.Namespace)
+		if err != nil {
+			return nil
+		}
+		pods, err := k.GetPodsByLabel(app.Spec.Selector.MatchLabels, meta.Namespace)
+		if err != nil {
+			return err
+		}
+		podList = pods.Items
+	} else {
+		pod, err := k.GetPod(meta.Name, meta.Namespace)
+		if err != nil {
+			return nil
+		}
+		podList = []coreV1.Pod{*pod}
+	}
+	ttl := time.Duration(opt.Get().Exchange.ReuseShadowTtl) * time.Minute
+	if len(filterRunningPods(podList)) == 1 && time.Since(podList[0].CreationTimestamp.Time) < ttl {
+		return nil
+	}
+	log.Info().Msgf("Shadow %s is not running or exceeded reuse ttl, recreating it", meta.Name)
+	if opt.Get().Global.UseShadowDeployment {
+		_ = k.RemoveDeployment(meta.Name, meta.Namespace)
+	} else {
+		_ = k.RemovePod(meta.Name, meta.Namespace)
+	}
+	if err := k.RemoveConfigMap(meta.Name, meta.Namespace); err != nil && !k8sErrors.IsNotFound(err) {
+		return err
+	}
+	for _, pod := range podList {
+		if _, err := k.WaitPodTerminate(pod.Name, meta.Namespace); err != nil && !k8sErrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeExistingShadow remove shadow with the same name, or with the same role and target config, left by previous run,
+// target label is not compared since it's randomly generated by each run
+func (k *Kubernetes) removeExistingShadow(meta *ResourceMeta) error {
+	var selector map[string]string
+	if meta.Labels[util.KtRole] != "" && meta.Annotations[util.KtConfig] != "" {
+		selector = map[string]string{util.ControlBy: util.KubernetesToolkit, util.KtRole: meta.Labels[util.KtRole]}
+	}
+	var podsToWait []string
+	names := []string{meta.Name}
+	if opt.Get().Global.UseShadowDeployment {
+		if selector != nil {
+			if apps, err := k.GetDeploymentsByLabel(selector, meta.Namespace); err == nil {
+				for _, app := range apps.Items {
+					if isSameTarget(app.Annotations, meta) && !util.Contains(names, app.Name) {
+						names = append(names, app.Name)
+					}
+				}
+			}
+		}
+		for _, name := range names {
+			app, err := k.GetDeployment(name, meta.Namespace)
+			if err != nil {
+				continue
+			}
+			if pods, err2 := k.GetPodsByLabel(app.Spec.Selector.MatchLabels, meta.Namespace); err2 == nil {
+				for _, pod := range pods.Items {
+					podsToWait = append(podsToWait, pod.Name)
+				}
+			}
+			if err = k.RemoveDeployment(name, meta.Namespace); err != nil && !k8sErrors.IsNotFound(err) {
+				return err
+			}
+			log.Info().Msgf("Removed existing shadow deployment %s", name)
+		}
+	} else {
+		if selector != nil {
+			if pods, err := k.GetPodsByLabel(selector, meta.Namespace); err == nil {
+				for _, pod := range pods.Items {
+					if isSameTarget(pod.Annotations, meta) && !util.Contains(names, pod.Name) {
+						names = append(names, pod.Name)
+					}
+				}
+			}
+		}
+		for _, name := range names {
+			if err := k.RemovePod(name, meta.Namespace); err != nil {
+				if k8sErrors.IsNotFound(err) {
+					continue
+				}
+				return err
+			}
+			log.Info().Msgf("Removed existing shadow pod %s", name)
+			podsToWait = append(podsToWait, name)
+		}
+	}
+	// config map of shadow has the same name as shadow itself
+	for _, name := range names {
+		if err := k.RemoveConfigMap(name, meta.Namespace); err != nil {
+			if k8sErrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		log.Info().Msgf("Removed existing shadow config map %s", name)
+	}
+	for _, name := range podsToWait {
+		if _, err := k.WaitPodTerminate(name, meta.Namespace); err != nil && !k8sErrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// isSameTarget whether shadow left by previous run is created for the same target as current one
+func isSameTarget(annotations map[string]string, meta *ResourceMeta) bool {
+	return annotations[util.KtConfig] == meta.Annotations[util.KtConfig]
+}
+
+// logShadowExists make it explicit when shadow creation failed because of resource left by previous run
+func logShadowExists(err error, kind, name, namespace string) {
+	if k8sErrors.IsAlreadyExists(err) {
+		log.Warn().Msgf("Shadow %s %s already exists in namespace %s, "+
+			"use '--forceDeleteExisting' to remove it before creating", kind, name, namespace)
+	}
+}
+
+// rollbackShadow stop heartbeats and remove resources created when shadow creation failed,
+// resources not created by current call (e.g. create failed as already exists) are kept
+func (k *Kubernetes) rollbackShadow(meta *ResourceMeta, sshKeyMeta *SSHkeyMeta, created *shadowCreation) {
+	log.Info().Msgf("Failed to create shadow %s, cleaning up created resources", meta.Name)
+	for _, ticker := range created.heartBeats {
+		ticker.Stop()
+	}
+	var err error
+	if created.shadowCreated {
+		if opt.Get().Global.UseShadowDeployment {
+			err = k.RemoveDeployment(meta.Name, meta.Namespace)
+		} else {
+			err = k.RemovePod(meta.Name, meta.Namespace)
+		}
+		if err != nil && !k8sErrors.IsNotFound(err) {
+			log.Warn().Err(err).Msgf("Failed to remove shadow %s", meta.Name)
+		}
+	}
+	if created.configMapCreated {
+		if err = k.RemoveConfigMap(sshKeyMeta.SshConfigMapName, meta.Namespace); err != nil && !k8sErrors.IsNotFound(err) {
+			log.Warn().Err(err).Msgf("Failed to remove config map %s", sshKeyMeta.SshConfigMapName)
+		}
+	}
+	if created.privateKeyCreated {
+		if err = os.Remove(sshKeyMeta.PrivateKeyPath); err != nil && !os.IsNotExist(err) {
+			log.Debug().Err(err).Msgf("Failed to remove private key %s", sshKeyMeta.PrivateKeyPath)
+		}
+	}
+	// nothing left for cleanup on exit
+	opt.Store.Shadow = util.Remove(opt.Store.Shadow, meta.Name)
+}
+
+func (k *Kubernetes) createAndGetPod(metaAndSpec *PodMetaAndSpec, sshcm string, created *shadowCreation) (*coreV1.Pod, error) {
+	name, namespace := metaAndSpec.Meta.Name, metaAndSpec.Meta.Namespace
 	if opt.Get().Global.UseShadowDeployment {
 		if err := k.createShadowDeployment(metaAndSpec, sshcm); err != nil {
 			return nil, err
 		}
-		log.Info().Msgf("Creating shadow deployment %s in namespace %s", metaAndSpec.Meta.Name, metaAndSpec.Meta.Namespace)
+		created.shadowCreated = true
+		created.heartBeats = append(created.heartBeats, SetupHeartBeat(name, namespace, k.UpdateDeploymentHeartBeat))
+		log.Info().Msgf("Creating shadow deployment %s in namespace %s", name, namespace)
 		delete(metaAndSpec.Meta.Labels, util.ControlBy)
-		pods, err := k.WaitPodsReady(metaAndSpec.Meta.Labels, metaAndSpec.Meta.Namespace, opt.Get().Global.PodCreationTimeout)
+		pods, err := k.WaitPodsReady(metaAndSpec.Meta.Labels, namespace, opt.Get().Global.PodCreationTimeout)
 		if err != nil {
 			return nil, err
 		}
@@ -112,9 +405,23 @@ func (k *Kubernetes) createAndGetPod(metaAndSpec *PodMetaAndSpec, sshcm string)
 		if err := k.createShadowPod(metaAndSpec, sshcm); err != nil {
 			return nil, err
 		}
-		log.Info().Msgf("Deploying shadow pod %s in namespace %s", metaAndSpec.Meta.Name, metaAndSpec.Meta.Namespace)
-		return k.WaitPodReady(metaAndSpec.Meta.Name, metaAndSpec.Meta.Namespace, opt.Get().Global.PodCreationTimeout)
+		created.shadowCreated = true
+		created.heartBeats = append(created.heartBeats, SetupHeartBeat(name, namespace, k.UpdatePodHeartBeat))
+		log.Info().Msgf("Deploying shadow pod %s in namespace %s", name, namespace)
+		return k.waitShadowReady(name, namespace, opt.Get().Global.PodCreationTimeout)
+	}
+}
+
+// waitShadowReady wait for shadow pod running, and report its progress meanwhile
+func (k *Kubernetes) waitShadowReady(name, namespace string, timeoutSec int) (*coreV1.Pod, error) {
+	pod, err := WaitPod(fmt.Sprintf("pod %s", name), timeoutSec, func() (*coreV1.Pod, error) {
+		return k.GetPod(name, namespace)
+	}, isShadowRunning)
+	if err != nil {
+		return nil, err
 	}
+	log.Info().Msgf("Pod %s is ready", pod.Name)
+	return pod, nil
 }
 
 func filterRunningPods(pods []coreV1.Pod) []coreV1.Pod {
@@ -135,7 +442,6 @@ func (k *Kubernetes) createShadowDeployment(metaAndSpec *PodMetaAndSpec, sshcm s
 		Create(context.TODO(), deployment, metav1.CreateOptions{}); err != nil {
 		return err
 	}
-	SetupHeartBeat(metaAndSpec.Meta.Name, metaAndSpec.Meta.Namespace, k.UpdateDeploymentHeartBeat)
 	return nil
 }
 
@@ -147,7 +453,6 @@ func (k *Kubernetes) createShadowPod(metaAndSpec *PodMetaAndSpec, sshcm string)
 		Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
 		return err
 	}
-	SetupHeartBeat(metaAndSpec.Meta.Name, metaAndSpec.Meta.Namespace, k.UpdatePodHeartBeat)
 	return nil
 }
 
@@ -163,7 +468,7 @@ func (k *Kubernetes) appendSshVolume(podSpec *coreV1.PodSpec, sshcm string) {
 	}
 }
 
-func (k *Kubernetes) tryGetExistingShadows(resourceMeta *ResourceMeta, sshKeyMeta *SSHkeyMeta) (*coreV1.Pod, *util.SSHGenerator, error) {
+func (k *Kubernetes) tryGetExistingShadows(resourceMeta *ResourceMeta, sshKeyMeta *SSHkeyMeta, increaseRef bool) (*coreV1.Pod, *util.SSHGenerator, error) {
 	var app *appV1.Deployment
 	var pod *coreV1.Pod
 	if opt.Get().Global.UseShadowDeployment {
@@ -213,7 +518,9 @@ func (k *Kubernetes) tryGetExistingShadows(resourceMeta *ResourceMeta, sshKeyMet
 		return nil, nil, err
 	}
 
-	if opt.Get().Global.UseShadowDeployment {
+	if !increaseRef {
+		log.Info().Msgf("Found shadow %s, reuse it", resourceMeta.Name)
+	} else if opt.Get().Global.UseShadowDeployment {
 		log.Info().Msgf("Found shadow daemon deployment, reuse it")
 		if err = k.IncreaseDeploymentRef(resourceMeta.Name, resourceMeta.Namespace); err != nil {
 			return nil, nil, err
@@ -247,3 +554,83 @@ func getSSHVolume(volume string) coreV1.Volume {
 	return sshVolume
 }
 
+// checkPriorityClass make sure specified priority class exists, otherwise shadow pod would be rejected by api server
+func (k *Kubernetes) checkPriorityClass(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := k.Clientset.SchedulingV1().PriorityClasses().Get(context.TODO(), name, metav1.GetOptions{}); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return fmt.Errorf("priority class '%s' not exists", name)
+		} else if k8sErrors.IsForbidden(err) {
+			// priority class is cluster scoped, user may not be allowed to read it
+			log.Warn().Msgf("No permission to check priority class '%s', assuming it exists", name)
+			return nil
+		}
+		return fmt.Errorf("failed to check priority class '%s': %s", name, err)
+	}
+	return nil
+}
+
+// ParseHostAliases parse host aliases in '<hostname>=<ip>' format, use ',' separated, hostnames of same ip are grouped
+func ParseHostAliases(text string) ([]coreV1.HostAlias, error) {
+	var aliases []coreV1.HostAlias
+	if text == "" {
+		return aliases, nil
+	}
+	for _, item := range strings.Split(text, ",") {
+		host, ip, found := strings.Cut(strings.TrimSpace(item), "=")
+		if !found || host == "" || !isValidHostname(host) {
+			return nil, fmt.Errorf("invalid host alias '%s', should be in '<hostname>=<ip>' format", item)
+		}
+		if net.ParseIP(ip) == nil {
+			return nil, fmt.Errorf("invalid ip address '%s' of host alias '%s'", ip, host)
+		}
+		aliases = mergeHostAliases(aliases, []coreV1.HostAlias{{IP: ip, Hostnames: []string{host}}})
+	}
+	return aliases, nil
+}
+
+// mergeHostAliases append extra host aliases to base ones, hostname in extra overrides the same one in base
+func mergeHostAliases(base, extra []coreV1.HostAlias) []coreV1.HostAlias {
+	overridden := map[string]bool{}
+	for _, alias := range extra {
+		for _, host := range alias.Hostnames {
+			overridden[host] = true
+		}
+	}
+	var merged []coreV1.HostAlias
+	appendHost := func(ip, host string) {
+		for i := range merged {
+			if merged[i].IP == ip {
+				if !util.Contains(merged[i].Hostnames, host) {
+					merged[i].Hostnames = append(merged[i].Hostnames, host)
+				}
+				return
+			}
+		}
+		merged = append(merged, coreV1.HostAlias{IP: ip, Hostnames: []string{host}})
+	}
+	for _, alias := range base {
+		for _, host := range alias.Hostnames {
+			if !overridden[host] {
+				appendHost(alias.IP, host)
+			}
+		}
+	}
+	for _, alias := range extra {
+		for _, host := range alias.Hostnames {
+			appendHost(alias.IP, host)
+		}
+	}
+	return merged
+}
+
+func isValidHostname(host string) bool {
+	for _, c := range host {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
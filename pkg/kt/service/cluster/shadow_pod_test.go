@@ -0,0 +1,238 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	schedulingV1 "k8s.io/api/scheduling/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	k8sTesting "k8s.io/client-go/testing"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKubernetes_rollbackShadow(t *testing.T) {
+	k := &Kubernetes{
+		Clientset: testclient.NewSimpleClientset(&coreV1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "shadow-a", Namespace: "default"},
+		}),
+	}
+	keyPath := filepath.Join(t.TempDir(), "shadow-a.key")
+	require.Nil(t, os.WriteFile(keyPath, []byte("key"), 0400))
+	opt.Store.Shadow = "shadow-b,shadow-a"
+
+	ticker := time.NewTicker(time.Millisecond)
+
+	// pod not found should not stop removing other resources
+	k.rollbackShadow(&ResourceMeta{Name: "shadow-a", Namespace: "default"},
+		&SSHkeyMeta{SshConfigMapName: "shadow-a", PrivateKeyPath: keyPath}, &shadowCreation{
+			configMapCreated: true, shadowCreated: true, privateKeyCreated: true, heartBeats: []*time.Ticker{ticker}})
+
+	_, err := k.Clientset.CoreV1().ConfigMaps("default").Get(context.TODO(), "shadow-a", metav1.GetOptions{})
+	require.True(t, k8sErrors.IsNotFound(err))
+	_, err = os.Stat(keyPath)
+	require.True(t, os.IsNotExist(err))
+	require.Equal(t, "shadow-b", opt.Store.Shadow)
+	// drain tick fired before stop
+	select {
+	case <-ticker.C:
+	default:
+	}
+	select {
+	case <-ticker.C:
+		require.Fail(t, "heartbeat should be stopped")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestKubernetes_createShadow_alreadyExists(t *testing.T) {
+	existing := &coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "shadow-a", Namespace: "default"}}
+	existingCm := &coreV1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "shadow-b", Namespace: "default"}}
+	k := &Kubernetes{Clientset: testclient.NewSimpleClientset(existing, existingCm)}
+	dir := t.TempDir()
+
+	// shadow pod already exists, only config map created by this call is removed
+	_, _, _, err := k.createShadow(&PodMetaAndSpec{
+		Meta: &ResourceMeta{Name: "shadow-a", Namespace: "default", Labels: map[string]string{}},
+	}, &SSHkeyMeta{SshConfigMapName: "shadow-a", PrivateKeyPath: filepath.Join(dir, "shadow-a.key")})
+	require.True(t, k8sErrors.IsAlreadyExists(err))
+	_, err = k.Clientset.CoreV1().Pods("default").Get(context.TODO(), "shadow-a", metav1.GetOptions{})
+	require.Nil(t, err)
+	_, err = k.Clientset.CoreV1().ConfigMaps("default").Get(context.TODO(), "shadow-a", metav1.GetOptions{})
+	require.True(t, k8sErrors.IsNotFound(err))
+	_, err = os.Stat(filepath.Join(dir, "shadow-a.key"))
+	require.True(t, os.IsNotExist(err), "private key saved by this call should be removed")
+
+	// config map already exists, neither it nor the shadow of same name is touched
+	_, err = k.Clientset.CoreV1().Pods("default").Create(context.TODO(),
+		&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "shadow-b", Namespace: "default"}}, metav1.CreateOptions{})
+	require.Nil(t, err)
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "shadow-b.key"), []byte("key of other session"), 0400))
+	_, _, _, err = k.createShadow(&PodMetaAndSpec{
+		Meta: &ResourceMeta{Name: "shadow-b", Namespace: "default", Labels: map[string]string{}},
+	}, &SSHkeyMeta{SshConfigMapName: "shadow-b", PrivateKeyPath: filepath.Join(dir, "shadow-b.key")})
+	require.True(t, k8sErrors.IsAlreadyExists(err))
+	_, err = k.Clientset.CoreV1().Pods("default").Get(context.TODO(), "shadow-b", metav1.GetOptions{})
+	require.Nil(t, err)
+	_, err = k.Clientset.CoreV1().ConfigMaps("default").Get(context.TODO(), "shadow-b", metav1.GetOptions{})
+	require.Nil(t, err)
+	key, err := os.ReadFile(filepath.Join(dir, "shadow-b.key"))
+	require.Nil(t, err)
+	require.Equal(t, "key of other session", string(key), "private key of other session should be kept")
+}
+
+func TestKubernetes_GetOrCreateShadow_reuseHeartBeat(t *testing.T) {
+	k := &Kubernetes{
+		Clientset: testclient.NewSimpleClientset(&coreV1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "shadow-a", Namespace: "default", CreationTimestamp: metav1.Now()},
+			Status:     coreV1.PodStatus{Phase: coreV1.PodRunning, PodIP: "10.0.0.1"},
+		}, &coreV1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "shadow-a", Namespace: "default"},
+			Data:       map[string]string{util.SshAuthKey: "pub", util.SshAuthPrivateKey: "key"},
+		}),
+	}
+	keyDir := util.KtKeyDir
+	util.KtKeyDir = t.TempDir()
+	var heartBeatNames []string
+	setupHeartBeat = func(name, namespace string, updater func(string, string)) *time.Ticker {
+		heartBeatNames = append(heartBeatNames, name)
+		return nil
+	}
+	opt.Store.Component = util.ComponentExchange
+	opt.Get().Global.Namespace = "default"
+	opt.Get().Exchange.ReuseShadow = true
+	opt.Get().Exchange.ReuseShadowTtl = 60
+	opt.Get().Exchange.Mode = util.ExchangeModeSelector
+	defer func() {
+		util.KtKeyDir = keyDir
+		setupHeartBeat = SetupHeartBeat
+		opt.Store.Component = ""
+		opt.Store.Shadow = ""
+		opt.Get().Exchange.ReuseShadow = false
+		opt.Get().Exchange.ReuseShadowTtl = 0
+		opt.Get().Exchange.Mode = ""
+	}()
+
+	// exposed ports should not change name of shadow whose heartbeat is started
+	podIp, podName, _, err := k.GetOrCreateShadow("shadow-a", map[string]string{}, map[string]string{},
+		map[string]string{}, "8080:80,9090", map[int]string{}, nil)
+	require.Nil(t, err)
+	require.Equal(t, "10.0.0.1", podIp)
+	require.Equal(t, "shadow-a", podName)
+	require.Equal(t, []string{"shadow-a", "shadow-a"}, heartBeatNames)
+}
+
+func TestKubernetes_removeStaleShadow(t *testing.T) {
+	k := &Kubernetes{
+		Clientset: testclient.NewSimpleClientset(&coreV1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "shadow-a", Namespace: "default", CreationTimestamp: metav1.Now()},
+			Status:     coreV1.PodStatus{Phase: coreV1.PodRunning},
+		}),
+	}
+	opt.Get().Exchange.ReuseShadowTtl = 60
+
+	// running shadow within ttl should be kept
+	require.Nil(t, k.removeStaleShadow(&ResourceMeta{Name: "shadow-a", Namespace: "default"}))
+	_, err := k.GetPod("shadow-a", "default")
+	require.Nil(t, err)
+
+	// shadow not exist is ok
+	require.Nil(t, k.removeStaleShadow(&ResourceMeta{Name: "shadow-b", Namespace: "default"}))
+}
+
+func TestKubernetes_removeExistingShadow(t *testing.T) {
+	// target label of shadow created by previous run is different from current one
+	shadowLabels := map[string]string{util.ControlBy: util.KubernetesToolkit,
+		util.KtRole: util.RoleExchangeShadow, util.KtTarget: "previous"}
+	k := &Kubernetes{
+		Clientset: testclient.NewSimpleClientset(&coreV1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "shadow-old", Namespace: "default", Labels: shadowLabels,
+				Annotations: map[string]string{util.KtConfig: "service=web"}},
+		}, &coreV1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "shadow-old", Namespace: "default", Labels: shadowLabels},
+		}, &coreV1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "shadow-a", Namespace: "default"},
+		}, &coreV1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "shadow-other", Namespace: "default", Labels: shadowLabels,
+				Annotations: map[string]string{util.KtConfig: "service=api"}},
+		}, &coreV1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "shadow-other", Namespace: "default", Labels: shadowLabels},
+		}),
+	}
+	opt.Get().Global.UseShadowDeployment = false
+
+	require.Nil(t, k.removeExistingShadow(&ResourceMeta{Name: "shadow-a", Namespace: "default",
+		Labels:      map[string]string{util.KtRole: util.RoleExchangeShadow, util.KtTarget: "current"},
+		Annotations: map[string]string{util.KtConfig: "service=web"}}))
+	for _, name := range []string{"shadow-a", "shadow-old"} {
+		_, err := k.GetPod(name, "default")
+		require.True(t, k8sErrors.IsNotFound(err), "shadow %s should be removed", name)
+	}
+	_, err := k.GetConfigMap("shadow-old", "default")
+	require.True(t, k8sErrors.IsNotFound(err))
+	_, err = k.GetPod("shadow-other", "default")
+	require.Nil(t, err, "shadow of other target should be kept")
+	_, err = k.GetConfigMap("shadow-other", "default")
+	require.Nil(t, err, "config map of other target should be kept")
+}
+
+func TestKubernetes_checkPriorityClass(t *testing.T) {
+	k := &Kubernetes{
+		Clientset: testclient.NewSimpleClientset(&schedulingV1.PriorityClass{
+			ObjectMeta: metav1.ObjectMeta{Name: "kt-low"},
+			Value:      -10,
+		}),
+	}
+	require.Nil(t, k.checkPriorityClass(""))
+	require.Nil(t, k.checkPriorityClass("kt-low"))
+	require.NotNil(t, k.checkPriorityClass("kt-high"))
+
+	client := testclient.NewSimpleClientset()
+	client.PrependReactor("get", "priorityclasses", func(action k8sTesting.Action) (bool, runtime.Object, error) {
+		return true, nil, k8sErrors.NewForbidden(schedulingV1.Resource("priorityclasses"), "kt-low", fmt.Errorf("denied"))
+	})
+	k = &Kubernetes{Clientset: client}
+	require.Nil(t, k.checkPriorityClass("kt-low"), "forbidden to read priority class should not stop shadow creation")
+	client.PrependReactor("get", "priorityclasses", func(action k8sTesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("connection refused")
+	})
+	require.NotNil(t, k.checkPriorityClass("kt-low"))
+}
+
+func TestParseHostAliases(t *testing.T) {
+	aliases, err := ParseHostAliases("db.internal=10.0.0.5, cache.internal=10.0.0.5,mq=10.0.0.6")
+	require.Nil(t, err)
+	require.Equal(t, []coreV1.HostAlias{
+		{IP: "10.0.0.5", Hostnames: []string{"db.internal", "cache.internal"}},
+		{IP: "10.0.0.6", Hostnames: []string{"mq"}},
+	}, aliases)
+	_, err = ParseHostAliases("db.internal")
+	require.NotNil(t, err)
+	_, err = ParseHostAliases("=10.0.0.5")
+	require.NotNil(t, err)
+	_, err = ParseHostAliases("db.internal=10.0.0")
+	require.NotNil(t, err)
+	_, err = ParseHostAliases("db internal=10.0.0.5")
+	require.NotNil(t, err)
+}
+
+func TestMergeHostAliases(t *testing.T) {
+	origin := []coreV1.HostAlias{
+		{IP: "10.0.0.1", Hostnames: []string{"db.internal", "cache.internal"}},
+		{IP: "10.0.0.2", Hostnames: []string{"mq"}},
+	}
+	extra := []coreV1.HostAlias{{IP: "10.0.0.5", Hostnames: []string{"db.internal"}}}
+	require.Equal(t, []coreV1.HostAlias{
+		{IP: "10.0.0.1", Hostnames: []string{"cache.internal"}},
+		{IP: "10.0.0.2", Hostnames: []string{"mq"}},
+		{IP: "10.0.0.5", Hostnames: []string{"db.internal"}},
+	}, mergeHostAliases(origin, extra), "user specified alias should override origin one")
+}
@@ -0,0 +1,42 @@
+package cluster
+
+import (
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	coreV1 "k8s.io/api/core/v1"
+)
+
+// isShadowRunning report progress of shadow pod according to its status, then check whether it's running
+func isShadowRunning(pod *coreV1.Pod) (bool, error) {
+	if phase, detail := getShadowPhase(pod); phase != "" {
+		util.ReportProgress(pod.Name, phase, detail)
+	}
+	return isPodRunning(pod)
+}
+
+// getShadowPhase infer progress phase of shadow pod from its conditions and container statuses
+func getShadowPhase(pod *coreV1.Pod) (string, string) {
+	if pod.DeletionTimestamp != nil {
+		return "", ""
+	}
+	for _, c := range pod.Status.ContainerStatuses {
+		if c.State.Running != nil {
+			return util.PhaseStarting, ""
+		}
+	}
+	if pod.Status.Phase == coreV1.PodRunning {
+		return util.PhaseStarting, ""
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == coreV1.PodScheduled && c.Status == coreV1.ConditionTrue {
+			for _, cs := range pod.Status.ContainerStatuses {
+				if cs.State.Waiting != nil && cs.State.Waiting.Reason != "" {
+					return util.PhasePullingImage, cs.State.Waiting.Reason
+				}
+			}
+			return util.PhasePullingImage, ""
+		} else if c.Type == coreV1.PodScheduled && c.Reason != "" {
+			return util.PhaseScheduling, c.Reason
+		}
+	}
+	return util.PhaseScheduling, ""
+}
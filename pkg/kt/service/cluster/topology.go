@@ -0,0 +1,44 @@
+package cluster
+
+import (
+	"fmt"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strconv"
+	"strings"
+)
+
+// ParseTopologySpread parse topology spread constraints in '<topologyKey>:<maxSkew>[:<whenUnsatisfiable>]' format,
+// use ',' separated, pods with specified labels are counted for skew
+func ParseTopologySpread(text string, labels map[string]string) ([]coreV1.TopologySpreadConstraint, error) {
+	var constraints []coreV1.TopologySpreadConstraint
+	if text == "" {
+		return constraints, nil
+	}
+	for _, item := range strings.Split(text, ",") {
+		parts := strings.Split(strings.TrimSpace(item), ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid topology spread '%s', should be in "+
+				"'<topologyKey>:<maxSkew>[:<whenUnsatisfiable>]' format", item)
+		}
+		maxSkew, err := strconv.Atoi(parts[1])
+		if err != nil || maxSkew <= 0 {
+			return nil, fmt.Errorf("max skew of topology spread '%s' should be a positive integer", item)
+		}
+		whenUnsatisfiable := coreV1.DoNotSchedule
+		if len(parts) == 3 {
+			whenUnsatisfiable = coreV1.UnsatisfiableConstraintAction(parts[2])
+			if whenUnsatisfiable != coreV1.DoNotSchedule && whenUnsatisfiable != coreV1.ScheduleAnyway {
+				return nil, fmt.Errorf("invalid action '%s' of topology spread '%s', supported are %s, %s",
+					parts[2], item, coreV1.DoNotSchedule, coreV1.ScheduleAnyway)
+			}
+		}
+		constraints = append(constraints, coreV1.TopologySpreadConstraint{
+			MaxSkew:           int32(maxSkew),
+			TopologyKey:       parts[0],
+			WhenUnsatisfiable: whenUnsatisfiable,
+			LabelSelector:     &metav1.LabelSelector{MatchLabels: labels},
+		})
+	}
+	return constraints, nil
+}
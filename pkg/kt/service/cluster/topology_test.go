@@ -0,0 +1,59 @@
+package cluster
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func TestParseTopologySpread(t *testing.T) {
+	labels := map[string]string{"app": "demo"}
+	constraints, err := ParseTopologySpread("topology.kubernetes.io/zone:1, kubernetes.io/hostname:2:ScheduleAnyway", labels)
+	require.Nil(t, err)
+	require.Equal(t, []coreV1.TopologySpreadConstraint{
+		{MaxSkew: 1, TopologyKey: "topology.kubernetes.io/zone", WhenUnsatisfiable: coreV1.DoNotSchedule,
+			LabelSelector: &metav1.LabelSelector{MatchLabels: labels}},
+		{MaxSkew: 2, TopologyKey: "kubernetes.io/hostname", WhenUnsatisfiable: coreV1.ScheduleAnyway,
+			LabelSelector: &metav1.LabelSelector{MatchLabels: labels}},
+	}, constraints)
+	constraints, err = ParseTopologySpread("", labels)
+	require.Nil(t, err)
+	require.Empty(t, constraints)
+	_, err = ParseTopologySpread("topology.kubernetes.io/zone", labels)
+	require.NotNil(t, err, "max skew is required")
+	_, err = ParseTopologySpread("topology.kubernetes.io/zone:0", labels)
+	require.NotNil(t, err, "max skew should be positive")
+	_, err = ParseTopologySpread("topology.kubernetes.io/zone:1:Never", labels)
+	require.NotNil(t, err, "unknown action should fail")
+}
+
+func TestKubernetes_newShadowMeta_topology(t *testing.T) {
+	k := &Kubernetes{Clientset: testclient.NewSimpleClientset()}
+	opt.Get().Global.AllowInjection = true
+	defer func() {
+		opt.Get().Global.TopologySpread = ""
+		opt.Get().Global.CopyTopologySpread = false
+		opt.Get().Global.AllowInjection = false
+	}()
+	origin := coreV1.TopologySpreadConstraint{MaxSkew: 1, TopologyKey: "kubernetes.io/hostname",
+		WhenUnsatisfiable: coreV1.ScheduleAnyway}
+	target := &coreV1.PodSpec{TopologySpreadConstraints: []coreV1.TopologySpreadConstraint{origin}}
+
+	podMeta, _, err := k.newShadowMeta("shadow", map[string]string{}, map[string]string{}, map[string]string{},
+		"", map[int]string{}, target)
+	require.Nil(t, err)
+	require.Empty(t, podMeta.Topology, "constraints of target should not be copied by default")
+
+	opt.Get().Global.TopologySpread = "topology.kubernetes.io/zone:1"
+	opt.Get().Global.CopyTopologySpread = true
+	podMeta, _, err = k.newShadowMeta("shadow", map[string]string{"app": "demo"}, map[string]string{},
+		map[string]string{}, "", map[int]string{}, target)
+	require.Nil(t, err)
+	require.Len(t, podMeta.Topology, 2)
+	require.Equal(t, map[string]string{"app": "demo"}, podMeta.Topology[0].LabelSelector.MatchLabels)
+	require.Equal(t, origin, podMeta.Topology[1])
+	require.Equal(t, podMeta.Topology, createPod(podMeta).Spec.TopologySpreadConstraints)
+}
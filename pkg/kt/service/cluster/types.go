@@ -2,9 +2,12 @@ package cluster
 
 import (
 	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"io"
 	appV1 "k8s.io/api/apps/v1"
+	batchV1 "k8s.io/api/batch/v1"
 	coreV1 "k8s.io/api/core/v1"
 	extV1 "k8s.io/api/extensions/v1beta1"
+	netV1 "k8s.io/api/networking/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -14,33 +17,53 @@ type KubernetesInterface interface {
 	GetPodsByLabel(labels map[string]string, namespace string) (*coreV1.PodList, error)
 	UpdatePod(pod *coreV1.Pod) (*coreV1.Pod, error)
 	RemovePod(name, namespace string) error
-	GetOrCreateShadow(name string, labels, annotations, envs map[string]string, portsToExpose string, portNameDict map[int]string) (string, string, string, error)
+	GetOrCreateShadow(name string, labels, annotations, envs map[string]string, portsToExpose string, portNameDict map[int]string,
+		target *coreV1.PodSpec) (string, string, string, error)
+	RenderShadow(name string, labels, annotations, envs map[string]string, portsToExpose string, portNameDict map[int]string,
+		target *coreV1.PodSpec) ([]any, error)
 	CreateRouterPod(name string, labels, annotations map[string]string, ports map[int]int) (*coreV1.Pod, error)
 	CreateRectifierPod(name string) (*coreV1.Pod, error)
+	CreateOriginCopyPod(name string, app *appV1.Deployment) (*coreV1.Pod, error)
+	CreateConnectGateway(name string, replicas int32, envs map[string]string) error
+	GetConnectGatewayPod(namespace string) (string, string, error)
 	UpdatePodHeartBeat(name, namespace string)
 	WaitPodReady(name, namespace string, timeoutSec int) (*coreV1.Pod, error)
 	WaitPodTerminate(name, namespace string) (*coreV1.Pod, error)
 	WatchPod(name, namespace string, fAdd, fDel, fMod func(*coreV1.Pod))
 	ExecInPod(containerName, podName, namespace string, cmd ...string) (string, string, error)
-	AddEphemeralContainer(containerName, podName string, envs map[string]string) (string, error)
+	TailPodLogs(containerName, podName, namespace string) (io.ReadCloser, error)
+	AddEphemeralContainer(containerName, podName, targetContainer string, envs map[string]string) (string, error)
 	RemoveEphemeralContainer(containerName, podName string, namespace string) error
-	IncreasePodRef(name ,namespace string) error
+	IsEphemeralContainerSupported() (bool, string)
+	IncreasePodRef(name, namespace string) error
 	DecreasePodRef(name, namespace string) (bool, error)
+	GetPodOs(spec *coreV1.PodSpec) string
 
 	GetDeployment(name string, namespace string) (*appV1.Deployment, error)
 	GetDeploymentsByLabel(labels map[string]string, namespace string) (*appV1.DeploymentList, error)
 	GetAllDeploymentInNamespace(namespace string) (*appV1.DeploymentList, error)
+	GetReplicaSet(name string, namespace string) (*appV1.ReplicaSet, error)
 	UpdateDeployment(deployment *appV1.Deployment) (*appV1.Deployment, error)
 	RemoveDeployment(name, namespace string) error
-	IncreaseDeploymentRef(name ,namespace string) error
+	IncreaseDeploymentRef(name, namespace string) error
 	DecreaseDeploymentRef(name, namespace string) (bool, error)
 	ScaleTo(deployment, namespace string, replicas *int32) (err error)
 
+	GetJob(name string, namespace string) (*batchV1.Job, error)
+
+	CreatePreheatDaemonSet(name, image, os string) (*appV1.DaemonSet, error)
+	GetDaemonSet(name string, namespace string) (*appV1.DaemonSet, error)
+	GetDaemonSetsByLabel(labels map[string]string, namespace string) (*appV1.DaemonSetList, error)
+	UpdateDaemonSetHeartBeat(name, namespace string)
+	RemoveDaemonSet(name, namespace string) error
+
 	GetService(name, namespace string) (*coreV1.Service, error)
+	GetEndpoints(name, namespace string) (*coreV1.Endpoints, error)
 	GetServicesBySelector(matchLabels map[string]string, namespace string) ([]coreV1.Service, error)
 	GetAllServiceInNamespace(namespace string) (*coreV1.ServiceList, error)
 	GetServicesByLabel(labels map[string]string, namespace string) (*coreV1.ServiceList, error)
 	CreateService(metaAndSpec *SvcMetaAndSpec) (*coreV1.Service, error)
+	RenderService(metaAndSpec *SvcMetaAndSpec) *coreV1.Service
 	UpdateService(svc *coreV1.Service) (*coreV1.Service, error)
 	RemoveService(name, namespace string) (err error)
 	UpdateServiceHeartBeat(name, namespace string)
@@ -48,13 +71,35 @@ type KubernetesInterface interface {
 
 	GetConfigMap(name, namespace string) (*coreV1.ConfigMap, error)
 	GetConfigMapsByLabel(labels map[string]string, namespace string) (*coreV1.ConfigMapList, error)
+	UpdateConfigMap(configMap *coreV1.ConfigMap) (*coreV1.ConfigMap, error)
 	RemoveConfigMap(name, namespace string) (err error)
 	UpdateConfigMapHeartBeat(name, namespace string)
+	GetSecret(name, namespace string) (*coreV1.Secret, error)
 
 	GetAllIngressInNamespace(namespace string) (*extV1.IngressList, error)
+	GetIngressesByLabel(labels map[string]string, namespace string) (*netV1.IngressList, error)
+	CreateIngress(metaAndSpec *IngressMetaAndSpec) (*netV1.Ingress, error)
+	UpdateIngressHeartBeat(name, namespace string)
+	RemoveIngress(name, namespace string) error
+	GetIngressClasses() ([]netV1.IngressClass, error)
+
+	IsIstioInstalled() bool
+	GetAllVirtualServiceInNamespace(namespace string) ([]VirtualService, error)
+	CreateMirrorVirtualService(name, namespace, host, mirrorHost string, percent int) error
+	RenderMirrorVirtualService(name, namespace, host, mirrorHost string, percent int) any
+	RemoveVirtualService(name, namespace string) error
+	IsGatewayApiInstalled() bool
+	GetAllHttpRouteInNamespace(namespace string) ([]HTTPRoute, error)
+	CreateHttpRoute(route *HTTPRoute) error
+	UpdateHttpRouteHeartBeat(name, namespace string)
+	RemoveHttpRoute(name, namespace string) error
 
 	GetKtResources(namespace string) ([]coreV1.Pod, []coreV1.ConfigMap, []appV1.Deployment, []coreV1.Service, error)
 	GetAllNamespaces() (*coreV1.NamespaceList, error)
+	GetNamespace(name string) (*coreV1.Namespace, error)
+	CreateNamespace(name string) (*coreV1.Namespace, error)
+	RemoveNamespace(name string) error
+	IsNamespaceEmpty(name string) (bool, error)
 	ClusterCidr(namespace string) (cidr []string, excludeCidr []string)
 }
 
@@ -64,7 +109,7 @@ type Kubernetes struct {
 }
 
 // Cli the singleton type
-var instance *Kubernetes
+var instance KubernetesInterface
 
 // Ins get singleton instance
 func Ins() KubernetesInterface {
@@ -75,3 +120,8 @@ func Ins() KubernetesInterface {
 	}
 	return instance
 }
+
+// SetIns replace the singleton instance, e.g. with a fake implementation in tests
+func SetIns(k KubernetesInterface) {
+	instance = k
+}
@@ -16,8 +16,10 @@ import (
 )
 
 type DnsServer struct {
-	dnsAddresses []string
-	extraDomains map[string]string
+	dnsAddresses      []string
+	extraDomains      map[string]string
+	clusterDnsAddress string
+	clusterSuffixes   []string
 }
 
 func SetupLocalDns(remoteDnsPort, localDnsPort int, dnsOrder []string) error {
@@ -26,9 +28,18 @@ func SetupLocalDns(remoteDnsPort, localDnsPort int, dnsOrder []string) error {
 		upstreamDnsAddresses := getDnsAddresses(dnsOrder, GetNameServer(), remoteDnsPort)
 		// domain-name -> ip
 		extraDomains := getIngressDomains()
+		for domain, ip := range getServiceMappingDomains() {
+			extraDomains[domain] = ip
+		}
 		log.Info().Msgf("Setup local DNS with upstream %v", upstreamDnsAddresses)
+		var clusterSuffixes []string
+		if opt.Get().Connect.SplitDns {
+			clusterSuffixes = getClusterDomainSuffixes()
+			log.Info().Msgf("Only domains with suffix %v will be resolved by cluster DNS", clusterSuffixes)
+		}
 		HandleExtraDomainMapping(extraDomains, localDnsPort)
-		res <-common.SetupDnsServer(&DnsServer{upstreamDnsAddresses, extraDomains}, localDnsPort, "udp")
+		res <- common.SetupDnsServer(&DnsServer{upstreamDnsAddresses, extraDomains,
+			getClusterDnsAddress(remoteDnsPort), clusterSuffixes}, localDnsPort, "udp")
 	}()
 	select {
 	case err := <-res:
@@ -62,12 +73,59 @@ func getIngressDomains() map[string]string {
 	return ingressDomains
 }
 
+// getServiceMappingDomains domains of services mapped to fixed ip via '--mapService'
+func getServiceMappingDomains() map[string]string {
+	// already validated before connect
+	services, _ := ParseServiceMapping(opt.Get().Connect.MapService)
+	for name, ip := range services {
+		log.Info().Msgf("Service %s is mapped to %s", name, ip)
+	}
+	return GetServiceMappingDomains(services, opt.Get().Global.Namespace, opt.Get().Connect.ClusterDomain)
+}
+
+// getClusterDomainSuffixes domain suffixes should be resolved by cluster dns in split dns mode
+func getClusterDomainSuffixes() []string {
+	suffixes := []string{"svc", opt.Get().Connect.ClusterDomain}
+	if namespaces, err := cluster.Ins().GetAllNamespaces(); err == nil {
+		for _, ns := range namespaces.Items {
+			suffixes = append(suffixes, ns.Name)
+		}
+	} else {
+		log.Debug().Err(err).Msgf("Failed to list namespaces, only current namespace would be resolved by cluster DNS")
+		suffixes = append(suffixes, opt.Get().Global.Namespace)
+	}
+	for _, suffix := range strings.Split(opt.Get().Connect.IncludeDomains, ",") {
+		if len(suffix) > 0 {
+			suffixes = append(suffixes, suffix)
+		}
+	}
+	return suffixes
+}
+
+// isClusterDomain check whether domain is a short name or ends with any cluster domain suffix
+func isClusterDomain(domain string, clusterSuffixes []string) bool {
+	name := strings.TrimSuffix(domain, ".")
+	if !strings.Contains(name, ".") {
+		return true
+	}
+	for _, suffix := range clusterSuffixes {
+		if strings.HasSuffix(name, "."+strings.Trim(suffix, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+func getClusterDnsAddress(clusterDnsPort int) string {
+	return fmt.Sprintf("tcp:%s:%d", common.Localhost, clusterDnsPort)
+}
+
 func getDnsAddresses(dnsOrder []string, upstreamDns string, clusterDnsPort int) []string {
 	upstreamPattern := fmt.Sprintf("^([cdptu]{3}:)?%s(:[0-9]+)?$", util.DnsOrderUpstream)
 	var dnsAddresses []string
 	for _, dnsAddr := range dnsOrder {
 		if dnsAddr == util.DnsOrderCluster {
-			dnsAddresses = append(dnsAddresses, fmt.Sprintf("tcp:%s:%d", common.Localhost, clusterDnsPort))
+			dnsAddresses = append(dnsAddresses, getClusterDnsAddress(clusterDnsPort))
 		} else if ok, err := regexp.MatchString(upstreamPattern, dnsAddr); err == nil && ok {
 			upstreamParts := strings.Split(dnsAddr, ":")
 			if upstreamDns != "" {
@@ -110,28 +168,49 @@ func getDnsAddresses(dnsOrder []string, upstreamDns string, clusterDnsPort int)
 func (s *DnsServer) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
 	msg := (&dns.Msg{}).SetReply(req)
 	msg.Authoritative = true
-	msg.Answer = query(req, s.dnsAddresses, s.extraDomains)
+	msg.Answer = query(req, s.getDnsAddressesOf(req.Question[0].Name), s.clusterDnsAddress, s.extraDomains)
 	if err := w.WriteMsg(msg); err != nil {
 		log.Warn().Err(err).Msgf("Failed to reply dns request")
 	}
 }
 
-func query(req *dns.Msg, dnsAddresses []string, extraDomains map[string]string) []dns.RR {
+// getDnsAddressesOf in split dns mode, cluster domains only query cluster dns, others skip cluster dns
+func (s *DnsServer) getDnsAddressesOf(domain string) []string {
+	if s.clusterSuffixes == nil {
+		return s.dnsAddresses
+	}
+	if isClusterDomain(domain, s.clusterSuffixes) {
+		return []string{s.clusterDnsAddress}
+	}
+	var dnsAddresses []string
+	for _, dnsAddr := range s.dnsAddresses {
+		if dnsAddr != s.clusterDnsAddress {
+			dnsAddresses = append(dnsAddresses, dnsAddr)
+		}
+	}
+	return dnsAddresses
+}
+
+func query(req *dns.Msg, dnsAddresses []string, clusterDnsAddress string, extraDomains map[string]string) []dns.RR {
 	domain := req.Question[0].Name
 	qtype := req.Question[0].Qtype
 
-	answer := common.ReadCache(domain, qtype, int64(opt.Get().Connect.DnsCacheTtl))
-	if answer != nil {
-		log.Debug().Msgf("Found domain %s (%d) in cache", domain, qtype)
-		return answer
+	// mapped domains always take precedence over cached or upstream answers
+	if ip, exists := extraDomains[strings.TrimSuffix(domain, ".")]; exists {
+		return []dns.RR{toARecord(domain, ip)}
 	}
-
 	for host, ip := range extraDomains {
 		if wildcardMatch(host, domain) {
 			return []dns.RR{toARecord(domain, ip)}
 		}
 	}
 
+	answer := common.ReadCache(domain, qtype, int64(opt.Get().Connect.DnsCacheTtl))
+	if answer != nil {
+		log.Debug().Msgf("Found domain %s (%d) in cache", domain, qtype)
+		return answer
+	}
+
 	for _, dnsAddr := range dnsAddresses {
 		dnsParts := strings.SplitN(dnsAddr, ":", 3)
 		protocol := dnsParts[0]
@@ -143,6 +222,10 @@ func query(req *dns.Msg, dnsAddresses []string, extraDomains map[string]string)
 		}
 		res, err := common.NsLookup(domain, qtype, protocol, fmt.Sprintf("%s:%d", ip, port))
 		if res != nil && len(res.Answer) > 0 {
+			if dnsAddr == clusterDnsAddress {
+				// let applications re-resolve cluster domains soon, in case service is re-pointed
+				overrideTtl(res.Answer, uint32(opt.Get().Connect.DnsTtl))
+			}
 			// only record none-empty result of cluster dns
 			log.Debug().Msgf("Found domain %s (%d) in dns (%s:%d)", domain, qtype, ip, port)
 			common.WriteCache(domain, qtype, res.Answer, time.Now().Unix())
@@ -157,12 +240,18 @@ func query(req *dns.Msg, dnsAddresses []string, extraDomains map[string]string)
 	return []dns.RR{}
 }
 
+func overrideTtl(answer []dns.RR, ttl uint32) {
+	for _, rr := range answer {
+		rr.Header().Ttl = ttl
+	}
+}
+
 func wildcardMatch(pattenDomain, targetDomain string) bool {
 	if !strings.HasSuffix(pattenDomain, ".") {
 		pattenDomain = pattenDomain + "."
 	}
 	if strings.Contains(pattenDomain, "*") {
-		ok, err := regexp.MatchString("^" + strings.ReplaceAll(strings.ReplaceAll(pattenDomain, ".", "\\."), "*", ".*") + "$", targetDomain)
+		ok, err := regexp.MatchString("^"+strings.ReplaceAll(strings.ReplaceAll(pattenDomain, ".", "\\."), "*", ".*")+"$", targetDomain)
 		return ok && err == nil
 	} else {
 		return pattenDomain == targetDomain
@@ -170,12 +259,12 @@ func wildcardMatch(pattenDomain, targetDomain string) bool {
 }
 
 func toARecord(domain, ip string) dns.RR {
-	return &dns.A {
-		Hdr: dns.RR_Header {
-			Name: domain,
-			Rrtype: dns.TypeA,
-			Class: dns.ClassINET,
-			Ttl: 5,
+	return &dns.A{
+		Hdr: dns.RR_Header{
+			Name:     domain,
+			Rrtype:   dns.TypeA,
+			Class:    dns.ClassINET,
+			Ttl:      uint32(opt.Get().Connect.DnsTtl),
 			Rdlength: 4,
 		},
 		A: net.ParseIP(ip),
@@ -1,8 +1,14 @@
 package dns
 
 import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/common"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/miekg/dns"
+	"net"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func Test_getDnsAddresses(t *testing.T) {
@@ -17,24 +23,24 @@ func Test_getDnsAddresses(t *testing.T) {
 	}{
 		{
 			args: args{
-				dnsOrder: []string{"cluster", "upstream", "tcp:upstream", "upstream:123", "tcp:upstream:123"},
-				upstreamDns: "1.2.3.4",
+				dnsOrder:       []string{"cluster", "upstream", "tcp:upstream", "upstream:123", "tcp:upstream:123"},
+				upstreamDns:    "1.2.3.4",
 				clusterDnsPort: 5353,
 			},
 			want: []string{"tcp:127.0.0.1:5353", "udp:1.2.3.4:53", "tcp:1.2.3.4:53", "udp:1.2.3.4:123", "tcp:1.2.3.4:123"},
 		},
 		{
 			args: args{
-				dnsOrder: []string{"7.8.9.0", "tcp:7.8.9.0", "7.8.9.0:123", "tcp:7.8.9.0:123"},
-				upstreamDns: "1.2.3.4",
+				dnsOrder:       []string{"7.8.9.0", "tcp:7.8.9.0", "7.8.9.0:123", "tcp:7.8.9.0:123"},
+				upstreamDns:    "1.2.3.4",
 				clusterDnsPort: 5353,
 			},
 			want: []string{"udp:7.8.9.0:53", "tcp:7.8.9.0:53", "udp:7.8.9.0:123", "tcp:7.8.9.0:123"},
 		},
 		{
 			args: args{
-				dnsOrder: []string{"", "tcp:", ":123", "tcp:7.8.9.0:123:53"},
-				upstreamDns: "1.2.3.4",
+				dnsOrder:       []string{"", "tcp:", ":123", "tcp:7.8.9.0:123:53"},
+				upstreamDns:    "1.2.3.4",
 				clusterDnsPort: 5353,
 			},
 			want: []string{"udp::53", "tcp::53", "udp::123"},
@@ -108,3 +114,88 @@ func Test_wildcardMatch(t *testing.T) {
 		})
 	}
 }
+
+func Test_isClusterDomain(t *testing.T) {
+	suffixes := []string{"svc", "cluster.local", "default", "corp.internal."}
+	tests := []struct {
+		domain string
+		want   bool
+	}{
+		{domain: "myservice.", want: true},
+		{domain: "myservice.default.", want: true},
+		{domain: "myservice.default.svc.", want: true},
+		{domain: "myservice.default.svc.cluster.local.", want: true},
+		{domain: "api.corp.internal.", want: true},
+		{domain: "google.com.", want: false},
+		{domain: "myservice.other.", want: false},
+		{domain: "notcluster.local.", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			if got := isClusterDomain(tt.domain, suffixes); got != tt.want {
+				t.Errorf("isClusterDomain(%s) got: %v, want: %v", tt.domain, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getDnsAddressesOf(t *testing.T) {
+	s := &DnsServer{
+		dnsAddresses:      []string{"tcp:127.0.0.1:5353", "udp:1.2.3.4:53"},
+		clusterDnsAddress: "tcp:127.0.0.1:5353",
+		clusterSuffixes:   []string{"svc", "cluster.local", "default"},
+	}
+	if got := s.getDnsAddressesOf("myservice."); !reflect.DeepEqual(got, []string{"tcp:127.0.0.1:5353"}) {
+		t.Errorf("cluster domain got: %v", got)
+	}
+	if got := s.getDnsAddressesOf("google.com."); !reflect.DeepEqual(got, []string{"udp:1.2.3.4:53"}) {
+		t.Errorf("public domain got: %v", got)
+	}
+	s.clusterSuffixes = nil
+	if got := s.getDnsAddressesOf("google.com."); !reflect.DeepEqual(got, s.dnsAddresses) {
+		t.Errorf("split dns disabled got: %v", got)
+	}
+}
+
+func Test_queryTtl(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	server := &dns.Server{Listener: listener, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		msg := (&dns.Msg{}).SetReply(req)
+		msg.Answer = []dns.RR{&dns.A{
+			Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 30},
+			A:   net.ParseIP("10.0.0.1"),
+		}}
+		_ = w.WriteMsg(msg)
+	})}
+	go func() {
+		_ = server.ActivateAndServe()
+	}()
+	defer server.Shutdown()
+
+	opt.Get().Connect.DnsTtl = 2
+	dnsAddress := fmt.Sprintf("tcp:%s", listener.Addr().String())
+	req := (&dns.Msg{}).SetQuestion("ttl-cluster.default.svc.cluster.local.", dns.TypeA)
+	if answer := query(req, []string{dnsAddress}, dnsAddress, map[string]string{}); len(answer) != 1 || answer[0].Header().Ttl != 2 {
+		t.Errorf("ttl of cluster domain should be overridden, got: %v", answer)
+	}
+	req = (&dns.Msg{}).SetQuestion("ttl-upstream.example.com.", dns.TypeA)
+	if answer := query(req, []string{dnsAddress}, "tcp:127.0.0.1:1", map[string]string{}); len(answer) != 1 || answer[0].Header().Ttl != 30 {
+		t.Errorf("ttl of upstream domain should be kept, got: %v", answer)
+	}
+}
+
+func Test_queryMappedDomain(t *testing.T) {
+	opt.Get().Connect.DnsCacheTtl = 60
+	domain := "mapped.default.svc.cluster.local."
+	// cached answer of cluster dns should not hide mapped ip
+	common.WriteCache(domain, dns.TypeA, []dns.RR{toARecord(domain, "10.0.0.1")}, time.Now().Unix())
+	req := (&dns.Msg{}).SetQuestion(domain, dns.TypeA)
+	answer := query(req, []string{"tcp:127.0.0.1:1"}, "tcp:127.0.0.1:1",
+		map[string]string{"*.svc.cluster.local": "10.0.0.3", "mapped.default.svc.cluster.local": "10.0.0.5"})
+	if len(answer) != 1 || answer[0].(*dns.A).A.String() != "10.0.0.5" {
+		t.Errorf("mapped domain should be answered with mapped ip, got: %v", answer)
+	}
+}
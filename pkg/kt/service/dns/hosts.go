@@ -68,11 +68,19 @@ func DumpHosts(hostsMap map[string]string, namespaceToDrop string) error {
 	return nil
 }
 
+// isFullDomainOf check whether hosts record is full domain of specified namespace,
+// records of any cluster domain are matched if cluster domain is not yet known
+func isFullDomainOf(record, namespace string) bool {
+	if opt.Get().Connect.ClusterDomain == "" {
+		return strings.Contains(record, fmt.Sprintf(".%s.svc.", namespace))
+	}
+	return strings.HasSuffix(record, fmt.Sprintf(".%s.svc.%s", namespace, opt.Get().Connect.ClusterDomain))
+}
+
 func dropHosts(rawLines []string, namespaceToDrop string) ([]string, []string, error) {
 	escapeBegin := -1
 	escapeEnd := -1
 	midDomain := fmt.Sprintf(".%s", namespaceToDrop)
-	fullDomain := fmt.Sprintf(".%s.svc.%s", namespaceToDrop, opt.Get().Connect.ClusterDomain)
 	keepShortDomain := namespaceToDrop != opt.Get().Global.Namespace
 	recordsToKeep := make([]string, 0)
 	for i, l := range rawLines {
@@ -85,7 +93,7 @@ func dropHosts(rawLines []string, namespaceToDrop string) ([]string, []string, e
 				if keepShortDomain {
 					recordsToKeep = append(recordsToKeep, l)
 				}
-			} else if !strings.HasSuffix(l, midDomain) && !strings.HasSuffix(l, fullDomain) {
+			} else if !strings.HasSuffix(l, midDomain) && !isFullDomainOf(l, namespaceToDrop) {
 				recordsToKeep = append(recordsToKeep, l)
 			}
 		}
@@ -157,9 +165,9 @@ func loadHostsFile() ([]string, error) {
 
 func updateHostsFile(lines []string) error {
 	lock := flock.New(fmt.Sprintf("%s/hosts.lock", util.KtLockDir))
-	timeoutContext, cancel := context.WithTimeout(context.TODO(), 2 * time.Second)
+	timeoutContext, cancel := context.WithTimeout(context.TODO(), 2*time.Second)
 	defer cancel()
-	if ok, err := lock.TryLockContext(timeoutContext, 100 * time.Millisecond); !ok {
+	if ok, err := lock.TryLockContext(timeoutContext, 100*time.Millisecond); !ok {
 		return fmt.Errorf("failed to require hosts lock")
 	} else if err != nil {
 		log.Error().Err(err).Msgf("require hosts file failed with error")
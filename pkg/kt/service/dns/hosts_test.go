@@ -1,6 +1,7 @@
 package dns
 
 import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
 	"github.com/stretchr/testify/require"
 	"testing"
 )
@@ -136,6 +137,26 @@ func TestDropHosts(t *testing.T) {
 	}
 }
 
+func TestDropHostsOfNamespace(t *testing.T) {
+	lines := []string{
+		"# Kt Hosts Begin",
+		"172.12.3.4 tomcat.default",
+		"172.12.3.4 tomcat.default.svc.cluster.internal",
+		"172.12.3.5 nginx.other",
+		"172.12.3.5 nginx.other.svc.cluster.internal",
+		"# Kt Hosts End",
+	}
+	opt.Get().Global.Namespace = "default"
+	for _, clusterDomain := range []string{"", "cluster.internal"} {
+		opt.Get().Connect.ClusterDomain = clusterDomain
+		_, linesToKeep, err := dropHosts(lines, "default")
+		require.Nil(t, err)
+		require.Equal(t, []string{"172.12.3.5 nginx.other", "172.12.3.5 nginx.other.svc.cluster.internal"}, linesToKeep,
+			"cluster domain '%s'", clusterDomain)
+	}
+	opt.Get().Connect.ClusterDomain = ""
+}
+
 func TestDumpHosts(t *testing.T) {
 	type args struct {
 		hostsToDump    map[string]string
@@ -0,0 +1,47 @@
+package dns
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"strings"
+)
+
+// ParseServiceMapping parse comma separated '<service>=<ip>' mappings into service name to ip map
+func ParseServiceMapping(mapping string) (map[string]string, error) {
+	services := make(map[string]string)
+	if mapping == "" {
+		return services, nil
+	}
+	for _, item := range strings.Split(mapping, ",") {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 || strings.Trim(parts[0], ".") == "" {
+			return nil, fmt.Errorf("invalid service mapping '%s', should be in '<service>=<ip>' format", item)
+		}
+		if !util.IsValidIp(parts[1]) {
+			return nil, fmt.Errorf("invalid ip address '%s' of service mapping '%s'", parts[1], item)
+		}
+		services[strings.Trim(parts[0], ".")] = parts[1]
+	}
+	return services, nil
+}
+
+// GetServiceMappingDomains expand each mapped service name to all domains it could be queried with
+func GetServiceMappingDomains(services map[string]string, namespace, clusterDomain string) map[string]string {
+	domains := make(map[string]string)
+	for name, ip := range services {
+		if strings.HasSuffix(name, ".svc."+clusterDomain) {
+			domains[name] = ip
+			continue
+		}
+		name = strings.TrimSuffix(name, ".svc")
+		if !strings.Contains(name, ".") {
+			// short name refers to service in current namespace
+			domains[name] = ip
+			name = fmt.Sprintf("%s.%s", name, namespace)
+		}
+		domains[name] = ip
+		domains[name+".svc"] = ip
+		domains[fmt.Sprintf("%s.svc.%s", name, clusterDomain)] = ip
+	}
+	return domains
+}
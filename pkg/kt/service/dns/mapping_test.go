@@ -0,0 +1,40 @@
+package dns
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseServiceMapping(t *testing.T) {
+	services, err := ParseServiceMapping("frontend=10.0.0.5,backend.other=10.0.0.6")
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{"frontend": "10.0.0.5", "backend.other": "10.0.0.6"}, services)
+	services, err = ParseServiceMapping("")
+	require.Nil(t, err)
+	require.Empty(t, services)
+
+	_, err = ParseServiceMapping("frontend")
+	require.NotNil(t, err, "mapping without ip should fail")
+	_, err = ParseServiceMapping("=10.0.0.5")
+	require.NotNil(t, err, "mapping without service name should fail")
+	_, err = ParseServiceMapping("frontend=10.0.0")
+	require.NotNil(t, err, "invalid ip should fail")
+}
+
+func TestGetServiceMappingDomains(t *testing.T) {
+	domains := GetServiceMappingDomains(map[string]string{
+		"frontend":                  "10.0.0.5",
+		"backend.other":             "10.0.0.6",
+		"api.dev.svc.cluster.local": "10.0.0.7",
+	}, "default", "cluster.local")
+	require.Equal(t, map[string]string{
+		"frontend":                           "10.0.0.5",
+		"frontend.default":                   "10.0.0.5",
+		"frontend.default.svc":               "10.0.0.5",
+		"frontend.default.svc.cluster.local": "10.0.0.5",
+		"backend.other":                      "10.0.0.6",
+		"backend.other.svc":                  "10.0.0.6",
+		"backend.other.svc.cluster.local":    "10.0.0.6",
+		"api.dev.svc.cluster.local":          "10.0.0.7",
+	}, domains)
+}
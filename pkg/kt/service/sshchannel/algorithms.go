@@ -0,0 +1,136 @@
+package sshchannel
+
+import (
+	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/wzshiming/sshproxy"
+	"golang.org/x/crypto/ssh"
+	"io/ioutil"
+	"strings"
+)
+
+// algorithmSet ssh algorithms of one kind, default ones are used when not specified, legacy ones are weak and
+// only allowed with '--sshLegacyAlgorithms'
+type algorithmSet struct {
+	kind     string
+	defaults []string
+	optional []string
+	legacy   []string
+}
+
+// CipherSet ciphers supported by ssh tunnel
+var CipherSet = algorithmSet{
+	kind: "cipher",
+	defaults: []string{
+		"chacha20-poly1305@openssh.com", "aes128-gcm@openssh.com",
+		"aes256-ctr", "aes192-ctr", "aes128-ctr",
+	},
+	legacy: []string{"aes128-cbc", "3des-cbc", "arcfour256", "arcfour128", "arcfour"},
+}
+
+// KeyExchangeSet key exchange algorithms supported by ssh tunnel
+var KeyExchangeSet = algorithmSet{
+	kind: "key exchange algorithm",
+	defaults: []string{
+		"curve25519-sha256", "curve25519-sha256@libssh.org",
+		"ecdh-sha2-nistp256", "ecdh-sha2-nistp384", "ecdh-sha2-nistp521",
+	},
+	optional: []string{"diffie-hellman-group14-sha256", "diffie-hellman-group14-sha1"},
+	legacy:   []string{"diffie-hellman-group1-sha1"},
+}
+
+// MacSet mac algorithms supported by ssh tunnel
+var MacSet = algorithmSet{
+	kind:     "mac algorithm",
+	defaults: []string{"hmac-sha2-256-etm@openssh.com", "hmac-sha2-256"},
+	optional: []string{"hmac-sha1"},
+	legacy:   []string{"hmac-sha1-96"},
+}
+
+// ValidateAlgorithms check specified ssh algorithms are all supported
+func ValidateAlgorithms(ciphers, keyExchanges, macs string) error {
+	if err := CipherSet.validate(ciphers); err != nil {
+		return err
+	}
+	if err := KeyExchangeSet.validate(keyExchanges); err != nil {
+		return err
+	}
+	return MacSet.validate(macs)
+}
+
+// Ciphers ciphers used by ssh tunnel
+func Ciphers() []string {
+	return CipherSet.resolve(opt.Get().Global.SshCiphers)
+}
+
+// KeyExchanges key exchange algorithms used by ssh tunnel
+func KeyExchanges() []string {
+	return KeyExchangeSet.resolve(opt.Get().Global.SshKex)
+}
+
+// MACs mac algorithms used by ssh tunnel
+func MACs() []string {
+	return MacSet.resolve(opt.Get().Global.SshMacs)
+}
+
+func (s algorithmSet) validate(algorithms string) error {
+	allowed := s.allowed()
+	for _, algorithm := range splitAlgorithms(algorithms) {
+		if util.Contains(s.legacy, algorithm) && !opt.Get().Global.SshLegacyAlgorithms {
+			return fmt.Errorf("ssh %s '%s' is considered weak, use '--sshLegacyAlgorithms' if it's required",
+				s.kind, algorithm)
+		} else if !util.Contains(allowed, algorithm) {
+			return fmt.Errorf("unsupported ssh %s '%s', allowed values are: %s", s.kind, algorithm,
+				strings.Join(allowed, ", "))
+		}
+	}
+	return nil
+}
+
+// allowed algorithms could be specified, legacy ones are only included when explicitly enabled
+func (s algorithmSet) allowed() []string {
+	allowed := append(append([]string{}, s.defaults...), s.optional...)
+	if opt.Get().Global.SshLegacyAlgorithms {
+		allowed = append(allowed, s.legacy...)
+	}
+	return allowed
+}
+
+// resolve algorithms specified by user, or the default ones (plus legacy ones if enabled)
+func (s algorithmSet) resolve(algorithms string) []string {
+	if specified := splitAlgorithms(algorithms); specified != nil {
+		return specified
+	}
+	if opt.Get().Global.SshLegacyAlgorithms {
+		return append(append([]string{}, s.defaults...), s.legacy...)
+	}
+	return s.defaults
+}
+
+func splitAlgorithms(algorithms string) []string {
+	if algorithms == "" {
+		return nil
+	}
+	return strings.Split(algorithms, ",")
+}
+
+func newDialer(privateKey, sshAddress string) (*sshproxy.Dialer, error) {
+	keyData, err := ioutil.ReadFile(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(keyData)
+	if err != nil {
+		return nil, err
+	}
+	config := &ssh.ClientConfig{
+		User:            "root",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+	config.Ciphers = Ciphers()
+	config.KeyExchanges = KeyExchanges()
+	config.MACs = MACs()
+	return sshproxy.NewDialerWithConfig(sshAddress, config)
+}
@@ -0,0 +1,44 @@
+package sshchannel
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestValidateAlgorithms(t *testing.T) {
+	require.Nil(t, ValidateAlgorithms("", "", ""))
+	require.Nil(t, ValidateAlgorithms("aes256-ctr,aes128-gcm@openssh.com", "curve25519-sha256", "hmac-sha2-256"))
+	err := ValidateAlgorithms("aes256-ctr,blowfish-cbc", "", "")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "blowfish-cbc")
+	require.Contains(t, err.Error(), "aes256-ctr")
+	require.NotNil(t, ValidateAlgorithms("", "diffie-hellman-group-exchange-sha1", ""))
+	require.NotNil(t, ValidateAlgorithms("", "", "hmac-md5"))
+}
+
+func TestValidateAlgorithms_legacy(t *testing.T) {
+	defer func() { opt.Get().Global.SshLegacyAlgorithms = false }()
+	err := ValidateAlgorithms("arcfour", "", "")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "--sshLegacyAlgorithms")
+	require.NotNil(t, ValidateAlgorithms("", "diffie-hellman-group1-sha1", ""))
+	require.NotNil(t, ValidateAlgorithms("", "", "hmac-sha1-96"))
+	opt.Get().Global.SshLegacyAlgorithms = true
+	require.Nil(t, ValidateAlgorithms("arcfour,3des-cbc,aes128-cbc", "diffie-hellman-group1-sha1", "hmac-sha1-96"))
+}
+
+func TestResolveAlgorithms(t *testing.T) {
+	defer func() {
+		opt.Get().Global.SshLegacyAlgorithms = false
+		opt.Get().Global.SshCiphers = ""
+	}()
+	require.Equal(t, CipherSet.defaults, Ciphers())
+	require.NotContains(t, KeyExchanges(), "diffie-hellman-group1-sha1")
+	require.NotContains(t, MACs(), "hmac-sha1-96")
+	opt.Get().Global.SshLegacyAlgorithms = true
+	require.Contains(t, Ciphers(), "arcfour")
+	require.Contains(t, KeyExchanges(), "diffie-hellman-group1-sha1")
+	opt.Get().Global.SshCiphers = "aes256-ctr"
+	require.Equal(t, []string{"aes256-ctr"}, Ciphers())
+}
@@ -0,0 +1,158 @@
+package sshchannel
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// poolRetryInterval time to wait before opening channel again after target failed
+const poolRetryInterval = 1 * time.Second
+
+// poolReadBufferSize size of each read from pooled channel
+const poolReadBufferSize = 32 * 1024
+
+// connPool keep specified number of established channels to target on the shared ssh client,
+// so that forwarded request needn't wait for channel opening, an idle channel is only replaced when it failed
+type connPool struct {
+	address string
+	dial    func(network, address string) (net.Conn, error)
+	idle    chan *pooledConn
+	done    chan struct{}
+	once    sync.Once
+}
+
+// pooledConn channel whose data is read in background, so that failure of idle channel could be noticed,
+// ssh channel does not support read deadline, thus it cannot be peeked and interrupted
+type pooledConn struct {
+	net.Conn
+	reader *io.PipeReader
+	failed chan struct{}
+}
+
+func newPooledConn(conn net.Conn) *pooledConn {
+	reader, writer := io.Pipe()
+	c := &pooledConn{Conn: conn, reader: reader, failed: make(chan struct{})}
+	go func() {
+		buf := make([]byte, poolReadBufferSize)
+		for {
+			n, err := conn.Read(buf)
+			if n > 0 {
+				// block until data is consumed, data sent by target before channel taken is kept
+				if _, err2 := writer.Write(buf[:n]); err2 != nil {
+					return
+				}
+			}
+			if err != nil {
+				close(c.failed)
+				_ = writer.CloseWithError(err)
+				return
+			}
+		}
+	}()
+	return c
+}
+
+func (c *pooledConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+func (c *pooledConn) Close() error {
+	_ = c.reader.Close()
+	return c.Conn.Close()
+}
+
+// isFailed whether channel already closed by target
+func (c *pooledConn) isFailed() bool {
+	select {
+	case <-c.failed:
+		return true
+	default:
+		return false
+	}
+}
+
+// withChannelPool take channels to target from pool if '--tunnelPoolSize' specified,
+// returned function should be called to stop the pool once channels are no longer needed
+func withChannelPool(dial func(network, address string) (net.Conn, error), address string) (
+	func(string, string) (net.Conn, error), func()) {
+	size := opt.Get().Global.TunnelPoolSize
+	if size <= 0 {
+		return dial, func() {}
+	}
+	pool := newConnPool(size, address, dial)
+	return func(_, _ string) (net.Conn, error) {
+		return pool.get()
+	}, pool.close
+}
+
+func newConnPool(size int, address string, dial func(network, address string) (net.Conn, error)) *connPool {
+	p := &connPool{
+		address: address,
+		dial:    dial,
+		idle:    make(chan *pooledConn),
+		done:    make(chan struct{}),
+	}
+	// each filler holds one idle channel until it's taken or failed
+	for i := 0; i < size; i++ {
+		go p.fill()
+	}
+	return p
+}
+
+// get take a live established channel, or open a new one if none available
+func (p *connPool) get() (net.Conn, error) {
+	for {
+		select {
+		case c := <-p.idle:
+			if !c.isFailed() {
+				return c, nil
+			}
+			_ = c.Close()
+		default:
+			return p.dial("tcp", p.address)
+		}
+	}
+}
+
+// close stop filling the pool, idle channels are closed by fillers
+func (p *connPool) close() {
+	p.once.Do(func() {
+		close(p.done)
+	})
+}
+
+func (p *connPool) fill() {
+	for {
+		conn, err := p.dial("tcp", p.address)
+		if err != nil {
+			log.Debug().Err(err).Msgf("Failed to open channel to %s", p.address)
+			select {
+			case <-p.done:
+				return
+			case <-time.After(poolRetryInterval):
+				continue
+			}
+		}
+		c := newPooledConn(conn)
+		select {
+		case p.idle <- c:
+		case <-c.failed:
+			log.Debug().Msgf("Idle channel to %s closed, replacing it", p.address)
+			_ = c.Close()
+			// avoid busy reopening if target always closes idle connection at once
+			select {
+			case <-p.done:
+				return
+			case <-time.After(poolRetryInterval):
+			}
+		case <-p.done:
+			_ = c.Close()
+			return
+		}
+	}
+}
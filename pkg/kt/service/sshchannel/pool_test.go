@@ -0,0 +1,302 @@
+package sshchannel
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConnPool(t *testing.T) {
+	listener := startEchoServer(t)
+	pool := newConnPool(2, listener.Addr().String(), net.Dial)
+	defer pool.close()
+	for i := 0; i < 5; i++ {
+		conn, err := pool.get()
+		require.Nil(t, err)
+		require.Nil(t, echo(conn))
+		_ = conn.Close()
+	}
+	pool.close()
+	conn, err := pool.get()
+	require.Nil(t, err, "should dial directly after pool closed")
+	require.Nil(t, echo(conn))
+	_ = conn.Close()
+}
+
+func TestPooledConn(t *testing.T) {
+	server, client := net.Pipe()
+	c := newPooledConn(client)
+	go func() {
+		_, _ = server.Write([]byte("hi"))
+	}()
+	time.Sleep(10 * time.Millisecond)
+	require.False(t, c.isFailed(), "connection should not be treated as failed when target sent data")
+	buf := make([]byte, 2)
+	_, err := io.ReadFull(c, buf)
+	require.Nil(t, err)
+	require.Equal(t, "hi", string(buf), "data received while idle should not be lost")
+
+	_ = server.Close()
+	select {
+	case <-c.failed:
+	case <-time.After(time.Second):
+		t.Fatal("connection closed by target should be detected")
+	}
+	_, err = c.Read(buf)
+	require.Equal(t, io.EOF, err)
+}
+
+func TestConnPoolReplaceFailed(t *testing.T) {
+	servers := make(chan net.Conn, 10)
+	dialCount := int32(0)
+	pool := newConnPool(1, "target", func(_, _ string) (net.Conn, error) {
+		atomic.AddInt32(&dialCount, 1)
+		server, client := net.Pipe()
+		servers <- server
+		return client, nil
+	})
+	defer pool.close()
+	first := <-servers
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, int32(1), atomic.LoadInt32(&dialCount), "idle channel should be kept as long as it's alive")
+
+	_ = first.Close()
+	select {
+	case <-servers:
+	case <-time.After(2 * poolRetryInterval):
+		t.Fatal("failed channel should be replaced")
+	}
+	require.Equal(t, int32(2), atomic.LoadInt32(&dialCount))
+}
+
+// shadowLatency simulated network delay between local and shadow pod, the round trip of opening channel is what
+// pool saves, on loopback without delay opening channel costs almost nothing, and pool would only add overhead
+const shadowLatency = 1 * time.Millisecond
+
+func TestConnPoolOverSsh(t *testing.T) {
+	target := startEchoServer(t).Addr().String()
+	dial := dialViaSsh(t, startSshServer(t))
+	pool := newConnPool(2, target, dial)
+	defer pool.close()
+	for i := 0; i < 5; i++ {
+		conn, err := pool.get()
+		require.Nil(t, err)
+		require.Nil(t, echo(conn))
+		_ = conn.Close()
+	}
+}
+
+// BenchmarkChannelPerRequest open a new ssh channel for each request, as tunnels without '--tunnelPoolSize' do
+func BenchmarkChannelPerRequest(b *testing.B) {
+	target := startEchoServer(b).Addr().String()
+	dial := dialViaSsh(b, startSshServer(b))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		conn, err := dial("tcp", target)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err = echo(conn); err != nil {
+			b.Fatal(err)
+		}
+		_ = conn.Close()
+	}
+}
+
+// BenchmarkChannelPool take ssh channels opened in advance, which is the only per-request cost being saved
+func BenchmarkChannelPool(b *testing.B) {
+	target := startEchoServer(b).Addr().String()
+	pool := newConnPool(16, target, dialViaSsh(b, startSshServer(b)))
+	defer pool.close()
+	time.Sleep(100 * time.Millisecond)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		conn, err := pool.get()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err = echo(conn); err != nil {
+			b.Fatal(err)
+		}
+		_ = conn.Close()
+	}
+}
+
+// dialViaSsh open channels to target via a single ssh connection, the same way tunnels to shadow pod do
+func dialViaSsh(t testing.TB, sshAddress string) func(network, address string) (net.Conn, error) {
+	privateKey := filepath.Join(t.TempDir(), "kt.key")
+	_, err := util.Generate(privateKey)
+	require.Nil(t, err)
+	dialer, err := newDialer(privateKey, sshAddress)
+	require.Nil(t, err)
+	t.Cleanup(func() {
+		_ = dialer.Close()
+	})
+	return func(network, address string) (net.Conn, error) {
+		return dialer.DialContext(context.Background(), network, address)
+	}
+}
+
+// startSshServer minimal ssh server accepting any key, which only serves 'direct-tcpip' channels like sshd does
+func startSshServer(t testing.TB) string {
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	require.Nil(t, err)
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(ssh.ConnMetadata, ssh.PublicKey) (*ssh.Permissions, error) {
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	go func() {
+		for {
+			conn, err2 := listener.Accept()
+			if err2 != nil {
+				return
+			}
+			// delay data sent back by shadow pod, as if it is behind port forward of api server
+			go serveSshConn(newDelayedConn(conn, shadowLatency), config)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// delayedConn deliver data written to it after a fixed delay, without limiting throughput
+type delayedConn struct {
+	net.Conn
+	delay  time.Duration
+	queue  chan delayedChunk
+	closed chan struct{}
+	once   sync.Once
+}
+
+type delayedChunk struct {
+	data []byte
+	due  time.Time
+}
+
+func newDelayedConn(conn net.Conn, delay time.Duration) *delayedConn {
+	c := &delayedConn{Conn: conn, delay: delay, queue: make(chan delayedChunk, 1024), closed: make(chan struct{})}
+	go func() {
+		for {
+			select {
+			case chunk := <-c.queue:
+				time.Sleep(time.Until(chunk.due))
+				if _, err := c.Conn.Write(chunk.data); err != nil {
+					return
+				}
+			case <-c.closed:
+				return
+			}
+		}
+	}()
+	return c
+}
+
+func (c *delayedConn) Write(b []byte) (int, error) {
+	select {
+	case c.queue <- delayedChunk{data: append([]byte{}, b...), due: time.Now().Add(c.delay)}:
+		return len(b), nil
+	case <-c.closed:
+		return 0, net.ErrClosed
+	}
+}
+
+func (c *delayedConn) Close() error {
+	c.once.Do(func() {
+		close(c.closed)
+	})
+	return c.Conn.Close()
+}
+
+func serveSshConn(conn net.Conn, config *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, newChannel.ChannelType())
+			continue
+		}
+		go serveDirectTcp(newChannel)
+	}
+}
+
+func serveDirectTcp(newChannel ssh.NewChannel) {
+	var msg struct {
+		Host     string
+		Port     uint32
+		OrigHost string
+		OrigPort uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &msg); err != nil {
+		_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	target, err := net.Dial("tcp", net.JoinHostPort(msg.Host, strconv.Itoa(int(msg.Port))))
+	if err != nil {
+		_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		_ = target.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	go func() {
+		_, _ = io.Copy(target, channel)
+		_ = target.Close()
+	}()
+	_, _ = io.Copy(channel, target)
+	_ = channel.Close()
+}
+
+func echo(conn net.Conn) error {
+	if _, err := conn.Write([]byte("x")); err != nil {
+		return err
+	}
+	_, err := io.ReadFull(conn, make([]byte, 1))
+	return err
+}
+
+func startEchoServer(t testing.TB) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	go func() {
+		for {
+			conn, err2 := listener.Accept()
+			if err2 != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener
+}
@@ -0,0 +1,171 @@
+package sshchannel
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RedactedValue placeholder of header value hidden in record file
+const RedactedValue = "<redacted>"
+
+// RecordedRequest http request forwarded to local, saved as one json line in record file
+type RecordedRequest struct {
+	Time          string      `json:"time"`
+	Port          int         `json:"port"`
+	Method        string      `json:"method"`
+	Uri           string      `json:"uri"`
+	Host          string      `json:"host"`
+	Header        http.Header `json:"header"`
+	Body          []byte      `json:"body,omitempty"`
+	BodyTruncated bool        `json:"bodyTruncated,omitempty"`
+}
+
+// Recorder append requests to record file, shared by all forwarded ports
+type Recorder struct {
+	file          *os.File
+	bodyLimit     int
+	redactHeaders []string
+	sync.Mutex
+}
+
+// activeRecorder recorder of requests forwarded to local, nil if recording not enabled
+var activeRecorder *Recorder
+
+// StartRecording record requests forwarded to local via reverse tunnels into specified file
+func StartRecording(path string, bodyLimit int, redactHeaders string) error {
+	r, err := newRecorder(path, bodyLimit, redactHeaders)
+	if err != nil {
+		return err
+	}
+	activeRecorder = r
+	return nil
+}
+
+// newRecorder open record file in append mode, only accessible by current user since it may contain credentials
+func newRecorder(path string, bodyLimit int, redactHeaders string) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{file: file, bodyLimit: bodyLimit}
+	for _, header := range strings.Split(redactHeaders, ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			r.redactHeaders = append(r.redactHeaders, http.CanonicalHeaderKey(header))
+		}
+	}
+	return r, nil
+}
+
+// ReadRecords read all requests from record file
+func ReadRecords(path string) ([]RecordedRequest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var records []RecordedRequest
+	decoder := json.NewDecoder(file)
+	for {
+		var record RecordedRequest
+		if err = decoder.Decode(&record); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+}
+
+// record parse http requests from stream, and save them to record file until stream closed
+func (r *Recorder) record(stream io.Reader, port int) {
+	reader := bufio.NewReader(stream)
+	// keep consuming the stream, otherwise forwarding would be blocked
+	defer io.Copy(io.Discard, reader)
+	for {
+		req, err := http.ReadRequest(reader)
+		if err != nil {
+			if err != io.EOF {
+				log.Debug().Err(err).Msgf("Stop recording non-http traffic of port %d", port)
+			}
+			return
+		}
+		record := RecordedRequest{
+			Time:   time.Now().Format(time.RFC3339Nano),
+			Port:   port,
+			Method: req.Method,
+			Uri:    req.RequestURI,
+			Host:   req.Host,
+			Header: req.Header,
+		}
+		for _, header := range r.redactHeaders {
+			if _, exists := record.Header[header]; exists {
+				record.Header[header] = []string{RedactedValue}
+			}
+		}
+		if r.bodyLimit > 0 {
+			record.Body, _ = io.ReadAll(io.LimitReader(req.Body, int64(r.bodyLimit)))
+			if n, _ := io.Copy(io.Discard, req.Body); n > 0 {
+				record.BodyTruncated = true
+			}
+		}
+		_, _ = io.Copy(io.Discard, req.Body)
+		r.save(&record)
+	}
+}
+
+func (r *Recorder) save(record *RecordedRequest) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to encode recorded request")
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	if _, err = r.file.Write(append(data, '\n')); err != nil {
+		log.Warn().Err(err).Msgf("Failed to write record file")
+	}
+}
+
+// recordedConn connection whose written data is copied to recorder
+type recordedConn struct {
+	net.Conn
+	writer *io.PipeWriter
+}
+
+func (c *recordedConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		_, _ = c.writer.Write(b[:n])
+	}
+	return n, err
+}
+
+func (c *recordedConn) Close() error {
+	_ = c.writer.Close()
+	return c.Conn.Close()
+}
+
+// withRecorder wrap dial function, requests sent to target are recorded
+func withRecorder(dial func(network, address string) (net.Conn, error), r *Recorder) func(network, address string) (net.Conn, error) {
+	return func(network, address string) (net.Conn, error) {
+		conn, err := dial(network, address)
+		if err != nil {
+			return nil, err
+		}
+		_, portText, _ := net.SplitHostPort(address)
+		port, _ := strconv.Atoi(portText)
+		reader, writer := io.Pipe()
+		go r.record(reader, port)
+		return &recordedConn{Conn: conn, writer: writer}, nil
+	}
+}
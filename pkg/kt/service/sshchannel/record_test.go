@@ -0,0 +1,51 @@
+package sshchannel
+
+import (
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "record.jsonl")
+	r, err := newRecorder(path, 4, "authorization, cookie")
+	require.Nil(t, err)
+	info, err := os.Stat(path)
+	require.Nil(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm(), "record file should only be accessible by owner")
+
+	local, remote := net.Pipe()
+	dial := withRecorder(func(_, _ string) (net.Conn, error) {
+		return local, nil
+	}, r)
+	conn, err := dial("tcp", "127.0.0.1:8080")
+	require.Nil(t, err)
+	go func() {
+		_, _ = io.Copy(io.Discard, remote)
+	}()
+	_, err = conn.Write([]byte("POST /api?id=1 HTTP/1.1\r\nHost: app.default\r\nAuthorization: Bearer abc\r\n" +
+		"Content-Length: 6\r\n\r\nabcdef"))
+	require.Nil(t, err)
+	_, err = conn.Write([]byte("GET /health HTTP/1.1\r\nHost: app.default\r\n\r\n"))
+	require.Nil(t, err)
+	_ = conn.Close()
+
+	var records []RecordedRequest
+	require.Eventually(t, func() bool {
+		records, err = ReadRecords(path)
+		return err == nil && len(records) == 2
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, "POST", records[0].Method)
+	require.Equal(t, "/api?id=1", records[0].Uri)
+	require.Equal(t, "app.default", records[0].Host)
+	require.Equal(t, 8080, records[0].Port)
+	require.Equal(t, []string{RedactedValue}, records[0].Header["Authorization"])
+	require.Equal(t, "abcd", string(records[0].Body))
+	require.True(t, records[0].BodyTruncated)
+	require.Equal(t, "/health", records[1].Uri)
+	require.False(t, records[1].BodyTruncated)
+}
@@ -0,0 +1,61 @@
+package sshchannel
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// retryInterval time between attempts of connecting local service during restart grace period
+const retryInterval = 200 * time.Millisecond
+
+// retryDialer dial target repeatedly until grace period since it became unavailable passed
+type retryDialer struct {
+	dial  func(network, address string) (net.Conn, error)
+	grace time.Duration
+	// downSince time when target became unavailable, zero if it's available
+	downSince time.Time
+	sync.Mutex
+}
+
+// withRetry wrap dial function, connections are held and retried while target is restarting, once target
+// has been unavailable longer than grace period, connections fail immediately until it's back
+func withRetry(dial func(network, address string) (net.Conn, error), grace time.Duration) func(network, address string) (net.Conn, error) {
+	r := &retryDialer{dial: dial, grace: grace}
+	return r.dialWithRetry
+}
+
+func (r *retryDialer) dialWithRetry(network, address string) (net.Conn, error) {
+	for {
+		conn, err := r.dial(network, address)
+		if err == nil {
+			r.markUp(address)
+			return conn, nil
+		}
+		if time.Since(r.markDown(address)) >= r.grace {
+			return nil, err
+		}
+		time.Sleep(retryInterval)
+	}
+}
+
+func (r *retryDialer) markUp(address string) {
+	r.Lock()
+	defer r.Unlock()
+	if !r.downSince.IsZero() {
+		log.Info().Msgf("Local service %s is back after %s", address, time.Since(r.downSince).Round(time.Millisecond))
+		r.downSince = time.Time{}
+	}
+}
+
+func (r *retryDialer) markDown(address string) time.Time {
+	r.Lock()
+	defer r.Unlock()
+	if r.downSince.IsZero() {
+		log.Info().Msgf("Local service %s unavailable, holding requests for up to %s", address, r.grace)
+		r.downSince = time.Now()
+	}
+	return r.downSince
+}
@@ -0,0 +1,37 @@
+package sshchannel
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestWithRetry(t *testing.T) {
+	attempts := 0
+	dial := withRetry(func(network, address string) (net.Conn, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, fmt.Errorf("connection refused")
+		}
+		client, _ := net.Pipe()
+		return client, nil
+	}, 2*time.Second)
+	conn, err := dial("tcp", "127.0.0.1:8080")
+	require.Nil(t, err, "connection should succeed once local service is back")
+	_ = conn.Close()
+	require.Equal(t, 3, attempts)
+
+	dial = withRetry(func(network, address string) (net.Conn, error) {
+		return nil, fmt.Errorf("connection refused")
+	}, 500*time.Millisecond)
+	start := time.Now()
+	_, err = dial("tcp", "127.0.0.1:8080")
+	require.NotNil(t, err)
+	require.True(t, time.Since(start) >= 500*time.Millisecond, "should retry during grace period")
+	start = time.Now()
+	_, err = dial("tcp", "127.0.0.1:8080")
+	require.NotNil(t, err)
+	require.True(t, time.Since(start) < retryInterval, "should fail immediately after grace period passed")
+}
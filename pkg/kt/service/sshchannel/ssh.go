@@ -5,18 +5,25 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
 	"io"
 	"net"
+	"os"
+	"path"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/wzshiming/socks5"
-	"github.com/wzshiming/sshproxy"
+	"golang.org/x/crypto/ssh"
 )
 
-type SocksLogger struct {}
+// largeFileSize files bigger than 10 MB are logged when uploading
+const largeFileSize = 10 * 1024 * 1024
+
+type SocksLogger struct{}
 
 func (s SocksLogger) Println(v ...any) {
 	_, _ = util.BackgroundLogger.Write([]byte(fmt.Sprint(v...) + util.Eol))
@@ -24,7 +31,7 @@ func (s SocksLogger) Println(v ...any) {
 
 // StartSocks5Proxy start socks5 proxy
 func (c *Cli) StartSocks5Proxy(privateKey, sshAddress, socks5Address string) (err error) {
-	dialer, err := sshproxy.NewDialer(getSshTunnelAddress(privateKey, sshAddress))
+	dialer, err := newDialer(privateKey, sshAddress)
 	if err != nil {
 		return err
 	}
@@ -39,7 +46,7 @@ func (c *Cli) StartSocks5Proxy(privateKey, sshAddress, socks5Address string) (er
 
 // RunScript run the script on remote host.
 func (c *Cli) RunScript(privateKey, sshAddress, script string) (result string, err error) {
-	dialer, err := sshproxy.NewDialer(getSshTunnelAddress(privateKey, sshAddress))
+	dialer, err := newDialer(privateKey, sshAddress)
 	if err != nil {
 		return "", err
 	}
@@ -70,10 +77,110 @@ func (c *Cli) RunScript(privateKey, sshAddress, script string) (result string, e
 	return output, nil
 }
 
+// UploadFiles copy local files to remote host, key is local path and value is remote path
+func (c *Cli) UploadFiles(privateKey, sshAddress string, files map[string]string) error {
+	dialer, err := newDialer(privateKey, sshAddress)
+	if err != nil {
+		return err
+	}
+	defer dialer.Close()
+
+	conn, err := dialer.SSHClient(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for localPath, remotePath := range files {
+		if err = uploadFile(conn, localPath, remotePath); err != nil {
+			return fmt.Errorf("failed to upload %s: %s", localPath, err)
+		}
+	}
+	return nil
+}
+
+// uploadFile stream file content to a temporary remote file, then rename it, so partial file would never be seen
+func uploadFile(conn *ssh.Client, localPath, remotePath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > largeFileSize {
+		log.Info().Msgf("Uploading large file %s (%d MB)", localPath, info.Size()/1024/1024)
+	}
+
+	session, err := conn.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	tmpPath := remotePath + ".kt-sync"
+	session.Stdin = f
+	return session.Run(fmt.Sprintf("mkdir -p %s && cat > %s && chmod %o %s && mv -f %s %s",
+		util.ShellQuote(path.Dir(remotePath)), util.ShellQuote(tmpPath), info.Mode().Perm(),
+		util.ShellQuote(tmpPath), util.ShellQuote(tmpPath), util.ShellQuote(remotePath)))
+}
+
 // ForwardRemoteToLocal forward remote request to local
 func (c *Cli) ForwardRemoteToLocal(privateKey, sshAddress, remoteEndpoint, localEndpoint string) error {
+	return c.forwardRemote(privateKey, sshAddress, remoteEndpoint, localEndpoint, false)
+}
+
+// ForwardRemoteToRemote forward remote request to another address accessible from remote host
+func (c *Cli) ForwardRemoteToRemote(privateKey, sshAddress, remoteEndpoint, targetEndpoint string) error {
+	return c.forwardRemote(privateKey, sshAddress, remoteEndpoint, targetEndpoint, true)
+}
+
+// ForwardLocalToRemote listen on local endpoint, and forward requests to address accessible from remote host,
+// requests are served in background, error is returned only if local endpoint cannot be listened
+func (c *Cli) ForwardLocalToRemote(privateKey, sshAddress, localEndpoint, remoteEndpoint string) error {
+	dialer, err := newDialer(privateKey, sshAddress)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", localEndpoint)
+	if err != nil {
+		_ = dialer.Close()
+		return err
+	}
+	log.Info().Msgf("Tunnel %s -> %s established", localEndpoint, remoteEndpoint)
+	dial, closePool := withChannelPool(func(network, address string) (net.Conn, error) {
+		// dialer reconnects ssh automatically if previous connection is broken
+		return dialer.DialContext(context.Background(), network, address)
+	}, remoteEndpoint)
+	go func() {
+		defer dialer.Close()
+		defer closePool()
+		defer listener.Close()
+		for {
+			client, err2 := listener.Accept()
+			if err2 != nil {
+				log.Error().Err(err2).Msgf("Failed to accept local request")
+				return
+			}
+			go func() {
+				remote, err3 := dial("tcp", remoteEndpoint)
+				if err3 != nil {
+					_ = client.Close()
+					log.Warn().Err(err3).Msgf("Failed to connect %s via shadow pod", remoteEndpoint)
+					return
+				}
+				handleClient(client, remote)
+			}()
+		}
+	}()
+	return nil
+}
+
+func (c *Cli) forwardRemote(privateKey, sshAddress, remoteEndpoint, targetEndpoint string, targetOnRemote bool) error {
 	// Handle incoming connections on reverse forwarded tunnel
-	dialer, err := sshproxy.NewDialer(getSshTunnelAddress(privateKey, sshAddress))
+	dialer, err := newDialer(privateKey, sshAddress)
 	if err != nil {
 		return err
 	}
@@ -93,17 +200,77 @@ func (c *Cli) ForwardRemoteToLocal(privateKey, sshAddress, remoteEndpoint, local
 		return err
 	}
 	defer listener.Close()
+	if !c.addListener(listener) {
+		return nil
+	}
 
-	log.Info().Msgf("Reverse tunnel %s -> %s established", remoteEndpoint, localEndpoint)
+	dial := net.Dial
+	// channels of reverse tunnel are opened by remote side, only channels to target on remote could be pooled
+	if targetOnRemote {
+		var closePool func()
+		dial, closePool = withChannelPool(func(network, address string) (net.Conn, error) {
+			return dialer.DialContext(context.Background(), network, address)
+		}, targetEndpoint)
+		defer closePool()
+	}
+	if !targetOnRemote && opt.Get().Global.RestartGrace > 0 {
+		dial = withRetry(dial, time.Duration(opt.Get().Global.RestartGrace)*time.Second)
+	}
+	if !targetOnRemote && opt.Get().Global.StubUnbound != "" {
+		status, message, _ := ParseStubResponse(opt.Get().Global.StubUnbound)
+		dial = withStub(dial, status, message)
+	}
+	if !targetOnRemote && activeThrottle != nil {
+		dial = withThrottle(dial, activeThrottle)
+	}
+	if !targetOnRemote && activeRecorder != nil {
+		dial = withRecorder(dial, activeRecorder)
+	}
+	log.Info().Msgf("Reverse tunnel %s -> %s established", remoteEndpoint, targetEndpoint)
 	for {
-		if err = handleRequest(listener, localEndpoint); errors.Is(err, io.EOF) {
+		if err = c.handleRequest(listener, targetEndpoint, dial); c.isDraining() {
+			return nil
+		} else if errors.Is(err, io.EOF) {
 			return err
 		}
 	}
 }
 
-func getSshTunnelAddress(privateKey string, sshAddress string) string {
-	return fmt.Sprintf("ssh://root@%s?identity_file=%s", sshAddress, privateKey)
+// Drain stop accepting new requests from remote, and wait for in-flight requests to finish
+func (c *Cli) Drain(timeout time.Duration) bool {
+	c.Lock()
+	c.draining = true
+	for _, listener := range c.listeners {
+		_ = listener.Close()
+	}
+	c.listeners = nil
+	c.Unlock()
+
+	deadline := time.Now().Add(timeout)
+	for atomic.LoadInt32(&c.activeConns) > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		log.Debug().Msgf("Waiting for %d in-flight requests ...", atomic.LoadInt32(&c.activeConns))
+		time.Sleep(500 * time.Millisecond)
+	}
+	return true
+}
+
+func (c *Cli) addListener(listener net.Listener) bool {
+	c.Lock()
+	defer c.Unlock()
+	if c.draining {
+		return false
+	}
+	c.listeners = append(c.listeners, listener)
+	return true
+}
+
+func (c *Cli) isDraining() bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.draining
 }
 
 func disconnectRemotePort(privateKey, sshAddress, remoteEndpoint string, c *Cli) {
@@ -117,7 +284,7 @@ func disconnectRemotePort(privateKey, sshAddress, remoteEndpoint string, c *Cli)
 	}
 }
 
-func handleRequest(listener net.Listener, localEndpoint string) error {
+func (c *Cli) handleRequest(listener net.Listener, localEndpoint string, dial func(string, string) (net.Conn, error)) error {
 	defer func() {
 		if r := recover(); r != nil {
 			log.Error().Msgf("Failed to handle request: %v", r)
@@ -127,6 +294,9 @@ func handleRequest(listener net.Listener, localEndpoint string) error {
 	// Wait requests from remote endpoint
 	client, err := listener.Accept()
 	if err != nil {
+		if c.isDraining() {
+			return err
+		}
 		log.Error().Err(err).Msgf("Failed to accept remote request")
 		if !errors.Is(err, io.EOF) {
 			time.Sleep(2 * time.Second)
@@ -135,7 +305,7 @@ func handleRequest(listener net.Listener, localEndpoint string) error {
 	}
 
 	// Open a (local) connection to localEndpoint whose content will be forwarded to remoteEndpoint
-	local, err := net.Dial("tcp", localEndpoint)
+	local, err := dial("tcp", localEndpoint)
 	if err != nil {
 		_ = client.Close()
 		log.Error().Err(err).Msgf("Local service error")
@@ -143,7 +313,11 @@ func handleRequest(listener net.Listener, localEndpoint string) error {
 	}
 
 	// Handle request in individual coroutine, current coroutine continue to accept more requests
-	go handleClient(client, local)
+	atomic.AddInt32(&c.activeConns, 1)
+	go func() {
+		defer atomic.AddInt32(&c.activeConns, -1)
+		handleClient(client, local)
+	}()
 	return nil
 }
 
@@ -157,7 +331,7 @@ func handleClient(client net.Conn, remote net.Conn) {
 		if _, err := io.Copy(client, remoteReader); err != nil {
 			log.Warn().Err(err).Msgf("Error while copy remote->local")
 		}
-		done<-1
+		done <- 1
 	}()
 
 	// Start local -> remote data transfer
@@ -167,7 +341,7 @@ func handleClient(client net.Conn, remote net.Conn) {
 		if _, err := io.Copy(remote, localReader); err != nil {
 			log.Warn().Err(err).Msgf("Error while copy local->remote")
 		}
-		done<-1
+		done <- 1
 	}()
 
 	<-done
@@ -180,6 +354,6 @@ func handleClient(client net.Conn, remote net.Conn) {
 func handleBrokenTunnel(done chan int) {
 	if r := recover(); r != nil {
 		log.Error().Msgf("Ssh tunnel broken: %v", r)
-		done<-1
+		done <- 1
 	}
 }
@@ -0,0 +1,57 @@
+package sshchannel
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// stubReadTimeout time to wait for request header before sending stub response
+const stubReadTimeout = 3 * time.Second
+
+// ParseStubResponse parse stub response in '<status>[:<message>]' format, empty text means stub disabled
+func ParseStubResponse(text string) (int, string, error) {
+	if text == "" {
+		return 0, "", nil
+	}
+	statusText, message, _ := strings.Cut(text, ":")
+	status, err := strconv.Atoi(statusText)
+	if err != nil || status < 100 || status > 599 {
+		return 0, "", fmt.Errorf("invalid stub response '%s', should be in '<status>:<message>' format, e.g. '503:not started'", text)
+	}
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return status, message, nil
+}
+
+// withStub wrap dial function, connections to target without live listener are answered with canned http response
+func withStub(dial func(network, address string) (net.Conn, error), status int, message string) func(network, address string) (net.Conn, error) {
+	return func(network, address string) (net.Conn, error) {
+		conn, err := dial(network, address)
+		if err == nil {
+			return conn, nil
+		}
+		log.Debug().Err(err).Msgf("Local service %s unavailable, responding with stub", address)
+		client, server := net.Pipe()
+		go serveStub(server, status, message)
+		return client, nil
+	}
+}
+
+func serveStub(conn net.Conn, status int, message string) {
+	defer conn.Close()
+	// read request header before responding, so that client would not see connection reset
+	_ = conn.SetReadDeadline(time.Now().Add(stubReadTimeout))
+	_, _ = http.ReadRequest(bufio.NewReader(conn))
+	_ = conn.SetDeadline(time.Now().Add(stubReadTimeout))
+	body := message + "\n"
+	_, _ = fmt.Fprintf(conn, "HTTP/1.1 %d %s\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Length: %d\r\n"+
+		"Connection: close\r\n\r\n%s", status, http.StatusText(status), len(body), body)
+}
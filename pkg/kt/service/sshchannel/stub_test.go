@@ -0,0 +1,54 @@
+package sshchannel
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestParseStubResponse(t *testing.T) {
+	status, message, err := ParseStubResponse("503:Local service not started")
+	require.Nil(t, err)
+	require.Equal(t, 503, status)
+	require.Equal(t, "Local service not started", message)
+	status, message, err = ParseStubResponse("502")
+	require.Nil(t, err)
+	require.Equal(t, 502, status)
+	require.Equal(t, "Bad Gateway", message)
+	status, _, err = ParseStubResponse("")
+	require.Nil(t, err)
+	require.Equal(t, 0, status)
+	_, _, err = ParseStubResponse("abc:message")
+	require.NotNil(t, err)
+	_, _, err = ParseStubResponse("999")
+	require.NotNil(t, err)
+}
+
+func TestWithStub(t *testing.T) {
+	dial := withStub(func(network, address string) (net.Conn, error) {
+		return nil, fmt.Errorf("connection refused")
+	}, 503, "not started")
+	conn, err := dial("tcp", "127.0.0.1:8080")
+	require.Nil(t, err)
+	defer conn.Close()
+	go func() {
+		_, _ = conn.Write([]byte("GET / HTTP/1.1\r\nHost: app\r\n\r\n"))
+	}()
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.Nil(t, err)
+	require.Equal(t, 503, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.Nil(t, err)
+	require.Equal(t, "not started\n", string(body))
+
+	listener := startEchoServer(t)
+	dial = withStub(net.Dial, 503, "not started")
+	conn, err = dial("tcp", listener.Addr().String())
+	require.Nil(t, err)
+	require.Nil(t, echo(conn), "live listener should be connected directly")
+	_ = conn.Close()
+}
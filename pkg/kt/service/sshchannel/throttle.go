@@ -0,0 +1,107 @@
+package sshchannel
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// throttleBurst max bytes transferred at once, so that large chunks could still pass a low rate limit
+const throttleBurst = 32 * 1024
+
+// Throttle limitation applied to each connection forwarded to local
+type Throttle struct {
+	// bytesPerSecond max rate of each direction, 0 for unlimited
+	bytesPerSecond int
+	// latency delay before data from remote is delivered to local
+	latency time.Duration
+}
+
+// activeThrottle limitation of connections forwarded to local, nil if not enabled
+var activeThrottle *Throttle
+
+// StartThrottle limit bandwidth and add latency to connections forwarded to local via reverse tunnels
+func StartThrottle(bytesPerSecond int, latency time.Duration) {
+	activeThrottle = &Throttle{bytesPerSecond: bytesPerSecond, latency: latency}
+}
+
+// ParseRate parse bandwidth text like '512', '100K', '100KB', '1M/s' into bytes per second
+func ParseRate(text string) (int, error) {
+	value := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(text)), "/S")
+	value = strings.TrimSuffix(value, "B")
+	unit := 1
+	for suffix, size := range map[string]int{"K": 1024, "M": 1024 * 1024, "G": 1024 * 1024 * 1024} {
+		if strings.HasSuffix(value, suffix) {
+			value = strings.TrimSuffix(value, suffix)
+			unit = size
+		}
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number*float64(unit) < 1 {
+		return 0, fmt.Errorf("invalid rate '%s', should be bytes per second like '512', '100K' or '1M'", text)
+	}
+	return int(number * float64(unit)), nil
+}
+
+// throttledConn connection whose read and write are limited by token buckets
+type throttledConn struct {
+	net.Conn
+	reader  *rate.Limiter
+	writer  *rate.Limiter
+	latency time.Duration
+}
+
+func (c *throttledConn) Read(b []byte) (int, error) {
+	if c.reader != nil && len(b) > throttleBurst {
+		b = b[:throttleBurst]
+	}
+	n, err := c.Conn.Read(b)
+	if n > 0 && c.reader != nil {
+		_ = c.reader.WaitN(context.Background(), n)
+	}
+	return n, err
+}
+
+func (c *throttledConn) Write(b []byte) (int, error) {
+	if c.latency > 0 {
+		time.Sleep(c.latency)
+	}
+	written := 0
+	for written < len(b) {
+		chunk := b[written:]
+		if c.writer != nil {
+			if len(chunk) > throttleBurst {
+				chunk = chunk[:throttleBurst]
+			}
+			_ = c.writer.WaitN(context.Background(), len(chunk))
+		}
+		n, err := c.Conn.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// withThrottle wrap dial function, only connections to local service are throttled, the ssh connection itself
+// is left untouched, so that its keepalive and tunnel reconnecting are not affected
+func withThrottle(dial func(network, address string) (net.Conn, error), t *Throttle) func(network, address string) (net.Conn, error) {
+	return func(network, address string) (net.Conn, error) {
+		conn, err := dial(network, address)
+		if err != nil {
+			return nil, err
+		}
+		tc := &throttledConn{Conn: conn, latency: t.latency}
+		if t.bytesPerSecond > 0 {
+			tc.reader = rate.NewLimiter(rate.Limit(t.bytesPerSecond), throttleBurst)
+			tc.writer = rate.NewLimiter(rate.Limit(t.bytesPerSecond), throttleBurst)
+		}
+		return tc, nil
+	}
+}
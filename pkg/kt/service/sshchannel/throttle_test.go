@@ -0,0 +1,42 @@
+package sshchannel
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRate(t *testing.T) {
+	for text, expected := range map[string]int{"512": 512, "100K": 100 * 1024, "100kb": 100 * 1024, "1M/s": 1024 * 1024, "1.5K": 1536} {
+		rate, err := ParseRate(text)
+		require.Nil(t, err, text)
+		require.Equal(t, expected, rate, text)
+	}
+	for _, text := range []string{"", "fast", "0", "-1K"} {
+		_, err := ParseRate(text)
+		require.NotNil(t, err, text)
+	}
+}
+
+func TestWithThrottle(t *testing.T) {
+	client, server := net.Pipe()
+	dial := withThrottle(func(_, _ string) (net.Conn, error) {
+		return client, nil
+	}, &Throttle{bytesPerSecond: throttleBurst, latency: 100 * time.Millisecond})
+	conn, err := dial("tcp", "127.0.0.1:8080")
+	require.Nil(t, err)
+	go func() {
+		_, _ = io.Copy(io.Discard, server)
+	}()
+
+	start := time.Now()
+	// the first burst passes immediately, the second one waits for tokens
+	n, err := conn.Write(make([]byte, 2*throttleBurst))
+	require.Nil(t, err)
+	require.Equal(t, 2*throttleBurst, n)
+	require.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+	_ = conn.Close()
+}
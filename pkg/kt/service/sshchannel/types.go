@@ -1,15 +1,34 @@
 package sshchannel
 
+import (
+	"net"
+	"sync"
+	"time"
+)
+
 // Channel network channel
 type Channel interface {
 	StartSocks5Proxy(privateKey, sshAddress, socks5Address string) error
 	ForwardRemoteToLocal(privateKey, sshAddress, remoteEndpoint, localEndpoint string) error
+	ForwardRemoteToRemote(privateKey, sshAddress, remoteEndpoint, targetEndpoint string) error
+	ForwardLocalToRemote(privateKey, sshAddress, localEndpoint, remoteEndpoint string) error
 	RunScript(privateKey, sshAddress, script string) (string, error)
+	UploadFiles(privateKey, sshAddress string, files map[string]string) error
+	Drain(timeout time.Duration) bool
 }
 
 // Cli the singleton type
-type Cli struct {}
-var instance *Cli
+type Cli struct {
+	// listeners reverse tunnel listeners on remote side
+	listeners []net.Listener
+	// draining whether stop accepting new requests
+	draining bool
+	// activeConns count of requests being forwarded
+	activeConns int32
+	sync.Mutex
+}
+
+var instance Channel
 
 // Ins get singleton instance
 func Ins() Channel {
@@ -18,3 +37,8 @@ func Ins() Channel {
 	}
 	return instance
 }
+
+// SetIns replace the singleton instance, e.g. with a stub implementation in tests
+func SetIns(c Channel) {
+	instance = c
+}
@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"github.com/alibaba/kt-connect/pkg/common"
 	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/sshchannel"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
 	"io"
 	"os"
 	"os/exec"
+	"strings"
 )
 
 // Version check sshuttle version
@@ -36,6 +38,11 @@ func (s *Cli) Connect(req *SSHVPNRequest) *exec.Cmd {
 	}
 
 	subCommand := fmt.Sprintf("ssh -oStrictHostKeyChecking=no -oUserKnownHostsFile=/dev/null -i %s", req.RemoteSSHPKPath)
+	subCommand += fmt.Sprintf(" -oCiphers=%s -oKexAlgorithms=%s -oMACs=%s", strings.Join(sshchannel.Ciphers(), ","),
+		strings.Join(sshchannel.KeyExchanges(), ","), strings.Join(sshchannel.MACs(), ","))
+	if opt.Get().Connect.Compression {
+		subCommand += " -C"
+	}
 	remoteAddr := fmt.Sprintf("root@%s:%d", common.Localhost, req.LocalSshPort)
 	args = append(args, "--ssh-cmd", subCommand, "--remote", remoteAddr, "--exclude", common.Localhost)
 	if opt.Get().Connect.ExcludeIps != "" {
@@ -23,10 +23,11 @@ func (s *Cli) ToSocks(sockAddr string) error {
 		var key = new(engine.Key)
 		key.Proxy = sockAddr
 		key.Device = fmt.Sprintf("tun://%s", s.GetName())
+		key.MTU = opt.Get().Connect.Mtu
 		key.LogLevel = logLevel
 		tunLog.SetOutput(util.BackgroundLogger)
 		engine.Insert(key)
-		tunSignal <-engine.Start()
+		tunSignal <- engine.Start()
 
 		defer func() {
 			if err := engine.Stop(); err != nil {
@@ -2,9 +2,13 @@ package tun
 
 import (
 	"fmt"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -13,6 +17,14 @@ func (s *Cli) CheckContext() error {
 	if !util.CanRun(exec.Command("which", "ip")) {
 		return fmt.Errorf("failed to found 'ip' command")
 	}
+	if opt.Get().Connect.Exec != "" {
+		if !util.CanRun(exec.Command("which", "iptables")) {
+			return fmt.Errorf("failed to found 'iptables' command, which is required by '--exec'")
+		}
+		if _, err := os.Stat(filepath.Join(util.CgroupRootLinux, "cgroup.controllers")); err != nil {
+			return fmt.Errorf("cgroup v2 is not mounted at %s, which is required by '--exec'", util.CgroupRootLinux)
+		}
+	}
 	return nil
 }
 
@@ -32,16 +44,16 @@ func (s *Cli) SetRoute(ipRange []string, excludeIpRange []string) error {
 	}
 	var lastErr error
 	anyRouteOk := false
+	tcpOnly := opt.Get().Connect.TcpOnly
+	scoped := opt.Get().Connect.Exec != ""
 	for _, r := range ipRange {
 		log.Info().Msgf("Adding route to %s", r)
-		// run command: ip route add 10.96.0.0/16 dev kt0
-		_, _, err = util.RunAndWait(exec.Command("ip",
-			"route",
-			"add",
-			r,
-			"dev",
-			s.GetName(),
-		))
+		// run command: ip route add 10.96.0.0/16 dev kt0 [table 7556]
+		_, _, err = util.RunAndWait(exec.Command("ip", routeArgs(r, s.GetName(), tcpOnly || scoped)...))
+		if err == nil && tcpOnly {
+			// run command: ip rule add to 10.96.0.0/16 ipproto tcp lookup 7556 priority 7556
+			_, _, err = util.RunAndWait(exec.Command("ip", ruleArgs("add", r)...))
+		}
 		if err != nil {
 			log.Warn().Msgf("Failed to set route %s to tun device", r)
 			lastErr = err
@@ -52,17 +64,24 @@ func (s *Cli) SetRoute(ipRange []string, excludeIpRange []string) error {
 	if !anyRouteOk {
 		return AllRouteFailError{lastErr}
 	}
+	if scoped {
+		if err = setupProcessScope(s.GetName()); err != nil {
+			log.Error().Msgf("Failed to limit route to process started by '--exec'")
+			return AllRouteFailError{err}
+		}
+	}
 	return lastErr
 }
 
 // CheckRoute check whether all route rule setup properly
 func (s *Cli) CheckRoute(ipRange []string) []string {
 	var failedIpRange []string
-	// run command: ip route show
-	out, _, err := util.RunAndWait(exec.Command("ip",
-		"route",
-		"show",
-	))
+	// run command: ip route show [table 7556]
+	args := []string{"route", "show"}
+	if opt.Get().Connect.TcpOnly || opt.Get().Connect.Exec != "" {
+		args = append(args, "table", util.TunRouteTableLinux)
+	}
+	out, _, err := util.RunAndWait(exec.Command("ip", args...))
 	if err != nil {
 		log.Warn().Msgf("Failed to get route table")
 		return []string{}
@@ -87,8 +106,77 @@ func (s *Cli) CheckRoute(ipRange []string) []string {
 
 // RestoreRoute delete route rules made by kt
 func (s *Cli) RestoreRoute() error {
-	// Route will be auto removed when tun device destroyed
-	return nil
+	// Route will be auto removed when tun device destroyed, but policy routing rules will not
+	if !opt.Get().Connect.TcpOnly && opt.Get().Connect.Exec == "" {
+		return nil
+	}
+	// run command: ip rule del lookup 7556 priority 7556, until no rule left
+	for i := 0; i < maxRuleCount; i++ {
+		if _, _, err := util.RunAndWait(exec.Command("ip", ruleArgs("del", "")...)); err != nil {
+			break
+		}
+	}
+	if opt.Get().Connect.Exec == "" {
+		return nil
+	}
+	// run command: iptables -t mangle -D OUTPUT -m cgroup --path kt-connect-exec -j MARK --set-mark 0x1d84
+	_, _, err := util.RunAndWait(exec.Command("iptables", markArgs("-D")...))
+	// cgroup can only be removed after all processes in it exited
+	if err2 := os.Remove(filepath.Join(util.CgroupRootLinux, util.TunCgroupLinux)); err2 != nil && !os.IsNotExist(err2) {
+		log.Debug().Err(err2).Msgf("Failed to remove cgroup %s", util.TunCgroupLinux)
+	}
+	return err
+}
+
+// setupProcessScope only let traffic from processes in kt cgroup use route table of tun device
+func setupProcessScope(device string) error {
+	if err := os.MkdirAll(filepath.Join(util.CgroupRootLinux, util.TunCgroupLinux), 0755); err != nil {
+		return err
+	}
+	// run command: iptables -t mangle -A OUTPUT -m cgroup --path kt-connect-exec -j MARK --set-mark 0x1d84
+	if _, _, err := util.RunAndWait(exec.Command("iptables", markArgs("-A")...)); err != nil {
+		return err
+	}
+	// run command: ip rule add fwmark 0x1d84 lookup 7556 priority 7556
+	if _, _, err := util.RunAndWait(exec.Command("ip", "rule", "add", "fwmark", util.TunFwmarkLinux,
+		"lookup", util.TunRouteTableLinux, "priority", util.TunRulePriorityLinux)); err != nil {
+		return err
+	}
+	// responses arrive from tun device, while the reverse path of cluster ips is not in main route table
+	// run command: sysctl -w net.ipv4.conf.kt0.rp_filter=2
+	_, _, err := util.RunAndWait(exec.Command("sysctl", "-w", fmt.Sprintf("net.ipv4.conf.%s.rp_filter=2", device)))
+	return err
+}
+
+// JoinProcessScope move process into kt cgroup, so that its traffic is routed to tun device
+func JoinProcessScope(pid int) error {
+	procsFile := filepath.Join(util.CgroupRootLinux, util.TunCgroupLinux, "cgroup.procs")
+	return os.WriteFile(procsFile, []byte(strconv.Itoa(pid)), 0644)
+}
+
+// maxRuleCount upper limit of policy routing rules to delete, avoid endless loop
+const maxRuleCount = 256
+
+func routeArgs(ipRange, device string, tcpOnly bool) []string {
+	args := []string{"route", "add", ipRange, "dev", device}
+	if tcpOnly {
+		// keep route out of main table, so that only traffic matches the rule would use it
+		args = append(args, "table", util.TunRouteTableLinux)
+	}
+	return args
+}
+
+func markArgs(action string) []string {
+	return []string{"-t", "mangle", action, "OUTPUT", "-m", "cgroup", "--path", util.TunCgroupLinux,
+		"-j", "MARK", "--set-mark", util.TunFwmarkLinux}
+}
+
+func ruleArgs(action, ipRange string) []string {
+	args := []string{"rule", action}
+	if ipRange != "" {
+		args = append(args, "to", ipRange, "ipproto", "tcp")
+	}
+	return append(args, "lookup", util.TunRouteTableLinux, "priority", util.TunRulePriorityLinux)
 }
 
 func (s *Cli) GetName() string {
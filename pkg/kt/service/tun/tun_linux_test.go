@@ -0,0 +1,23 @@
+package tun
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_routeArgs(t *testing.T) {
+	require.Equal(t, []string{"route", "add", "10.96.0.0/16", "dev", "kt0"}, routeArgs("10.96.0.0/16", "kt0", false))
+	require.Equal(t, []string{"route", "add", "10.96.0.0/16", "dev", "kt0", "table", "7556"},
+		routeArgs("10.96.0.0/16", "kt0", true))
+}
+
+func Test_ruleArgs(t *testing.T) {
+	require.Equal(t, []string{"rule", "add", "to", "10.96.0.0/16", "ipproto", "tcp", "lookup", "7556", "priority", "7556"},
+		ruleArgs("add", "10.96.0.0/16"))
+	require.Equal(t, []string{"rule", "del", "lookup", "7556", "priority", "7556"}, ruleArgs("del", ""))
+}
+
+func Test_markArgs(t *testing.T) {
+	require.Equal(t, []string{"-t", "mangle", "-A", "OUTPUT", "-m", "cgroup", "--path", "kt-connect-exec",
+		"-j", "MARK", "--set-mark", "0x1d84"}, markArgs("-A"))
+}
@@ -0,0 +1,105 @@
+package transmission
+
+import (
+	"github.com/alibaba/kt-connect/pkg/common"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/rs/zerolog/log"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// directDialTimeout timeout of checking whether pod ip is directly reachable
+const directDialTimeout = 500 * time.Millisecond
+
+// directRelays listeners of direct connections, closed on exit
+var directRelays []net.Listener
+var directRelaysLock sync.Mutex
+
+// getReachablePodIp return ip of pod if '--directPodIp' specified and its ssh port could be dialed directly,
+// otherwise empty, relay of pod ip has no heartbeat and reconnecting like port-forward, thus never used by default
+func getReachablePodIp(podName string) string {
+	if !opt.Get().Global.DirectPodIp {
+		return ""
+	}
+	if isProxyConfigured() {
+		// traffic to cluster is expected to go through the proxy, never bypass it
+		log.Debug().Msgf("Proxy is configured, using port-forward")
+		return ""
+	}
+	pod, err := cluster.Ins().GetPod(podName, opt.Get().Global.Namespace)
+	if err != nil || pod.Status.PodIP == "" {
+		return ""
+	}
+	address := net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(common.StandardSshPort))
+	conn, err := net.DialTimeout("tcp", address, directDialTimeout)
+	if err != nil {
+		log.Debug().Msgf("Pod ip %s is not directly reachable, using port-forward", pod.Status.PodIP)
+		return ""
+	}
+	_ = conn.Close()
+	return pod.Status.PodIP
+}
+
+// relayPortToLocal relay local port to target address, in place of port-forward via api server
+func relayPortToLocal(target, listenAddress string, localPort int) error {
+	listener, err := net.Listen("tcp", net.JoinHostPort(listenAddress, strconv.Itoa(localPort)))
+	if err != nil {
+		return err
+	}
+	directRelaysLock.Lock()
+	directRelays = append(directRelays, listener)
+	directRelaysLock.Unlock()
+	go func() {
+		for {
+			conn, err2 := listener.Accept()
+			if err2 != nil {
+				return
+			}
+			go relayConn(conn, target)
+		}
+	}()
+	log.Info().Msgf("Direct connection %s:%d -> %s established", listenAddress, localPort, target)
+	return nil
+}
+
+// StopDirectRelays close listeners of all direct connections
+func StopDirectRelays() {
+	directRelaysLock.Lock()
+	defer directRelaysLock.Unlock()
+	for _, listener := range directRelays {
+		_ = listener.Close()
+	}
+	directRelays = nil
+}
+
+// isProxyConfigured check whether api server is accessed via proxy, by '--proxy' or environment variables
+func isProxyConfigured() bool {
+	if opt.Get().Global.Proxy != "" {
+		return true
+	}
+	for _, env := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"} {
+		if os.Getenv(env) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func relayConn(conn net.Conn, target string) {
+	defer conn.Close()
+	upstream, err := net.DialTimeout("tcp", target, directDialTimeout)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to dial %s", target)
+		return
+	}
+	defer upstream.Close()
+	go func() {
+		_, _ = io.Copy(upstream, conn)
+	}()
+	_, _ = io.Copy(conn, upstream)
+}
@@ -0,0 +1,70 @@
+package transmission
+
+import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster/fake"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/stretchr/testify/require"
+	"io"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net"
+	"strconv"
+	"testing"
+)
+
+func Test_getReachablePodIp(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	cluster.SetIns(fake.NewKubernetes(
+		&coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "unreachable", Namespace: "default"},
+			Status: coreV1.PodStatus{PodIP: "192.0.2.1"}},
+	))
+	defer cluster.SetIns(nil)
+	require.Empty(t, getReachablePodIp("unreachable"), "should use port-forward by default")
+
+	opt.Get().Global.DirectPodIp = true
+	defer func() { opt.Get().Global.DirectPodIp = false }()
+	require.Empty(t, getReachablePodIp("unreachable"), "should fall back to port-forward")
+	require.Empty(t, getReachablePodIp("not-exist"))
+}
+
+func Test_isProxyConfigured(t *testing.T) {
+	for _, env := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"} {
+		t.Setenv(env, "")
+	}
+	require.False(t, isProxyConfigured())
+	t.Setenv("https_proxy", "http://proxy.corp:3128")
+	require.True(t, isProxyConfigured())
+	t.Setenv("https_proxy", "")
+	opt.Get().Global.Proxy = "http://proxy.corp:3128"
+	defer func() { opt.Get().Global.Proxy = "" }()
+	require.True(t, isProxyConfigured())
+	opt.Get().Global.DirectPodIp = true
+	defer func() { opt.Get().Global.DirectPodIp = false }()
+	require.Empty(t, getReachablePodIp("any"), "should not bypass proxy")
+}
+
+func Test_relaySshPortToLocal(t *testing.T) {
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer upstream.Close()
+	go func() {
+		conn, err2 := upstream.Accept()
+		if err2 == nil {
+			_, _ = conn.Write([]byte("SSH-2.0-test"))
+			_ = conn.Close()
+		}
+	}()
+	localPort := util.GetRandomTcpPort()
+	require.Nil(t, relayPortToLocal(upstream.Addr().String(), "127.0.0.1", localPort))
+	conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(localPort)))
+	require.Nil(t, err)
+	defer conn.Close()
+	data, _ := io.ReadAll(conn)
+	require.Equal(t, "SSH-2.0-test", string(data))
+
+	StopDirectRelays()
+	_, err = net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(localPort)))
+	require.NotNil(t, err, "listener should be closed")
+}
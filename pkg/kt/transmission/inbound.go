@@ -6,6 +6,8 @@ import (
 	"github.com/alibaba/kt-connect/pkg/kt/service/sshchannel"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
+	"net"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -13,23 +15,50 @@ import (
 // ForwardPodToLocal mapping pod port to local port
 func ForwardPodToLocal(exposePorts, podName, privateKey string) (int, error) {
 	log.Info().Msgf("Forwarding pod %s to local via port %s", podName, exposePorts)
-	localSshPort := util.GetRandomTcpPort()
+	localSshPort, err := ForwardSshPortToLocal(podName)
+	if err != nil {
+		return -1, err
+	}
+	util.ReportProgress(podName, util.PhaseSshReady, fmt.Sprintf("local port %d", localSshPort))
 
-	// port forward pod 22 -> local <random port>
-	if _, err := SetupPortForwardToLocal(podName, common.StandardSshPort, localSshPort); err != nil {
+	err = ForwardRemotePortsViaSshTunnel(exposePorts, localSshPort, privateKey)
+	if err != nil {
 		return -1, err
 	}
+	util.ReportProgress(podName, util.PhaseTunnelEstablished, exposePorts)
+
+	return localSshPort, nil
+}
 
-	err := ForwardRemotePortsViaSshTunnel(exposePorts, localSshPort, privateKey)
+// ForwardSshPortToLocal port forward ssh port of pod to a random local port
+func ForwardSshPortToLocal(podName string) (int, error) {
+	localSshPort := util.GetRandomTcpPort()
+	listenAddress, err := GetListenAddress()
 	if err != nil {
 		return -1, err
 	}
 
+	// dial pod ip directly if enabled and reachable, e.g. running inside cluster or via vpn
+	if podIp := getReachablePodIp(podName); podIp != "" {
+		target := net.JoinHostPort(podIp, strconv.Itoa(common.StandardSshPort))
+		if err = relayPortToLocal(target, listenAddress, localSshPort); err != nil {
+			return -1, err
+		}
+		return localSshPort, nil
+	}
+	// port forward pod 22 -> local <random port>
+	if _, err = SetupPortForwardOnAddress(podName, listenAddress, common.StandardSshPort, localSshPort); err != nil {
+		return -1, err
+	}
 	return localSshPort, nil
 }
 
 // ForwardRemotePortsViaSshTunnel forward multiple remote ports to local
 func ForwardRemotePortsViaSshTunnel(exposePorts string, localSshPort int, privateKey string) error {
+	listenAddress, err := GetListenAddress()
+	if err != nil {
+		return err
+	}
 	// supports multi port-pairs
 	portPairs := strings.Split(exposePorts, ",")
 	res := make(chan error)
@@ -38,7 +67,7 @@ func ForwardRemotePortsViaSshTunnel(exposePorts string, localSshPort int, privat
 		if err2 != nil {
 			return err2
 		}
-		forwardRemotePortViaSshTunnel(localPort, remotePort, localSshPort, privateKey, res)
+		forwardRemotePortViaSshTunnel(listenAddress, localPort, remotePort, localSshPort, privateKey, res)
 	}
 	select {
 	case err := <-res:
@@ -53,21 +82,48 @@ func ForwardRemotePortsViaSshTunnel(exposePorts string, localSshPort int, privat
 }
 
 // ForwardRemotePortViaSshTunnel forward remote pod to local
-func forwardRemotePortViaSshTunnel(localPort, remotePort, localSshPort int, privateKey string, res chan error) {
-	remoteEndpoint := fmt.Sprintf("127.0.0.1:%d", localSshPort)
+func forwardRemotePortViaSshTunnel(listenAddress string, localPort, remotePort, localSshPort int, privateKey string, res chan error) {
+	remoteEndpoint := net.JoinHostPort(listenAddress, strconv.Itoa(localSshPort))
 	localEndpoint := fmt.Sprintf("0.0.0.0:%d", remotePort)
-	sshAddress := fmt.Sprintf("127.0.0.1:%d", localPort)
+	sshAddress := net.JoinHostPort(listenAddress, strconv.Itoa(localPort))
 	log.Debug().Msgf("Forwarding %s to local endpoint %s via %s", remoteEndpoint, localEndpoint, sshAddress)
-	sshReverseTunnel(privateKey, remoteEndpoint, localEndpoint, sshAddress, res)
+	sshReverseTunnel(sshchannel.Ins().ForwardRemoteToLocal, privateKey, remoteEndpoint, localEndpoint, sshAddress, res)
+}
+
+// ForwardRemotePortsToUpstream forward requests to specified ports of shadow pod to an upstream address in cluster
+func ForwardRemotePortsToUpstream(ports []int, upstreamIp string, localSshPort int, privateKey string) error {
+	listenAddress, err := GetListenAddress()
+	if err != nil {
+		return err
+	}
+	res := make(chan error)
+	for _, port := range ports {
+		sshAddress := net.JoinHostPort(listenAddress, strconv.Itoa(localSshPort))
+		remoteEndpoint := fmt.Sprintf("0.0.0.0:%d", port)
+		upstreamEndpoint := net.JoinHostPort(upstreamIp, strconv.Itoa(port))
+		log.Debug().Msgf("Forwarding %s to upstream endpoint %s via %s", remoteEndpoint, upstreamEndpoint, sshAddress)
+		sshReverseTunnel(sshchannel.Ins().ForwardRemoteToRemote, privateKey, sshAddress, remoteEndpoint, upstreamEndpoint, res)
+	}
+	select {
+	case err = <-res:
+		return err
+	case <-time.After(1 * time.Second):
+		go func() {
+			// consume the res channel to avoid block reverse tunnel
+			<-res
+		}()
+	}
+	return nil
 }
 
-func sshReverseTunnel(privateKey, remoteEndpoint, localEndpoint, sshAddress string, res chan error) {
+func sshReverseTunnel(forward func(string, string, string, string) error,
+	privateKey, remoteEndpoint, localEndpoint, sshAddress string, res chan error) {
 	go func() {
-		err := sshchannel.Ins().ForwardRemoteToLocal(privateKey, remoteEndpoint, localEndpoint, sshAddress)
+		err := forward(privateKey, remoteEndpoint, localEndpoint, sshAddress)
 		if err != nil {
 			if res != nil {
 				log.Error().Err(err).Msgf("Failed to setup reverse tunnel")
-				res <-err
+				res <- err
 			} else {
 				log.Debug().Err(err).Msgf("Reverse tunnel interrupted")
 			}
@@ -75,6 +131,6 @@ func sshReverseTunnel(privateKey, remoteEndpoint, localEndpoint, sshAddress stri
 
 		time.Sleep(10 * time.Second)
 		log.Debug().Msgf("Reverse tunnel reconnecting ...")
-		sshReverseTunnel(privateKey, remoteEndpoint, localEndpoint, sshAddress, nil)
+		sshReverseTunnel(forward, privateKey, remoteEndpoint, localEndpoint, sshAddress, nil)
 	}()
 }
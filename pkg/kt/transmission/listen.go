@@ -0,0 +1,31 @@
+package transmission
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/common"
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	"github.com/rs/zerolog/log"
+)
+
+// GetListenAddress local address for tunnel listeners and accessing local service
+func GetListenAddress() (string, error) {
+	address := opt.Get().Global.ListenInterface
+	if address == "" || address == common.Localhost {
+		return common.Localhost, nil
+	} else if address == opt.Store.LoopbackAlias {
+		return address, nil
+	}
+	if !util.IsValidIp(address) {
+		return "", fmt.Errorf("invalid listen interface address '%s', should be an ipv4 address", address)
+	}
+	created, err := util.EnsureLocalAddress(address)
+	if err != nil {
+		return "", err
+	}
+	if created {
+		log.Info().Msgf("Loopback alias %s created", address)
+		opt.Store.LoopbackAlias = address
+	}
+	return address, nil
+}
@@ -2,10 +2,12 @@ package transmission
 
 import (
 	"fmt"
+	"github.com/alibaba/kt-connect/pkg/common"
 	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
 	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
 	"github.com/alibaba/kt-connect/pkg/kt/util"
 	"github.com/rs/zerolog/log"
+	coreV1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/tools/portforward"
 	"k8s.io/client-go/transport/spdy"
 	"net/http"
@@ -17,16 +19,63 @@ import (
 
 // SetupPortForwardToLocal mapping local port to shadow pod ssh port
 func SetupPortForwardToLocal(podName string, remotePort, localPort int) (chan int, error) {
+	return SetupPortForwardOnAddress(podName, common.Localhost, remotePort, localPort)
+}
+
+// SetupPortForwardOnAddress mapping local port of specified address to shadow pod port
+func SetupPortForwardOnAddress(podName, address string, remotePort, localPort int) (chan int, error) {
+	gone := make(chan int)
+	return gone, setupPortForwardToLocal(podName, address, remotePort, localPort, gone, true, nil)
+}
+
+// SetupPortForwardWithFailover mapping local port to shadow pod port, and switch to another running pod
+// with same labels when current pod is gone, so that the local port keeps available
+func SetupPortForwardWithFailover(podName string, labels map[string]string, remotePort, localPort int) (chan int, error) {
+	address, err := GetListenAddress()
+	if err != nil {
+		return nil, err
+	}
 	gone := make(chan int)
-	return gone, setupPortForwardToLocal(podName, remotePort, localPort, gone, true)
+	pick := func(current string) string {
+		return pickRunningPod(current, labels)
+	}
+	return gone, setupPortForwardToLocal(podName, address, remotePort, localPort, gone, true, pick)
+}
+
+// pickRunningPod keep using current pod if it's still running, otherwise choose another running pod with labels
+func pickRunningPod(current string, labels map[string]string) string {
+	pods, err := cluster.Ins().GetPodsByLabel(labels, opt.Get().Global.Namespace)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to list pods for port forward failover")
+		return current
+	}
+	candidate := ""
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != coreV1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if pod.Name == current {
+			return current
+		}
+		if candidate == "" {
+			candidate = pod.Name
+		}
+	}
+	if candidate == "" {
+		log.Debug().Msgf("No running pod available for port forward failover, keep using %s", current)
+		return current
+	}
+	log.Info().Msgf("Pod %s is gone, switching port forward to pod %s", current, candidate)
+	return candidate
 }
 
-func setupPortForwardToLocal(podName string, remotePort, localPort int, gone chan int, isInitConnect bool) error {
+func setupPortForwardToLocal(podName, address string, remotePort, localPort int, gone chan int, isInitConnect bool,
+	pick func(string) string) error {
 	ready := make(chan struct{})
 	var ticker *time.Ticker
 	go func() {
 		stop := make(chan struct{})
-		fw, err := createPortForwarder(podName, remotePort, localPort, stop, ready)
+		fw, err := createPortForwarder(podName, address, remotePort, localPort, stop, ready)
 		if err != nil {
 			log.Warn().Err(err).Msgf("Invalid port forward parameter")
 			return
@@ -45,13 +94,16 @@ func setupPortForwardToLocal(podName string, remotePort, localPort int, gone cha
 		}
 		time.Sleep(time.Duration(opt.Get().Global.PortForwardTimeout) * time.Second)
 		log.Debug().Msgf("Port forward reconnecting ...")
-		_ = setupPortForwardToLocal(podName, remotePort, localPort, gone, false)
+		if pick != nil {
+			podName = pick(podName)
+		}
+		_ = setupPortForwardToLocal(podName, address, remotePort, localPort, gone, false, pick)
 	}()
 
 	select {
 	case <-ready:
-		ticker = cluster.SetupPortForwardHeartBeat(localPort)
-		log.Info().Msgf("Port forward local:%d -> pod %s:%d established", localPort, podName, remotePort)
+		ticker = cluster.SetupPortForwardHeartBeat(address, localPort)
+		log.Info().Msgf("Port forward %s:%d -> pod %s:%d established", address, localPort, podName, remotePort)
 		return nil
 	case <-time.After(time.Duration(opt.Get().Global.PortForwardTimeout) * time.Second):
 		return fmt.Errorf("connect to port-forward failed")
@@ -59,7 +111,7 @@ func setupPortForwardToLocal(podName string, remotePort, localPort int, gone cha
 }
 
 // createPortForwarder fetch a port forward handler
-func createPortForwarder(podName string, remotePort, localPort int, stop, ready chan struct{}) (*portforward.PortForwarder, error) {
+func createPortForwarder(podName, address string, remotePort, localPort int, stop, ready chan struct{}) (*portforward.PortForwarder, error) {
 	apiPath := fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/portforward", opt.Get().Global.Namespace, podName)
 	log.Debug().Msgf("Request port forward pod:%d -> local:%d via %s", remotePort, localPort, opt.Store.RestConfig.Host)
 	apiUrl, err := parseReqHost(opt.Store.RestConfig.Host, apiPath)
@@ -74,7 +126,11 @@ func createPortForwarder(podName string, remotePort, localPort int, stop, ready
 
 	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, apiUrl)
 	ports := []string{fmt.Sprintf("%d:%d", localPort, remotePort)}
-	return portforward.New(dialer, ports, stop, ready, util.BackgroundLogger, util.BackgroundLogger)
+	if address == common.Localhost {
+		// listen to both ipv4 and ipv6 localhost
+		address = "localhost"
+	}
+	return portforward.NewOnAddresses(dialer, []string{address}, ports, stop, ready, util.BackgroundLogger, util.BackgroundLogger)
 }
 
 // parseReqHost get the final url to port forward api
@@ -1,6 +1,12 @@
 package transmission
 
 import (
+	opt "github.com/alibaba/kt-connect/pkg/kt/command/options"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster"
+	"github.com/alibaba/kt-connect/pkg/kt/service/cluster/fake"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"net/url"
 	"reflect"
 	"testing"
@@ -57,3 +63,28 @@ func Test_parseReqHost(t *testing.T) {
 		})
 	}
 }
+
+func Test_pickRunningPod(t *testing.T) {
+	opt.Get().Global.Namespace = "default"
+	labels := map[string]string{"kt-role": "shadow-connect"}
+	newPod := func(name string, phase coreV1.PodPhase, podLabels map[string]string) *coreV1.Pod {
+		return &coreV1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: podLabels},
+			Status:     coreV1.PodStatus{Phase: phase},
+		}
+	}
+	k := fake.NewKubernetes(
+		newPod("shadow-a", coreV1.PodRunning, labels),
+		newPod("shadow-b", coreV1.PodPending, labels),
+		newPod("other", coreV1.PodRunning, map[string]string{"kt-role": "shadow-exchange"}),
+	)
+	cluster.SetIns(k)
+	defer cluster.SetIns(nil)
+
+	require.Equal(t, "shadow-a", pickRunningPod("shadow-a", labels), "running pod should be kept")
+	require.Equal(t, "shadow-a", pickRunningPod("shadow-x", labels), "should switch to running pod with same labels")
+
+	_, err := k.UpdatePod(newPod("shadow-a", coreV1.PodFailed, labels))
+	require.Nil(t, err)
+	require.Equal(t, "shadow-x", pickRunningPod("shadow-x", labels), "should keep current pod if no other pod running")
+}
@@ -0,0 +1,241 @@
+package transmission
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/kt/service/sshchannel"
+	"github.com/alibaba/kt-connect/pkg/kt/util"
+	fs "github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// syncDelay time to wait for more changes before uploading
+const syncDelay = 500 * time.Millisecond
+
+type folderSyncer struct {
+	localPath  string
+	remotePath string
+	ignores    []string
+	sshAddress string
+	privateKey string
+	pending    map[string]bool
+	sync.Mutex
+}
+
+// syncWatchers watchers of running folder syncers, closed on exit
+var syncWatchers []*fs.Watcher
+var syncWatchersLock sync.Mutex
+
+// StartSync keep local files synced to shadow pod, syncPaths is in '<local-path>:<remote-path>' format,
+// nothing to do if it's empty
+func StartSync(syncPaths, syncIgnore string, localSshPort int, privateKey string) error {
+	if syncPaths == "" {
+		return nil
+	}
+	localPath, remotePath, err := ParseSyncPaths(syncPaths)
+	if err != nil {
+		return err
+	}
+	return SyncFolder(localPath, remotePath, strings.Split(syncIgnore, ","), localSshPort, privateKey)
+}
+
+// StopSync stop watching local changes of all folder syncers
+func StopSync() {
+	syncWatchersLock.Lock()
+	defer syncWatchersLock.Unlock()
+	for _, watcher := range syncWatchers {
+		_ = watcher.Close()
+	}
+	syncWatchers = nil
+}
+
+// ParseSyncPaths parse '<local-path>:<remote-path>' into local and remote path
+func ParseSyncPaths(syncPaths string) (string, string, error) {
+	pos := strings.LastIndex(syncPaths, ":")
+	if pos <= 0 {
+		return "", "", fmt.Errorf("invalid sync paths '%s', should be '<local-path>:<remote-path>'", syncPaths)
+	}
+	localPath, remotePath := syncPaths[0:pos], syncPaths[pos+1:]
+	if !strings.HasPrefix(remotePath, "/") {
+		return "", "", fmt.Errorf("remote sync path '%s' must be an absolute path", remotePath)
+	}
+	if _, err := os.Stat(localPath); err != nil {
+		return "", "", fmt.Errorf("local sync path '%s' is not accessible: %s", localPath, err)
+	}
+	return filepath.Clean(localPath), path.Clean(remotePath), nil
+}
+
+// SyncFolder upload local files to shadow pod via ssh, and keep uploading changed files in background
+func SyncFolder(localPath, remotePath string, ignores []string, localSshPort int, privateKey string) error {
+	listenAddress, err := GetListenAddress()
+	if err != nil {
+		return err
+	}
+	s := &folderSyncer{
+		localPath:  localPath,
+		remotePath: remotePath,
+		ignores:    ignores,
+		sshAddress: net.JoinHostPort(listenAddress, strconv.Itoa(localSshPort)),
+		privateKey: privateKey,
+		pending:    make(map[string]bool),
+	}
+	watcher, err := fs.NewWatcher()
+	if err != nil {
+		return err
+	}
+	files, err := s.scan(localPath, watcher)
+	if err != nil {
+		_ = watcher.Close()
+		return err
+	}
+	log.Info().Msgf("Uploading %d files from %s to %s", len(files), localPath, remotePath)
+	if err = sshchannel.Ins().UploadFiles(s.privateKey, s.sshAddress, files); err != nil {
+		_ = watcher.Close()
+		return err
+	}
+	syncWatchersLock.Lock()
+	syncWatchers = append(syncWatchers, watcher)
+	syncWatchersLock.Unlock()
+	go s.watch(watcher)
+	return nil
+}
+
+// scan find all files not ignored under specified path, and add folders to watcher
+func (s *folderSyncer) scan(root string, watcher *fs.Watcher) (map[string]string, error) {
+	files := make(map[string]string)
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != s.localPath && isSyncIgnored(s.relativePath(p), s.ignores) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return watcher.Add(p)
+		} else if info.Mode().IsRegular() {
+			files[p] = s.toRemotePath(p)
+			if p == s.localPath {
+				return watcher.Add(p)
+			}
+		}
+		return nil
+	})
+	return files, err
+}
+
+func (s *folderSyncer) watch(watcher *fs.Watcher) {
+	defer watcher.Close()
+	timer := time.NewTimer(syncDelay)
+	timer.Stop()
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if isSyncIgnored(s.relativePath(event.Name), s.ignores) {
+				continue
+			}
+			s.Lock()
+			s.pending[event.Name] = true
+			s.Unlock()
+			timer.Reset(syncDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Warn().Err(err).Msgf("Error occurred when watching %s", s.localPath)
+		case <-timer.C:
+			s.flush(watcher)
+		}
+	}
+}
+
+// flush upload changed files and remove deleted files on remote side
+func (s *folderSyncer) flush(watcher *fs.Watcher) {
+	s.Lock()
+	changed := s.pending
+	s.pending = make(map[string]bool)
+	s.Unlock()
+
+	uploads := make(map[string]string)
+	removes := make([]string, 0)
+	for p := range changed {
+		info, err := os.Stat(p)
+		if os.IsNotExist(err) {
+			removes = append(removes, util.ShellQuote(s.toRemotePath(p)))
+		} else if err != nil {
+			log.Warn().Err(err).Msgf("Failed to check changed file %s", p)
+		} else if info.IsDir() {
+			files, err2 := s.scan(p, watcher)
+			if err2 != nil {
+				log.Warn().Err(err2).Msgf("Failed to scan changed folder %s", p)
+			}
+			for k, v := range files {
+				uploads[k] = v
+			}
+		} else if info.Mode().IsRegular() {
+			uploads[p] = s.toRemotePath(p)
+		}
+	}
+	if len(uploads) > 0 {
+		if err := sshchannel.Ins().UploadFiles(s.privateKey, s.sshAddress, uploads); err != nil {
+			log.Warn().Err(err).Msgf("Failed to sync changed files")
+		} else {
+			log.Info().Msgf("Synced %d changed files to %s", len(uploads), s.remotePath)
+		}
+	}
+	if len(removes) > 0 {
+		if _, err := sshchannel.Ins().RunScript(s.privateKey, s.sshAddress, "rm -rf "+strings.Join(removes, " ")); err != nil {
+			log.Warn().Err(err).Msgf("Failed to remove deleted files")
+		} else {
+			log.Info().Msgf("Removed %d deleted files from %s", len(removes), s.remotePath)
+		}
+	}
+}
+
+func (s *folderSyncer) relativePath(p string) string {
+	rel, err := filepath.Rel(s.localPath, p)
+	if err != nil {
+		return p
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (s *folderSyncer) toRemotePath(p string) string {
+	rel := s.relativePath(p)
+	if rel == "." {
+		// sync a single file
+		return s.remotePath
+	}
+	return path.Join(s.remotePath, rel)
+}
+
+// isSyncIgnored check whether relative path or any of its parent folder matches ignore patterns
+func isSyncIgnored(relativePath string, ignores []string) bool {
+	segments := strings.Split(relativePath, "/")
+	for _, pattern := range ignores {
+		if pattern == "" {
+			continue
+		}
+		if matched, _ := path.Match(pattern, relativePath); matched {
+			return true
+		}
+		for _, segment := range segments {
+			if matched, _ := path.Match(pattern, segment); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package transmission
+
+import (
+	fs "github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseSyncPaths(t *testing.T) {
+	dir := t.TempDir()
+	localPath, remotePath, err := ParseSyncPaths(dir + "/:/app/data/")
+	require.Nil(t, err)
+	require.Equal(t, dir, localPath)
+	require.Equal(t, "/app/data", remotePath)
+
+	_, _, err = ParseSyncPaths(dir)
+	require.NotNil(t, err, "remote path is required")
+	_, _, err = ParseSyncPaths(dir + ":app/data")
+	require.NotNil(t, err, "remote path should be absolute")
+	_, _, err = ParseSyncPaths(dir + string(os.PathSeparator) + "not-exist:/app/data")
+	require.NotNil(t, err, "local path should exist")
+}
+
+func Test_isSyncIgnored(t *testing.T) {
+	ignores := []string{".git", "*.log", "build/tmp"}
+	require.True(t, isSyncIgnored(".git", ignores))
+	require.True(t, isSyncIgnored(".git/config", ignores))
+	require.True(t, isSyncIgnored("logs/app.log", ignores))
+	require.True(t, isSyncIgnored("build/tmp", ignores))
+	require.False(t, isSyncIgnored("build/app", ignores))
+	require.False(t, isSyncIgnored("src/main.go", ignores))
+	require.False(t, isSyncIgnored("src/main.go", []string{""}))
+}
+
+func TestStartSync(t *testing.T) {
+	require.Nil(t, StartSync("", ".git", 2222, "key"), "nothing to sync")
+	require.NotNil(t, StartSync("not-exist:/app", ".git", 2222, "key"), "invalid paths should fail")
+}
+
+func TestStopSync(t *testing.T) {
+	watcher, err := fs.NewWatcher()
+	require.Nil(t, err)
+	require.Nil(t, watcher.Add(t.TempDir()))
+	syncWatchers = append(syncWatchers, watcher)
+	s := &folderSyncer{localPath: "/tmp", pending: make(map[string]bool)}
+	done := make(chan bool)
+	go func() {
+		s.watch(watcher)
+		done <- true
+	}()
+
+	StopSync()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.Fail(t, "watching should stop")
+	}
+	require.Empty(t, syncWatchers)
+}
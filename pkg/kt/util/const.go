@@ -5,9 +5,23 @@ import "fmt"
 const (
 	// EnvKubeConfig environment variable for kube config file
 	EnvKubeConfig = "KUBECONFIG"
+	// EnvKtNamespace environment variable for default namespace
+	EnvKtNamespace = "KT_NAMESPACE"
 
 	// KubernetesToolkit name of this tool
 	KubernetesToolkit = "kt"
+	// NamespaceKubeSystem namespace of kubernetes system components
+	NamespaceKubeSystem = "kube-system"
+	// KtClusterConfig name of config map for cluster wide default options and policies
+	KtClusterConfig = "kt-connect-config"
+	// LabelOs node label of operating system
+	LabelOs = "kubernetes.io/os"
+	// LabelOsBeta deprecated node label of operating system
+	LabelOsBeta = "beta.kubernetes.io/os"
+	// OsLinux linux operating system
+	OsLinux = "linux"
+	// OsWindows windows operating system
+	OsWindows = "windows"
 	// ComponentConnect connect command
 	ComponentConnect = "connect"
 	// ComponentExchange exchange command
@@ -18,6 +32,8 @@ const (
 	ComponentPreview = "preview"
 	// ComponentForward forward command
 	ComponentForward = "forward"
+	// ComponentPreheat preheat command
+	ComponentPreheat = "preheat"
 
 	// ImageKtShadow default shadow image
 	ImageKtShadow = "registry.cn-hangzhou.aliyuncs.com/rdc-incubator/kt-connect-shadow"
@@ -36,6 +52,12 @@ const (
 	ExchangeModeEphemeral = "ephemeral"
 	// ExchangeModeSelector selector mode
 	ExchangeModeSelector = "selector"
+	// ExchangeModeAuto choose ephemeral or scale mode according to cluster capabilities
+	ExchangeModeAuto = "auto"
+	// ProtocolTcp tcp protocol of exposed port
+	ProtocolTcp = "tcp"
+	// ProtocolUdp udp protocol of exposed port
+	ProtocolUdp = "udp"
 	// MeshModeAuto auto mode
 	MeshModeAuto = "auto"
 	// MeshModeManual manual mode
@@ -61,14 +83,26 @@ const (
 	KtConfig = "kt-config"
 	// KtUser annotation used for record independent username
 	KtUser = "kt-user"
+	// KtHost annotation used for record hostname of local machine
+	KtHost = "kt-host"
+	// KtKubeUser annotation used for record user authenticated by kubernetes
+	KtKubeUser = "kt-kube-user"
+	// KtVersion annotation used for record version of ktctl which created the resource
+	KtVersion = "kt-version"
 	// KtSelector annotation used for record service origin selector
 	KtSelector = "kt-selector"
+	// KtSessionAffinity annotation used for record service origin session affinity config
+	KtSessionAffinity = "kt-session-affinity"
 	// KtRefCount annotation used for count of shared pod / service
 	KtRefCount = "kt-ref-count"
 	// KtLastHeartBeat annotation used for timestamp of last heart beat
 	KtLastHeartBeat = "kt-last-heart-beat"
 	// KtLock annotation used for avoid auto mesh conflict
 	KtLock = "kt-lock"
+	// KtExpireAt annotation used for record timestamp after which shadow could be reaped by any kt command
+	KtExpireAt = "kt-expire-at"
+	// KtDebugPort annotation used for record shadow pod port forwarded to local debugger
+	KtDebugPort = "kt-debug-port"
 
 	// PostfixRsaKey postfix of local private key name
 	PostfixRsaKey = ".key"
@@ -84,6 +118,22 @@ const (
 	DefaultNamespace = "default"
 	// KtExchangeContainer name of exchange ephemeral container
 	KtExchangeContainer = "kt-exchange"
+	// IstioSidecarContainer name of container injected by istio
+	IstioSidecarContainer = "istio-proxy"
+	// IstioSidecarStatus annotation added to pod with istio sidecar injected
+	IstioSidecarStatus = "sidecar.istio.io/status"
+	// DefaultTunMtu default MTU of tun device, leave room for overhead of overlay network and vpn below common 1500
+	DefaultTunMtu = 1400
+	// MinTunMtu minimal MTU of tun device, as required by IPv4
+	MinTunMtu = 576
+	// MaxTunMtu maximal MTU of tun device, as jumbo frame
+	MaxTunMtu = 9000
+	// DefaultClusterDomain default domain suffix of kubernetes cluster
+	DefaultClusterDomain = "cluster.local"
+	// AuthorizedKeysFile authorized keys file of ssh server in shadow pod
+	AuthorizedKeysFile = "/root/.ssh/authorized_keys"
+	// UdpRelayPidFile pid file of udp relay started in ephemeral container for each redirect port
+	UdpRelayPidFile = "/tmp/kt-udp-relay-%d.pid"
 	// DefaultContainer default container name
 	DefaultContainer = "standalone"
 	// StuntmanServiceSuffix suffix of stuntman service name
@@ -92,10 +142,22 @@ const (
 	RouterPodSuffix = "-kt-router"
 	// ExchangePodInfix exchange pod name
 	ExchangePodInfix = "-kt-exchange-"
+	// MirrorSuffix suffix of mirror service and istio virtual service name
+	MirrorSuffix = "-kt-mirror"
+	// IngressSuffix suffix of ingress exposing exchanged service
+	IngressSuffix = "-kt-ingress"
+	// GatewaySuffix suffix of service and gateway api http route created for header based exchange
+	GatewaySuffix = "-kt-gateway"
+	// OriginCopyPodInfix origin copy pod name
+	OriginCopyPodInfix = "-kt-origin-"
 	// MeshPodInfix mesh pod and mesh service name
 	MeshPodInfix = "-kt-mesh-"
 	// RectifierPodPrefix rectifier pod name
 	RectifierPodPrefix = "kt-rectifier-"
+	// ConnectGatewayName name of shared connect gateway deployment and its config map
+	ConnectGatewayName = "kt-connect-gateway"
+	// PreheatPrefix preheat daemon set name
+	PreheatPrefix = "kt-preheat-"
 	// RoleConnectShadow shadow role
 	RoleConnectShadow = "shadow-connect"
 	// RoleExchangeShadow shadow role
@@ -104,8 +166,18 @@ const (
 	RoleMeshShadow = "shadow-mesh"
 	// RolePreviewShadow shadow role
 	RolePreviewShadow = "shadow-preview"
+	// RoleForwardShadow shadow role
+	RoleForwardShadow = "shadow-forward"
+	// RoleConnectGateway shared connect shadow role
+	RoleConnectGateway = "connect-gateway"
 	// RoleRouter router role
 	RoleRouter = "router"
+	// RoleOriginCopy copy of origin pod role
+	RoleOriginCopy = "origin-copy"
+	// RoleOriginDraining origin pod detached from deployment and waiting for connections to drain
+	RoleOriginDraining = "origin-draining"
+	// RolePreheat image preheat role
+	RolePreheat = "preheat"
 	// SortByName birdseye sort
 	SortByName = "name"
 	// SortByStatus birdseye sort
@@ -116,21 +188,40 @@ const (
 	TunNameLinux = "kt0"
 	// TunNameMac tun device name in MacOS
 	TunNameMac = "utun"
+	// TunRouteTableLinux route table for tun device when only tcp traffic is routed, in linux
+	TunRouteTableLinux = "7556"
+	// TunRulePriorityLinux priority of policy routing rules for tun device, in linux
+	TunRulePriorityLinux = "7556"
+	// TunFwmarkLinux firewall mark of packets from process started by '--exec', in linux
+	TunFwmarkLinux = "0x1d84"
+	// TunCgroupLinux cgroup holding process started by '--exec', relative to cgroup v2 root, in linux
+	TunCgroupLinux = "kt-connect-exec"
+	// CgroupRootLinux mount point of cgroup v2 hierarchy, in linux
+	CgroupRootLinux = "/sys/fs/cgroup"
+	// DefaultNameSuffixLength default length of random suffix of generated resource names
+	DefaultNameSuffixLength = 5
+	// MinNameSuffixLength min length of random suffix of generated resource names
+	MinNameSuffixLength = 3
+	// MaxNameSuffixLength max length of random suffix of generated resource names
+	MaxNameSuffixLength = 16
 	// AlternativeDnsPort alternative port for local dns
 	AlternativeDnsPort = 10053
 
 	// ResourceHeartBeatIntervalMinus interval of resource heart beat
 	ResourceHeartBeatIntervalMinus = 2
+	// NamespaceCheckIntervalSec interval of checking whether target namespace is terminated
+	NamespaceCheckIntervalSec = 15
+	// NamespaceEmptyCheckTimes times of re-checking whether namespace created by kt is empty before removing it
+	NamespaceEmptyCheckTimes = 5
 	// PortForwardHeartBeatIntervalSec interval of port-forward heart beat
 	PortForwardHeartBeatIntervalSec = 60
-
 )
 
 var (
-	KtHome = fmt.Sprintf("%s/.kt", UserHome)
-	KtKeyDir = fmt.Sprintf("%s/key", KtHome)
-	KtPidDir = fmt.Sprintf("%s/pid", KtHome)
-	KtLockDir = fmt.Sprintf("%s/lock", KtHome)
+	KtHome       = fmt.Sprintf("%s/.kt", UserHome)
+	KtKeyDir     = fmt.Sprintf("%s/key", KtHome)
+	KtPidDir     = fmt.Sprintf("%s/pid", KtHome)
+	KtLockDir    = fmt.Sprintf("%s/lock", KtHome)
 	KtProfileDir = fmt.Sprintf("%s/profile", KtHome)
 	KtConfigFile = fmt.Sprintf("%s/config", KtHome)
-)
\ No newline at end of file
+)
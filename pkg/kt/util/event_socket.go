@@ -0,0 +1,165 @@
+package util
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// EventExit event sent to socket clients before process exits
+	EventExit = "exit"
+	// EventResponse event sent to socket client as result of its command
+	EventResponse = "response"
+	// eventSocketMode only owner of socket could connect to it, since commands can change the exchange
+	eventSocketMode = 0600
+)
+
+// CommandResponse response of command received from event socket
+type CommandResponse struct {
+	Time    string `json:"time"`
+	Event   string `json:"event"`
+	Command string `json:"command"`
+	Result  any    `json:"result,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// EventSocket unix domain socket sending lifecycle events to clients, and accepting commands from them
+type EventSocket struct {
+	path     string
+	listener net.Listener
+	handler  func(command string) (any, error)
+	clients  map[net.Conn]bool
+	sync.Mutex
+}
+
+// activeEventSocket event socket of current process, nil if not enabled
+var activeEventSocket *EventSocket
+
+// StartEventSocket listen on unix domain socket of specified path, each line received is handled as a command
+func StartEventSocket(path string, handler func(command string) (any, error)) error {
+	if info, err := os.Stat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("event socket path %s already exists and is not a socket", path)
+		}
+		if conn, err2 := net.Dial("unix", path); err2 == nil {
+			_ = conn.Close()
+			return fmt.Errorf("event socket %s is in use by another process", path)
+		}
+		// remove socket file left by previous process
+		_ = os.Remove(path)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	if err = os.Chmod(path, eventSocketMode); err != nil {
+		_ = listener.Close()
+		return fmt.Errorf("failed to restrict permission of event socket %s: %s", path, err)
+	}
+	// let the user who runs ktctl via sudo connect to it
+	if os.Getenv("SUDO_UID") != "" {
+		if err = FixFileOwner(path); err != nil {
+			_ = listener.Close()
+			return fmt.Errorf("failed to change owner of event socket %s: %s", path, err)
+		}
+	}
+	s := &EventSocket{path: path, listener: listener, handler: handler, clients: map[net.Conn]bool{}}
+	activeEventSocket = s
+	go s.serve()
+	log.Info().Msgf("Event socket listening at %s", path)
+	return nil
+}
+
+// StopEventSocket notify clients of exiting, then close event socket and remove the socket file
+func StopEventSocket() {
+	s := activeEventSocket
+	if s == nil {
+		return
+	}
+	activeEventSocket = nil
+	s.broadcast(map[string]string{"time": time.Now().Format(time.RFC3339), "event": EventExit})
+	_ = s.listener.Close()
+	s.Lock()
+	for conn := range s.clients {
+		_ = conn.Close()
+	}
+	s.Unlock()
+	_ = os.Remove(s.path)
+	log.Debug().Msgf("Event socket %s closed", s.path)
+}
+
+// sendEvent send event to clients of event socket, if enabled
+func sendEvent(event any) {
+	if s := activeEventSocket; s != nil {
+		s.broadcast(event)
+	}
+}
+
+func (s *EventSocket) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.Lock()
+		s.clients[conn] = true
+		s.Unlock()
+		go s.handle(conn)
+	}
+}
+
+// handle read commands from client line by line until connection closed
+func (s *EventSocket) handle(conn net.Conn) {
+	defer func() {
+		s.Lock()
+		delete(s.clients, conn)
+		s.Unlock()
+		_ = conn.Close()
+	}()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		command := strings.TrimSpace(scanner.Text())
+		if command == "" {
+			continue
+		}
+		rsp := CommandResponse{Time: time.Now().Format(time.RFC3339), Event: EventResponse, Command: command}
+		result, err := s.handler(command)
+		if err != nil {
+			rsp.Error = err.Error()
+		} else {
+			rsp.Result = result
+		}
+		s.send(conn, rsp)
+	}
+}
+
+func (s *EventSocket) broadcast(event any) {
+	s.Lock()
+	conns := make([]net.Conn, 0, len(s.clients))
+	for conn := range s.clients {
+		conns = append(conns, conn)
+	}
+	s.Unlock()
+	for _, conn := range conns {
+		s.send(conn, event)
+	}
+}
+
+func (s *EventSocket) send(conn net.Conn, event any) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to encode event")
+		return
+	}
+	_ = conn.SetWriteDeadline(time.Now().Add(time.Second))
+	if _, err = conn.Write(append(data, '\n')); err != nil {
+		log.Debug().Err(err).Msgf("Failed to send event to socket client")
+	}
+}
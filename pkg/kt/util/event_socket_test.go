@@ -0,0 +1,70 @@
+package util
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEventSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "kt-socket")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.sock")
+	defer func() { reachedPhases = map[string]int{} }()
+
+	require.Nil(t, StartEventSocket(path, func(command string) (any, error) {
+		if command == "status" {
+			return map[string]string{"state": "running"}, nil
+		}
+		return nil, fmt.Errorf("unknown command")
+	}))
+	info, err := os.Stat(path)
+	require.Nil(t, err)
+	require.Equal(t, os.FileMode(eventSocketMode), info.Mode().Perm(), "only owner should access event socket")
+	require.NotNil(t, StartEventSocket(path, nil), "socket in use should not be taken over")
+	conn, err := net.Dial("unix", path)
+	require.Nil(t, err)
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	_, err = conn.Write([]byte("status\n"))
+	require.Nil(t, err)
+	var rsp CommandResponse
+	line, err := reader.ReadBytes('\n')
+	require.Nil(t, err)
+	require.Nil(t, json.Unmarshal(line, &rsp))
+	require.Equal(t, EventResponse, rsp.Event)
+	require.Equal(t, "status", rsp.Command)
+	require.Equal(t, map[string]any{"state": "running"}, rsp.Result)
+
+	_, err = conn.Write([]byte("restart\n"))
+	require.Nil(t, err)
+	line, err = reader.ReadBytes('\n')
+	require.Nil(t, err)
+	rsp = CommandResponse{}
+	require.Nil(t, json.Unmarshal(line, &rsp))
+	require.Equal(t, "unknown command", rsp.Error)
+
+	ReportProgress("shadow-socket", PhaseStarting, "")
+	var event ProgressEvent
+	line, err = reader.ReadBytes('\n')
+	require.Nil(t, err)
+	require.Nil(t, json.Unmarshal(line, &event))
+	require.Equal(t, "progress", event.Event)
+	require.Equal(t, PhaseStarting, event.Phase)
+	require.Equal(t, PhaseStarting, ReachedPhases()["shadow-socket"])
+
+	StopEventSocket()
+	line, err = reader.ReadBytes('\n')
+	require.Nil(t, err)
+	require.Nil(t, json.Unmarshal(line, &event))
+	require.Equal(t, EventExit, event.Event)
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err), "socket file should be removed")
+}
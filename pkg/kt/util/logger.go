@@ -1,6 +1,7 @@
 package util
 
 import (
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"io"
 	"io/fs"
@@ -28,6 +29,12 @@ func PrepareLogger(enable bool) {
 	}
 }
 
+// StatusLog log key status message, which should be printed even in quiet mode
+func StatusLog() *zerolog.Event {
+	statusLogger := log.Logger.Level(zerolog.InfoLevel)
+	return statusLogger.Info()
+}
+
 type FileWriter struct {
 	file *os.File
 }
@@ -58,5 +65,5 @@ func CleanBackgroundLogs() {
 
 // isExpired check whether file haven't been modified over 24 hours
 func isExpired(info fs.FileInfo) bool {
-	return info.ModTime().Unix() < time.Now().Unix() - (3600 * 24)
+	return info.ModTime().Unix() < time.Now().Unix()-(3600*24)
 }
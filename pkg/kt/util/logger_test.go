@@ -0,0 +1,23 @@
+package util
+
+import (
+	"bytes"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestStatusLog(t *testing.T) {
+	origin := log.Logger
+	defer func() {
+		log.Logger = origin
+	}()
+	buf := &bytes.Buffer{}
+	log.Logger = zerolog.New(buf).Level(zerolog.ErrorLevel)
+
+	log.Info().Msg("info")
+	require.Empty(t, buf.String(), "info log should be suppressed")
+	StatusLog().Msg("status")
+	require.Contains(t, buf.String(), "status", "status log should be printed in quiet mode")
+}
@@ -0,0 +1,29 @@
+package util
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+)
+
+// EnsureLocalAddress make sure the address can be listened locally, return true if loopback alias is created
+func EnsureLocalAddress(address string) (bool, error) {
+	if IsLocalInterfaceAddress(address) {
+		return false, nil
+	}
+	if !net.ParseIP(address).IsLoopback() {
+		return false, fmt.Errorf("address %s is not found on any local interface", address)
+	}
+	// run command: ifconfig lo0 alias 127.0.0.50 up
+	if _, _, err := RunAndWait(exec.Command("ifconfig", "lo0", "alias", address, "up")); err != nil {
+		return false, fmt.Errorf("failed to create loopback alias %s: %s", address, err)
+	}
+	return true, nil
+}
+
+// RemoveLoopbackAlias remove loopback alias created by EnsureLocalAddress
+func RemoveLoopbackAlias(address string) error {
+	// run command: ifconfig lo0 -alias 127.0.0.50
+	_, _, err := RunAndWait(exec.Command("ifconfig", "lo0", "-alias", address))
+	return err
+}
@@ -0,0 +1,22 @@
+//go:build !darwin
+
+package util
+
+import (
+	"fmt"
+	"net"
+)
+
+// EnsureLocalAddress make sure the address can be listened locally, return true if loopback alias is created
+func EnsureLocalAddress(address string) (bool, error) {
+	// the whole loopback range is routed to loopback device on linux and windows, no alias is required
+	if net.ParseIP(address).IsLoopback() || IsLocalInterfaceAddress(address) {
+		return false, nil
+	}
+	return false, fmt.Errorf("address %s is not found on any local interface", address)
+}
+
+// RemoveLoopbackAlias remove loopback alias created by EnsureLocalAddress
+func RemoveLoopbackAlias(address string) error {
+	return nil
+}
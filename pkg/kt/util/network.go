@@ -29,6 +29,21 @@ func GetRandomTcpPort() int {
 	return port
 }
 
+// IsLocalInterfaceAddress check whether the ip address is assigned to any local network interface
+func IsLocalInterfaceAddress(address string) bool {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to list local interface addresses")
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.String() == address {
+			return true
+		}
+	}
+	return false
+}
+
 // ParsePortMapping parse <port> or <localPort>:<removePort> parameter
 func ParsePortMapping(exposePort string) (int, int, error) {
 	localPort := exposePort
@@ -49,6 +64,31 @@ func ParsePortMapping(exposePort string) (int, int, error) {
 	return lp, rp, nil
 }
 
+// SplitExposeProtocol split '<port-mapping>/<protocol>' parameter, protocol is tcp if not specified
+func SplitExposeProtocol(exposePort string) (string, string, error) {
+	pos := strings.LastIndex(exposePort, "/")
+	if pos < 0 {
+		return exposePort, ProtocolTcp, nil
+	}
+	protocol := strings.ToLower(exposePort[pos+1:])
+	if protocol != ProtocolTcp && protocol != ProtocolUdp {
+		return "", "", fmt.Errorf("invalid protocol '%s' of port '%s', supported are %s, %s",
+			exposePort[pos+1:], exposePort, ProtocolTcp, ProtocolUdp)
+	}
+	return exposePort[0:pos], protocol, nil
+}
+
+// FilterExposeByProtocol get expose ports of specified protocol, with protocol suffix removed
+func FilterExposeByProtocol(exposePorts, protocol string) string {
+	var ports []string
+	for _, exposePort := range strings.Split(exposePorts, ",") {
+		if port, p, err := SplitExposeProtocol(exposePort); err == nil && p == protocol {
+			ports = append(ports, port)
+		}
+	}
+	return strings.Join(ports, ",")
+}
+
 // FindBrokenLocalPort Check if all ports has process listening to
 // Return empty string if all ports are listened, otherwise return the first broken port
 func FindBrokenLocalPort(exposePorts string) string {
@@ -89,7 +129,7 @@ func FindInvalidRemotePort(exposePorts string, svcPorts map[int]string) string {
 
 // IsValidIp check if specified ip address valid
 func IsValidIp(ip string) bool {
-	if ok, err := regexp.MatchString("^" + IpAddrPattern + "$", ip); ok && err == nil {
+	if ok, err := regexp.MatchString("^"+IpAddrPattern+"$", ip); ok && err == nil {
 		return true
 	}
 	return false
@@ -11,3 +11,22 @@ func TestExtractHostIp(t *testing.T) {
 	require.Equal(t, "1.2.3.4", ExtractHostIp("http://1.2.3.4:8080/a/b/c"))
 	require.Equal(t, "127.0.0.1", ExtractHostIp("http://localhost:8080/a/b/c"))
 }
+
+func TestSplitExposeProtocol(t *testing.T) {
+	port, protocol, err := SplitExposeProtocol("8080:80")
+	require.Nil(t, err)
+	require.Equal(t, "8080:80", port)
+	require.Equal(t, ProtocolTcp, protocol)
+	port, protocol, err = SplitExposeProtocol("53/UDP")
+	require.Nil(t, err)
+	require.Equal(t, "53", port)
+	require.Equal(t, ProtocolUdp, protocol)
+	_, _, err = SplitExposeProtocol("80/sctp")
+	require.NotNil(t, err)
+}
+
+func TestFilterExposeByProtocol(t *testing.T) {
+	require.Equal(t, "8080,9090:90", FilterExposeByProtocol("8080,5353:53/udp,9090:90/tcp", ProtocolTcp))
+	require.Equal(t, "5353:53", FilterExposeByProtocol("8080,5353:53/udp,9090:90/tcp", ProtocolUdp))
+	require.Equal(t, "", FilterExposeByProtocol("8080", ProtocolUdp))
+}
@@ -0,0 +1,101 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// PhaseScheduling shadow pod is waiting for a node
+	PhaseScheduling = "Scheduling"
+	// PhasePullingImage shadow pod is scheduled, its image is being pulled
+	PhasePullingImage = "PullingImage"
+	// PhaseStarting container of shadow pod is started
+	PhaseStarting = "Starting"
+	// PhaseSshReady ssh port of shadow pod is forwarded to local
+	PhaseSshReady = "SshReady"
+	// PhaseTunnelEstablished tunnels between shadow pod and local are established
+	PhaseTunnelEstablished = "TunnelEstablished"
+
+	// OutputText print progress as log
+	OutputText = "text"
+	// OutputJson print progress as json events to stdout, one event per line
+	OutputJson = "json"
+)
+
+// phases progress phases of shadow pod in order
+var phases = []string{PhaseScheduling, PhasePullingImage, PhaseStarting, PhaseSshReady, PhaseTunnelEstablished}
+
+// ProgressEvent progress event printed in json output mode
+type ProgressEvent struct {
+	Time   string `json:"time"`
+	Event  string `json:"event"`
+	Pod    string `json:"pod"`
+	Phase  string `json:"phase"`
+	Detail string `json:"detail,omitempty"`
+}
+
+var (
+	progressOutput           = OutputText
+	progressWriter io.Writer = os.Stdout
+	// reachedPhases index of last reported phase of each pod
+	reachedPhases = map[string]int{}
+	progressLock  sync.Mutex
+)
+
+// SetProgressOutput change format of progress output, 'text' or 'json'
+func SetProgressOutput(output string) error {
+	if output != OutputText && output != OutputJson {
+		return fmt.Errorf("invalid output format '%s', should be '%s' or '%s'", output, OutputText, OutputJson)
+	}
+	progressOutput = output
+	return nil
+}
+
+// ReportProgress report a phase of shadow pod, phases already passed are ignored, so it's safe to call repeatedly
+func ReportProgress(pod, phase, detail string) {
+	index := -1
+	for i, p := range phases {
+		if p == phase {
+			index = i
+		}
+	}
+	progressLock.Lock()
+	defer progressLock.Unlock()
+	if last, exists := reachedPhases[pod]; index < 0 || (exists && last >= index) {
+		return
+	}
+	reachedPhases[pod] = index
+	event := ProgressEvent{
+		Time:   time.Now().Format(time.RFC3339),
+		Event:  "progress",
+		Pod:    pod,
+		Phase:  phase,
+		Detail: detail,
+	}
+	sendEvent(event)
+	if progressOutput == OutputJson {
+		data, _ := json.Marshal(event)
+		_, _ = progressWriter.Write(append(data, '\n'))
+	} else if detail != "" {
+		log.Info().Msgf("[%d/%d] Shadow pod %s: %s (%s)", index+1, len(phases), pod, phase, detail)
+	} else {
+		log.Info().Msgf("[%d/%d] Shadow pod %s: %s", index+1, len(phases), pod, phase)
+	}
+}
+
+// ReachedPhases last reported phase of each shadow pod
+func ReachedPhases() map[string]string {
+	progressLock.Lock()
+	defer progressLock.Unlock()
+	reached := make(map[string]string, len(reachedPhases))
+	for pod, index := range reachedPhases {
+		reached[pod] = phases[index]
+	}
+	return reached
+}
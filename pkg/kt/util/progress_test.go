@@ -0,0 +1,37 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReportProgress(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	progressWriter = buffer
+	defer func() {
+		progressWriter = os.Stdout
+		progressOutput = OutputText
+	}()
+	require.NotNil(t, SetProgressOutput("yaml"))
+	require.Nil(t, SetProgressOutput(OutputJson))
+
+	ReportProgress("shadow-a", PhaseScheduling, "")
+	ReportProgress("shadow-a", PhaseScheduling, "")
+	ReportProgress("shadow-a", PhasePullingImage, "ContainerCreating")
+	ReportProgress("shadow-a", PhaseScheduling, "")
+	ReportProgress("shadow-b", PhaseStarting, "")
+	ReportProgress("shadow-a", "Unknown", "")
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Len(t, lines, 3, "repeated and passed phases should be ignored")
+	var event ProgressEvent
+	require.Nil(t, json.Unmarshal([]byte(lines[1]), &event))
+	require.Equal(t, "progress", event.Event)
+	require.Equal(t, "shadow-a", event.Pod)
+	require.Equal(t, PhasePullingImage, event.Phase)
+	require.Equal(t, "ContainerCreating", event.Detail)
+}
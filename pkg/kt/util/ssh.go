@@ -30,8 +30,17 @@ func NewSSHGenerator(privateKey string, publicKey string, privateKeyPath string)
 	}
 }
 
-// Generate generate SSHGenerator
+// Generate generate SSHGenerator and save its private key to file
 func Generate(privateKeyPath string) (*SSHGenerator, error) {
+	sshKey, err := GenerateKeyPair(privateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	return sshKey, sshKey.SavePrivateKey()
+}
+
+// GenerateKeyPair generate SSHGenerator without saving its private key, e.g. before the key is known to be used
+func GenerateKeyPair(privateKeyPath string) (*SSHGenerator, error) {
 	privateKey, err := generatePrivateKey(SshBitSize)
 	if err != nil {
 		return nil, err
@@ -48,9 +57,13 @@ func Generate(privateKeyPath string) (*SSHGenerator, error) {
 		PrivateKeyPath: privateKeyPath,
 		PublicKey:      publicKeyBytes,
 	}
-	_ = os.Remove(sshKey.PrivateKeyPath)
-	err = WritePrivateKey(sshKey.PrivateKeyPath, sshKey.PrivateKey)
-	return sshKey, err
+	return sshKey, nil
+}
+
+// SavePrivateKey write private key to file, existing file is replaced
+func (g *SSHGenerator) SavePrivateKey() error {
+	_ = os.Remove(g.PrivateKeyPath)
+	return WritePrivateKey(g.PrivateKeyPath, g.PrivateKey)
 }
 
 // PrivateKeyPath ...
@@ -58,6 +71,11 @@ func PrivateKeyPath(name string) string {
 	return fmt.Sprintf("%s/%s%s", KtKeyDir, name, PostfixRsaKey)
 }
 
+// GatewayKeyName name of private key for connecting through connect gateway, each process has its own key
+func GatewayKeyName() string {
+	return fmt.Sprintf("%s-%d", ConnectGatewayName, os.Getpid())
+}
+
 // CleanRsaKeys ...
 func CleanRsaKeys() {
 	files, _ := ioutil.ReadDir(KtKeyDir)
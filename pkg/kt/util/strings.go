@@ -1,12 +1,15 @@
 package util
 
 import (
+	"crypto/sha1"
 	"fmt"
 	"math/rand"
 	"regexp"
 	"strings"
 	"time"
 	"unicode"
+
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 func init() {
@@ -24,6 +27,63 @@ func RandomString(n int) string {
 	return string(b)
 }
 
+// nameSuffixRunes characters of random suffix of generated resource names
+var nameSuffixRunes = []rune("abcdefghijklmnopqrstuvwxyz")
+
+// nameSuffixLength length of random suffix of generated resource names
+var nameSuffixLength = DefaultNameSuffixLength
+
+// SetNameSuffix change length and charset of random suffix of generated resource names, empty charset for default
+func SetNameSuffix(length int, charset string) error {
+	if length < MinNameSuffixLength || length > MaxNameSuffixLength {
+		return fmt.Errorf("name suffix length %d is out of range, should be between %d and %d",
+			length, MinNameSuffixLength, MaxNameSuffixLength)
+	}
+	if charset != "" {
+		if !regexp.MustCompile("^[a-z0-9]+$").MatchString(charset) {
+			return fmt.Errorf("invalid name suffix charset '%s', only lowercase letters and digits are allowed", charset)
+		}
+		nameSuffixRunes = []rune(charset)
+	}
+	nameSuffixLength = length
+	return nil
+}
+
+// RandomSuffix generate random suffix of resource names
+func RandomSuffix() string {
+	b := make([]rune, nameSuffixLength)
+	for i := range b {
+		b[i] = nameSuffixRunes[rand.Intn(len(nameSuffixRunes))]
+	}
+	return string(b)
+}
+
+// RandomName append random suffix to prefix, the prefix is truncated if the name would exceed length limit of dns label
+func RandomName(prefix string) string {
+	if maxLen := validation.DNS1123LabelMaxLength - nameSuffixLength; len(prefix) > maxLen {
+		// keep the dash before suffix, so that the truncated part is still recognizable
+		prefix = strings.TrimRight(prefix[:maxLen-1], "-") + "-"
+	}
+	return prefix + RandomSuffix()
+}
+
+// CheckNameValid verify generated resource name is a valid dns label, which is required by most resource types
+func CheckNameValid(name string) error {
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		return fmt.Errorf("invalid resource name '%s': %s", name, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// ShortHash Generate stable lowercase hex string of specified length from text
+func ShortHash(text string, n int) string {
+	hash := fmt.Sprintf("%x", sha1.Sum([]byte(text)))
+	if n < len(hash) {
+		return hash[:n]
+	}
+	return hash
+}
+
 // RandomSeconds Generate random duration number in second
 func RandomSeconds(min, max int) time.Duration {
 	val := rand.Intn(max)
@@ -60,6 +120,22 @@ func Append(base string, inc string) string {
 	}
 }
 
+// Remove delete segment from a comma separated string
+func Remove(base string, item string) string {
+	segments := make([]string, 0)
+	for _, s := range strings.Split(base, ",") {
+		if s != "" && s != item {
+			segments = append(segments, s)
+		}
+	}
+	return strings.Join(segments, ",")
+}
+
+// ShellQuote wrap text with single quote, so it can be used as one argument of shell command
+func ShellQuote(text string) string {
+	return "'" + strings.ReplaceAll(text, "'", `'\''`) + "'"
+}
+
 // RemoveColor remove shell color character in text
 func RemoveColor(msg string) string {
 	colorExp := regexp.MustCompile("\033\\[[0-9]+m")
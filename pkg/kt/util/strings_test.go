@@ -2,6 +2,7 @@ package util
 
 import (
 	"github.com/stretchr/testify/require"
+	"strings"
 	"testing"
 )
 
@@ -59,3 +60,37 @@ func Test_DashSeparated(t *testing.T) {
 	require.Equal(t, "text-word", DashSeparated("text-word"))
 	require.Equal(t, "t-e-x-t-w-o-r-d", DashSeparated("TEXT-WORD"))
 }
+
+func Test_Remove(t *testing.T) {
+	require.Equal(t, "a,c", Remove("a,b,c", "b"))
+	require.Equal(t, "b", Remove("a,b", "a"))
+	require.Equal(t, "", Remove("a", "a"))
+	require.Equal(t, "a,b", Remove("a,b", "c"))
+}
+
+func Test_ShortHash(t *testing.T) {
+	require.Equal(t, ShortHash("svc-a:8080", 5), ShortHash("svc-a:8080", 5))
+	require.NotEqual(t, ShortHash("svc-a:8080", 5), ShortHash("svc-a:8081", 5))
+	require.Len(t, ShortHash("svc-a", 5), 5)
+	require.Len(t, ShortHash("svc-a", 100), 40)
+}
+
+func Test_ShellQuote(t *testing.T) {
+	require.Equal(t, "'/app/data'", ShellQuote("/app/data"))
+	require.Equal(t, `'/app/it'\''s here'`, ShellQuote("/app/it's here"))
+}
+
+func TestRandomName(t *testing.T) {
+	defer func() { _ = SetNameSuffix(DefaultNameSuffixLength, "abcdefghijklmnopqrstuvwxyz") }()
+	require.Regexp(t, "^kt-probe-[a-z]{5}$", RandomName("kt-probe-"))
+	require.NotNil(t, SetNameSuffix(2, ""), "too short suffix should fail")
+	require.NotNil(t, SetNameSuffix(8, "ABC"), "uppercase charset should fail")
+	require.Nil(t, SetNameSuffix(8, "0123456789abcdef"))
+	require.Regexp(t, "^kt-probe-[0-9a-f]{8}$", RandomName("kt-probe-"))
+
+	name := RandomName(strings.Repeat("a", 50) + "-kt-exchange-")
+	require.LessOrEqual(t, len(name), 63)
+	require.Regexp(t, "^a{50}-kt-[0-9a-f]{8}$", name, "prefix should be truncated without double dashes")
+	require.Nil(t, CheckNameValid(name))
+	require.NotNil(t, CheckNameValid("app.v1-kt-exchange-abcde"))
+}
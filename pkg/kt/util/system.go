@@ -78,9 +78,9 @@ func watchPidFile(pidFile string, ch chan os.Signal) {
 
 	for event := range watcher.Events {
 		log.Debug().Msgf("Received event %s", event)
-		if event.Op & fs.Remove == fs.Remove || event.Op & fs.Rename == fs.Rename {
+		if event.Op&fs.Remove == fs.Remove || event.Op&fs.Rename == fs.Rename {
 			log.Info().Msgf("Pid file was removed")
-			ch <-os.Interrupt
+			ch <- os.Interrupt
 		}
 	}
 }
@@ -145,6 +145,15 @@ func GetLocalUserName() string {
 	return u.Username
 }
 
+// GetLocalHostName get hostname of current machine
+func GetLocalHostName() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return hostname
+}
+
 // IsCmd check running in windows cmd shell
 func IsCmd() bool {
 	proc, _ := ps.FindProcess(os.Getppid())
@@ -3,7 +3,10 @@
 package util
 
 import (
+	"fmt"
+	"golang.org/x/sys/unix"
 	"os"
+	"strings"
 )
 
 func IsRunAsAdmin() bool {
@@ -13,3 +16,15 @@ func IsRunAsAdmin() bool {
 func GetAdminUserName() string {
 	return "root"
 }
+
+// ParseSignal get signal by name, e.g. 'USR1' or 'SIGUSR1'
+func ParseSignal(name string) (os.Signal, error) {
+	name = strings.ToUpper(name)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if sig := unix.SignalNum(name); sig != 0 {
+		return sig, nil
+	}
+	return nil, fmt.Errorf("unknown signal '%s'", name)
+}
@@ -1,8 +1,10 @@
 package util
 
 import (
+	"fmt"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/sys/windows"
+	"os"
 )
 
 // Refer to https://github.com/golang/go/issues/28804
@@ -34,3 +36,8 @@ func IsRunAsAdmin() bool {
 func GetAdminUserName() string {
 	return "administrator"
 }
+
+// ParseSignal sending signal to other process is not supported on windows
+func ParseSignal(name string) (os.Signal, error) {
+	return nil, fmt.Errorf("sending signal '%s' is not supported on windows", name)
+}
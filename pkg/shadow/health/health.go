@@ -0,0 +1,21 @@
+package health
+
+import (
+	"fmt"
+	"github.com/alibaba/kt-connect/pkg/common"
+	"github.com/rs/zerolog/log"
+	"net/http"
+)
+
+// Start setup health endpoint, which always reports ready as long as shadow is running
+func Start(port int) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(common.ShadowHealthPath, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+	log.Info().Msgf("Health endpoint listening on port %d", port)
+	if err := http.ListenAndServe(fmt.Sprintf(":%d", port), mux); err != nil {
+		log.Error().Err(err).Msgf("Failed to start health endpoint")
+	}
+}